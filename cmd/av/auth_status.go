@@ -7,6 +7,7 @@ import (
 
 	"emperror.dev/errors"
 	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/config"
 	"github.com/aviator-co/av/internal/gh"
 
 	"github.com/aviator-co/av/internal/avgql"
@@ -75,8 +76,12 @@ func checkGitHubAuthStatus() error {
 		return errors.Wrap(err, "Failed to query GitHub")
 	}
 
+	_, _ = fmt.Fprint(os.Stderr, "Logged in to GitHub")
+	if host := config.Av.GitHub.Host(); host != "github.com" {
+		_, _ = fmt.Fprint(os.Stderr, " (", colors.UserInput(host), ")")
+	}
 	_, _ = fmt.Fprint(os.Stderr,
-		"Logged in to GitHub as ", colors.UserInput(viewer.Name),
+		" as ", colors.UserInput(viewer.Name),
 		" (", colors.UserInput(viewer.Login), ").\n",
 	)
 	return nil
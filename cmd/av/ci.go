@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/cleanup"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/fatih/color"
+	"github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var ciCmd = &cobra.Command{
+	Use:   "ci",
+	Short: "commands for running av in CI (e.g., GitHub Actions)",
+}
+
+// The values of the --comment flag of `av ci sync`.
+const (
+	ciCommentNone     = "none"
+	ciCommentFailures = "failures"
+	ciCommentAll      = "all"
+)
+
+// The statuses of the branches reported by `av ci sync`.
+const (
+	ciStatusSynced   = "synced"
+	ciStatusUpToDate = "up-to-date"
+	ciStatusConflict = "conflict"
+	ciStatusSkipped  = "skipped"
+)
+
+var ciSyncFlags struct {
+	// Which results to comment on the pull requests (see ciComment*).
+	Comment string
+}
+
+var ciSyncCmd = &cobra.Command{
+	Use:   "sync [flags]",
+	Short: "sync all stacks onto the latest trunk (for use in CI)",
+	Long: `Sync all stacks of the repository onto the latest commit of their trunk.
+
+This is meant to be run in CI (e.g., in a GitHub Actions workflow that runs
+whenever the trunk changes) and implies --ci. The stacks are reconstructed from
+the open pull requests that were created by av, so this works in a fresh clone
+of the repository. Each stack is synced independently: if a stack can't be
+synced because of a conflict, its sync is aborted and the other stacks are still
+synced.
+
+The result for each branch is written to stdout as a line of JSON. With
+--comment, the results are also posted as comments on the pull requests.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) (reterr error) {
+		switch ciSyncFlags.Comment {
+		case ciCommentNone, ciCommentFailures, ciCommentAll:
+		default:
+			return errors.Errorf(
+				"invalid value %q for --comment (expected none, failures, or all)",
+				ciSyncFlags.Comment,
+			)
+		}
+		rootFlags.CI = true
+		color.NoColor = true
+
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		if _, ok := db.ReadTx().Repository(); !ok {
+			if err := initRepository(repo, db); err != nil {
+				return err
+			}
+		}
+		if _, err := repo.Run(&git.RunOpts{
+			Args:      []string{"fetch", "--prune", "origin"},
+			ExitError: true,
+		}); err != nil {
+			return errors.WrapIf(err, "failed to fetch from origin")
+		}
+		client, err := getGitHubClient()
+		if err != nil {
+			return err
+		}
+		ctx := context.Background()
+		if err := importPullRequestBranches(ctx, repo, db, client); err != nil {
+			return err
+		}
+
+		// Return to wherever the CI job checked out the repository when we're
+		// done (which is usually a detached HEAD).
+		orig, err := repo.Git("rev-parse", "--abbrev-ref", "HEAD")
+		if err == nil && orig == "HEAD" {
+			orig, err = repo.Git("rev-parse", "HEAD")
+		}
+		if err != nil {
+			return errors.WrapIf(err, "failed to determine HEAD")
+		}
+		defer func() {
+			if _, err := repo.Git("checkout", "--quiet", orig); err != nil && reterr == nil {
+				reterr = errors.WrapIff(err, "failed to check out %q", orig)
+			}
+		}()
+
+		db, err = getDB(repo)
+		if err != nil {
+			return err
+		}
+		stacks := ciStacks(db.ReadTx())
+		enc := json.NewEncoder(os.Stdout)
+		conflicts := 0
+		for _, stack := range stacks {
+			results, err := ciSyncStack(repo, stack)
+			if err != nil {
+				return err
+			}
+			for _, result := range results {
+				if result.Status == ciStatusConflict {
+					conflicts++
+				}
+				if err := enc.Encode(result); err != nil {
+					return err
+				}
+				if err := commentCISyncResult(ctx, client, result); err != nil {
+					// Failing to comment shouldn't hide the results of the
+					// other stacks.
+					logrus.WithError(err).WithField("branch", result.Branch).
+						Warn("failed to comment on pull request")
+				}
+			}
+		}
+
+		if conflicts > 0 {
+			_, _ = fmt.Fprint(os.Stderr,
+				colors.Failure("Failed to sync ", conflicts, " branch(es) because of conflicts.\n"),
+			)
+			return actions.ErrExitSilently{ExitCode: 1}
+		}
+		return nil
+	},
+}
+
+// ciSyncResult is the result of `av ci sync` for a single branch.
+type ciSyncResult struct {
+	Branch      string `json:"branch"`
+	PullRequest int64  `json:"pullRequest,omitempty"`
+	Status      string `json:"status"`
+	Message     string `json:"message"`
+
+	pullRequestID string
+}
+
+// importPullRequestBranches imports the branches of the open pull requests
+// that were created by av (along with their merged parents) into the av
+// database.
+func importPullRequestBranches(
+	ctx context.Context,
+	repo *git.Repo,
+	db meta.DB,
+	client *gh.Client,
+) (reterr error) {
+	tx := db.WriteTx()
+	cu := cleanup.New(func() {
+		logrus.WithError(reterr).Debug("aborting db transaction")
+		tx.Abort()
+	})
+	defer cu.Cleanup()
+	info, ok := tx.Repository()
+	if !ok {
+		return actions.ErrRepoNotInitialized
+	}
+
+	var prs []gh.PullRequest
+	var cursor string
+	for {
+		page, err := client.RepoPullRequests(ctx, gh.RepoPullRequestOpts{
+			Owner:  info.Owner,
+			Repo:   info.Name,
+			After:  cursor,
+			States: []githubv4.PullRequestState{githubv4.PullRequestStateOpen},
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to fetch pull requests from GitHub")
+		}
+		prs = append(prs, page.PullRequests...)
+		if !page.HasNextPage {
+			break
+		}
+		cursor = page.EndCursor
+	}
+	imported, err := actions.ImportPullRequestBranches(repo, tx, prs)
+	if err != nil {
+		return err
+	}
+
+	// The parents of the imported branches might have been merged already (in
+	// which case they aren't among the open pull requests).
+	for i := 0; i < len(imported); i++ {
+		branch, _ := tx.Branch(imported[i])
+		if branch.Parent.Trunk {
+			continue
+		}
+		if _, ok := tx.Branch(branch.Parent.Name); ok {
+			continue
+		}
+		page, err := client.GetPullRequests(ctx, gh.GetPullRequestsInput{
+			Owner:       info.Owner,
+			Repo:        info.Name,
+			HeadRefName: branch.Parent.Name,
+			States:      []githubv4.PullRequestState{githubv4.PullRequestStateMerged},
+		})
+		if err != nil {
+			return err
+		}
+		parents, err := actions.ImportPullRequestBranches(repo, tx, page.PullRequests)
+		if err != nil {
+			return err
+		}
+		imported = append(imported, parents...)
+	}
+	if len(imported) > 0 {
+		_, _ = fmt.Fprint(os.Stderr,
+			"Imported ", colors.UserInput(len(imported)), " branch(es) from pull requests.\n",
+		)
+	}
+
+	cu.Cancel()
+	return tx.Commit()
+}
+
+// ciStacks returns the branches of every stack (starting with the root).
+func ciStacks(tx meta.ReadTx) [][]string {
+	var roots []string
+	for _, branch := range tx.AllBranches() {
+		if branch.IsStackRoot() {
+			roots = append(roots, branch.Name)
+		}
+	}
+	sort.Strings(roots)
+	var stacks [][]string
+	for _, root := range roots {
+		stacks = append(stacks, append([]string{root}, meta.SubsequentBranches(tx, root)...))
+	}
+	return stacks
+}
+
+// ciSyncStack syncs the given stack onto its trunk and returns the results for
+// the branches that haven't been merged yet. If the sync runs into a conflict,
+// it's aborted.
+func ciSyncStack(repo *git.Repo, stack []string) ([]ciSyncResult, error) {
+	db, err := getDB(repo)
+	if err != nil {
+		return nil, err
+	}
+	tx := db.ReadTx()
+	var branches []meta.Branch
+	heads := make(map[string]string)
+	for _, name := range stack {
+		branch, _ := tx.Branch(name)
+		if branch.MergeCommit != "" ||
+			(branch.PullRequest != nil && branch.PullRequest.State != githubv4.PullRequestStateOpen) {
+			continue
+		}
+		head, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + name})
+		if err != nil {
+			return nil, errors.WrapIff(err, "failed to determine HEAD for branch %q", name)
+		}
+		heads[name] = head
+		branches = append(branches, branch)
+	}
+	if len(branches) == 0 {
+		return nil, nil
+	}
+	trunk, _ := meta.Trunk(tx, branches[0].Name)
+	if root, freeze := meta.StackFreeze(tx, branches[0].Name); freeze != nil {
+		_, _ = fmt.Fprint(os.Stderr, "Skipping the stack of ", colors.UserInput(root), colors.Faint(" (frozen)"), "\n")
+		var results []ciSyncResult
+		for _, branch := range branches {
+			result := ciSyncResult{
+				Branch: branch.Name,
+				Status: ciStatusSkipped,
+				Message: fmt.Sprintf(
+					"did not rebase the branch onto the latest %s because the stack was frozen by %s",
+					trunk, freeze.By,
+				),
+			}
+			if branch.PullRequest != nil {
+				result.PullRequest = branch.PullRequest.Number
+				result.pullRequestID = branch.PullRequest.ID
+			}
+			results = append(results, result)
+		}
+		return results, nil
+	}
+
+	_, _ = fmt.Fprint(os.Stderr, "Syncing the stack of ", colors.UserInput(branches[0].Name), "...\n")
+	if _, err := repo.CheckoutBranch(&git.CheckoutBranch{Name: branches[0].Name}); err != nil {
+		return nil, err
+	}
+	syncErr := runStackSync(actions.StackSyncConfig{Trunk: true})
+	conflict, err := syncConflictBranch(repo)
+	if err != nil {
+		return nil, err
+	}
+	if conflict == "" && syncErr != nil {
+		return nil, syncErr
+	}
+	// The branches that weren't synced because of the conflict (the
+	// descendants of the conflicting branch and any other conflicting
+	// branches).
+	var pending []string
+	if conflict != "" {
+		state, err := actions.ReadStackSyncState(repo)
+		if err != nil {
+			return nil, err
+		}
+		pending = state.Branches[slices.Index(state.Branches, conflict)+1:]
+		if err := abortStackSync(); err != nil {
+			return nil, err
+		}
+	}
+
+	var results []ciSyncResult
+	for _, branch := range branches {
+		result := ciSyncResult{Branch: branch.Name}
+		if branch.PullRequest != nil {
+			result.PullRequest = branch.PullRequest.Number
+			result.pullRequestID = branch.PullRequest.ID
+		}
+		switch {
+		case branch.Name == conflict:
+			result.Status = ciStatusConflict
+			result.Message = fmt.Sprintf(
+				"could not rebase the branch onto the latest %s because of a conflict", trunk,
+			)
+		case slices.Contains(pending, branch.Name):
+			result.Status = ciStatusSkipped
+			result.Message = fmt.Sprintf(
+				"did not rebase the branch onto the latest %s because of a conflict in %s",
+				trunk, conflict,
+			)
+		default:
+			head, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + branch.Name})
+			if err != nil {
+				return nil, errors.WrapIff(err, "failed to determine HEAD for branch %q", branch.Name)
+			}
+			if head == heads[branch.Name] {
+				result.Status = ciStatusUpToDate
+				result.Message = fmt.Sprintf("the branch is up-to-date with %s", trunk)
+			} else {
+				result.Status = ciStatusSynced
+				result.Message = fmt.Sprintf("rebased the branch onto the latest %s", trunk)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// commentCISyncResult posts the result as a comment on the pull request of the
+// branch (depending on --comment).
+func commentCISyncResult(ctx context.Context, client *gh.Client, result ciSyncResult) error {
+	if result.pullRequestID == "" {
+		return nil
+	}
+	var body string
+	switch result.Status {
+	case ciStatusConflict, ciStatusSkipped:
+		if ciSyncFlags.Comment == ciCommentNone {
+			return nil
+		}
+		body = fmt.Sprintf(
+			"av %s.\n\nRun `av stack sync --trunk` locally to resolve the conflict.",
+			result.Message,
+		)
+	case ciStatusSynced:
+		if ciSyncFlags.Comment != ciCommentAll {
+			return nil
+		}
+		body = fmt.Sprintf("av %s.", result.Message)
+	default:
+		return nil
+	}
+	return client.AddComment(ctx, result.pullRequestID, body)
+}
+
+func init() {
+	ciCmd.AddCommand(ciSyncCmd)
+	ciSyncCmd.Flags().StringVar(
+		&ciSyncFlags.Comment, "comment", ciCommentNone,
+		"comment the results on the pull requests (none, failures, or all)",
+	)
+}
@@ -30,10 +30,27 @@ var commitAmendCmd = &cobra.Command{
 			return err
 		}
 
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+
 		currentBranchName, err := repo.CurrentBranchName()
 		if err != nil {
 			return errors.WrapIf(err, "failed to determine current branch")
 		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		if err := ensureNotReadOnly(db.ReadTx(), currentBranchName); err != nil {
+			return err
+		}
+		if err := ensureStacksNotFrozen(db.ReadTx(), currentBranchName); err != nil {
+			return err
+		}
+		if err := snapshotBranches(repo, db.ReadTx(), currentBranchName); err != nil {
+			return err
+		}
 
 		commitArgs := []string{"commit", "--amend"}
 		if commitAmendFlags.NoEdit {
@@ -44,7 +61,7 @@ var commitAmendCmd = &cobra.Command{
 		}
 
 		if _, err := repo.Run(&git.RunOpts{
-			Args:        commitArgs,
+			Args:        actions.WithCommitTrailers(db.ReadTx(), currentBranchName, commitArgs),
 			ExitError:   true,
 			Interactive: true,
 		}); err != nil {
@@ -61,10 +78,6 @@ var commitAmendCmd = &cobra.Command{
 			return err
 		}
 		ctx := context.Background()
-		db, err := getDB(repo)
-		if err != nil {
-			return err
-		}
 		tx := db.WriteTx()
 		defer tx.Abort()
 
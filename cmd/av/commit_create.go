@@ -1,12 +1,15 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"emperror.dev/errors"
 	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/config"
 	"github.com/aviator-co/av/internal/git"
 	"github.com/aviator-co/av/internal/meta"
 	"github.com/aviator-co/av/internal/utils/colors"
@@ -30,11 +33,22 @@ var commitCreateCmd = &cobra.Command{
 			return err
 		}
 
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+
 		currentBranchName, err := repo.CurrentBranchName()
 		if err != nil {
 			return errors.WrapIf(err, "failed to determine current branch")
 		}
 
+		if config.Av.Stack.GuardTrunkCommits {
+			currentBranchName, err = guardTrunkCommit(repo, currentBranchName, commitCreateFlags.All)
+			if err != nil {
+				return err
+			}
+		}
+
 		if err := commitCreate(repo, currentBranchName, commitCreateFlags); err != nil {
 			return err
 		}
@@ -54,6 +68,21 @@ func commitCreate(repo *git.Repo, currentBranchName string, flags struct {
 	Message string
 	All     bool
 }) error {
+	db, err := getDB(repo)
+	if err != nil {
+		return err
+	}
+	if err := ensureNotReadOnly(db.ReadTx(), currentBranchName); err != nil {
+		return err
+	}
+	// The new commit itself doesn't rewrite anything, but the children of the
+	// branch are restacked onto it.
+	if len(meta.SubsequentBranches(db.ReadTx(), currentBranchName)) > 0 {
+		if err := ensureStacksNotFrozen(db.ReadTx(), currentBranchName); err != nil {
+			return err
+		}
+	}
+
 	commitArgs := []string{"commit"}
 	if commitCreateFlags.All {
 		commitArgs = append(commitArgs, "--all")
@@ -63,7 +92,7 @@ func commitCreate(repo *git.Repo, currentBranchName string, flags struct {
 	}
 
 	if _, err := repo.Run(&git.RunOpts{
-		Args:        commitArgs,
+		Args:        actions.WithCommitTrailers(db.ReadTx(), currentBranchName, commitArgs),
 		ExitError:   true,
 		Interactive: true,
 	}); err != nil {
@@ -80,10 +109,6 @@ func commitCreate(repo *git.Repo, currentBranchName string, flags struct {
 		return err
 	}
 	ctx := context.Background()
-	db, err := getDB(repo)
-	if err != nil {
-		return err
-	}
 	tx := db.WriteTx()
 	defer tx.Abort()
 
@@ -101,3 +126,65 @@ func commitCreate(repo *git.Repo, currentBranchName string, flags struct {
 
 	return nil
 }
+
+// guardTrunkCommit warns the user if they're about to commit staged changes
+// (or, with all, any changes to tracked files) directly to the trunk and offers
+// to create a new stacked branch instead. It returns the name of the branch
+// that the commit should be created on.
+func guardTrunkCommit(repo *git.Repo, currentBranchName string, all bool) (string, error) {
+	db, err := getDB(repo)
+	if err != nil {
+		return "", err
+	}
+	trunk, err := actions.ChangesOnTrunk(repo, db.ReadTx(), all)
+	if err != nil {
+		return "", err
+	}
+	if trunk == "" {
+		return currentBranchName, nil
+	}
+
+	_, _ = fmt.Fprint(
+		os.Stderr,
+		colors.Warning("WARNING: you're about to commit directly to the trunk branch "),
+		colors.UserInput(trunk),
+		colors.Warning(".\n"),
+	)
+	if !isInteractive() {
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			colors.Faint("  - Use "),
+			colors.CliCmd("av stack branch <branch-name>"),
+			colors.Faint(" to create a stacked branch first (the changes are carried over).\n"),
+		)
+		return "", actions.ErrExitSilently{ExitCode: 1}
+	}
+
+	_, _ = fmt.Fprint(
+		os.Stderr,
+		"Enter a name to create a new stacked branch (or leave empty to commit to ",
+		colors.UserInput(trunk),
+		" anyway): ",
+	)
+	name, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return currentBranchName, nil
+	}
+	if err := ensureNoBranchCaseCollision(repo, name); err != nil {
+		return "", err
+	}
+	tx := db.WriteTx()
+	defer tx.Abort()
+	// The staged changes are carried over to the new branch by Git.
+	if err := createStackedBranch(repo, tx, name, trunk, trunk, true, 0); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return name, nil
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
 	"github.com/aviator-co/av/internal/git"
 	"github.com/aviator-co/av/internal/utils/colors"
 	"github.com/spf13/cobra"
@@ -19,6 +20,11 @@ var commitSplitCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+
 		if clean, err := repo.CheckCleanWorkdir(); err != nil {
 			return err
 		} else if !clean {
@@ -36,9 +42,28 @@ var commitSplitCmd = &cobra.Command{
 			return errors.Errorf("cannot get the current commit object: %v", err)
 		}
 
+		// Add --verbose to show the diffs to be committed.
+		commitArgs := []string{"commit", "--verbose", "--reedit-message", currentCommitOID}
+		if currentBranchName != "" {
+			db, err := getDB(repo)
+			if err != nil {
+				return err
+			}
+			if err := ensureNotReadOnly(db.ReadTx(), currentBranchName); err != nil {
+				return err
+			}
+			if err := ensureStacksNotFrozen(db.ReadTx(), currentBranchName); err != nil {
+				return err
+			}
+			if err := snapshotBranches(repo, db.ReadTx(), currentBranchName); err != nil {
+				return err
+			}
+			commitArgs = actions.WithCommitTrailers(db.ReadTx(), currentBranchName, commitArgs)
+		}
+
 		// From here, we use detached HEAD, so that even if something goes wrong or user
 		// aborts the operation in the middle, the original branch is intact.
-		if err := splitCommit(repo, currentBranchName, currentCommitOID); err != nil {
+		if err := splitCommit(repo, currentBranchName, currentCommitOID, commitArgs); err != nil {
 			commitSplitAbortMessage(currentBranchName, currentCommitOID)
 			return err
 		}
@@ -46,7 +71,9 @@ var commitSplitCmd = &cobra.Command{
 	},
 }
 
-func splitCommit(repo *git.Repo, currentBranchName, currentCommitOID string) error {
+// splitCommit splits the given commit by creating commits (with the given `git
+// commit` arguments) until all of its changes are committed.
+func splitCommit(repo *git.Repo, currentBranchName, currentCommitOID string, commitArgs []string) error {
 	if _, err := repo.Git("switch", "--detach", currentCommitOID); err != nil {
 		return err
 	}
@@ -79,8 +106,7 @@ func splitCommit(repo *git.Repo, currentBranchName, currentCommitOID string) err
 		}
 
 		if _, err := repo.Run(&git.RunOpts{
-			// Add --verbose to show the diffs to be committed.
-			Args:        []string{"commit", "--verbose", "--reedit-message", currentCommitOID},
+			Args:        commitArgs,
 			ExitError:   true,
 			Interactive: true,
 		}); err != nil {
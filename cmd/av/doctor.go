@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/spf13/cobra"
+)
+
+// doctorCheck is a single check performed by `av doctor`.
+type doctorCheck struct {
+	// A short description of what is being checked.
+	Name string
+	// Run performs the check. If a problem is found, a description of the
+	// problem (and optionally, advice on how to fix it) is returned.
+	Run func(repo *git.Repo, db meta.DB) (problem string, advice string, err error)
+	// If set, the check is skipped unless it returns true (e.g., for checks
+	// that have to be enabled in the config).
+	Enabled func() bool
+}
+
+var doctorChecks = []doctorCheck{
+	{
+		Name:    "no staged changes on trunk",
+		Run:     doctorCheckTrunkCommit,
+		Enabled: func() bool { return config.Av.Stack.GuardTrunkCommits },
+	},
+	{Name: "no cycles in branch metadata", Run: doctorCheckCycles},
+	{Name: "all tracked branches exist", Run: doctorCheckMissingBranches},
+	{Name: "recorded parent commits are part of branch history", Run: doctorCheckParentHeads},
+}
+
+var doctorCmd = &cobra.Command{
+	Use:          "doctor",
+	Short:        "check the repository for common problems",
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+
+		problems := 0
+		for _, check := range doctorChecks {
+			if check.Enabled != nil && !check.Enabled() {
+				continue
+			}
+			problem, advice, err := check.Run(repo, db)
+			if err != nil {
+				return err
+			}
+			if problem == "" {
+				_, _ = fmt.Fprint(os.Stderr, colors.Success("  ✓ "), check.Name, "\n")
+				continue
+			}
+			problems++
+			_, _ = fmt.Fprint(
+				os.Stderr,
+				colors.Failure("  ✗ ", check.Name, ": ", problem), "\n",
+			)
+			if advice != "" {
+				_, _ = fmt.Fprint(os.Stderr, colors.Faint("      ", advice), "\n")
+			}
+		}
+
+		if problems > 0 {
+			return actions.ErrExitSilently{ExitCode: 1}
+		}
+		return nil
+	},
+}
+
+func doctorCheckTrunkCommit(repo *git.Repo, db meta.DB) (string, string, error) {
+	trunk, err := actions.ChangesOnTrunk(repo, db.ReadTx(), false)
+	if err != nil || trunk == "" {
+		return "", "", err
+	}
+	return fmt.Sprintf("there are staged changes on the trunk branch %q", trunk),
+		"Use `av stack branch <branch-name>` to move them onto a new stacked branch.",
+		nil
+}
+
+func doctorCheckCycles(_ *git.Repo, db meta.DB) (string, string, error) {
+	cycles := meta.FindCycles(db.ReadTx().AllBranches())
+	if len(cycles) == 0 {
+		return "", "", nil
+	}
+	var problems []string
+	for _, cycle := range cycles {
+		problems = append(problems, strings.Join(cycle.Branches, ", "))
+	}
+	return fmt.Sprintf("the parents of these branches form a cycle: %s", strings.Join(problems, "; ")),
+		"Use `av stack orphan` on one of the branches (or edit .git/av/av.db) to break the cycle.",
+		nil
+}
+
+func doctorCheckMissingBranches(repo *git.Repo, db meta.DB) (string, string, error) {
+	missing, err := actions.MissingBranches(repo, db.ReadTx())
+	if err != nil || len(missing) == 0 {
+		return "", "", err
+	}
+	return fmt.Sprintf(
+			"these branches were deleted outside of av: %s",
+			strings.Join(missing, ", "),
+		),
+		"Use `av stack tree --prune-metadata` to stop tracking them.",
+		nil
+}
+
+func doctorCheckParentHeads(repo *git.Repo, db meta.DB) (string, string, error) {
+	var stale []string
+	for name, branch := range db.ReadTx().AllBranches() {
+		if exists, err := repo.DoesBranchExist(name); err != nil {
+			return "", "", err
+		} else if !exists {
+			continue
+		}
+		res, err := actions.ReconcileParentHead(repo, branch)
+		if err != nil {
+			return "", "", err
+		}
+		if res.Updated || res.Stale {
+			stale = append(stale, name)
+		}
+	}
+	if len(stale) == 0 {
+		return "", "", nil
+	}
+	slices.Sort(stale)
+	return fmt.Sprintf(
+			"the parent branches of these branches were rewritten outside of av: %s",
+			strings.Join(stale, ", "),
+		),
+		"Use `av stack sync` to rebase them onto the latest version of their parents.",
+		nil
+}
@@ -1,16 +1,26 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"fmt"
 	"os"
 	"os/exec"
 	"path"
+	"slices"
 	"strings"
 
 	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/gh"
 	"github.com/aviator-co/av/internal/git"
 	"github.com/aviator-co/av/internal/meta"
 	"github.com/aviator-co/av/internal/meta/jsonfiledb"
 	"github.com/aviator-co/av/internal/meta/refmeta"
+	"github.com/aviator-co/av/internal/reorder"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/aviator-co/av/internal/utils/timeutils"
+	"github.com/mattn/go-isatty"
 	"github.com/sirupsen/logrus"
 )
 
@@ -65,3 +75,272 @@ func getDB(repo *git.Repo) (meta.DB, error) {
 	}
 	return db, nil
 }
+
+// snapshotBranches records the tips of the given branches (and the branches in
+// their stacks) before a history-rewriting operation so that they can be
+// recovered manually if something goes wrong (see actions.SnapshotBranches).
+func snapshotBranches(repo *git.Repo, tx meta.ReadTx, branches ...string) error {
+	var all []string
+	for _, branch := range branches {
+		all = append(all, branch)
+		if stack, err := meta.StackBranches(tx, branch); err == nil {
+			all = append(all, stack...)
+		}
+	}
+	slices.Sort(all)
+	if _, err := actions.SnapshotBranches(repo, slices.Compact(all)); err != nil {
+		return errors.WrapIf(err, "failed to back up the branches before rewriting them")
+	}
+	return nil
+}
+
+// ensureStacksNotFrozen makes sure that none of the given branches belongs to a
+// frozen stack (see av stack freeze) before rewriting their history.
+func ensureStacksNotFrozen(tx meta.ReadTx, branches ...string) error {
+	for _, name := range branches {
+		root, freeze := meta.StackFreeze(tx, name)
+		if freeze == nil {
+			continue
+		}
+		_, _ = fmt.Fprint(os.Stderr,
+			colors.Failure("The stack of ", root, " is frozen, so its history can't be rewritten.\n"),
+		)
+		printStackFreeze(freeze)
+		_, _ = fmt.Fprint(os.Stderr,
+			colors.Faint("  - Use "), colors.CliCmd("av stack unfreeze"),
+			colors.Faint(" to unfreeze it.\n"),
+		)
+		return actions.ErrExitSilently{ExitCode: 1}
+	}
+	return nil
+}
+
+// ensureNotReadOnly makes sure that none of the given branches is a read-only
+// branch of someone else (see av stack adopt --read-only) before committing to
+// them or rewriting their history.
+func ensureNotReadOnly(tx meta.ReadTx, branches ...string) error {
+	for _, name := range branches {
+		if branch, _ := tx.Branch(name); !branch.ReadOnly {
+			continue
+		}
+		_, _ = fmt.Fprint(os.Stderr,
+			colors.Failure("Branch ", name, " is read-only since it belongs to someone else.\n"),
+			colors.Faint("  - Use "), colors.CliCmd("av stack branch <branch-name>"),
+			colors.Faint(" to stack a branch of your own on top of it.\n"),
+		)
+		return actions.ErrExitSilently{ExitCode: 1}
+	}
+	return nil
+}
+
+// githubWriteAccessChecked is set once the GitHub token was checked by
+// ensureGitHubWriteAccess, so that it's only checked once per command.
+var githubWriteAccessChecked bool
+
+// ensureGitHubWriteAccess makes sure that the GitHub token can create and
+// update pull requests in the repository before starting an operation that does
+// so (see actions.CheckWriteAccess).
+func ensureGitHubWriteAccess(ctx context.Context, client *gh.Client, tx meta.ReadTx) error {
+	if githubWriteAccessChecked {
+		return nil
+	}
+	repository, ok := tx.Repository()
+	if !ok {
+		// The operation will fail with a better error later on.
+		return nil
+	}
+	if err := actions.CheckWriteAccess(ctx, client, repository); actions.IsOffline(err) {
+		return err
+	} else if err != nil {
+		_, _ = fmt.Fprint(os.Stderr,
+			colors.Failure("Error: ", err.Error(), "."), "\n",
+			colors.Faint("  - Use "), colors.CliCmd("av auth status"),
+			colors.Faint(" to see which token is used.\n"),
+		)
+		return actions.ErrExitSilently{ExitCode: 1}
+	}
+	githubWriteAccessChecked = true
+	return nil
+}
+
+// printQueuedOffline tells the user that an update of a pull request was
+// queued because GitHub can't be reached (see actions.IsOffline).
+func printQueuedOffline(what string) {
+	_, _ = fmt.Fprint(os.Stderr,
+		colors.Warning("GitHub can't be reached:"), " queued the update of ", what,
+		" (run ", colors.CliCmd("av push --pending"), " once you're online)\n",
+	)
+}
+
+// printStackFreeze prints who froze a stack, when, and why.
+func printStackFreeze(freeze *meta.FreezeInfo) {
+	_, _ = fmt.Fprint(os.Stderr,
+		colors.Faint("  - Frozen by "), colors.UserInput(freeze.By),
+		colors.Faint(" on ", timeutils.FormatLocal(freeze.Time)), "\n",
+	)
+	if freeze.Reason != "" {
+		_, _ = fmt.Fprint(os.Stderr, colors.Faint("  - Reason: "), freeze.Reason, "\n")
+	}
+}
+
+// isInteractive returns true if stdin is a terminal (i.e., it's possible to
+// prompt the user for input) and av isn't running in CI mode. Checking for a
+// character device isn't enough since /dev/null is one too (e.g., when av is
+// run by a script or another program with its stdin closed), in which case a
+// prompt would only read EOF.
+func isInteractive() bool {
+	if rootFlags.CI {
+		return false
+	}
+	return isatty.IsTerminal(os.Stdin.Fd()) || isatty.IsCygwinTerminal(os.Stdin.Fd())
+}
+
+// getCurrentBranchName returns the name of the current branch.
+//
+// If the repository is in detached HEAD (e.g., after a failed rebase or a
+// bisect), this tries to determine which stack branch HEAD belongs to and
+// offers to reattach to (or fast-forward) that branch instead of failing
+// outright.
+func getCurrentBranchName(repo *git.Repo, db meta.DB) (string, error) {
+	currentBranch, err := repo.CurrentBranchName()
+	if err == nil {
+		return currentBranch, nil
+	}
+	if dh, dhErr := repo.DetachedHead(); dhErr != nil || !dh {
+		return "", err
+	}
+	if op, opErr := repo.OperationInProgress(); opErr != nil || op != git.OperationNone {
+		// The user should finish the in-progress operation first.
+		return "", err
+	}
+
+	head, revErr := repo.RevParse(&git.RevParse{Rev: "HEAD"})
+	if revErr != nil {
+		return "", err
+	}
+	candidates, findErr := actions.FindDetachedHeadBranches(repo, db.ReadTx(), head)
+	if findErr != nil {
+		logrus.WithError(findErr).Debug("failed to find branches for detached HEAD")
+		return "", err
+	}
+	if len(candidates) != 1 {
+		if len(candidates) > 1 {
+			_, _ = fmt.Fprint(
+				os.Stderr,
+				"HEAD is detached at ", colors.UserInput(git.ShortSha(head)),
+				", which could belong to any of the following branches:\n",
+			)
+			for _, c := range candidates {
+				_, _ = fmt.Fprint(os.Stderr, "  - ", colors.UserInput(c.Name), "\n")
+			}
+		}
+		return "", err
+	}
+
+	candidate := candidates[0]
+	args := []string{"checkout", candidate.Name}
+	action := "reattach to"
+	if candidate.FastForward {
+		args = []string{"checkout", "-B", candidate.Name}
+		action = "fast-forward and reattach to"
+	}
+	_, _ = fmt.Fprint(
+		os.Stderr,
+		"HEAD is detached at ", colors.UserInput(git.ShortSha(head)),
+		", which belongs to stack branch ", colors.UserInput(candidate.Name), ".\n",
+	)
+	if !isInteractive() {
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			colors.Troubleshooting("  - Run "),
+			colors.CliCmd("git ", strings.Join(args, " ")),
+			colors.Troubleshooting(" to ", action, " the branch.\n"),
+		)
+		return "", actions.ErrExitSilently{ExitCode: 1}
+	}
+	_, _ = fmt.Fprint(os.Stderr, "Would you like to ", action, " it? [y/N]: ")
+	choice, readErr := bufio.NewReader(os.Stdin).ReadString('\n')
+	if readErr != nil || !strings.EqualFold(strings.TrimSpace(choice), "y") {
+		return "", actions.ErrExitSilently{ExitCode: 1}
+	}
+	if _, err := repo.Run(&git.RunOpts{Args: args, ExitError: true}); err != nil {
+		return "", errors.WrapIff(err, "failed to %s branch %q", action, candidate.Name)
+	}
+	return candidate.Name, nil
+}
+
+// ensureNoGitOperationInProgress returns an error (after printing guidance on
+// how to proceed) if a Git operation like a rebase or merge is in progress.
+// Mutating commands should call this before touching the repository, since
+// running them in the middle of such an operation tends to fail with confusing
+// errors (or worse, mix the operation's changes into unrelated branches).
+func ensureNoGitOperationInProgress(repo *git.Repo) error {
+	op, err := repo.OperationInProgress()
+	if err != nil {
+		return err
+	}
+	if op == git.OperationNone {
+		return nil
+	}
+
+	_, _ = fmt.Fprint(
+		os.Stderr,
+		colors.Failure("A ", op, " is currently in progress.\n"),
+	)
+	if _, err := actions.ReadStackSyncState(repo); err == nil {
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			colors.Troubleshooting("  - It was started by "),
+			colors.CliCmd("av stack sync"),
+			colors.Troubleshooting(". Resolve any conflicts and run "),
+			colors.CliCmd("av stack sync --continue"),
+			colors.Troubleshooting(",\n    or run "),
+			colors.CliCmd("av stack sync --abort"),
+			colors.Troubleshooting(" to cancel it.\n"),
+		)
+	} else if _, err := reorder.ReadContinuation(repo); err == nil {
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			colors.Troubleshooting("  - It was started by "),
+			colors.CliCmd("av stack reorder"),
+			colors.Troubleshooting(". Resolve any conflicts and run "),
+			colors.CliCmd("av stack reorder --continue"),
+			colors.Troubleshooting(",\n    or run "),
+			colors.CliCmd("av stack reorder --abort"),
+			colors.Troubleshooting(" to cancel it.\n"),
+		)
+	} else {
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			colors.Troubleshooting("  - Resolve any conflicts and run "),
+			colors.CliCmd("git ", op, " --continue"),
+			colors.Troubleshooting(",\n    or run "),
+			colors.CliCmd("git ", op, " --abort"),
+			colors.Troubleshooting(" to cancel it.\n"),
+		)
+	}
+	return actions.ErrExitSilently{ExitCode: 1}
+}
+
+// ensureNoBranchCaseCollision fails if creating a branch with the given name
+// would collide with an existing branch (other than the ones given in ignore)
+// on a case-insensitive filesystem.
+func ensureNoBranchCaseCollision(repo *git.Repo, name string, ignore ...string) error {
+	existing, err := repo.BranchCaseCollision(name, ignore...)
+	if err != nil {
+		return err
+	}
+	if existing == "" {
+		return nil
+	}
+	_, _ = fmt.Fprint(
+		os.Stderr,
+		colors.Failure("Cannot create branch ", name, ": it conflicts with the existing branch ", existing, ".\n"),
+		colors.Troubleshooting("  - Branch names that only differ in case refer to the same file on\n"),
+		colors.Troubleshooting("    case-insensitive filesystems (e.g., on macOS and Windows).\n"),
+		colors.Troubleshooting("  - Use a different name, or use "),
+		colors.CliCmd(existing),
+		colors.Troubleshooting(" if you meant the existing branch.\n"),
+	)
+	return actions.ErrExitSilently{ExitCode: 1}
+}
@@ -3,58 +3,151 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/git"
 	"github.com/aviator-co/av/internal/meta"
 	"github.com/aviator-co/av/internal/utils/cleanup"
+	"github.com/aviator-co/av/internal/utils/colors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+var initFlags struct {
+	// If true, write a GitHub Actions workflow that keeps the stacks synced
+	// instead of initializing the repository.
+	Actions bool
+	// Which results of the sync the workflow comments on the pull requests.
+	Comment string
+}
+
 var initCmd = &cobra.Command{
 	Use: "init",
-	RunE: func(cmd *cobra.Command, args []string) (reterr error) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		repo, err := getRepo()
 		if err != nil {
 			return err
 		}
+		if initFlags.Actions {
+			return writeSyncWorkflow(repo)
+		}
 
 		db, err := getDB(repo)
 		if err != nil {
 			return err
 		}
-		tx := db.WriteTx()
-		cu := cleanup.New(func() {
-			logrus.WithError(reterr).Debug("aborting db transaction")
-			tx.Abort()
-		})
-		defer cu.Cleanup()
-
-		client, err := getGitHubClient()
-		if err != nil {
+		if err := initRepository(repo, db); err != nil {
 			return err
 		}
+		_, _ = fmt.Println("Successfully initialized repository for use with av!")
+		return nil
+	},
+}
 
-		origin, err := repo.Origin()
-		if err != nil {
-			return err
+// writeSyncWorkflow writes the GitHub Actions workflow that runs `av ci sync`
+// whenever a trunk of the repository is updated.
+func writeSyncWorkflow(repo *git.Repo) error {
+	switch initFlags.Comment {
+	case ciCommentNone, ciCommentFailures, ciCommentAll:
+	default:
+		return errors.Errorf(
+			"invalid value %q for --comment (expected none, failures, or all)",
+			initFlags.Comment,
+		)
+	}
+	defaultBranch, err := repo.DefaultBranch()
+	if err != nil {
+		return err
+	}
+	trunks := []string{defaultBranch}
+	// Stacks can also be based on other trunks (e.g., release branches).
+	if db, err := getDB(repo); err == nil {
+		tx := db.ReadTx()
+		for name := range tx.AllBranches() {
+			if trunk, ok := meta.Trunk(tx, name); ok && !slices.Contains(trunks, trunk) {
+				trunks = append(trunks, trunk)
+			}
 		}
+	}
+	sort.Strings(trunks[1:])
+	writeStack := config.Av.PullRequest.WriteStack
+	if writeStack == "" {
+		writeStack = config.WriteStackBottom
+	}
 
-		ghRepo, err := client.GetRepositoryBySlug(context.Background(), origin.RepoSlug)
-		if err != nil {
-			return err
-		}
+	path := filepath.Join(repo.Dir(), actions.SyncWorkflowPath)
+	if _, err := os.Stat(path); err == nil {
+		return errors.Errorf("%s already exists (delete it to generate it again)", actions.SyncWorkflowPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	workflow := actions.SyncWorkflow(actions.SyncWorkflowOpts{
+		Trunks:     trunks,
+		WriteStack: writeStack,
+		Comment:    initFlags.Comment,
+	})
+	if err := os.WriteFile(path, []byte(workflow), 0o644); err != nil {
+		return errors.WrapIf(err, "failed to write the workflow")
+	}
+	_, _ = fmt.Fprint(os.Stderr,
+		"Wrote the GitHub Actions workflow to ", colors.UserInput(actions.SyncWorkflowPath), ".\n",
+		colors.Troubleshooting("  - commit it and push it to "), colors.UserInput(defaultBranch),
+		colors.Troubleshooting(" to keep the stacks synced with the trunk\n"),
+		colors.Troubleshooting("  - store a personal access token in the "), colors.UserInput("AV_GITHUB_TOKEN"),
+		colors.Troubleshooting(" secret so that the checks re-run after each sync\n"),
+	)
+	return nil
+}
 
-		tx.SetRepository(meta.Repository{
-			ID:    ghRepo.ID,
-			Owner: ghRepo.Owner.Login,
-			Name:  ghRepo.Name,
-		})
+// initRepository records the GitHub repository (determined from the origin
+// remote) in the av database.
+func initRepository(repo *git.Repo, db meta.DB) (reterr error) {
+	tx := db.WriteTx()
+	cu := cleanup.New(func() {
+		logrus.WithError(reterr).Debug("aborting db transaction")
+		tx.Abort()
+	})
+	defer cu.Cleanup()
 
-		cu.Cancel()
-		if err := tx.Commit(); err != nil {
-			return err
-		}
-		_, _ = fmt.Println("Successfully initialized repository for use with av!")
-		return nil
-	},
+	client, err := getGitHubClient()
+	if err != nil {
+		return err
+	}
+
+	origin, err := repo.Origin()
+	if err != nil {
+		return err
+	}
+
+	ghRepo, err := client.GetRepositoryBySlug(context.Background(), origin.RepoSlug)
+	if err != nil {
+		return err
+	}
+
+	tx.SetRepository(meta.Repository{
+		ID:    ghRepo.ID,
+		Owner: ghRepo.Owner.Login,
+		Name:  ghRepo.Name,
+	})
+
+	cu.Cancel()
+	return tx.Commit()
+}
+
+func init() {
+	initCmd.Flags().BoolVar(
+		&initFlags.Actions, "actions", false,
+		"write a GitHub Actions workflow that keeps the stacks synced with the trunk",
+	)
+	initCmd.Flags().StringVar(
+		&initFlags.Comment, "comment", ciCommentFailures,
+		"with --actions, which sync results to comment on the pull requests (none, failures, or all)",
+	)
 }
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -24,8 +25,13 @@ import (
 var rootFlags struct {
 	Debug     bool
 	Directory string
+	CI        bool
 }
 
+// If true, the check for a new version of av is skipped (e.g., for commands
+// whose output is embedded somewhere else).
+var skipCliVersionCheck bool
+
 var rootCmd = &cobra.Command{
 	Use: "av",
 
@@ -45,6 +51,15 @@ var rootCmd = &cobra.Command{
 			logrus.SetLevel(logrus.DebugLevel)
 			logrus.WithField("av_version", config.Version).Debug("enabled debug logging")
 		}
+		if !rootFlags.CI {
+			rootFlags.CI = isTruthyEnv(os.Getenv("AV_CI"))
+		}
+		if rootFlags.CI {
+			// Logs of CI jobs aren't terminals (even if they render colors),
+			// so keep the output plain.
+			color.NoColor = true
+			logrus.Debug("running in CI mode")
+		}
 
 		repoConfigDir := ""
 		repo, err := getRepo()
@@ -86,14 +101,25 @@ func init() {
 		&rootFlags.Directory, "repo", "C", "",
 		"directory to use for git repository",
 	)
+	rootCmd.PersistentFlags().BoolVar(
+		&rootFlags.CI, "ci", false,
+		"run non-interactively (never prompt) with plain output (also enabled by AV_CI=1)",
+	)
 	rootCmd.AddCommand(
 		branchMetaCmd,
+		ciCmd,
 		commitCmd,
+		doctorCmd,
 		fetchCmd,
 		initCmd,
 		prCmd,
+		promptCmd,
+		pushCmd,
 		stackCmd,
+		statusCmd,
+		switchCmd,
 		versionCmd,
+		watchCmd,
 		authCmd,
 	)
 }
@@ -104,7 +130,9 @@ func main() {
 	startTime := time.Now()
 	err := rootCmd.Execute()
 	logrus.WithField("duration", time.Since(startTime)).Debug("command exited")
-	checkCliVersion()
+	if !rootFlags.CI && !skipCliVersionCheck {
+		checkCliVersion()
+	}
 	var exitSilently actions.ErrExitSilently
 	if errors.As(err, &exitSilently) {
 		os.Exit(exitSilently.ExitCode)
@@ -123,6 +151,13 @@ func main() {
 	}
 }
 
+// isTruthyEnv returns true if the value of an environment variable enables a
+// boolean setting (e.g., "1" or "true").
+func isTruthyEnv(value string) bool {
+	b, err := strconv.ParseBool(strings.TrimSpace(value))
+	return err == nil && b
+}
+
 func checkCliVersion() {
 	if config.Version == config.VersionDev {
 		logrus.Debug("skipping CLI version check (development version)")
@@ -151,13 +186,26 @@ func checkCliVersion() {
 var once sync.Once
 var lazyGithubClient *gh.Client
 
+// discoverGitHubAPIToken returns the token for the configured GitHub instance:
+// the configured token or, for GitHub Enterprise Server, the GH_ENTERPRISE_TOKEN
+// (or GITHUB_ENTERPRISE_TOKEN) environment variable that the GitHub CLI uses as
+// well. Otherwise, the token that the GitHub CLI is logged in with for the host
+// of the instance is used.
 func discoverGitHubAPIToken() string {
 	if config.Av.GitHub.Token != "" {
 		return config.Av.GitHub.Token
 	}
+	host := config.Av.GitHub.Host()
+	if host != "github.com" {
+		for _, env := range []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"} {
+			if token := os.Getenv(env); token != "" {
+				return token
+			}
+		}
+	}
 	if ghCli, err := exec.LookPath("gh"); err == nil {
 		var stdout bytes.Buffer
-		cmd := exec.Command(ghCli, "auth", "token")
+		cmd := exec.Command(ghCli, "auth", "token", "--hostname", host)
 		cmd.Stdout = &stdout
 		cmd.Stderr = nil
 		if err := cmd.Run(); err == nil {
@@ -176,7 +224,7 @@ func getGitHubClient() (*gh.Client, error) {
 	}
 	var err error
 	once.Do(func() {
-		lazyGithubClient, err = gh.NewClient(token)
+		lazyGithubClient, err = gh.NewClient(token, config.Av.GitHub.BaseURL)
 	})
 	return lazyGithubClient, err
 }
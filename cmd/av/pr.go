@@ -12,7 +12,14 @@ var prCmd = &cobra.Command{
 func init() {
 	prCmd.AddCommand(
 		prCreateCmd,
+		prApplySuggestionCmd,
+		prBodyCmd,
+		prChecksCmd,
+		prCommentsCmd,
+		prMergeCmd,
 		prQueueCmd,
+		prReadyCmd,
+		prRestackCmd,
 		prStatusCmd,
 	)
 }
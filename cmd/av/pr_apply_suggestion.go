@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var prApplySuggestionFlags struct {
+	// If true, create a fixup commit for the commit that last changed the
+	// suggested lines.
+	Fixup bool
+}
+
+var prApplySuggestionCmd = &cobra.Command{
+	Use:   "apply-suggestion <comment-url|comment-id> [--fixup]",
+	Short: "apply a suggestion from a review comment to the branch of its pull request",
+	Long: `Apply a suggested change from a pull request review comment.
+
+The suggestion is applied to the branch of the pull request that the comment was
+made on (which doesn't have to be the current branch) and committed there. The
+branches stacked on top of it are then restacked locally. Nothing is pushed; use
+"av stack sync" or "av stack submit" afterwards to update the pull requests.
+
+The comment can be given as its URL (as copied from GitHub, e.g.,
+https://github.com/owner/repo/pull/123#discussion_r456) or as its ID (456).
+
+With --fixup, a fixup commit is created for the commit of the branch that last
+changed the suggested lines instead, so that it can be squashed into that commit
+with "git rebase --autosquash".`,
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		commentID, err := parseReviewCommentID(args[0])
+		if err != nil {
+			return err
+		}
+
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.WriteTx()
+		defer tx.Abort()
+
+		state, err := actions.ReadStackSyncState(repo)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if state.CurrentBranch != "" {
+			return errors.New("a sync is in progress: use av stack sync --continue or --abort")
+		}
+		if clean, err := repo.CheckCleanWorkdir(); err != nil {
+			return err
+		} else if !clean {
+			return errors.New("the working directory is not clean, please stash or commit your changes first")
+		}
+
+		repository, ok := tx.Repository()
+		if !ok {
+			return actions.ErrRepoNotInitialized
+		}
+		client, err := getGitHubClient()
+		if err != nil {
+			return err
+		}
+		comment, err := client.PullRequestReviewComment(ctx, repository.Owner, repository.Name, commentID)
+		if err != nil {
+			return err
+		}
+		suggestion, ok := actions.ParseSuggestion(comment.Body)
+		if !ok {
+			return errors.Errorf("review comment %d doesn't contain a suggestion", commentID)
+		}
+		branchName, err := pullRequestBranch(tx, comment.PullRequestNumber())
+		if err != nil {
+			return err
+		}
+		if err := ensureNotReadOnly(tx, branchName); err != nil {
+			return err
+		}
+		// Committing to the branch restacks its descendants, which rewrites
+		// them.
+		if err := ensureStacksNotFrozen(tx, meta.SubsequentBranches(tx, branchName)...); err != nil {
+			return err
+		}
+
+		// The lines of the file that the suggestion replaces. If the comment is
+		// outdated, only the lines that it was originally made on are known.
+		commit, start, end := comment.CommitID, comment.StartLine, comment.Line
+		if end == 0 {
+			commit, start, end = comment.OriginalCommitID, comment.OriginalStartLine, comment.OriginalLine
+		}
+		if start == 0 {
+			start = end
+		}
+		original, err := suggestionOriginalLines(repo, commit, comment.Path, start, end)
+		if err != nil {
+			return err
+		}
+
+		// Everything is validated against the head of the branch before
+		// anything is changed, so that a suggestion that can't be applied
+		// leaves the working tree alone.
+		content, err := repo.Run(&git.RunOpts{
+			Args:      []string{"show", "refs/heads/" + branchName + ":" + comment.Path},
+			ExitError: true,
+		})
+		if err != nil {
+			return errors.WrapIff(err, "failed to read %s", comment.Path)
+		}
+		updated, at, err := actions.ApplySuggestion(string(content.Stdout), original, start, suggestion)
+		if err != nil {
+			return errors.WrapIff(err, "failed to apply the suggestion to %s", comment.Path)
+		}
+		commitArgs := []string{
+			"commit",
+			"--message", fmt.Sprintf("Apply suggestion from @%s", comment.User.Login),
+			"--message", comment.HTMLURL,
+		}
+		if prApplySuggestionFlags.Fixup {
+			// The suggestion isn't committed yet, so the replaced lines are still
+			// at the head of the branch.
+			target, err := actions.SuggestionFixupTarget(
+				repo, tx, branchName, comment.Path, at, at+end-start,
+			)
+			if err != nil {
+				return err
+			}
+			commitArgs = []string{"commit", "--fixup", target}
+		}
+
+		// The file is overwritten with the content of the branch (and restored if
+		// the commit fails), so any local changes to it would be lost.
+		status, err := repo.Run(&git.RunOpts{
+			Args:      []string{"status", "--porcelain", "--", comment.Path},
+			ExitError: true,
+		})
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(status.Stdout)) > 0 {
+			return errors.Errorf("%s has uncommitted changes (commit or stash them first)", comment.Path)
+		}
+
+		previousBranch, err := repo.CheckoutBranch(&git.CheckoutBranch{Name: branchName})
+		if err != nil {
+			return err
+		}
+		if err := commitSuggestion(repo, comment.Path, updated, actions.WithCommitTrailers(tx, branchName, commitArgs)); err != nil {
+			if _, rerr := repo.CheckoutBranch(&git.CheckoutBranch{Name: previousBranch}); rerr != nil {
+				logrus.WithError(rerr).Warn("failed to switch back to the previous branch")
+			}
+			return err
+		}
+		_, _ = fmt.Fprint(os.Stderr,
+			"Applied the suggestion to ", colors.UserInput(branchName), "\n",
+		)
+
+		state.OriginalBranch = previousBranch
+		return actions.SyncStack(
+			ctx, repo, client, tx, meta.SubsequentBranches(tx, branchName), state, actions.WithLocalOnly(),
+		)
+	},
+}
+
+// commitSuggestion writes the updated content of the file (of the checked out
+// branch) and commits it. If that fails, the file is restored.
+func commitSuggestion(repo *git.Repo, path string, updated string, commitArgs []string) error {
+	if err := os.WriteFile(filepath.Join(repo.Dir(), path), []byte(updated), 0o644); err != nil {
+		return errors.WrapIff(err, "failed to write %s", path)
+	}
+	_, err := repo.Run(&git.RunOpts{Args: []string{"add", "--", path}, ExitError: true})
+	if err == nil {
+		_, err = repo.Run(&git.RunOpts{Args: commitArgs, ExitError: true})
+	}
+	if err == nil {
+		return nil
+	}
+	if _, rerr := repo.Run(&git.RunOpts{
+		Args:      []string{"checkout", "HEAD", "--", path},
+		ExitError: true,
+	}); rerr != nil {
+		logrus.WithError(rerr).Warnf("failed to restore %s", path)
+	}
+	_, _ = fmt.Fprint(os.Stderr,
+		"\n", colors.Failure("Failed to commit the suggestion."), "\n",
+	)
+	return actions.ErrExitSilently{ExitCode: 1}
+}
+
+var reviewCommentURLPattern = regexp.MustCompile(`#discussion_r(\d+)$`)
+
+// parseReviewCommentID parses the ID of a review comment from its URL (or the
+// ID itself).
+func parseReviewCommentID(arg string) (int64, error) {
+	if m := reviewCommentURLPattern.FindStringSubmatch(arg); m != nil {
+		arg = m[1]
+	}
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid review comment %q: expected its URL or ID", arg)
+	}
+	return id, nil
+}
+
+// pullRequestBranch returns the (tracked) branch of the pull request with the
+// given number.
+func pullRequestBranch(tx meta.ReadTx, number int64) (string, error) {
+	for _, branch := range tx.AllBranches() {
+		if branch.PullRequest != nil && branch.PullRequest.Number == number {
+			return branch.Name, nil
+		}
+	}
+	return "", errors.Errorf("pull request #%d doesn't belong to a branch that is tracked by av", number)
+}
+
+// suggestionOriginalLines returns the given (1-based, inclusive) lines of the
+// file at the commit that a suggestion was made on. The commit is fetched if
+// it's not available locally (e.g., if someone else pushed it).
+func suggestionOriginalLines(repo *git.Repo, commit, path string, start, end int) (string, error) {
+	if _, err := repo.Run(&git.RunOpts{
+		Args:      []string{"cat-file", "-e", commit + "^{commit}"},
+		ExitError: true,
+	}); err != nil {
+		if _, err := repo.Run(&git.RunOpts{
+			Args:      []string{"fetch", "origin", commit},
+			ExitError: true,
+		}); err != nil {
+			return "", errors.WrapIff(err, "failed to fetch commit %s", commit)
+		}
+	}
+	out, err := repo.Run(&git.RunOpts{
+		Args:      []string{"show", commit + ":" + path},
+		ExitError: true,
+	})
+	if err != nil {
+		return "", errors.WrapIff(err, "failed to read %s at %s", path, commit)
+	}
+	lines := strings.SplitAfter(strings.TrimSuffix(string(out.Stdout), "\n"), "\n")
+	if start < 1 || end > len(lines) || start > end {
+		return "", errors.Errorf("lines %d-%d don't exist in %s at %s", start, end, path, commit)
+	}
+	return strings.Join(lines[start-1:end], ""), nil
+}
+
+func init() {
+	prApplySuggestionCmd.Flags().BoolVar(
+		&prApplySuggestionFlags.Fixup, "fixup", false,
+		"create a fixup commit for the commit that last changed the suggested lines",
+	)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/editor"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/shurcooL/githubv4"
+	"github.com/spf13/cobra"
+)
+
+var prBodyFlags struct {
+	// If true, edit the description in the editor.
+	Edit bool
+}
+
+var prBodyCmd = &cobra.Command{
+	Use:   "body [<branch>] [--edit]",
+	Short: "show or edit the description of a pull request",
+	Long: `Show or edit the description of the pull request of a branch (the current branch
+by default).
+
+Only the part of the description that was written by people is shown. The
+sections that av manages (the stack, the description of the stack, and the av
+metadata) are left out. With --edit, the description is opened in your editor
+and the pull request is updated with the edited description once the editor is
+closed. The sections that av manages are kept as they are. If GitHub can't be
+reached once the editor is closed, the update is queued until av push --pending
+is run.`,
+	SilenceUsage: true,
+	Args:         cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.ReadTx()
+
+		var branchName string
+		if len(args) > 0 {
+			branchName = args[0]
+		} else if branchName, err = getCurrentBranchName(repo, db); err != nil {
+			return err
+		}
+		branch, ok := tx.Branch(branchName)
+		if !ok {
+			return errors.Errorf("branch %q is not tracked by av", branchName)
+		}
+		if branch.PullRequest == nil || branch.PullRequest.ID == "" {
+			return errors.Errorf("branch %q doesn't have a pull request", branchName)
+		}
+
+		client, err := getGitHubClient()
+		if err != nil {
+			return err
+		}
+		ctx := context.Background()
+		pr, err := client.PullRequest(ctx, branch.PullRequest.ID)
+		if err != nil {
+			return err
+		}
+		content := actions.PRBodyContent(pr.Body)
+		if !prBodyFlags.Edit {
+			fmt.Println(content)
+			return nil
+		}
+
+		if branch.ReadOnly {
+			return errors.Errorf("branch %q is read-only since it belongs to someone else", branchName)
+		}
+		if err := ensureGitHubWriteAccess(ctx, client, tx); err != nil {
+			return err
+		}
+		text := content + "\n\n" +
+			"%% Edit the description of pull request #" + fmt.Sprint(pr.Number) + " (" + branchName + ").\n" +
+			"%% The stack and the av metadata are kept as they are.\n" +
+			"%% Lines starting with '%%' are ignored.\n"
+		edited, err := editor.Launch(repo, editor.Config{
+			Text:           text,
+			TmpFilePattern: "pr-body-*.av.md",
+			CommentPrefix:  "%%",
+		})
+		if err != nil {
+			return errors.WrapIf(err, "text editor failed")
+		}
+		if actions.PRBodyEqual(content, edited) {
+			_, _ = fmt.Fprint(os.Stderr, "The description of pull request ", colors.UserInput("#", pr.Number),
+				" wasn't changed.\n",
+			)
+			return nil
+		}
+
+		// The managed sections might have been updated while the editor was
+		// open (e.g., by av stack sync), so keep their latest version.
+		number := pr.Number
+		pr, err = client.PullRequest(ctx, pr.ID)
+		if err == nil {
+			_, err = client.UpdatePullRequest(ctx, githubv4.UpdatePullRequestInput{
+				PullRequestID: githubv4.ID(pr.ID),
+				Body:          gh.Ptr(githubv4.String(actions.ReplacePRBodyContent(pr.Body, edited))),
+			})
+		}
+		if actions.IsOffline(err) {
+			if err := actions.QueuePullRequestBody(repo, branchName, edited); err != nil {
+				return err
+			}
+			printQueuedOffline(fmt.Sprint("the description of pull request #", number))
+			return nil
+		} else if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprint(os.Stderr,
+			"Updated the description of pull request ", colors.UserInput("#", pr.Number), ": ", pr.Permalink, "\n",
+		)
+		return nil
+	},
+}
+
+func init() {
+	prBodyCmd.Flags().BoolVar(
+		&prBodyFlags.Edit, "edit", false,
+		"edit the description in your editor",
+	)
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+var prChecksFlags struct {
+	// If true, block until all checks have completed.
+	Wait bool
+	// If true, keep showing the (refreshed) checks of all pull requests in the
+	// current stack until all checks have completed.
+	Watch bool
+	// If true, show the checks of all pull requests in the current stack.
+	All bool
+	// If true, re-run the failed GitHub Actions jobs of the pull request(s).
+	RerunFailed bool
+	// How long to wait for the checks to complete (with Wait).
+	Timeout time.Duration
+	// How often to poll GitHub for the state of the checks (with Wait).
+	Interval time.Duration
+}
+
+var prChecksCmd = &cobra.Command{
+	Use:   "checks [flags]",
+	Short: "show (or wait for) the CI checks of the pull request",
+	Long: `Show the CI checks of the pull request for the current branch.
+
+With --wait, block until all checks have completed (or until one of them fails).
+With --watch, show a continuously refreshing view of the checks of every pull
+request in the stack until all checks have completed. With --rerun-failed,
+re-run the failed GitHub Actions jobs of the pull request (or, with --all, of
+every pull request in the stack).
+
+The command exits with 1 if any check failed and with 2 if some checks are still
+pending (i.e., without --wait or after the timeout).`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.ReadTx()
+		currentBranchName, err := getCurrentBranchName(repo, db)
+		if err != nil {
+			return err
+		}
+
+		branchNames := []string{currentBranchName}
+		if prChecksFlags.All || prChecksFlags.Watch {
+			branchNames, err = meta.StackBranches(tx, currentBranchName)
+			if err != nil {
+				return err
+			}
+		}
+		var branches []meta.Branch
+		for _, name := range branchNames {
+			branch, _ := tx.Branch(name)
+			if branch.PullRequest != nil && branch.PullRequest.ID != "" {
+				branches = append(branches, branch)
+			}
+		}
+		if len(branches) == 0 {
+			return errors.New(
+				"this branch has no associated pull request (run `av pr create` to create one)",
+			)
+		}
+
+		client, err := getGitHubClient()
+		if err != nil {
+			return err
+		}
+
+		if prChecksFlags.RerunFailed {
+			repository, ok := tx.Repository()
+			if !ok {
+				return actions.ErrRepoNotInitialized
+			}
+			return rerunFailedChecks(client, repository, branches)
+		}
+
+		deadline := time.Now().Add(prChecksFlags.Timeout)
+		if prChecksFlags.Watch {
+			return watchPullRequestChecks(client, branches, deadline)
+		}
+		lastPending := -1
+		for {
+			checks, err := queryPullRequestChecks(client, branches)
+			if err != nil {
+				return err
+			}
+			state := prChecksState(checks)
+			if !prChecksFlags.Wait || state != gh.CheckStatePending {
+				renderPullRequestChecks(os.Stderr, branches, checks, time.Time{})
+				return prChecksExitError(state)
+			}
+			if time.Now().Add(prChecksFlags.Interval).After(deadline) {
+				renderPullRequestChecks(os.Stderr, branches, checks, time.Time{})
+				printPullRequestChecksTimeout()
+				return prChecksExitError(state)
+			}
+
+			pending := 0
+			for _, c := range checks {
+				for _, check := range c.Checks {
+					if check.State == gh.CheckStatePending {
+						pending++
+					}
+				}
+			}
+			if pending != lastPending {
+				_, _ = fmt.Fprint(
+					os.Stderr,
+					colors.Faint("Waiting for ", pending, " pending check(s)...\n"),
+				)
+				lastPending = pending
+			}
+			time.Sleep(prChecksFlags.Interval)
+		}
+	},
+}
+
+func init() {
+	prChecksCmd.Flags().BoolVar(
+		&prChecksFlags.Wait, "wait", false,
+		"block until all checks have completed (or one of them failed)",
+	)
+	prChecksCmd.Flags().BoolVar(
+		&prChecksFlags.Watch, "watch", false,
+		"show a continuously refreshing view of the checks of the stack",
+	)
+	prChecksCmd.MarkFlagsMutuallyExclusive("wait", "watch")
+	prChecksCmd.Flags().BoolVar(
+		&prChecksFlags.All, "all", false,
+		"show the checks of all pull requests in the current stack",
+	)
+	prChecksCmd.Flags().BoolVar(
+		&prChecksFlags.RerunFailed, "rerun-failed", false,
+		"re-run the failed GitHub Actions jobs (of all pull requests in the stack with --all)",
+	)
+	prChecksCmd.MarkFlagsMutuallyExclusive("rerun-failed", "wait")
+	prChecksCmd.MarkFlagsMutuallyExclusive("rerun-failed", "watch")
+	prChecksCmd.Flags().DurationVar(
+		&prChecksFlags.Timeout, "timeout", time.Hour,
+		"how long to wait for the checks to complete (with --wait or --watch)",
+	)
+	prChecksCmd.Flags().DurationVar(
+		&prChecksFlags.Interval, "interval", 15*time.Second,
+		"how often to poll GitHub for the state of the checks (with --wait or --watch)",
+	)
+}
+
+func queryPullRequestChecks(client *gh.Client, branches []meta.Branch) ([]*gh.PullRequestChecks, error) {
+	var res []*gh.PullRequestChecks
+	for _, branch := range branches {
+		checks, err := client.PullRequestChecks(context.Background(), branch.PullRequest.ID)
+		if err != nil {
+			return nil, errors.WrapIff(err, "failed to get the checks of pull request #%d", branch.PullRequest.Number)
+		}
+		res = append(res, checks)
+	}
+	return res, nil
+}
+
+// prChecksState returns the combined state of the checks of all pull requests.
+func prChecksState(checks []*gh.PullRequestChecks) gh.CheckState {
+	state := gh.CheckStateSuccess
+	for _, c := range checks {
+		switch c.State() {
+		case gh.CheckStateFailure:
+			return gh.CheckStateFailure
+		case gh.CheckStatePending:
+			state = gh.CheckStatePending
+		}
+	}
+	return state
+}
+
+func prChecksExitError(state gh.CheckState) error {
+	switch state {
+	case gh.CheckStateFailure:
+		return actions.ErrExitSilently{ExitCode: 1}
+	case gh.CheckStatePending:
+		return actions.ErrExitSilently{ExitCode: 2}
+	}
+	return nil
+}
+
+// watchPullRequestChecks keeps showing the checks of the given branches until
+// all checks have completed. On a terminal, the view is redrawn on every
+// refresh. Otherwise, the view is printed whenever the state of a check
+// changes.
+func watchPullRequestChecks(client *gh.Client, branches []meta.Branch, deadline time.Time) error {
+	tty := isatty.IsTerminal(os.Stderr.Fd())
+	var lastSignature string
+	for {
+		checks, err := queryPullRequestChecks(client, branches)
+		if err != nil {
+			return err
+		}
+		state := prChecksState(checks)
+		now := time.Now()
+		if tty {
+			// Move the cursor to the top-left corner and clear the screen.
+			_, _ = fmt.Fprint(os.Stderr, "\x1b[H\x1b[2J")
+			renderPullRequestChecks(os.Stderr, branches, checks, now)
+			if state == gh.CheckStatePending {
+				_, _ = fmt.Fprint(os.Stderr, "\n", colors.Faint(
+					"Refreshing every ", prChecksFlags.Interval,
+					" (last updated at ", now.Format(time.TimeOnly), "). Press Ctrl-C to stop.",
+				), "\n")
+			}
+		} else if signature := pullRequestChecksSignature(checks); signature != lastSignature {
+			renderPullRequestChecks(os.Stderr, branches, checks, now)
+			_, _ = fmt.Fprint(os.Stderr, "\n")
+			lastSignature = signature
+		}
+
+		if state != gh.CheckStatePending {
+			return prChecksExitError(state)
+		}
+		if now.Add(prChecksFlags.Interval).After(deadline) {
+			printPullRequestChecksTimeout()
+			return prChecksExitError(state)
+		}
+		time.Sleep(prChecksFlags.Interval)
+	}
+}
+
+// pullRequestChecksSignature returns a string that changes whenever a check is
+// added or changes its state.
+func pullRequestChecksSignature(checks []*gh.PullRequestChecks) string {
+	var sb strings.Builder
+	for _, c := range checks {
+		for _, check := range c.Checks {
+			_, _ = fmt.Fprintf(&sb, "%s:%s:%s\n", c.HeadOID, check.Name, check.State)
+		}
+	}
+	return sb.String()
+}
+
+func printPullRequestChecksTimeout() {
+	_, _ = fmt.Fprint(
+		os.Stderr,
+		colors.Failure("Timed out after ", prChecksFlags.Timeout, " waiting for the checks to complete.\n"),
+	)
+}
+
+// renderPullRequestChecks writes the checks of the given branches. If now is
+// non-zero, the duration and the URL of every check is included.
+func renderPullRequestChecks(w io.Writer, branches []meta.Branch, checks []*gh.PullRequestChecks, now time.Time) {
+	indent := "    "
+	for i, branch := range branches {
+		_, _ = fmt.Fprint(
+			w,
+			emojiForRequiredCheckResult(string(checks[i].State())), " ",
+			"#", branch.PullRequest.Number, " ",
+			colors.UserInput(branch.Name), "\n",
+		)
+		if len(checks[i].Checks) == 0 {
+			_, _ = fmt.Fprint(w, indent, colors.Faint("No checks"), "\n")
+		}
+		for _, check := range checks[i].Checks {
+			_, _ = fmt.Fprint(
+				w,
+				indent, emojiForRequiredCheckResult(string(check.State)), " ", check.Name,
+			)
+			if !now.IsZero() {
+				if d := check.Duration(now); d > 0 {
+					_, _ = fmt.Fprint(w, " ", colors.Faint("(", d.Round(time.Second), ")"))
+				}
+			}
+			if check.URL != "" && (!now.IsZero() || check.State == gh.CheckStateFailure) {
+				_, _ = fmt.Fprint(w, " ", colors.Faint(check.URL))
+			}
+			_, _ = fmt.Fprint(w, "\n")
+		}
+	}
+}
+
+// rerunFailedChecks re-runs the failed GitHub Actions jobs of the pull requests
+// of the given branches. Failed checks that aren't GitHub Actions jobs (e.g.,
+// commit statuses of external CI systems) can't be re-run through the API and
+// are only reported.
+func rerunFailedChecks(client *gh.Client, repository meta.Repository, branches []meta.Branch) error {
+	checks, err := queryPullRequestChecks(client, branches)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	rerun, failures := 0, 0
+	for i, branch := range branches {
+		var names []string
+		var runIDs []int64
+		var other []gh.Check
+		for _, check := range checks[i].Checks {
+			if check.State != gh.CheckStateFailure {
+				continue
+			}
+			if check.WorkflowRunID == 0 {
+				other = append(other, check)
+				continue
+			}
+			names = append(names, check.Name)
+			if !slices.Contains(runIDs, check.WorkflowRunID) {
+				runIDs = append(runIDs, check.WorkflowRunID)
+			}
+		}
+		if len(names) == 0 && len(other) == 0 {
+			continue
+		}
+
+		_, _ = fmt.Fprint(os.Stderr,
+			"#", branch.PullRequest.Number, " ", colors.UserInput(branch.Name), "\n",
+		)
+		failed := false
+		for _, runID := range runIDs {
+			if err := client.RerunFailedWorkflowJobs(ctx, repository.Owner, repository.Name, runID); err != nil {
+				_, _ = fmt.Fprint(os.Stderr, "    ", colors.Failure(err.Error()), "\n")
+				failed = true
+			}
+		}
+		if len(names) > 0 && !failed {
+			rerun += len(names)
+			for _, name := range names {
+				_, _ = fmt.Fprint(os.Stderr, "    re-running ", name, "\n")
+			}
+		} else if failed {
+			failures++
+		}
+		for _, check := range other {
+			_, _ = fmt.Fprint(os.Stderr, "    ",
+				colors.Faint("can't re-run ", check.Name, " (not a GitHub Actions job)"), "\n",
+			)
+			if check.URL != "" {
+				_, _ = fmt.Fprint(os.Stderr, "      ", colors.Faint(check.URL), "\n")
+			}
+		}
+	}
+
+	switch {
+	case failures > 0:
+		_, _ = fmt.Fprint(os.Stderr, colors.Failure(
+			"Failed to re-run some checks (GitHub only re-runs a workflow once all of its jobs have completed).\n",
+		))
+		return actions.ErrExitSilently{ExitCode: 1}
+	case rerun == 0:
+		_, _ = fmt.Fprint(os.Stderr, "No failed GitHub Actions jobs to re-run.\n")
+	default:
+		_, _ = fmt.Fprint(os.Stderr,
+			colors.Success("Re-running ", rerun, " failed check(s)."), " Run ",
+			colors.CliCmd("av pr checks --wait"), " to wait for the results.\n",
+		)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/shurcooL/githubv4"
+	"github.com/spf13/cobra"
+)
+
+var prCommentsCmd = &cobra.Command{
+	Use:   "comments",
+	Short: "show the unresolved review comments of the pull requests in the stack",
+	Long: `Show the unresolved review comments of every pull request in the current stack.
+
+The review threads are grouped by branch (starting with the root of the stack)
+and by file, along with a link to each thread, so that the feedback can be
+worked through from the bottom of the stack up.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.ReadTx()
+		currentBranchName, err := getCurrentBranchName(repo, db)
+		if err != nil {
+			return err
+		}
+		branchNames, err := meta.StackBranches(tx, currentBranchName)
+		if err != nil {
+			return err
+		}
+		client, err := getGitHubClient()
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		total := 0
+		for _, name := range branchNames {
+			branch, _ := tx.Branch(name)
+			pr := branch.PullRequest
+			if pr == nil || pr.ID == "" || pr.State == githubv4.PullRequestStateMerged {
+				continue
+			}
+			threads, err := client.UnresolvedReviewThreads(ctx, pr.ID)
+			if err != nil {
+				return err
+			}
+			total += len(threads)
+			printReviewThreads(name, pr.Number, threads)
+		}
+		if total == 0 {
+			_, _ = fmt.Fprint(os.Stderr, "No unresolved review comments in the stack.\n")
+		}
+		return nil
+	},
+}
+
+// printReviewThreads prints the unresolved review threads of the pull request
+// of a branch, grouped by file.
+func printReviewThreads(branchName string, number int64, threads []gh.ReviewThread) {
+	_, _ = fmt.Fprint(os.Stdout, colors.UserInput(branchName), " ", colors.UserInput("#", number))
+	if len(threads) == 0 {
+		_, _ = fmt.Fprint(os.Stdout, colors.Faint(": no unresolved comments"), "\n\n")
+		return
+	}
+	_, _ = fmt.Fprintf(os.Stdout, " (%d unresolved)\n", len(threads))
+
+	sort.SliceStable(threads, func(i, j int) bool {
+		if threads[i].Path != threads[j].Path {
+			return threads[i].Path < threads[j].Path
+		}
+		return threads[i].Line < threads[j].Line
+	})
+	var path string
+	for i, thread := range threads {
+		if i == 0 || thread.Path != path {
+			path = thread.Path
+			_, _ = fmt.Fprint(os.Stdout, "  ", path, "\n")
+		}
+		_, _ = fmt.Fprint(os.Stdout, "    ")
+		if thread.Line != 0 {
+			_, _ = fmt.Fprint(os.Stdout, "L", thread.Line, " ")
+		}
+		if thread.IsOutdated {
+			_, _ = fmt.Fprint(os.Stdout, colors.Faint("(outdated) "))
+		}
+		if len(thread.Comments) > 0 {
+			_, _ = fmt.Fprint(os.Stdout, colors.Faint(thread.Comments[0].URL))
+		}
+		_, _ = fmt.Fprint(os.Stdout, "\n")
+		for _, comment := range thread.Comments {
+			_, _ = fmt.Fprint(os.Stdout,
+				"      ", colors.UserInput(comment.Author), ": ", commentSummary(comment.Body), "\n",
+			)
+		}
+	}
+	_, _ = fmt.Fprint(os.Stdout, "\n")
+}
+
+// commentSummary returns the first line of a comment (shortened if it's long).
+func commentSummary(body string) string {
+	body = strings.TrimSpace(body)
+	line, rest, _ := strings.Cut(body, "\n")
+	line = strings.TrimSpace(line)
+	if runes := []rune(line); len(runes) > 100 {
+		return string(runes[:100]) + "…"
+	}
+	if strings.TrimSpace(rest) != "" {
+		return line + " …"
+	}
+	return line
+}
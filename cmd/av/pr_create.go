@@ -2,23 +2,31 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os"
 
 	"emperror.dev/errors"
 	"github.com/aviator-co/av/internal/actions"
 	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
 	"github.com/spf13/cobra"
 )
 
 var prCreateFlags struct {
-	Draft     bool
-	Force     bool
-	NoPush    bool
-	Title     string
-	Body      string
-	Edit      bool
-	Reviewers []string
+	prSetupFlags
+
+	Draft    bool
+	Force    bool
+	NoPush   bool
+	Title    string
+	Body     string
+	Edit     bool
+	Stack    bool
+	Template string
 }
 
 var prCreateCmd = &cobra.Command{
@@ -37,7 +45,17 @@ Examples:
     > EOF
 
   Create a pull request, assigning reviewers:
-    $ av pr create --reviewers "example,@example-org/example-team"
+    $ av pr create --reviewer example --reviewer @example-org/example-team
+
+  Create a pull request with a label, assigned to yourself:
+    $ av pr create --label bug --assignee @me
+
+  Create pull requests for all of the branches of the current stack:
+    $ av pr create --stack
+
+  Create a PR whose body starts with the .github/PULL_REQUEST_TEMPLATE/bugfix.md
+  template:
+    $ av pr create --template bugfix
 `,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) (reterr error) {
@@ -45,6 +63,19 @@ Examples:
 		if err != nil {
 			return err
 		}
+
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+
+		if prCreateFlags.Stack && (prCreateFlags.Title != "" || prCreateFlags.Body != "" ||
+			prCreateFlags.Edit || prCreateFlags.Force) {
+			return errors.New("cannot use --title, --body, --edit, or --force with --stack")
+		}
+		if prCreateFlags.Template != "" && prCreateFlags.Body != "" {
+			return errors.New("cannot use --template with --body")
+		}
+
 		branchName, err := repo.CurrentBranchName()
 		if err != nil {
 			return errors.WrapIf(err, "failed to determine current branch")
@@ -77,6 +108,12 @@ Examples:
 		}
 
 		ctx := context.Background()
+		if err := ensureGitHubWriteAccess(ctx, client, tx); err != nil {
+			return err
+		}
+		if prCreateFlags.Stack {
+			return prCreateStack(ctx, repo, client, tx, branchName, draft, prSetup(cmd, prCreateFlags.prSetupFlags, true))
+		}
 		res, err := actions.CreatePullRequest(
 			ctx, repo, client, tx,
 			actions.CreatePullRequestOpts{
@@ -87,6 +124,7 @@ Examples:
 				Force:      prCreateFlags.Force,
 				Draft:      draft,
 				Edit:       prCreateFlags.Edit,
+				Template:   prCreateFlags.Template,
 			},
 		)
 		if err != nil {
@@ -97,27 +135,82 @@ Examples:
 		}
 
 		// Do this after creating the PR and committing the transaction so that
-		// our local database is up-to-date even if this fails.
-		if len(prCreateFlags.Reviewers) > 0 {
-			if err := actions.AddPullRequestReviewers(ctx, client, res.Pull.ID, prCreateFlags.Reviewers); err != nil {
+		// our local database is up-to-date even if this fails. The defaults
+		// from the config only apply to new pull requests.
+		if setup := prSetup(cmd, prCreateFlags.prSetupFlags, res.Created); !setup.IsEmpty() {
+			repository, _ := tx.Repository()
+			if err := actions.SetUpPullRequest(ctx, client, repository, res.Pull, setup); err != nil {
 				return err
 			}
 		}
 
-		if config.Av.PullRequest.WriteStack != "" {
-			actions.UpdatePullRequestsWithStackForStack(ctx, client, repo, tx, branchName, config.Av.PullRequest.WriteStack)
+		// The new pull request changes the stack of the other pull requests of
+		// the stack, so refresh it (if it was written to them).
+		if err := actions.RefreshPullRequestStacks(ctx, client, repo, tx, []string{branchName}, nil); err != nil {
+			return err
 		}
 
 		return nil
 	},
 }
 
+// prCreateStack creates (or updates) the pull requests of all of the branches
+// of the stack of the given branch, starting from the root, and writes the
+// stack to the pull requests so that reviewers can navigate between them.
+func prCreateStack(
+	ctx context.Context,
+	repo *git.Repo,
+	client *gh.Client,
+	tx meta.WriteTx,
+	branchName string,
+	draft bool,
+	setup actions.PullRequestSetup,
+) error {
+	branches, err := meta.StackBranches(tx, branchName)
+	if err != nil {
+		return err
+	}
+	created, err := submitBranches(ctx, repo, client, tx, branches, submitOpts{
+		Draft:    draft,
+		NoPush:   prCreateFlags.NoPush,
+		Template: prCreateFlags.Template,
+	})
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := setUpCreatedPullRequests(ctx, client, tx, created, setup); err != nil {
+		return err
+	}
+
+	// The stack is always written since it's the point of creating the pull
+	// requests of the whole stack at once.
+	writeStack := config.Av.PullRequest.WriteStack
+	if writeStack == "" {
+		writeStack = config.WriteStackBottom
+	}
+	if err := actions.UpdatePullRequestsWithStack(ctx, client, repo, tx, branches, writeStack); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprint(os.Stderr,
+		"\n", colors.Success(fmt.Sprintf("Created %d pull request(s) for the stack.", len(created))), "\n",
+	)
+	if len(created) > 0 && config.Av.PullRequest.OpenBrowser {
+		actions.OpenPullRequestInBrowser(created[0].Branch.PullRequest.Permalink)
+	}
+	return nil
+}
+
 func init() {
 
 	// av pr create
 	prCreateCmd.Flags().BoolVar(
 		&prCreateFlags.Draft, "draft", false,
-		"create the pull request in draft mode",
+		"create the pull request in draft mode (defaults to the pullRequest.draft config)",
 	)
 	prCreateCmd.Flags().BoolVar(
 		&prCreateFlags.Force, "force", false,
@@ -135,12 +228,17 @@ func init() {
 		&prCreateFlags.Body, "body", "b", "",
 		"body of the pull request to create (a value of - will read from stdin)",
 	)
+	prCreateCmd.Flags().StringVar(
+		&prCreateFlags.Template, "template", "",
+		"start the body of the pull request with the given template from the\nPULL_REQUEST_TEMPLATE directory (by file name)",
+	)
 	prCreateCmd.Flags().BoolVar(
 		&prCreateFlags.Edit, "edit", false,
 		"always open an editor to edit the pull request title and description",
 	)
-	prCreateCmd.Flags().StringSliceVar(
-		&prCreateFlags.Reviewers, "reviewers", nil,
-		"add reviewers to the pull request (can be usernames or team names)",
+	addPRSetupFlags(prCreateCmd, &prCreateFlags.prSetupFlags)
+	prCreateCmd.Flags().BoolVar(
+		&prCreateFlags.Stack, "stack", false,
+		"create (or update) the pull requests of all of the branches of the current stack",
 	)
 }
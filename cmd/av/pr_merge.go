@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/shurcooL/githubv4"
+	"github.com/spf13/cobra"
+)
+
+// The ways that av pr merge can merge a pull request.
+const (
+	// Merge the pull request right away.
+	prMergeViaMerge = "merge"
+	// Enable GitHub's auto-merge for the pull request.
+	prMergeViaAutoMerge = "auto-merge"
+	// Add the pull request to the merge queue (see newPullRequestQueue).
+	prMergeViaQueue = "queue"
+)
+
+var prMergeFlags struct {
+	// How to merge the pull request (see prMergeVia*). By default, pull
+	// requests are queued if a merge queue is configured and merged right away
+	// otherwise.
+	Via string
+	// The merge method to use ("merge", "squash", or "rebase").
+	Method string
+}
+
+var prMergeCmd = &cobra.Command{
+	Use:   "merge [flags]",
+	Short: "merge or queue the bottom-most pull request of the stack",
+	Long: `Merge the bottom-most pull request of the current stack that hasn't been merged
+yet, then sync the rest of the stack onto the trunk (which also retargets the
+pull requests).
+
+How the pull request is merged depends on --via:
+
+  merge       Merge the pull request right away (if it's mergeable). The
+              following pull requests of the stack are merged as well, one after
+              the other, as long as they're mergeable once they target the trunk.
+  auto-merge  Enable GitHub's auto-merge for the pull request so that GitHub
+              merges it once all of its requirements are met.
+  queue       Add the pull request to the merge queue: GitHub's merge queue if
+              github.mergeQueue is set in the config and Aviator's MergeQueue
+              otherwise.
+
+By default, pull requests are queued if github.mergeQueue or an Aviator API token
+is configured and merged right away otherwise. Since a queued (or auto-merged)
+pull request isn't merged right away, run av pr merge again once it's merged to
+sync the rest of the stack and continue with the next pull request (or use
+av pr queue --all to queue the whole stack).`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		via := prMergeFlags.Via
+		if via == "" {
+			via = prMergeViaMerge
+			if config.Av.GitHub.MergeQueue || config.Av.Aviator.APIToken != "" {
+				via = prMergeViaQueue
+			}
+		}
+		if via != prMergeViaMerge && via != prMergeViaAutoMerge && via != prMergeViaQueue {
+			return errors.Errorf("invalid value %q for --via (expected merge, auto-merge, or queue)", via)
+		}
+		method, err := parseMergeMethod(prMergeFlags.Method)
+		if err != nil {
+			return err
+		}
+
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		currentBranchName, err := getCurrentBranchName(repo, db)
+		if err != nil {
+			return err
+		}
+		client, err := getGitHubClient()
+		if err != nil {
+			return err
+		}
+		var queue pullRequestQueue
+		if via == prMergeViaQueue {
+			queue, err = newPullRequestQueue(db.ReadTx())
+			if err != nil {
+				return err
+			}
+		}
+
+		ctx := context.Background()
+		var merged int
+		for {
+			// The sync modifies the metadata, so it has to be re-read every
+			// time.
+			db, err := getDB(repo)
+			if err != nil {
+				return err
+			}
+			branch, ok, err := nextBranchToLand(db.ReadTx(), currentBranchName, landScope{})
+			if err != nil {
+				return err
+			}
+			if !ok {
+				_, _ = fmt.Fprint(os.Stderr, colors.Success("All pull requests in the stack have been merged.\n"))
+				return nil
+			}
+			if branch.PullRequest == nil || branch.PullRequest.ID == "" {
+				return errors.Errorf(
+					"branch %q has no associated pull request (run `av pr create` to create one)",
+					branch.Name,
+				)
+			}
+
+			pr, err := client.PullRequest(ctx, branch.PullRequest.ID)
+			if err != nil {
+				return err
+			}
+			if pr.State == githubv4.PullRequestStateMerged || !branch.Parent.Trunk {
+				// Either the pull request was merged outside of av or its parent
+				// was merged but the stack hasn't been synced yet. Either way,
+				// the stack needs to catch up first.
+				if pr.State == githubv4.PullRequestStateMerged {
+					if err := recordLandedPullRequest(db, branch.Name, pr); err != nil {
+						return err
+					}
+				}
+				if err := syncLandedStack(repo, "av pr merge"); err != nil {
+					return err
+				}
+				continue
+			}
+
+			switch via {
+			case prMergeViaQueue:
+				return queueBottomPullRequest(ctx, queue, branch, pr)
+			case prMergeViaAutoMerge:
+				return enableAutoMerge(ctx, client, branch, pr, method)
+			}
+
+			checks, err := client.PullRequestChecks(ctx, pr.ID)
+			if err != nil {
+				return err
+			}
+			readiness := actions.CheckLandReadiness(branch, pr, checks)
+			unmet := checkBranchProtection(ctx, client, branch, pr, checks, method)
+			if readiness.Ready && len(unmet) > 0 {
+				readiness = actions.LandReadiness{
+					Reason: "the branch protection rules of " + pr.BaseBranchName() + " aren't met",
+				}
+			}
+			if !readiness.Ready {
+				_, _ = fmt.Fprint(
+					os.Stderr,
+					"Pull request #", pr.Number, " (", colors.UserInput(branch.Name), ") can't be merged yet: ",
+					readiness.Reason, "\n",
+				)
+				for _, req := range unmet {
+					_, _ = fmt.Fprint(os.Stderr, "  - ", req.Description, "\n")
+				}
+				if merged > 0 {
+					// The pull requests below were merged, which is what was
+					// asked for.
+					return nil
+				}
+				_, _ = fmt.Fprint(os.Stderr,
+					colors.Faint("  - use "), colors.CliCmd("av pr merge --via auto-merge"),
+					colors.Faint(" to merge it once it's mergeable\n"),
+				)
+				return actions.ErrExitSilently{ExitCode: 1}
+			}
+			mergedPR, err := mergeLandedPullRequest(ctx, client, branch, pr, method)
+			if err != nil {
+				return err
+			}
+			if err := recordLandedPullRequest(db, branch.Name, mergedPR); err != nil {
+				return err
+			}
+			merged++
+			if err := syncLandedStack(repo, "av pr merge"); err != nil {
+				return err
+			}
+		}
+	},
+}
+
+// ensurePullRequestTargetsTrunk makes sure that the pull request of the bottom
+// of the stack is open and targets the trunk before it's handed over to GitHub
+// or the merge queue (which would merge it into whatever it targets).
+func ensurePullRequestTargetsTrunk(branch meta.Branch, pr *gh.PullRequest) error {
+	switch {
+	case pr.State != githubv4.PullRequestStateOpen:
+		return errors.Errorf("pull request #%d (%s) is %s", pr.Number, branch.Name, pr.State)
+	case pr.IsDraft:
+		return errors.Errorf("pull request #%d (%s) is a draft (run `av pr ready` first)", pr.Number, branch.Name)
+	case pr.BaseBranchName() != branch.Parent.Name:
+		return errors.Errorf(
+			"pull request #%d (%s) doesn't target %s yet (run `av stack sync` to retarget it)",
+			pr.Number, branch.Name, branch.Parent.Name,
+		)
+	}
+	return nil
+}
+
+func queueBottomPullRequest(
+	ctx context.Context,
+	queue pullRequestQueue,
+	branch meta.Branch,
+	pr *gh.PullRequest,
+) error {
+	if err := ensurePullRequestTargetsTrunk(branch, pr); err != nil {
+		return err
+	}
+	state, err := queue.Status(ctx, branch.PullRequest)
+	if err != nil {
+		return err
+	}
+	if state.Status != "" {
+		_, _ = fmt.Fprint(os.Stderr,
+			"Pull request #", pr.Number, " (", colors.UserInput(branch.Name), ") is already queued (",
+			colors.UserInput(state.Status), ").\n",
+		)
+	} else {
+		if err := queue.Enqueue(ctx, branch.PullRequest); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprint(os.Stderr,
+			"Queued pull request #", pr.Number, " (", colors.UserInput(branch.Name), "): ", pr.Permalink, "\n",
+		)
+	}
+	printMergeResumeHint()
+	return nil
+}
+
+func enableAutoMerge(
+	ctx context.Context,
+	client *gh.Client,
+	branch meta.Branch,
+	pr *gh.PullRequest,
+	method githubv4.PullRequestMergeMethod,
+) error {
+	if err := ensurePullRequestTargetsTrunk(branch, pr); err != nil {
+		return err
+	}
+	if _, err := client.EnablePullRequestAutoMerge(ctx, pr.ID, method); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprint(os.Stderr,
+		"Enabled auto-merge for pull request #", pr.Number, " (", colors.UserInput(branch.Name), "): ",
+		pr.Permalink, "\n",
+	)
+	printMergeResumeHint()
+	return nil
+}
+
+func printMergeResumeHint() {
+	_, _ = fmt.Fprint(os.Stderr,
+		colors.Faint("  - once it's merged, run "), colors.CliCmd("av pr merge"),
+		colors.Faint(" again to sync the rest of the stack and continue with the next pull request\n"),
+	)
+}
+
+func init() {
+	prMergeCmd.Flags().StringVar(
+		&prMergeFlags.Via, "via", "",
+		"how to merge the pull request (merge, auto-merge, or queue)",
+	)
+	prMergeCmd.Flags().StringVar(
+		&prMergeFlags.Method, "method", "squash",
+		"the merge method to use (merge, squash, or rebase)",
+	)
+}
@@ -5,12 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/shurcooL/githubv4"
 	"github.com/sirupsen/logrus"
 
 	"github.com/aviator-co/av/internal/actions"
 	"github.com/aviator-co/av/internal/avgql"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
 	"github.com/shurcooL/graphql"
 	"github.com/spf13/cobra"
 )
@@ -18,11 +24,26 @@ import (
 var prQueueFlags struct {
 	SkipLine bool
 	Targets  string
+	// If true, queue every pull request of the stack (one after the other).
+	All bool
+	// How long to wait for the stack to be merged (with All).
+	Timeout time.Duration
+	// How often to check the state of the pull requests (with All).
+	Interval time.Duration
 }
 
 var prQueueCmd = &cobra.Command{
-	Use:          "queue",
-	Short:        "queue a pull request for the current branch",
+	Use:   "queue",
+	Short: "queue a pull request for the current branch",
+	Long: `Queue the pull request of the current branch with Aviator's MergeQueue (or with
+GitHub's merge queue if github.mergeQueue is set in the config).
+
+With --all, every pull request of the stack is queued in order, starting from
+the bottom of the stack. Since a pull request can only be merged into the trunk
+once its parent has been merged, each pull request is queued once the previous
+one has been merged and the rest of the stack has been synced onto the trunk
+(which retargets the next pull request). The command keeps running until the
+whole stack has been merged or a pull request gets blocked in the queue.`,
 	SilenceUsage: true,
 	Args:         cobra.NoArgs,
 	// error or reterr from emperror.dev/errors here?
@@ -37,11 +58,22 @@ var prQueueCmd = &cobra.Command{
 			return err
 		}
 
+		if prQueueFlags.SkipLine && !config.Av.GitHub.MergeQueue {
+			return errors.New("--skip-line is only supported with GitHub's merge queue (github.mergeQueue)")
+		}
+
 		tx := db.ReadTx()
-		currentBranchName, err := repo.CurrentBranchName()
+		currentBranchName, err := getCurrentBranchName(repo, db)
+		if err != nil {
+			return err
+		}
+		queue, err := newPullRequestQueue(tx)
 		if err != nil {
 			return err
 		}
+		if prQueueFlags.All {
+			return queueStack(repo, queue, currentBranchName)
+		}
 
 		branch, _ := tx.Branch(currentBranchName)
 		if branch.PullRequest == nil {
@@ -50,61 +82,233 @@ var prQueueCmd = &cobra.Command{
 			)
 		}
 
-		prNumber := branch.PullRequest.Number
-		repository, exists := tx.Repository()
-		if !exists {
-			return actions.ErrRepoNotInitialized
+		if err := queue.Enqueue(context.Background(), branch.PullRequest); err != nil {
+			logrus.WithError(err).Debug("failed to queue pull request")
+			return err
 		}
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			"Queued pull request ", colors.UserInput(branch.PullRequest.Permalink), ".\n",
+		)
 
-		var variables = map[string]interface{}{
-			"repoOwner": graphql.String(repository.Owner),
-			"repoName":  graphql.String(repository.Name),
-			"prNumber":  graphql.Int(prNumber),
+		return nil
+	},
+}
+
+// pullRequestQueue is a merge queue that pull requests can be added to.
+type pullRequestQueue interface {
+	// Status returns the state of the pull request in the queue (with an empty
+	// Status if the pull request isn't queued).
+	Status(ctx context.Context, pr *meta.PullRequest) (queueState, error)
+	// Enqueue adds the pull request to the queue.
+	Enqueue(ctx context.Context, pr *meta.PullRequest) error
+}
+
+type queueState struct {
+	Status string
+	// True if the pull request won't be merged without somebody intervening.
+	Blocked bool
+	// Why the pull request is in its current state (if known).
+	Reason string
+}
+
+// newPullRequestQueue returns the merge queue of the repository: GitHub's merge
+// queue if it's enabled in the config and Aviator's MergeQueue otherwise.
+func newPullRequestQueue(tx meta.ReadTx) (pullRequestQueue, error) {
+	if config.Av.GitHub.MergeQueue {
+		client, err := getGitHubClient()
+		if err != nil {
+			return nil, err
 		}
+		return githubQueue{client: client, jump: prQueueFlags.SkipLine}, nil
+	}
+	repository, ok := tx.Repository()
+	if !ok {
+		return nil, actions.ErrRepoNotInitialized
+	}
+	client, err := avgql.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	return aviatorQueue{client: client, repository: repository}, nil
+}
+
+// aviatorQueue is Aviator's MergeQueue.
+type aviatorQueue struct {
+	client     *graphql.Client
+	repository meta.Repository
+}
+
+func (q aviatorQueue) Status(ctx context.Context, pr *meta.PullRequest) (queueState, error) {
+	status, err := avgql.PullRequestQueueStatus(ctx, q.client, q.repository.Owner, q.repository.Name, pr.Number)
+	if err != nil {
+		return queueState{}, err
+	}
+	// MergeQueue might know about the merge before GitHub reports it.
+	if !status.IsQueued() && status.Status != "MERGED" {
+		return queueState{}, nil
+	}
+	return queueState{
+		Status:  status.Status,
+		Blocked: status.Status == "BLOCKED",
+		Reason:  status.StatusReason,
+	}, nil
+}
+
+func (q aviatorQueue) Enqueue(ctx context.Context, pr *meta.PullRequest) error {
+	return avgql.QueuePullRequest(ctx, q.client, q.repository.Owner, q.repository.Name, pr.Number)
+}
+
+// githubQueue is GitHub's (native) merge queue.
+type githubQueue struct {
+	client *gh.Client
+	// If true, pull requests are added to the front of the queue.
+	jump bool
+}
 
-		// I have a feeling this would be better written inside of av/internals
-		client, err := avgql.NewClient()
+func (q githubQueue) Status(ctx context.Context, pr *meta.PullRequest) (queueState, error) {
+	entry, err := q.client.PullRequestMergeQueueEntry(ctx, pr.ID)
+	if err != nil || entry == nil {
+		return queueState{}, err
+	}
+	return queueState{
+		Status:  fmt.Sprintf("%s, position %d", entry.State, entry.Position),
+		Blocked: entry.State == gh.MergeQueueStateUnmergeable,
+	}, nil
+}
+
+func (q githubQueue) Enqueue(ctx context.Context, pr *meta.PullRequest) error {
+	if pr.ID == "" {
+		return errors.New("the pull request of the branch is unknown (run `av fetch` to update it)")
+	}
+	_, err := q.client.EnqueuePullRequest(ctx, pr.ID, q.jump)
+	return err
+}
+
+// queueStack queues the pull requests of the stack one after the other (each
+// one once its parent has been merged) until the whole stack has been merged.
+func queueStack(repo *git.Repo, queue pullRequestQueue, currentBranchName string) error {
+	client, err := getGitHubClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	deadline := time.Now().Add(prQueueFlags.Timeout)
+	var lastStatus string
+	for {
+		// The sync modifies the metadata, so it has to be re-read every time.
+		db, err := getDB(repo)
 		if err != nil {
 			return err
 		}
-
-		var mutation struct {
-			QueuePullRequest struct {
-				QueuePullRequestPayload struct {
-					PullRequest struct {
-						// We don't currently use anything here, but we need to select
-						// at least one field to make the GraphQL query valid.
-						Status graphql.String
-					}
-				} `graphql:"... on QueuePullRequestPayload"`
-			} `graphql:"queuePullRequest(input: {repoOwner: $repoOwner, repoName:$repoName, number:$prNumber})"`
+		branch, ok, err := nextBranchToLand(db.ReadTx(), currentBranchName, landScope{})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			_, _ = fmt.Fprint(os.Stderr, colors.Success("All pull requests in the stack have been merged.\n"))
+			return nil
+		}
+		if branch.PullRequest == nil || branch.PullRequest.ID == "" {
+			return fmt.Errorf(
+				"branch %q has no associated pull request (run `av pr create` to create one)",
+				branch.Name,
+			)
 		}
 
-		err = client.Mutate(context.Background(), &mutation, variables)
+		pr, err := client.PullRequest(ctx, branch.PullRequest.ID)
 		if err != nil {
-			logrus.WithError(err).Debug("failed to queue pull request")
-			return fmt.Errorf("failed to queue pull request: %s", err)
+			return err
+		}
+		if pr.State == githubv4.PullRequestStateMerged {
+			_, _ = fmt.Fprint(os.Stderr,
+				"Pull request #", pr.Number, " (", colors.UserInput(branch.Name), ") was merged.\n",
+			)
+			if err := recordLandedPullRequest(db, branch.Name, pr); err != nil {
+				return err
+			}
+			if err := syncLandedStack(repo, "av pr queue --all"); err != nil {
+				return err
+			}
+			lastStatus = ""
+			continue
 		}
-		_, _ = fmt.Fprint(
-			os.Stderr,
-			"Queued pull request ", colors.UserInput(branch.PullRequest.Permalink), ".\n",
-		)
 
-		return nil
-	},
+		if branch.Parent.Trunk && pr.BaseBranchName() == branch.Parent.Name {
+			state, err := queue.Status(ctx, branch.PullRequest)
+			if err != nil {
+				return err
+			}
+			if state.Blocked {
+				_, _ = fmt.Fprint(os.Stderr,
+					colors.Failure("Pull request #", pr.Number, " (", branch.Name, ") is blocked in the queue"),
+				)
+				if state.Reason != "" {
+					_, _ = fmt.Fprint(os.Stderr, ": ", state.Reason)
+				}
+				_, _ = fmt.Fprint(os.Stderr, "\n",
+					colors.Faint("  - once the problem is fixed, run "), colors.CliCmd("av pr queue --all"),
+					colors.Faint(" again to continue queueing the stack\n"),
+				)
+				return actions.ErrExitSilently{ExitCode: 1}
+			}
+			if state.Status == "" {
+				if err := queue.Enqueue(ctx, branch.PullRequest); err != nil {
+					return err
+				}
+				_, _ = fmt.Fprint(os.Stderr,
+					"Queued pull request #", pr.Number, " (", colors.UserInput(branch.Name), ").\n",
+				)
+				state.Status = "QUEUED"
+			}
+			if state.Status != lastStatus {
+				_, _ = fmt.Fprint(os.Stderr,
+					"  - waiting for pull request #", pr.Number, " to be merged (",
+					colors.UserInput(state.Status), ")\n",
+				)
+				lastStatus = state.Status
+			}
+		} else if !branch.Parent.Trunk {
+			// The parent was merged but the stack hasn't been synced yet.
+			if err := syncLandedStack(repo, "av pr queue --all"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if time.Now().Add(prQueueFlags.Interval).After(deadline) {
+			_, _ = fmt.Fprint(
+				os.Stderr,
+				colors.Failure("Timed out after ", prQueueFlags.Timeout, " waiting for the stack to be merged.\n"),
+			)
+			return actions.ErrExitSilently{ExitCode: 1}
+		}
+		time.Sleep(prQueueFlags.Interval)
+	}
 }
 
 func init() {
 	prQueueCmd.Flags().BoolVar(
 		&prQueueFlags.SkipLine, "skip-line", false,
-		"skip in front of the existing pull requests, merge this pull request right now",
+		"add the pull request to the front of GitHub's merge queue (requires github.mergeQueue)",
 	)
 	prQueueCmd.Flags().StringVarP(
 		&prQueueFlags.Targets, "targets", "t", "",
 		"additional targets affected by this pull request",
 	)
-	// These flags are not yet supported.
+	prQueueCmd.Flags().BoolVar(
+		&prQueueFlags.All, "all", false,
+		"queue every pull request of the stack in order (each one once its parent was merged)",
+	)
+	prQueueCmd.Flags().DurationVar(
+		&prQueueFlags.Timeout, "timeout", 6*time.Hour,
+		"how long to wait for the stack to be merged (with --all)",
+	)
+	prQueueCmd.Flags().DurationVar(
+		&prQueueFlags.Interval, "interval", 30*time.Second,
+		"how often to check the state of the pull requests (with --all)",
+	)
+	// This flag is not yet supported.
 	_ = prQueueCmd.Flags().MarkHidden("targets")
-	_ = prQueueCmd.Flags().MarkHidden("skip-line")
-
 }
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/spf13/cobra"
+)
+
+var prReadyFlags struct {
+	// If true, mark the pull requests of all the branches in the current stack
+	// as ready for review.
+	Stack bool
+}
+
+var prReadyCmd = &cobra.Command{
+	Use:   "ready [<branch>] [--stack]",
+	Short: "mark draft pull requests as ready for review",
+	Long: `Mark the draft pull request of a branch (the current branch by default) as
+ready for review.
+
+With --stack, the draft pull requests of all the branches in the current stack
+are marked as ready for review. Work-in-progress branches (see "av stack wip")
+and read-only branches are skipped.`,
+	SilenceUsage: true,
+	Args:         cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if prReadyFlags.Stack && len(args) > 0 {
+			return errors.New("cannot specify a branch with --stack")
+		}
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.ReadTx()
+
+		var branchNames []string
+		if len(args) > 0 {
+			branchNames = args
+		} else {
+			currentBranch, err := getCurrentBranchName(repo, db)
+			if err != nil {
+				return err
+			}
+			branchNames = []string{currentBranch}
+			if prReadyFlags.Stack {
+				branchNames, err = meta.StackBranches(tx, currentBranch)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		client, err := getGitHubClient()
+		if err != nil {
+			return err
+		}
+		ctx := context.Background()
+		if err := ensureGitHubWriteAccess(ctx, client, tx); err != nil {
+			return err
+		}
+
+		marked := 0
+		for _, branchName := range branchNames {
+			branch, ok := tx.Branch(branchName)
+			if !ok {
+				return errors.Errorf("branch %q is not tracked by av", branchName)
+			}
+			if branch.PullRequest == nil || branch.PullRequest.ID == "" {
+				if !prReadyFlags.Stack {
+					return errors.Errorf("branch %q doesn't have a pull request", branchName)
+				}
+				continue
+			}
+			if prReadyFlags.Stack && (branch.WIP || branch.ReadOnly) {
+				reason := "work in progress"
+				if branch.ReadOnly {
+					reason = "read-only"
+				}
+				_, _ = fmt.Fprint(os.Stderr,
+					"  - skipping ", colors.UserInput(branchName), colors.Faint(" (", reason, ")"), "\n",
+				)
+				continue
+			}
+			pr, err := client.PullRequest(ctx, branch.PullRequest.ID)
+			if err != nil {
+				return err
+			}
+			if !pr.IsDraft {
+				_, _ = fmt.Fprint(os.Stderr,
+					"  - ", colors.UserInput(branchName), " ", colors.UserInput("#", pr.Number),
+					" is already ready for review\n",
+				)
+				continue
+			}
+			if _, err := client.MarkPullRequestReadyForReview(ctx, pr.ID); err != nil {
+				return err
+			}
+			marked++
+			_, _ = fmt.Fprint(os.Stderr,
+				"  - ", colors.UserInput(branchName), " ", colors.UserInput("#", pr.Number),
+				" is now ready for review: ", pr.Permalink, "\n",
+			)
+		}
+		if prReadyFlags.Stack {
+			_, _ = fmt.Fprint(os.Stderr,
+				colors.Success(fmt.Sprintf("Marked %d pull request(s) as ready for review.", marked)), "\n",
+			)
+		}
+		return nil
+	},
+}
+
+func init() {
+	prReadyCmd.Flags().BoolVar(
+		&prReadyFlags.Stack, "stack", false,
+		"mark the pull requests of all the branches in the current stack as ready for review",
+	)
+}
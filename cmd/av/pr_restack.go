@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/spf13/cobra"
+)
+
+var prRestackCmd = &cobra.Command{
+	Use:   "restack",
+	Short: "update the base branches of the pull requests of the current stack",
+	Long: `Update the base branch of each pull request of the current stack to the branch
+that it should be merged into.
+
+When the pull request of a branch is merged, the pull requests of its children
+are still based on the merged branch. This changes their base branch to the
+branch that the merged branch was merged into (usually the trunk), without
+syncing the stack. av stack sync does the same for the stacks that it syncs.
+
+If GitHub can't be reached, the update is queued until av push --pending is
+run.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.ReadTx()
+		currentBranch, err := getCurrentBranchName(repo, db)
+		if err != nil {
+			return err
+		}
+		branches, err := meta.StackBranches(tx, currentBranch)
+		if err != nil {
+			return err
+		}
+
+		client, err := getGitHubClient()
+		if err != nil {
+			return err
+		}
+		ctx := context.Background()
+		err = ensureGitHubWriteAccess(ctx, client, tx)
+		var results []actions.RetargetResult
+		if err == nil {
+			results, err = actions.RetargetPullRequests(ctx, client, tx, branches)
+			actions.PrintRetargetResults(results)
+		}
+		if actions.IsOffline(err) {
+			if err := actions.QueueRetarget(repo, branches...); err != nil {
+				return err
+			}
+			printQueuedOffline("the base branches of the pull requests")
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			_, _ = fmt.Fprint(os.Stderr,
+				colors.Success("The pull requests of the stack are already based on the right branches."), "\n",
+			)
+		}
+		return nil
+	},
+}
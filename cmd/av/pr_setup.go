@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// prSetupFlags are the flags that add reviewers, labels, and assignees to the
+// pull requests that a command creates (see actions.PullRequestSetup).
+type prSetupFlags struct {
+	Reviewers []string
+	Labels    []string
+	Assignees []string
+}
+
+// addPRSetupFlags registers the flags of prSetupFlags. Each flag can be given
+// multiple times (or with comma-separated values), and the plural forms (e.g.,
+// --reviewers) are accepted as well.
+func addPRSetupFlags(cmd *cobra.Command, flags *prSetupFlags) {
+	cmd.Flags().StringSliceVar(
+		&flags.Reviewers, "reviewer", nil,
+		"request reviews from the given users or teams (e.g., alice or @org/team);\ndefaults to the pullRequest.reviewers config",
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.Labels, "label", nil,
+		"add the given labels; defaults to the pullRequest.labels config",
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.Assignees, "assignee", nil,
+		"assign the given users (@me for yourself); defaults to the pullRequest.assignees config",
+	)
+	cmd.Flags().SetNormalizeFunc(func(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+		switch name {
+		case "reviewers", "labels", "assignees":
+			name = name[:len(name)-1]
+		}
+		return pflag.NormalizedName(name)
+	})
+}
+
+// prSetup returns what to add to the pull requests: the values that were given
+// with the flags and, if defaults is true, the pullRequest config for the
+// flags that weren't given.
+func prSetup(cmd *cobra.Command, flags prSetupFlags, defaults bool) actions.PullRequestSetup {
+	var setup actions.PullRequestSetup
+	if defaults {
+		setup = actions.PullRequestSetup{
+			Reviewers: config.Av.PullRequest.Reviewers,
+			Labels:    config.Av.PullRequest.Labels,
+			Assignees: config.Av.PullRequest.Assignees,
+		}
+	}
+	if cmd.Flags().Changed("reviewer") {
+		setup.Reviewers = flags.Reviewers
+	}
+	if cmd.Flags().Changed("label") {
+		setup.Labels = flags.Labels
+	}
+	if cmd.Flags().Changed("assignee") {
+		setup.Assignees = flags.Assignees
+	}
+	return setup
+}
@@ -5,9 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/aviator-co/av/internal/actions"
 	"github.com/aviator-co/av/internal/avgql"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/meta"
 	"github.com/aviator-co/av/internal/utils/colors"
 	"github.com/aviator-co/av/internal/utils/timeutils"
 	"github.com/shurcooL/githubv4"
@@ -15,12 +19,24 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var prStatusFlags struct {
+	// If true, show the state, reviews, and checks of all pull requests in the
+	// current stack.
+	Stack bool
+}
+
 var prStatusCmd = &cobra.Command{
 	Use:          "status",
 	Short:        "check pr status",
 	SilenceUsage: true,
 	Args:         cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if prStatusFlags.Stack {
+			return showStackPullRequestStatus()
+		}
+		if config.Av.GitHub.MergeQueue {
+			return showGitHubMergeQueueStatus()
+		}
 		variables, err := getQueryVariables()
 		if err != nil {
 			return err
@@ -198,6 +214,201 @@ var prStatusCmd = &cobra.Command{
 	},
 }
 
+// showGitHubMergeQueueStatus shows the status of the pull request of the current
+// branch in GitHub's (native) merge queue.
+func showGitHubMergeQueueStatus() error {
+	repo, err := getRepo()
+	if err != nil {
+		return err
+	}
+	db, err := getDB(repo)
+	if err != nil {
+		return err
+	}
+	currentBranchName, err := getCurrentBranchName(repo, db)
+	if err != nil {
+		return err
+	}
+	branch, _ := db.ReadTx().Branch(currentBranchName)
+	if branch.PullRequest == nil || branch.PullRequest.ID == "" {
+		return errors.New(
+			"this branch has no associated pull request (run `av pr create` to create one)",
+		)
+	}
+	client, err := getGitHubClient()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	pr, err := client.PullRequest(ctx, branch.PullRequest.ID)
+	if err != nil {
+		return err
+	}
+	var entry *gh.MergeQueueEntry
+	if pr.State == githubv4.PullRequestStateOpen {
+		entry, err = client.PullRequestMergeQueueEntry(ctx, pr.ID)
+		if err != nil {
+			return err
+		}
+	}
+	checks, err := client.PullRequestChecks(ctx, pr.ID)
+	if err != nil {
+		return err
+	}
+
+	indent := "    "
+	_, _ = fmt.Fprint(os.Stderr, "#", pr.Number, " ", colors.UserInput(pr.Title), "\n")
+	_, _ = fmt.Fprint(os.Stderr, indent, "Status: ")
+	switch {
+	case entry != nil:
+		_, _ = fmt.Fprint(os.Stderr,
+			colors.UserInput("QUEUED"), " (", strings.ToLower(strings.ReplaceAll(entry.State, "_", " ")),
+			", position ", entry.Position, ")\n",
+			indent, "Queued at: ", colors.UserInput(timeutils.FormatLocal(entry.EnqueuedAt)), "\n",
+		)
+	default:
+		_, _ = fmt.Fprint(os.Stderr, colors.UserInput(pr.State), "\n")
+	}
+	_, _ = fmt.Fprint(os.Stderr,
+		indent, "Author: ", colors.UserInput(pr.Author.Login), "\n",
+		indent, "Base branch: ", colors.UserInput(pr.BaseBranchName(), " <- ", pr.HeadBranchName()), "\n\n",
+	)
+	_, _ = fmt.Fprint(os.Stderr, "Checks\n")
+	for _, check := range checks.Checks {
+		_, _ = fmt.Fprint(os.Stderr,
+			indent, emojiForRequiredCheckResult(string(check.State)), " ", colors.UserInput(check.Name), "\n",
+		)
+	}
+	return nil
+}
+
+// showStackPullRequestStatus shows the state of the pull requests of all of the
+// branches in the current stack along with their reviews and checks, so that
+// it's easy to tell which parts of the stack are blocked.
+func showStackPullRequestStatus() error {
+	repo, err := getRepo()
+	if err != nil {
+		return err
+	}
+	db, err := getDB(repo)
+	if err != nil {
+		return err
+	}
+	tx := db.ReadTx()
+	currentBranchName, err := getCurrentBranchName(repo, db)
+	if err != nil {
+		return err
+	}
+	branches, err := meta.StackBranches(tx, currentBranchName)
+	if err != nil {
+		return err
+	}
+	client, err := getGitHubClient()
+	if err != nil {
+		return err
+	}
+	statuses, err := actions.StackPullRequestStatus(context.Background(), client, tx, branches)
+	if err != nil {
+		return err
+	}
+
+	indent := "    "
+	for _, status := range statuses {
+		blockers := status.Blockers()
+		pr := status.PullRequest
+		switch {
+		case pr == nil:
+			_, _ = fmt.Fprint(os.Stderr, "\u2796 ", colors.UserInput(status.Branch), "\n")
+			_, _ = fmt.Fprint(os.Stderr, indent, colors.Faint("No pull request"), "\n")
+			continue
+		case pr.State != githubv4.PullRequestStateOpen:
+			_, _ = fmt.Fprint(os.Stderr, "\u2796 ")
+		case len(blockers) > 0:
+			_, _ = fmt.Fprint(os.Stderr, "\u26D4 ")
+		default:
+			_, _ = fmt.Fprint(os.Stderr, "\u2705 ")
+		}
+		_, _ = fmt.Fprint(os.Stderr,
+			"#", pr.Number, " ", colors.UserInput(status.Branch), " ", colors.Faint(pr.Title), "\n",
+		)
+
+		_, _ = fmt.Fprint(os.Stderr, indent, "State: ", colors.UserInput(pr.State))
+		if pr.IsDraft && pr.State == githubv4.PullRequestStateOpen {
+			_, _ = fmt.Fprint(os.Stderr, " (draft)")
+		}
+		_, _ = fmt.Fprint(os.Stderr, "\n")
+		if status.Reviews != nil {
+			_, _ = fmt.Fprint(os.Stderr, indent, "Review: ", colors.UserInput(reviewDecisionText(status.Reviews)))
+			if len(status.Reviews.ChangesRequestedBy) > 0 {
+				_, _ = fmt.Fprint(os.Stderr,
+					", changes requested by ", colors.UserInput(formatLogins(status.Reviews.ChangesRequestedBy)),
+				)
+			}
+			if len(status.Reviews.ApprovedBy) > 0 {
+				_, _ = fmt.Fprint(os.Stderr,
+					", approved by ", colors.UserInput(formatLogins(status.Reviews.ApprovedBy)),
+				)
+			}
+			if len(status.Reviews.Pending) > 0 {
+				var pending []string
+				for _, req := range status.Reviews.Pending {
+					pending = append(pending, req.Reviewer)
+				}
+				_, _ = fmt.Fprint(os.Stderr, ", waiting for ", colors.UserInput(formatLogins(pending)))
+			}
+			_, _ = fmt.Fprint(os.Stderr, "\n")
+		}
+		if status.Checks != nil {
+			counts := make(map[gh.CheckState]int)
+			var failed []string
+			for _, check := range status.Checks.Checks {
+				counts[check.State]++
+				if check.State == gh.CheckStateFailure {
+					failed = append(failed, check.Name)
+				}
+			}
+			_, _ = fmt.Fprint(os.Stderr,
+				indent, "Checks: ", emojiForRequiredCheckResult(string(status.Checks.State())), " ",
+				counts[gh.CheckStateSuccess], " passed, ",
+				counts[gh.CheckStateFailure], " failed, ",
+				counts[gh.CheckStatePending], " pending",
+			)
+			if len(failed) > 0 {
+				_, _ = fmt.Fprint(os.Stderr, " (", colors.Failure(strings.Join(failed, ", ")), ")")
+			}
+			_, _ = fmt.Fprint(os.Stderr, "\n")
+		}
+		if len(blockers) > 0 {
+			_, _ = fmt.Fprint(os.Stderr, indent, "Blocked by: ", colors.Warning(strings.Join(blockers, ", ")), "\n")
+		}
+	}
+	return nil
+}
+
+// reviewDecisionText returns the review decision of a pull request in a human
+// readable form.
+func reviewDecisionText(reviews *gh.PullRequestReviews) string {
+	switch reviews.Decision {
+	case githubv4.PullRequestReviewDecisionApproved:
+		return "approved"
+	case githubv4.PullRequestReviewDecisionChangesRequested:
+		return "changes requested"
+	case githubv4.PullRequestReviewDecisionReviewRequired:
+		return "review required"
+	default:
+		return "no review required"
+	}
+}
+
+// formatLogins formats the given logins (or team slugs) as mentions.
+func formatLogins(logins []string) string {
+	mentions := make([]string, len(logins))
+	for i, login := range logins {
+		mentions[i] = "@" + login
+	}
+	return strings.Join(mentions, ", ")
+}
+
 func getQueryVariables() (map[string]interface{}, error) {
 	repo, err := getRepo()
 	if err != nil {
@@ -248,3 +459,10 @@ func emojiForRequiredCheckResult(result string) string {
 		return "\u231B"
 	}
 }
+
+func init() {
+	prStatusCmd.Flags().BoolVar(
+		&prStatusFlags.Stack, "stack", false,
+		"show the state, reviews, and checks of all pull requests in the current stack",
+	)
+}
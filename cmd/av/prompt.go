@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/aviator-co/av/internal/utils/templateutils"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// defaultPromptFormat is the documented (and stable) default output format of
+// av prompt.
+const defaultPromptFormat = `{{.Stack}} {{.Position}}/{{.Total}}` +
+	`{{if .NeedsSync}} !sync{{end}}{{if .PullRequest}} #{{.PullRequest}}{{end}}`
+
+var promptFlags struct {
+	// The Go template to print the summary with.
+	Format string
+}
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt [--format=<template>]",
+	Short: "print a one-line summary of the current branch for shell prompts",
+	Long: `Print a one-line summary of the current branch for shell prompts.
+
+By default, the summary looks like "feature-a 2/5 !sync #123": the name of the
+stack (its root branch), the position of the branch in the stack, "!sync" if
+the branch (or one of the branches below it) needs to be synced, and the number
+of the pull request of the branch (if any). Nothing is printed if the current
+branch isn't part of a stack.
+
+This command doesn't access the network and caches the expensive checks, so it's
+fast enough to run every time the prompt is shown.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	// The prompt is shown all the time, so skip loading the configuration
+	// (which isn't needed here) to keep this fast.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if rootFlags.Debug {
+			logrus.SetLevel(logrus.DebugLevel)
+		}
+		skipCliVersionCheck = true
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tmpl, err := template.New("prompt").Parse(promptFlags.Format)
+		if err != nil {
+			return errors.WrapIf(err, "invalid --format")
+		}
+
+		// Errors are only logged since a broken prompt is worse than an empty
+		// one.
+		repo, err := getRepo()
+		if err != nil {
+			logrus.WithError(err).Debug("not in a Git repository")
+			return nil
+		}
+		dbPath := filepath.Join(repo.AvDir(), "av.db")
+		if _, err := os.Stat(dbPath); err != nil {
+			return nil
+		}
+		db, err := jsonfiledb.OpenPath(dbPath)
+		if err != nil {
+			logrus.WithError(err).Debug("failed to read the av database")
+			return nil
+		}
+		currentBranch, err := repo.CurrentBranchName()
+		if err != nil {
+			return nil
+		}
+		info, ok := actions.GetPromptInfo(repo, db.ReadTx(), currentBranch)
+		if !ok {
+			return nil
+		}
+		out, err := templateutils.String(tmpl, info)
+		if err != nil {
+			return errors.WrapIf(err, "failed to format the prompt")
+		}
+		fmt.Println(strings.TrimSpace(out))
+		return nil
+	},
+}
+
+func init() {
+	promptCmd.Flags().StringVar(
+		&promptFlags.Format, "format", defaultPromptFormat,
+		"the Go template to print the summary with",
+	)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/spf13/cobra"
+)
+
+var pushFlags struct {
+	// If true, push the branches that were queued while offline.
+	Pending bool
+}
+
+var pushCmd = &cobra.Command{
+	Use:   "push [<branch>] [--pending]",
+	Short: "push a branch and update its pull request",
+	Long: `Push a branch (the current branch by default) and update its pull request
+(its base branch and the stack information in its description), as
+"av stack sync" does after syncing it.
+
+If GitHub can't be reached when "av stack sync" pushes a branch (e.g., because
+you're offline), the sync still finishes locally and the branch is queued. The
+same goes for the updates of pull requests by "av pr restack", "av pr body", and
+"av stack describe-branch". Use "av push --pending" once you're back online to
+push the queued branches and update their pull requests.`,
+	SilenceUsage: true,
+	Args:         cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if pushFlags.Pending && len(args) > 0 {
+			return errors.New("cannot specify a branch with --pending")
+		}
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.WriteTx()
+		defer tx.Abort()
+
+		client, err := getGitHubClient()
+		if err != nil {
+			return err
+		}
+		ctx := context.Background()
+
+		if pushFlags.Pending {
+			updates, err := actions.ReadPendingUpdates(repo)
+			if err != nil {
+				return err
+			}
+			if len(updates) == 0 {
+				_, _ = fmt.Fprint(os.Stderr, "There are no pending pull request updates.\n")
+				return nil
+			}
+			if err := ensureGitHubWriteAccess(ctx, client, tx); err != nil {
+				return err
+			}
+			pushed, err := actions.PushPendingUpdates(ctx, repo, client, tx)
+			if cerr := tx.Commit(); cerr != nil && err == nil {
+				err = cerr
+			}
+			if actions.IsOffline(err) {
+				_, _ = fmt.Fprint(os.Stderr,
+					colors.Failure("GitHub still can't be reached; the remaining updates are kept."), "\n",
+				)
+				return actions.ErrExitSilently{ExitCode: 1}
+			} else if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprint(os.Stderr,
+				"\n", colors.Success(fmt.Sprintf("Updated %d pending branch(es).", len(pushed))), "\n",
+			)
+			return nil
+		}
+
+		var branchName string
+		if len(args) > 0 {
+			branchName = args[0]
+		} else {
+			branchName, err = getCurrentBranchName(repo, db)
+			if err != nil {
+				return err
+			}
+		}
+		branch, ok := tx.Branch(branchName)
+		if !ok {
+			return errors.Errorf("branch %q is not tracked by av", branchName)
+		}
+		if err := ensureNotReadOnly(tx, branchName); err != nil {
+			return err
+		}
+		switch {
+		case branch.WIP:
+			return errors.Errorf("branch %q is a work in progress (unmark it with av stack unwip first)", branchName)
+		case branch.PullRequest == nil || branch.PullRequest.ID == "":
+			return errors.Errorf("branch %q doesn't have a pull request (create one with av pr create)", branchName)
+		}
+		if err := ensureGitHubWriteAccess(ctx, client, tx); err != nil {
+			return err
+		}
+		if err := actions.PushBranch(ctx, repo, client, tx, branchName); err != nil {
+			return err
+		}
+		return tx.Commit()
+	},
+}
+
+func init() {
+	pushCmd.Flags().BoolVar(
+		&pushFlags.Pending, "pending", false,
+		"push the branches (and update the pull requests) that were queued while GitHub couldn't be reached",
+	)
+}
@@ -12,18 +12,39 @@ var stackCmd = &cobra.Command{
 
 func init() {
 	stackCmd.AddCommand(
+		stackAdoptCmd,
+		stackBisectCmd,
 		stackBranchCmd,
 		stackBranchCommitCmd,
+		stackCopyToPRCmd,
+		stackDeleteCmd,
+		stackDescribeCmd,
+		stackDescribeBranchCmd,
 		stackDiffCmd,
+		stackFoldCmd,
 		stackForEachCmd,
+		stackFreezeCmd,
+		stackGotoCmd,
+		stackInsertCmd,
+		stackLandCmd,
+		stackNewCmd,
 		stackNextCmd,
 		stackPrevCmd,
 		stackOrphanCmd,
+		stackRebaseCmd,
+		stackRenameCmd,
 		stackReorderCmd,
+		stackReportCmd,
 		stackReparentCmd,
+		stackSplitCmd,
+		stackStatsCmd,
 		stackSyncCmd,
 		stackSubmitCmd,
+		stackSwitchCmd,
 		stackTidyCmd,
 		stackTreeCmd,
+		stackUnfreezeCmd,
+		stackUnwipCmd,
+		stackWIPCmd,
 	)
 }
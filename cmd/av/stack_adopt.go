@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/spf13/cobra"
+)
+
+var stackAdoptFlags struct {
+	// The parent branch of the adopted branch.
+	Parent string
+	// If true, adopt someone else's branch as a read-only branch.
+	ReadOnly bool
+}
+
+var stackAdoptCmd = &cobra.Command{
+	Use:   "adopt [<branch>] [--parent=<parent>] [--read-only]",
+	Short: "add an existing branch to a stack",
+	Long: `Add an existing branch (the current branch by default) that was created
+without av (e.g., with "git checkout -b") to a stack.
+
+Unless --parent is given, the parent of the branch is inferred from its
+history: it's the trunk or tracked branch that the branch has the fewest commits
+on top of. The branch isn't rebased; use "av stack sync" to rebase it onto the
+latest version of its parent afterwards.
+
+With --read-only, the branch is tracked as someone else's branch (e.g., a
+colleague's branch that is still in review) that stacks of your own can be based
+on. It's fetched from the remote if it doesn't exist locally. av never rebases,
+pushes or creates a pull request for a read-only branch: "av stack sync" updates
+it from the remote instead and rebases the branches stacked on top of it.`,
+	SilenceUsage: true,
+	Args:         cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.WriteTx()
+		defer tx.Abort()
+
+		var branchName string
+		if len(args) > 0 {
+			branchName = args[0]
+		} else {
+			branchName, err = repo.CurrentBranchName()
+			if err != nil {
+				return err
+			}
+		}
+		branch, err := actions.AdoptBranch(repo, tx, actions.AdoptOpts{
+			Branch:   branchName,
+			Parent:   stackAdoptFlags.Parent,
+			ReadOnly: stackAdoptFlags.ReadOnly,
+		})
+		if err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+		readOnly := ""
+		if branch.ReadOnly {
+			readOnly = "read-only "
+		}
+		_, _ = fmt.Fprint(os.Stderr,
+			"Adopted ", readOnly, "branch ", colors.UserInput(branchName),
+			" with parent ", colors.UserInput(branch.Parent.Name), "\n",
+		)
+		if stackAdoptFlags.Parent == "" {
+			_, _ = fmt.Fprint(os.Stderr,
+				colors.Faint("  - If that's not the right parent, use "),
+				colors.CliCmd("av stack sync --parent <parent>"),
+				colors.Faint(" to move the branch.\n"),
+			)
+		}
+		return nil
+	},
+}
+
+func init() {
+	stackAdoptCmd.Flags().StringVar(
+		&stackAdoptFlags.Parent, "parent", "",
+		"the parent branch (inferred from the history of the branch by default)",
+	)
+	stackAdoptCmd.Flags().BoolVar(
+		&stackAdoptFlags.ReadOnly, "read-only", false,
+		"adopt someone else's branch that is never rebased or pushed by av",
+	)
+}
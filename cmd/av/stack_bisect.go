@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/aviator-co/av/internal/utils/executils"
+	"github.com/spf13/cobra"
+)
+
+var stackBisectFlags struct {
+	// If true, test the branches one after the other instead of
+	// binary-searching them.
+	Linear bool
+}
+
+var stackBisectCmd = &cobra.Command{
+	Use:   "bisect [flags] -- <command> [args...]",
+	Short: "find the first branch of the stack for which a command fails",
+	Long: `Find the first branch of the stack for which a command (e.g., a test suite)
+fails.
+
+The command is run for the branches from the bottom of the stack up to the
+current branch, each in a temporary worktree (so the working tree of the
+repository is left alone). A branch fails if the command exits with a non-zero
+exit code. The parent of the bottom-most branch (usually the trunk) is assumed
+to pass.
+
+By default, the current branch is tested first and the rest of the stack is
+binary-searched. With --linear, the branches are tested one after the other
+(which helps if the failure doesn't reproduce in every branch after the one
+that introduced it).
+
+Exits with 0 if the first failing branch was found and with 2 if the command
+passed for the current branch (so there's nothing to bisect).
+
+Examples:
+  Find the branch that breaks the tests:
+    $ av stack bisect -- make test
+`,
+	SilenceUsage: true,
+	Args:         cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (reterr error) {
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.ReadTx()
+		currentBranch, err := getCurrentBranchName(repo, db)
+		if err != nil {
+			return err
+		}
+		if _, ok := tx.Branch(currentBranch); !ok {
+			return errors.Errorf("branch %q is not part of a stack", currentBranch)
+		}
+		branches, err := meta.PreviousBranches(tx, currentBranch)
+		if err != nil {
+			return err
+		}
+		branches = append(branches, currentBranch)
+		heads := make(map[string]string)
+		for _, branch := range branches {
+			head, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + branch})
+			if err != nil {
+				return errors.WrapIff(err, "failed to determine HEAD for branch %q", branch)
+			}
+			heads[branch] = head
+		}
+
+		// Interrupting the command (e.g., with Ctrl-C) has to stop it without
+		// killing av so that the temporary worktree is still removed.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		dir, err := os.MkdirTemp("", "av-bisect-")
+		if err != nil {
+			return errors.WrapIf(err, "failed to create a temporary directory")
+		}
+		worktree := filepath.Join(dir, "worktree")
+		if _, err := repo.Run(&git.RunOpts{
+			Args:      []string{"worktree", "add", "--quiet", "--detach", worktree, heads[currentBranch]},
+			ExitError: true,
+		}); err != nil {
+			_ = os.RemoveAll(dir)
+			return errors.WrapIf(err, "failed to create a temporary worktree")
+		}
+		defer func() {
+			if _, err := repo.Run(&git.RunOpts{
+				Args:      []string{"worktree", "remove", "--force", worktree},
+				ExitError: true,
+			}); err != nil && reterr == nil {
+				reterr = errors.WrapIf(err, "failed to remove the temporary worktree")
+			}
+			_ = os.RemoveAll(dir)
+		}()
+
+		_, _ = fmt.Fprint(os.Stderr,
+			"Bisecting ", colors.UserInput(len(branches)), " branch(es) with ",
+			colors.CliCmd(executils.FormatCommandLine(args)), "\n",
+		)
+		test := func(branch string) (bool, error) {
+			_, _ = fmt.Fprint(os.Stderr,
+				"  - testing branch ", colors.UserInput(branch),
+				" (", git.ShortSha(heads[branch]), ")...\n",
+			)
+			if _, err := repo.Run(&git.RunOpts{
+				Args:      []string{"-C", worktree, "checkout", "--quiet", "--detach", heads[branch]},
+				ExitError: true,
+			}); err != nil {
+				return false, errors.WrapIff(err, "failed to check out branch %q", branch)
+			}
+			c := exec.CommandContext(ctx, args[0], args[1:]...)
+			c.Dir = worktree
+			c.Stdout = os.Stdout
+			c.Stderr = os.Stderr
+			err := c.Run()
+			if ctx.Err() != nil {
+				return false, errors.New("interrupted")
+			}
+			var exitErr *exec.ExitError
+			if err != nil && !errors.As(err, &exitErr) {
+				return false, errors.Wrapf(err, "failed to execute command for branch %q", branch)
+			}
+			if err != nil {
+				_, _ = fmt.Fprint(os.Stderr, "    ", colors.Failure("failed"), "\n")
+				return false, nil
+			}
+			_, _ = fmt.Fprint(os.Stderr, "    ", colors.Success("passed"), "\n")
+			return true, nil
+		}
+
+		index, err := actions.BisectStack(branches, stackBisectFlags.Linear, test)
+		if err != nil {
+			return err
+		}
+		if index < 0 {
+			_, _ = fmt.Fprint(os.Stderr,
+				"The command passed for ", colors.UserInput(currentBranch), ", so there's nothing to bisect.\n",
+			)
+			return actions.ErrExitSilently{ExitCode: 2}
+		}
+
+		culprit, _ := tx.Branch(branches[index])
+		_, _ = fmt.Fprint(os.Stderr, "\nThe command first fails for branch ", colors.UserInput(culprit.Name))
+		if culprit.PullRequest != nil && culprit.PullRequest.Number != 0 {
+			_, _ = fmt.Fprint(os.Stderr, " (#", culprit.PullRequest.Number, ")")
+		}
+		_, _ = fmt.Fprint(os.Stderr, ".\n")
+		if index == 0 {
+			_, _ = fmt.Fprint(os.Stderr,
+				colors.Faint("  - This is the bottom of the stack, so the command might fail for "),
+				colors.UserInput(culprit.Parent.Name), colors.Faint(" too.\n"),
+			)
+		}
+		return nil
+	},
+}
+
+func init() {
+	stackBisectCmd.Flags().BoolVar(
+		&stackBisectFlags.Linear, "linear", false,
+		"test the branches one after the other instead of binary-searching them",
+	)
+}
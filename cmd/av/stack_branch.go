@@ -1,16 +1,20 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"strings"
 
 	"emperror.dev/errors"
 	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/gh"
 	"github.com/aviator-co/av/internal/git"
 	"github.com/aviator-co/av/internal/meta"
 	"github.com/aviator-co/av/internal/utils/cleanup"
 	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/shurcooL/githubv4"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -26,6 +30,21 @@ var stackBranchFlags struct {
 	Rename bool
 	// If true, rename the current branch even if a pull request exists.
 	Force bool
+	// If set, generate the branch name from this message (when no branch name
+	// is given explicitly).
+	Message string
+	// If true, create an empty commit with Message on the new branch.
+	EmptyCommit bool
+	// If true, bring uncommitted changes along to the new branch when it's
+	// created from a parent other than the current branch.
+	CarryChanges bool
+	// If set, the number of the GitHub issue that the new branch is for. The
+	// branch name is generated from the issue title (when no branch name is
+	// given explicitly) and the pull request of the branch closes the issue.
+	Issue int64
+	// If false, create the branch without switching to it (e.g., to create
+	// several branches of a stack up front).
+	Checkout bool
 }
 var stackBranchCmd = &cobra.Command{
 	Use:     "branch [flags] <branch-name>",
@@ -36,24 +55,80 @@ var stackBranchCmd = &cobra.Command{
 If the --rename/-m flag is given, the current branch is renamed to the name
 given as the first argument to the command. Branches should only be renamed
 with this command (not with git branch -m ...) because av needs to update
-internal tracking metadata that defines the order of branches within a stack.`,
+internal tracking metadata that defines the order of branches within a stack.
+
+If no branch name is given, the name is generated from the --message flag
+(using the configured branch name prefix). With --empty-commit, an empty commit
+carrying the message is created on the new branch.
+
+With --issue, the branch is created for the given GitHub issue: if no branch
+name is given, the name is generated from the issue number and title, and the
+pull request of the branch (see av pr create) is linked to the issue with
+"Fixes #<issue>" so that merging it closes the issue.
+
+With --checkout=false, the branch is created without switching to it (and the
+working tree is left untouched).`,
 	SilenceUsage: true,
-	Args:         cobra.ExactArgs(1),
+	Args:         cobra.RangeArgs(0, 1),
 	RunE: func(cmd *cobra.Command, args []string) (reterr error) {
 		repo, err := getRepo()
 		if err != nil {
 			return err
 		}
 
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+
 		db, err := getDB(repo)
 		if err != nil {
 			return err
 		}
 
-		branchName := args[0]
+		if stackBranchFlags.EmptyCommit && stackBranchFlags.Message == "" {
+			return errors.New("--empty-commit requires --message")
+		}
+
+		if stackBranchFlags.Issue != 0 && stackBranchFlags.Rename {
+			return errors.New("--issue can't be used with --rename")
+		}
+		if !stackBranchFlags.Checkout && stackBranchFlags.Rename {
+			return errors.New("--checkout=false can't be used with --rename")
+		}
+		checkout := stackBranchFlags.Checkout
+		var issue *gh.Issue
+		if stackBranchFlags.Issue != 0 {
+			issue, err = fetchIssue(db.ReadTx(), stackBranchFlags.Issue)
+			if err != nil {
+				return err
+			}
+		}
+
+		var branchName string
+		if len(args) == 1 {
+			branchName = args[0]
+		} else if issue != nil {
+			branchName, err = branchNameFromMessageUnique(
+				repo, fmt.Sprintf("%d %s", issue.Number, issue.Title),
+			)
+			if err != nil {
+				return err
+			}
+		} else if stackBranchFlags.Message == "" || stackBranchFlags.Rename {
+			_ = cmd.Usage()
+			return errors.New("need a branch name, a message, or an issue")
+		} else {
+			branchName, err = branchNameFromMessageUnique(repo, stackBranchFlags.Message)
+			if err != nil {
+				return err
+			}
+		}
 		if stackBranchFlags.Rename {
 			return stackBranchMove(repo, db, branchName, stackBranchFlags.Force)
 		}
+		if err := ensureNoBranchCaseCollision(repo, branchName); err != nil {
+			return err
+		}
 
 		tx := db.WriteTx()
 		cu := cleanup.New(func() {
@@ -68,9 +143,51 @@ internal tracking metadata that defines the order of branches within a stack.`,
 			return errors.WrapIf(err, "failed to determine repository default branch")
 		}
 
-		// Determine the parent branch and make sure it's checked out
-		var parentBranchName string
+		// Validate the parent before touching the working tree so that a typo
+		// doesn't leave the changes stashed away.
 		if stackBranchFlags.Parent != "" {
+			if err := validateParentBranch(repo, tx, stackBranchFlags.Parent, defaultBranch); err != nil {
+				return err
+			}
+		}
+
+		// Uncommitted changes are carried over to the new branch by Git when
+		// branching from the current branch, but checking out a different
+		// parent would either fail or mix the changes into the parent. In that
+		// case, stash the changes and re-apply them once the new branch exists.
+		// Without checking out the new branch, the changes stay where they are.
+		clean := true
+		if checkout {
+			clean, err = repo.CheckCleanWorkdir()
+			if err != nil {
+				return err
+			}
+		}
+		carryChanges := false
+		if !clean && stackBranchFlags.Parent != "" {
+			carryChanges, err = confirmCarryChanges()
+			if err != nil {
+				return err
+			}
+			if _, err := repo.Run(&git.RunOpts{
+				Args:      []string{"stash", "push", "--include-untracked", "--message", "av stack branch"},
+				ExitError: true,
+			}); err != nil {
+				return errors.WrapIf(err, "failed to stash uncommitted changes")
+			}
+			cu.Add(func() {
+				if err := popStash(repo); err != nil {
+					logrus.WithError(err).Warn("cleanup error: failed to restore uncommitted changes")
+				}
+			})
+		}
+
+		// Determine the parent branch and make sure it's checked out (unless the
+		// new branch won't be checked out either)
+		var parentBranchName string
+		if stackBranchFlags.Parent != "" && !checkout {
+			parentBranchName = stackBranchFlags.Parent
+		} else if stackBranchFlags.Parent != "" {
 			parentBranchName = stackBranchFlags.Parent
 			origBranch, err := repo.CheckoutBranch(&git.CheckoutBranch{Name: parentBranchName})
 			if err != nil {
@@ -83,76 +200,79 @@ internal tracking metadata that defines the order of branches within a stack.`,
 			})
 		} else {
 			var err error
-			parentBranchName, err = repo.CurrentBranchName()
+			parentBranchName, err = getCurrentBranchName(repo, db)
 			if err != nil {
 				return errors.WrapIff(err, "failed to get current branch name")
 			}
 		}
 
-		// Currently, we only allow the repo default branch to be a trunk.
-		// We might want to allow other branches to be trunks in the future, but
-		// that does run the risk of allowing the user to get into a weird state
-		// (where some stacks assume a branch is a trunk and others don't).
-		isBranchFromTrunk := parentBranchName == defaultBranch
-		var parentHead string
-		if !isBranchFromTrunk {
-			var err error
-			parentHead, err = repo.RevParse(&git.RevParse{Rev: parentBranchName})
-			if err != nil {
+		if err := createStackedBranch(
+			repo, tx, branchName, parentBranchName, defaultBranch, checkout, stackBranchFlags.Issue,
+		); err != nil {
+			return err
+		}
+
+		cu.Cancel()
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+		if carryChanges {
+			if err := popStash(repo); err != nil {
 				return errors.WrapIff(
 					err,
-					"failed to determine head commit of branch %q",
-					parentHead,
+					"created branch %q but failed to restore uncommitted changes (they're still available in the stash)",
+					branchName,
 				)
 			}
+			_, _ = fmt.Fprint(
+				os.Stderr,
+				"Carried uncommitted changes over to branch ",
+				colors.UserInput(branchName),
+				"\n",
+			)
+		} else if !clean {
+			_, _ = fmt.Fprint(
+				os.Stderr,
+				colors.Faint("Uncommitted changes were carried over to the new branch.\n"),
+			)
 		}
 
-		// Create a new branch off of the parent
-		logrus.WithFields(logrus.Fields{
-			"parent":     parentBranchName,
-			"new_branch": branchName,
-		}).Debug("creating new branch from parent")
-		if _, err := repo.CheckoutBranch(&git.CheckoutBranch{
-			Name:      branchName,
-			NewBranch: true,
-		}); err != nil {
-			return errors.WrapIff(err, "checkout error")
-		}
-
-		tx.SetBranch(meta.Branch{
-			Name: branchName,
-			Parent: meta.BranchState{
-				Name:  parentBranchName,
-				Trunk: isBranchFromTrunk,
-				Head:  parentHead,
-			},
-		})
-
-		// If this isn't a new stack root, update the parent metadata to include
-		// the new branch as a child.
-		if !isBranchFromTrunk {
-			parentMeta, ok := tx.Branch(parentBranchName)
-			if !ok {
-				// Handle case where the user created first branch by
-				// `git switch -c` from trunk (i.e., created first branch
-				// without using av), then wants to create a stacked branch
-				// after it.
-				parentMeta = meta.Branch{
-					Name: parentBranchName,
-					Parent: meta.BranchState{
-						// Assume the parent is a branch from the default branch
-						Name:  defaultBranch,
-						Trunk: true,
-					},
-				}
+		if stackBranchFlags.EmptyCommit {
+			// Use commit-tree rather than `git commit --allow-empty` so that
+			// any staged changes aren't swept into the commit.
+			ref := "refs/heads/" + branchName
+			commit, err := repo.Git(
+				"commit-tree", ref+"^{tree}", "-p", ref, "-m", stackBranchFlags.Message,
+			)
+			if err == nil {
+				err = repo.UpdateRef(&git.UpdateRef{Ref: "refs/heads/" + branchName, New: commit})
+			}
+			if err != nil {
+				return errors.WrapIff(err, "created branch %q but failed to create commit", branchName)
 			}
-			logrus.WithField("meta", parentMeta).Debug("writing parent branch metadata")
-			tx.SetBranch(parentMeta)
 		}
 
-		cu.Cancel()
-		if err := tx.Commit(); err != nil {
-			return err
+		if issue != nil {
+			_, _ = fmt.Fprint(
+				os.Stderr,
+				"Created branch ",
+				colors.UserInput(branchName),
+				" for issue ",
+				colors.UserInput("#", issue.Number),
+				": ",
+				issue.Title,
+				"\n",
+			)
+		} else if len(args) == 0 || !checkout {
+			// Let the user know what name we came up with (or that the branch
+			// was created even though it's not checked out).
+			_, _ = fmt.Fprint(
+				os.Stderr,
+				"Created branch ",
+				colors.UserInput(branchName),
+				"\n",
+			)
 		}
 		return nil
 	},
@@ -167,6 +287,191 @@ func init() {
 		BoolVarP(&stackBranchFlags.Rename, "rename", "m", false, "rename the current branch")
 	stackBranchCmd.Flags().
 		BoolVar(&stackBranchFlags.Force, "force", false, "force rename the current branch")
+	stackBranchCmd.Flags().
+		BoolVar(&stackBranchFlags.CarryChanges, "carry-changes", false, "bring uncommitted changes along to the new branch without prompting (when used with --parent)")
+	stackBranchCmd.Flags().
+		StringVar(&stackBranchFlags.Message, "message", "", "generate the branch name from the given message")
+	stackBranchCmd.Flags().
+		BoolVar(&stackBranchFlags.EmptyCommit, "empty-commit", false, "create an empty commit with the message on the new branch")
+	stackBranchCmd.Flags().
+		Int64Var(&stackBranchFlags.Issue, "issue", 0, "create the branch for the given GitHub issue (its pull request closes the issue)")
+	stackBranchCmd.Flags().
+		BoolVar(&stackBranchFlags.Checkout, "checkout", true, "switch to the new branch (use --checkout=false to only create it)")
+}
+
+// createStackedBranch creates a new branch off the given parent (which has to
+// be checked out if the new branch is checked out) and records it (and the
+// parent, if it isn't tracked yet) in the metadata. With checkout, the new
+// branch is checked out.
+func createStackedBranch(
+	repo *git.Repo,
+	tx meta.WriteTx,
+	branchName string,
+	parentBranchName string,
+	defaultBranch string,
+	checkout bool,
+	issue int64,
+) error {
+	// Besides the repo default branch, only the branches that existing
+	// stacks are already based on are considered trunks. Otherwise, some
+	// stacks could assume that a branch is a trunk while others don't.
+	isBranchFromTrunk := meta.IsTrunk(tx, parentBranchName, defaultBranch)
+	var parentHead string
+	if !isBranchFromTrunk {
+		var err error
+		parentHead, err = repo.RevParse(&git.RevParse{Rev: "refs/heads/" + parentBranchName})
+		if err != nil {
+			return errors.WrapIff(
+				err,
+				"failed to determine head commit of branch %q",
+				parentBranchName,
+			)
+		}
+	}
+
+	// Create a new branch off of the parent
+	logrus.WithFields(logrus.Fields{
+		"parent":     parentBranchName,
+		"new_branch": branchName,
+	}).Debug("creating new branch from parent")
+	if !checkout {
+		if _, err := repo.Run(&git.RunOpts{
+			Args:      []string{"branch", "--no-track", branchName, "refs/heads/" + parentBranchName},
+			ExitError: true,
+		}); err != nil {
+			return errors.WrapIff(err, "failed to create branch %q", branchName)
+		}
+	} else if err := actions.SwitchBranch(repo, &git.CheckoutBranch{
+		Name:      branchName,
+		NewBranch: true,
+	}); err != nil {
+		return errors.WrapIff(err, "checkout error")
+	}
+
+	tx.SetBranch(meta.Branch{
+		Name: branchName,
+		Parent: meta.BranchState{
+			Name:  parentBranchName,
+			Trunk: isBranchFromTrunk,
+			Head:  parentHead,
+		},
+		Issue: issue,
+	})
+
+	// If this isn't a new stack root, update the parent metadata to include
+	// the new branch as a child.
+	if !isBranchFromTrunk {
+		parentMeta, ok := tx.Branch(parentBranchName)
+		if !ok {
+			// Handle case where the user created first branch by
+			// `git switch -c` from trunk (i.e., created first branch
+			// without using av), then wants to create a stacked branch
+			// after it.
+			parentMeta = meta.Branch{
+				Name: parentBranchName,
+				Parent: meta.BranchState{
+					// Assume the parent is a branch from the default branch
+					Name:  defaultBranch,
+					Trunk: true,
+				},
+			}
+		}
+		logrus.WithField("meta", parentMeta).Debug("writing parent branch metadata")
+		tx.SetBranch(parentMeta)
+	}
+	return nil
+}
+
+// fetchIssue fetches the given issue of the repository from GitHub. A warning
+// is shown if the issue is already closed.
+func fetchIssue(tx meta.ReadTx, number int64) (*gh.Issue, error) {
+	repository, ok := tx.Repository()
+	if !ok {
+		return nil, actions.ErrRepoNotInitialized
+	}
+	client, err := getGitHubClient()
+	if err != nil {
+		return nil, err
+	}
+	issue, err := client.Issue(context.Background(), repository.Owner, repository.Name, number)
+	if err != nil {
+		return nil, err
+	}
+	if issue.State == githubv4.IssueStateClosed {
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			colors.Warning("Issue #", issue.Number, " is already closed.\n"),
+		)
+	}
+	return issue, nil
+}
+
+// confirmCarryChanges determines whether uncommitted changes should be brought
+// along to the new branch. If --carry-changes was not given, the user is asked
+// (if we're running interactively).
+func confirmCarryChanges() (bool, error) {
+	if stackBranchFlags.CarryChanges {
+		return true, nil
+	}
+	if !isInteractive() {
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			colors.Failure("The working tree has uncommitted changes.\n"),
+			colors.Faint("  - Use --carry-changes to bring them along to the new branch.\n"),
+		)
+		return false, actions.ErrExitSilently{ExitCode: 1}
+	}
+	_, _ = fmt.Fprint(
+		os.Stderr,
+		"The working tree has uncommitted changes. ",
+		"Bring them along to the new branch? [y/N]: ",
+	)
+	choice, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+	if !strings.EqualFold(strings.TrimSpace(choice), "y") {
+		_, _ = fmt.Fprint(os.Stderr, colors.Failure("Aborting.\n"))
+		return false, actions.ErrExitSilently{ExitCode: 1}
+	}
+	return true, nil
+}
+
+// validateParentBranch makes sure that the given branch can be used as the
+// parent of a new stacked branch: it has to exist and either be a trunk or be
+// tracked by av.
+func validateParentBranch(repo *git.Repo, tx meta.ReadTx, name string, defaultBranch string) error {
+	exists, err := repo.DoesBranchExist(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.Errorf("parent branch %q does not exist", name)
+	}
+	if meta.IsTrunk(tx, name, defaultBranch) {
+		return nil
+	}
+	if _, ok := tx.Branch(name); !ok {
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			colors.Failure("Branch ", name, " is not tracked by av and is not a trunk.\n"),
+			colors.Faint("  - Use "),
+			colors.CliCmd("av stack adopt ", name),
+			colors.Faint(" to add it to a stack first.\n"),
+		)
+		return actions.ErrExitSilently{ExitCode: 1}
+	}
+	return nil
+}
+
+// popStash re-applies (and drops) the most recent stash entry, restoring the
+// staged state of the stashed changes.
+func popStash(repo *git.Repo) error {
+	_, err := repo.Run(&git.RunOpts{
+		Args:      []string{"stash", "pop", "--index"},
+		ExitError: true,
+	})
+	return err
 }
 
 func stackBranchMove(
@@ -201,6 +506,9 @@ func stackBranchMove(
 	if oldBranch == newBranch {
 		return errors.Errorf("cannot rename branch to itself")
 	}
+	if err := ensureNoBranchCaseCollision(repo, newBranch, oldBranch); err != nil {
+		return err
+	}
 
 	currentMeta, ok := tx.Branch(oldBranch)
 	if !ok {
@@ -225,7 +533,9 @@ func stackBranchMove(
 					currentMeta.PullRequest.Number,
 					" would be orphaned.\n",
 				),
-				colors.Faint("  - Use --force to override this check.\n"),
+				colors.Faint("  - Use "), colors.CliCmd("av stack rename"),
+				colors.Faint(" to rename the branch along with its pull request,\n"),
+				colors.Faint("    or --force to override this check.\n"),
 			)
 
 			return actions.ErrExitSilently{ExitCode: 127}
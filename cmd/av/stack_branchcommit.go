@@ -64,11 +64,6 @@ var stackBranchCommitCmd = &cobra.Command{
 				_ = cmd.Usage()
 				return errors.New("Need a branch name or a commit message")
 			}
-			// TODO(oleg): add suffix if branch already exists.
-			branchName = branchNameFromMessage(stackBranchCommitFlags.Message)
-			if branchName == "" {
-				return errors.New("Cannot create a valid branch name from the message")
-			}
 		}
 
 		repo, err := getRepo()
@@ -76,6 +71,19 @@ var stackBranchCommitCmd = &cobra.Command{
 			return err
 		}
 
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+
+		if branchName == "" {
+			branchName, err = branchNameFromMessageUnique(repo, stackBranchCommitFlags.Message)
+			if err != nil {
+				return err
+			}
+		} else if err := ensureNoBranchCaseCollision(repo, branchName); err != nil {
+			return err
+		}
+
 		db, err := getDB(repo)
 		if err != nil {
 			return err
@@ -94,7 +102,7 @@ var stackBranchCommitCmd = &cobra.Command{
 			return errors.WrapIf(err, "failed to determine repository default branch")
 		}
 
-		parentBranchName, err := repo.CurrentBranchName()
+		parentBranchName, err := getCurrentBranchName(repo, db)
 		if err != nil {
 			return errors.WrapIff(err, "failed to get current branch name")
 		}
@@ -184,7 +192,7 @@ var stackBranchCommitCmd = &cobra.Command{
 		}
 
 		if _, err := repo.Run(&git.RunOpts{
-			Args:        commitArgs,
+			Args:        actions.WithCommitTrailers(tx, branchName, commitArgs),
 			ExitError:   true,
 			Interactive: true,
 		}); err != nil {
@@ -231,3 +239,32 @@ func branchNameFromMessage(message string) string {
 	}
 	return name
 }
+
+// branchNameFromMessageUnique generates a branch name from the message (see
+// branchNameFromMessage) and adds a numeric suffix if a branch with that name
+// already exists.
+func branchNameFromMessageUnique(repo *git.Repo, message string) (string, error) {
+	base := branchNameFromMessage(message)
+	if base == "" {
+		return "", errors.New("Cannot create a valid branch name from the message")
+	}
+	name := base
+	for i := 2; ; i++ {
+		exists, err := repo.DoesBranchExist(name)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			// Also avoid names that only differ in case from an existing
+			// branch (see ensureNoBranchCaseCollision).
+			collision, err := repo.BranchCaseCollision(name)
+			if err != nil {
+				return "", err
+			}
+			if collision == "" {
+				return name, nil
+			}
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+}
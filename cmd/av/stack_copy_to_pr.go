@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/spf13/cobra"
+)
+
+var stackCopyToPRFlags struct {
+	// The title of the pull request (fetched from GitHub by default).
+	Title string
+	// If true, only show the files changed by the squash commit.
+	Stat bool
+}
+
+var stackCopyToPRCmd = &cobra.Command{
+	Use:   "copy-to-pr [<branch>] [--title=<title>] [--stat]",
+	Short: "preview the commit that squash-merging a pull request would create",
+	Long: `Create the commit that GitHub would create when squash-merging the pull
+request of the given branch (the current branch by default), and show its
+message and diff.
+
+The branches below the branch in the stack are assumed to be squash-merged
+first (as with "av stack land --method=squash"), so the commit is based on the
+latest fetched commit of the trunk with the changes of those branches applied,
+and its diff contains exactly the changes of the branch. The commit message
+follows GitHub's default for squash merges. The commit isn't referenced by any
+branch, so it doesn't change anything, but it can be checked out or
+cherry-picked with its hash.`,
+	SilenceUsage: true,
+	Args:         cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.ReadTx()
+
+		var branchName string
+		if len(args) > 0 {
+			branchName = args[0]
+		} else {
+			branchName, err = repo.CurrentBranchName()
+			if err != nil {
+				return err
+			}
+		}
+		branch, ok := tx.Branch(branchName)
+		if !ok {
+			return errors.Errorf("branch %q is not tracked by av", branchName)
+		}
+
+		opts := actions.SquashPreviewOpts{
+			Title:  stackCopyToPRFlags.Title,
+			Number: branch.PullRequest.GetNumber(),
+		}
+		if opts.Title == "" && branch.PullRequest != nil && branch.PullRequest.ID != "" {
+			client, err := getGitHubClient()
+			if err != nil {
+				return err
+			}
+			pr, err := client.PullRequest(context.Background(), branch.PullRequest.ID)
+			if err != nil {
+				return err
+			}
+			opts.Title = pr.Title
+			opts.Number = pr.Number
+		}
+
+		commit, err := actions.SquashPreview(repo, tx, branchName, opts)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprint(os.Stderr,
+			"Squash commit of ", colors.UserInput(branchName), ": ", colors.UserInput(commit), "\n",
+		)
+		showArgs := []string{"show"}
+		if stackCopyToPRFlags.Stat {
+			showArgs = append(showArgs, "--stat")
+		}
+		// Like av stack diff, this doesn't set ExitError so that git uses the
+		// output pager.
+		_, err = repo.Run(&git.RunOpts{
+			Args:        append(showArgs, commit, "--"),
+			Interactive: true,
+		})
+		return err
+	},
+}
+
+func init() {
+	stackCopyToPRCmd.Flags().StringVar(
+		&stackCopyToPRFlags.Title, "title", "",
+		"the title of the pull request (fetched from GitHub by default; required for "+
+			"branches with several commits that don't have a pull request)",
+	)
+	stackCopyToPRCmd.Flags().BoolVar(
+		&stackCopyToPRFlags.Stat, "stat", false,
+		"only show the files changed by the commit",
+	)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/aviator-co/av/internal/utils/stringutils"
+	"github.com/kr/text"
+	"github.com/shurcooL/githubv4"
+	"github.com/spf13/cobra"
+)
+
+var stackDeleteFlags struct {
+	// If true, also delete the branch on the remote.
+	Remote bool
+	// If true, delete the branch even if its commits aren't merged.
+	Force bool
+}
+
+var stackDeleteCmd = &cobra.Command{
+	Use:   "delete [<branch>] [--remote] [--force]",
+	Short: "delete a branch and move its children onto its parent",
+	Long: `Delete a branch (the current branch by default) from the stack.
+
+The children of the branch are moved onto the parent of the deleted branch and
+rebased onto it (without the commits of the deleted branch), and the rest of
+the stack is restacked on top of them. Nothing is pushed; use "av stack sync"
+or "av stack submit" afterwards to update the pull requests.
+
+With --remote, the branch is deleted on the remote as well (which closes its
+pull request), and the pull requests of its children are retargeted onto the
+parent branch first.
+
+If there are conflicts while rebasing a child, resolve them, continue with
+"av stack sync --continue", and then run the command again to finish the
+deletion.`,
+	SilenceUsage: true,
+	Args:         cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.WriteTx()
+		defer func() { tx.Abort() }()
+
+		state, err := actions.ReadStackSyncState(repo)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if state.CurrentBranch != "" {
+			return errors.New("a sync is in progress: use av stack sync --continue or --abort")
+		}
+		if err := ensureCleanWorkingTreeForSync(repo, false, false); err != nil {
+			return err
+		}
+
+		var name string
+		if len(args) > 0 {
+			name = args[0]
+		} else if name, err = getCurrentBranchName(repo, db); err != nil {
+			return err
+		}
+		branch, ok := tx.Branch(name)
+		if !ok {
+			return errors.Errorf("branch %q is not tracked by av", name)
+		}
+		if err := ensureStacksNotFrozen(tx, name); err != nil {
+			return err
+		}
+		merged := branch.MergeCommit != "" ||
+			(branch.PullRequest != nil && branch.PullRequest.State == githubv4.PullRequestStateMerged)
+		if !stackDeleteFlags.Force && !merged {
+			commits, err := actions.BranchCommits(repo, tx, name)
+			if err != nil {
+				return err
+			}
+			if len(commits) > 0 {
+				return errors.Errorf(
+					"branch %q has %d commit(s) that aren't merged (use --force to delete it anyway)",
+					name, len(commits),
+				)
+			}
+		}
+		// GitHub is only needed to delete the branch on the remote, so make
+		// sure that it can be updated before anything is changed locally.
+		var client *gh.Client
+		if stackDeleteFlags.Remote {
+			if client, err = getGitHubClient(); err != nil {
+				return err
+			}
+			if err := ensureGitHubWriteAccess(ctx, client, tx); err != nil {
+				return err
+			}
+		}
+		if err := snapshotBranches(repo, tx, name); err != nil {
+			return err
+		}
+
+		// The branch that is checked out at the end: if the deleted branch is
+		// checked out, its parent takes its place.
+		originalBranch, _ := repo.CurrentBranchName()
+		if originalBranch == name {
+			originalBranch = branch.Parent.Name
+		}
+		parent := branch.Parent
+		children := meta.ChildrenNames(tx, name)
+		for _, child := range children {
+			res, err := actions.Reparent(repo, tx, actions.ReparentOpts{
+				Branch:         child,
+				NewParent:      parent.Name,
+				NewParentTrunk: parent.Trunk,
+			})
+			if err != nil {
+				return err
+			}
+			if !res.Success {
+				// av stack sync --continue finishes the reparent of the child
+				// and restacks its subtree. The branch itself is only deleted
+				// once all of its children are moved.
+				state = actions.StackSyncState{
+					OriginalBranch: originalBranch,
+					CurrentBranch:  child,
+					Branches:       append([]string{child}, meta.SubsequentBranches(tx, child)...),
+					Config: actions.StackSyncConfig{
+						Parent:  parent.Name,
+						Trunk:   parent.Trunk,
+						NoFetch: true,
+						NoPush:  true,
+					},
+				}
+				if err := actions.WriteStackSyncState(repo, &state); err != nil {
+					return errors.Wrap(err, "failed to write stack sync state")
+				}
+				_, _ = fmt.Fprint(os.Stderr,
+					"Failed to move ", colors.UserInput(child), " onto ", colors.UserInput(parent.Name),
+					": resolve the conflicts, continue with ", colors.CliCmd("av stack sync --continue"),
+					",\nand then run ", colors.CliCmd("av stack delete ", name),
+					" again to delete the branch.\n",
+				)
+				hint := stringutils.RemoveLines(res.Hint, "hint: ")
+				_, _ = fmt.Fprint(os.Stderr,
+					"hint:\n",
+					text.Indent(hint, "    "),
+					"\n",
+				)
+				return tx.Commit()
+			}
+			// Restack the subtree of the child right away so that it's
+			// recorded even if moving one of the next children runs into a
+			// conflict.
+			state = actions.StackSyncState{OriginalBranch: originalBranch}
+			err = actions.SyncStack(
+				ctx, repo, client, tx, meta.SubsequentBranches(tx, child), state, actions.WithLocalOnly(),
+			)
+			if errors.As(err, &actions.ErrExitSilently{}) {
+				_, _ = fmt.Fprint(os.Stderr,
+					"Once the sync is done, run ", colors.CliCmd("av stack delete ", name),
+					" again to delete the branch.\n",
+				)
+			}
+			if err != nil {
+				return err
+			}
+			tx = db.WriteTx()
+		}
+
+		if current, _ := repo.CurrentBranchName(); current == name {
+			if _, err := repo.CheckoutBranch(&git.CheckoutBranch{Name: parent.Name}); err != nil {
+				return err
+			}
+		}
+		if _, err := repo.Git("branch", "--delete", "--force", name); err != nil {
+			return errors.WrapIff(err, "failed to delete branch %q", name)
+		}
+		tx.DeleteBranch(name)
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprint(os.Stderr, "Deleted branch ", colors.UserInput(name), "\n")
+		if _, err := repo.CheckoutBranch(&git.CheckoutBranch{Name: originalBranch}); err != nil {
+			return err
+		}
+
+		if stackDeleteFlags.Remote {
+			if err := deleteRemoteBranch(ctx, repo, client, db.ReadTx(), name, children); err != nil {
+				_, _ = fmt.Fprint(os.Stderr,
+					colors.Faint("  - the branch was deleted locally; once the problem is fixed, run "),
+					colors.CliCmd("av pr restack"), colors.Faint(" and "),
+					colors.CliCmd("git push origin --delete ", name),
+					colors.Faint(" to delete it on the remote\n"),
+				)
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// deleteRemoteBranch deletes a branch on the remote after retargeting the pull
+// requests of its former children onto their new parent (GitHub would close
+// them otherwise).
+func deleteRemoteBranch(
+	ctx context.Context,
+	repo *git.Repo,
+	client *gh.Client,
+	tx meta.ReadTx,
+	name string,
+	children []string,
+) error {
+	for _, childName := range children {
+		child, _ := tx.Branch(childName)
+		if child.PullRequest == nil || child.PullRequest.State != githubv4.PullRequestStateOpen {
+			continue
+		}
+		if _, err := client.UpdatePullRequest(ctx, githubv4.UpdatePullRequestInput{
+			PullRequestID: githubv4.ID(child.PullRequest.ID),
+			BaseRefName:   gh.Ptr(githubv4.String(child.Parent.Name)),
+		}); err != nil {
+			return err
+		}
+	}
+	if exists, err := repo.DoesRemoteBranchExist(name); err != nil {
+		return err
+	} else if !exists {
+		return nil
+	}
+	if _, err := repo.Run(&git.RunOpts{
+		Args:      []string{"push", "origin", "--delete", name},
+		ExitError: true,
+	}); err != nil {
+		return errors.WrapIff(err, "failed to delete branch %q on the remote", name)
+	}
+	_, _ = fmt.Fprint(os.Stderr, "  - deleted ", colors.UserInput("origin/", name), "\n")
+	return nil
+}
+
+func init() {
+	stackDeleteCmd.Flags().BoolVar(
+		&stackDeleteFlags.Remote, "remote", false,
+		"also delete the branch on the remote",
+	)
+	stackDeleteCmd.Flags().BoolVar(
+		&stackDeleteFlags.Force, "force", false,
+		"delete the branch even if its commits aren't merged",
+	)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/editor"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/spf13/cobra"
+)
+
+var stackDescribeFlags struct {
+	// If true, edit the description in the editor.
+	Edit bool
+	// If true, remove the description.
+	Clear bool
+}
+
+var stackDescribeCmd = &cobra.Command{
+	Use:   "describe [flags] [<description>]",
+	Short: "show or set the description of the current stack",
+	Long: `Show or set a free-form description of the current stack.
+
+The description is stored in the av metadata of the root branch of the stack.
+It's shown in av stack tree and added to the top of the pull request of the
+root branch the next time the pull request is updated (e.g., by av stack sync or
+av pr create).
+
+Without any arguments, the current description is printed. With --edit, the
+description is opened in your editor.`,
+	SilenceUsage: true,
+	Args:         cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if stackDescribeFlags.Clear && (len(args) > 0 || stackDescribeFlags.Edit) {
+			return errors.New("--clear cannot be used with a description or --edit")
+		}
+		if stackDescribeFlags.Edit && len(args) > 0 {
+			return errors.New("--edit cannot be used with a description")
+		}
+
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.WriteTx()
+		defer tx.Abort()
+
+		currentBranch, err := getCurrentBranchName(repo, db)
+		if err != nil {
+			return err
+		}
+		rootName, ok := meta.Root(tx, currentBranch)
+		if !ok {
+			return errors.Errorf("branch %q is not in a stack", currentBranch)
+		}
+		root, _ := tx.Branch(rootName)
+
+		var description string
+		switch {
+		case stackDescribeFlags.Clear:
+		case stackDescribeFlags.Edit:
+			text := root.StackDescription + "\n\n" +
+				"%% Describe the stack whose root is " + rootName + ".\n" +
+				"%% Lines starting with '%%' are ignored and an empty description removes it.\n"
+			description, err = editor.Launch(repo, editor.Config{
+				Text:           text,
+				TmpFilePattern: "stack-description-*.av.md",
+				CommentPrefix:  "%%",
+			})
+			if err != nil {
+				return errors.WrapIf(err, "text editor failed")
+			}
+		case len(args) == 1:
+			description = args[0]
+		default:
+			if root.StackDescription == "" {
+				_, _ = fmt.Fprint(os.Stderr,
+					"The stack of ", colors.UserInput(rootName), " has no description.\n",
+					colors.Faint("  - Use "), colors.CliCmd("av stack describe <description>"),
+					colors.Faint(" to add one.\n"),
+				)
+				return nil
+			}
+			fmt.Println(root.StackDescription)
+			return nil
+		}
+
+		root.StackDescription = strings.TrimSpace(description)
+		tx.SetBranch(root)
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		if root.StackDescription == "" {
+			_, _ = fmt.Fprint(os.Stderr, "Removed the description of the stack of ", colors.UserInput(rootName), "\n")
+		} else {
+			_, _ = fmt.Fprint(os.Stderr, "Updated the description of the stack of ", colors.UserInput(rootName), "\n")
+		}
+		return nil
+	},
+}
+
+func init() {
+	stackDescribeCmd.Flags().BoolVar(
+		&stackDescribeFlags.Edit, "edit", false,
+		"edit the description in your editor",
+	)
+	stackDescribeCmd.Flags().BoolVar(
+		&stackDescribeFlags.Clear, "clear", false,
+		"remove the description",
+	)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/editor"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/shurcooL/githubv4"
+	"github.com/spf13/cobra"
+)
+
+var stackDescribeBranchFlags struct {
+	// If true, edit the description in the editor.
+	Edit bool
+	// If true, remove the description.
+	Clear bool
+	// If true, don't update the description of the pull request of the branch.
+	NoPR bool
+}
+
+var stackDescribeBranchCmd = &cobra.Command{
+	Use:   "describe-branch [flags] [<description>]",
+	Short: "show or set the description of the current branch",
+	Long: `Show or set the description of the current branch.
+
+The description is stored in the branch.<name>.description Git config (the same
+one that git branch --edit-description sets), so it stays with the branch
+locally. It's shown in av stack tree and used as the description of the pull
+request when one is created for the branch. If the branch already has an open
+pull request, its description is updated as well (unless --no-pr is given).
+Removing the description of the branch removes the description of the pull
+request too, unless it was changed on GitHub since. If GitHub can't be reached,
+the update of the pull request is queued until av push --pending is run.
+
+Without any arguments, the current description is printed. With --edit, the
+description is opened in your editor.`,
+	SilenceUsage: true,
+	Args:         cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if stackDescribeBranchFlags.Clear && (len(args) > 0 || stackDescribeBranchFlags.Edit) {
+			return errors.New("--clear cannot be used with a description or --edit")
+		}
+		if stackDescribeBranchFlags.Edit && len(args) > 0 {
+			return errors.New("--edit cannot be used with a description")
+		}
+
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.ReadTx()
+		currentBranch, err := getCurrentBranchName(repo, db)
+		if err != nil {
+			return err
+		}
+		current, err := repo.BranchDescription(currentBranch)
+		if err != nil {
+			return err
+		}
+
+		var description string
+		switch {
+		case stackDescribeBranchFlags.Clear:
+		case stackDescribeBranchFlags.Edit:
+			text := current + "\n\n" +
+				"%% Describe the branch " + currentBranch + ".\n" +
+				"%% Lines starting with '%%' are ignored and an empty description removes it.\n"
+			description, err = editor.Launch(repo, editor.Config{
+				Text:           text,
+				TmpFilePattern: "branch-description-*.av.md",
+				CommentPrefix:  "%%",
+			})
+			if err != nil {
+				return errors.WrapIf(err, "text editor failed")
+			}
+		case len(args) == 1:
+			description = args[0]
+		default:
+			if current == "" {
+				_, _ = fmt.Fprint(os.Stderr,
+					"Branch ", colors.UserInput(currentBranch), " has no description.\n",
+					colors.Faint("  - Use "), colors.CliCmd("av stack describe-branch <description>"),
+					colors.Faint(" to add one.\n"),
+				)
+				return nil
+			}
+			fmt.Println(current)
+			return nil
+		}
+
+		description = strings.TrimSpace(description)
+		if err := repo.SetBranchDescription(currentBranch, description); err != nil {
+			return err
+		}
+		if description == "" {
+			_, _ = fmt.Fprint(os.Stderr, "Removed the description of branch ", colors.UserInput(currentBranch), "\n")
+		} else {
+			_, _ = fmt.Fprint(os.Stderr, "Updated the description of branch ", colors.UserInput(currentBranch), "\n")
+		}
+
+		branch, _ := tx.Branch(currentBranch)
+		if stackDescribeBranchFlags.NoPR || branch.ReadOnly ||
+			branch.PullRequest == nil || branch.PullRequest.ID == "" {
+			return nil
+		}
+		client, err := getGitHubClient()
+		if err != nil {
+			return err
+		}
+		err = updateBranchDescriptionPR(context.Background(), client, tx, branch, current, description)
+		if actions.IsOffline(err) {
+			if err := actions.QueuePullRequestBody(repo, currentBranch, description); err != nil {
+				return err
+			}
+			printQueuedOffline("the description of the pull request")
+			return nil
+		}
+		return err
+	},
+}
+
+// updateBranchDescriptionPR replaces the part of the description of the pull
+// request of the branch that was written by people with the description of the
+// branch (see av pr body). If the description of the branch was removed, the
+// pull request keeps its description unless it's the previous description of
+// the branch.
+func updateBranchDescriptionPR(
+	ctx context.Context,
+	client *gh.Client,
+	tx meta.ReadTx,
+	branch meta.Branch,
+	previous string,
+	description string,
+) error {
+	if err := ensureGitHubWriteAccess(ctx, client, tx); err != nil {
+		return err
+	}
+	pr, err := client.PullRequest(ctx, branch.PullRequest.ID)
+	if err != nil {
+		return err
+	}
+	content := actions.PRBodyContent(pr.Body)
+	if pr.State != githubv4.PullRequestStateOpen || actions.PRBodyEqual(content, description) {
+		return nil
+	}
+	if description == "" && !actions.PRBodyEqual(content, previous) {
+		_, _ = fmt.Fprint(os.Stderr,
+			"  - kept the description of pull request ", colors.UserInput("#", pr.Number),
+			" since it was changed on GitHub\n",
+		)
+		return nil
+	}
+	if _, err := client.UpdatePullRequest(ctx, githubv4.UpdatePullRequestInput{
+		PullRequestID: githubv4.ID(pr.ID),
+		Body:          gh.Ptr(githubv4.String(actions.ReplacePRBodyContent(pr.Body, description))),
+	}); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprint(os.Stderr,
+		"Updated the description of pull request ", colors.UserInput("#", pr.Number), ": ", pr.Permalink, "\n",
+	)
+	return nil
+}
+
+func init() {
+	stackDescribeBranchCmd.Flags().BoolVar(
+		&stackDescribeBranchFlags.Edit, "edit", false,
+		"edit the description in your editor",
+	)
+	stackDescribeBranchCmd.Flags().BoolVar(
+		&stackDescribeBranchFlags.Clear, "clear", false,
+		"remove the description",
+	)
+	stackDescribeBranchCmd.Flags().BoolVar(
+		&stackDescribeBranchFlags.NoPR, "no-pr", false,
+		"don't update the description of the pull request of the branch",
+	)
+}
@@ -31,7 +31,7 @@ Generates the diff between the working tree and the parent branch
 			return err
 		}
 
-		currentBranchName, err := repo.CurrentBranchName()
+		currentBranchName, err := getCurrentBranchName(repo, db)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/shurcooL/githubv4"
+	"github.com/spf13/cobra"
+)
+
+var stackFoldFlags struct {
+	// If true, close the pull request of the folded branch.
+	ClosePR bool
+}
+
+var stackFoldCmd = &cobra.Command{
+	Use:   "fold [--close-pr]",
+	Short: "fold the current branch into its parent",
+	Long: `Fold the current branch into its parent branch.
+
+The parent branch is fast-forwarded to include the commits of the current
+branch, the children of the current branch are moved onto the parent, and the
+current branch is deleted. The branch has to be synced with its parent first
+(see "av stack sync").
+
+The parent branch isn't pushed: use "av stack sync" or "av stack submit" to
+update its pull request.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.WriteTx()
+		defer tx.Abort()
+
+		currentBranch, err := getCurrentBranchName(repo, db)
+		if err != nil {
+			return err
+		}
+		branch, ok := tx.Branch(currentBranch)
+		if !ok {
+			return errors.Errorf("branch %q is not tracked by av", currentBranch)
+		}
+		if err := ensureNotReadOnly(tx, currentBranch, branch.Parent.Name); err != nil {
+			return err
+		}
+		if err := ensureStacksNotFrozen(tx, currentBranch); err != nil {
+			return err
+		}
+		parent := branch.Parent.Name
+		if err := snapshotBranches(repo, tx, currentBranch, parent); err != nil {
+			return err
+		}
+		if err := actions.FoldBranch(repo, tx, currentBranch); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprint(os.Stderr,
+			"Folded branch ", colors.UserInput(currentBranch),
+			" into ", colors.UserInput(parent), "\n",
+		)
+
+		if branch.PullRequest != nil && branch.PullRequest.State == githubv4.PullRequestStateOpen {
+			if stackFoldFlags.ClosePR {
+				if err := closeFoldedPullRequest(branch.PullRequest.ID, parent); err != nil {
+					return err
+				}
+				_, _ = fmt.Fprint(os.Stderr,
+					"  - closed pull request ", colors.UserInput("#", branch.PullRequest.Number), "\n",
+				)
+			} else {
+				_, _ = fmt.Fprint(os.Stderr,
+					colors.Faint("  - The pull request "), colors.UserInput("#", branch.PullRequest.Number),
+					colors.Faint(" of the folded branch is still open (use "),
+					colors.CliCmd("--close-pr"), colors.Faint(" to close it).\n"),
+				)
+			}
+		}
+		_, _ = fmt.Fprint(os.Stderr,
+			colors.Faint("  - Use "), colors.CliCmd("av stack sync"),
+			colors.Faint(" to push "), colors.UserInput(parent),
+			colors.Faint(" and update its pull request.\n"),
+		)
+		return nil
+	},
+}
+
+// closeFoldedPullRequest closes the pull request of a branch that was folded
+// into the given parent branch (with a comment that says where its commits
+// went).
+func closeFoldedPullRequest(id string, parent string) error {
+	ctx := context.Background()
+	client, err := getGitHubClient()
+	if err != nil {
+		return err
+	}
+	if err := client.AddComment(
+		ctx, id, fmt.Sprintf("The commits of this pull request were folded into the branch `%s`.", parent),
+	); err != nil {
+		return err
+	}
+	if _, err := client.ClosePullRequest(ctx, id); err != nil {
+		return err
+	}
+	return nil
+}
+
+func init() {
+	stackFoldCmd.Flags().BoolVar(
+		&stackFoldFlags.ClosePR, "close-pr", false,
+		"close the pull request of the folded branch",
+	)
+}
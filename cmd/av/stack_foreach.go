@@ -44,6 +44,11 @@ Examples:
 		if err != nil {
 			return err
 		}
+
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+
 		db, err := getDB(repo)
 		if err != nil {
 			return err
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/spf13/cobra"
+)
+
+var stackFreezeFlags struct {
+	// Why the stack is frozen.
+	Reason string
+}
+
+var stackFreezeCmd = &cobra.Command{
+	Use:   "freeze [--reason=<reason>]",
+	Short: "freeze the current stack to prevent its history from being rewritten",
+	Long: `Freeze the current stack to prevent its history from being rewritten.
+
+While a stack is frozen, av refuses to run commands that rewrite the history of
+its branches (e.g., av stack sync, av stack reorder, av commit amend, and
+av commit split), which is useful while the stack is being reviewed or is queued
+to be merged. The errors say who froze the stack and why.
+
+Use av stack unfreeze to unfreeze the stack.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.WriteTx()
+		defer tx.Abort()
+
+		root, err := currentStackRoot(repo, db, tx)
+		if err != nil {
+			return err
+		}
+		if root.Freeze != nil {
+			_, _ = fmt.Fprint(os.Stderr, "The stack of ", colors.UserInput(root.Name), " is already frozen.\n")
+			printStackFreeze(root.Freeze)
+			return nil
+		}
+
+		root.Freeze = &meta.FreezeInfo{
+			By:     gitIdentity(repo),
+			Time:   time.Now(),
+			Reason: strings.TrimSpace(stackFreezeFlags.Reason),
+		}
+		tx.SetBranch(root)
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprint(os.Stderr,
+			"Froze the stack of ", colors.UserInput(root.Name), "\n",
+			colors.Faint("  - Use "), colors.CliCmd("av stack unfreeze"),
+			colors.Faint(" to allow rewriting its history again.\n"),
+		)
+		return nil
+	},
+}
+
+var stackUnfreezeCmd = &cobra.Command{
+	Use:          "unfreeze",
+	Short:        "unfreeze the current stack",
+	Long:         `Unfreeze the current stack that was frozen with "av stack freeze".`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.WriteTx()
+		defer tx.Abort()
+
+		root, err := currentStackRoot(repo, db, tx)
+		if err != nil {
+			return err
+		}
+		if root.Freeze == nil {
+			_, _ = fmt.Fprint(os.Stderr, "The stack of ", colors.UserInput(root.Name), " is not frozen.\n")
+			return nil
+		}
+
+		root.Freeze = nil
+		tx.SetBranch(root)
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprint(os.Stderr, "Unfroze the stack of ", colors.UserInput(root.Name), "\n")
+		return nil
+	},
+}
+
+func init() {
+	stackFreezeCmd.Flags().StringVar(
+		&stackFreezeFlags.Reason, "reason", "",
+		"why the stack is frozen (shown when somebody tries to rewrite it)",
+	)
+}
+
+// currentStackRoot returns the root branch of the stack of the current branch.
+func currentStackRoot(repo *git.Repo, db meta.DB, tx meta.ReadTx) (meta.Branch, error) {
+	currentBranch, err := getCurrentBranchName(repo, db)
+	if err != nil {
+		return meta.Branch{}, err
+	}
+	rootName, ok := meta.Root(tx, currentBranch)
+	if !ok {
+		return meta.Branch{}, errors.Errorf("branch %q is not in a stack", currentBranch)
+	}
+	root, _ := tx.Branch(rootName)
+	return root, nil
+}
+
+// gitIdentity returns the Git identity of the user (e.g., "Jane Doe
+// <jane@example.com>"), falling back to the name of the OS user.
+func gitIdentity(repo *git.Repo) string {
+	// The identity is followed by the timestamp and the timezone.
+	ident, err := repo.Git("var", "GIT_COMMITTER_IDENT")
+	if fields := strings.Fields(ident); err == nil && len(fields) > 2 {
+		return strings.Join(fields[:len(fields)-2], " ")
+	}
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "unknown"
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/spf13/cobra"
+)
+
+var stackGotoCmd = &cobra.Command{
+	Use:     "goto <branch>",
+	Aliases: []string{"g"},
+	Short:   "checkout a branch in the current stack",
+	Long: `Checkout a branch in the current stack.
+
+Unlike "git checkout", only branches that belong to the same stack as the
+current branch are accepted (and offered for shell completion). This avoids
+accidentally checking out a similarly named branch from another stack.`,
+	Args: cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		branches, err := currentStackBranches()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		var res []string
+		for _, branch := range branches {
+			if strings.HasPrefix(branch, toComplete) {
+				res = append(res, branch)
+			}
+		}
+		return res, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+
+		branches, err := currentStackBranches()
+		if err != nil {
+			return err
+		}
+
+		branchToCheckout := args[0]
+		if !slices.Contains(branches, branchToCheckout) {
+			_, _ = fmt.Fprint(
+				os.Stderr,
+				colors.Failure("Branch ", branchToCheckout, " is not in the current stack.\n"),
+				colors.Faint("  - Branches in the current stack: ", strings.Join(branches, ", "), "\n"),
+			)
+			return actions.ErrExitSilently{ExitCode: 1}
+		}
+
+		if err := actions.SwitchBranch(repo, &git.CheckoutBranch{
+			Name: branchToCheckout,
+		}); err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			"Checked out branch ",
+			colors.UserInput(branchToCheckout),
+			"\n",
+		)
+		return nil
+	},
+}
+
+// currentStackBranches returns the branches of the stack that the current
+// branch belongs to (excluding the trunk).
+func currentStackBranches() ([]string, error) {
+	repo, err := getRepo()
+	if err != nil {
+		return nil, err
+	}
+	db, err := getDB(repo)
+	if err != nil {
+		return nil, err
+	}
+	currentBranch, err := getCurrentBranchName(repo, db)
+	if err != nil {
+		return nil, err
+	}
+	branches, err := meta.StackBranches(db.ReadTx(), currentBranch)
+	if err != nil {
+		return nil, errors.WrapIff(err, "failed to determine the current stack")
+	}
+	return branches, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/spf13/cobra"
+)
+
+var stackInsertCmd = &cobra.Command{
+	Use:   "insert <branch-name>",
+	Short: "create a new branch between the current branch and its children",
+	Long: `Create a new branch that is stacked on the current branch, and move the
+children of the current branch onto it.
+
+This is useful to add a preparatory change in the middle of a stack. The new
+branch starts at the current branch's HEAD, so the children don't need to be
+rebased until commits are added to it (use "av stack sync" afterwards to rebase
+them and to update the base branches of their pull requests).`,
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		branchName := args[0]
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.WriteTx()
+		defer tx.Abort()
+
+		currentBranch, err := repo.CurrentBranchName()
+		if err != nil {
+			return err
+		}
+		if _, ok := tx.Branch(currentBranch); !ok {
+			return errors.Errorf(
+				"branch %q is not tracked by av (use av stack branch to start a new stack)", currentBranch,
+			)
+		}
+		if exists, err := repo.DoesBranchExist(branchName); err != nil {
+			return err
+		} else if exists {
+			return errors.Errorf("branch %q already exists", branchName)
+		}
+		if err := ensureNoBranchCaseCollision(repo, branchName); err != nil {
+			return err
+		}
+		if err := ensureStacksNotFrozen(tx, currentBranch); err != nil {
+			return err
+		}
+
+		head, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + currentBranch})
+		if err != nil {
+			return errors.WrapIff(err, "failed to determine head commit of branch %q", currentBranch)
+		}
+		if err := actions.SwitchBranch(repo, &git.CheckoutBranch{
+			Name:      branchName,
+			NewBranch: true,
+		}); err != nil {
+			return errors.WrapIff(err, "checkout error")
+		}
+
+		children := meta.Children(tx, currentBranch)
+		tx.SetBranch(meta.Branch{
+			Name:   branchName,
+			Parent: meta.BranchState{Name: currentBranch, Head: head},
+		})
+		// The children keep the commit they're based on, which is part of the
+		// history of the new branch as well.
+		for _, child := range children {
+			child.Parent.Name = branchName
+			tx.SetBranch(child)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprint(os.Stderr,
+			"Created branch ", colors.UserInput(branchName), " on top of ", colors.UserInput(currentBranch), "\n",
+		)
+		for _, child := range children {
+			_, _ = fmt.Fprint(os.Stderr,
+				"  - moved ", colors.UserInput(child.Name), " onto ", colors.UserInput(branchName), "\n",
+			)
+		}
+		if len(children) > 0 {
+			_, _ = fmt.Fprint(os.Stderr,
+				colors.Faint("  - Use "), colors.CliCmd("av stack sync"),
+				colors.Faint(" after committing to rebase the children onto the new branch.\n"),
+			)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var stackLandFlags struct {
+	// If true, keep watching the stack and merge each pull request as soon as
+	// it becomes mergeable.
+	Auto bool
+	// The merge method to use ("merge", "squash", or "rebase").
+	Method string
+	// How long to wait for the stack to land (with Auto).
+	Timeout time.Duration
+	// How often to poll GitHub for the state of the pull requests (with Auto).
+	Interval time.Duration
+	// If true, only show where each pull request is in the landing pipeline.
+	Status bool
+	landScope
+}
+
+// landScope restricts which pull requests of the stack are landed.
+type landScope struct {
+	// If set, only land the pull requests up to (and including) this branch.
+	Until string
+	// If set, only land the pull requests of this branch, its ancestors, and
+	// its descendants (leaving the sibling subtrees open).
+	Subtree string
+}
+
+var stackLandCmd = &cobra.Command{
+	Use:   "land [flags]",
+	Short: "merge the pull requests of the stack",
+	Long: `Merge the pull requests of the current stack, starting from the bottom.
+
+Each pull request is merged once it's mergeable (approved, all checks passed,
+and its parent merged) and meets the branch protection rules of the trunk
+(required checks and reviews, linear history, signed commits, and resolved
+conversations). Every unmet requirement is reported. After each merge, the
+rest of the stack is synced onto the trunk (which also retargets the pull
+requests).
+
+Without --auto, this stops at the first pull request that can't be merged yet.
+With --auto, this keeps watching the stack until all pull requests have landed
+(or one of them can't be merged without intervention).
+
+With --until, only the pull requests up to (and including) the given branch are
+merged. The branches above it are synced onto the trunk and left open.
+
+With --subtree, only the pull requests of the given branch, its ancestors, and
+its descendants are merged. The sibling subtrees are synced onto the trunk and
+left open.
+
+With --status, nothing is merged. Instead, this shows the landing order of the
+pull requests, their review, check, and MergeQueue state (and position in the
+queue), and which pull request is currently holding up the rest of the stack.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		method, err := parseMergeMethod(stackLandFlags.Method)
+		if err != nil {
+			return err
+		}
+
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		currentBranchName, err := getCurrentBranchName(repo, db)
+		if err != nil {
+			return err
+		}
+		for _, name := range []string{stackLandFlags.Until, stackLandFlags.Subtree} {
+			if name == "" {
+				continue
+			}
+			if err := validateLandBranch(db.ReadTx(), currentBranchName, name); err != nil {
+				return err
+			}
+		}
+		if stackLandFlags.Subtree != "" {
+			// The stack is synced from the current branch after each merge, so
+			// it has to be in the subtree for the subtree to be restacked.
+			subtree := append(
+				[]string{stackLandFlags.Subtree},
+				meta.SubsequentBranches(db.ReadTx(), stackLandFlags.Subtree)...,
+			)
+			if !slices.Contains(subtree, currentBranchName) {
+				return errors.Errorf(
+					"the current branch must be %q or one of its descendants (check out the top of the subtree)",
+					stackLandFlags.Subtree,
+				)
+			}
+		}
+		client, err := getGitHubClient()
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		if stackLandFlags.Status {
+			return showLandStatus(ctx, db, client, currentBranchName, stackLandFlags.landScope)
+		}
+		deadline := time.Now().Add(stackLandFlags.Timeout)
+		var lastReason string
+		for {
+			// The sync modifies the metadata, so it has to be re-read every
+			// time.
+			db, err := getDB(repo)
+			if err != nil {
+				return err
+			}
+			branch, ok, err := nextBranchToLand(db.ReadTx(), currentBranchName, stackLandFlags.landScope)
+			if err != nil {
+				return err
+			}
+			if !ok && stackLandFlags.Until != "" {
+				_, _ = fmt.Fprint(os.Stderr, colors.Success(
+					"All pull requests up to ", stackLandFlags.Until, " have landed.\n",
+				))
+				return nil
+			} else if !ok && stackLandFlags.Subtree != "" {
+				_, _ = fmt.Fprint(os.Stderr, colors.Success(
+					"All pull requests of the subtree of ", stackLandFlags.Subtree, " have landed.\n",
+				))
+				return nil
+			} else if !ok {
+				_, _ = fmt.Fprint(os.Stderr, colors.Success("All pull requests in the stack have landed.\n"))
+				return nil
+			}
+			if branch.PullRequest == nil || branch.PullRequest.ID == "" {
+				return errors.Errorf(
+					"branch %q has no associated pull request (run `av pr create` to create one)",
+					branch.Name,
+				)
+			}
+
+			pr, err := client.PullRequest(ctx, branch.PullRequest.ID)
+			if err != nil {
+				return err
+			}
+			if pr.State == githubv4.PullRequestStateMerged {
+				// Merged outside of av, so the stack just needs to catch up.
+				if err := recordLandedPullRequest(db, branch.Name, pr); err != nil {
+					return err
+				}
+				if err := syncLandedStack(repo, "av stack land"); err != nil {
+					return err
+				}
+				continue
+			}
+			checks, err := client.PullRequestChecks(ctx, pr.ID)
+			if err != nil {
+				return err
+			}
+
+			readiness := actions.CheckLandReadiness(branch, pr, checks)
+			unmet := checkBranchProtection(ctx, client, branch, pr, checks, method)
+			if readiness.Ready && len(unmet) > 0 {
+				readiness = actions.LandReadiness{
+					Reason: "the branch protection rules of " + pr.BaseBranchName() + " aren't met",
+				}
+				for _, req := range unmet {
+					readiness.Blocked = readiness.Blocked || req.Blocked
+				}
+			}
+			if readiness.Ready {
+				merged, err := mergeLandedPullRequest(ctx, client, branch, pr, method)
+				if err != nil {
+					return err
+				}
+				if err := recordLandedPullRequest(db, branch.Name, merged); err != nil {
+					return err
+				}
+				if err := syncLandedStack(repo, "av stack land"); err != nil {
+					return err
+				}
+				lastReason = ""
+				continue
+			}
+
+			if readiness.Reason != lastReason {
+				_, _ = fmt.Fprint(
+					os.Stderr,
+					"Pull request #", pr.Number, " (", colors.UserInput(branch.Name), ") can't be merged yet: ",
+					readiness.Reason, "\n",
+				)
+				for _, req := range unmet {
+					_, _ = fmt.Fprint(os.Stderr, "  - ", req.Description, "\n")
+				}
+				lastReason = readiness.Reason
+			}
+			if readiness.Blocked || !stackLandFlags.Auto {
+				return actions.ErrExitSilently{ExitCode: 1}
+			}
+			if time.Now().Add(stackLandFlags.Interval).After(deadline) {
+				_, _ = fmt.Fprint(
+					os.Stderr,
+					colors.Failure("Timed out after ", stackLandFlags.Timeout, " waiting for the stack to land.\n"),
+				)
+				return actions.ErrExitSilently{ExitCode: 1}
+			}
+			time.Sleep(stackLandFlags.Interval)
+		}
+	},
+}
+
+func init() {
+	stackLandCmd.Flags().BoolVar(
+		&stackLandFlags.Auto, "auto", false,
+		"keep watching the stack and merge each pull request as soon as it's mergeable",
+	)
+	stackLandCmd.Flags().StringVar(
+		&stackLandFlags.Method, "method", "squash",
+		"the merge method to use (merge, squash, or rebase)",
+	)
+	stackLandCmd.Flags().DurationVar(
+		&stackLandFlags.Timeout, "timeout", 3*time.Hour,
+		"how long to wait for the stack to land (with --auto)",
+	)
+	stackLandCmd.Flags().DurationVar(
+		&stackLandFlags.Interval, "interval", 30*time.Second,
+		"how often to poll GitHub for the state of the pull requests (with --auto)",
+	)
+	stackLandCmd.Flags().BoolVar(
+		&stackLandFlags.Status, "status", false,
+		"show where each pull request is in the landing pipeline instead of merging",
+	)
+	stackLandCmd.Flags().StringVar(
+		&stackLandFlags.Until, "until", "",
+		"only land the pull requests up to (and including) this branch",
+	)
+	stackLandCmd.Flags().StringVar(
+		&stackLandFlags.Subtree, "subtree", "",
+		"only land the pull requests of this branch, its ancestors, and its descendants",
+	)
+	stackLandCmd.MarkFlagsMutuallyExclusive("status", "auto")
+	stackLandCmd.MarkFlagsMutuallyExclusive("until", "subtree")
+}
+
+// parseMergeMethod parses the value of a --method flag.
+func parseMergeMethod(name string) (githubv4.PullRequestMergeMethod, error) {
+	switch strings.ToLower(name) {
+	case "merge":
+		return githubv4.PullRequestMergeMethodMerge, nil
+	case "squash":
+		return githubv4.PullRequestMergeMethodSquash, nil
+	case "rebase":
+		return githubv4.PullRequestMergeMethodRebase, nil
+	}
+	return "", errors.Errorf("invalid merge method %q (expected merge, squash, or rebase)", name)
+}
+
+// validateLandBranch makes sure that the branch given to --until or --subtree
+// belongs to the stack of the current branch.
+func validateLandBranch(tx meta.ReadTx, currentBranchName string, name string) error {
+	if _, ok := tx.Branch(name); !ok {
+		return errors.Errorf("branch %q is not tracked by av", name)
+	}
+	branches, err := meta.StackBranches(tx, currentBranchName)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(branches, name) {
+		return errors.Errorf("branch %q is not in the stack of %q", name, currentBranchName)
+	}
+	return nil
+}
+
+// landBranches returns the branches of the stack in the order they would land,
+// restricted to the given scope.
+func landBranches(tx meta.ReadTx, branchName string, scope landScope) ([]string, error) {
+	name := scope.Until
+	if name == "" {
+		name = scope.Subtree
+	}
+	if name == "" {
+		return meta.StackBranches(tx, branchName)
+	}
+	previous, err := meta.PreviousBranches(tx, name)
+	if err != nil {
+		return nil, err
+	}
+	branches := append(previous, name)
+	if scope.Subtree != "" {
+		branches = append(branches, meta.SubsequentBranches(tx, name)...)
+	}
+	return branches, nil
+}
+
+// nextBranchToLand returns the bottom-most branch of the stack (within the
+// given scope) that hasn't been merged yet. False is returned if all branches
+// have been merged.
+func nextBranchToLand(tx meta.ReadTx, branchName string, scope landScope) (meta.Branch, bool, error) {
+	branches, err := landBranches(tx, branchName, scope)
+	if err != nil {
+		return meta.Branch{}, false, err
+	}
+	for _, name := range branches {
+		branch, _ := tx.Branch(name)
+		if branch.MergeCommit != "" ||
+			(branch.PullRequest != nil && branch.PullRequest.State == githubv4.PullRequestStateMerged) {
+			continue
+		}
+		return branch, true, nil
+	}
+	return meta.Branch{}, false, nil
+}
+
+// checkBranchProtection returns the requirements of the branch protection
+// rules of the trunk that the pull request doesn't meet yet. The rules only
+// apply once the pull request targets the trunk. Since GitHub enforces the rules
+// anyway, failing to read them isn't fatal.
+func checkBranchProtection(
+	ctx context.Context,
+	client *gh.Client,
+	branch meta.Branch,
+	pr *gh.PullRequest,
+	checks *gh.PullRequestChecks,
+	method githubv4.PullRequestMergeMethod,
+) []actions.UnmetRequirement {
+	if !branch.Parent.Trunk || pr.BaseBranchName() != branch.Parent.Name {
+		return nil
+	}
+	state, err := client.PullRequestProtection(ctx, pr.ID)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to check the branch protection rules")
+		return nil
+	}
+	return actions.CheckBranchProtection(pr, checks, state, method)
+}
+
+func mergeLandedPullRequest(
+	ctx context.Context,
+	client *gh.Client,
+	branch meta.Branch,
+	pr *gh.PullRequest,
+	method githubv4.PullRequestMergeMethod,
+) (*gh.PullRequest, error) {
+	_, _ = fmt.Fprint(
+		os.Stderr,
+		"Merging pull request #", pr.Number, " (", colors.UserInput(branch.Name), ")... ",
+	)
+	merged, err := client.MergePullRequest(ctx, githubv4.MergePullRequestInput{
+		PullRequestID: pr.ID,
+		MergeMethod:   &method,
+		// Make sure we merge what we checked.
+		ExpectedHeadOid: gh.Ptr(githubv4.GitObjectID(pr.HeadRefOID)),
+	})
+	if err != nil {
+		_, _ = fmt.Fprint(os.Stderr, colors.Failure("failed"), "\n")
+		return nil, errors.WrapIff(err, "failed to merge pull request #%d", pr.Number)
+	}
+	_, _ = fmt.Fprint(os.Stderr, colors.Success("okay"), "\n")
+	return merged, nil
+}
+
+// recordLandedPullRequest records that the pull request of the branch was
+// merged so that the following sync treats the branch as merged even if GitHub
+// is slow to report it.
+func recordLandedPullRequest(db meta.DB, branchName string, pr *gh.PullRequest) error {
+	tx := db.WriteTx()
+	defer tx.Abort()
+	branch, _ := tx.Branch(branchName)
+	branch.MergeCommit = pr.GetMergeCommit()
+	if branch.PullRequest != nil {
+		branch.PullRequest.State = pr.State
+	}
+	tx.SetBranch(branch)
+	return tx.Commit()
+}
+
+// syncLandedStack syncs the rest of the stack onto the trunk after a pull
+// request has been merged (the same as `av stack sync --trunk`). If the sync
+// stops because of a conflict, the user is told to run resumeCmd afterwards.
+func syncLandedStack(repo *git.Repo, resumeCmd string) error {
+	// The sync fails with ErrExitSilently if it stops because of a conflict.
+	syncErr := runStackSync(actions.StackSyncConfig{Trunk: true})
+	if branch, err := syncConflictBranch(repo); err == nil && branch != "" {
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			colors.Failure("Stopped since the sync needs attention.\n"),
+			colors.Faint("  - Once the sync is done, run "), colors.CliCmd(resumeCmd),
+			colors.Faint(" again to continue.\n"),
+		)
+		return actions.ErrExitSilently{ExitCode: 1}
+	}
+	return syncErr
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/avgql"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/shurcooL/githubv4"
+	"github.com/shurcooL/graphql"
+	"github.com/sirupsen/logrus"
+)
+
+// landStatus is the state of a pull request in the landing pipeline.
+type landStatus struct {
+	Branch meta.Branch
+	Pull   *gh.PullRequest
+	Checks *gh.PullRequestChecks
+	// The MergeQueue status of the pull request (nil if unknown).
+	Queue     *avgql.QueueStatus
+	Readiness actions.LandReadiness
+}
+
+// showLandStatus shows, for each pull request of the stack that hasn't been
+// merged yet (in the order they would land), whether it's ready to be merged
+// and which pull request is holding up the rest of the stack. Only the pull
+// requests within the given scope are shown.
+func showLandStatus(ctx context.Context, db meta.DB, client *gh.Client, branchName string, scope landScope) error {
+	tx := db.ReadTx()
+	names, err := landBranches(tx, branchName, scope)
+	if err != nil {
+		return err
+	}
+
+	// The MergeQueue status is only available with an Aviator API token.
+	var queueClient *graphql.Client
+	if config.Av.Aviator.APIToken != "" {
+		queueClient, err = avgql.NewClient()
+		if err != nil {
+			logrus.WithError(err).Debug("failed to create Aviator API client")
+		}
+	}
+	repository, _ := tx.Repository()
+
+	var unmerged, ids []string
+	for _, name := range names {
+		branch, _ := tx.Branch(name)
+		if branch.MergeCommit != "" ||
+			(branch.PullRequest != nil && branch.PullRequest.State == githubv4.PullRequestStateMerged) {
+			continue
+		}
+		unmerged = append(unmerged, name)
+		if branch.PullRequest != nil && branch.PullRequest.ID != "" {
+			ids = append(ids, branch.PullRequest.ID)
+		}
+	}
+	// Query all of the pull requests (and their checks) at once.
+	prs, err := client.PullRequestsWithStatus(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	var statuses []landStatus
+	for _, name := range unmerged {
+		branch, _ := tx.Branch(name)
+		if branch.PullRequest == nil || branch.PullRequest.ID == "" {
+			// Nothing after this can land until it has a pull request.
+			statuses = append(statuses, landStatus{
+				Branch: branch,
+				Readiness: actions.LandReadiness{
+					Reason:  "the branch has no pull request (run `av pr create` to create one)",
+					Blocked: true,
+				},
+			})
+			continue
+		}
+		pr, checks := prs[0].PullRequest, prs[0].Checks
+		prs = prs[1:]
+		status := landStatus{Branch: branch, Pull: pr, Checks: checks}
+		if len(statuses) == 0 {
+			status.Readiness = actions.CheckLandReadiness(branch, pr, checks)
+		} else {
+			status.Readiness = actions.CheckPullRequestReadiness(pr, checks)
+		}
+		if queueClient != nil {
+			queue, err := avgql.PullRequestQueueStatus(
+				ctx, queueClient, repository.Owner, repository.Name, pr.Number,
+			)
+			if err != nil {
+				// Don't bother with the other pull requests since they would
+				// most likely fail the same way.
+				logrus.WithError(err).Debug("failed to get MergeQueue status")
+				queueClient = nil
+			} else {
+				status.Queue = queue
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	if len(statuses) == 0 {
+		_, _ = fmt.Fprint(os.Stderr, colors.Success("All pull requests in the stack have landed.\n"))
+		return nil
+	}
+	renderLandStatus(os.Stdout, statuses)
+	return nil
+}
+
+// renderLandStatus writes the landing pipeline of the stack to w.
+func renderLandStatus(w io.Writer, statuses []landStatus) {
+	_, _ = fmt.Fprint(w, "Landing order:\n")
+	blocker := -1
+	for i, status := range statuses {
+		if blocker == -1 && !status.Readiness.Ready {
+			blocker = i
+		}
+		_, _ = fmt.Fprint(w, "  ", i+1, ". ")
+		if status.Pull != nil {
+			_, _ = fmt.Fprint(w, "#", status.Pull.Number, " ")
+		}
+		_, _ = fmt.Fprint(w, colors.UserInput(status.Branch.Name))
+		switch {
+		case status.Readiness.Ready && i == 0:
+			_, _ = fmt.Fprint(w, " ", colors.Success("ready to merge"))
+		case status.Readiness.Ready:
+			_, _ = fmt.Fprint(w, " ", colors.Success("ready to merge after the pull requests above"))
+		default:
+			_, _ = fmt.Fprint(w, " ", colors.Faint(status.Readiness.Reason))
+		}
+		_, _ = fmt.Fprint(w, "\n")
+		if status.Pull == nil {
+			continue
+		}
+		details := []string{
+			"review: " + reviewDecisionString(status.Pull.ReviewDecision),
+			"checks: " + checksStateString(status.Checks),
+		}
+		if status.Queue != nil {
+			queue := "not queued"
+			if status.Queue.IsQueued() {
+				queue = strings.ToLower(status.Queue.Status)
+				if status.Queue.Position > 0 {
+					queue += fmt.Sprintf(" at position %d", status.Queue.Position)
+				}
+				if status.Queue.StatusReason != "" {
+					queue += " (" + status.Queue.StatusReason + ")"
+				}
+			}
+			details = append(details, "queue: "+queue)
+		}
+		_, _ = fmt.Fprint(w, "       ", colors.Faint(strings.Join(details, ", ")), "\n")
+	}
+
+	_, _ = fmt.Fprint(w, "\n")
+	if blocker == -1 {
+		_, _ = fmt.Fprint(w,
+			colors.Success("Nothing is blocking the stack"), ": run ", colors.CliCmd("av stack land"),
+			" to merge it.\n",
+		)
+		return
+	}
+	status := statuses[blocker]
+	_, _ = fmt.Fprint(w, colors.Warning("Blocking the stack: "))
+	if status.Pull != nil {
+		_, _ = fmt.Fprint(w, "#", status.Pull.Number, " ")
+	}
+	_, _ = fmt.Fprint(w, colors.UserInput(status.Branch.Name), ": ", status.Readiness.Reason, "\n")
+}
+
+func reviewDecisionString(decision githubv4.PullRequestReviewDecision) string {
+	switch decision {
+	case githubv4.PullRequestReviewDecisionApproved:
+		return "approved"
+	case githubv4.PullRequestReviewDecisionChangesRequested:
+		return "changes requested"
+	case githubv4.PullRequestReviewDecisionReviewRequired:
+		return "review required"
+	default:
+		return "no review required"
+	}
+}
+
+func checksStateString(checks *gh.PullRequestChecks) string {
+	if len(checks.Checks) == 0 {
+		return "none"
+	}
+	switch checks.State() {
+	case gh.CheckStateSuccess:
+		return "passed"
+	case gh.CheckStateFailure:
+		return "failed"
+	default:
+		return "pending"
+	}
+}
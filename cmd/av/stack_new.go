@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/maps"
+)
+
+var stackNewFlags struct {
+	// The name of the stack template (see config.Stack.Templates) to create
+	// the branches from.
+	Template string
+	// If true, don't create (draft) pull requests for the new branches.
+	NoPR bool
+}
+
+var stackNewCmd = &cobra.Command{
+	Use:   "new --template <template> <name>",
+	Short: "create a new stack from a template",
+	Long: `Create all of the branches of a new stack at once from a stack template
+(see stack.templates in the configuration).
+
+Each branch of the template is created on top of the previous one (the first
+one on top of the current branch) with an empty commit that describes it, and
+is named after the given name and the name of the branch in the template (e.g.,
+"login-schema"). Unless --no-pr is given, the branches are pushed and draft pull
+requests are created for them. The first branch is checked out afterwards.`,
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		template, ok := config.Av.Stack.Templates[strings.ToLower(stackNewFlags.Template)]
+		if !ok || len(template) == 0 {
+			names := maps.Keys(config.Av.Stack.Templates)
+			slices.Sort(names)
+			if len(names) == 0 {
+				return errors.Errorf(
+					"stack template %q not found (no templates are configured in stack.templates)",
+					stackNewFlags.Template,
+				)
+			}
+			return errors.Errorf(
+				"stack template %q not found (available templates: %s)",
+				stackNewFlags.Template, strings.Join(names, ", "),
+			)
+		}
+
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.WriteTx()
+		defer tx.Abort()
+
+		parentName, err := getCurrentBranchName(repo, db)
+		if err != nil {
+			return err
+		}
+		defaultBranch, err := repo.DefaultBranch()
+		if err != nil {
+			return errors.WrapIf(err, "failed to determine repository default branch")
+		}
+		if err := validateParentBranch(repo, tx, parentName, defaultBranch); err != nil {
+			return err
+		}
+		parent := meta.BranchState{Name: parentName, Trunk: meta.IsTrunk(tx, parentName, defaultBranch)}
+
+		var branches []string
+		for _, entry := range template {
+			branchName, err := branchNameFromMessageUnique(repo, args[0]+" "+entry.Name)
+			if err != nil {
+				return err
+			}
+			parentHead, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + parent.Name})
+			if err != nil {
+				return errors.WrapIff(err, "failed to determine head commit of branch %q", parent.Name)
+			}
+			if !parent.Trunk {
+				parent.Head = parentHead
+			}
+			message := entry.Description
+			if message == "" {
+				message = entry.Name
+			}
+			commit, err := repo.Git(
+				"commit-tree", parentHead+"^{tree}", "-p", parentHead, "-m", args[0]+": "+message,
+			)
+			if err != nil {
+				return errors.WrapIff(err, "failed to create the commit of branch %q", branchName)
+			}
+			if err := repo.UpdateRef(&git.UpdateRef{
+				Ref: "refs/heads/" + branchName,
+				New: commit,
+				Old: git.Missing,
+			}); err != nil {
+				return errors.WrapIff(err, "failed to create branch %q", branchName)
+			}
+			tx.SetBranch(meta.Branch{Name: branchName, Parent: parent})
+			_, _ = fmt.Fprint(os.Stderr,
+				"Created branch ", colors.UserInput(branchName),
+				" on top of ", colors.UserInput(parent.Name), "\n",
+			)
+			branches = append(branches, branchName)
+			parent = meta.BranchState{Name: branchName}
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+		if _, err := repo.CheckoutBranch(&git.CheckoutBranch{Name: branches[0]}); err != nil {
+			return errors.WrapIff(err, "failed to switch to branch %q", branches[0])
+		}
+		if stackNewFlags.NoPR {
+			return nil
+		}
+
+		_, _ = fmt.Fprint(os.Stderr, "\n")
+		client, err := getGitHubClient()
+		if err != nil {
+			return err
+		}
+		ctx := context.Background()
+		tx = db.WriteTx()
+		if err := ensureGitHubWriteAccess(ctx, client, tx); err != nil {
+			return err
+		}
+		created, err := submitBranches(ctx, repo, client, tx, branches, submitOpts{Draft: true})
+		if err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+		// Like with av pr create --stack, the stack is always written so that
+		// the pull requests link to each other from the start.
+		writeStack := config.Av.PullRequest.WriteStack
+		if writeStack == "" {
+			writeStack = config.WriteStackBottom
+		}
+		stackBranches, err := meta.StackBranches(tx, branches[0])
+		if err != nil {
+			return err
+		}
+		if err := actions.UpdatePullRequestsWithStack(ctx, client, repo, tx, stackBranches, writeStack); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprint(os.Stderr,
+			"\n", colors.Success(fmt.Sprintf("Created %d draft pull request(s) for the stack.", len(created))), "\n",
+		)
+		return nil
+	},
+}
+
+func init() {
+	stackNewCmd.Flags().StringVar(
+		&stackNewFlags.Template, "template", "",
+		"the name of the stack template (see stack.templates in the configuration)",
+	)
+	_ = stackNewCmd.MarkFlagRequired("template")
+	stackNewCmd.Flags().BoolVar(
+		&stackNewFlags.NoPR, "no-pr", false,
+		"only create the branches (without pull requests)",
+	)
+}
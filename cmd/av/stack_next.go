@@ -1,11 +1,14 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
 	"github.com/aviator-co/av/internal/git"
 	"github.com/aviator-co/av/internal/meta"
 	"github.com/aviator-co/av/internal/utils/colors"
@@ -15,12 +18,15 @@ import (
 var stackNextFlags struct {
 	// should we go to the last
 	Last bool
+	// If true, sync the branch onto its parent after checking it out.
+	Sync bool
 }
 
 var stackNextCmd = &cobra.Command{
 	Use:     "next [<n>|--last]",
 	Aliases: []string{"n"},
 	Short:   "checkout the next branch in the stack",
+	Args:    cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Get the subsequent branches so we can checkout the nth one
 		repo, err := getRepo()
@@ -28,49 +34,63 @@ var stackNextCmd = &cobra.Command{
 			return err
 		}
 
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+
 		db, err := getDB(repo)
 		if err != nil {
 			return err
 		}
 		tx := db.ReadTx()
 
-		currentBranch, err := repo.CurrentBranchName()
+		currentBranch, err := getCurrentBranchName(repo, db)
 		if err != nil {
 			return err
 		}
-		subsequentBranches := meta.SubsequentBranches(tx, currentBranch)
 
-		var branchToCheckout string
-		if stackNextFlags.Last {
-			if len(subsequentBranches) == 0 {
-				return errors.New("already on last branch in stack\n")
+		var n int = 1
+		if len(args) == 1 {
+			if stackNextFlags.Last {
+				_ = cmd.Usage()
+				return errors.New("cannot use <n> with --last")
 			}
-			branchToCheckout = subsequentBranches[len(subsequentBranches)-1]
-		} else {
-			if len(subsequentBranches) == 0 {
-				return errors.New("there is no next branch")
+			var err error
+			n, err = strconv.Atoi(args[0])
+			if err != nil {
+				return errors.New("invalid number (unable to parse)")
 			}
-			var n int = 1
-			if len(args) == 1 {
-				var err error
-				n, err = strconv.Atoi(args[0])
-				if err != nil {
-					return errors.New("invalid number (unable to parse)")
-				}
-			} else if len(args) > 1 {
-				_ = cmd.Usage()
-				return errors.New("too many arguments")
+		}
+		if n <= 0 {
+			return errors.New("invalid number (must be >= 1)")
+		}
+
+		// Walk down the stack one child at a time (asking which way to go if
+		// the stack branches out).
+		branchToCheckout := currentBranch
+		steps := 0
+		for stackNextFlags.Last || steps < n {
+			child, err := chooseChildBranch(tx, branchToCheckout)
+			if err != nil {
+				return err
 			}
-			if n <= 0 {
-				return errors.New("invalid number (must be >= 1)")
+			if child == "" {
+				break
 			}
-			if n > len(subsequentBranches) {
-				return fmt.Errorf("invalid number (there are only %d subsequent branches in the stack)", len(subsequentBranches))
+			branchToCheckout = child
+			steps++
+		}
+		if steps == 0 {
+			if stackNextFlags.Last {
+				return errors.New("already on last branch in stack")
 			}
-			branchToCheckout = subsequentBranches[n-1]
+			return errors.New("there is no next branch")
+		}
+		if !stackNextFlags.Last && steps < n {
+			return fmt.Errorf("invalid number (there are only %d subsequent branches in the stack)", steps)
 		}
 
-		if _, err := repo.CheckoutBranch(&git.CheckoutBranch{
+		if err := actions.SwitchBranch(repo, &git.CheckoutBranch{
 			Name: branchToCheckout,
 		}); err != nil {
 			return err
@@ -83,6 +103,10 @@ var stackNextCmd = &cobra.Command{
 			"\n",
 		)
 
+		if stackNextFlags.Sync {
+			_, _ = fmt.Fprint(os.Stderr, "\n")
+			return runStackSync(actions.StackSyncConfig{Current: true, NoFetch: true, NoPush: true})
+		}
 		return nil
 	},
 }
@@ -92,4 +116,47 @@ func init() {
 		&stackNextFlags.Last, "last", false,
 		"go to the last branch in the current stack",
 	)
+	stackNextCmd.Flags().BoolVar(
+		&stackNextFlags.Sync, "sync", false,
+		"sync the branch onto its parent after checking it out (without fetching or pushing)",
+	)
+}
+
+// chooseChildBranch returns the child of the given branch to move to. If the
+// branch has several children, the user is asked to pick one (if we're running
+// interactively). Returns an empty string if the branch has no children.
+func chooseChildBranch(tx meta.ReadTx, branch string) (string, error) {
+	children := meta.ChildrenNames(tx, branch)
+	switch len(children) {
+	case 0:
+		return "", nil
+	case 1:
+		return children[0], nil
+	}
+
+	if !isInteractive() {
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			colors.Failure("Branch ", branch, " has multiple children: ", strings.Join(children, ", "), "\n"),
+			colors.Faint("  - Use "), colors.CliCmd("av switch <branch>"),
+			colors.Faint(" to check out one of them.\n"),
+		)
+		return "", actions.ErrExitSilently{ExitCode: 1}
+	}
+	_, _ = fmt.Fprint(os.Stderr, "Branch ", colors.UserInput(branch), " has multiple children:\n")
+	for i, child := range children {
+		_, _ = fmt.Fprint(os.Stderr, "  ", i+1, ") ", colors.UserInput(child), "\n")
+	}
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		_, _ = fmt.Fprintf(os.Stderr, "Which one? [1-%d]: ", len(children))
+		choice, err := reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		i, err := strconv.Atoi(strings.TrimSpace(choice))
+		if err == nil && i >= 1 && i <= len(children) {
+			return children[i-1], nil
+		}
+	}
 }
@@ -20,6 +20,10 @@ var stackOrphanCmd = &cobra.Command{
 			return err
 		}
 
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+
 		db, err := getDB(repo)
 		if err != nil {
 			return err
@@ -27,7 +31,7 @@ var stackOrphanCmd = &cobra.Command{
 		tx := db.WriteTx()
 		defer tx.Abort()
 
-		currentBranch, err := repo.CurrentBranchName()
+		currentBranch, err := getCurrentBranchName(repo, db)
 		if err != nil {
 			return errors.WrapIf(err, "failed to determine current branch")
 		}
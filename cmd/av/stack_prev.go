@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
 	"github.com/aviator-co/av/internal/git"
 	"github.com/aviator-co/av/internal/meta"
 	"github.com/aviator-co/av/internal/utils/colors"
@@ -21,18 +22,24 @@ var stackPrevCmd = &cobra.Command{
 	Use:     "prev [<n>|--first]",
 	Aliases: []string{"p"},
 	Short:   "checkout the previous branch in the stack",
+	Args:    cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Get the previous branches so we can checkout the nth one
 		repo, err := getRepo()
 		if err != nil {
 			return err
 		}
+
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+
 		db, err := getDB(repo)
 		if err != nil {
 			return err
 		}
 		tx := db.ReadTx()
-		currentBranch, err := repo.CurrentBranchName()
+		currentBranch, err := getCurrentBranchName(repo, db)
 		if err != nil {
 			return err
 		}
@@ -59,9 +66,6 @@ var stackPrevCmd = &cobra.Command{
 				if err != nil {
 					return errors.New("invalid number (unable to parse)")
 				}
-			} else if len(args) > 1 {
-				_ = cmd.Usage()
-				return errors.New("too many arguments")
 			}
 			if n <= 0 {
 				return errors.New("invalid number (must be >= 1)")
@@ -72,7 +76,7 @@ var stackPrevCmd = &cobra.Command{
 			branchToCheckout = previousBranches[len(previousBranches)-n]
 		}
 
-		if _, err := repo.CheckoutBranch(&git.CheckoutBranch{
+		if err := actions.SwitchBranch(repo, &git.CheckoutBranch{
 			Name: branchToCheckout,
 		}); err != nil {
 			return err
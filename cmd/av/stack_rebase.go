@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/spf13/cobra"
+)
+
+var stackRebaseFlags struct {
+	// If true, run an interactive rebase of the commits of the current branch.
+	Interactive bool
+}
+
+var stackRebaseCmd = &cobra.Command{
+	Use:   "rebase --interactive",
+	Short: "interactively rebase the commits of the current branch",
+	Long: `Interactively rebase the commits of the current branch (and only those).
+
+This runs "git rebase --interactive" with the commit that the current branch is
+based on (the recorded HEAD of its parent) as the upstream, so that the commits
+of the parent branches can't be rebased by accident. Afterwards, the branches
+stacked on top of the current branch are restacked locally. Nothing is pushed;
+use "av stack sync" or "av stack submit" afterwards to update the pull requests.
+
+If the rebase stops (e.g., because of a conflict or an "edit" command), finish
+it with "av stack sync --continue" (instead of "git rebase --continue") so that
+the descendants are restacked as well, or give up with "av stack sync --abort".`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !stackRebaseFlags.Interactive {
+			return errors.New(
+				"av stack rebase only supports --interactive (use av stack sync to rebase onto the parent branch)",
+			)
+		}
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.WriteTx()
+		defer tx.Abort()
+
+		state, err := actions.ReadStackSyncState(repo)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if state.CurrentBranch != "" {
+			return errors.New("a sync is in progress: use av stack sync --continue or --abort")
+		}
+		if err := ensureCleanWorkingTreeForSync(repo, false, false); err != nil {
+			return err
+		}
+
+		currentBranch, err := repo.CurrentBranchName()
+		if err != nil {
+			return err
+		}
+		branch, ok := tx.Branch(currentBranch)
+		if !ok {
+			return errors.Errorf("branch %q is not tracked by av (use av stack adopt first)", currentBranch)
+		}
+		if err := ensureNotReadOnly(tx, currentBranch); err != nil {
+			return err
+		}
+		if err := ensureStacksNotFrozen(tx, currentBranch); err != nil {
+			return err
+		}
+		base, err := actions.BranchBase(repo, tx, currentBranch)
+		if err != nil {
+			return err
+		}
+		if err := snapshotBranches(repo, tx, currentBranch); err != nil {
+			return err
+		}
+
+		res, err := repo.Run(&git.RunOpts{
+			Args:        []string{"rebase", "--interactive", base},
+			Interactive: true,
+		})
+		if err != nil {
+			return err
+		}
+		op, err := repo.OperationInProgress()
+		if err != nil {
+			return err
+		}
+		subtree := append([]string{currentBranch}, meta.SubsequentBranches(tx, currentBranch)...)
+		if op == git.OperationRebase {
+			// The rebase stopped, so finish it (and restack the descendants)
+			// with av stack sync --continue. The parent of the branch doesn't
+			// change.
+			state = actions.StackSyncState{
+				OriginalBranch: currentBranch,
+				CurrentBranch:  currentBranch,
+				Branches:       subtree,
+				Continuation: &actions.SyncBranchContinuation{
+					NewParentName:   branch.Parent.Name,
+					NewParentCommit: branch.Parent.Head,
+				},
+				Config: actions.StackSyncConfig{
+					NoFetch: true,
+					NoPush:  true,
+				},
+			}
+			if err := actions.WriteStackSyncState(repo, &state); err != nil {
+				return errors.Wrap(err, "failed to write stack sync state")
+			}
+			_, _ = fmt.Fprint(os.Stderr,
+				"\nThe rebase of ", colors.UserInput(currentBranch), " stopped: continue with ",
+				colors.CliCmd("av stack sync --continue"), " (or ", colors.CliCmd("av stack sync --abort"), ")\n",
+			)
+			return tx.Commit()
+		}
+		if res.ExitCode != 0 {
+			_, _ = fmt.Fprint(os.Stderr,
+				"\n", colors.Failure("The rebase of ", currentBranch, " failed."), "\n",
+			)
+			return actions.ErrExitSilently{ExitCode: 1}
+		}
+
+		// The descendants are only restacked locally, so GitHub isn't needed
+		// (and the rebase works without a token).
+		state = actions.StackSyncState{OriginalBranch: currentBranch}
+		return actions.SyncStack(context.Background(), repo, nil, tx, subtree[1:], state, actions.WithLocalOnly())
+	},
+}
+
+func init() {
+	stackRebaseCmd.Flags().BoolVarP(
+		&stackRebaseFlags.Interactive, "interactive", "i", false,
+		"interactively rebase the commits of the current branch",
+	)
+}
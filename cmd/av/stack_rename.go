@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/spf13/cobra"
+)
+
+var stackRenameCmd = &cobra.Command{
+	Use:   "rename <new-name>",
+	Short: "rename the current branch along with its remote branch and pull request",
+	Long: `Rename the current branch.
+
+The branches stacked on top of the current branch are updated to refer to the
+new name. If the branch was pushed, it's renamed on GitHub as well, which keeps
+its pull request (and retargets the pull requests of its children) instead of
+orphaning it.`,
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		newName := args[0]
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.WriteTx()
+		defer tx.Abort()
+
+		oldName, err := repo.CurrentBranchName()
+		if err != nil {
+			return err
+		}
+		branch, ok := tx.Branch(oldName)
+		if !ok {
+			return errors.Errorf("branch %q is not tracked by av (use av stack adopt first)", oldName)
+		}
+		if oldName == newName {
+			return errors.New("cannot rename branch to itself")
+		}
+		if _, err := repo.Run(&git.RunOpts{
+			Args:      []string{"check-ref-format", "--branch", newName},
+			ExitError: true,
+		}); err != nil {
+			return errors.Errorf("%q is not a valid branch name", newName)
+		}
+		if exists, err := repo.DoesBranchExist(newName); err != nil {
+			return err
+		} else if exists {
+			return errors.Errorf("branch %q already exists", newName)
+		}
+		if err := ensureNoBranchCaseCollision(repo, newName, oldName); err != nil {
+			return err
+		}
+
+		// Rename the remote branch first so that nothing changes locally if
+		// that fails.
+		remote, err := repo.DoesRemoteBranchExist(oldName)
+		if err != nil {
+			return err
+		}
+		if remote {
+			repository, ok := tx.Repository()
+			if !ok {
+				return actions.ErrRepoNotInitialized
+			}
+			client, err := getGitHubClient()
+			if err != nil {
+				return err
+			}
+			if err := client.RenameBranch(
+				context.Background(), repository.Owner, repository.Name, oldName, newName,
+			); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprint(os.Stderr,
+				"  - renamed ", colors.UserInput("origin/", oldName),
+				" to ", colors.UserInput("origin/", newName), " on GitHub\n",
+			)
+		}
+
+		if _, err := repo.Run(&git.RunOpts{
+			Args:      []string{"branch", "-m", oldName, newName},
+			ExitError: true,
+		}); err != nil {
+			return errors.WrapIff(err, "failed to rename branch %q", oldName)
+		}
+		if remote {
+			if _, err := repo.Run(&git.RunOpts{
+				Args:      []string{"fetch", "origin", newName},
+				ExitError: true,
+			}); err != nil {
+				return errors.WrapIff(err, "failed to fetch %q", newName)
+			}
+			if _, err := repo.Git("update-ref", "-d", "refs/remotes/origin/"+oldName); err != nil {
+				return err
+			}
+			if _, err := repo.Git("branch", "--set-upstream-to", "origin/"+newName, newName); err != nil {
+				return errors.WrapIff(err, "failed to set the upstream of %q", newName)
+			}
+		}
+
+		branch.Name = newName
+		tx.SetBranch(branch)
+		for _, child := range meta.Children(tx, oldName) {
+			child.Parent.Name = newName
+			tx.SetBranch(child)
+		}
+		tx.DeleteBranch(oldName)
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprint(os.Stderr,
+			"Renamed branch ", colors.UserInput(oldName), " to ", colors.UserInput(newName), "\n",
+		)
+		if branch.PullRequest != nil {
+			_, _ = fmt.Fprint(os.Stderr,
+				colors.Faint("  - Use "), colors.CliCmd("av stack sync"),
+				colors.Faint(" to update the descriptions of the pull requests in the stack.\n"),
+			)
+		}
+		return nil
+	},
+}
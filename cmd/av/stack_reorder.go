@@ -20,6 +20,9 @@ import (
 var stackReorderFlags struct {
 	Continue bool
 	Abort    bool
+	// If true, only the order of the branches is edited (and each branch keeps
+	// its commits).
+	Branches bool
 }
 
 const stackReorderDoc = `
@@ -30,6 +33,10 @@ in the stack.
 
 Branches can be re-arranged within the stack and commits can be edited,
 squashed, dropped, or moved within the stack.
+
+With --branches, only the branches are listed (one per line). Reordering the
+lines reorders the branches: the parents of the branches are updated and the
+branches are restacked, each with its own commits.
 `
 
 var stackReorderCmd = &cobra.Command{
@@ -86,6 +93,9 @@ var stackReorderCmd = &cobra.Command{
 				)
 				return actions.ErrExitSilently{ExitCode: 127}
 			}
+			if err := ensureNoGitOperationInProgress(repo); err != nil {
+				return err
+			}
 			tx := db.ReadTx()
 			currentBranch, err := repo.CurrentBranchName()
 			if err != nil {
@@ -103,6 +113,12 @@ var stackReorderCmd = &cobra.Command{
 			if err != nil {
 				return err
 			}
+			if err := ensureStacksNotFrozen(tx, root); err != nil {
+				return err
+			}
+			if err := snapshotBranches(repo, tx, root); err != nil {
+				return err
+			}
 
 			plan, err := stackReorderEditPlan(repo, initialPlan)
 			if err != nil {
@@ -151,13 +167,20 @@ func init() {
 		BoolVar(&stackReorderFlags.Continue, "continue", false, "continue a previous reorder")
 	stackReorderCmd.Flags().
 		BoolVar(&stackReorderFlags.Abort, "abort", false, "abort a previous reorder")
-	stackReorderCmd.MarkFlagsMutuallyExclusive("continue", "abort")
+	stackReorderCmd.Flags().
+		BoolVar(&stackReorderFlags.Branches, "branches", false, "only edit the order of the branches")
+	stackReorderCmd.MarkFlagsMutuallyExclusive("continue", "abort", "branches")
 }
 
 func stackReorderEditPlan(repo *git.Repo, initialPlan []reorder.Cmd) ([]reorder.Cmd, error) {
 	plan := initialPlan
 edit:
-	plan, err := reorder.EditPlan(repo, plan)
+	var err error
+	if stackReorderFlags.Branches {
+		plan, err = reorder.EditBranchOrder(repo, plan)
+	} else {
+		plan, err = reorder.EditPlan(repo, plan)
+	}
 	if err != nil {
 		return nil, err
 	}
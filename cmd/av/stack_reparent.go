@@ -1,24 +1,129 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/meta"
 	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/aviator-co/av/internal/utils/stringutils"
+	"github.com/kr/text"
 	"github.com/spf13/cobra"
 )
 
 var stackReparentCmd = &cobra.Command{
-	Use:    "reparent <new-parent>",
-	Hidden: true,
+	Use:   "reparent <new-parent>",
+	Short: "move the current branch (and its children) onto a different parent",
+	Long: `Move the current branch onto a different parent branch.
+
+The current branch is rebased onto the new parent (which can be a trunk or any
+branch that is tracked by av), and the branches stacked on top of it are rebased
+along with it. Nothing is pushed; use "av stack sync" or "av stack submit"
+afterwards to update the pull requests.
+
+If there are conflicts, resolve them and continue with "av stack sync --continue"
+(or give up with "av stack sync --abort").`,
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, _ = fmt.Fprint(os.Stderr,
-			colors.Failure("ERROR: "),
-			"The ", colors.CliCmd("av stack reparent"), " command is deprecated: ",
-			"use ", colors.CliCmd("av stack sync --parent <new-parent>"), " instead.",
-			"\n",
-		)
-		os.Exit(1)
-		return nil
+		ctx := context.Background()
+		newParent := args[0]
+
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.WriteTx()
+		defer tx.Abort()
+
+		state, err := actions.ReadStackSyncState(repo)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if state.CurrentBranch != "" {
+			return errors.New("a sync is in progress: use av stack sync --continue or --abort")
+		}
+		if err := ensureCleanWorkingTreeForSync(repo, false, false); err != nil {
+			return err
+		}
+
+		currentBranch, err := repo.CurrentBranchName()
+		if err != nil {
+			return err
+		}
+		if _, ok := tx.Branch(currentBranch); !ok {
+			return errors.Errorf("branch %q is not tracked by av (use av stack adopt first)", currentBranch)
+		}
+		defaultBranch, err := repo.DefaultBranch()
+		if err != nil {
+			return err
+		}
+		if err := validateParentBranch(repo, tx, newParent, defaultBranch); err != nil {
+			return err
+		}
+		if err := ensureNotReadOnly(tx, currentBranch); err != nil {
+			return err
+		}
+		if err := ensureStacksNotFrozen(tx, currentBranch); err != nil {
+			return err
+		}
+		subtree := append([]string{currentBranch}, meta.SubsequentBranches(tx, currentBranch)...)
+		if err := snapshotBranches(repo, tx, currentBranch); err != nil {
+			return err
+		}
+
+		// The sync state is only written if there are conflicts, so that
+		// av stack sync --continue finishes the reparent and then restacks the
+		// subtree (and nothing else) locally.
+		state = actions.StackSyncState{
+			OriginalBranch: currentBranch,
+			CurrentBranch:  currentBranch,
+			Branches:       subtree,
+			Config: actions.StackSyncConfig{
+				Parent:  newParent,
+				NoFetch: true,
+				NoPush:  true,
+			},
+		}
+		res, err := actions.Reparent(repo, tx, actions.ReparentOpts{
+			Branch:         currentBranch,
+			NewParent:      newParent,
+			NewParentTrunk: meta.IsTrunk(tx, newParent, defaultBranch),
+		})
+		if err != nil {
+			return err
+		}
+		if !res.Success {
+			if err := actions.WriteStackSyncState(repo, &state); err != nil {
+				return errors.Wrap(err, "failed to write stack sync state")
+			}
+			_, _ = fmt.Fprint(os.Stderr,
+				"Failed to re-parent branch: resolve the conflicts and continue with ",
+				colors.CliCmd("av stack sync --continue"),
+				"\n",
+			)
+			hint := stringutils.RemoveLines(res.Hint, "hint: ")
+			_, _ = fmt.Fprint(os.Stderr,
+				"hint:\n",
+				text.Indent(hint, "    "),
+				"\n",
+			)
+			return tx.Commit()
+		}
+		state.Config.Parent = ""
+
+		// The subtree is only restacked locally, so GitHub isn't needed (and
+		// the reparent works without a token).
+		return actions.SyncStack(ctx, repo, nil, tx, subtree, state, actions.WithLocalOnly())
 	},
 }
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/shurcooL/githubv4"
+	"github.com/spf13/cobra"
+)
+
+var stackReportFlags struct {
+	// The number of days without a new commit after which a branch is stale.
+	StaleDays int
+	// The number of days that a stack can be behind its trunk before it's
+	// reported.
+	UnsyncedDays int
+	// The format of the report (see actions.StackReportFormat*).
+	Format string
+	// If true, don't query GitHub for the state of the pull requests.
+	NoFetch bool
+}
+
+var stackReportCmd = &cobra.Command{
+	Use:   "report [flags]",
+	Short: "report the stacks that need attention",
+	Long: `Report the stacks that need attention: branches without any new commits for a
+while, stacks that haven't been synced with their trunk for a while, and pull
+requests that are waiting for you (e.g., because changes were requested, checks
+failed, or they're approved and ready to land).
+
+The report is written to stdout. With --format=markdown or --format=slack, it's
+formatted for posting as a digest (e.g., from a scheduled job).`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if stackReportFlags.StaleDays <= 0 || stackReportFlags.UnsyncedDays <= 0 {
+			return errors.New("--stale-days and --unsynced-days must be positive")
+		}
+		switch stackReportFlags.Format {
+		case actions.StackReportFormatText, actions.StackReportFormatMarkdown, actions.StackReportFormatSlack:
+		default:
+			return errors.Errorf(
+				"invalid value %q for --format (expected text, markdown, or slack)",
+				stackReportFlags.Format,
+			)
+		}
+
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.ReadTx()
+		now := time.Now()
+		stacks, err := actions.StackReport(repo, tx, actions.StackReportOpts{
+			Now:           now,
+			StaleAfter:    time.Duration(stackReportFlags.StaleDays) * 24 * time.Hour,
+			UnsyncedAfter: time.Duration(stackReportFlags.UnsyncedDays) * 24 * time.Hour,
+		})
+		if err != nil {
+			return err
+		}
+
+		if !stackReportFlags.NoFetch {
+			client, err := getGitHubClient()
+			if err != nil {
+				return err
+			}
+			// Query the pull requests of all of the stacks at once.
+			var ids []string
+			var branches []*actions.StackReportBranch
+			for _, stack := range stacks {
+				for _, branch := range stack.Branches {
+					if branch.PullRequest == nil || branch.PullRequest.ID == "" ||
+						branch.PullRequest.State != githubv4.PullRequestStateOpen {
+						continue
+					}
+					ids = append(ids, branch.PullRequest.ID)
+					branches = append(branches, branch)
+				}
+			}
+			prs, err := client.PullRequestsWithStatus(context.Background(), ids)
+			if err != nil {
+				return err
+			}
+			for i, pr := range prs {
+				branches[i].Actions = actions.PullRequestActions(pr.PullRequest, pr.Checks)
+			}
+		}
+
+		return actions.RenderStackReport(os.Stdout, stacks, stackReportFlags.Format, now)
+	},
+}
+
+func init() {
+	stackReportCmd.Flags().IntVar(
+		&stackReportFlags.StaleDays, "stale-days", 7,
+		"report branches without any new commits for this many days",
+	)
+	stackReportCmd.Flags().IntVar(
+		&stackReportFlags.UnsyncedDays, "unsynced-days", 3,
+		"report stacks that have been behind their trunk for this many days",
+	)
+	stackReportCmd.Flags().StringVar(
+		&stackReportFlags.Format, "format", actions.StackReportFormatText,
+		"the format of the report (text, markdown, or slack)",
+	)
+	stackReportCmd.Flags().BoolVar(
+		&stackReportFlags.NoFetch, "no-fetch", false,
+		"don't query GitHub for the state of the pull requests",
+	)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/editor"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/spf13/cobra"
+)
+
+var stackSplitCmd = &cobra.Command{
+	Use:   "split",
+	Short: "split the current branch into multiple stacked branches",
+	Long: `Split the current branch into multiple stacked branches.
+
+The commits of the current branch are opened in your editor. Add a
+"branch <name>" line before each commit that should start a new branch. The
+current branch keeps the commits before the first new branch (along with its
+pull request), and each new branch is stacked on top of the previous one. The
+children of the current branch are moved onto the last new branch.
+
+The commits themselves aren't rewritten, so this never causes conflicts.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.WriteTx()
+		defer tx.Abort()
+
+		currentBranch, err := getCurrentBranchName(repo, db)
+		if err != nil {
+			return err
+		}
+		if _, ok := tx.Branch(currentBranch); !ok {
+			return errors.Errorf("branch %q is not tracked by av", currentBranch)
+		}
+		if err := ensureNotReadOnly(tx, currentBranch); err != nil {
+			return err
+		}
+		if err := ensureStacksNotFrozen(tx, currentBranch); err != nil {
+			return err
+		}
+		commits, err := actions.BranchCommits(repo, tx, currentBranch)
+		if err != nil {
+			return err
+		}
+		if len(commits) < 2 {
+			_, _ = fmt.Fprint(os.Stderr,
+				colors.Failure("Branch ", currentBranch, " needs at least two commits to be split.\n"),
+			)
+			return actions.ErrExitSilently{ExitCode: 1}
+		}
+
+		text := strings.Builder{}
+		text.WriteString("branch " + currentBranch + "\n")
+		for _, commit := range commits {
+			title, err := repo.Git("log", "-1", "--format=%s", commit)
+			if err != nil {
+				return err
+			}
+			text.WriteString("pick " + git.ShortSha(commit) + "  # " + title + "\n")
+		}
+		text.WriteString(stackSplitInstructions)
+		res, err := editor.Launch(repo, editor.Config{
+			Text:              text.String(),
+			TmpFilePattern:    "stack-split-*.av",
+			CommentPrefix:     "#",
+			EndOfLineComments: true,
+		})
+		if err != nil {
+			return errors.WrapIf(err, "text editor failed")
+		}
+		parts, err := parseStackSplit(res, currentBranch, commits)
+		if err != nil {
+			return err
+		}
+		if len(parts) == 1 {
+			_, _ = fmt.Fprint(os.Stderr, "No new branches were added, nothing to do.\n")
+			return nil
+		}
+
+		if err := snapshotBranches(repo, tx, currentBranch); err != nil {
+			return err
+		}
+		if err := actions.SplitBranch(repo, tx, currentBranch, parts); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprint(os.Stderr, "Split branch ", colors.UserInput(currentBranch), " into:\n")
+		for _, part := range parts {
+			_, _ = fmt.Fprint(os.Stderr,
+				"  - ", colors.UserInput(part.Branch),
+				colors.Faint(" (", len(part.Commits), " commit(s))"), "\n",
+			)
+		}
+		_, _ = fmt.Fprint(os.Stderr,
+			"Checked out branch ", colors.UserInput(parts[len(parts)-1].Branch), "\n",
+			colors.Faint("  - Use "), colors.CliCmd("av stack submit"),
+			colors.Faint(" to push the branches and create pull requests for the new ones.\n"),
+		)
+		return nil
+	},
+}
+
+// parseStackSplit parses the edited text of av stack split into the parts of
+// the split. The commits may be abbreviated but have to be in their original
+// order.
+func parseStackSplit(text string, currentBranch string, commits []string) ([]actions.SplitPart, error) {
+	parts := []actions.SplitPart{{Branch: currentBranch}}
+	seen := map[string]bool{currentBranch: true}
+	next := 0
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "branch", "b":
+			if len(fields) != 2 {
+				return nil, errors.Errorf("invalid line %q: expected \"branch <name>\"", line)
+			}
+			name := fields[1]
+			if name == currentBranch && next == 0 {
+				// The first line (the current branch).
+				continue
+			}
+			if seen[name] {
+				return nil, errors.Errorf("branch %q is used more than once", name)
+			}
+			seen[name] = true
+			if next == 0 {
+				return nil, errors.Errorf(
+					"branch %q has no commits: the first commits stay in %q", name, currentBranch,
+				)
+			}
+			if len(parts[len(parts)-1].Commits) == 0 {
+				return nil, errors.Errorf("branch %q has no commits", parts[len(parts)-1].Branch)
+			}
+			parts = append(parts, actions.SplitPart{Branch: name})
+		case "pick", "p":
+			if len(fields) != 2 {
+				return nil, errors.Errorf("invalid line %q: expected \"pick <commit>\"", line)
+			}
+			if next >= len(commits) || !strings.HasPrefix(commits[next], fields[1]) {
+				return nil, errors.Errorf(
+					"unexpected commit %q: the commits can't be reordered or removed (use %s instead)",
+					fields[1], "av stack reorder",
+				)
+			}
+			parts[len(parts)-1].Commits = append(parts[len(parts)-1].Commits, commits[next])
+			next++
+		default:
+			return nil, errors.Errorf("invalid line %q", line)
+		}
+	}
+	if next != len(commits) {
+		return nil, errors.Errorf(
+			"commit %s is missing: the commits can't be reordered or removed (use %s instead)",
+			git.ShortSha(commits[next]), "av stack reorder",
+		)
+	}
+	if last := parts[len(parts)-1]; len(last.Commits) == 0 {
+		return nil, errors.Errorf("branch %q has no commits", last.Branch)
+	}
+	return parts, nil
+}
+
+const stackSplitInstructions = `
+# Split the branch by adding "branch <name>" lines before the commits that
+# should start a new branch. Each new branch is stacked on top of the branch
+# before it, and the first commits stay in the current branch.
+#
+# The commits can't be reordered or removed (use av stack reorder for that).
+`
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/spf13/cobra"
+)
+
+var stackStatsFlags struct {
+	// If true, print the metrics as JSON.
+	JSON bool
+	// If true, don't query GitHub for the time-to-merge of merged branches.
+	NoFetch bool
+}
+
+var stackStatsCmd = &cobra.Command{
+	Use:   "stats [--json] [--no-fetch]",
+	Short: "show metrics of the stacks",
+	Long: `Show metrics of the stacks: the number of branches and commits, the size of
+the diffs of the branches, the age of the stacks, and how long the pull requests
+of the merged branches took to be merged.
+
+The metrics are aggregated from the local repository and the pull requests of
+the merged branches that are still tracked by av (so merged branches that were
+pruned aren't included). The metrics are written to
+stdout; use --json to process them further.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		stacks, err := actions.StackStatsFor(repo, db.ReadTx())
+		if err != nil {
+			return err
+		}
+
+		if !stackStatsFlags.NoFetch {
+			client, err := getGitHubClient()
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+			for _, stack := range stacks {
+				for _, branch := range stack.Branches {
+					if !branch.Merged || branch.PullRequest == nil || branch.PullRequest.ID == "" {
+						continue
+					}
+					pr, err := client.PullRequest(ctx, branch.PullRequest.ID)
+					if err != nil {
+						return err
+					}
+					if pr.MergedAt != nil {
+						branch.TimeToMerge = pr.MergedAt.Sub(pr.CreatedAt.Time)
+					}
+				}
+			}
+		}
+
+		if stackStatsFlags.JSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(stacks)
+		}
+		actions.RenderStackStats(os.Stdout, stacks, time.Now())
+		return nil
+	},
+}
+
+func init() {
+	stackStatsCmd.Flags().BoolVar(
+		&stackStatsFlags.JSON, "json", false,
+		"print the metrics as JSON",
+	)
+	stackStatsCmd.Flags().BoolVar(
+		&stackStatsFlags.NoFetch, "no-fetch", false,
+		"don't query GitHub for the time-to-merge of merged branches",
+	)
+}
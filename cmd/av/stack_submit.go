@@ -2,20 +2,29 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"strings"
 
 	"emperror.dev/errors"
 	"github.com/aviator-co/av/internal/actions"
 	"github.com/aviator-co/av/internal/config"
 	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/git"
 	"github.com/aviator-co/av/internal/meta"
 	"github.com/aviator-co/av/internal/utils/cleanup"
+	"github.com/aviator-co/av/internal/utils/colors"
 	"github.com/shurcooL/githubv4"
 	"github.com/spf13/cobra"
 )
 
 var stackSubmitFlags struct {
+	prSetupFlags
+
 	Current bool
+	// If true, create new pull requests as drafts (see
+	// config.PullRequest.Draft).
+	Draft bool
 }
 
 var stackSubmitCmd = &cobra.Command{
@@ -24,7 +33,13 @@ var stackSubmitCmd = &cobra.Command{
 	Long: strings.TrimSpace(`
 	Create pull requests for every branch in the stack
 
-If the --current flag is given, this command will create pull requests up to the current branch.`),
+If the --current flag is given, this command will create pull requests up to the current branch.
+
+If the --draft flag is given, the new pull requests are created as drafts (use
+"av pr ready" to mark them as ready for review later).
+
+Branches that are marked as work in progress (see "av stack wip") and the
+branches stacked on top of them are skipped.`),
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, _ []string) error {
 		// Get the all branches in the stack
@@ -33,6 +48,10 @@ If the --current flag is given, this command will create pull requests up to the
 			return err
 		}
 
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+
 		db, err := getDB(repo)
 		if err != nil {
 			return err
@@ -41,7 +60,7 @@ If the --current flag is given, this command will create pull requests up to the
 		cu := cleanup.New(func() { tx.Abort() })
 		defer cu.Cleanup()
 
-		currentBranch, err := repo.CurrentBranchName()
+		currentBranch, err := getCurrentBranchName(repo, db)
 		if err != nil {
 			return err
 		}
@@ -60,41 +79,29 @@ If the --current flag is given, this command will create pull requests up to the
 			branchesToSubmit = currentStackBranches
 		}
 
-		// ensure pull requests for each branch in the stack
-		var lastCreatedPullRequest *meta.PullRequest
 		ctx := context.Background()
 		client, err := getGitHubClient()
 		if err != nil {
 			return err
 		}
-		for _, branchName := range branchesToSubmit {
-			// TODO: should probably commit database after every call to this
-			// since we're just syncing state from GitHub
-			result, err := actions.CreatePullRequest(
-				ctx, repo, client, tx,
-				actions.CreatePullRequestOpts{
-					BranchName:    branchName,
-					Draft:         config.Av.PullRequest.Draft,
-					NoOpenBrowser: true,
-				},
-			)
-			if err != nil {
-				return err
-			}
-			if result.Created {
-				lastCreatedPullRequest = result.Branch.PullRequest
-			}
-			// make sure the base branch of the PR is up to date if it already exists
-			if !result.Created && result.Pull.BaseRefName != result.Branch.Parent.Name {
-				if _, err := client.UpdatePullRequest(
-					ctx, githubv4.UpdatePullRequestInput{
-						PullRequestID: githubv4.ID(result.Branch.PullRequest.ID),
-						BaseRefName:   gh.Ptr(githubv4.String(result.Branch.Parent.Name)),
-					},
-				); err != nil {
-					return errors.Wrap(err, "failed to update PR base branch")
-				}
-			}
+		if err := ensureGitHubWriteAccess(ctx, client, tx); err != nil {
+			return err
+		}
+		// TODO: should probably commit database after every pull request
+		// since we're just syncing state from GitHub
+		draft := config.Av.PullRequest.Draft
+		if cmd.Flags().Changed("draft") {
+			draft = stackSubmitFlags.Draft
+		}
+		created, err := submitBranches(ctx, repo, client, tx, branchesToSubmit, submitOpts{
+			Draft: draft,
+		})
+		if err != nil {
+			return err
+		}
+		var lastCreatedPullRequest *meta.PullRequest
+		if len(created) > 0 {
+			lastCreatedPullRequest = created[len(created)-1].Branch.PullRequest
 		}
 
 		cu.Cancel()
@@ -102,10 +109,15 @@ If the --current flag is given, this command will create pull requests up to the
 			return err
 		}
 
-		if config.Av.PullRequest.WriteStack != "" {
-			if err = actions.UpdatePullRequestsWithStack(ctx, client, repo, tx, currentStackBranches, config.Av.PullRequest.WriteStack); err != nil {
-				return err
-			}
+		setup := prSetup(cmd, stackSubmitFlags.prSetupFlags, true)
+		if err := setUpCreatedPullRequests(ctx, client, tx, created, setup); err != nil {
+			return err
+		}
+
+		// Even if pullRequest.writeStack isn't set, the stack that was written
+		// to the pull requests before is refreshed.
+		if err = actions.UpdatePullRequestsWithStack(ctx, client, repo, tx, currentStackBranches, config.Av.PullRequest.WriteStack); err != nil {
+			return err
 		}
 
 		if lastCreatedPullRequest != nil && config.Av.PullRequest.OpenBrowser {
@@ -116,9 +128,123 @@ If the --current flag is given, this command will create pull requests up to the
 	},
 }
 
+// setUpCreatedPullRequests adds the reviewers, labels, and assignees of the
+// setup to the pull requests that were created.
+func setUpCreatedPullRequests(
+	ctx context.Context,
+	client *gh.Client,
+	tx meta.ReadTx,
+	created []*actions.CreatePullRequestResult,
+	setup actions.PullRequestSetup,
+) error {
+	if setup.IsEmpty() {
+		return nil
+	}
+	repository, _ := tx.Repository()
+	for _, res := range created {
+		_, _ = fmt.Fprint(os.Stderr,
+			"Setting up pull request ", colors.UserInput("#", res.Pull.Number), "...\n",
+		)
+		if err := actions.SetUpPullRequest(ctx, client, repository, res.Pull, setup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type submitOpts struct {
+	// If true, new pull requests are created as drafts.
+	Draft bool
+	// If true, the branches aren't pushed before their pull requests are
+	// created.
+	NoPush bool
+	// The name of the pull request template to start the bodies of new pull
+	// requests with (see actions.ReadPullRequestTemplate).
+	Template string
+}
+
+// submitBranches creates pull requests for the given branches (in order) or
+// makes sure that the base branches of their existing pull requests are
+// up-to-date. Work-in-progress branches (and the branches stacked on top of
+// them) and read-only branches are skipped. Returns the results for the pull
+// requests that were created.
+func submitBranches(
+	ctx context.Context,
+	repo *git.Repo,
+	client *gh.Client,
+	tx meta.WriteTx,
+	branches []string,
+	opts submitOpts,
+) ([]*actions.CreatePullRequestResult, error) {
+	var created []*actions.CreatePullRequestResult
+	// Query the existing pull requests of the whole stack at once rather than
+	// one by one for each branch.
+	prs, err := actions.FetchPullRequests(ctx, client, tx, branches)
+	if err != nil {
+		return nil, err
+	}
+	// Work-in-progress branches (and the branches stacked on top of them)
+	// aren't pushed, so their pull requests can't be created.
+	wip := map[string]bool{}
+	for _, branchName := range branches {
+		branch, _ := tx.Branch(branchName)
+		if branch.ReadOnly {
+			// The branch (and its pull request) belongs to someone else,
+			// but the branches stacked on top of it are submitted.
+			_, _ = fmt.Fprint(os.Stderr,
+				"Skipping branch ", colors.UserInput(branchName),
+				colors.Faint(" (read-only)"), "\n",
+			)
+			continue
+		}
+		if branch.WIP || wip[branch.Parent.Name] {
+			wip[branchName] = true
+			_, _ = fmt.Fprint(os.Stderr,
+				"Skipping branch ", colors.UserInput(branchName),
+				colors.Faint(" (work in progress)"), "\n",
+			)
+			continue
+		}
+		result, err := actions.CreatePullRequest(
+			ctx, repo, client, tx,
+			actions.CreatePullRequestOpts{
+				BranchName:    branchName,
+				Draft:         opts.Draft,
+				NoPush:        opts.NoPush,
+				NoOpenBrowser: true,
+				Template:      opts.Template,
+				PullRequest:   prs[branchName],
+			},
+		)
+		if err != nil {
+			return created, err
+		}
+		if result.Created {
+			created = append(created, result)
+		}
+		// make sure the base branch of the PR is up to date if it already exists
+		if !result.Created && result.Pull.BaseRefName != result.Branch.Parent.Name {
+			if _, err := client.UpdatePullRequest(
+				ctx, githubv4.UpdatePullRequestInput{
+					PullRequestID: githubv4.ID(result.Branch.PullRequest.ID),
+					BaseRefName:   gh.Ptr(githubv4.String(result.Branch.Parent.Name)),
+				},
+			); err != nil {
+				return created, errors.Wrap(err, "failed to update PR base branch")
+			}
+		}
+	}
+	return created, nil
+}
+
 func init() {
 	stackSubmitCmd.Flags().BoolVar(
 		&stackSubmitFlags.Current, "current", false,
 		"only create pull requests up to the current branch",
 	)
+	stackSubmitCmd.Flags().BoolVar(
+		&stackSubmitFlags.Draft, "draft", false,
+		"create the pull requests in draft mode (defaults to the pullRequest.draft config)",
+	)
+	addPRSetupFlags(stackSubmitCmd, &stackSubmitFlags.prSetupFlags)
 }
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/aviator-co/av/internal/utils/stackutils"
+	"github.com/aviator-co/av/internal/utils/stringutils"
+	"github.com/spf13/cobra"
+)
+
+var stackSwitchCmd = &cobra.Command{
+	Use:   "switch [<query>]",
+	Short: "pick a branch from the stack tree and check it out",
+	Long: `Pick a branch from the stack tree and check it out.
+
+The branches of all stacks are listed in the same order as in "av stack tree".
+Choose a branch by its number, or type (part of) its name to narrow down the
+list. The search is fuzzy: the typed characters have to appear in the branch
+name in the same order, but not necessarily next to each other (e.g., "fb"
+matches "feature-bar").
+
+If a query is given and only one branch matches it, that branch is checked out
+right away.`,
+	SilenceUsage: true,
+	Args:         cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+
+		var currentBranch string
+		if dh, err := repo.DetachedHead(); err != nil {
+			return err
+		} else if !dh {
+			currentBranch, err = repo.CurrentBranchName()
+			if err != nil {
+				return err
+			}
+		}
+		rootNodes, err := stackutils.BuildStackTree(repo, db.ReadTx(), currentBranch)
+		if err != nil {
+			return err
+		}
+		var entries []switchEntry
+		for _, node := range rootNodes {
+			entries = appendSwitchEntries(entries, 0, node)
+		}
+
+		var query string
+		if len(args) > 0 {
+			query = args[0]
+		}
+		branchToCheckout, err := pickSwitchEntry(entries, currentBranch, query)
+		if err != nil || branchToCheckout == "" {
+			return err
+		}
+		if branchToCheckout == currentBranch {
+			_, _ = fmt.Fprint(os.Stderr, "Already on branch ", colors.UserInput(branchToCheckout), "\n")
+			return nil
+		}
+
+		if err := actions.SwitchBranch(repo, &git.CheckoutBranch{
+			Name: branchToCheckout,
+		}); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			"Checked out branch ",
+			colors.UserInput(branchToCheckout),
+			"\n",
+		)
+		return nil
+	},
+}
+
+// switchEntry is a branch in the list shown by av stack switch.
+type switchEntry struct {
+	Name string
+	// The number of columns to indent the branch by (as in av stack tree).
+	Columns int
+}
+
+// appendSwitchEntries appends the branches of the tree in the order that
+// stackutils.PrintNode prints them.
+func appendSwitchEntries(entries []switchEntry, columns int, node *stackutils.StackTreeNode) []switchEntry {
+	for i, child := range node.Children {
+		entries = appendSwitchEntries(entries, columns+i, child)
+	}
+	if node.Branch.Deleted {
+		return entries
+	}
+	return append(entries, switchEntry{Name: node.Branch.BranchName, Columns: columns})
+}
+
+func filterSwitchEntries(entries []switchEntry, query string) []switchEntry {
+	var res []switchEntry
+	for _, entry := range entries {
+		if stringutils.FuzzyMatch(query, entry.Name) {
+			res = append(res, entry)
+		}
+	}
+	return res
+}
+
+func printSwitchEntries(entries []switchEntry, currentBranch string) {
+	width := len(strconv.Itoa(len(entries)))
+	for i, entry := range entries {
+		_, _ = fmt.Fprintf(os.Stderr, " %*d)%s * ", width, i+1, strings.Repeat(" │", entry.Columns))
+		_, _ = fmt.Fprint(os.Stderr, colors.UserInput(entry.Name))
+		if entry.Name == currentBranch {
+			_, _ = fmt.Fprint(os.Stderr, colors.Faint(" (HEAD)"))
+		}
+		_, _ = fmt.Fprint(os.Stderr, "\n")
+	}
+}
+
+// pickSwitchEntry returns the branch that matches the query or asks the user
+// to pick one. An empty string is returned if the user cancels.
+func pickSwitchEntry(entries []switchEntry, currentBranch string, query string) (string, error) {
+	if len(entries) == 0 {
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			colors.Failure("There are no branches tracked by av.\n"),
+			colors.Faint("  - Use "), colors.CliCmd("av stack branch"),
+			colors.Faint(" to create a stacked branch.\n"),
+		)
+		return "", actions.ErrExitSilently{ExitCode: 1}
+	}
+
+	matches := filterSwitchEntries(entries, query)
+	if query != "" && len(matches) == 1 {
+		return matches[0].Name, nil
+	}
+	if len(matches) == 0 {
+		_, _ = fmt.Fprint(os.Stderr, colors.Failure("No branches match ", query, ".\n"))
+		if !isInteractive() {
+			return "", actions.ErrExitSilently{ExitCode: 1}
+		}
+		matches = entries
+	}
+	if !isInteractive() {
+		if query == "" {
+			_, _ = fmt.Fprint(os.Stderr, colors.Failure("Cannot ask which branch to switch to: not running interactively.\n"))
+		} else {
+			_, _ = fmt.Fprint(os.Stderr, colors.Failure("Multiple branches match ", query, ":\n"))
+		}
+		printSwitchEntries(matches, currentBranch)
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			colors.Faint("  - Use "), colors.CliCmd("av stack switch <query>"),
+			colors.Faint(" with a query that matches a single branch.\n"),
+		)
+		return "", actions.ErrExitSilently{ExitCode: 1}
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		printSwitchEntries(matches, currentBranch)
+		_, _ = fmt.Fprintf(os.Stderr, "Branch number or search text (empty to cancel): ")
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		input = strings.TrimSpace(input)
+		if input == "" {
+			_, _ = fmt.Fprint(os.Stderr, "Nothing was checked out.\n")
+			return "", nil
+		}
+		if i, err := strconv.Atoi(input); err == nil {
+			if i >= 1 && i <= len(matches) {
+				return matches[i-1].Name, nil
+			}
+			_, _ = fmt.Fprint(os.Stderr, colors.Failure("There is no branch number ", i, ".\n"))
+			continue
+		}
+		filtered := filterSwitchEntries(entries, input)
+		switch len(filtered) {
+		case 0:
+			_, _ = fmt.Fprint(os.Stderr, colors.Failure("No branches match ", input, ".\n"))
+			matches = entries
+		case 1:
+			return filtered[0].Name, nil
+		default:
+			matches = filtered
+		}
+	}
+}
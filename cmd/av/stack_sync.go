@@ -1,14 +1,16 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"os"
-	"path"
 	"strings"
 
 	"emperror.dev/errors"
 	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/gh"
 	"github.com/aviator-co/av/internal/git"
 	"github.com/aviator-co/av/internal/meta"
 	"github.com/aviator-co/av/internal/utils/colors"
@@ -16,6 +18,7 @@ import (
 	"github.com/kr/text"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
 )
 
@@ -26,10 +29,23 @@ var stackSyncFlags struct {
 	Abort    bool
 	Continue bool
 	Skip     bool
+	// If true, only report which branches would conflict.
+	Check bool
+	// If true, only report what the sync would do.
+	DryRun bool
+	// If true, stash the changes in the working tree before the sync and
+	// reapply them afterwards (see config.Sync.Autostash).
+	Autostash bool
+	// How the branches are updated with the changes of their parents (see
+	// config.Sync.Strategy).
+	Strategy string
+	// If true, only sync the branches that the sync would change (see
+	// actions.DirtyBranches).
+	OnlyDirty bool
 }
 
 var stackSyncCmd = &cobra.Command{
-	Use:   "sync",
+	Use:   "sync [<branch>...]",
 	Short: "Synchronize stacked branches",
 	Long: strings.TrimSpace(`
 Synchronize stacked branches to be up-to-date with their parent branches.
@@ -42,13 +58,21 @@ If the --current flag is given, this command will not recursively sync dependent
 branches of the current branch within the stack. This allows you to make changes
 to the current branch before syncing the rest of the stack.
 
+If branches are given, only those branches and their descendants are synced
+(or, with --current, only the given branches) instead of the whole stack of the
+current branch.
+
 If the --trunk flag is given, this command will synchronize changes from the
 latest commit to the repository base branch (e.g., main or master) into the
 stack. This is useful for rebasing a whole stack on the latest changes from the
 base branch.
+
+With --strategy=merge, the parent of each branch is merged into the branch
+instead of rebasing the branch, so that the branches never have to be
+force-pushed.
 `),
-	Args: cobra.NoArgs,
-	RunE: func(cmd *cobra.Command, args []string) error {
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) (reterr error) {
 		ctx := context.Background()
 
 		repo, err := getRepo()
@@ -73,21 +97,86 @@ base branch.
 			return err
 		}
 
+		strategyName := config.Av.Sync.Strategy
+		if cmd.Flags().Changed("strategy") {
+			strategyName = stackSyncFlags.Strategy
+		}
+		strategy, err := actions.ParseSyncStrategy(strategyName)
+		if err != nil {
+			return err
+		}
+		if strategy == actions.SyncStrategyMerge && stackSyncFlags.Parent != "" {
+			return errors.New("--parent rebases the branch, so it can't be used with the merge strategy (use --strategy=rebase)")
+		}
+		if stackSyncFlags.ReviewersKeep && stackSyncFlags.NoPush {
+			return errors.New("--reviewers-keep can't be used with --no-push")
+		}
+		// If no strategy options are given, the sync.strategyOptions config is
+		// used when the branches are rebased.
+		var strategyOptions []string
+		if cmd.Flags().Changed("strategy-option") {
+			strategyOptions, err = actions.ParseStrategyOptions(stackSyncFlags.StrategyOptions)
+			if err != nil {
+				return err
+			}
+		}
+		summaryComment := config.Av.Sync.SummaryComment
+		if cmd.Flags().Changed("summary-comment") {
+			if stackSyncFlags.SummaryComment && stackSyncFlags.NoPush {
+				return errors.New("--summary-comment can't be used with --no-push")
+			}
+			summaryComment = stackSyncFlags.SummaryComment
+		}
+
+		// The branches to sync if branches were given (see stackSyncSubset).
+		var subset []string
+		if len(args) > 0 {
+			if stackSyncFlags.All || stackSyncFlags.Parent != "" ||
+				stackSyncFlags.Continue || stackSyncFlags.Abort || stackSyncFlags.Skip {
+				return errors.New("branches can't be given with --all, --parent, --continue, --abort, or --skip")
+			}
+			subset, err = stackSyncSubset(tx, args, stackSyncFlags.Current)
+			if err != nil {
+				return err
+			}
+		}
+
+		if stackSyncFlags.Check || stackSyncFlags.DryRun {
+			if state.CurrentBranch != "" {
+				return errors.New("a sync is in progress: use --continue or --abort")
+			}
+			return stackSyncCheck(repo, tx, subset, stackSyncFlags.DryRun)
+		}
+
 		if stackSyncFlags.Abort {
-			if state.CurrentBranch == "" || state.Continuation == nil {
+			if state.CurrentBranch == "" {
 				// Try to clear the state file if it exists just to be safe.
 				_ = actions.WriteStackSyncState(repo, nil)
 				return errors.New("no sync in progress")
 			}
 
-			// Abort the rebase if we need to
-			if stat, _ := os.Stat(path.Join(repo.GitDir(), "REBASE_HEAD")); stat != nil {
+			// Abort the rebase (or merge) if we need to
+			if op, err := repo.OperationInProgress(); err != nil {
+				return err
+			} else if op == git.OperationRebase {
 				if _, err := repo.Rebase(git.RebaseOpts{Abort: true}); err != nil {
 					return errors.WrapIf(err, "failed to abort in-progress rebase")
 				}
+			} else if op == git.OperationMerge && state.Config.Strategy == actions.SyncStrategyMerge {
+				if _, err := repo.Merge(git.MergeOpts{Abort: true}); err != nil {
+					return err
+				}
 			}
 
-			err := actions.WriteStackSyncState(repo, nil)
+			// Undo the changes to the branches that were already synced.
+			restored, err := actions.RestoreOriginalBranches(repo, tx, state)
+			if err != nil {
+				return err
+			}
+			if err := tx.Commit(); err != nil {
+				return err
+			}
+			err = actions.WriteStackSyncState(repo, nil)
 			if err != nil {
 				return errors.Wrap(err, "failed to reset stack sync state")
 			}
@@ -95,20 +184,45 @@ base branch.
 				return errors.Wrap(err, "failed to checkout original branch")
 			}
 			_, _ = fmt.Fprintf(os.Stderr, "Aborted stack sync for branch %q\n", state.CurrentBranch)
+			for _, name := range restored {
+				_, _ = fmt.Fprint(os.Stderr, "  - restored ", colors.UserInput(name), "\n")
+			}
+			if state.Autostash != "" {
+				return actions.ApplyAutostash(repo, state.Autostash)
+			}
 			return nil
 		}
 
+		if stackSyncFlags.Skip && state.Config.Strategy == actions.SyncStrategyMerge {
+			return errors.New("--skip can't be used when merging: resolve the conflicts or use --abort")
+		}
+
+		// Changes in the working tree are stashed when a new sync starts (see
+		// below), so they don't need to be checked.
+		autostash := config.Av.Sync.Autostash
+		if cmd.Flags().Changed("autostash") {
+			autostash = stackSyncFlags.Autostash
+		}
+		autostash = autostash && !stackSyncFlags.Continue && !stackSyncFlags.Skip && state.CurrentBranch == ""
 		if !stackSyncFlags.Skip {
-			// Make sure all changes are staged unless --skip. git rebase --skip will
-			// clean up the changes.
-			diff, err := repo.Diff(&git.DiffOpts{Quiet: true})
-			if err != nil {
+			// Make sure the working tree is clean unless --skip. git rebase --skip
+			// will clean up the changes.
+			if err := ensureCleanWorkingTreeForSync(repo, stackSyncFlags.Continue, autostash); err != nil {
 				return err
 			}
-			if !diff.Empty {
-				return errors.New(
-					"refusing to sync: there are unstaged changes in the working tree (use `git add` to stage changes)",
+		}
+
+		if !stackSyncFlags.Continue && !stackSyncFlags.Skip && state.CurrentBranch != "" {
+			// The rebase that stopped the sync was finished (or aborted) with
+			// Git directly, so pick up where the sync left off instead of
+			// starting over from the root of the stack.
+			if op, err := repo.OperationInProgress(); err != nil {
+				return err
+			} else if op == git.OperationNone {
+				_, _ = fmt.Fprint(os.Stderr,
+					"Resuming the sync that stopped at ", colors.UserInput(state.CurrentBranch), "...\n\n",
 				)
+				stackSyncFlags.Continue = true
 			}
 		}
 
@@ -121,6 +235,9 @@ base branch.
 			if state.CurrentBranch != "" {
 				return errors.New("a sync is already in progress: use --continue or --abort")
 			}
+			if err := ensureNoGitOperationInProgress(repo); err != nil {
+				return err
+			}
 
 			// NOTE: We have to read the current branch name from the stored
 			// state if we're continuing a sync (the case above) because it's
@@ -135,13 +252,76 @@ base branch.
 			}
 
 			state.OriginalBranch = state.CurrentBranch
+			switch {
+			case len(subset) > 0:
+				err = ensureStacksNotFrozen(tx, subset...)
+			case !stackSyncFlags.All:
+				err = ensureStacksNotFrozen(tx, state.CurrentBranch)
+			}
+			if err != nil {
+				return err
+			}
+			switch {
+			case stackSyncFlags.All:
+				err = snapshotBranches(repo, tx, maps.Keys(tx.AllBranches())...)
+			case len(subset) > 0:
+				err = snapshotBranches(repo, tx, subset...)
+			default:
+				err = snapshotBranches(repo, tx, state.CurrentBranch)
+			}
+			if err != nil {
+				return err
+			}
+			// Remember the original state of the branches so that they can be
+			// restored with --abort.
+			var scope []string
+			if stackSyncFlags.All {
+				scope = maps.Keys(tx.AllBranches())
+			} else if len(subset) > 0 {
+				scope = subset
+			} else if stack, err := meta.StackBranches(tx, state.CurrentBranch); err == nil {
+				scope = stack
+			}
+			if err := actions.RecordOriginalBranches(repo, tx, &state, scope); err != nil {
+				return err
+			}
+			if autostash {
+				state.Autostash, err = actions.CreateAutostash(repo, "av stack sync autostash")
+				if err != nil {
+					return err
+				}
+				defer func() {
+					// If the sync failed before it stopped at a conflict (which
+					// keeps the changes stashed until it's done), reapply the
+					// changes right away if they'd end up on the right branch.
+					if reterr == nil || state.Autostash == "" {
+						return
+					}
+					if current, err := syncConflictBranch(repo); err != nil || current != "" {
+						return
+					}
+					if current, err := repo.CurrentBranchName(); err == nil && current == state.OriginalBranch {
+						if err := actions.ApplyAutostash(repo, state.Autostash); err == nil {
+							return
+						}
+					}
+					_, _ = fmt.Fprint(os.Stderr,
+						colors.Warning("The changes that were stashed before the sync are still in the stash ("),
+						colors.UserInput(git.ShortSha(state.Autostash)), colors.Warning(")."), "\n",
+					)
+				}()
+			}
 			state.Config = actions.StackSyncConfig{
-				Current: stackSyncFlags.Current,
-				Trunk:   stackSyncFlags.Trunk,
-				NoPush:  stackSyncFlags.NoPush,
-				NoFetch: stackSyncFlags.NoFetch,
-				Parent:  stackSyncFlags.Parent,
-				Prune:   stackSyncFlags.Prune,
+				Current:         stackSyncFlags.Current,
+				Trunk:           stackSyncFlags.Trunk,
+				NoPush:          stackSyncFlags.NoPush,
+				NoFetch:         stackSyncFlags.NoFetch,
+				Parent:          stackSyncFlags.Parent,
+				Prune:           stackSyncFlags.Prune,
+				Strategy:        strategy,
+				StrategyOptions: strategyOptions,
+				ReviewersKeep:   stackSyncFlags.ReviewersKeep,
+				SummaryComment:  summaryComment,
 			}
 		}
 
@@ -156,13 +336,23 @@ base branch.
 				return err
 			}
 			opts := actions.ReparentOpts{
-				Branch:         state.CurrentBranch,
-				NewParent:      state.Config.Parent,
-				NewParentTrunk: state.Config.Parent == defaultBranch,
+				Branch:          state.CurrentBranch,
+				NewParent:       state.Config.Parent,
+				NewParentTrunk:  isReparentTargetTrunk(tx, state.Config, defaultBranch),
+				StrategyOptions: state.Config.StrategyOptions,
 			}
 			if stackSyncFlags.Continue || stackSyncFlags.Skip {
 				res, err = actions.ReparentSkipContinue(repo, tx, opts, stackSyncFlags.Skip)
 			} else {
+				if !opts.NewParentTrunk {
+					if err := validateParentBranch(repo, tx, opts.NewParent, defaultBranch); err != nil {
+						return err
+					}
+				} else if state.Config.Trunk && shouldFetchTrunks(state.Config.NoFetch) {
+					if err := actions.FetchTrunk(repo, opts.NewParent); err != nil {
+						return err
+					}
+				}
 				res, err = actions.Reparent(repo, tx, opts)
 			}
 			if err != nil {
@@ -208,6 +398,9 @@ base branch.
 				)
 			}
 			branchesToSync = state.Branches[currentIdx:]
+		} else if len(subset) > 0 {
+			branchesToSync = subset
+			state.Branches = branchesToSync
 		} else if state.Config.Current {
 			// If we're continuing, we assume the previous branches are already
 			// synced correctly and we just need to sync the subsequent
@@ -223,6 +416,13 @@ base branch.
 				if !br.IsStackRoot() {
 					continue
 				}
+				if br.Freeze != nil {
+					_, _ = fmt.Fprint(os.Stderr,
+						"Skipping the stack of ", colors.UserInput(br.Name),
+						colors.Faint(" (frozen)"), "\n",
+					)
+					continue
+				}
 				branchesToSync = append(branchesToSync, br.Name)
 				nextBranches := meta.SubsequentBranches(tx, branchesToSync[len(branchesToSync)-1])
 				branchesToSync = append(branchesToSync, nextBranches...)
@@ -241,10 +441,72 @@ base branch.
 		}
 		// Either way (--continue or not), we sync all subsequent branches
 
+		if !stackSyncFlags.Continue && !stackSyncFlags.Skip && !state.Config.NoFetch {
+			branchesToSync, err = handleRemotelyDeletedBranches(repo, tx, branchesToSync)
+			if err != nil {
+				return err
+			}
+			state.Branches = branchesToSync
+		}
+		if !stackSyncFlags.Continue && !stackSyncFlags.Skip && state.Config.Trunk {
+			if err := confirmStackTrunks(repo, tx, branchesToSync); err != nil {
+				return err
+			}
+			if shouldFetchTrunks(state.Config.NoFetch) {
+				if err := actions.FetchTrunks(repo, tx, branchesToSync); err != nil {
+					return err
+				}
+			}
+		}
+
+		if stackSyncFlags.OnlyDirty && !stackSyncFlags.Continue && !stackSyncFlags.Skip {
+			dirty, err := actions.DirtyBranches(repo, tx, branchesToSync, actions.DirtyBranchesOpts{
+				ToTrunk: state.Config.Trunk,
+				NoPush:  state.Config.NoPush,
+			})
+			if err != nil {
+				return err
+			}
+			if clean := len(branchesToSync) - len(dirty); clean > 0 {
+				_, _ = fmt.Fprint(os.Stderr,
+					"Skipping ", clean, " branch(es) that are already up-to-date",
+					colors.Faint(" (--only-dirty)"), "\n\n",
+				)
+			}
+			if len(dirty) == 0 {
+				// There's nothing to sync, so don't bother GitHub either.
+				_, _ = fmt.Fprint(os.Stderr, colors.Success("All branches are already up-to-date."), "\n")
+				if err := actions.WriteStackSyncState(repo, nil); err != nil {
+					return errors.Wrap(err, "failed to reset stack sync state")
+				}
+				if err := tx.Commit(); err != nil {
+					return err
+				}
+				if state.Autostash != "" {
+					return actions.ApplyAutostash(repo, state.Autostash)
+				}
+				return nil
+			}
+			branchesToSync = dirty
+			state.Branches = branchesToSync
+		}
+
 		logrus.WithField("branches", branchesToSync).Debug("determined branches to sync")
-		client, err := getGitHubClient()
-		if err != nil {
-			return err
+		// GitHub is only needed to fetch the pull requests and to push the
+		// branches, so a purely local sync (e.g., the one that av stack
+		// rebase or av stack reparent continues) works without a token.
+		var client *gh.Client
+		if !state.Config.NoFetch || !state.Config.NoPush {
+			client, err = getGitHubClient()
+			if err != nil {
+				return err
+			}
+		}
+		// Pushing the branches updates their pull requests as well.
+		if !state.Config.NoPush {
+			if err := ensureGitHubWriteAccess(ctx, client, tx); err != nil {
+				return err
+			}
 		}
 
 		var syncOpts []actions.SyncStackOpt
@@ -260,6 +522,292 @@ base branch.
 	},
 }
 
+// stackSyncSubset returns the branches to sync when branches are given to av
+// stack sync: each of the given branches and (unless current) its descendants,
+// in the order of their stacks so that parents are synced before their
+// children. Branches whose parents aren't synced are rebased onto the current
+// commit of their parent.
+func stackSyncSubset(tx meta.ReadTx, names []string, current bool) ([]string, error) {
+	selected := make(map[string]bool)
+	var roots []string
+	for _, name := range names {
+		if _, ok := tx.Branch(name); !ok {
+			return nil, errors.Errorf("branch %q is not tracked by av", name)
+		}
+		root, ok := meta.Root(tx, name)
+		if !ok {
+			return nil, errors.Errorf("branch %q is not in a stack", name)
+		}
+		if !slices.Contains(roots, root) {
+			roots = append(roots, root)
+		}
+		selected[name] = true
+		if !current {
+			for _, descendant := range meta.SubsequentBranches(tx, name) {
+				selected[descendant] = true
+			}
+		}
+	}
+
+	var branches []string
+	for _, root := range roots {
+		stack, err := meta.StackBranches(tx, root)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range stack {
+			if selected[name] {
+				branches = append(branches, name)
+			}
+		}
+	}
+	return branches, nil
+}
+
+// runStackSync syncs the stack of the current branch (as if `av stack sync` was
+// run with the given config). It's used by commands that sync as part of a
+// larger operation.
+func runStackSync(config actions.StackSyncConfig) error {
+	stackSyncFlags.StackSyncConfig = config
+	stackSyncFlags.All = false
+	stackSyncFlags.Check = false
+	stackSyncFlags.DryRun = false
+	stackSyncFlags.Abort = false
+	stackSyncFlags.Continue = false
+	stackSyncFlags.Skip = false
+	stackSyncFlags.OnlyDirty = false
+	return stackSyncCmd.RunE(stackSyncCmd, nil)
+}
+
+// abortStackSync aborts the in-progress sync (as if `av stack sync --abort` was
+// run).
+func abortStackSync() error {
+	stackSyncFlags.Abort = true
+	defer func() { stackSyncFlags.Abort = false }()
+	return stackSyncCmd.RunE(stackSyncCmd, nil)
+}
+
+// syncConflictBranch returns the branch on which an in-progress sync stopped
+// because of a conflict (or an empty string if no sync is in progress).
+func syncConflictBranch(repo *git.Repo) (string, error) {
+	state, err := actions.ReadStackSyncState(repo)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return state.CurrentBranch, nil
+}
+
+// ensureCleanWorkingTreeForSync makes sure that the working tree doesn't contain
+// changes that would get in the way of rebasing the stack (or that would end up
+// in the wrong branch). Changes to tracked files always block the sync (except
+// for staged changes when continuing a sync, which are the resolution of the
+// conflict, and with autostash, since they'll be stashed). Whether untracked
+// files and modified submodules block the sync is configurable.
+func ensureCleanWorkingTreeForSync(repo *git.Repo, continuing bool, autostash bool) error {
+	status, err := repo.Status()
+	if err != nil {
+		return err
+	}
+
+	type dirtyPaths struct {
+		what   string
+		paths  []string
+		advice string
+	}
+	var dirty []dirtyPaths
+	if autostash {
+		status.Unstaged = nil
+		status.Staged = nil
+	}
+	if unstaged := append(status.Unstaged, status.Unmerged...); len(unstaged) > 0 {
+		advice := "commit or stash them first"
+		if continuing {
+			advice = "use `git add` to stage the resolved conflicts"
+		}
+		dirty = append(dirty, dirtyPaths{"unstaged changes", unstaged, advice})
+	}
+	if !continuing && len(status.Staged) > 0 {
+		dirty = append(dirty, dirtyPaths{"staged changes", status.Staged, "commit or stash them first"})
+	}
+	if continuing {
+		// Make sure we don't commit unresolved conflicts into the rewritten
+		// branch.
+		markers, err := repo.StagedConflictMarkers()
+		if err != nil {
+			return err
+		}
+		if len(markers) > 0 {
+			dirty = append(dirty, dirtyPaths{
+				"unresolved conflict markers", markers,
+				"resolve the conflicts and stage the files with `git add` first",
+			})
+		}
+	}
+	if !config.Av.Sync.IgnoreUntracked && len(status.Untracked) > 0 {
+		dirty = append(dirty, dirtyPaths{
+			"untracked files", status.Untracked,
+			"commit, stash, or remove them first (or set sync.ignoreUntracked to true)",
+		})
+	}
+	if !config.Av.Sync.IgnoreSubmodules && len(status.Submodules) > 0 {
+		dirty = append(dirty, dirtyPaths{
+			"modified submodules", status.Submodules,
+			"run `git submodule update` first (or set sync.ignoreSubmodules to true)",
+		})
+	}
+	if len(dirty) == 0 {
+		return nil
+	}
+
+	const maxPaths = 5
+	for _, d := range dirty {
+		_, _ = fmt.Fprint(os.Stderr,
+			colors.Failure("Refusing to sync: the working tree has ", d.what, ":"), "\n",
+		)
+		for i, p := range d.paths {
+			if i == maxPaths {
+				_, _ = fmt.Fprint(os.Stderr, "    ... and ", len(d.paths)-maxPaths, " more\n")
+				break
+			}
+			_, _ = fmt.Fprint(os.Stderr, "    ", colors.UserInput(p), "\n")
+		}
+		_, _ = fmt.Fprint(os.Stderr, colors.Troubleshooting("  - ", d.advice), "\n")
+	}
+	return actions.ErrExitSilently{ExitCode: 1}
+}
+
+// shouldFetchTrunks returns true if the trunks should be fetched before syncing
+// with --trunk (see config.Sync.FetchTrunk).
+func shouldFetchTrunks(noFetch bool) bool {
+	return !noFetch && config.Av.Sync.FetchTrunk
+}
+
+// isReparentTargetTrunk returns true if the new parent given with --parent
+// should be considered a trunk. Besides the branches that are already trunks,
+// an untracked branch becomes a new trunk if --trunk is given as well (e.g., to
+// move a stack onto a release branch).
+func isReparentTargetTrunk(tx meta.ReadTx, config actions.StackSyncConfig, defaultBranch string) bool {
+	if meta.IsTrunk(tx, config.Parent, defaultBranch) {
+		return true
+	}
+	_, tracked := tx.Branch(config.Parent)
+	return config.Trunk && !tracked
+}
+
+// confirmStackTrunks checks whether any of the given branches belong to a stack
+// that is based on a trunk other than the repository's default branch (e.g., a
+// release branch). Such stacks are rebased onto their own trunk with --trunk,
+// which might not be what the user expects, so ask for confirmation first.
+func confirmStackTrunks(repo *git.Repo, tx meta.ReadTx, branches []string) error {
+	defaultBranch, err := repo.DefaultBranch()
+	if err != nil {
+		// Without a default branch, there's nothing to compare against.
+		logrus.WithError(err).Debug("failed to determine default branch, skipping trunk check")
+		return nil
+	}
+	var trunks []string
+	for _, branch := range branches {
+		trunk, ok := meta.Trunk(tx, branch)
+		if ok && trunk != defaultBranch && !slices.Contains(trunks, trunk) {
+			trunks = append(trunks, trunk)
+		}
+	}
+	if len(trunks) == 0 {
+		return nil
+	}
+
+	for _, trunk := range trunks {
+		_, _ = fmt.Fprint(os.Stderr,
+			"The stack is based on ", colors.UserInput(trunk),
+			" (not on the default branch ", colors.UserInput(defaultBranch), ")",
+			" and will be rebased onto the latest commit of ", colors.UserInput("origin/", trunk), ".\n",
+		)
+	}
+	if !isInteractive() {
+		return nil
+	}
+	_, _ = fmt.Fprint(os.Stderr, "Continue? [Y/n]: ")
+	choice, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if answer := strings.ToLower(strings.TrimSpace(choice)); answer != "" && answer != "y" {
+		_, _ = fmt.Fprint(os.Stderr,
+			colors.Failure("Aborting. "),
+			"Use ", colors.CliCmd("av stack sync --parent <branch>"),
+			" to move the stack onto a different trunk.\n",
+		)
+		return actions.ErrExitSilently{ExitCode: 1}
+	}
+	return nil
+}
+
+// handleRemotelyDeletedBranches checks whether the remote branches of any of the
+// given branches were deleted since they were pushed and asks the user whether
+// to push them again or to remove them from the stack. The branches that should
+// still be synced are returned.
+func handleRemotelyDeletedBranches(
+	repo *git.Repo,
+	tx meta.WriteTx,
+	branches []string,
+) ([]string, error) {
+	deleted, err := actions.RemotelyDeletedBranches(repo, tx, branches)
+	if err != nil {
+		return nil, err
+	}
+	if len(deleted) == 0 {
+		return branches, nil
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for _, name := range deleted {
+		branch, _ := tx.Branch(name)
+		actions.PrintRemoteBranchDeleted(name, branch.PullRequest)
+		if !isInteractive() {
+			_, _ = fmt.Fprint(os.Stderr,
+				colors.Troubleshooting("      - the branch will be synced but not pushed; use "),
+				colors.CliCmd("av pr create"),
+				colors.Troubleshooting(" to push it again or "),
+				colors.CliCmd("av stack orphan"),
+				colors.Troubleshooting(" to stop tracking it\n"),
+			)
+			continue
+		}
+
+		_, _ = fmt.Fprint(os.Stderr,
+			"    Push it again [p], remove it from the stack [r], or keep it as-is [k]? [p/r/K]: ",
+		)
+		choice, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToLower(strings.TrimSpace(choice)) {
+		case "p":
+			// Drop the stale remote-tracking branch (if any) so that the push
+			// expects the remote branch to not exist.
+			if _, err := repo.Git("update-ref", "-d", "refs/remotes/origin/"+name); err != nil {
+				return nil, err
+			}
+			if err := actions.Push(repo, name, actions.PushOpts{Force: actions.ForceWithLease}); err != nil {
+				return nil, err
+			}
+		case "r":
+			if err := actions.RemoveBranchFromStack(repo, tx, name); err != nil {
+				return nil, err
+			}
+			branches = slices.DeleteFunc(branches, func(b string) bool { return b == name })
+			_, _ = fmt.Fprint(os.Stderr,
+				"  - removed ", colors.UserInput(name),
+				" from the stack (the local branch was kept)\n",
+			)
+		}
+	}
+	return branches, nil
+}
+
 func init() {
 	stackSyncCmd.Flags().BoolVar(
 		&stackSyncFlags.All, "all", false,
@@ -275,12 +823,32 @@ func init() {
 	)
 	stackSyncCmd.Flags().BoolVar(
 		&stackSyncFlags.NoFetch, "no-fetch", false,
-		"do not fetch latest PR information from GitHub",
+		"do not fetch the latest trunk commits and PR information",
+	)
+	stackSyncCmd.Flags().BoolVar(
+		&stackSyncFlags.Autostash, "autostash", false,
+		"stash the changes in the working tree before syncing and reapply them afterwards",
 	)
 	stackSyncCmd.Flags().BoolVar(
 		&stackSyncFlags.Prune, "prune", false,
 		"delete the merged branches",
 	)
+	stackSyncCmd.Flags().StringVar(
+		&stackSyncFlags.Strategy, "strategy", "",
+		"update the branches by rebasing them onto their parents (rebase) or by merging\ntheir parents into them (merge); defaults to the sync.strategy config or rebase",
+	)
+	stackSyncCmd.Flags().StringArrayVarP(
+		&stackSyncFlags.StrategyOptions, "strategy-option", "X", nil,
+		"pass the option to the merge strategy when rebasing (or merging) the branches,\ne.g. theirs or histogram (can be given multiple times); defaults to the\nsync.strategyOptions config",
+	)
+	stackSyncCmd.Flags().BoolVar(
+		&stackSyncFlags.ReviewersKeep, "reviewers-keep", false,
+		"re-request reviews from the reviewers who approved a pull request before it was\npushed, with a comment that summarizes the changes",
+	)
+	stackSyncCmd.Flags().BoolVar(
+		&stackSyncFlags.SummaryComment, "summary-comment", false,
+		"post (or update) a comment on each pushed pull request that summarizes what\nchanged since the previous push; defaults to the sync.summaryComment config",
+	)
 	// TODO[mvp]: better name (--to-trunk?)
 	stackSyncCmd.Flags().BoolVar(
 		&stackSyncFlags.Trunk, "trunk", false,
@@ -302,9 +870,21 @@ func init() {
 		&stackSyncFlags.Parent, "parent", "",
 		"parent branch to rebase onto",
 	)
+	stackSyncCmd.Flags().BoolVar(
+		&stackSyncFlags.OnlyDirty, "only-dirty", false,
+		"skip the branches that are already up-to-date with their parents and their\nremote branches (without checking them out)",
+	)
+	stackSyncCmd.Flags().BoolVar(
+		&stackSyncFlags.Check, "check", false,
+		"only report which branches would conflict (without changing anything)",
+	)
+	stackSyncCmd.Flags().BoolVar(
+		&stackSyncFlags.DryRun, "dry-run", false,
+		"show which branches would be rebased and pushed (without changing anything)",
+	)
 
 	stackSyncCmd.MarkFlagsMutuallyExclusive("current", "all")
 	stackSyncCmd.MarkFlagsMutuallyExclusive("current", "trunk")
-	stackSyncCmd.MarkFlagsMutuallyExclusive("trunk", "parent")
-	stackSyncCmd.MarkFlagsMutuallyExclusive("continue", "abort", "skip")
+	stackSyncCmd.MarkFlagsMutuallyExclusive("continue", "abort", "skip", "check", "dry-run")
+	stackSyncCmd.MarkFlagsMutuallyExclusive("only-dirty", "continue", "abort", "skip")
 }
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+)
+
+// stackSyncCheck predicts which branches `av stack sync` (with the current
+// flags) would run into conflicts on, without changing anything (see
+// actions.CheckSync). If subset isn't empty, only those branches are checked
+// (see stackSyncSubset). With dryRun, it also shows which branches would be
+// pushed, and conflicts aren't treated as a failure.
+func stackSyncCheck(repo *git.Repo, tx meta.ReadTx, subset []string, dryRun bool) error {
+	currentBranch, err := repo.CurrentBranchName()
+	if err != nil {
+		return err
+	}
+	var branches []string
+	switch {
+	case len(subset) > 0:
+		branches = subset
+	case stackSyncFlags.All:
+		for _, br := range tx.AllBranches() {
+			if br.IsStackRoot() && br.Freeze == nil {
+				branches = append(branches, br.Name)
+				branches = append(branches, meta.SubsequentBranches(tx, br.Name)...)
+			}
+		}
+	case stackSyncFlags.Current:
+		branches = []string{currentBranch}
+	case stackSyncFlags.Parent != "":
+		branches = append([]string{currentBranch}, meta.SubsequentBranches(tx, currentBranch)...)
+	default:
+		branches, err = meta.StackBranches(tx, currentBranch)
+		if err != nil {
+			return err
+		}
+	}
+
+	if stackSyncFlags.Trunk && shouldFetchTrunks(stackSyncFlags.NoFetch) {
+		if err := actions.FetchTrunks(repo, tx, branches); err != nil {
+			return err
+		}
+	}
+
+	results, err := actions.CheckSync(repo, tx, branches, actions.SyncCheckOpts{
+		ToTrunk: stackSyncFlags.Trunk,
+		Parent:  stackSyncFlags.Parent,
+	})
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		_, _ = fmt.Fprint(os.Stderr, "Dry run: nothing will be changed.\n")
+	}
+	conflicts := 0
+	rebased := make(map[string]bool)
+	for _, res := range results {
+		rebased[res.Branch] = !res.ReadOnly && !res.UpToDate && res.NewHead != ""
+		_, _ = fmt.Fprint(os.Stderr, "  - ", colors.UserInput(res.Branch), ": ")
+		switch {
+		case res.ReadOnly && res.UpToDate:
+			_, _ = fmt.Fprint(os.Stderr, "read-only, already up-to-date with its remote branch\n")
+		case res.ReadOnly:
+			_, _ = fmt.Fprint(os.Stderr,
+				"read-only, would be updated to its remote branch (",
+				colors.UserInput(git.ShortSha(res.NewHead)), ")\n",
+			)
+		case res.BlockedBy != "":
+			_, _ = fmt.Fprint(os.Stderr, colors.Faint(
+				"can't be checked until the conflicts in ", res.BlockedBy, " are resolved",
+			), "\n")
+		case len(res.Conflicts) > 0:
+			conflicts++
+			_, _ = fmt.Fprint(os.Stderr,
+				colors.Failure("would conflict with ", res.Parent, " in:"), "\n",
+			)
+			for _, file := range res.Conflicts {
+				_, _ = fmt.Fprint(os.Stderr, "      ", file, "\n")
+			}
+		case res.UpToDate:
+			_, _ = fmt.Fprint(os.Stderr, "already up-to-date with ", colors.UserInput(res.Parent))
+			if dryRun && syncPushPrediction(repo, tx, res) == syncForcePush {
+				_, _ = fmt.Fprint(os.Stderr, ", but would be pushed (its remote branch is outdated)")
+			}
+			_, _ = fmt.Fprint(os.Stderr, "\n")
+		case dryRun:
+			// The new commit of a parent that is rebased as well is only
+			// simulated, so there's no point in showing it.
+			onto := colors.UserInput(git.ShortSha(res.NewParentCommit))
+			if rebased[res.Parent] {
+				onto = "once it's rebased"
+			}
+			_, _ = fmt.Fprint(os.Stderr,
+				"would be rebased onto ", colors.UserInput(res.Parent), " (", onto, ")",
+				syncPushPrediction(repo, tx, res), "\n",
+			)
+		default:
+			_, _ = fmt.Fprint(os.Stderr,
+				colors.Success("would be rebased onto ", res.Parent, " without conflicts"), "\n",
+			)
+		}
+	}
+	if dryRun {
+		if conflicts > 0 {
+			_, _ = fmt.Fprint(os.Stderr,
+				"\n", colors.Failure("The sync would stop at the first conflict."), "\n",
+			)
+		}
+		return nil
+	}
+	if conflicts > 0 {
+		_, _ = fmt.Fprint(os.Stderr,
+			"\n", colors.Failure(fmt.Sprintf("%d branch(es) would conflict.", conflicts)), "\n",
+		)
+		return actions.ErrExitSilently{ExitCode: 1}
+	}
+	_, _ = fmt.Fprint(os.Stderr, "\n", colors.Success("No conflicts expected."), "\n")
+	return nil
+}
+
+const syncForcePush = ", and force-pushed"
+
+// syncPushPrediction describes whether the sync would push the branch (see
+// actions.SyncBranch): only branches with a pull request whose remote branch
+// differs from the synced branch are pushed.
+func syncPushPrediction(repo *git.Repo, tx meta.ReadTx, res actions.SyncCheckResult) string {
+	if stackSyncFlags.NoPush {
+		return ""
+	}
+	branch, _ := tx.Branch(res.Branch)
+	remote, err := repo.RevParse(&git.RevParse{Rev: "refs/remotes/origin/" + res.Branch})
+	switch {
+	case branch.WIP:
+		return colors.Faint(", not pushed (work in progress)")
+	case branch.PullRequest == nil || branch.PullRequest.ID == "":
+		return colors.Faint(", not pushed (no pull request)")
+	case err != nil:
+		return colors.Faint(", not pushed (no remote branch)")
+	case remote == res.NewHead:
+		return ""
+	default:
+		return syncForcePush
+	}
+}
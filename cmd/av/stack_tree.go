@@ -1,10 +1,40 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/avgql"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
 	"github.com/aviator-co/av/internal/utils/stackutils"
+	"github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+var stackTreeFlags struct {
+	// If true, remove the metadata of branches that were deleted outside of av
+	// without asking.
+	PruneMetadata bool
+	// If true, show the review state of the pull requests.
+	Reviews bool
+	// Only show the stacks that have commits by this author (by default, the
+	// current Git user).
+	Author string
+	// If true, show the stacks of all users.
+	AllUsers bool
+}
+
 var stackTreeCmd = &cobra.Command{
 	Use:     "tree",
 	Aliases: []string{"t"},
@@ -19,6 +49,9 @@ var stackTreeCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		if err := pruneMissingBranchMetadata(repo, db); err != nil {
+			return err
+		}
 		tx := db.ReadTx()
 
 		var currentBranch string
@@ -31,10 +64,273 @@ var stackTreeCmd = &cobra.Command{
 			}
 		}
 
-		rootNodes := stackutils.BuildStackTree(repo, tx, currentBranch)
+		rootNodes, err := stackutils.BuildStackTree(repo, tx, currentBranch)
+		if err != nil {
+			return err
+		}
+		author := stackTreeFlags.Author
+		if author == "" && !stackTreeFlags.AllUsers {
+			// Not every repository has a user configured, in which case all
+			// stacks are shown.
+			author, _ = repo.Git("config", "user.email")
+		}
+		hidden := 0
+		if author != "" {
+			rootNodes, hidden = filterStacksByAuthor(repo, tx, rootNodes, currentBranch, author)
+		}
+		annotateLastSync(repo, tx, rootNodes)
+		if config.Av.Aviator.APIToken != "" {
+			annotateQueueStatus(tx, rootNodes)
+		}
+		if stackTreeFlags.Reviews {
+			client, err := getGitHubClient()
+			if err != nil {
+				return err
+			}
+			if err := annotateReviewStatus(client, tx, rootNodes); err != nil {
+				return err
+			}
+		}
 		for _, node := range rootNodes {
 			stackutils.PrintNode(0, currentBranch, true, node)
 		}
+		if hidden > 0 {
+			_, _ = fmt.Fprint(os.Stderr,
+				colors.Faint(fmt.Sprintf("%d stack(s) of other users are hidden (use ", hidden)),
+				colors.CliCmd("av stack tree --all-users"), colors.Faint(" to show them).\n"),
+			)
+		}
 		return nil
 	},
 }
+
+func init() {
+	stackTreeCmd.Flags().BoolVar(
+		&stackTreeFlags.PruneMetadata, "prune-metadata", false,
+		"remove the metadata of branches that were deleted outside of av without asking",
+	)
+	stackTreeCmd.Flags().BoolVar(
+		&stackTreeFlags.Reviews, "reviews", false,
+		"show whether the pull requests have the reviews they require (and who still needs to review them)",
+	)
+	stackTreeCmd.Flags().StringVar(
+		&stackTreeFlags.Author, "author", "",
+		"only show the stacks with commits by this author (name or email; defaults to the current Git user)",
+	)
+	stackTreeCmd.Flags().BoolVar(
+		&stackTreeFlags.AllUsers, "all-users", false,
+		"show the stacks of all users",
+	)
+	stackTreeCmd.MarkFlagsMutuallyExclusive("author", "all-users")
+}
+
+// filterStacksByAuthor removes the stacks that don't have any commits by the
+// given author from the tree. Stacks that don't have any commits yet (so their
+// author isn't known) and the stack of the current branch are always kept.
+// Returns the remaining tree and the number of stacks that were removed.
+func filterStacksByAuthor(
+	repo *git.Repo,
+	tx meta.ReadTx,
+	rootNodes []*stackutils.StackTreeNode,
+	currentBranch string,
+	author string,
+) ([]*stackutils.StackTreeNode, int) {
+	// visit returns whether the subtree contains the current branch or a
+	// branch by the author, and whether any of its branches has commits.
+	var visit func(node *stackutils.StackTreeNode) (keep bool, hasCommits bool)
+	visit = func(node *stackutils.StackTreeNode) (bool, bool) {
+		keep := node.Branch.BranchName == currentBranch
+		authors, err := actions.BranchAuthors(repo, tx, node.Branch.BranchName)
+		if err != nil {
+			logrus.WithError(err).WithField("branch", node.Branch.BranchName).
+				Debug("failed to determine the authors of the branch")
+		}
+		hasCommits := len(authors) > 0
+		keep = keep || actions.MatchesAuthor(authors, author)
+		for _, child := range node.Children {
+			childKeep, childHasCommits := visit(child)
+			keep = keep || childKeep
+			hasCommits = hasCommits || childHasCommits
+		}
+		return keep, hasCommits
+	}
+
+	var filtered []*stackutils.StackTreeNode
+	hidden := 0
+	for _, root := range rootNodes {
+		// The roots are the trunks and their children are the stacks.
+		var stacks []*stackutils.StackTreeNode
+		for _, stack := range root.Children {
+			if keep, hasCommits := visit(stack); keep || !hasCommits {
+				stacks = append(stacks, stack)
+			} else {
+				hidden++
+			}
+		}
+		if len(stacks) == 0 && root.Branch.BranchName != currentBranch {
+			continue
+		}
+		root.Children = stacks
+		filtered = append(filtered, root)
+	}
+	return filtered, hidden
+}
+
+// pruneMissingBranchMetadata looks for branches that are tracked by av but
+// were deleted outside of av (e.g., with `git branch -D`) and removes their
+// metadata if the user agrees (or if --prune-metadata was given). Otherwise,
+// the branches are shown as deleted in the tree.
+func pruneMissingBranchMetadata(repo *git.Repo, db meta.DB) error {
+	missing, err := actions.MissingBranches(repo, db.ReadTx())
+	if err != nil || len(missing) == 0 {
+		return err
+	}
+
+	if !stackTreeFlags.PruneMetadata {
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			"These branches are tracked by av but no longer exist: ",
+			colors.UserInput(strings.Join(missing, ", ")), "\n",
+		)
+		if !isInteractive() {
+			_, _ = fmt.Fprint(
+				os.Stderr,
+				colors.Faint("  - Use "), colors.CliCmd("av stack tree --prune-metadata"),
+				colors.Faint(" to stop tracking them.\n"),
+			)
+			return nil
+		}
+		_, _ = fmt.Fprint(os.Stderr, "Stop tracking them? [y/N]: ")
+		choice, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(strings.TrimSpace(choice), "y") {
+			return nil
+		}
+	}
+
+	tx := db.WriteTx()
+	if err := actions.PruneBranchMetadata(repo, tx, missing); err != nil {
+		tx.Abort()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, name := range missing {
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			"Removed the metadata of deleted branch ", colors.UserInput(name), "\n",
+		)
+	}
+	return nil
+}
+
+// annotateLastSync adds when the branches were last synced to the tree.
+func annotateLastSync(repo *git.Repo, tx meta.ReadTx, rootNodes []*stackutils.StackTreeNode) {
+	now := time.Now()
+	var visit func(node *stackutils.StackTreeNode)
+	visit = func(node *stackutils.StackTreeNode) {
+		for _, child := range node.Children {
+			visit(child)
+		}
+		status, err := actions.BranchLastSync(repo, tx, node.Branch.BranchName, now)
+		if err != nil {
+			logrus.WithError(err).WithField("branch", node.Branch.BranchName).
+				Debug("failed to determine the last sync")
+			return
+		}
+		if status.Synced {
+			node.Branch.LastSync = status.String()
+		}
+	}
+	for _, node := range rootNodes {
+		visit(node)
+	}
+}
+
+// annotateQueueStatus adds the MergeQueue status (and position) of the pull
+// requests to the tree. The tree is still useful without it, so errors (e.g., if the Aviator
+// API can't be reached) are only logged.
+func annotateQueueStatus(tx meta.ReadTx, rootNodes []*stackutils.StackTreeNode) {
+	repository, ok := tx.Repository()
+	if !ok {
+		return
+	}
+	client, err := avgql.NewClient()
+	if err != nil {
+		logrus.WithError(err).Debug("failed to create Aviator API client")
+		return
+	}
+	failed := false
+	var visit func(node *stackutils.StackTreeNode)
+	visit = func(node *stackutils.StackTreeNode) {
+		for _, child := range node.Children {
+			visit(child)
+		}
+		branch, ok := tx.Branch(node.Branch.BranchName)
+		if failed || !ok || branch.PullRequest == nil || branch.PullRequest.Number == 0 ||
+			branch.MergeCommit != "" {
+			return
+		}
+		status, err := avgql.PullRequestQueueStatus(
+			context.Background(), client, repository.Owner, repository.Name, branch.PullRequest.Number,
+		)
+		if err != nil {
+			// Don't bother with the other pull requests since they would most
+			// likely fail the same way.
+			logrus.WithError(err).Debug("failed to get MergeQueue status")
+			failed = true
+			return
+		}
+		if status.IsQueued() {
+			node.Branch.QueueStatus = status.Status
+			node.Branch.QueueStatusReason = status.StatusReason
+			node.Branch.QueuePosition = status.Position
+		}
+	}
+	for _, node := range rootNodes {
+		visit(node)
+	}
+}
+
+// annotateReviewStatus adds the review state of the open pull requests to the
+// tree.
+func annotateReviewStatus(client *gh.Client, tx meta.ReadTx, rootNodes []*stackutils.StackTreeNode) error {
+	var visit func(node *stackutils.StackTreeNode) error
+	visit = func(node *stackutils.StackTreeNode) error {
+		for _, child := range node.Children {
+			if err := visit(child); err != nil {
+				return err
+			}
+		}
+		branch, ok := tx.Branch(node.Branch.BranchName)
+		if !ok || branch.PullRequest == nil || branch.PullRequest.ID == "" ||
+			branch.MergeCommit != "" || branch.PullRequest.State != githubv4.PullRequestStateOpen {
+			return nil
+		}
+		reviews, err := client.PullRequestReviews(context.Background(), branch.PullRequest.ID)
+		if err != nil {
+			return errors.WrapIff(err, "failed to get the reviews of pull request #%d", branch.PullRequest.Number)
+		}
+		node.Branch.ReviewDecision = string(reviews.Decision)
+		for _, req := range reviews.Pending {
+			reviewer := "@" + req.Reviewer
+			if req.AsCodeOwner {
+				reviewer += " (code owner)"
+			}
+			node.Branch.ReviewPending = append(node.Branch.ReviewPending, reviewer)
+		}
+		for _, login := range reviews.ChangesRequestedBy {
+			node.Branch.ReviewChangesRequestedBy = append(node.Branch.ReviewChangesRequestedBy, "@"+login)
+		}
+		return nil
+	}
+	for _, node := range rootNodes {
+		if err := visit(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
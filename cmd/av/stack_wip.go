@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/spf13/cobra"
+)
+
+var stackWIPCmd = &cobra.Command{
+	Use:   "wip [<branch>]",
+	Short: "mark a branch as work in progress",
+	Long: `Mark a branch (the current branch by default) as work in progress.
+
+Work-in-progress branches are still rebased by "av stack sync", but they're
+never pushed and no pull requests are created or updated for them (nor for the
+branches stacked on top of them by "av stack submit") until they're unmarked
+with "av stack unwip".`,
+	SilenceUsage: true,
+	Args:         cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setBranchWIP(args, true)
+	},
+}
+
+var stackUnwipCmd = &cobra.Command{
+	Use:          "unwip [<branch>]",
+	Short:        "unmark a work-in-progress branch",
+	Long:         `Unmark a branch (the current branch by default) that was marked as work in progress with "av stack wip".`,
+	SilenceUsage: true,
+	Args:         cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setBranchWIP(args, false)
+	},
+}
+
+func setBranchWIP(args []string, wip bool) error {
+	repo, err := getRepo()
+	if err != nil {
+		return err
+	}
+	db, err := getDB(repo)
+	if err != nil {
+		return err
+	}
+	tx := db.WriteTx()
+	defer tx.Abort()
+
+	var branchName string
+	if len(args) > 0 {
+		branchName = args[0]
+	} else {
+		branchName, err = getCurrentBranchName(repo, db)
+		if err != nil {
+			return err
+		}
+	}
+	branch, ok := tx.Branch(branchName)
+	if !ok {
+		return errors.Errorf("branch %q is not tracked by av", branchName)
+	}
+
+	if branch.WIP == wip {
+		if wip {
+			_, _ = fmt.Fprint(os.Stderr, "Branch ", colors.UserInput(branchName), " is already marked as work in progress\n")
+		} else {
+			_, _ = fmt.Fprint(os.Stderr, "Branch ", colors.UserInput(branchName), " is not marked as work in progress\n")
+		}
+		return nil
+	}
+	branch.WIP = wip
+	tx.SetBranch(branch)
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if wip {
+		_, _ = fmt.Fprint(os.Stderr,
+			"Marked branch ", colors.UserInput(branchName), " as work in progress\n",
+			colors.Faint("  - it won't be pushed until it's unmarked with "),
+			colors.CliCmd("av stack unwip"), "\n",
+		)
+	} else {
+		_, _ = fmt.Fprint(os.Stderr,
+			"Branch ", colors.UserInput(branchName), " is no longer marked as work in progress\n",
+			colors.Faint("  - use "), colors.CliCmd("av pr create"),
+			colors.Faint(" or "), colors.CliCmd("av stack sync"),
+			colors.Faint(" to push it\n"),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:          "status",
+	Short:        "show the status of the current branch",
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		db, err := getDB(repo)
+		if err != nil {
+			return err
+		}
+		tx := db.ReadTx()
+
+		currentBranch, err := getCurrentBranchName(repo, db)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprint(os.Stderr, "On branch ", colors.UserInput(currentBranch), "\n")
+
+		if branch, ok := tx.Branch(currentBranch); ok {
+			parent := colors.UserInput(branch.Parent.Name)
+			if branch.Parent.Trunk {
+				parent += " (trunk)"
+			}
+			_, _ = fmt.Fprint(os.Stderr, "  - Parent: ", parent, "\n")
+			if branch.PullRequest != nil {
+				_, _ = fmt.Fprint(
+					os.Stderr,
+					"  - Pull request: ",
+					colors.UserInput("#", branch.PullRequest.Number),
+					" ", branch.PullRequest.Permalink, "\n",
+				)
+			}
+			lastSync, err := actions.BranchLastSync(repo, tx, currentBranch, time.Now())
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprint(os.Stderr, "  - Last synced: ", lastSync.String(), "\n")
+
+			// Predicting the conflicts requires git merge-tree --write-tree (Git
+			// 2.38), so don't fail the whole command if it's not available.
+			divergence, err := actions.CheckTrunkDivergence(repo, tx, currentBranch)
+			if err != nil {
+				logrus.WithError(err).Debug("failed to check the divergence from the trunk")
+			} else if divergence != nil {
+				printTrunkDivergence(divergence)
+			}
+		}
+
+		if !config.Av.Stack.GuardTrunkCommits {
+			return nil
+		}
+		trunk, err := actions.ChangesOnTrunk(repo, tx, false)
+		if err != nil {
+			return err
+		}
+		if trunk != "" {
+			_, _ = fmt.Fprint(
+				os.Stderr,
+				"\n",
+				colors.Warning("There are staged changes on the trunk branch "),
+				colors.UserInput(trunk),
+				colors.Warning(".\n"),
+				colors.Faint("  - Use "),
+				colors.CliCmd("av stack branch <branch-name>"),
+				colors.Faint(" to move them onto a new stacked branch before committing.\n"),
+			)
+		}
+		return nil
+	},
+}
+
+// printTrunkDivergence shows how far the stack is behind its trunk and warns if
+// syncing it onto the latest trunk would conflict.
+func printTrunkDivergence(divergence *actions.TrunkDivergence) {
+	remoteTrunk := colors.UserInput("origin/" + divergence.Trunk)
+	if divergence.Behind == 0 {
+		_, _ = fmt.Fprint(os.Stderr, "  - Trunk: up-to-date with ", remoteTrunk, "\n")
+		return
+	}
+	_, _ = fmt.Fprint(os.Stderr,
+		"  - Trunk: stack root ", colors.UserInput(divergence.Root), " is ",
+		divergence.Behind, " commit(s) behind ", remoteTrunk,
+	)
+	if len(divergence.Conflicts) == 0 {
+		_, _ = fmt.Fprint(os.Stderr, " (no conflicts expected)\n")
+		return
+	}
+	_, _ = fmt.Fprint(os.Stderr, "\n")
+
+	_, _ = fmt.Fprint(os.Stderr,
+		"\n", colors.Warning("Syncing the stack onto "), remoteTrunk,
+		colors.Warning(" would conflict in:"), "\n",
+	)
+	for _, res := range divergence.Conflicts {
+		_, _ = fmt.Fprint(os.Stderr,
+			"  - ", colors.UserInput(res.Branch), ": ", strings.Join(res.Conflicts, ", "), "\n",
+		)
+	}
+	_, _ = fmt.Fprint(os.Stderr,
+		colors.Faint("  - Use "), colors.CliCmd("av stack sync --trunk"),
+		colors.Faint(" to sync the stack before it diverges any further.\n"),
+	)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/spf13/cobra"
+)
+
+var switchCmd = &cobra.Command{
+	Use:     "switch [<branch> | -]",
+	Aliases: []string{"back"},
+	Short:   "switch to a branch (or back to the previously visited branch)",
+	Long: `Switch to the given branch.
+
+If no branch is given (or the branch is "-"), switch back to the branch that
+was checked out before the current one, similar to "cd -". This is useful when
+hopping around a stack (e.g., to address review feedback) and then returning to
+where you were. "av back" is shorthand for "av switch -".`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+
+		if err := ensureNoGitOperationInProgress(repo); err != nil {
+			return err
+		}
+
+		var branchToCheckout string
+		if len(args) == 0 || args[0] == "-" {
+			branchToCheckout, err = actions.PreviousBranch(repo)
+			if err != nil {
+				return err
+			}
+		} else {
+			branchToCheckout = args[0]
+		}
+
+		if err := actions.SwitchBranch(repo, &git.CheckoutBranch{
+			Name: branchToCheckout,
+		}); err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			"Checked out branch ",
+			colors.UserInput(branchToCheckout),
+			"\n",
+		)
+		return nil
+	},
+}
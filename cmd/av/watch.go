@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var watchFlags struct {
+	// If true, restack the children of a branch whenever the branch changes.
+	Restack bool
+	// If true, sync the stacks onto their trunk whenever the trunk changes on
+	// origin.
+	SyncTrunk bool
+	// How often to fetch from origin (zero to never fetch).
+	FetchInterval time.Duration
+	// How often to check the repository for changes.
+	PollInterval time.Duration
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [flags]",
+	Short: "keep the stacks up-to-date in the background",
+	Long: `Watch the repository and keep the stacks up-to-date in the background.
+
+Whenever a branch changes (e.g., because it was amended or rebased with plain
+Git), its children are restacked on top of it. Origin is fetched periodically
+and, with --sync-trunk, the stacks are synced onto their trunk whenever it
+moves. Nothing is pushed.
+
+Changes are only made while the working tree is clean and no Git operation is
+in progress. If restacking runs into a conflict, it's undone and you're told to
+run av stack sync yourself.`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if watchFlags.PollInterval <= 0 {
+			return errors.New("--poll-interval must be positive")
+		}
+		repo, err := getRepo()
+		if err != nil {
+			return err
+		}
+		if _, err := getDB(repo); err != nil {
+			return err
+		}
+		// Nobody is around to answer prompts while syncing in the background.
+		rootFlags.CI = true
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		_, _ = fmt.Fprint(os.Stderr,
+			"Watching ", colors.UserInput(repo.Dir()), " for changes (press Ctrl-C to stop)...\n",
+		)
+
+		w := &watcher{repo: repo, failed: make(map[string]string)}
+		var lastFetch time.Time
+		for {
+			if watchFlags.FetchInterval > 0 && time.Since(lastFetch) >= watchFlags.FetchInterval {
+				lastFetch = time.Now()
+				w.fetch()
+			}
+			if watchFlags.Restack {
+				w.restack()
+			}
+			select {
+			case <-ctx.Done():
+				_, _ = fmt.Fprint(os.Stderr, "Stopped watching.\n")
+				return nil
+			case <-time.After(watchFlags.PollInterval):
+			}
+		}
+	},
+}
+
+// watcher performs the actions of `av watch`.
+type watcher struct {
+	repo *git.Repo
+	// The branches that couldn't be restacked (because of a conflict) mapped to
+	// the head of their parent at that time, so that they're not retried until
+	// the parent changes again.
+	failed map[string]string
+	// Why the watcher is currently waiting to make changes (to only report it
+	// once).
+	waiting string
+}
+
+func (w *watcher) logf(format string, args ...any) {
+	_, _ = fmt.Fprint(os.Stderr,
+		colors.Faint(time.Now().Format("15:04:05"), " "), fmt.Sprintf(format, args...), "\n",
+	)
+}
+
+// ready returns true if it's safe to modify the branches (i.e., the user isn't
+// in the middle of something).
+func (w *watcher) ready() bool {
+	reason, err := w.busyReason()
+	if err != nil {
+		logrus.WithError(err).Debug("failed to determine the state of the repository")
+		return false
+	}
+	if reason != w.waiting && reason != "" {
+		w.logf("waiting until %s", reason)
+	}
+	w.waiting = reason
+	return reason == ""
+}
+
+func (w *watcher) busyReason() (string, error) {
+	if op, err := w.repo.OperationInProgress(); err != nil {
+		return "", err
+	} else if op != git.OperationNone {
+		return "the " + string(op) + " in progress is done", nil
+	}
+	if branch, err := syncConflictBranch(w.repo); err != nil {
+		return "", err
+	} else if branch != "" {
+		return "the sync in progress is done", nil
+	}
+	if detached, err := w.repo.DetachedHead(); err != nil {
+		return "", err
+	} else if detached {
+		return "a branch is checked out", nil
+	}
+	status, err := w.repo.Status()
+	if err != nil {
+		return "", err
+	}
+	if len(status.Staged) > 0 || len(status.Unstaged) > 0 || len(status.Unmerged) > 0 ||
+		(!config.Av.Sync.IgnoreUntracked && len(status.Untracked) > 0) ||
+		(!config.Av.Sync.IgnoreSubmodules && len(status.Submodules) > 0) {
+		return "the working tree is clean", nil
+	}
+	return "", nil
+}
+
+// fetch fetches from origin and, with --sync-trunk, syncs the stacks whose
+// trunk moved.
+func (w *watcher) fetch() {
+	if _, err := w.repo.Run(&git.RunOpts{
+		Args:      []string{"fetch", "--quiet", "--prune", "origin"},
+		ExitError: true,
+	}); err != nil {
+		w.logf("%s %v", colors.Warning("failed to fetch from origin:"), err)
+		return
+	}
+	if !watchFlags.SyncTrunk {
+		return
+	}
+	db, err := getDB(w.repo)
+	if err != nil {
+		w.logf("%s %v", colors.Warning("failed to read the av metadata:"), err)
+		return
+	}
+	tx := db.ReadTx()
+	for _, stack := range ciStacks(tx) {
+		root, _ := tx.Branch(stack[0])
+		if root.MergeCommit != "" || root.Freeze != nil {
+			continue
+		}
+		upToDate, err := w.repo.IsAncestor("refs/remotes/origin/"+root.Parent.Name, "refs/heads/"+root.Name)
+		if err != nil || upToDate {
+			continue
+		}
+		if !w.ready() {
+			return
+		}
+		w.logf("%s moved on origin, syncing the stack of %s...",
+			colors.UserInput(root.Parent.Name), colors.UserInput(root.Name))
+		w.sync(root.Name, actions.StackSyncConfig{Trunk: true, NoPush: true})
+	}
+}
+
+// restack restacks the children of the branches that changed.
+func (w *watcher) restack() {
+	db, err := getDB(w.repo)
+	if err != nil {
+		w.logf("%s %v", colors.Warning("failed to read the av metadata:"), err)
+		return
+	}
+	tx := db.ReadTx()
+	branches, err := actions.BranchesNeedingRestack(w.repo, tx)
+	if err != nil {
+		w.logf("%s %v", colors.Warning("failed to check the branches:"), err)
+		return
+	}
+	for _, name := range branches {
+		branch, _ := tx.Branch(name)
+		if _, freeze := meta.StackFreeze(tx, name); freeze != nil {
+			continue
+		}
+		parentHead, err := w.repo.RevParse(&git.RevParse{Rev: "refs/heads/" + branch.Parent.Name})
+		if err != nil || w.failed[name] == parentHead {
+			continue
+		}
+		if !w.ready() {
+			return
+		}
+		w.logf("%s changed, restacking %s...", colors.UserInput(branch.Parent.Name), colors.UserInput(name))
+		if !w.sync(name, actions.StackSyncConfig{NoPush: true, NoFetch: true}) {
+			w.failed[name] = parentHead
+		}
+		// The sync restacks the whole stack, so the other branches have to be
+		// checked again.
+		return
+	}
+}
+
+// sync syncs the stack of the given branch and returns to the branch that was
+// checked out. If the sync runs into a conflict, it's aborted. Returns true if
+// the sync succeeded.
+func (w *watcher) sync(branchName string, syncConfig actions.StackSyncConfig) bool {
+	orig, err := w.repo.CurrentBranchName()
+	if err != nil {
+		w.logf("%s %v", colors.Warning("failed to determine the current branch:"), err)
+		return false
+	}
+	defer func() {
+		if _, err := w.repo.CheckoutBranch(&git.CheckoutBranch{Name: orig}); err != nil {
+			w.logf("%s %v", colors.Warning("failed to check out "+orig+":"), err)
+		}
+	}()
+	if orig != branchName {
+		if _, err := w.repo.CheckoutBranch(&git.CheckoutBranch{Name: branchName}); err != nil {
+			w.logf("%s %v", colors.Warning("failed to check out "+branchName+":"), err)
+			return false
+		}
+	}
+
+	syncErr := runStackSync(syncConfig)
+	conflict, err := syncConflictBranch(w.repo)
+	if err != nil {
+		w.logf("%s %v", colors.Warning("failed to read the sync state:"), err)
+		return false
+	}
+	if conflict != "" {
+		if err := abortStackSync(); err != nil {
+			w.logf("%s %v", colors.Warning("failed to abort the sync:"), err)
+			return false
+		}
+		w.logf("%s %s conflicts with its parent; run %s to resolve the conflict",
+			colors.Warning("could not restack:"), colors.UserInput(conflict), colors.CliCmd("av stack sync"))
+		return false
+	}
+	if syncErr != nil {
+		w.logf("%s %v", colors.Warning("failed to sync:"), syncErr)
+		return false
+	}
+	w.logf("%s", colors.Success("done"))
+	return true
+}
+
+func init() {
+	watchCmd.Flags().BoolVar(
+		&watchFlags.Restack, "restack", true,
+		"restack the children of a branch whenever the branch changes",
+	)
+	watchCmd.Flags().BoolVar(
+		&watchFlags.SyncTrunk, "sync-trunk", false,
+		"sync the stacks onto their trunk whenever it moves on origin (without pushing)",
+	)
+	watchCmd.Flags().DurationVar(
+		&watchFlags.FetchInterval, "fetch-interval", 5*time.Minute,
+		"how often to fetch from origin (0 to never fetch)",
+	)
+	watchCmd.Flags().DurationVar(
+		&watchFlags.PollInterval, "poll-interval", 2*time.Second,
+		"how often to check the repository for changes",
+	)
+}
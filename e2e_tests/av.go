@@ -102,3 +102,10 @@ func Chdir(t *testing.T, dir string) {
 		}
 	})
 }
+
+// WithoutGitHubToken makes av run without a GitHub token for the rest of the
+// test (as if none was configured).
+func WithoutGitHubToken(t *testing.T) {
+	t.Setenv("AV_GITHUB_TOKEN", "")
+	t.Setenv("GITHUB_TOKEN", "")
+}
@@ -0,0 +1,35 @@
+package e2e_tests
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetachedHeadRecovery(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	// Create one -> two stack
+	RequireAv(t, "stack", "branch", "one")
+	gittest.CommitFile(t, repo, "one.txt", []byte("one"))
+	RequireAv(t, "stack", "branch", "two")
+	gittest.CommitFile(t, repo, "two.txt", []byte("two"))
+	gittest.CommitFile(t, repo, "two.txt", []byte("two\ntwo"))
+
+	// Detach in the middle of branch two (e.g., as a bisect would).
+	RequireCmd(t, "git", "checkout", "--detach", "two~1")
+	out := Av(t, "stack", "prev")
+	require.Equal(t, 1, out.ExitCode)
+	require.Contains(t, out.Stderr, "belongs to stack branch two")
+	require.Contains(t, out.Stderr, "git checkout two")
+
+	// Create a commit on top of branch one while detached.
+	RequireCmd(t, "git", "checkout", "--detach", "one")
+	gittest.CommitFile(t, repo, "one.txt", []byte("one\none"))
+	out = Av(t, "stack", "next")
+	require.Equal(t, 1, out.ExitCode)
+	require.Contains(t, out.Stderr, "belongs to stack branch one")
+	require.Contains(t, out.Stderr, "git checkout -B one")
+}
@@ -0,0 +1,86 @@
+package e2e_tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrunkCommitGuard(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "doctor")
+
+	// Stage some changes on trunk.
+	require.NoError(t, os.WriteFile("README.md", []byte("changed\n"), 0644))
+	RequireCmd(t, "git", "add", "README.md")
+
+	// The guard is opt-in.
+	require.NotContains(t, RequireAv(t, "doctor").Stderr, "staged changes on the trunk branch")
+	require.NotContains(t, RequireAv(t, "status").Stderr, "av stack branch")
+
+	require.NoError(t, os.WriteFile(
+		filepath.Join(repo.AvDir(), "config.yaml"),
+		[]byte("stack:\n  guardTrunkCommits: true\n"),
+		0644,
+	))
+	doctor := Av(t, "doctor")
+	require.Equal(t, 1, doctor.ExitCode)
+	require.Contains(t, doctor.Stderr, "staged changes on the trunk branch")
+
+	status := RequireAv(t, "status")
+	require.Contains(t, status.Stderr, "av stack branch")
+
+	// Committing to trunk is refused when we can't prompt the user (stdin is
+	// /dev/null here, which is a character device but not a terminal).
+	commit := Av(t, "commit", "create", "-m", "oops")
+	require.Equal(t, 1, commit.ExitCode)
+	require.NotContains(t, commit.Stderr, "Enter a name to create a new stacked branch")
+	require.Contains(t, commit.Stderr, "to create a stacked branch first")
+	RequireCurrentBranchName(t, repo, "main")
+	hasChanges, err := repo.HasChangesToBeCommitted()
+	require.NoError(t, err)
+	require.True(t, hasChanges, "changes should not have been committed")
+
+	// Once the changes are on a stacked branch, everything is fine.
+	RequireAv(t, "stack", "branch", "feature")
+	RequireAv(t, "commit", "create", "-m", "feature")
+	RequireAv(t, "doctor")
+}
+
+func TestTrunkCommitGuardAll(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+	require.NoError(t, os.WriteFile(
+		filepath.Join(repo.AvDir(), "config.yaml"),
+		[]byte("stack:\n  guardTrunkCommits: true\n"),
+		0644,
+	))
+
+	// A stack that's based on a release branch, which makes it a trunk too.
+	RequireCmd(t, "git", "checkout", "-b", "release")
+	RequireCmd(t, "git", "checkout", "-b", "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"))
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+	tx := db.WriteTx()
+	tx.SetBranch(meta.Branch{Name: "stack-1", Parent: meta.BranchState{Name: "release", Trunk: true}})
+	require.NoError(t, tx.Commit())
+
+	for _, trunk := range []string{"main", "release"} {
+		gittest.CheckoutBranch(t, repo, trunk)
+		// Only unstaged changes, which --all would commit.
+		require.NoError(t, os.WriteFile("README.md", []byte("changed on "+trunk+"\n"), 0644))
+		commit := Av(t, "commit", "create", "--all", "-m", "oops")
+		require.Equal(t, 1, commit.ExitCode, "committing to %q should be refused", trunk)
+		require.Contains(t, commit.Stderr, "you're about to commit directly to the trunk branch")
+		RequireCurrentBranchName(t, repo, trunk)
+		RequireCmd(t, "git", "checkout", "--", "README.md")
+	}
+}
@@ -0,0 +1,31 @@
+package e2e_tests
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefuseDuringGitOperation(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "one")
+	gittest.CommitFile(t, repo, "file.txt", []byte("one\n"))
+	RequireCmd(t, "git", "checkout", "main")
+	gittest.CommitFile(t, repo, "file.txt", []byte("main\n"))
+	RequireCmd(t, "git", "checkout", "one")
+
+	// Start a merge that conflicts.
+	require.NotEqual(t, 0, Cmd(t, "git", "merge", "main").ExitCode)
+
+	out := Av(t, "stack", "branch", "two")
+	require.Equal(t, 1, out.ExitCode)
+	require.Contains(t, out.Stderr, "A merge is currently in progress")
+	require.Contains(t, out.Stderr, "git merge --abort")
+	RequireCurrentBranchName(t, repo, "one")
+
+	RequireCmd(t, "git", "merge", "--abort")
+	RequireAv(t, "stack", "branch", "two")
+}
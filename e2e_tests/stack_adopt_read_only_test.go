@@ -0,0 +1,48 @@
+package e2e_tests
+
+import (
+	"os"
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackAdoptReadOnly(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	// Someone else's branch that only exists on the remote.
+	RequireCmd(t, "git", "checkout", "-b", "theirs")
+	gittest.CommitFile(t, repo, "their-file", []byte("theirs\n"))
+	RequireCmd(t, "git", "push", "origin", "theirs")
+	RequireCmd(t, "git", "checkout", "main")
+	RequireCmd(t, "git", "branch", "-D", "theirs")
+	RequireCmd(t, "git", "update-ref", "-d", "refs/remotes/origin/theirs")
+
+	RequireAv(t, "stack", "adopt", "theirs", "--read-only")
+	assert.Equal(t,
+		meta.BranchState{Name: "main", Trunk: true},
+		GetStoredParentBranchState(t, repo, "theirs"),
+	)
+
+	// The read-only branch can't be modified.
+	gittest.CheckoutBranch(t, repo, "theirs")
+	requireFileContent(t, "their-file", "theirs\n")
+	require.NoError(t, os.WriteFile("my-file", []byte("mine\n"), 0644))
+	RequireCmd(t, "git", "add", "my-file")
+	assert.NotEqual(t, 0, Av(t, "commit", "create", "-m", "mine").ExitCode)
+
+	// Branches can be stacked on top of it.
+	RequireAv(t, "stack", "branch", "mine")
+	RequireAv(t, "commit", "create", "-m", "mine")
+	assert.Equal(t, "theirs", GetStoredParentBranchState(t, repo, "mine").Name)
+
+	tree := RequireAv(t, "stack", "tree")
+	assert.Contains(t, tree.Stdout, "read-only")
+
+	RequireAv(t, "stack", "sync", "--no-fetch", "--no-push")
+	RequireCurrentBranchName(t, repo, "mine")
+}
@@ -0,0 +1,61 @@
+package e2e_tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+)
+
+func TestStackBisect(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	// Create a stack of four branches where the third one "breaks the build".
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "one", []byte("1\n"))
+	RequireAv(t, "stack", "branch", "stack-2")
+	gittest.CommitFile(t, repo, "two", []byte("2\n"))
+	RequireAv(t, "stack", "branch", "stack-3")
+	gittest.CommitFile(t, repo, "broken", []byte("3\n"))
+	RequireAv(t, "stack", "branch", "stack-4")
+	gittest.CommitFile(t, repo, "four", []byte("4\n"))
+
+	for _, args := range [][]string{
+		{"stack", "bisect", "--", "test", "!", "-e", "broken"},
+		{"stack", "bisect", "--linear", "--", "test", "!", "-e", "broken"},
+	} {
+		out := RequireAv(t, args...)
+		require.Contains(t, out.Stderr, "The command first fails for branch stack-3.")
+	}
+	RequireCurrentBranchName(t, repo, "stack-4")
+
+	// The command passes on stack-2, so there's nothing to bisect.
+	RequireAv(t, "stack", "prev", "2")
+	out := Av(t, "stack", "bisect", "--", "test", "!", "-e", "broken")
+	require.Equal(t, 2, out.ExitCode)
+	require.Contains(t, out.Stderr, "nothing to bisect")
+
+	// The temporary worktrees are cleaned up.
+	worktrees, err := repo.Git("worktree", "list")
+	require.NoError(t, err)
+	require.NotContains(t, worktrees, "av-bisect-")
+}
+
+func TestStackBisectInterrupted(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "one", []byte("1\n"))
+
+	// Interrupt av (as Ctrl-C would) while the command is running.
+	out := Av(t, "stack", "bisect", "--", "sh", "-c", "kill -INT $PPID; sleep 5")
+	require.Equal(t, 1, out.ExitCode)
+	require.Contains(t, out.Stderr, "interrupted")
+
+	// The temporary worktree is removed anyway.
+	worktrees, err := repo.Git("worktree", "list")
+	require.NoError(t, err)
+	require.NotContains(t, worktrees, "av-bisect-")
+}
@@ -1,8 +1,10 @@
 package e2e_tests
 
 import (
+	"os"
 	"testing"
 
+	"github.com/aviator-co/av/internal/git"
 	"github.com/aviator-co/av/internal/git/gittest"
 	"github.com/aviator-co/av/internal/meta"
 	"github.com/aviator-co/av/internal/meta/jsonfiledb"
@@ -95,3 +97,160 @@ func TestStackBranchRetroactiveMove(t *testing.T) {
 	)
 	require.NotContainsf(t, branches, "one", "expected one to be deleted from the branch metadata")
 }
+
+func TestStackBranchFromMessage(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "--message", "Add rate limiting to API")
+	RequireCurrentBranchName(t, repo, "add-rate-limiting-to-api")
+
+	// Generating the same name again should add a suffix and create the
+	// requested empty commit.
+	RequireAv(
+		t,
+		"stack",
+		"branch",
+		"--message",
+		"Add rate limiting to API",
+		"--empty-commit",
+	)
+	RequireCurrentBranchName(t, repo, "add-rate-limiting-to-api-2")
+	require.Equal(
+		t,
+		"add-rate-limiting-to-api",
+		GetStoredParentBranchState(t, repo, "add-rate-limiting-to-api-2").Name,
+	)
+	subject, err := repo.Git("log", "-1", "--format=%s")
+	require.NoError(t, err)
+	require.Equal(t, "Add rate limiting to API", subject)
+}
+
+func TestStackBranchCarryChanges(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "one")
+	gittest.CommitFile(t, repo, "one.txt", []byte("one\n"))
+
+	// Start coding before branching.
+	require.NoError(t, os.WriteFile("README.md", []byte("more\n"), 0644))
+	require.NoError(t, os.WriteFile("new.txt", []byte("new\n"), 0644))
+	RequireCmd(t, "git", "add", "new.txt")
+
+	// Without --carry-changes (and without a terminal to prompt), we refuse to
+	// touch the uncommitted changes.
+	require.NotEqual(t, 0, Av(t, "stack", "branch", "--parent", "main", "two").ExitCode)
+	RequireCurrentBranchName(t, repo, "one")
+
+	RequireAv(t, "stack", "branch", "--parent", "main", "two", "--carry-changes")
+	RequireCurrentBranchName(t, repo, "two")
+	require.Equal(t, "main", GetStoredParentBranchState(t, repo, "two").Name)
+
+	contents, err := os.ReadFile("new.txt")
+	require.NoError(t, err)
+	require.Equal(t, "new\n", string(contents))
+	staged, err := repo.Git("diff", "--cached", "--name-only")
+	require.NoError(t, err)
+	require.Equal(t, "new.txt", staged, "staged changes should remain staged")
+
+	// The parent branch should be left untouched.
+	RequireCmd(t, "git", "stash", "push", "--include-untracked")
+	RequireCmd(t, "git", "checkout", "one")
+	clean, err := repo.CheckCleanWorkdir()
+	require.NoError(t, err)
+	require.True(t, clean)
+}
+
+func TestStackBranchCaseCollision(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "feature-x")
+	gittest.CommitFile(t, repo, "one.txt", []byte("one"))
+	RequireAv(t, "stack", "branch", "two")
+
+	// Branch names that only differ in case are refused.
+	res := Av(t, "stack", "branch", "Feature-X")
+	require.Equal(t, 1, res.ExitCode)
+	require.Contains(t, res.Stderr, "conflicts with the existing branch feature-x")
+	RequireCurrentBranchName(t, repo, "two")
+
+	res = Av(t, "stack", "branch", "-m", "FEATURE-x")
+	require.Equal(t, 1, res.ExitCode)
+	require.Contains(t, res.Stderr, "conflicts with the existing branch feature-x")
+	RequireCurrentBranchName(t, repo, "two")
+}
+
+func TestStackBranchParent(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "one")
+	gittest.CommitFile(t, repo, "one.txt", []byte("one\n"))
+	RequireCmd(t, "git", "checkout", "-b", "untracked", "main")
+	gittest.CommitFile(t, repo, "untracked.txt", []byte("untracked\n"))
+
+	// The parent has to exist and be tracked by av (or be a trunk).
+	res := Av(t, "stack", "branch", "--parent", "nope", "two")
+	require.Equal(t, 1, res.ExitCode)
+	require.Contains(t, res.Stderr, `parent branch "nope" does not exist`)
+	res = Av(t, "stack", "branch", "--parent", "untracked", "two")
+	require.Equal(t, 1, res.ExitCode)
+	require.Contains(t, res.Stderr, "is not tracked by av")
+	RequireCurrentBranchName(t, repo, "untracked")
+
+	RequireAv(t, "stack", "branch", "--parent", "one", "two")
+	RequireCurrentBranchName(t, repo, "two")
+	oneHead, err := repo.RevParse(&git.RevParse{Rev: "one"})
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		meta.BranchState{Name: "one", Head: oneHead},
+		GetStoredParentBranchState(t, repo, "two"),
+	)
+
+	RequireAv(t, "stack", "branch", "--parent", "main", "three")
+	RequireCurrentBranchName(t, repo, "three")
+	require.Equal(
+		t,
+		meta.BranchState{Name: "main", Trunk: true},
+		GetStoredParentBranchState(t, repo, "three"),
+	)
+}
+
+func TestStackBranchNoCheckout(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "one")
+	oneHead := gittest.CommitFile(t, repo, "one.txt", []byte("one\n"))
+	require.NoError(t, os.WriteFile("one.txt", []byte("changed\n"), 0644))
+
+	// Scaffold the rest of the stack without leaving the current branch (and
+	// without touching the uncommitted changes).
+	RequireAv(t, "stack", "branch", "--checkout=false", "two")
+	RequireAv(t, "stack", "branch", "--checkout=false", "--parent", "two",
+		"--message", "Add three", "--empty-commit")
+	RequireCurrentBranchName(t, repo, "one")
+	contents, err := os.ReadFile("one.txt")
+	require.NoError(t, err)
+	require.Equal(t, "changed\n", string(contents))
+
+	twoHead, err := repo.RevParse(&git.RevParse{Rev: "two"})
+	require.NoError(t, err)
+	require.Equal(t, oneHead, twoHead)
+	require.Equal(
+		t,
+		meta.BranchState{Name: "one", Head: oneHead},
+		GetStoredParentBranchState(t, repo, "two"),
+	)
+	require.Equal(
+		t,
+		meta.BranchState{Name: "two", Head: twoHead},
+		GetStoredParentBranchState(t, repo, "add-three"),
+	)
+	subject, err := repo.Git("log", "-1", "--format=%s", "add-three")
+	require.NoError(t, err)
+	require.Equal(t, "Add three", subject)
+}
@@ -0,0 +1,53 @@
+package e2e_tests
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+var squashCommitRe = regexp.MustCompile(`Squash commit of [^:]+: ([0-9a-f]{40})`)
+
+func TestStackCopyToPR(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "one", []byte("1a\n"), gittest.WithMessage("Add one"))
+	RequireAv(t, "stack", "branch", "stack-2")
+	gittest.CommitFile(t, repo, "two", []byte("2a\n"), gittest.WithMessage("Add two"))
+	gittest.CommitFile(t, repo, "two", []byte("2a\n2b\n"), gittest.WithMessage("Extend two"))
+	stack2Head, err := repo.Git("rev-parse", "HEAD")
+	require.NoError(t, err)
+
+	// A branch with several commits needs the title of its pull request.
+	require.NotEqual(t, 0, Av(t, "stack", "copy-to-pr").ExitCode)
+
+	res := RequireAv(t, "stack", "copy-to-pr", "--title", "Add two")
+	commit := squashCommitRe.FindStringSubmatch(res.Stderr)[1]
+
+	// The commit has the changes of stack-2 on top of the squashed stack-1, and
+	// nothing was changed.
+	msg, err := repo.Git("log", "-1", "--format=%B", commit)
+	require.NoError(t, err)
+	require.Equal(t, "Add two\n\n* Add two\n\n* Extend two", msg)
+	files, err := repo.Git("diff", "--name-only", commit+"~", commit)
+	require.NoError(t, err)
+	require.Equal(t, "two", files)
+	parentMsg, err := repo.Git("log", "-1", "--format=%s", commit+"~")
+	require.NoError(t, err)
+	require.Equal(t, "Squashed stack-1", parentMsg)
+	head, err := repo.Git("rev-parse", "stack-2")
+	require.NoError(t, err)
+	require.Equal(t, stack2Head, head)
+	RequireCurrentBranchName(t, repo, "stack-2")
+
+	// A single commit keeps its message.
+	res = RequireAv(t, "stack", "copy-to-pr", "stack-1")
+	commit = squashCommitRe.FindStringSubmatch(res.Stderr)[1]
+	msg, err = repo.Git("log", "-1", "--format=%B", commit)
+	require.NoError(t, err)
+	require.Equal(t, "Add one", msg)
+}
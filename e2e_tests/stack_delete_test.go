@@ -0,0 +1,107 @@
+package e2e_tests
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackDelete(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "foo")
+	gittest.CommitFile(t, repo, "foo.txt", []byte("foo"))
+	RequireAv(t, "stack", "branch", "bar")
+	gittest.CommitFile(t, repo, "bar.txt", []byte("bar"))
+	RequireAv(t, "stack", "branch", "spam")
+	gittest.CommitFile(t, repo, "spam.txt", []byte("spam"))
+	gittest.CheckoutBranch(t, repo, "bar")
+	RequireAv(t, "stack", "branch", "eggs")
+	gittest.CommitFile(t, repo, "eggs.txt", []byte("eggs"))
+
+	// bar has commits that would be lost.
+	require.NotEqual(t, 0, Av(t, "stack", "delete", "bar").ExitCode)
+
+	// Delete bar from the middle of the stack: its children are moved onto foo.
+	RequireAv(t, "stack", "delete", "bar", "--force")
+	RequireCurrentBranchName(t, repo, "eggs")
+	require.Equal(t, 1, Cmd(t, "git", "show-ref", "refs/heads/bar").ExitCode)
+	require.NotContains(t, Av(t, "stack", "tree").Stdout, "bar")
+	for _, child := range []string{"spam", "eggs"} {
+		require.Equal(t, "foo", GetStoredParentBranchState(t, repo, child).Name)
+		gittest.CheckoutBranch(t, repo, child)
+		requireFileContent(t, "foo.txt", "foo")
+		requireFileContent(t, child+".txt", child)
+		require.NoFileExists(t, "bar.txt")
+	}
+
+	// Delete the root of the stack: the checked out branch is replaced by the
+	// trunk.
+	gittest.CheckoutBranch(t, repo, "foo")
+	RequireAv(t, "stack", "delete", "--force")
+	RequireCurrentBranchName(t, repo, "main")
+	require.Equal(t, meta.BranchState{Name: "main", Trunk: true}, GetStoredParentBranchState(t, repo, "spam"))
+	gittest.CheckoutBranch(t, repo, "spam")
+	require.NoFileExists(t, "foo.txt")
+	requireFileContent(t, "spam.txt", "spam")
+}
+
+func TestStackDeleteWithoutGitHubToken(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+	WithoutGitHubToken(t)
+
+	RequireAv(t, "stack", "branch", "foo")
+	gittest.CommitFile(t, repo, "foo.txt", []byte("foo"))
+	RequireAv(t, "stack", "branch", "bar")
+	gittest.CommitFile(t, repo, "bar.txt", []byte("bar"))
+	RequireAv(t, "stack", "branch", "spam")
+	gittest.CommitFile(t, repo, "spam.txt", []byte("spam"))
+
+	// Deleting the branch on the remote needs GitHub, so nothing is changed.
+	require.NotEqual(t, 0, Av(t, "stack", "delete", "bar", "--force", "--remote").ExitCode)
+	RequireCmd(t, "git", "show-ref", "refs/heads/bar")
+	require.Equal(t, "bar", GetStoredParentBranchState(t, repo, "spam").Name)
+
+	// Deleting it locally doesn't need GitHub.
+	RequireAv(t, "stack", "delete", "bar", "--force")
+	require.Equal(t, 1, Cmd(t, "git", "show-ref", "refs/heads/bar").ExitCode)
+	require.Equal(t, "foo", GetStoredParentBranchState(t, repo, "spam").Name)
+	require.NotContains(t, Av(t, "stack", "tree").Stdout, "bar")
+}
+
+func TestStackDeleteConflictingChild(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "foo")
+	gittest.CommitFile(t, repo, "foo.txt", []byte("foo"))
+	RequireAv(t, "stack", "branch", "bar")
+	gittest.CommitFile(t, repo, "bar.txt", []byte("bar"))
+	RequireAv(t, "stack", "branch", "child-a")
+	gittest.CommitFile(t, repo, "a.txt", []byte("a"))
+	RequireAv(t, "stack", "branch", "child-a2")
+	gittest.CommitFile(t, repo, "a2.txt", []byte("a2"))
+	gittest.CheckoutBranch(t, repo, "bar")
+	RequireAv(t, "stack", "branch", "child-b")
+	// Changing a file that only exists on bar conflicts once the commits of
+	// bar are dropped.
+	gittest.CommitFile(t, repo, "bar.txt", []byte("bar\nb"))
+
+	// child-a is moved (and its subtree restacked) before child-b conflicts.
+	Av(t, "stack", "delete", "bar", "--force")
+	state, err := actions.ReadStackSyncState(repo)
+	require.NoError(t, err)
+	require.Equal(t, "child-b", state.CurrentBranch)
+	require.Equal(t, "foo", GetStoredParentBranchState(t, repo, "child-a").Name)
+	childAHead, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/child-a"})
+	require.NoError(t, err)
+	require.Equal(t, childAHead, GetStoredParentBranchState(t, repo, "child-a2").Head)
+	require.NotEqual(t, 0, Cmd(t, "git", "cat-file", "-e", "child-a2:bar.txt").ExitCode)
+	RequireCmd(t, "git", "cat-file", "-e", "child-a2:a2.txt")
+}
@@ -0,0 +1,32 @@
+package e2e_tests
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackDescribeBranch(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "login-form")
+	gittest.CommitFile(t, repo, "form", []byte("form\n"))
+	require.Contains(t, RequireAv(t, "stack", "describe-branch").Stderr, "has no description")
+
+	RequireAv(t, "stack", "describe-branch", "Add the login form\n\nIt doesn't submit anything yet.")
+	description, err := repo.Git("config", "branch.login-form.description")
+	require.NoError(t, err)
+	require.Equal(t, "Add the login form\n\nIt doesn't submit anything yet.", description)
+	require.Equal(t,
+		"Add the login form\n\nIt doesn't submit anything yet.\n",
+		RequireAv(t, "stack", "describe-branch").Stdout,
+	)
+	require.Contains(t, RequireAv(t, "stack", "tree").Stdout, "Add the login form ...")
+
+	RequireAv(t, "stack", "describe-branch", "--clear")
+	_, err = repo.Git("config", "branch.login-form.description")
+	require.Error(t, err)
+	require.NotContains(t, RequireAv(t, "stack", "tree").Stdout, "Add the login form")
+}
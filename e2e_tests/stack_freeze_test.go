@@ -0,0 +1,51 @@
+package e2e_tests
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackFreeze(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"))
+	RequireAv(t, "stack", "branch", "stack-2")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n2a\n"))
+
+	RequireAv(t, "stack", "freeze", "--reason", "queued to merge")
+	require.Contains(t, RequireAv(t, "stack", "tree").Stdout, "frozen")
+
+	gittest.CheckoutBranch(t, repo, "stack-1")
+	stack1Head := gittest.CommitFile(t, repo, "other-file", []byte("1b\n"))
+	stack2Head, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/stack-2"})
+	require.NoError(t, err)
+
+	// The sync refuses to rewrite the frozen stack and says who froze it.
+	sync := Av(t, "stack", "sync", "--no-fetch", "--no-push")
+	require.NotEqual(t, 0, sync.ExitCode)
+	require.Contains(t, sync.Stderr, "is frozen")
+	require.Contains(t, sync.Stderr, "av-test <av-test@nonexistant>")
+	require.Contains(t, sync.Stderr, "queued to merge")
+	newStack2Head, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/stack-2"})
+	require.NoError(t, err)
+	require.Equal(t, stack2Head, newStack2Head)
+
+	require.NotEqual(t, 0, Av(t, "commit", "amend", "--no-edit").ExitCode)
+
+	// Syncing all stacks skips the frozen one.
+	RequireAv(t, "stack", "sync", "--all", "--no-fetch", "--no-push")
+	newStack2Head, err = repo.RevParse(&git.RevParse{Rev: "refs/heads/stack-2"})
+	require.NoError(t, err)
+	require.Equal(t, stack2Head, newStack2Head)
+
+	RequireAv(t, "stack", "unfreeze")
+	RequireAv(t, "stack", "sync", "--no-fetch", "--no-push")
+	ok, err := repo.IsAncestor(stack1Head, "refs/heads/stack-2")
+	require.NoError(t, err)
+	require.True(t, ok, "stack-2 should be synced after unfreezing the stack")
+}
@@ -0,0 +1,37 @@
+package e2e_tests
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackGoto(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	// Create two stacks:
+	//     main -> one -> two
+	//     main -> other
+	RequireAv(t, "stack", "branch", "one")
+	gittest.CommitFile(t, repo, "one.txt", []byte("one"))
+	RequireAv(t, "stack", "branch", "two")
+	gittest.CommitFile(t, repo, "two.txt", []byte("two"))
+	RequireAv(t, "stack", "branch", "--parent", "main", "other")
+	gittest.CommitFile(t, repo, "other.txt", []byte("other"))
+	RequireCmd(t, "git", "checkout", "two")
+
+	RequireAv(t, "stack", "goto", "one")
+	RequireCurrentBranchName(t, repo, "one")
+
+	// Branches from other stacks can't be checked out.
+	require.NotEqual(t, 0, Av(t, "stack", "goto", "other").ExitCode)
+	RequireCurrentBranchName(t, repo, "one")
+
+	// Completion only offers branches from the current stack.
+	completions := RequireAv(t, "__complete", "stack", "goto", "")
+	require.Contains(t, completions.Stdout, "one\n")
+	require.Contains(t, completions.Stdout, "two\n")
+	require.NotContains(t, completions.Stdout, "other")
+}
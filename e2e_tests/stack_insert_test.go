@@ -0,0 +1,32 @@
+package e2e_tests
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackInsert(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"), gittest.WithMessage("Commit 1a"))
+	RequireAv(t, "stack", "branch", "stack-2")
+	gittest.CommitFile(t, repo, "other-file", []byte("2a\n"), gittest.WithMessage("Commit 2a"))
+	gittest.CheckoutBranch(t, repo, "stack-1")
+
+	RequireAv(t, "stack", "insert", "stack-mid")
+	RequireCurrentBranchName(t, repo, "stack-mid")
+	assert.Equal(t, "stack-1", GetStoredParentBranchState(t, repo, "stack-mid").Name)
+	assert.Equal(t, "stack-mid", GetStoredParentBranchState(t, repo, "stack-2").Name)
+
+	// The new branch can't be created twice.
+	require.NotEqual(t, 0, Av(t, "stack", "insert", "stack-mid").ExitCode)
+
+	gittest.CommitFile(t, repo, "mid-file", []byte("mid\n"), gittest.WithMessage("Commit mid"))
+	RequireAv(t, "stack", "sync", "--no-fetch", "--no-push")
+	RequireCmd(t, "git", "merge-base", "--is-ancestor", "stack-mid", "stack-2")
+}
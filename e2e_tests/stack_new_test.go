@@ -0,0 +1,61 @@
+package e2e_tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackNewTemplate(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	require.NoError(t, os.WriteFile(
+		filepath.Join(repo.AvDir(), "config.yaml"),
+		[]byte(`stack:
+  templates:
+    feature:
+      - name: schema
+        description: Add the database schema
+      - name: backend
+      - name: frontend
+        description: Add the UI
+`),
+		0644,
+	))
+
+	res := Av(t, "stack", "new", "--template", "nope", "--no-pr", "login")
+	require.NotEqual(t, 0, res.ExitCode)
+	require.Contains(t, res.Stderr, "available templates: feature")
+
+	RequireAv(t, "stack", "new", "--template", "feature", "--no-pr", "login")
+	RequireCurrentBranchName(t, repo, "login-schema")
+	require.Equal(
+		t,
+		meta.BranchState{Name: "main", Trunk: true},
+		GetStoredParentBranchState(t, repo, "login-schema"),
+	)
+	schemaHead, err := repo.RevParse(&git.RevParse{Rev: "login-schema"})
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		meta.BranchState{Name: "login-schema", Head: schemaHead},
+		GetStoredParentBranchState(t, repo, "login-backend"),
+	)
+	require.Equal(t, "login-backend", GetStoredParentBranchState(t, repo, "login-frontend").Name)
+
+	for branch, subject := range map[string]string{
+		"login-schema":   "login: Add the database schema",
+		"login-backend":  "login: backend",
+		"login-frontend": "login: Add the UI",
+	} {
+		out, err := repo.Git("log", "-1", "--format=%s", branch)
+		require.NoError(t, err)
+		require.Equal(t, subject, out)
+	}
+}
@@ -0,0 +1,64 @@
+package e2e_tests
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackNextPrev(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	// one -> two -> three
+	//    \-> four
+	RequireAv(t, "stack", "branch", "one")
+	gittest.CommitFile(t, repo, "one.txt", []byte("one\n"))
+	RequireAv(t, "stack", "branch", "two")
+	gittest.CommitFile(t, repo, "two.txt", []byte("two\n"))
+	RequireAv(t, "stack", "branch", "three")
+	gittest.CommitFile(t, repo, "three.txt", []byte("three\n"))
+	RequireAv(t, "stack", "branch", "--parent", "one", "four")
+	gittest.CommitFile(t, repo, "four.txt", []byte("four\n"))
+
+	RequireAv(t, "stack", "prev", "--first")
+	RequireCurrentBranchName(t, repo, "one")
+
+	// The stack branches out after one, so we can't tell where to go without
+	// asking.
+	res := Av(t, "stack", "next")
+	require.Equal(t, 1, res.ExitCode)
+	require.Contains(t, res.Stderr, "Branch one has multiple children: four, two")
+	RequireCurrentBranchName(t, repo, "one")
+
+	gittest.CheckoutBranch(t, repo, "two")
+	RequireAv(t, "stack", "next")
+	RequireCurrentBranchName(t, repo, "three")
+	res = Av(t, "stack", "next")
+	require.NotEqual(t, 0, res.ExitCode)
+	require.Contains(t, res.Stderr, "there is no next branch")
+
+	RequireAv(t, "stack", "prev", "2")
+	RequireCurrentBranchName(t, repo, "one")
+	res = Av(t, "stack", "prev", "2")
+	require.NotEqual(t, 0, res.ExitCode)
+	require.Contains(t, res.Stderr, "there is no previous branch")
+
+	gittest.CheckoutBranch(t, repo, "two")
+	res = Av(t, "stack", "next", "2")
+	require.NotEqual(t, 0, res.ExitCode)
+	require.Contains(t, res.Stderr, "there are only 1 subsequent branches")
+	RequireAv(t, "stack", "next", "--last")
+	RequireCurrentBranchName(t, repo, "three")
+
+	// With --sync, the branch is synced onto its parent after checking it
+	// out.
+	gittest.CheckoutBranch(t, repo, "two")
+	twoHead := gittest.CommitFile(t, repo, "two.txt", []byte("two\nmore\n"))
+	RequireAv(t, "stack", "next", "--sync")
+	RequireCurrentBranchName(t, repo, "three")
+	ok, err := repo.IsAncestor(twoHead, "refs/heads/three")
+	require.NoError(t, err)
+	require.True(t, ok, "three should be synced onto two")
+}
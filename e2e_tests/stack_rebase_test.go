@@ -0,0 +1,52 @@
+package e2e_tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackRebaseInteractive(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+	// The rebase is local, so it doesn't need GitHub.
+	WithoutGitHubToken(t)
+
+	RequireAv(t, "stack", "branch", "foo")
+	gittest.CommitFile(t, repo, "foo.txt", []byte("foo"), gittest.WithMessage("foo"))
+	RequireAv(t, "stack", "branch", "bar")
+	gittest.CommitFile(t, repo, "bar.txt", []byte("bar"), gittest.WithMessage("bar 1"))
+	gittest.CommitFile(t, repo, "bar.txt", []byte("bar\nbar"), gittest.WithMessage("bar 2"))
+	RequireAv(t, "stack", "branch", "spam")
+	gittest.CommitFile(t, repo, "spam.txt", []byte("spam"), gittest.WithMessage("spam"))
+	gittest.CheckoutBranch(t, repo, "bar")
+
+	// Only the commits of bar are part of the todo list, so squashing the
+	// second line squashes the commits of bar (and not foo).
+	t.Setenv("GIT_SEQUENCE_EDITOR", "sed -i -e '2s/^pick/squash/'")
+	t.Setenv("GIT_EDITOR", "true")
+	RequireAv(t, "stack", "rebase", "--interactive")
+	RequireCurrentBranchName(t, repo, "bar")
+
+	log := func(rev string) []string {
+		return strings.Split(strings.TrimSpace(RequireCmd(t, "git", "log", "--format=%s", rev).Stdout), "\n")
+	}
+	require.Equal(t, []string{"bar 1", "foo"}, log("main..bar"))
+	// spam is restacked on top of the rewritten bar.
+	require.Equal(t, []string{"spam", "bar 1", "foo"}, log("main..spam"))
+	gittest.CheckoutBranch(t, repo, "spam")
+	requireFileContent(t, "bar.txt", "bar\nbar")
+
+	// A rebase that stops is finished with av stack sync --continue.
+	gittest.CheckoutBranch(t, repo, "bar")
+	t.Setenv("GIT_SEQUENCE_EDITOR", "sed -i -e '1s/^pick/edit/'")
+	RequireAv(t, "stack", "rebase", "--interactive")
+	gittest.CommitFile(t, repo, "edit.txt", []byte("edit"), gittest.WithMessage("edit"))
+	RequireAv(t, "stack", "sync", "--continue")
+	RequireCurrentBranchName(t, repo, "bar")
+	require.Equal(t, []string{"spam", "edit", "bar 1", "foo"}, log("main..spam"))
+
+	require.NotEqual(t, 0, Av(t, "stack", "rebase").ExitCode)
+}
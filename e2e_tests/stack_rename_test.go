@@ -0,0 +1,33 @@
+package e2e_tests
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackRename(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "foo")
+	gittest.CommitFile(t, repo, "foo.txt", []byte("foo"))
+	RequireAv(t, "stack", "branch", "bar")
+	gittest.CommitFile(t, repo, "bar.txt", []byte("bar"))
+	gittest.CheckoutBranch(t, repo, "foo")
+
+	RequireAv(t, "stack", "rename", "foo-renamed")
+	RequireCurrentBranchName(t, repo, "foo-renamed")
+	require.Equal(t, 1, Cmd(t, "git", "show-ref", "refs/heads/foo").ExitCode)
+	require.Equal(t, "foo-renamed", GetStoredParentBranchState(t, repo, "bar").Name)
+	require.Equal(t, "main", GetStoredParentBranchState(t, repo, "foo-renamed").Name)
+
+	// The stack is still intact.
+	gittest.CheckoutBranch(t, repo, "bar")
+	RequireAv(t, "stack", "sync", "--no-fetch", "--no-push")
+	requireFileContent(t, "foo.txt", "foo")
+
+	require.NotEqual(t, 0, Av(t, "stack", "rename", "foo-renamed").ExitCode)
+	require.NotEqual(t, 0, Av(t, "stack", "rename", "bad..name").ExitCode)
+}
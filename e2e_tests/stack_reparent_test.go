@@ -2,9 +2,11 @@ package e2e_tests
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
 	"github.com/stretchr/testify/require"
 )
 
@@ -73,6 +75,50 @@ func TestStackSyncReparentTrunk(t *testing.T) {
 	RequireAv(t, "stack", "sync", "--parent", "main", "--no-fetch", "--no-push")
 }
 
+func TestStackSyncReparentSubStack(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "foo")
+	gittest.CommitFile(t, repo, "foo.txt", []byte("foo"))
+	RequireAv(t, "stack", "branch", "bar")
+	gittest.CommitFile(t, repo, "bar.txt", []byte("bar"))
+	RequireAv(t, "stack", "branch", "spam")
+	gittest.CommitFile(t, repo, "spam.txt", []byte("spam"))
+	RequireCmd(t, "git", "checkout", "main")
+	RequireAv(t, "stack", "branch", "other")
+	gittest.CommitFile(t, repo, "other.txt", []byte("other"))
+
+	// Detach bar (and spam along with it) into its own stack.
+	RequireCmd(t, "git", "checkout", "bar")
+	RequireAv(t, "stack", "sync", "--parent", "main", "--no-fetch", "--no-push")
+	require.Equal(t, meta.BranchState{Name: "main", Trunk: true}, GetStoredParentBranchState(t, repo, "bar"))
+	require.Equal(t, "bar", GetStoredParentBranchState(t, repo, "spam").Name)
+	RequireCmd(t, "git", "checkout", "spam")
+	requireFileContent(t, "spam.txt", "spam")
+	requireFileContent(t, "bar.txt", "bar")
+	require.NoFileExists(t, "foo.txt")
+
+	// Move the sub-stack onto a branch of another stack.
+	RequireCmd(t, "git", "checkout", "bar")
+	RequireAv(t, "stack", "sync", "--parent", "other", "--no-fetch", "--no-push")
+	require.Equal(t, "other", GetStoredParentBranchState(t, repo, "bar").Name)
+	RequireCmd(t, "git", "checkout", "spam")
+	requireFileContent(t, "other.txt", "other")
+	requireFileContent(t, "spam.txt", "spam")
+
+	// Untracked branches can only become the parent if they're a trunk.
+	RequireCmd(t, "git", "checkout", "-b", "release", "main")
+	RequireCmd(t, "git", "push", "origin", "release")
+	RequireCmd(t, "git", "checkout", "bar")
+	res := Av(t, "stack", "sync", "--parent", "release", "--no-fetch", "--no-push")
+	require.Equal(t, 1, res.ExitCode)
+	require.Contains(t, res.Stderr, "is not tracked by av")
+	RequireAv(t, "stack", "sync", "--parent", "release", "--trunk", "--no-fetch", "--no-push")
+	require.Equal(t, meta.BranchState{Name: "release", Trunk: true}, GetStoredParentBranchState(t, repo, "bar"))
+	require.Equal(t, "bar", GetStoredParentBranchState(t, repo, "spam").Name)
+}
+
 func requireFileContent(t *testing.T, file string, expected string, args ...any) {
 	actual, err := os.ReadFile(file)
 	if err != nil {
@@ -80,3 +126,64 @@ func requireFileContent(t *testing.T, file string, expected string, args ...any)
 	}
 	require.Equal(t, expected, string(actual), args...)
 }
+
+func TestStackReparent(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+	// Reparenting is local, so it doesn't need GitHub.
+	WithoutGitHubToken(t)
+
+	RequireAv(t, "stack", "branch", "foo")
+	gittest.CommitFile(t, repo, "foo.txt", []byte("foo"))
+	RequireAv(t, "stack", "branch", "bar")
+	gittest.CommitFile(t, repo, "bar.txt", []byte("bar"))
+	RequireAv(t, "stack", "branch", "spam")
+	gittest.CommitFile(t, repo, "spam.txt", []byte("spam"))
+
+	// Move bar (and spam along with it) onto the trunk.
+	gittest.CheckoutBranch(t, repo, "bar")
+	RequireAv(t, "stack", "reparent", "main")
+	RequireCurrentBranchName(t, repo, "bar")
+	require.Equal(t, meta.BranchState{Name: "main", Trunk: true}, GetStoredParentBranchState(t, repo, "bar"))
+	require.Equal(t, "bar", GetStoredParentBranchState(t, repo, "spam").Name)
+	require.NoFileExists(t, "foo.txt")
+
+	gittest.CheckoutBranch(t, repo, "spam")
+	requireFileContent(t, "spam.txt", "spam")
+	requireFileContent(t, "bar.txt", "bar")
+	require.NoFileExists(t, "foo.txt")
+
+	// The parent has to be tracked.
+	Cmd(t, "git", "branch", "untracked", "main")
+	RequireCmd(t, "git", "checkout", "bar")
+	require.NotEqual(t, 0, Av(t, "stack", "reparent", "untracked").ExitCode)
+}
+
+func TestStackReparentUpdatesCommitTrailers(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+	require.NoError(t, os.WriteFile(
+		filepath.Join(repo.AvDir(), "config.yaml"),
+		[]byte("stack:\n  commitTrailers: true\n"),
+		0644,
+	))
+
+	RequireAv(t, "stack", "branch", "foo")
+	require.NoError(t, os.WriteFile("foo.txt", []byte("foo"), 0644))
+	RequireCmd(t, "git", "add", "foo.txt")
+	RequireAv(t, "commit", "create", "-m", "Foo")
+	RequireAv(t, "stack", "branch", "bar")
+	require.NoError(t, os.WriteFile("bar.txt", []byte("bar"), 0644))
+	RequireCmd(t, "git", "add", "bar.txt")
+	RequireAv(t, "commit", "create", "-m", "Bar")
+	trailer := func() string {
+		out, err := repo.Git("log", "-1", "--format=%(trailers:key=Stack-Parent,valueonly,separator=)", "bar")
+		require.NoError(t, err)
+		return out
+	}
+	require.Equal(t, "foo", trailer())
+
+	WithoutGitHubToken(t)
+	RequireAv(t, "stack", "reparent", "main")
+	require.Equal(t, "main", trailer())
+}
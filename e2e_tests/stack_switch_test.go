@@ -0,0 +1,42 @@
+package e2e_tests
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackSwitch(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	// Create two stacks:
+	//     main -> feature-bar -> feature-baz
+	//     main -> other
+	RequireAv(t, "stack", "branch", "feature-bar")
+	gittest.CommitFile(t, repo, "bar.txt", []byte("bar"))
+	RequireAv(t, "stack", "branch", "feature-baz")
+	gittest.CommitFile(t, repo, "baz.txt", []byte("baz"))
+	RequireAv(t, "stack", "branch", "--parent", "main", "other")
+	gittest.CommitFile(t, repo, "other.txt", []byte("other"))
+
+	// A fuzzy query that matches a single branch checks it out right away.
+	RequireAv(t, "stack", "switch", "fbz")
+	RequireCurrentBranchName(t, repo, "feature-baz")
+
+	// Branches from other stacks can be checked out too.
+	RequireAv(t, "stack", "switch", "oth")
+	RequireCurrentBranchName(t, repo, "other")
+
+	// Without a terminal, ambiguous queries fail and list the matches.
+	ambiguous := Av(t, "stack", "switch", "fb")
+	require.NotEqual(t, 0, ambiguous.ExitCode)
+	require.Contains(t, ambiguous.Stderr, "feature-bar")
+	require.Contains(t, ambiguous.Stderr, "feature-baz")
+	require.NotContains(t, ambiguous.Stderr, "* other")
+	RequireCurrentBranchName(t, repo, "other")
+
+	require.NotEqual(t, 0, Av(t, "stack", "switch", "nope").ExitCode)
+	RequireCurrentBranchName(t, repo, "other")
+}
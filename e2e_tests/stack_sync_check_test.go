@@ -0,0 +1,78 @@
+package e2e_tests
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackSyncCheck(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"), gittest.WithMessage("Commit 1a"))
+	RequireAv(t, "stack", "branch", "stack-2")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n2a\n"), gittest.WithMessage("Commit 2a"))
+	RequireAv(t, "stack", "branch", "stack-3")
+	gittest.CommitFile(t, repo, "other-file", []byte("3a\n"), gittest.WithMessage("Commit 3a"))
+
+	res := RequireAv(t, "stack", "sync", "--check")
+	assert.Contains(t, res.Stderr, "No conflicts expected.")
+
+	// Introduce a commit onto stack-1 that will conflict with stack-2.
+	gittest.WithCheckoutBranch(t, repo, "stack-1", func() {
+		gittest.CommitFile(t, repo, "my-file", []byte("1a\n1b\n"), gittest.WithMessage("Commit 1b"))
+	})
+	stack2Commit, err := repo.RevParse(&git.RevParse{Rev: "stack-2"})
+	require.NoError(t, err)
+
+	res = Av(t, "stack", "sync", "--check")
+	require.Equal(t, 1, res.ExitCode)
+	assert.Contains(t, res.Stderr, "stack-2: would conflict with stack-1 in:\n      my-file\n")
+	assert.Contains(t, res.Stderr, "stack-3: can't be checked until the conflicts in stack-2 are resolved")
+
+	// Nothing was changed.
+	RequireCurrentBranchName(t, repo, "stack-3")
+	commit, err := repo.RevParse(&git.RevParse{Rev: "stack-2"})
+	require.NoError(t, err)
+	assert.Equal(t, stack2Commit, commit)
+
+	res = RequireAv(t, "stack", "sync", "--dry-run")
+	assert.Contains(t, res.Stderr, "stack-1: already up-to-date with main")
+	assert.Contains(t, res.Stderr, "stack-2: would conflict with stack-1")
+	commit, err = repo.RevParse(&git.RevParse{Rev: "stack-2"})
+	require.NoError(t, err)
+	assert.Equal(t, stack2Commit, commit)
+}
+
+func TestStackSyncDryRun(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"), gittest.WithMessage("Commit 1a"))
+	RequireAv(t, "stack", "branch", "stack-2")
+	gittest.CommitFile(t, repo, "other-file", []byte("2a\n"), gittest.WithMessage("Commit 2a"))
+	RequireAv(t, "stack", "branch", "stack-3")
+	gittest.CommitFile(t, repo, "third-file", []byte("3a\n"), gittest.WithMessage("Commit 3a"))
+	gittest.CheckoutBranch(t, repo, "stack-1")
+	stack1Commit := gittest.CommitFile(t, repo, "my-file", []byte("1a\n1b\n"), gittest.WithMessage("Commit 1b"))
+	stack2Commit, err := repo.RevParse(&git.RevParse{Rev: "stack-2"})
+	require.NoError(t, err)
+
+	res := RequireAv(t, "stack", "sync", "--dry-run", "--no-push")
+	assert.Contains(t, res.Stderr, "stack-1: already up-to-date with main\n")
+	assert.Contains(t, res.Stderr,
+		"stack-2: would be rebased onto stack-1 ("+git.ShortSha(stack1Commit)+")\n")
+	assert.Contains(t, res.Stderr, "stack-3: would be rebased onto stack-2 (once it's rebased)\n")
+
+	// Nothing was changed.
+	RequireCurrentBranchName(t, repo, "stack-1")
+	commit, err := repo.RevParse(&git.RevParse{Rev: "stack-2"})
+	require.NoError(t, err)
+	assert.Equal(t, stack2Commit, commit)
+}
@@ -0,0 +1,97 @@
+package e2e_tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackSyncDirtyWorkingTree(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "one")
+	gittest.CommitFile(t, repo, "one.txt", []byte("one\n"))
+	RequireAv(t, "stack", "branch", "two")
+	gittest.CommitFile(t, repo, "two.txt", []byte("two\n"))
+	RequireCmd(t, "git", "checkout", "one")
+	gittest.CommitFile(t, repo, "one.txt", []byte("one\nmore\n"))
+
+	// Untracked files are ignored by default.
+	require.NoError(t, os.WriteFile("notes.txt", []byte("notes\n"), 0644))
+	RequireAv(t, "stack", "sync", "--no-fetch", "--no-push")
+
+	// ...unless configured otherwise.
+	require.NoError(t, os.WriteFile(
+		filepath.Join(repo.AvDir(), "config.yaml"),
+		[]byte("sync:\n  ignoreUntracked: false\n"),
+		0644,
+	))
+	out := Av(t, "stack", "sync", "--no-fetch", "--no-push")
+	require.Equal(t, 1, out.ExitCode)
+	require.Contains(t, out.Stderr, "the working tree has untracked files")
+	require.Contains(t, out.Stderr, "notes.txt")
+	require.NoError(t, os.Remove("notes.txt"))
+
+	// Changes to tracked files always block the sync, even if they're staged.
+	require.NoError(t, os.WriteFile("one.txt", []byte("changed\n"), 0644))
+	RequireCmd(t, "git", "add", "one.txt")
+	out = Av(t, "stack", "sync", "--no-fetch", "--no-push")
+	require.Equal(t, 1, out.ExitCode)
+	require.Contains(t, out.Stderr, "the working tree has staged changes")
+	RequireCmd(t, "git", "reset", "--hard")
+	RequireAv(t, "stack", "sync", "--no-fetch", "--no-push")
+}
+
+func TestStackSyncAutostash(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "one")
+	gittest.CommitFile(t, repo, "one.txt", []byte("one\n"))
+	RequireAv(t, "stack", "branch", "two")
+	gittest.CommitFile(t, repo, "two.txt", []byte("two\n"))
+	RequireCmd(t, "git", "checkout", "one")
+	oneHead := gittest.CommitFile(t, repo, "one.txt", []byte("one\nmore\n"))
+	RequireCmd(t, "git", "checkout", "two")
+
+	// The changes are stashed during the sync and reapplied afterwards.
+	require.NoError(t, os.WriteFile("two.txt", []byte("two\nwip\n"), 0644))
+	out := RequireAv(t, "stack", "sync", "--autostash", "--no-fetch", "--no-push")
+	require.Contains(t, out.Stderr, "Stashed the changes in the working tree")
+	require.Contains(t, out.Stderr, "Applied the stashed changes")
+	requireFileContent(t, "two.txt", "two\nwip\n")
+	ok, err := repo.IsAncestor(oneHead, "refs/heads/two")
+	require.NoError(t, err)
+	require.True(t, ok, "two should be synced")
+	stashes, err := repo.Git("stash", "list")
+	require.NoError(t, err)
+	require.Empty(t, stashes)
+
+	// If the sync stops at a conflict, the changes are reapplied once it's
+	// done.
+	RequireCmd(t, "git", "checkout", "--", ".")
+	RequireCmd(t, "git", "checkout", "one")
+	oneHead = gittest.CommitFile(t, repo, "two.txt", []byte("conflict\n"))
+	RequireCmd(t, "git", "checkout", "two")
+	require.NoError(t, os.WriteFile(
+		filepath.Join(repo.AvDir(), "config.yaml"),
+		[]byte("sync:\n  autostash: true\n"),
+		0644,
+	))
+	require.NoError(t, os.WriteFile("staged.txt", []byte("staged\n"), 0644))
+	RequireCmd(t, "git", "add", "staged.txt")
+	out = Av(t, "stack", "sync", "--no-fetch", "--no-push")
+	require.NotEqual(t, 0, out.ExitCode)
+	require.NoError(t, os.WriteFile("two.txt", []byte("resolved\n"), 0644))
+	RequireCmd(t, "git", "add", "two.txt")
+	RequireAv(t, "stack", "sync", "--continue")
+	RequireCurrentBranchName(t, repo, "two")
+	requireFileContent(t, "staged.txt", "staged\n")
+	ok, err = repo.IsAncestor(oneHead, "refs/heads/two")
+	require.NoError(t, err)
+	require.True(t, ok, "two should be synced")
+}
@@ -0,0 +1,41 @@
+package e2e_tests
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackSyncTrunkFetchesTrunk(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"))
+
+	// Somebody else pushes to main, which hasn't been fetched yet.
+	var mainCommit string
+	gittest.WithCheckoutBranch(t, repo, "main", func() {
+		oldMain, err := repo.RevParse(&git.RevParse{Rev: "HEAD"})
+		require.NoError(t, err)
+		mainCommit = gittest.CommitFile(t, repo, "main-file", []byte("main\n"))
+		RequireCmd(t, "git", "push", "origin", "main")
+		RequireCmd(t, "git", "reset", "--hard", oldMain)
+		RequireCmd(t, "git", "update-ref", "refs/remotes/origin/main", oldMain)
+	})
+
+	// With --no-fetch, the last fetched commit of main is used.
+	res := RequireAv(t, "stack", "sync", "--trunk", "--dry-run", "--no-fetch", "--no-push")
+	require.NotContains(t, res.Stderr, "Fetching the latest commit")
+	require.Contains(t, res.Stderr, "already up-to-date with main")
+
+	// Otherwise, main is fetched first.
+	res = RequireAv(t, "stack", "sync", "--trunk", "--dry-run", "--no-push")
+	require.Contains(t, res.Stderr, "Fetching the latest commit of origin/main")
+	require.Contains(t, res.Stderr, "would be rebased onto main ("+git.ShortSha(mainCommit)+")")
+	originMain, err := repo.RevParse(&git.RevParse{Rev: "refs/remotes/origin/main"})
+	require.NoError(t, err)
+	require.Equal(t, mainCommit, originMain)
+}
@@ -0,0 +1,53 @@
+package e2e_tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackSyncGeneratedFiles(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	require.NoError(t, os.WriteFile(
+		filepath.Join(repo.AvDir(), "config.yaml"),
+		[]byte(`sync:
+  generatedFiles:
+    - paths: ["*.lock"]
+      command: 'for f in "$@"; do echo regenerated > "$f"; done'
+`),
+		0644,
+	))
+
+	require.NoError(t, os.Mkdir(filepath.Join(repo.Dir(), "deps"), 0755))
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "deps/yarn.lock", []byte("1a\n"))
+	RequireAv(t, "stack", "branch", "stack-2")
+	gittest.CommitFile(t, repo, "deps/yarn.lock", []byte("2a\n"))
+	gittest.CommitFile(t, repo, "my-file", []byte("2a\n"))
+	gittest.CheckoutBranch(t, repo, "stack-1")
+	stack1Head := gittest.CommitFile(t, repo, "deps/yarn.lock", []byte("1b\n"))
+
+	// The conflict is only in the generated file, so it's regenerated and the
+	// sync continues.
+	sync := RequireAv(t, "stack", "sync", "--no-fetch", "--no-push")
+	require.Contains(t, sync.Stderr, "regenerating conflicting file(s) deps/yarn.lock")
+	contents, err := repo.Git("show", "stack-2:deps/yarn.lock")
+	require.NoError(t, err)
+	require.Equal(t, "regenerated", contents)
+	require.Equal(t, stack1Head, GetStoredParentBranchState(t, repo, "stack-2").Head)
+
+	// Conflicts in other files still have to be resolved by hand: the first
+	// commit of stack-2 is regenerated, but the sync stops at the second one.
+	gittest.CommitFile(t, repo, "deps/yarn.lock", []byte("1c\n"))
+	gittest.CommitFile(t, repo, "my-file", []byte("1c\n"))
+	sync = Av(t, "stack", "sync", "--no-fetch", "--no-push")
+	require.NotEqual(t, 0, sync.ExitCode)
+	require.Contains(t, sync.Stderr, "regenerating conflicting file(s) deps/yarn.lock")
+	require.Contains(t, sync.Stderr, "Write my-file")
+	RequireAv(t, "stack", "sync", "--abort")
+}
@@ -0,0 +1,100 @@
+package e2e_tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackSyncMergeStrategy(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"))
+	RequireAv(t, "stack", "branch", "stack-2")
+	gittest.CommitFile(t, repo, "other-file", []byte("2a\n"))
+	stack2Head, err := repo.RevParse(&git.RevParse{Rev: "HEAD"})
+	require.NoError(t, err)
+
+	gittest.CheckoutBranch(t, repo, "stack-1")
+	stack1Head := gittest.CommitFile(t, repo, "my-file", []byte("1b\n"))
+
+	res := RequireAv(t, "stack", "sync", "--no-fetch", "--no-push", "--strategy=merge")
+	require.Contains(t, res.Stderr, "merged without conflicts")
+	RequireCurrentBranchName(t, repo, "stack-1")
+
+	// stack-1 is merged into stack-2, which keeps its original commits.
+	parents, err := repo.Git("log", "-1", "--format=%P", "stack-2")
+	require.NoError(t, err)
+	require.Equal(t, []string{stack2Head, stack1Head}, strings.Fields(parents))
+	subject, err := repo.Git("log", "-1", "--format=%s", "stack-2")
+	require.NoError(t, err)
+	require.Equal(t, "Merge stack-1 into stack-2", subject)
+	require.Equal(t, stack1Head, GetStoredParentBranchState(t, repo, "stack-2").Head)
+
+	// Syncing again doesn't change anything.
+	res = RequireAv(t, "stack", "sync", "--no-fetch", "--no-push", "--strategy=merge")
+	require.Contains(t, res.Stderr, "already up-to-date with parent stack-1")
+}
+
+func TestStackSyncMergeStrategyConflict(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	// The strategy can be set in the config as well.
+	require.NoError(t, os.WriteFile(
+		filepath.Join(repo.AvDir(), "config.yaml"),
+		[]byte("sync:\n  strategy: merge\n"),
+		0644,
+	))
+
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"))
+	RequireAv(t, "stack", "branch", "stack-2")
+	stack2Head := gittest.CommitFile(t, repo, "my-file", []byte("2a\n"))
+	gittest.CheckoutBranch(t, repo, "stack-1")
+	stack1Head := gittest.CommitFile(t, repo, "my-file", []byte("1b\n"))
+
+	res := Av(t, "stack", "sync", "--no-fetch", "--no-push")
+	require.NotEqual(t, 0, res.ExitCode)
+	require.Contains(t, res.Stderr, "merge conflict in:")
+	require.Contains(t, res.Stderr, "my-file")
+
+	// Commits can't be skipped in a merge.
+	res = Av(t, "stack", "sync", "--skip")
+	require.NotEqual(t, 0, res.ExitCode)
+	require.Contains(t, res.Stderr, "--skip can't be used when merging")
+
+	// Aborting the sync aborts the merge.
+	RequireAv(t, "stack", "sync", "--abort")
+	op, err := repo.OperationInProgress()
+	require.NoError(t, err)
+	require.Equal(t, git.OperationNone, op)
+	RequireCurrentBranchName(t, repo, "stack-1")
+
+	res = Av(t, "stack", "sync", "--no-fetch", "--no-push")
+	require.NotEqual(t, 0, res.ExitCode)
+	require.NoError(t, os.WriteFile("my-file", []byte("1b\n2a\n"), 0644))
+	RequireCmd(t, "git", "add", "my-file")
+	RequireAv(t, "stack", "sync", "--continue")
+	RequireCurrentBranchName(t, repo, "stack-1")
+
+	parents, err := repo.Git("log", "-1", "--format=%P", "stack-2")
+	require.NoError(t, err)
+	require.Equal(t, []string{stack2Head, stack1Head}, strings.Fields(parents))
+	content, err := repo.Git("show", "stack-2:my-file")
+	require.NoError(t, err)
+	require.Equal(t, "1b\n2a", content)
+	require.Equal(t, stack1Head, GetStoredParentBranchState(t, repo, "stack-2").Head)
+
+	// Reparenting always rebases.
+	res = Av(t, "stack", "sync", "--no-fetch", "--no-push", "--parent", "main")
+	require.NotEqual(t, 0, res.ExitCode)
+	require.Contains(t, res.Stderr, "can't be used with the merge strategy")
+}
@@ -0,0 +1,62 @@
+package e2e_tests
+
+import (
+	"os"
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackSyncOnlyDirty(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	// Create a tree-shaped stack:
+	//     stack-1
+	//     ├── stack-2a ── stack-3a
+	//     └── stack-2b
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "1-file", []byte("1a\n"))
+	RequireAv(t, "stack", "branch", "stack-2a")
+	gittest.CommitFile(t, repo, "2a-file", []byte("2a\n"))
+	RequireAv(t, "stack", "branch", "stack-3a")
+	gittest.CommitFile(t, repo, "3a-file", []byte("3a\n"))
+	gittest.CheckoutBranch(t, repo, "stack-1")
+	RequireAv(t, "stack", "branch", "stack-2b")
+	gittest.CommitFile(t, repo, "2b-file", []byte("2b\n"))
+
+	// Only stack-3a is out of date with its parent.
+	gittest.CheckoutBranch(t, repo, "stack-2a")
+	stack2aHead := gittest.CommitFile(t, repo, "2a-file", []byte("2a\n2b\n"))
+	stack2bHead, err := repo.RevParse(&git.RevParse{Rev: "stack-2b"})
+	require.NoError(t, err)
+
+	sync := RequireAv(t, "stack", "sync", "--no-fetch", "--no-push", "--only-dirty")
+	require.Contains(t, sync.Stderr, "Skipping 3 branch(es) that are already up-to-date")
+	ok, err := repo.IsAncestor(stack2aHead, "refs/heads/stack-3a")
+	require.NoError(t, err)
+	require.True(t, ok, "stack-3a should be synced onto stack-2a")
+	newStack2bHead, err := repo.RevParse(&git.RevParse{Rev: "stack-2b"})
+	require.NoError(t, err)
+	require.Equal(t, stack2bHead, newStack2bHead)
+	RequireCurrentBranchName(t, repo, "stack-2a")
+
+	// Now everything is up-to-date.
+	sync = RequireAv(t, "stack", "sync", "--no-fetch", "--no-push", "--only-dirty")
+	require.Contains(t, sync.Stderr, "Skipping 4 branch(es)")
+	require.Contains(t, sync.Stderr, "All branches are already up-to-date.")
+	RequireCurrentBranchName(t, repo, "stack-2a")
+
+	// GitHub isn't needed (not even to push) if there's nothing to sync, so
+	// this works without a token.
+	WithoutGitHubToken(t)
+	sync = RequireAv(t, "stack", "sync", "--no-fetch", "--only-dirty")
+	require.Contains(t, sync.Stderr, "All branches are already up-to-date.")
+	require.NotContains(t, sync.Stderr, "Pushing")
+	RequireCurrentBranchName(t, repo, "stack-2a")
+	_, err = actions.ReadStackSyncState(repo)
+	require.True(t, os.IsNotExist(err), "the sync state should be cleared")
+}
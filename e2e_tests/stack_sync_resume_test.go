@@ -0,0 +1,70 @@
+package e2e_tests
+
+import (
+	"os"
+	"testing"
+
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackSyncResumeAfterGitRebaseContinue(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"), gittest.WithMessage("Commit 1a"))
+	RequireAv(t, "stack", "branch", "stack-2")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n2a\n"), gittest.WithMessage("Commit 2a"))
+	RequireAv(t, "stack", "branch", "stack-3")
+	gittest.CommitFile(t, repo, "other-file", []byte("3a\n"), gittest.WithMessage("Commit 3a"))
+
+	gittest.WithCheckoutBranch(t, repo, "stack-1", func() {
+		gittest.CommitFile(t, repo, "my-file", []byte("1a\n1b\n"), gittest.WithMessage("Commit 1b"))
+	})
+	require.NotEqual(t, 0, Av(t, "stack", "sync", "--no-fetch", "--no-push").ExitCode)
+
+	// Resolve the conflict and finish the rebase with Git instead of av.
+	require.NoError(t, os.WriteFile("my-file", []byte("1a\n1b\n2a\n"), 0644))
+	RequireCmd(t, "git", "add", "my-file")
+	t.Setenv("GIT_EDITOR", "true")
+	RequireCmd(t, "git", "rebase", "--continue")
+
+	// A plain sync picks up where the previous one stopped.
+	res := RequireAv(t, "stack", "sync", "--no-fetch", "--no-push")
+	assert.Contains(t, res.Stderr, "Resuming the sync that stopped at stack-2")
+	RequireCurrentBranchName(t, repo, "stack-3")
+	RequireCmd(t, "git", "merge-base", "--is-ancestor", "stack-1", "stack-2")
+	RequireCmd(t, "git", "merge-base", "--is-ancestor", "stack-2", "stack-3")
+	stack1Commit, err := repo.RevParse(&git.RevParse{Rev: "stack-1"})
+	require.NoError(t, err)
+	assert.Equal(t, stack1Commit, GetStoredParentBranchState(t, repo, "stack-2").Head)
+}
+
+func TestStackSyncContinueAfterGitRebaseAbort(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"), gittest.WithMessage("Commit 1a"))
+	RequireAv(t, "stack", "branch", "stack-2")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n2a\n"), gittest.WithMessage("Commit 2a"))
+	origStack1Commit, err := repo.RevParse(&git.RevParse{Rev: "stack-1"})
+	require.NoError(t, err)
+
+	gittest.WithCheckoutBranch(t, repo, "stack-1", func() {
+		gittest.CommitFile(t, repo, "my-file", []byte("1a\n1b\n"), gittest.WithMessage("Commit 1b"))
+	})
+	require.NotEqual(t, 0, Av(t, "stack", "sync", "--no-fetch", "--no-push").ExitCode)
+	RequireCmd(t, "git", "rebase", "--abort")
+
+	// The aborted rebase isn't mistaken for a completed one: stack-2 is
+	// rebased again (and conflicts again).
+	res := Av(t, "stack", "sync", "--continue")
+	require.NotEqual(t, 0, res.ExitCode)
+	assert.Contains(t, res.Stderr, "the rebase was aborted outside of av")
+	assert.Equal(t, origStack1Commit, GetStoredParentBranchState(t, repo, "stack-2").Head)
+	RequireAv(t, "stack", "sync", "--abort")
+}
@@ -0,0 +1,156 @@
+package e2e_tests
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackSyncConflictingSibling(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	// Create a stack where stack-1 has two children (stack-2a and stack-2b)
+	// and only stack-2a conflicts with stack-1.
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"))
+	RequireAv(t, "stack", "branch", "stack-2a")
+	gittest.CommitFile(t, repo, "my-file", []byte("2a\n"))
+	RequireAv(t, "stack", "branch", "stack-3a")
+	gittest.CommitFile(t, repo, "other-file", []byte("3a\n"))
+	gittest.CheckoutBranch(t, repo, "stack-1")
+	RequireAv(t, "stack", "branch", "stack-2b")
+	gittest.CommitFile(t, repo, "b-file", []byte("2b\n"))
+
+	gittest.CheckoutBranch(t, repo, "stack-1")
+	stack1Head := gittest.CommitFile(t, repo, "my-file", []byte("1b\n"))
+
+	// stack-2a comes first but its conflict shouldn't stop stack-2b from
+	// being synced.
+	sync := Av(t, "stack", "sync", "--no-fetch", "--no-push")
+	require.NotEqual(t, 0, sync.ExitCode)
+	require.Contains(t, sync.Stderr, "Could not sync the following branches because of conflicts")
+	require.Contains(t, sync.Stderr, "stack-2a (and its descendants stack-3a)")
+
+	ok, err := repo.IsAncestor(stack1Head, "refs/heads/stack-2b")
+	require.NoError(t, err)
+	require.True(t, ok, "stack-2b should be synced despite the conflict in stack-2a")
+	require.Equal(t, stack1Head, GetStoredParentBranchState(t, repo, "stack-2b").Head)
+
+	// The sync stopped at the conflict in stack-2a.
+	state, err := actions.ReadStackSyncState(repo)
+	require.NoError(t, err)
+	require.Equal(t, "stack-2a", state.CurrentBranch)
+	require.Equal(t, []string{"stack-2a", "stack-3a"}, state.Branches)
+
+	require.NoError(t, os.WriteFile("my-file", []byte("1b\n2a\n"), 0644))
+	RequireCmd(t, "git", "add", "my-file")
+	RequireAv(t, "stack", "sync", "--continue")
+	for _, branch := range []string{"stack-2a", "stack-3a"} {
+		ok, err := repo.IsAncestor(stack1Head, "refs/heads/"+branch)
+		require.NoError(t, err)
+		require.True(t, ok, "%s should be synced after continuing", branch)
+	}
+	RequireCurrentBranchName(t, repo, "stack-1")
+}
+
+func TestStackSyncConflictingSiblings(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	// Both children of stack-1 conflict with it.
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"))
+	RequireAv(t, "stack", "branch", "stack-2a")
+	gittest.CommitFile(t, repo, "my-file", []byte("2a\n"))
+	RequireAv(t, "stack", "branch", "stack-3a")
+	gittest.CommitFile(t, repo, "other-file", []byte("3a\n"))
+	gittest.CheckoutBranch(t, repo, "stack-1")
+	RequireAv(t, "stack", "branch", "stack-2b")
+	gittest.CommitFile(t, repo, "my-file", []byte("2b\n"))
+
+	gittest.CheckoutBranch(t, repo, "stack-1")
+	stack1Head := gittest.CommitFile(t, repo, "my-file", []byte("1b\n"))
+
+	// stack-2a is postponed, and the sync stops at the conflict in stack-2b.
+	// The postponed branches must still be synced once it's resolved.
+	sync := Av(t, "stack", "sync", "--no-fetch", "--no-push")
+	require.NotEqual(t, 0, sync.ExitCode)
+	state, err := actions.ReadStackSyncState(repo)
+	require.NoError(t, err)
+	require.Equal(t, "stack-2b", state.CurrentBranch)
+	require.Equal(t, []string{"stack-2b", "stack-2a", "stack-3a"}, state.Branches)
+
+	require.NoError(t, os.WriteFile("my-file", []byte("1b\n2b\n"), 0644))
+	RequireCmd(t, "git", "add", "my-file")
+	sync = Av(t, "stack", "sync", "--continue")
+	require.NotEqual(t, 0, sync.ExitCode, "the sync should stop at the conflict in stack-2a")
+	state, err = actions.ReadStackSyncState(repo)
+	require.NoError(t, err)
+	require.Equal(t, "stack-2a", state.CurrentBranch)
+
+	require.NoError(t, os.WriteFile("my-file", []byte("1b\n2a\n"), 0644))
+	RequireCmd(t, "git", "add", "my-file")
+	RequireAv(t, "stack", "sync", "--continue")
+	for _, branch := range []string{"stack-2a", "stack-3a", "stack-2b"} {
+		ok, err := repo.IsAncestor(stack1Head, "refs/heads/"+branch)
+		require.NoError(t, err)
+		require.True(t, ok, "%s should be synced after continuing", branch)
+	}
+	RequireCurrentBranchName(t, repo, "stack-1")
+}
+
+func TestStackSyncTree(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	// Create a tree-shaped stack:
+	//     stack-1
+	//     ├── stack-2a ── stack-3a
+	//     └── stack-2b ── stack-3b
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "1-file", []byte("1a\n"))
+	RequireAv(t, "stack", "branch", "stack-2a")
+	gittest.CommitFile(t, repo, "2a-file", []byte("2a\n"))
+	RequireAv(t, "stack", "branch", "stack-3a")
+	gittest.CommitFile(t, repo, "3a-file", []byte("3a\n"))
+	gittest.CheckoutBranch(t, repo, "stack-1")
+	RequireAv(t, "stack", "branch", "stack-2b")
+	gittest.CommitFile(t, repo, "2b-file", []byte("2b\n"))
+	RequireAv(t, "stack", "branch", "stack-3b")
+	gittest.CommitFile(t, repo, "3b-file", []byte("3b\n"))
+
+	gittest.CheckoutBranch(t, repo, "stack-1")
+	stack1Head := gittest.CommitFile(t, repo, "1-file", []byte("1b\n"))
+
+	// Syncing from a leaf of one subtree syncs the whole tree, and every
+	// branch is synced after its parent.
+	gittest.CheckoutBranch(t, repo, "stack-3b")
+	sync := RequireAv(t, "stack", "sync", "--no-fetch", "--no-push")
+	order := []string{"stack-2a", "stack-3a", "stack-2b", "stack-3b"}
+	last := -1
+	for _, branch := range order {
+		i := strings.Index(sync.Stderr, "Synchronizing branch "+branch+"...")
+		require.Greater(t, i, last, "%s should be synced after %v", branch, order)
+		last = i
+	}
+
+	for _, branch := range order {
+		ok, err := repo.IsAncestor(stack1Head, "refs/heads/"+branch)
+		require.NoError(t, err)
+		require.True(t, ok, "%s should be synced", branch)
+	}
+	require.Equal(t, stack1Head, GetStoredParentBranchState(t, repo, "stack-2a").Head)
+	require.Equal(t, stack1Head, GetStoredParentBranchState(t, repo, "stack-2b").Head)
+	for child, parent := range map[string]string{"stack-3a": "stack-2a", "stack-3b": "stack-2b"} {
+		parentHead, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + parent})
+		require.NoError(t, err)
+		require.Equal(t, parentHead, GetStoredParentBranchState(t, repo, child).Head)
+	}
+	RequireCurrentBranchName(t, repo, "stack-3b")
+}
@@ -0,0 +1,116 @@
+package e2e_tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackSyncDetectsSquashMergedParent(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"), gittest.WithMessage("Commit 1a"))
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n1b\n"), gittest.WithMessage("Commit 1b"))
+	RequireAv(t, "stack", "branch", "stack-2")
+	gittest.CommitFile(t, repo, "other-file", []byte("2a\n"), gittest.WithMessage("Commit 2a"))
+
+	RequireCmd(t, "git", "push", "--set-upstream", "origin", "stack-1", "stack-2")
+
+	// stack-1 is squash-merged into main (after another commit landed there)
+	// without av knowing about it, since the branch has no pull request, and
+	// its remote branch is deleted.
+	var squashCommit string
+	gittest.WithCheckoutBranch(t, repo, "main", func() {
+		gittest.CommitFile(t, repo, "main-file", []byte("main\n"))
+		RequireCmd(t, "git", "merge", "--squash", "stack-1")
+		RequireCmd(t, "git", "commit", "-m", "Squashed stack-1")
+		var err error
+		squashCommit, err = repo.RevParse(&git.RevParse{Rev: "HEAD"})
+		require.NoError(t, err)
+		gittest.CommitFile(t, repo, "main-file", []byte("main\nlater\n"))
+		RequireCmd(t, "git", "push", "origin", "main")
+	})
+	RequireCmd(t, "git", "push", "origin", "--delete", "stack-1")
+
+	sync := RequireAv(t, "stack", "sync", "--no-fetch", "--no-push")
+	require.Contains(t, sync.Stderr, "it was squash-merged")
+	// Only stack-1 was merged.
+	require.Equal(t, 1, strings.Count(sync.Stderr, "skipping sync for merged branch"))
+
+	// stack-2 is moved onto main (on top of the squash commit, without the
+	// commits of stack-1).
+	require.Equal(t,
+		meta.BranchState{Name: "main", Trunk: true},
+		GetStoredParentBranchState(t, repo, "stack-2"),
+	)
+	commits, err := repo.Git("log", "--format=%s", squashCommit+"..stack-2")
+	require.NoError(t, err)
+	require.Equal(t, "Commit 2a", commits)
+	ok, err := repo.IsAncestor(squashCommit, "refs/heads/stack-2")
+	require.NoError(t, err)
+	require.True(t, ok, "stack-2 should be rebased onto the squash commit")
+}
+
+func TestStackSyncSkipsSquashMergeCheckForPushedBranches(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"), gittest.WithMessage("Commit 1a"))
+	RequireCmd(t, "git", "push", "--set-upstream", "origin", "stack-1")
+
+	// The changes of stack-1 land in main, but its remote branch still exists
+	// (and it doesn't have a closed pull request), so it isn't considered
+	// merged.
+	gittest.WithCheckoutBranch(t, repo, "main", func() {
+		RequireCmd(t, "git", "merge", "--squash", "stack-1")
+		RequireCmd(t, "git", "commit", "-m", "Squashed stack-1")
+		RequireCmd(t, "git", "push", "origin", "main")
+	})
+
+	sync := RequireAv(t, "stack", "sync", "--no-fetch", "--no-push")
+	require.NotContains(t, sync.Stderr, "it was squash-merged")
+	require.NotContains(t, sync.Stderr, "skipping sync for merged branch")
+}
+
+func TestStackSyncTrunkSkipsSquashMergedCommits(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	// stack-2 was created on top of a branch that isn't tracked by av, so av
+	// only knows that it's based on main.
+	RequireCmd(t, "git", "checkout", "-b", "other")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"), gittest.WithMessage("Commit 1a"))
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n1b\n"), gittest.WithMessage("Commit 1b"))
+	RequireCmd(t, "git", "checkout", "-b", "stack-2")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n1b\n2a\n"), gittest.WithMessage("Commit 2a"))
+	RequireAv(t, "stack", "adopt", "--parent", "main")
+
+	// The other branch is squash-merged into main.
+	var squashCommit string
+	gittest.WithCheckoutBranch(t, repo, "main", func() {
+		RequireCmd(t, "git", "merge", "--squash", "other")
+		RequireCmd(t, "git", "commit", "-m", "Squashed other")
+		var err error
+		squashCommit, err = repo.RevParse(&git.RevParse{Rev: "HEAD"})
+		require.NoError(t, err)
+		RequireCmd(t, "git", "push", "origin", "main")
+	})
+
+	// Replaying 1a onto the squash commit would conflict, so the commits of the
+	// other branch are skipped.
+	sync := RequireAv(t, "stack", "sync", "--trunk", "--no-fetch", "--no-push")
+	require.Contains(t, sync.Stderr, "they were squash-merged in commit "+git.ShortSha(squashCommit))
+	commits, err := repo.Git("log", "--format=%s", squashCommit+"..stack-2")
+	require.NoError(t, err)
+	require.Equal(t, "Commit 2a", commits)
+	ok, err := repo.IsAncestor(squashCommit, "refs/heads/stack-2")
+	require.NoError(t, err)
+	require.True(t, ok, "stack-2 should be rebased onto the squash commit")
+}
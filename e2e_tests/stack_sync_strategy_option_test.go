@@ -0,0 +1,48 @@
+package e2e_tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackSyncStrategyOption(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"))
+	RequireAv(t, "stack", "branch", "stack-2")
+	gittest.CommitFile(t, repo, "my-file", []byte("2a\n"))
+	gittest.CheckoutBranch(t, repo, "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1b\n"))
+
+	// When rebasing, "theirs" is the branch that's being rebased, so its
+	// changes win the conflict.
+	RequireAv(t, "stack", "sync", "--no-fetch", "--no-push", "-X", "theirs")
+	contents, err := repo.Git("show", "stack-2:my-file")
+	require.NoError(t, err)
+	require.Equal(t, "2a", contents)
+
+	// The options can be set in the config as well. "ours" is the parent, so
+	// the conflicting commit of stack-2 ends up empty and is dropped.
+	require.NoError(t, os.WriteFile(
+		filepath.Join(repo.AvDir(), "config.yaml"),
+		[]byte("sync:\n  strategyOptions: [ours]\n"),
+		0644,
+	))
+	stack1Head := gittest.CommitFile(t, repo, "my-file", []byte("1c\n"))
+	RequireAv(t, "stack", "sync", "--no-fetch", "--no-push")
+	contents, err = repo.Git("show", "stack-2:my-file")
+	require.NoError(t, err)
+	require.Equal(t, "1c", contents)
+	require.Equal(t, stack1Head, GetStoredParentBranchState(t, repo, "stack-2").Head)
+
+	// Unknown options are rejected before anything is synced.
+	sync := Av(t, "stack", "sync", "--no-fetch", "--no-push", "-X", "mine")
+	require.NotEqual(t, 0, sync.ExitCode)
+	require.Contains(t, sync.Stderr, `unknown strategy option "mine"`)
+}
@@ -0,0 +1,55 @@
+package e2e_tests
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackSyncSubset(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	// Create a tree-shaped stack:
+	//     stack-1
+	//     ├── stack-2a ── stack-3a
+	//     └── stack-2b
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "1-file", []byte("1a\n"))
+	RequireAv(t, "stack", "branch", "stack-2a")
+	gittest.CommitFile(t, repo, "2a-file", []byte("2a\n"))
+	RequireAv(t, "stack", "branch", "stack-3a")
+	gittest.CommitFile(t, repo, "3a-file", []byte("3a\n"))
+	gittest.CheckoutBranch(t, repo, "stack-1")
+	RequireAv(t, "stack", "branch", "stack-2b")
+	gittest.CommitFile(t, repo, "2b-file", []byte("2b\n"))
+
+	gittest.CheckoutBranch(t, repo, "stack-1")
+	stack1Head := gittest.CommitFile(t, repo, "1-file", []byte("1b\n"))
+	isSynced := func(branch string) bool {
+		ok, err := repo.IsAncestor(stack1Head, "refs/heads/"+branch)
+		require.NoError(t, err)
+		return ok
+	}
+
+	// Only the subtree of stack-2a is synced.
+	RequireAv(t, "stack", "sync", "--no-fetch", "--no-push", "stack-2a")
+	require.True(t, isSynced("stack-2a"))
+	require.True(t, isSynced("stack-3a"))
+	require.False(t, isSynced("stack-2b"), "stack-2b isn't below stack-2a")
+	RequireCurrentBranchName(t, repo, "stack-1")
+
+	// With --current, only the given branches are synced (not their
+	// descendants).
+	stack1Head = gittest.CommitFile(t, repo, "1-file", []byte("1c\n"))
+	RequireAv(t, "stack", "sync", "--no-fetch", "--no-push", "--current", "stack-2b", "stack-2a")
+	require.True(t, isSynced("stack-2a"))
+	require.True(t, isSynced("stack-2b"))
+	require.False(t, isSynced("stack-3a"), "stack-3a is a descendant of stack-2a")
+
+	// Branches can't be combined with --all.
+	sync := Av(t, "stack", "sync", "--no-fetch", "--no-push", "--all", "stack-2a")
+	require.NotEqual(t, 0, sync.ExitCode)
+	require.Contains(t, sync.Stderr, "branches can't be given with --all")
+}
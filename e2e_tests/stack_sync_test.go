@@ -101,6 +101,18 @@ func TestStackSync(t *testing.T) {
 		syncContinueWithoutResolving.ExitCode,
 		"stack sync --continue should return non-zero exit code if conflicts have not been resolved",
 	)
+	// staging the file doesn't resolve the conflict
+	RequireCmd(t, "git", "add", "my-file")
+	syncContinueWithMarkers := Av(t, "stack", "sync", "--continue")
+	require.NotEqual(
+		t,
+		0,
+		syncContinueWithMarkers.ExitCode,
+		"stack sync --continue should return non-zero exit code if conflict markers remain",
+	)
+	require.Contains(t, syncContinueWithMarkers.Stderr, "unresolved conflict markers")
+	require.Contains(t, syncContinueWithMarkers.Stderr, "my-file:")
+
 	// resolve the conflict
 	err := os.WriteFile(filepath.Join(repo.Dir(), "my-file"), []byte("1a\n1b\n2a\n"), 0644)
 	require.NoError(t, err)
@@ -221,6 +233,49 @@ func TestStackSyncAbort(t *testing.T) {
 	}, GetStoredParentBranchState(t, repo, "stack-2"))
 }
 
+func TestStackSyncAbortRestoresSyncedBranches(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireCmd(t, "git", "checkout", "-b", "stack-1")
+	gittest.CommitFile(t, repo, "one-file", []byte("1a\n"), gittest.WithMessage("Commit 1a"))
+	RequireAv(t, "stack", "branch", "stack-2")
+	gittest.CommitFile(t, repo, "my-file", []byte("2a\n"), gittest.WithMessage("Commit 2a"))
+	origStack1Commit, err := repo.RevParse(&git.RevParse{Rev: "stack-1"})
+	require.NoError(t, err)
+	origStack2Commit, err := repo.RevParse(&git.RevParse{Rev: "stack-2"})
+	require.NoError(t, err)
+
+	// Introduce a commit onto main that conflicts with stack-2 (but not with
+	// stack-1).
+	gittest.WithCheckoutBranch(t, repo, "main", func() {
+		gittest.CommitFile(t, repo, "my-file", []byte("main\n"), gittest.WithMessage("Commit main"))
+		RequireCmd(t, "git", "push", "origin", "main")
+	})
+
+	// stack-1 is rebased onto main before the sync stops at stack-2...
+	syncConflict := Av(t, "stack", "sync", "--trunk", "--no-fetch", "--no-push")
+	require.NotEqual(t, 0, syncConflict.ExitCode)
+	stack1Commit, err := repo.RevParse(&git.RevParse{Rev: "stack-1"})
+	require.NoError(t, err)
+	require.NotEqual(t, origStack1Commit, stack1Commit, "stack-1 should have been rebased")
+
+	// ... and the abort restores it.
+	RequireAv(t, "stack", "sync", "--abort")
+	RequireCurrentBranchName(t, repo, "stack-2")
+	stack1Commit, err = repo.RevParse(&git.RevParse{Rev: "stack-1"})
+	require.NoError(t, err)
+	require.Equal(t, origStack1Commit, stack1Commit, "stack-1 should be restored after abort")
+	stack2Commit, err := repo.RevParse(&git.RevParse{Rev: "stack-2"})
+	require.NoError(t, err)
+	require.Equal(t, origStack2Commit, stack2Commit)
+	require.Equal(t, meta.BranchState{
+		Name: "stack-1",
+		Head: origStack1Commit,
+	}, GetStoredParentBranchState(t, repo, "stack-2"))
+	require.NoFileExists(t, path.Join(repo.AvDir(), "stack-sync.state.json"))
+}
+
 func TestStackSyncWithLotsOfConflicts(t *testing.T) {
 	repo := gittest.NewTempRepo(t)
 	Chdir(t, repo.Dir())
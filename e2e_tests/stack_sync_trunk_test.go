@@ -5,6 +5,7 @@ import (
 
 	"github.com/aviator-co/av/internal/git"
 	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
 	"github.com/aviator-co/av/internal/meta/jsonfiledb"
 	"github.com/stretchr/testify/require"
 )
@@ -92,3 +93,48 @@ func TestStackSyncTrunk(t *testing.T) {
 		"commit 3a should be an ancestor of HEAD of stack-2 after running sync with --trunk",
 	)
 }
+
+func TestStackSyncTrunkNonDefault(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	// A stack that's based on a release branch rather than on main:
+	//     main:    X -> M
+	//     release:  \ -> R1 -> R2
+	//     stack-1:         \ -> 1a
+	RequireCmd(t, "git", "checkout", "-b", "release")
+	gittest.CommitFile(t, repo, "release-file", []byte("R1\n"), gittest.WithMessage("Commit R1"))
+	RequireCmd(t, "git", "push", "origin", "release")
+	RequireCmd(t, "git", "checkout", "-b", "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"), gittest.WithMessage("Commit 1a"))
+
+	// As with the test above, the metadata has to be set manually since only
+	// the default branch can be used as a trunk with `av stack branch`.
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err, "failed to open repo db")
+	tx := db.WriteTx()
+	tx.SetBranch(meta.Branch{Name: "stack-1", Parent: meta.BranchState{Name: "release", Trunk: true}})
+	require.NoError(t, tx.Commit())
+
+	var r2Commit, mCommit string
+	gittest.WithCheckoutBranch(t, repo, "release", func() {
+		r2Commit = gittest.CommitFile(t, repo, "release-file", []byte("R1\nR2\n"), gittest.WithMessage("Commit R2"))
+		RequireCmd(t, "git", "push", "origin", "release")
+	})
+	gittest.WithCheckoutBranch(t, repo, "main", func() {
+		mCommit = gittest.CommitFile(t, repo, "main-file", []byte("M\n"), gittest.WithMessage("Commit M"))
+		RequireCmd(t, "git", "push", "origin", "main")
+	})
+
+	// The stack is rebased onto its own trunk, not onto the default branch.
+	output := RequireAv(t, "stack", "sync", "--no-fetch", "--no-push", "--trunk")
+	require.Contains(t, output.Stderr, "The stack is based on release")
+	require.Equal(t, 0,
+		Cmd(t, "git", "merge-base", "--is-ancestor", r2Commit, "stack-1").ExitCode,
+		"commit R2 should be an ancestor of HEAD of stack-1 after running sync with --trunk",
+	)
+	require.NotEqual(t, 0,
+		Cmd(t, "git", "merge-base", "--is-ancestor", mCommit, "stack-1").ExitCode,
+		"commit M should not be an ancestor of HEAD of stack-1 after running sync with --trunk",
+	)
+}
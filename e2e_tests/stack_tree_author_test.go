@@ -0,0 +1,42 @@
+package e2e_tests
+
+import (
+	"os"
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackTreeAuthor(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "mine")
+	gittest.CommitFile(t, repo, "my-file", []byte("mine\n"))
+
+	// A stack of someone else.
+	gittest.CheckoutBranch(t, repo, "main")
+	RequireAv(t, "stack", "branch", "theirs")
+	require.NoError(t, os.WriteFile("their-file", []byte("theirs\n"), 0644))
+	RequireCmd(t, "git", "add", "their-file")
+	RequireCmd(t, "git",
+		"-c", "user.name=Someone Else", "-c", "user.email=someone@example.com",
+		"commit", "-m", "Their commit",
+	)
+	gittest.CheckoutBranch(t, repo, "mine")
+
+	tree := RequireAv(t, "stack", "tree")
+	assert.Contains(t, tree.Stdout, "mine")
+	assert.NotContains(t, tree.Stdout, "theirs")
+	assert.Contains(t, tree.Stderr, "1 stack(s) of other users are hidden")
+
+	tree = RequireAv(t, "stack", "tree", "--author", "someone")
+	assert.Contains(t, tree.Stdout, "theirs")
+	assert.Contains(t, tree.Stdout, "mine", "the stack of the current branch is always shown")
+
+	tree = RequireAv(t, "stack", "tree", "--all-users")
+	assert.Contains(t, tree.Stdout, "mine")
+	assert.Contains(t, tree.Stdout, "theirs")
+}
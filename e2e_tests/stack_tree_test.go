@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
 )
 
 func TestStackTree(t *testing.T) {
@@ -22,3 +23,35 @@ func TestStackTree(t *testing.T) {
 
 	RequireAv(t, "stack", "tree")
 }
+
+func TestStackTreePruneMetadata(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "foo")
+	gittest.CommitFile(t, repo, "foo", []byte("foo"))
+	RequireAv(t, "stack", "branch", "bar")
+	barHead := gittest.CommitFile(t, repo, "bar", []byte("bar"))
+	RequireAv(t, "stack", "branch", "baz")
+	gittest.CommitFile(t, repo, "baz", []byte("baz"))
+
+	// Delete the middle branch outside of av.
+	gittest.CheckoutBranch(t, repo, "main")
+	RequireCmd(t, "git", "branch", "-D", "bar")
+
+	// Without --prune-metadata (and without a terminal to ask), the branch is
+	// only reported.
+	output := RequireAv(t, "stack", "tree")
+	require.Contains(t, output.Stderr, "no longer exist: bar")
+	require.Equal(t, "foo", GetStoredParentBranchState(t, repo, "bar").Name)
+	require.Equal(t, 1, Av(t, "doctor").ExitCode)
+
+	RequireAv(t, "stack", "tree", "--prune-metadata")
+	// The commits of the deleted branch are still part of baz, so they are
+	// dropped on the next sync.
+	parent := GetStoredParentBranchState(t, repo, "baz")
+	require.Equal(t, "foo", parent.Name)
+	require.Equal(t, barHead, parent.Head)
+	require.Equal(t, 0, Av(t, "doctor").ExitCode)
+	require.NotContains(t, RequireAv(t, "stack", "tree").Stderr, "no longer exist")
+}
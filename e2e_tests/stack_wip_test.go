@@ -0,0 +1,45 @@
+package e2e_tests
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackWIP(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"))
+	RequireAv(t, "stack", "branch", "stack-2")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n2a\n"))
+
+	isWIP := func(name string) bool {
+		db, err := jsonfiledb.OpenRepo(repo)
+		require.NoError(t, err, "failed to open repo db")
+		branch, _ := db.ReadTx().Branch(name)
+		return branch.WIP
+	}
+
+	RequireAv(t, "stack", "wip")
+	require.True(t, isWIP("stack-2"))
+	require.False(t, isWIP("stack-1"))
+	require.Contains(t, RequireAv(t, "stack", "tree").Stdout, "wip")
+
+	// Work-in-progress branches are still synced locally.
+	gittest.CheckoutBranch(t, repo, "stack-1")
+	stack1Head := gittest.CommitFile(t, repo, "other-file", []byte("1b\n"))
+	RequireAv(t, "stack", "sync", "--no-fetch", "--no-push")
+	ok, err := repo.IsAncestor(stack1Head, "refs/heads/stack-2")
+	require.NoError(t, err)
+	require.True(t, ok, "stack-2 should be synced even though it's a work in progress")
+	require.True(t, isWIP("stack-2"))
+
+	RequireAv(t, "stack", "unwip", "stack-2")
+	require.False(t, isWIP("stack-2"))
+
+	require.NotEqual(t, 0, Av(t, "stack", "wip", "not-a-branch").ExitCode)
+}
@@ -0,0 +1,29 @@
+package e2e_tests
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusTrunkDivergence(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	RequireAv(t, "stack", "branch", "stack-1")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"))
+	status := RequireAv(t, "status")
+	require.Contains(t, status.Stderr, "Trunk: up-to-date with origin/main")
+
+	gittest.CheckoutBranch(t, repo, "main")
+	gittest.CommitFile(t, repo, "my-file", []byte("main\n"))
+	RequireCmd(t, "git", "push", "origin", "main")
+	gittest.CheckoutBranch(t, repo, "stack-1")
+
+	status = RequireAv(t, "status")
+	require.Contains(t, status.Stderr, "stack root stack-1 is 1 commit(s) behind origin/main")
+	require.Contains(t, status.Stderr, "would conflict in:")
+	require.Contains(t, status.Stderr, "stack-1: my-file")
+	require.Contains(t, status.Stderr, "av stack sync --trunk")
+}
@@ -0,0 +1,46 @@
+package e2e_tests
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwitchBack(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	Chdir(t, repo.Dir())
+
+	// Our stack looks like:
+	//     stack-1: main -> 1a
+	//     stack-2:          \ -> 2a
+	//     stack-3:                 \ -> 3a
+	require.Equal(t, 0, Cmd(t, "git", "checkout", "-b", "stack-1").ExitCode)
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n"), gittest.WithMessage("Commit 1a"))
+	RequireAv(t, "stack", "branch", "stack-2")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n2a\n"), gittest.WithMessage("Commit 2a"))
+	RequireAv(t, "stack", "branch", "stack-3")
+	gittest.CommitFile(t, repo, "my-file", []byte("1a\n2a\n3a\n"), gittest.WithMessage("Commit 3a"))
+
+	RequireAv(t, "stack", "prev", "--first")
+	RequireCurrentBranchName(t, repo, "stack-1")
+
+	RequireAv(t, "switch", "-")
+	RequireCurrentBranchName(t, repo, "stack-3")
+
+	RequireAv(t, "back")
+	RequireCurrentBranchName(t, repo, "stack-1")
+
+	// Intermediate checkouts done by av (e.g., during a sync) shouldn't affect
+	// where we go back to.
+	RequireAv(t, "switch", "stack-2")
+	RequireAv(t, "stack", "sync", "--all", "--no-fetch", "--no-push")
+	RequireCurrentBranchName(t, repo, "stack-2")
+	RequireAv(t, "back")
+	RequireCurrentBranchName(t, repo, "stack-1")
+
+	// Checkouts done outside of av fall back to Git's history.
+	require.Equal(t, 0, Cmd(t, "git", "checkout", "stack-3").ExitCode)
+	RequireAv(t, "back")
+	RequireCurrentBranchName(t, repo, "stack-1")
+}
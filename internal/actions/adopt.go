@@ -0,0 +1,215 @@
+package actions
+
+import (
+	"slices"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/sirupsen/logrus"
+)
+
+type AdoptOpts struct {
+	// The name of the (untracked) branch to adopt.
+	Branch string
+	// The parent of the branch. If empty, the parent is inferred from the
+	// history of the branch (see InferParent).
+	Parent string
+	// If true, the branch belongs to someone else and is tracked as read-only
+	// (see meta.Branch.ReadOnly). It's created from the remote branch if it
+	// doesn't exist locally.
+	ReadOnly bool
+}
+
+// AdoptBranch starts tracking an existing branch that was created without av
+// (e.g., with `git checkout -b`) by writing its metadata. The branch isn't
+// rebased: its parent head is set to the commit where it branched off the
+// parent. A read-only branch of someone else can be adopted even if it only
+// exists on the remote.
+func AdoptBranch(repo *git.Repo, tx meta.WriteTx, opts AdoptOpts) (meta.Branch, error) {
+	if _, ok := tx.Branch(opts.Branch); ok {
+		return meta.Branch{}, errors.Errorf("branch %q is already tracked by av", opts.Branch)
+	}
+	if opts.ReadOnly {
+		if err := createBranchFromRemote(repo, opts.Branch); err != nil {
+			return meta.Branch{}, err
+		}
+	}
+	if exists, err := repo.DoesBranchExist(opts.Branch); err != nil {
+		return meta.Branch{}, err
+	} else if !exists {
+		return meta.Branch{}, errors.Errorf("branch %q does not exist", opts.Branch)
+	}
+	trunks, err := trunkBranches(repo, tx)
+	if err != nil {
+		return meta.Branch{}, err
+	}
+	if slices.Contains(trunks, opts.Branch) {
+		return meta.Branch{}, errors.Errorf("branch %q is a trunk branch and can't be adopted", opts.Branch)
+	}
+
+	var parent meta.BranchState
+	if opts.Parent == "" {
+		parent, err = InferParent(repo, tx, opts.Branch)
+		if err != nil {
+			return meta.Branch{}, err
+		}
+	} else {
+		if opts.Parent == opts.Branch {
+			return meta.Branch{}, errors.New("a branch can't be its own parent")
+		}
+		parent.Name = opts.Parent
+		parent.Trunk = slices.Contains(trunks, opts.Parent)
+		if _, ok := tx.Branch(opts.Parent); !ok && !parent.Trunk {
+			return meta.Branch{}, errors.Errorf(
+				"parent branch %q is not tracked by av and is not a trunk (adopt it first)", opts.Parent,
+			)
+		}
+		if !parent.Trunk {
+			parent.Head, err = repo.MergeBase(&git.MergeBase{
+				Revs: []string{"refs/heads/" + opts.Branch, "refs/heads/" + opts.Parent},
+			})
+			if err != nil {
+				return meta.Branch{}, errors.WrapIff(
+					err, "branch %q doesn't share any history with %q", opts.Branch, opts.Parent,
+				)
+			}
+		}
+	}
+
+	branch := meta.Branch{Name: opts.Branch, Parent: parent, ReadOnly: opts.ReadOnly}
+	tx.SetBranch(branch)
+	return branch, nil
+}
+
+// createBranchFromRemote fetches the given branch from the remote and creates
+// the local branch from it (unless it already exists).
+func createBranchFromRemote(repo *git.Repo, branchName string) error {
+	if _, err := repo.Run(&git.RunOpts{
+		Args:      []string{"fetch", "origin", branchName},
+		ExitError: true,
+	}); err != nil {
+		return errors.WrapIff(err, "failed to fetch branch %q from origin", branchName)
+	}
+	if exists, err := repo.DoesBranchExist(branchName); err != nil || exists {
+		return err
+	}
+	if _, err := repo.Run(&git.RunOpts{
+		Args:      []string{"branch", "--track", branchName, "refs/remotes/origin/" + branchName},
+		ExitError: true,
+	}); err != nil {
+		return errors.WrapIff(err, "failed to create branch %q", branchName)
+	}
+	return nil
+}
+
+// InferParent returns the most likely parent of the given branch: the trunk
+// or tracked branch that it has the fewest commits on top of. Branches that
+// are based on the given branch aren't considered.
+func InferParent(repo *git.Repo, tx meta.ReadTx, branchName string) (meta.BranchState, error) {
+	head, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + branchName})
+	if err != nil {
+		return meta.BranchState{}, errors.WrapIff(err, "failed to determine HEAD for branch %q", branchName)
+	}
+	trunks, err := trunkBranches(repo, tx)
+	if err != nil {
+		return meta.BranchState{}, err
+	}
+
+	type candidate struct {
+		state meta.BranchState
+		ref   string
+	}
+	var candidates []candidate
+	for _, trunk := range trunks {
+		ref := "refs/remotes/origin/" + trunk
+		if exists, _ := repo.DoesRefExist(ref); !exists {
+			ref = "refs/heads/" + trunk
+		}
+		candidates = append(candidates, candidate{meta.BranchState{Name: trunk, Trunk: true}, ref})
+	}
+	var tracked []string
+	for name := range tx.AllBranches() {
+		if name != branchName && !slices.Contains(trunks, name) {
+			tracked = append(tracked, name)
+		}
+	}
+	slices.Sort(tracked)
+	for _, name := range tracked {
+		candidates = append(candidates, candidate{meta.BranchState{Name: name}, "refs/heads/" + name})
+	}
+
+	// Candidates are ranked by the number of commits the branch has on top of
+	// them. Ties (e.g., a tracked branch without commits of its own) are broken
+	// by preferring candidates that the branch is based on the tip of, and then
+	// by preferring tracked branches over trunks (unless neither was
+	// branched off their tip, in which case the trunk is the safer guess).
+	rank := func(state meta.BranchState, tip, base string) int {
+		switch {
+		case tip == base && !state.Trunk:
+			return 0
+		case tip == base:
+			return 1
+		case state.Trunk:
+			return 2
+		default:
+			return 3
+		}
+	}
+	var best *meta.BranchState
+	bestDistance, bestRank := 0, 0
+	for _, c := range candidates {
+		tip, err := repo.RevParse(&git.RevParse{Rev: c.ref})
+		if err != nil {
+			continue
+		}
+		base, err := repo.MergeBase(&git.MergeBase{Revs: []string{head, tip}})
+		if err != nil || (base == head && tip != head) {
+			// Either unrelated or based on the branch.
+			continue
+		}
+		commits, err := repo.RevList(git.RevListOpts{Specifiers: []string{head, "^" + base}})
+		if err != nil {
+			return meta.BranchState{}, err
+		}
+		r := rank(c.state, tip, base)
+		logrus.WithFields(logrus.Fields{
+			"candidate": c.state.Name,
+			"commits":   len(commits),
+			"rank":      r,
+		}).Debug("considering parent branch")
+		if best != nil && (len(commits) > bestDistance || (len(commits) == bestDistance && r >= bestRank)) {
+			continue
+		}
+		state := c.state
+		if !state.Trunk {
+			state.Head = base
+		}
+		best = &state
+		bestDistance, bestRank = len(commits), r
+	}
+	if best == nil {
+		return meta.BranchState{}, errors.Errorf(
+			"failed to infer the parent of %q: it doesn't share any history with the trunk or any tracked branch",
+			branchName,
+		)
+	}
+	return *best, nil
+}
+
+// trunkBranches returns the names of the trunk branches: the default branch of
+// the repository and the trunks of the existing stacks.
+func trunkBranches(repo *git.Repo, tx meta.ReadTx) ([]string, error) {
+	defaultBranch, err := repo.DefaultBranch()
+	if err != nil {
+		return nil, err
+	}
+	trunks := []string{defaultBranch}
+	for _, branch := range tx.AllBranches() {
+		if branch.Parent.Trunk && !slices.Contains(trunks, branch.Parent.Name) {
+			trunks = append(trunks, branch.Parent.Name)
+		}
+	}
+	slices.Sort(trunks[1:])
+	return trunks, nil
+}
@@ -0,0 +1,80 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdoptBranch(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+
+	_, err = repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	one := gittest.CommitFile(t, repo, "one", []byte("one\n"))
+	_, err = repo.Git("checkout", "-b", "sibling", "main")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "sibling", []byte("sibling\n"))
+	_, err = repo.Git("checkout", "-b", "two", "one")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "two", []byte("two\n"))
+	// A child of the branch that is adopted is never its parent.
+	_, err = repo.Git("checkout", "-b", "three")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "three", []byte("three\n"))
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{Name: "one", Parent: meta.BranchState{Name: "main", Trunk: true}})
+	tx.SetBranch(meta.Branch{Name: "three", Parent: meta.BranchState{Name: "one"}})
+
+	branch, err := actions.AdoptBranch(repo, tx, actions.AdoptOpts{Branch: "two"})
+	require.NoError(t, err)
+	require.Equal(t, meta.BranchState{Name: "one", Head: one}, branch.Parent)
+	stored, _ := tx.Branch("two")
+	require.Equal(t, branch, stored)
+
+	// The sibling shares its history with one, but it's based on the trunk.
+	branch, err = actions.AdoptBranch(repo, tx, actions.AdoptOpts{Branch: "sibling"})
+	require.NoError(t, err)
+	require.Equal(t, meta.BranchState{Name: "main", Trunk: true}, branch.Parent)
+
+	_, err = actions.AdoptBranch(repo, tx, actions.AdoptOpts{Branch: "two"})
+	require.Error(t, err, "already tracked")
+	_, err = actions.AdoptBranch(repo, tx, actions.AdoptOpts{Branch: "main"})
+	require.Error(t, err, "trunk")
+	_, err = actions.AdoptBranch(repo, tx, actions.AdoptOpts{Branch: "missing"})
+	require.Error(t, err, "missing")
+}
+
+func TestAdoptBranch_Parent(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+
+	_, err = repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	one := gittest.CommitFile(t, repo, "one", []byte("one\n"))
+	_, err = repo.Git("checkout", "-b", "two")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "two", []byte("two\n"))
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+
+	_, err = actions.AdoptBranch(repo, tx, actions.AdoptOpts{Branch: "two", Parent: "one"})
+	require.Error(t, err, "the parent must be tracked")
+
+	branch, err := actions.AdoptBranch(repo, tx, actions.AdoptOpts{Branch: "one", Parent: "main"})
+	require.NoError(t, err)
+	require.Equal(t, meta.BranchState{Name: "main", Trunk: true}, branch.Parent)
+	branch, err = actions.AdoptBranch(repo, tx, actions.AdoptOpts{Branch: "two", Parent: "one"})
+	require.NoError(t, err)
+	require.Equal(t, meta.BranchState{Name: "one", Head: one}, branch.Parent)
+}
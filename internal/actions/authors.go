@@ -0,0 +1,40 @@
+package actions
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+)
+
+// BranchAuthors returns the authors (formatted as "Name <email>") of the
+// commits that the given branch has on top of its parent (see BranchBase), in
+// the order of their most recent commits. A branch without commits of its own
+// has no authors.
+func BranchAuthors(repo *git.Repo, tx meta.ReadTx, branchName string) ([]string, error) {
+	base, err := BranchBase(repo, tx, branchName)
+	if err != nil {
+		return nil, err
+	}
+	out, err := repo.Git("log", "--format=%an <%ae>", base+"..refs/heads/"+branchName)
+	if err != nil {
+		return nil, err
+	}
+	var authors []string
+	for _, author := range strings.Split(out, "\n") {
+		if author != "" && !slices.Contains(authors, author) {
+			authors = append(authors, author)
+		}
+	}
+	return authors, nil
+}
+
+// MatchesAuthor returns true if any of the given authors (see BranchAuthors)
+// contains the given pattern (e.g., a name or an email address), ignoring case.
+func MatchesAuthor(authors []string, pattern string) bool {
+	pattern = strings.ToLower(pattern)
+	return slices.ContainsFunc(authors, func(author string) bool {
+		return strings.Contains(strings.ToLower(author), pattern)
+	})
+}
@@ -0,0 +1,74 @@
+package actions
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/utils/colors"
+)
+
+// CreateAutostash stashes the changes to tracked files in the working tree (as
+// `git rebase --autostash` does) and returns the stash commit, or an empty
+// string if there's nothing to stash. The stash is kept in the stash list until
+// it's applied with ApplyAutostash, so the changes aren't lost if the sync is
+// interrupted.
+func CreateAutostash(repo *git.Repo, message string) (string, error) {
+	status, err := repo.Status()
+	if err != nil {
+		return "", err
+	}
+	if len(status.Staged) == 0 && len(status.Unstaged) == 0 {
+		return "", nil
+	}
+	if _, err := repo.Git("stash", "push", "--message", message); err != nil {
+		return "", errors.WrapIf(err, "failed to stash the changes in the working tree")
+	}
+	stash, err := repo.RevParse(&git.RevParse{Rev: "refs/stash"})
+	if err != nil {
+		return "", errors.WrapIf(err, "failed to read the stash")
+	}
+	_, _ = fmt.Fprint(os.Stderr,
+		"Stashed the changes in the working tree (", colors.UserInput(git.ShortSha(stash)), ")\n\n",
+	)
+	return stash, nil
+}
+
+// ApplyAutostash reapplies the changes that were stashed with CreateAutostash
+// and drops the stash. If the changes don't apply cleanly, the working tree is
+// reset and the stash is kept so that the changes can be applied manually.
+func ApplyAutostash(repo *git.Repo, stash string) error {
+	out, err := repo.Run(&git.RunOpts{Args: []string{"stash", "apply", stash}})
+	if err != nil {
+		return err
+	}
+	if out.ExitCode != 0 {
+		if _, err := repo.Git("reset", "--hard"); err != nil {
+			return errors.WrapIf(err, "failed to reset the working tree")
+		}
+		_, _ = fmt.Fprint(os.Stderr,
+			colors.Warning("Applying the stashed changes resulted in conflicts."), "\n",
+			"  - Your changes are safe in the stash (", colors.UserInput(git.ShortSha(stash)), ").",
+			" Use ", colors.CliCmd("git stash pop"), " or ", colors.CliCmd("git stash drop"),
+			" at any time.\n",
+		)
+		return nil
+	}
+
+	// Drop the stash from wherever it ended up in the stash list (other
+	// stashes might have been pushed in the meantime).
+	list, err := repo.Git("stash", "list", "--format=%H")
+	if err != nil {
+		return err
+	}
+	if i := slices.Index(strings.Split(list, "\n"), stash); i >= 0 {
+		if _, err := repo.Git("stash", "drop", fmt.Sprintf("stash@{%d}", i)); err != nil {
+			return errors.WrapIf(err, "failed to drop the stash")
+		}
+	}
+	_, _ = fmt.Fprint(os.Stderr, "Applied the stashed changes\n")
+	return nil
+}
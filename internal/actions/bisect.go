@@ -0,0 +1,50 @@
+package actions
+
+// BisectStack finds the first of the given branches (ordered from the bottom
+// of the stack to the top) for which test reports a failure. The parent of the
+// first branch is assumed to pass. Unless linear is true, the last branch is
+// tested first and the rest is binary-searched, so only O(log n) branches are
+// tested. With linear, the branches are tested one after the other (which is
+// more robust if the failure isn't reproducible in every branch after the one
+// that introduced it).
+//
+// Returns the index of the first failing branch or -1 if none of the tested
+// branches failed.
+func BisectStack(branches []string, linear bool, test func(branch string) (bool, error)) (int, error) {
+	if linear {
+		for i, branch := range branches {
+			passed, err := test(branch)
+			if err != nil {
+				return -1, err
+			}
+			if !passed {
+				return i, nil
+			}
+		}
+		return -1, nil
+	}
+
+	if len(branches) == 0 {
+		return -1, nil
+	}
+	// good is the index of the last branch known to pass (-1 for the parent of
+	// the stack) and bad the index of the first branch known to fail.
+	good, bad := -1, len(branches)-1
+	passed, err := test(branches[bad])
+	if err != nil || passed {
+		return -1, err
+	}
+	for bad-good > 1 {
+		mid := (good + bad) / 2
+		passed, err := test(branches[mid])
+		if err != nil {
+			return -1, err
+		}
+		if passed {
+			good = mid
+		} else {
+			bad = mid
+		}
+	}
+	return bad, nil
+}
@@ -0,0 +1,51 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBisectStack(t *testing.T) {
+	branches := []string{"one", "two", "three", "four", "five"}
+	// failsFrom returns a test that fails for every branch starting at the
+	// given index and records the tested branches.
+	failsFrom := func(index int, tested *[]string) func(string) (bool, error) {
+		return func(branch string) (bool, error) {
+			*tested = append(*tested, branch)
+			for i, b := range branches {
+				if b == branch {
+					return i < index, nil
+				}
+			}
+			t.Fatalf("unexpected branch %q", branch)
+			return false, nil
+		}
+	}
+
+	for index := range branches {
+		var tested []string
+		res, err := actions.BisectStack(branches, false, failsFrom(index, &tested))
+		require.NoError(t, err)
+		require.Equal(t, index, res)
+		require.LessOrEqual(t, len(tested), 4)
+		require.Equal(t, "five", tested[0])
+
+		tested = nil
+		res, err = actions.BisectStack(branches, true, failsFrom(index, &tested))
+		require.NoError(t, err)
+		require.Equal(t, index, res)
+		require.Equal(t, branches[:index+1], tested)
+	}
+
+	// Nothing fails.
+	var tested []string
+	res, err := actions.BisectStack(branches, false, failsFrom(len(branches), &tested))
+	require.NoError(t, err)
+	require.Equal(t, -1, res)
+	require.Equal(t, []string{"five"}, tested)
+	res, err = actions.BisectStack(branches, true, failsFrom(len(branches), &tested))
+	require.NoError(t, err)
+	require.Equal(t, -1, res)
+}
@@ -0,0 +1,88 @@
+package actions
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/sirupsen/logrus"
+)
+
+// DetachedHeadBranch is a stack branch that a detached HEAD belongs to.
+type DetachedHeadBranch struct {
+	// The name of the branch.
+	Name string
+	// If true, HEAD is a descendant of the branch (e.g., because commits were
+	// created while in detached HEAD) and the branch can be fast-forwarded to
+	// HEAD. Otherwise, HEAD is one of the commits of the branch (e.g., after a
+	// bisect) and the branch can simply be checked out again.
+	FastForward bool
+}
+
+// FindDetachedHeadBranches determines which stack branches the given detached
+// HEAD commit belongs to.
+//
+// A commit belongs to a branch if it's one of the commits that the branch adds
+// on top of its parent, or if it's a descendant of the branch and none of the
+// branch's children.
+func FindDetachedHeadBranches(
+	repo *git.Repo,
+	tx meta.ReadTx,
+	head string,
+) ([]DetachedHeadBranch, error) {
+	var res []DetachedHeadBranch
+	for name, branch := range tx.AllBranches() {
+		if exists, err := repo.DoesBranchExist(name); err != nil {
+			return nil, err
+		} else if !exists {
+			continue
+		}
+
+		headInBranch, err := repo.IsAncestor(head, name)
+		if err != nil {
+			return nil, err
+		}
+		if headInBranch {
+			headInParent, err := repo.IsAncestor(head, branch.Parent.Name)
+			if err != nil {
+				logrus.WithError(err).WithField("branch", name).
+					Debug("failed to check if HEAD belongs to parent branch")
+				continue
+			}
+			if !headInParent {
+				res = append(res, DetachedHeadBranch{Name: name})
+			}
+			continue
+		}
+
+		branchInHead, err := repo.IsAncestor(name, head)
+		if err != nil {
+			return nil, err
+		}
+		if !branchInHead {
+			continue
+		}
+		// If HEAD is related to one of the children, it belongs to that child
+		// instead (or one of its descendants).
+		belongsToChild := false
+		for _, child := range meta.ChildrenNames(tx, name) {
+			if ok, err := repo.IsAncestor(child, head); err == nil && ok {
+				belongsToChild = true
+				break
+			}
+			if ok, err := repo.IsAncestor(head, child); err == nil && ok {
+				belongsToChild = true
+				break
+			}
+		}
+		if !belongsToChild {
+			res = append(res, DetachedHeadBranch{Name: name, FastForward: true})
+		}
+	}
+	// Sort for determinism.
+	slices.SortFunc(res, func(a, b DetachedHeadBranch) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return res, nil
+}
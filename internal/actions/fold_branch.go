@@ -0,0 +1,75 @@
+package actions
+
+import (
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+)
+
+// FoldBranch folds a branch into its parent: the parent is fast-forwarded to
+// the branch (so that it contains the commits of the branch), the children of
+// the branch are moved onto the parent, and the branch is deleted (both from
+// the repository and from the av metadata). The parent is checked out
+// afterwards.
+//
+// The branch must be checked out and stacked on top of the latest version of
+// its parent (i.e., it must be synced). It can't be folded into a trunk branch.
+func FoldBranch(repo *git.Repo, tx meta.WriteTx, branchName string) error {
+	branch, ok := tx.Branch(branchName)
+	if !ok {
+		return errors.Errorf("branch %q is not tracked by av", branchName)
+	}
+	parent := branch.Parent.Name
+	if branch.Parent.Trunk {
+		return errors.Errorf("branch %q can't be folded into the trunk branch %q", branchName, parent)
+	}
+	head, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + branchName})
+	if err != nil {
+		return errors.WrapIff(err, "failed to determine HEAD for branch %q", branchName)
+	}
+	parentHead, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + parent})
+	if err != nil {
+		return errors.WrapIff(err, "failed to determine HEAD for branch %q", parent)
+	}
+	if contained, err := repo.IsAncestor(parentHead, head); err != nil {
+		return err
+	} else if !contained {
+		return errors.Errorf(
+			"branch %q isn't based on the latest version of %q (run av stack sync first)",
+			branchName, parent,
+		)
+	}
+
+	if current, err := repo.CurrentBranchName(); err != nil {
+		return err
+	} else if current != branchName {
+		return errors.Errorf("branch %q must be checked out to fold it", branchName)
+	}
+
+	// The parent isn't checked out, so it can be moved directly. Checking it out
+	// afterwards doesn't touch the working tree since it then points to the same
+	// commit as the branch.
+	if err := repo.UpdateRef(&git.UpdateRef{
+		Ref: "refs/heads/" + parent,
+		New: head,
+		Old: parentHead,
+		// Keep the fold in the reflog so that it can be undone.
+		CreateReflog: true,
+	}); err != nil {
+		return err
+	}
+	if _, err := repo.CheckoutBranch(&git.CheckoutBranch{Name: parent}); err != nil {
+		return err
+	}
+	if _, err := repo.Git("branch", "--delete", "--force", branchName); err != nil {
+		return errors.WrapIff(err, "failed to delete branch %q", branchName)
+	}
+
+	for _, child := range meta.Children(tx, branchName) {
+		child.Parent.Name = parent
+		child.Parent.Head = head
+		tx.SetBranch(child)
+	}
+	tx.DeleteBranch(branchName)
+	return nil
+}
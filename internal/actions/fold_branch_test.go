@@ -0,0 +1,57 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFoldBranch(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+
+	_, err = repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	one := gittest.CommitFile(t, repo, "one", []byte("one\n"))
+	_, err = repo.Git("checkout", "-b", "two")
+	require.NoError(t, err)
+	two := gittest.CommitFile(t, repo, "two", []byte("two\n"))
+	_, err = repo.Git("checkout", "-b", "three")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "three", []byte("three\n"))
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{Name: "one", Parent: meta.BranchState{Name: "main", Trunk: true}})
+	tx.SetBranch(meta.Branch{Name: "two", Parent: meta.BranchState{Name: "one", Head: one}})
+	tx.SetBranch(meta.Branch{Name: "three", Parent: meta.BranchState{Name: "two", Head: two}})
+
+	// Only the checked out branch can be folded, and not into the trunk.
+	require.Error(t, actions.FoldBranch(repo, tx, "two"))
+	gittest.CheckoutBranch(t, repo, "one")
+	require.Error(t, actions.FoldBranch(repo, tx, "one"))
+
+	gittest.CheckoutBranch(t, repo, "two")
+	require.NoError(t, actions.FoldBranch(repo, tx, "two"))
+
+	current, err := repo.CurrentBranchName()
+	require.NoError(t, err)
+	require.Equal(t, "one", current)
+	head, err := repo.RevParse(&git.RevParse{Rev: "one"})
+	require.NoError(t, err)
+	require.Equal(t, two, head)
+	exists, err := repo.DoesBranchExist("two")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	_, ok := tx.Branch("two")
+	require.False(t, ok)
+	three, _ := tx.Branch("three")
+	require.Equal(t, meta.BranchState{Name: "one", Head: two}, three.Parent)
+}
@@ -0,0 +1,113 @@
+package actions
+
+import (
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+)
+
+// ImportPullRequestBranches creates the branch metadata for pull requests that
+// were created by av (and so carry av's metadata in their body) but aren't
+// tracked in the local repository (e.g., in a fresh clone in a CI job).
+//
+// Missing local branches are created from their remote-tracking branches, so
+// origin must have been fetched beforehand. The remote branches of merged pull
+// requests are usually deleted, so those are re-created at the last head of the
+// pull request if that commit is available (which it is if a child branch was
+// based on it). Sync needs the merged branches to move their children onto the
+// trunk without replaying the merged commits.
+//
+// Pull requests without av metadata and branches that are already tracked are
+// ignored. The names of the imported branches are returned.
+func ImportPullRequestBranches(
+	repo *git.Repo,
+	tx meta.WriteTx,
+	prs []gh.PullRequest,
+) ([]string, error) {
+	var imported []string
+	for _, pr := range prs {
+		name := pr.HeadBranchName()
+		if _, ok := tx.Branch(name); ok {
+			continue
+		}
+		prMeta, err := ReadPRMetadata(pr.Body)
+		if err != nil || prMeta.Parent == "" {
+			logrus.WithError(err).WithField("branch", name).
+				Debug("skipping pull request without av metadata")
+			continue
+		}
+
+		exists, err := repo.DoesBranchExist(name)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			start, err := importedBranchStart(repo, pr)
+			if err != nil {
+				return nil, err
+			}
+			if start != "" {
+				if _, err := repo.Run(&git.RunOpts{
+					Args:      []string{"branch", name, start},
+					ExitError: true,
+				}); err != nil {
+					return nil, errors.WrapIff(err, "failed to create branch %q", name)
+				}
+			}
+		}
+
+		branch := meta.Branch{
+			Name: name,
+			Parent: meta.BranchState{
+				Name:  prMeta.Parent,
+				Trunk: prMeta.Parent == prMeta.Trunk,
+			},
+			PullRequest: &meta.PullRequest{
+				ID:        pr.ID,
+				Number:    pr.Number,
+				Permalink: pr.Permalink,
+				State:     pr.State,
+			},
+			MergeCommit: pr.GetMergeCommit(),
+		}
+		if !branch.Parent.Trunk {
+			branch.Parent.Head = prMeta.ParentHead
+		}
+		tx.SetBranch(branch)
+		imported = append(imported, name)
+	}
+	return imported, nil
+}
+
+// importedBranchStart returns the commit to create the local branch for the
+// given pull request at (or an empty string if the branch can't be created).
+func importedBranchStart(repo *git.Repo, pr gh.PullRequest) (string, error) {
+	name := pr.HeadBranchName()
+	remoteExists, err := repo.DoesRemoteBranchExist(name)
+	if err != nil {
+		return "", err
+	}
+	if remoteExists {
+		return "refs/remotes/origin/" + name, nil
+	}
+	if pr.State == githubv4.PullRequestStateOpen {
+		return "", errors.Errorf("branch %q of pull request #%d not found on origin", name, pr.Number)
+	}
+	if pr.HeadRefOID == "" {
+		return "", nil
+	}
+	out, err := repo.Run(&git.RunOpts{
+		Args: []string{"cat-file", "-e", pr.HeadRefOID + "^{commit}"},
+	})
+	if err != nil {
+		return "", err
+	}
+	if out.ExitCode != 0 {
+		logrus.WithField("branch", name).Debug("head of merged pull request is not available locally")
+		return "", nil
+	}
+	return pr.HeadRefOID, nil
+}
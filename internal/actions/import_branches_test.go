@@ -0,0 +1,104 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/shurcooL/githubv4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportPullRequestBranches(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+	pushOpts := actions.PushOpts{Force: actions.ForceWithLease}
+
+	_, err = repo.Git("checkout", "-b", "zero")
+	require.NoError(t, err)
+	zeroHead := gittest.CommitFile(t, repo, "zero", []byte("zero\n"))
+	_, err = repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	one := gittest.CommitFile(t, repo, "one", []byte("one\n"))
+	require.NoError(t, actions.Push(repo, "one", pushOpts))
+	_, err = repo.Git("checkout", "-b", "two")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "two", []byte("two\n"))
+	require.NoError(t, actions.Push(repo, "two", pushOpts))
+	// Simulate a fresh clone where only the remote-tracking branch exists.
+	_, err = repo.Git("checkout", "main")
+	require.NoError(t, err)
+	_, err = repo.Git("branch", "-D", "zero", "two")
+	require.NoError(t, err)
+
+	body := func(prMeta actions.PRMetadata) string {
+		return actions.AddPRMetadataAndStack("Description.", prMeta, "", nil, "")
+	}
+	prs := []gh.PullRequest{
+		{
+			ID:          "pr-zero",
+			Number:      1,
+			HeadRefName: "zero",
+			HeadRefOID:  zeroHead,
+			State:       githubv4.PullRequestStateMerged,
+			Body:        body(actions.PRMetadata{Parent: "main", Trunk: "main"}),
+		},
+		{
+			ID:          "pr-one",
+			Number:      2,
+			HeadRefName: "one",
+			State:       githubv4.PullRequestStateOpen,
+			Body:        body(actions.PRMetadata{Parent: "zero", ParentHead: zeroHead, Trunk: "main"}),
+		},
+		{
+			ID:          "pr-two",
+			Number:      3,
+			HeadRefName: "refs/heads/two",
+			State:       githubv4.PullRequestStateOpen,
+			Body:        body(actions.PRMetadata{Parent: "one", ParentHead: one, Trunk: "main"}),
+		},
+		{
+			ID:          "pr-other",
+			Number:      4,
+			HeadRefName: "other",
+			State:       githubv4.PullRequestStateOpen,
+			Body:        "Not created by av.",
+		},
+	}
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+	imported, err := actions.ImportPullRequestBranches(repo, tx, prs)
+	require.NoError(t, err)
+	require.Equal(t, []string{"zero", "one", "two"}, imported)
+
+	zero, _ := tx.Branch("zero")
+	require.Equal(t, meta.BranchState{Name: "main", Trunk: true}, zero.Parent)
+	require.Equal(t, githubv4.PullRequestStateMerged, zero.PullRequest.State)
+	two, _ := tx.Branch("two")
+	require.Equal(t, meta.BranchState{Name: "one", Head: one}, two.Parent)
+	require.Equal(t, int64(3), two.PullRequest.Number)
+	_, ok := tx.Branch("other")
+	require.False(t, ok)
+
+	// The local branches are re-created from origin or, for the merged pull
+	// request whose remote branch is gone, from its last head.
+	for _, name := range []string{"zero", "two"} {
+		exists, err := repo.DoesBranchExist(name)
+		require.NoError(t, err)
+		require.True(t, exists, "branch %q should exist", name)
+	}
+	zeroTip, err := repo.RevParse(&git.RevParse{Rev: "zero"})
+	require.NoError(t, err)
+	require.Equal(t, zeroHead, zeroTip)
+
+	// Tracked branches are left alone.
+	imported, err = actions.ImportPullRequestBranches(repo, tx, prs)
+	require.NoError(t, err)
+	require.Empty(t, imported)
+}
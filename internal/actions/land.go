@@ -0,0 +1,160 @@
+package actions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/shurcooL/githubv4"
+)
+
+// LandReadiness describes whether a pull request can be merged.
+type LandReadiness struct {
+	// True if the pull request can be merged now.
+	Ready bool
+	// Why the pull request can't be merged (if not Ready).
+	Reason string
+	// True if the pull request won't become mergeable without somebody
+	// intervening (e.g., because a check failed or changes were requested).
+	// Otherwise, it might become mergeable by waiting (e.g., for pending checks
+	// or reviews).
+	Blocked bool
+}
+
+// CheckLandReadiness determines whether the pull request of the given branch
+// can be merged: it must be approved (or not require a review), all of its
+// checks must have passed, it must not conflict with its base branch, and its
+// parent must have been merged already (i.e., it must target the trunk).
+func CheckLandReadiness(branch meta.Branch, pr *gh.PullRequest, checks *gh.PullRequestChecks) LandReadiness {
+	switch {
+	case pr.State != githubv4.PullRequestStateOpen:
+		return LandReadiness{Reason: "the pull request is " + string(pr.State), Blocked: true}
+	case pr.IsDraft:
+		return LandReadiness{Reason: "the pull request is a draft", Blocked: true}
+	case !branch.Parent.Trunk:
+		return LandReadiness{Reason: "the parent branch " + branch.Parent.Name + " hasn't been merged yet"}
+	case pr.BaseBranchName() != branch.Parent.Name:
+		return LandReadiness{Reason: "the pull request doesn't target " + branch.Parent.Name + " yet"}
+	}
+	return CheckPullRequestReadiness(pr, checks)
+}
+
+// CheckPullRequestReadiness determines whether the pull request itself is ready
+// to be merged (see CheckLandReadiness), ignoring whether its parent has been
+// merged. This is what determines whether the pull request will be mergeable
+// once it's at the bottom of the stack.
+func CheckPullRequestReadiness(pr *gh.PullRequest, checks *gh.PullRequestChecks) LandReadiness {
+	switch {
+	case pr.State != githubv4.PullRequestStateOpen:
+		return LandReadiness{Reason: "the pull request is " + string(pr.State), Blocked: true}
+	case pr.IsDraft:
+		return LandReadiness{Reason: "the pull request is a draft", Blocked: true}
+	case pr.HeadRefOID != checks.HeadOID:
+		return LandReadiness{Reason: "waiting for GitHub to pick up the latest commit"}
+	case pr.ReviewDecision == githubv4.PullRequestReviewDecisionChangesRequested:
+		return LandReadiness{Reason: "changes were requested", Blocked: true}
+	case pr.Mergeable == githubv4.MergeableStateConflicting:
+		return LandReadiness{Reason: "the pull request has conflicts with " + pr.BaseBranchName(), Blocked: true}
+	}
+	switch checks.State() {
+	case gh.CheckStateFailure:
+		return LandReadiness{Reason: "some checks failed", Blocked: true}
+	case gh.CheckStatePending:
+		return LandReadiness{Reason: "waiting for checks to complete"}
+	}
+	switch {
+	case pr.ReviewDecision == githubv4.PullRequestReviewDecisionReviewRequired:
+		return LandReadiness{Reason: "waiting for an approving review"}
+	case pr.Mergeable != githubv4.MergeableStateMergeable:
+		return LandReadiness{Reason: "waiting for GitHub to determine whether the pull request can be merged"}
+	}
+	return LandReadiness{Ready: true}
+}
+
+// UnmetRequirement is a requirement of the branch protection rules of the base
+// branch of a pull request that the pull request doesn't meet.
+type UnmetRequirement struct {
+	Description string
+	// True if the requirement won't be met without somebody intervening (see
+	// LandReadiness.Blocked).
+	Blocked bool
+}
+
+// CheckBranchProtection returns the requirements of the branch protection rules
+// of the base branch that the pull request doesn't meet (yet). Which
+// requirements apply depends on the merge method (e.g., a merge commit
+// violates a linear history).
+func CheckBranchProtection(
+	pr *gh.PullRequest,
+	checks *gh.PullRequestChecks,
+	state *gh.PullRequestProtection,
+	method githubv4.PullRequestMergeMethod,
+) []UnmetRequirement {
+	rules := state.Protection
+	if rules == nil {
+		return nil
+	}
+	base := pr.BaseBranchName()
+	var unmet []UnmetRequirement
+	add := func(blocked bool, format string, args ...any) {
+		unmet = append(unmet, UnmetRequirement{Description: fmt.Sprintf(format, args...), Blocked: blocked})
+	}
+
+	for _, name := range rules.RequiredStatusChecks {
+		var checkState gh.CheckState
+		for _, check := range checks.Checks {
+			if check.Name == name {
+				checkState = check.State
+				// A check can be reported more than once (e.g., by different
+				// apps), and any failure fails the requirement.
+				if check.State == gh.CheckStateFailure {
+					break
+				}
+			}
+		}
+		switch checkState {
+		case "":
+			add(false, "required check %q hasn't been reported yet", name)
+		case gh.CheckStatePending:
+			add(false, "required check %q hasn't completed yet", name)
+		case gh.CheckStateFailure:
+			add(true, "required check %q failed", name)
+		}
+	}
+
+	if pr.ReviewDecision == githubv4.PullRequestReviewDecisionChangesRequested {
+		add(true, "changes were requested by a reviewer")
+	} else if state.Approvals < rules.RequiredApprovingReviewCount {
+		add(false, "%d approving review(s) required, %d given", rules.RequiredApprovingReviewCount, state.Approvals)
+	} else if rules.RequiresCodeOwnerReviews && pr.ReviewDecision == githubv4.PullRequestReviewDecisionReviewRequired {
+		add(false, "an approving review from a code owner is required")
+	}
+	if rules.RequiresConversationResolution && state.UnresolvedThreads > 0 {
+		add(true, "%d review conversation(s) must be resolved", state.UnresolvedThreads)
+	}
+	if state.MergeStateStatus == "BEHIND" {
+		add(true, "the branch must be up-to-date with %s", base)
+	}
+	if rules.RequiresLinearHistory && method == githubv4.PullRequestMergeMethodMerge {
+		add(true, "%s requires a linear history, so the pull request can't be merged with a merge commit", base)
+	}
+	if rules.RequiresSignatures {
+		switch method {
+		case githubv4.PullRequestMergeMethodRebase:
+			// GitHub can't sign the commits it creates when rebasing.
+			add(true, "%s requires signed commits, so the pull request can't be rebased", base)
+		case githubv4.PullRequestMergeMethodMerge:
+			if len(state.UnsignedCommits) > 0 {
+				var shas []string
+				for _, oid := range state.UnsignedCommits {
+					shas = append(shas, git.ShortSha(oid))
+				}
+				add(true, "%s requires signed commits, but these commits aren't signed: %s",
+					base, strings.Join(shas, ", "))
+			}
+		}
+	}
+	return unmet
+}
@@ -0,0 +1,129 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/shurcooL/githubv4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckLandReadiness(t *testing.T) {
+	branch := meta.Branch{Name: "one", Parent: meta.BranchState{Name: "main", Trunk: true}}
+	readyPR := func() *gh.PullRequest {
+		return &gh.PullRequest{
+			State:          githubv4.PullRequestStateOpen,
+			BaseRefName:    "main",
+			HeadRefOID:     "abc",
+			ReviewDecision: githubv4.PullRequestReviewDecisionApproved,
+			Mergeable:      githubv4.MergeableStateMergeable,
+		}
+	}
+	checks := func(states ...gh.CheckState) *gh.PullRequestChecks {
+		res := &gh.PullRequestChecks{HeadOID: "abc"}
+		for _, state := range states {
+			res.Checks = append(res.Checks, gh.Check{Name: "check", State: state})
+		}
+		return res
+	}
+
+	require.Equal(t,
+		actions.LandReadiness{Ready: true},
+		actions.CheckLandReadiness(branch, readyPR(), checks(gh.CheckStateSuccess)),
+	)
+
+	// No review is required.
+	pr := readyPR()
+	pr.ReviewDecision = ""
+	require.True(t, actions.CheckLandReadiness(branch, pr, checks()).Ready)
+
+	// Waiting might help.
+	res := actions.CheckLandReadiness(branch, readyPR(), checks(gh.CheckStateSuccess, gh.CheckStatePending))
+	require.False(t, res.Ready)
+	require.False(t, res.Blocked)
+	pr = readyPR()
+	pr.ReviewDecision = githubv4.PullRequestReviewDecisionReviewRequired
+	res = actions.CheckLandReadiness(branch, pr, checks())
+	require.False(t, res.Ready)
+	require.False(t, res.Blocked)
+	res = actions.CheckLandReadiness(
+		meta.Branch{Name: "two", Parent: meta.BranchState{Name: "one"}}, readyPR(), checks(),
+	)
+	require.False(t, res.Ready)
+	require.False(t, res.Blocked)
+	// The pull request itself is ready though.
+	require.True(t, actions.CheckPullRequestReadiness(readyPR(), checks()).Ready)
+
+	// Waiting won't help.
+	res = actions.CheckLandReadiness(branch, readyPR(), checks(gh.CheckStateSuccess, gh.CheckStateFailure))
+	require.False(t, res.Ready)
+	require.True(t, res.Blocked)
+	pr = readyPR()
+	pr.Mergeable = githubv4.MergeableStateConflicting
+	res = actions.CheckLandReadiness(branch, pr, checks())
+	require.False(t, res.Ready)
+	require.True(t, res.Blocked)
+}
+
+func TestCheckBranchProtection(t *testing.T) {
+	pr := &gh.PullRequest{
+		BaseRefName:    "main",
+		ReviewDecision: githubv4.PullRequestReviewDecisionReviewRequired,
+	}
+	checks := &gh.PullRequestChecks{Checks: []gh.Check{
+		{Name: "build", State: gh.CheckStateSuccess},
+		{Name: "test", State: gh.CheckStateFailure},
+		{Name: "lint", State: gh.CheckStatePending},
+	}}
+	state := &gh.PullRequestProtection{
+		Protection: &gh.BranchProtection{
+			RequiredApprovingReviewCount:   1,
+			RequiresCodeOwnerReviews:       true,
+			RequiredStatusChecks:           []string{"build", "test", "lint", "deploy"},
+			RequiresLinearHistory:          true,
+			RequiresSignatures:             true,
+			RequiresConversationResolution: true,
+		},
+		MergeStateStatus:  "BEHIND",
+		Approvals:         1,
+		UnsignedCommits:   []string{"0123456789abcdef"},
+		UnresolvedThreads: 2,
+	}
+
+	require.Equal(t, []actions.UnmetRequirement{
+		{Description: `required check "test" failed`, Blocked: true},
+		{Description: `required check "lint" hasn't completed yet`},
+		{Description: `required check "deploy" hasn't been reported yet`},
+		{Description: "an approving review from a code owner is required"},
+		{Description: "2 review conversation(s) must be resolved", Blocked: true},
+		{Description: "the branch must be up-to-date with main", Blocked: true},
+		{
+			Description: "main requires a linear history, so the pull request can't be merged with a merge commit",
+			Blocked:     true,
+		},
+		{
+			Description: "main requires signed commits, but these commits aren't signed: 0123456",
+			Blocked:     true,
+		},
+	}, actions.CheckBranchProtection(pr, checks, state, githubv4.PullRequestMergeMethodMerge))
+
+	// GitHub signs squash commits itself.
+	state = &gh.PullRequestProtection{
+		Protection: &gh.BranchProtection{
+			RequiredApprovingReviewCount: 2,
+			RequiresSignatures:           true,
+		},
+		Approvals:       1,
+		UnsignedCommits: []string{"0123456789abcdef"},
+	}
+	require.Equal(t, []actions.UnmetRequirement{
+		{Description: "2 approving review(s) required, 1 given"},
+	}, actions.CheckBranchProtection(pr, checks, state, githubv4.PullRequestMergeMethodSquash))
+
+	// Unprotected branches have no requirements.
+	require.Empty(t, actions.CheckBranchProtection(
+		pr, checks, &gh.PullRequestProtection{}, githubv4.PullRequestMergeMethodRebase,
+	))
+}
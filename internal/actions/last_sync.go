@@ -0,0 +1,121 @@
+package actions
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+)
+
+// RecordLastSync records that the given branch was just synced successfully
+// (along with the commit of the trunk that it's now based on).
+func RecordLastSync(repo *git.Repo, tx meta.WriteTx, branchName string, now time.Time) {
+	branch, ok := tx.Branch(branchName)
+	if !ok {
+		return
+	}
+	info := &meta.SyncInfo{Time: now}
+	if trunk, ok := meta.Trunk(tx, branchName); ok {
+		// The remote trunk might not exist (e.g., before the first push), in
+		// which case only the time is recorded.
+		info.TrunkCommit, _ = repo.MergeBase(&git.MergeBase{
+			Revs: []string{"refs/heads/" + branchName, "refs/remotes/origin/" + trunk},
+		})
+	}
+	branch.LastSync = info
+	tx.SetBranch(branch)
+}
+
+// LastSyncStatus describes when a branch was last synced and how far its
+// trunk has moved since then.
+type LastSyncStatus struct {
+	// False if the branch was never synced (or was synced by a version of av
+	// that didn't record it).
+	Synced bool
+	// How long ago the branch was synced.
+	Since time.Duration
+	// The trunk of the branch.
+	Trunk string
+	// The number of commits that were added to the remote trunk since the
+	// commit that the branch was based on when it was synced (or -1 if it's
+	// not known).
+	Behind int
+}
+
+// BranchLastSync determines the LastSyncStatus of the given branch.
+func BranchLastSync(repo *git.Repo, tx meta.ReadTx, branchName string, now time.Time) (LastSyncStatus, error) {
+	status := LastSyncStatus{Behind: -1}
+	status.Trunk, _ = meta.Trunk(tx, branchName)
+	branch, ok := tx.Branch(branchName)
+	if !ok || branch.LastSync == nil {
+		return status, nil
+	}
+	status.Synced = true
+	status.Since = now.Sub(branch.LastSync.Time)
+	if branch.LastSync.TrunkCommit == "" || status.Trunk == "" {
+		return status, nil
+	}
+	out, err := repo.Run(&git.RunOpts{
+		Args: []string{
+			"rev-list", "--count",
+			branch.LastSync.TrunkCommit + "..refs/remotes/origin/" + status.Trunk,
+		},
+	})
+	if err != nil {
+		return status, err
+	}
+	if out.ExitCode != 0 {
+		// The recorded commit might have been garbage collected or the remote
+		// trunk might be gone.
+		return status, nil
+	}
+	lines := out.Lines()
+	if len(lines) == 0 {
+		return status, nil
+	}
+	status.Behind, err = strconv.Atoi(lines[0])
+	if err != nil {
+		return status, errors.WrapIff(err, "failed to count the new commits of %q", status.Trunk)
+	}
+	return status, nil
+}
+
+// String returns a short description of the status (e.g., "3 days ago, 41
+// commits behind main").
+func (s LastSyncStatus) String() string {
+	if !s.Synced {
+		return "never"
+	}
+	str := formatAgo(s.Since)
+	switch {
+	case s.Behind == 0:
+		str += ", up-to-date with " + s.Trunk
+	case s.Behind == 1:
+		str += ", 1 commit behind " + s.Trunk
+	case s.Behind > 1:
+		str += fmt.Sprintf(", %d commits behind %s", s.Behind, s.Trunk)
+	}
+	return str
+}
+
+func formatAgo(d time.Duration) string {
+	var n int
+	var unit string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		n, unit = int(d.Minutes()), "minute"
+	case d < 24*time.Hour:
+		n, unit = int(d.Hours()), "hour"
+	default:
+		n, unit = int(d.Hours()/24), "day"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s ago", n, unit)
+}
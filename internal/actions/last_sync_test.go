@@ -0,0 +1,63 @@
+package actions_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBranchLastSync(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+	mainHead, err := repo.RevParse(&git.RevParse{Rev: "main"})
+	require.NoError(t, err)
+
+	_, err = repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "one", []byte("one\n"))
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{Name: "one", Parent: meta.BranchState{Name: "main", Trunk: true}})
+
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	status, err := actions.BranchLastSync(repo, tx, "one", now)
+	require.NoError(t, err)
+	require.False(t, status.Synced)
+	require.Equal(t, "never", status.String())
+
+	actions.RecordLastSync(repo, tx, "one", now.Add(-3*24*time.Hour))
+	branch, _ := tx.Branch("one")
+	require.Equal(t, mainHead, branch.LastSync.TrunkCommit)
+	status, err = actions.BranchLastSync(repo, tx, "one", now)
+	require.NoError(t, err)
+	require.Equal(t, "3 days ago, up-to-date with main", status.String())
+
+	// The trunk moves on.
+	gittest.CheckoutBranch(t, repo, "main")
+	gittest.CommitFile(t, repo, "two", []byte("two\n"))
+	gittest.CommitFile(t, repo, "three", []byte("three\n"))
+	_, err = repo.Git("push", "origin", "main")
+	require.NoError(t, err)
+	status, err = actions.BranchLastSync(repo, tx, "one", now)
+	require.NoError(t, err)
+	require.Equal(t, actions.LastSyncStatus{
+		Synced: true,
+		Since:  3 * 24 * time.Hour,
+		Trunk:  "main",
+		Behind: 2,
+	}, status)
+	require.Equal(t, "3 days ago, 2 commits behind main", status.String())
+
+	actions.RecordLastSync(repo, tx, "one", now.Add(-time.Hour))
+	status, err = actions.BranchLastSync(repo, tx, "one", now)
+	require.NoError(t, err)
+	require.Equal(t, "1 hour ago, 2 commits behind main", status.String())
+}
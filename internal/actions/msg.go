@@ -38,3 +38,25 @@ func msgRebaseResult(rebase *git.RebaseResult) {
 		// these should be handled externally
 	}
 }
+
+func msgMergeResult(merge *git.MergeResult) {
+	switch merge.Status {
+	case git.MergeAlreadyUpToDate:
+		_, _ = fmt.Fprint(os.Stderr, "  - already up to date\n")
+	case git.MergeUpdated:
+		_, _ = fmt.Fprint(os.Stderr, "  - ", colors.Success("merged without conflicts"), "\n")
+	case git.MergeConflict:
+		_, _ = fmt.Fprint(os.Stderr, "  - ", colors.Failure("merge conflict in:"), "\n")
+		for _, file := range merge.Conflicts {
+			_, _ = fmt.Fprint(os.Stderr, "        ", file, "\n")
+		}
+		_, _ = fmt.Fprint(
+			os.Stderr,
+			"  - resolve the conflicts, stage them with ", colors.CliCmd("git add"),
+			", and continue the sync with ", colors.CliCmd("av stack sync --continue"),
+			"\n",
+		)
+	case git.MergeAborted, git.MergeNotInProgress:
+		// these should be handled externally
+	}
+}
@@ -0,0 +1,164 @@
+package actions
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/sirupsen/logrus"
+)
+
+// The maximum number of commits of a branch that are inspected when looking
+// for a rewritten version of the recorded parent HEAD.
+const reconcilePatchIDSearchDepth = 100
+
+// ParentHeadReconciliation is the result of ReconcileParentHead.
+type ParentHeadReconciliation struct {
+	// The commit that should be used as the parent HEAD of the branch.
+	Head string
+	// True if Head differs from the recorded parent HEAD.
+	Updated bool
+	// True if the recorded parent HEAD is not part of the branch's history and
+	// no replacement could be found.
+	Stale bool
+}
+
+// ReconcileParentHead checks whether the recorded parent HEAD of the branch is
+// still part of the branch's history and, if it isn't, attempts to determine
+// the commit that the branch is actually based on.
+//
+// The recorded parent HEAD goes stale when the parent branch is rewritten
+// outside of av (e.g., with `git commit --amend` or `git rebase`) and the
+// branch itself is rebased manually afterwards. Using the stale commit as the
+// upstream of a rebase would replay the wrong range of commits (including the
+// commits of the parent branch itself).
+func ReconcileParentHead(repo *git.Repo, branch meta.Branch) (ParentHeadReconciliation, error) {
+	res := ParentHeadReconciliation{Head: branch.Parent.Head}
+	if branch.Parent.Trunk || branch.Parent.Head == "" {
+		return res, nil
+	}
+	if ok, err := repo.IsAncestor(branch.Parent.Head, branch.Name); err != nil || ok {
+		return res, err
+	}
+
+	// Every commit that the parent branch has pointed to locally is recorded in
+	// its reflog. The most recent one that the branch is based on is the commit
+	// that the branch was (manually) rebased onto.
+	candidate, err := reconcileFromReflog(repo, branch)
+	if err != nil {
+		return res, err
+	}
+	if candidate == "" {
+		// If the reflog isn't available (e.g., it has expired or the parent
+		// branch was recreated), look for a commit in the branch's history that
+		// introduces the same change as the recorded parent HEAD.
+		candidate, err = reconcileFromPatchID(repo, branch)
+		if err != nil {
+			logrus.WithError(err).WithField("branch", branch.Name).
+				Debug("failed to compare patch-ids of the parent HEAD")
+		}
+	}
+	if candidate == "" {
+		res.Stale = true
+		return res, nil
+	}
+	res.Head = candidate
+	res.Updated = true
+	return res, nil
+}
+
+func reconcileFromReflog(repo *git.Repo, branch meta.Branch) (string, error) {
+	if exists, err := repo.DoesBranchExist(branch.Parent.Name); err != nil || !exists {
+		return "", err
+	}
+	out, err := repo.Run(&git.RunOpts{
+		Args: []string{"reflog", "show", "--format=%H", "refs/heads/" + branch.Parent.Name, "--"},
+	})
+	if err != nil {
+		return "", err
+	}
+	if out.ExitCode != 0 {
+		// No reflog for this branch.
+		return "", nil
+	}
+	seen := make(map[string]bool)
+	for _, commit := range out.Lines() {
+		if seen[commit] || commit == branch.Parent.Head {
+			continue
+		}
+		seen[commit] = true
+		if ok, err := repo.IsAncestor(commit, branch.Name); err != nil {
+			return "", err
+		} else if ok {
+			return commit, nil
+		}
+	}
+	return "", nil
+}
+
+func reconcileFromPatchID(repo *git.Repo, branch meta.Branch) (string, error) {
+	show, err := repo.Run(&git.RunOpts{
+		Args:      []string{"show", "--no-color", "--format=commit %H", branch.Parent.Head, "--"},
+		ExitError: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	want, err := patchIDs(repo, show.Stdout)
+	if err != nil || len(want) == 0 {
+		// Commits without changes (e.g., empty commits) don't have a patch-id.
+		return "", err
+	}
+	wantID := want[0].ID
+
+	log, err := repo.Run(&git.RunOpts{
+		Args: []string{
+			"log", "--no-color", "--no-merges", "-p", "--format=commit %H",
+			"--max-count=" + strconv.Itoa(reconcilePatchIDSearchDepth), branch.Name, "--",
+		},
+		ExitError: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	ids, err := patchIDs(repo, log.Stdout)
+	if err != nil {
+		return "", err
+	}
+	// The log is ordered from the most recent commit, so this picks the most
+	// recent commit that introduces the same change.
+	for _, id := range ids {
+		if id.ID == wantID {
+			return id.Commit, nil
+		}
+	}
+	return "", nil
+}
+
+type patchID struct {
+	ID     string
+	Commit string
+}
+
+// patchIDs computes the patch-ids of the commits in the given `git log -p`
+// output (in the same order as the commits appear in the output).
+func patchIDs(repo *git.Repo, diff []byte) ([]patchID, error) {
+	out, err := repo.Run(&git.RunOpts{
+		Args:      []string{"patch-id", "--stable"},
+		Stdin:     bytes.NewReader(diff),
+		ExitError: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var res []patchID
+	for _, line := range out.Lines() {
+		id, commit, ok := strings.Cut(line, " ")
+		if ok {
+			res = append(res, patchID{ID: id, Commit: commit})
+		}
+	}
+	return res, nil
+}
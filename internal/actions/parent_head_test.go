@@ -0,0 +1,86 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcileParentHead(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+
+	_, err := repo.Git("checkout", "-b", "parent")
+	require.NoError(t, err)
+	p1 := gittest.CommitFile(t, repo, "parent", []byte("one\n"))
+	_, err = repo.Git("checkout", "-b", "child")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "child", []byte("child\n"))
+	child := meta.Branch{
+		Name:   "child",
+		Parent: meta.BranchState{Name: "parent", Head: p1},
+	}
+
+	res, err := actions.ReconcileParentHead(repo, child)
+	require.NoError(t, err)
+	require.Equal(t, actions.ParentHeadReconciliation{Head: p1}, res)
+
+	// Amend the parent outside of av and manually rebase the child on top of
+	// it. Then amend the parent again.
+	_, err = repo.Git("checkout", "parent")
+	require.NoError(t, err)
+	p2 := gittest.CommitFile(t, repo, "parent", []byte("two\n"), gittest.WithAmend())
+	_, err = repo.Git("rebase", "--onto", p2, p1, "child")
+	require.NoError(t, err)
+	_, err = repo.Git("checkout", "parent")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "parent", []byte("three\n"), gittest.WithAmend())
+
+	res, err = actions.ReconcileParentHead(repo, child)
+	require.NoError(t, err)
+	require.Equal(t, actions.ParentHeadReconciliation{Head: p2, Updated: true}, res)
+}
+
+func TestReconcileParentHeadPatchID(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+
+	_, err := repo.Git("checkout", "-b", "parent")
+	require.NoError(t, err)
+	p1 := gittest.CommitFile(t, repo, "parent", []byte("one\n"))
+	_, err = repo.Git("checkout", "-b", "child")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "child", []byte("child\n"))
+	child := meta.Branch{
+		Name:   "child",
+		Parent: meta.BranchState{Name: "parent", Head: p1},
+	}
+
+	// Rebase the parent on top of a new trunk commit (which changes its hash
+	// but not its patch) and manually rebase the child on top of it. Then
+	// delete the parent branch (and its reflog along with it).
+	_, err = repo.Git("checkout", "main")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "trunk", []byte("trunk\n"))
+	_, err = repo.Git("rebase", "main", "parent")
+	require.NoError(t, err)
+	p1Rebased, err := repo.Git("rev-parse", "parent")
+	require.NoError(t, err)
+	_, err = repo.Git("rebase", "--onto", "parent", p1, "child")
+	require.NoError(t, err)
+	_, err = repo.Git("branch", "-D", "parent")
+	require.NoError(t, err)
+
+	res, err := actions.ReconcileParentHead(repo, child)
+	require.NoError(t, err)
+	require.Equal(t, actions.ParentHeadReconciliation{Head: p1Rebased, Updated: true}, res)
+
+	// If the branch doesn't contain any version of the parent commit, the
+	// recorded commit is stale.
+	_, err = repo.Git("rebase", "--onto", "main", p1Rebased, "child")
+	require.NoError(t, err)
+	res, err = actions.ReconcileParentHead(repo, child)
+	require.NoError(t, err)
+	require.Equal(t, actions.ParentHeadReconciliation{Head: p1, Stale: true}, res)
+}
@@ -0,0 +1,336 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrOffline is returned when a branch can't be pushed because the remote
+// can't be reached.
+var ErrOffline = errors.Sentinel("the remote can't be reached")
+
+// offlinePushOutputs are substrings of the git push output that mean that the
+// remote couldn't be reached (for both HTTPS and SSH remotes).
+var offlinePushOutputs = []string{
+	"Could not resolve host",
+	"Could not resolve hostname",
+	"Network is unreachable",
+	"Connection timed out",
+	"Operation timed out",
+	"Failed to connect to",
+	"Temporary failure in name resolution",
+}
+
+func isOfflinePushOutput(output string) bool {
+	for _, pattern := range offlinePushOutputs {
+		if strings.Contains(output, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOffline returns true if the given error means that GitHub (either the Git
+// remote or the API) couldn't be reached, in which case the operation can be
+// retried later with PushPendingUpdates.
+func IsOffline(err error) bool {
+	return errors.Is(err, ErrOffline) || gh.IsNetworkError(err)
+}
+
+const pendingUpdatesFile = "pending-updates.json"
+
+// PendingUpdate is a branch whose push (and the corresponding pull request
+// update) failed because GitHub couldn't be reached (see queueOfflinePush), or
+// whose pull request couldn't be updated for the same reason.
+type PendingUpdate struct {
+	// The name of the branch.
+	Branch string `json:"branch"`
+	// How the branch was synced (which determines whether it's force-pushed).
+	Strategy SyncStrategy `json:"strategy,omitempty"`
+	// When the update was queued.
+	Time time.Time `json:"time"`
+	// If true, the pull request was converted to a draft for the push and has
+	// to be marked as ready for review again once it's updated.
+	Ready bool `json:"ready,omitempty"`
+	// If true, the branch doesn't have to be pushed: only its pull request has
+	// to be updated.
+	NoPush bool `json:"noPush,omitempty"`
+	// If true, the base branch of the pull request has to be updated (see
+	// RetargetPullRequests).
+	Retarget bool `json:"retarget,omitempty"`
+	// If set, the part of the description of the pull request that was written
+	// by people has to be replaced with it (see ReplacePRBodyContent).
+	Body *string `json:"body,omitempty"`
+}
+
+// ReadPendingUpdates returns the queued pull request updates (in the order in
+// which they were queued).
+func ReadPendingUpdates(repo *git.Repo) ([]PendingUpdate, error) {
+	data, err := os.ReadFile(path.Join(repo.AvDir(), pendingUpdatesFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var updates []PendingUpdate
+	if err := json.Unmarshal(data, &updates); err != nil {
+		return nil, errors.WrapIf(err, "failed to read the pending pull request updates")
+	}
+	return updates, nil
+}
+
+func writePendingUpdates(repo *git.Repo, updates []PendingUpdate) error {
+	file := path.Join(repo.AvDir(), pendingUpdatesFile)
+	if len(updates) == 0 {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(updates)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(repo.AvDir(), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+// RemovePendingUpdate removes the given branch from the queue (e.g., because
+// it was pushed in the meantime).
+func RemovePendingUpdate(repo *git.Repo, branchName string) error {
+	updates, err := ReadPendingUpdates(repo)
+	if err != nil || len(updates) == 0 {
+		return err
+	}
+	n := len(updates)
+	updates = slices.DeleteFunc(updates, func(u PendingUpdate) bool {
+		return u.Branch == branchName
+	})
+	if len(updates) == n {
+		return nil
+	}
+	return writePendingUpdates(repo, updates)
+}
+
+// updatePendingUpdate applies the change to the queued update of the branch,
+// or to a new update that doesn't push the branch if there is none yet.
+func updatePendingUpdate(repo *git.Repo, branchName string, change func(*PendingUpdate)) error {
+	updates, err := ReadPendingUpdates(repo)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(updates, func(u PendingUpdate) bool {
+		return u.Branch == branchName
+	})
+	if i == -1 {
+		updates = append(updates, PendingUpdate{Branch: branchName, Time: time.Now(), NoPush: true})
+		i = len(updates) - 1
+	}
+	change(&updates[i])
+	return writePendingUpdates(repo, updates)
+}
+
+// queueReadyForReview records that the pull request of the branch has to be
+// marked as ready for review again once GitHub can be reached (see
+// PendingUpdate.Ready).
+func queueReadyForReview(repo *git.Repo, branchName string) error {
+	return updatePendingUpdate(repo, branchName, func(u *PendingUpdate) {
+		u.Ready = true
+	})
+}
+
+// QueueRetarget records that the base branches of the pull requests of the
+// given branches have to be updated once GitHub can be reached (see
+// RetargetPullRequests).
+func QueueRetarget(repo *git.Repo, branchNames ...string) error {
+	for _, name := range branchNames {
+		if err := updatePendingUpdate(repo, name, func(u *PendingUpdate) {
+			u.Retarget = true
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueuePullRequestBody records that the part of the description of the pull
+// request of the branch that was written by people has to be replaced with the
+// given body once GitHub can be reached.
+func QueuePullRequestBody(repo *git.Repo, branchName string, body string) error {
+	return updatePendingUpdate(repo, branchName, func(u *PendingUpdate) {
+		u.Body = &body
+	})
+}
+
+// queueOfflinePush queues the branch after its push failed because GitHub
+// couldn't be reached, so that the sync can finish locally.
+func queueOfflinePush(repo *git.Repo, branchName string, strategy SyncStrategy, pushErr error) error {
+	logrus.WithError(pushErr).Debug("GitHub can't be reached, queueing the pull request update")
+	if err := updatePendingUpdate(repo, branchName, func(u *PendingUpdate) {
+		u.Strategy = strategy
+		u.Time = time.Now()
+		u.NoPush = false
+	}); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprint(os.Stderr,
+		"  - ", colors.Warning("GitHub can't be reached:"),
+		" queued the push of ", colors.UserInput(branchName),
+		" (run ", colors.CliCmd("av push --pending"), " once you're online)\n",
+	)
+	return nil
+}
+
+// PushPendingUpdates pushes the queued branches and updates their pull
+// requests (see PendingUpdate). Branches that are no longer tracked or that
+// don't have a pull request anymore are dropped from the queue. If a branch
+// fails to update (e.g., because GitHub still can't be reached), it's kept in
+// the queue along with the remaining branches. Returns the branches that were
+// pushed.
+func PushPendingUpdates(
+	ctx context.Context,
+	repo *git.Repo,
+	client *gh.Client,
+	tx meta.WriteTx,
+) ([]string, error) {
+	updates, err := ReadPendingUpdates(repo)
+	if err != nil {
+		return nil, err
+	}
+	var pushed []string
+	for i, update := range updates {
+		branch, ok := tx.Branch(update.Branch)
+		if !ok || branch.PullRequest == nil || branch.PullRequest.ID == "" {
+			_, _ = fmt.Fprint(os.Stderr,
+				"  - ", colors.UserInput(update.Branch),
+				": dropped (the branch isn't tracked or doesn't have a pull request anymore)\n",
+			)
+			continue
+		}
+		_, _ = fmt.Fprint(os.Stderr, "Updating branch ", colors.UserInput(update.Branch), "...\n")
+		if err := applyPendingUpdate(ctx, repo, client, tx, update, branch); err != nil {
+			if werr := writePendingUpdates(repo, updates[i:]); werr != nil {
+				return pushed, werr
+			}
+			return pushed, err
+		}
+		pushed = append(pushed, update.Branch)
+	}
+	return pushed, writePendingUpdates(repo, nil)
+}
+
+// PushBranch pushes the given branch and updates its pull request (as
+// `av stack sync` does after syncing it), applies the pending update of the
+// branch, if any, and removes it from the queue of pending updates.
+func PushBranch(ctx context.Context, repo *git.Repo, client *gh.Client, tx meta.WriteTx, branchName string) error {
+	updates, err := ReadPendingUpdates(repo)
+	if err != nil {
+		return err
+	}
+	update := PendingUpdate{Branch: branchName}
+	if i := slices.IndexFunc(updates, func(u PendingUpdate) bool { return u.Branch == branchName }); i != -1 {
+		update = updates[i]
+		update.NoPush = false
+	}
+	branch, _ := tx.Branch(branchName)
+	if err := applyPendingUpdate(ctx, repo, client, tx, update, branch); err != nil {
+		return err
+	}
+	return RemovePendingUpdate(repo, branchName)
+}
+
+// finishPendingUpdate applies the rest of the queued update of the branch (if
+// any) after the branch was pushed and its pull request updated, so that the
+// pull request updates that were queued while offline (e.g., a new
+// description) aren't lost. If that fails, the update is kept in the queue
+// without the push.
+func finishPendingUpdate(ctx context.Context, repo *git.Repo, client *gh.Client, tx meta.WriteTx, branchName string) error {
+	updates, err := ReadPendingUpdates(repo)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(updates, func(u PendingUpdate) bool { return u.Branch == branchName })
+	if i == -1 {
+		return nil
+	}
+	update := updates[i]
+	update.NoPush = true
+	branch, _ := tx.Branch(branchName)
+	if err := applyPendingUpdate(ctx, repo, client, tx, update, branch); err != nil {
+		if qerr := updatePendingUpdate(repo, branchName, func(u *PendingUpdate) {
+			u.NoPush = true
+		}); qerr != nil {
+			return qerr
+		}
+		if IsOffline(err) {
+			logrus.WithError(err).Debug("GitHub can't be reached, keeping the pending pull request update")
+			return nil
+		}
+		return err
+	}
+	return RemovePendingUpdate(repo, branchName)
+}
+
+func applyPendingUpdate(
+	ctx context.Context,
+	repo *git.Repo,
+	client *gh.Client,
+	tx meta.WriteTx,
+	update PendingUpdate,
+	branch meta.Branch,
+) error {
+	if !update.NoPush {
+		if err := syncBranchPushAndUpdatePullRequest(ctx, repo, client, tx, update.Branch, nil, update.Strategy); err != nil {
+			return err
+		}
+	}
+	if branch.PullRequest == nil || branch.PullRequest.ID == "" {
+		return nil
+	}
+	if update.Retarget {
+		results, err := RetargetPullRequests(ctx, client, tx, []string{update.Branch})
+		PrintRetargetResults(results)
+		if err != nil {
+			return err
+		}
+	}
+	if update.Body != nil {
+		pr, err := client.PullRequest(ctx, branch.PullRequest.ID)
+		if err != nil {
+			return err
+		}
+		if !PRBodyEqual(PRBodyContent(pr.Body), *update.Body) {
+			if _, err := client.UpdatePullRequest(ctx, githubv4.UpdatePullRequestInput{
+				PullRequestID: githubv4.ID(pr.ID),
+				Body:          gh.Ptr(githubv4.String(ReplacePRBodyContent(pr.Body, *update.Body))),
+			}); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprint(os.Stderr,
+				"  - updated the description of pull request ", colors.UserInput("#", pr.Number), "\n",
+			)
+		}
+	}
+	if update.Ready {
+		if _, err := client.MarkPullRequestReadyForReview(ctx, branch.PullRequest.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package actions_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/gh/ghtest"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushOffline(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	_, err := repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "one", []byte("one\n"))
+
+	// The .invalid top-level domain never resolves.
+	_, err = repo.Git("remote", "set-url", "origin", "https://github.invalid/owner/repo.git")
+	require.NoError(t, err)
+	err = actions.Push(repo, "one", actions.PushOpts{Force: actions.ForceWithLease})
+	require.True(t, actions.IsOffline(err), "unexpected error: %v", err)
+}
+
+func TestPendingUpdates(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+
+	updates, err := actions.ReadPendingUpdates(repo)
+	require.NoError(t, err)
+	require.Empty(t, updates)
+
+	require.NoError(t, actions.QueueRetarget(repo, "one", "two"))
+	updates, err = actions.ReadPendingUpdates(repo)
+	require.NoError(t, err)
+	require.Len(t, updates, 2)
+
+	require.NoError(t, actions.RemovePendingUpdate(repo, "two"))
+	require.NoError(t, actions.RemovePendingUpdate(repo, "three"))
+	require.NoError(t, actions.RemovePendingUpdate(repo, "one"))
+	updates, err = actions.ReadPendingUpdates(repo)
+	require.NoError(t, err)
+	require.Empty(t, updates)
+}
+
+func TestQueuePullRequestUpdates(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+
+	require.NoError(t, actions.QueueRetarget(repo, "one", "two"))
+	require.NoError(t, actions.QueuePullRequestBody(repo, "one", "Hello"))
+	updates, err := actions.ReadPendingUpdates(repo)
+	require.NoError(t, err)
+	require.Len(t, updates, 2)
+	// Only the pull requests have to be updated, the branches aren't pushed.
+	require.Equal(t, "one", updates[0].Branch)
+	require.True(t, updates[0].NoPush)
+	require.True(t, updates[0].Retarget)
+	require.Equal(t, gh.Ptr("Hello"), updates[0].Body)
+	require.Equal(t, "two", updates[1].Branch)
+	require.True(t, updates[1].NoPush)
+	require.Nil(t, updates[1].Body)
+}
+
+func TestPushPendingUpdatesBody(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{
+		Name:        "one",
+		Parent:      meta.BranchState{Name: "main", Trunk: true},
+		PullRequest: &meta.PullRequest{ID: "PR_1", Number: 1},
+	})
+	require.NoError(t, actions.QueuePullRequestBody(repo, "one", "New description"))
+
+	var updated string
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		if strings.Contains(string(body), "updatePullRequest(") {
+			updated = string(body)
+			_, _ = w.Write([]byte(`{"data": {"updatePullRequest": {"pullRequest": {"id": "PR_1"}}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"node": {"id": "PR_1", "number": 1, "state": "OPEN", "body": "Old description"}}}`))
+	}))
+
+	pushed, err := actions.PushPendingUpdates(context.Background(), repo, client, tx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"one"}, pushed)
+	require.Contains(t, updated, "New description")
+	updates, err := actions.ReadPendingUpdates(repo)
+	require.NoError(t, err)
+	require.Empty(t, updates)
+}
+
+func TestPushSyncedBranchesAppliesPendingBody(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	_, err := repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "one", []byte("one\n"))
+
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{
+		Name:        "one",
+		Parent:      meta.BranchState{Name: "main", Trunk: true},
+		PullRequest: &meta.PullRequest{ID: "PR_1", Number: 1},
+	})
+	// The description was edited while offline.
+	require.NoError(t, actions.QueuePullRequestBody(repo, "one", "New description"))
+
+	var updated []string
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		if strings.Contains(string(body), "updatePullRequest(") {
+			updated = append(updated, string(body))
+			_, _ = w.Write([]byte(`{"data": {"updatePullRequest": {"pullRequest": {"id": "PR_1"}}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"node": {"id": "PR_1", "number": 1, "state": "OPEN", "body": "Old description"}}}`))
+	}))
+
+	require.NoError(t, actions.PushSyncedBranches(context.Background(), repo, client, tx, actions.StackSyncState{
+		Pushes: []string{"one"},
+	}))
+	require.NotEmpty(t, updated)
+	require.Contains(t, updated[len(updated)-1], "New description")
+	updates, err := actions.ReadPendingUpdates(repo)
+	require.NoError(t, err)
+	require.Empty(t, updates)
+}
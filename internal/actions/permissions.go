@@ -0,0 +1,52 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/sirupsen/logrus"
+)
+
+// CheckWriteAccess makes sure that the GitHub token can create and update pull
+// requests in the repository before doing so. Otherwise, the GitHub API fails
+// with a generic error (usually 404 Not Found) in the middle of the operation,
+// which doesn't tell what's wrong with the token. If the access can't be
+// determined (e.g., because of a network error), the check is skipped and the
+// operation itself will fail if the token isn't good enough.
+func CheckWriteAccess(ctx context.Context, client *gh.Client, repository meta.Repository) error {
+	slug := repository.Owner + "/" + repository.Name
+	access, err := client.RepositoryAccess(ctx, repository.Owner, repository.Name)
+	if err != nil {
+		var httpErr *gh.HTTPError
+		if !errors.As(err, &httpErr) {
+			logrus.WithError(err).Debug("failed to check the access of the GitHub token, skipping the check")
+			return nil
+		}
+		switch httpErr.StatusCode {
+		case http.StatusUnauthorized:
+			return errors.New("the GitHub token is invalid or has expired")
+		case http.StatusForbidden, http.StatusNotFound:
+			return errors.Errorf(
+				"the GitHub token can't access %s (make sure that it has the repo scope "+
+					"and that it's authorized for the organization, if it uses SSO)",
+				slug,
+			)
+		}
+		logrus.WithError(err).Debug("failed to check the access of the GitHub token, skipping the check")
+		return nil
+	}
+
+	if !access.HasScope("repo") && (access.Private || !access.HasScope("public_repo")) {
+		return errors.Errorf(
+			"the GitHub token lacks the repo scope, which is needed to create and update pull requests in %s",
+			slug,
+		)
+	}
+	if !access.Push {
+		return errors.Errorf("the GitHub token lacks write access to %s", slug)
+	}
+	return nil
+}
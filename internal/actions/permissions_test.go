@@ -0,0 +1,50 @@
+package actions_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/gh/ghtest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckWriteAccess(t *testing.T) {
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/owner/writable":
+			w.Header().Set("X-OAuth-Scopes", "repo")
+			_, _ = w.Write([]byte(`{"private": true, "permissions": {"push": true}}`))
+		case "/api/v3/repos/owner/public":
+			w.Header().Set("X-OAuth-Scopes", "public_repo")
+			_, _ = w.Write([]byte(`{"private": false, "permissions": {"push": true}}`))
+		case "/api/v3/repos/owner/read-only":
+			_, _ = w.Write([]byte(`{"private": true, "permissions": {"push": false}}`))
+		case "/api/v3/repos/owner/no-scope":
+			w.Header().Set("X-OAuth-Scopes", "read:org")
+			_, _ = w.Write([]byte(`{"private": false, "permissions": {"push": true}}`))
+		case "/api/v3/repos/owner/unauthorized":
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/api/v3/repos/owner/flaky":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	check := func(name string) error {
+		return actions.CheckWriteAccess(context.Background(), client, meta.Repository{Owner: "owner", Name: name})
+	}
+
+	require.NoError(t, check("writable"))
+	require.NoError(t, check("public"))
+	// Other errors don't block the operation.
+	require.NoError(t, check("flaky"))
+
+	require.EqualError(t, check("read-only"), "the GitHub token lacks write access to owner/read-only")
+	require.ErrorContains(t, check("no-scope"), "the GitHub token lacks the repo scope")
+	require.ErrorContains(t, check("missing"), "the GitHub token can't access owner/missing")
+	require.ErrorContains(t, check("unauthorized"), "invalid or has expired")
+}
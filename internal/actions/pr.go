@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
 
@@ -47,6 +49,12 @@ type CreatePullRequestOpts struct {
 	Edit bool
 	// If true, do not open the browser after creating the PR
 	NoOpenBrowser bool
+	// The name of the pull request template to start the body with (see
+	// ReadPullRequestTemplate). If empty, the default template is used.
+	Template string
+	// The existing pull request of the branch if it was already queried (see
+	// FetchPullRequests). If nil, it's queried from GitHub.
+	PullRequest *gh.PullRequest
 }
 
 type CreatePullRequestResult struct {
@@ -93,21 +101,60 @@ func (e errPullRequestClosed) Error() string {
 	return fmt.Sprintf("pull request #%d is %s", e.Number, e.State)
 }
 
+// FetchPullRequests returns the pull requests of the given branches (of those
+// that have one) by branch name. The pull requests are queried all at once (see
+// gh.Client.PullRequests), so this should be used instead of querying the pull
+// request of each branch when operating on a whole stack.
+func FetchPullRequests(
+	ctx context.Context,
+	client *gh.Client,
+	tx meta.ReadTx,
+	branchNames []string,
+) (map[string]*gh.PullRequest, error) {
+	var ids, names []string
+	for _, name := range branchNames {
+		branch, _ := tx.Branch(name)
+		if branch.PullRequest == nil || branch.PullRequest.ID == "" {
+			continue
+		}
+		ids = append(ids, branch.PullRequest.ID)
+		names = append(names, name)
+	}
+	res := make(map[string]*gh.PullRequest, len(ids))
+	if len(ids) == 0 {
+		return res, nil
+	}
+	prs, err := client.PullRequests(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i, pr := range prs {
+		res[names[i]] = pr
+	}
+	return res, nil
+}
+
 // getExistingOpenPR returns an existing pull request for the given branch if
-// any exist and are open.
+// any exist and are open. If known is given, it's used instead of querying the
+// pull request of the branch (see FetchPullRequests).
 func getExistingOpenPR(
 	ctx context.Context,
 	client *gh.Client,
 	repoMeta meta.Repository,
 	branchMeta meta.Branch,
 	baseRefName string,
+	known *gh.PullRequest,
 ) (*gh.PullRequest, error) {
 	if branchMeta.PullRequest != nil {
-		logrus.WithField("pr", branchMeta.PullRequest.Number).
-			Debug("querying data for existing PR from GitHub")
-		pr, err := client.PullRequest(ctx, branchMeta.PullRequest.ID)
-		if err != nil {
-			return nil, errors.WrapIf(err, "querying existing pull request")
+		pr := known
+		if pr == nil || pr.ID != branchMeta.PullRequest.ID {
+			logrus.WithField("pr", branchMeta.PullRequest.Number).
+				Debug("querying data for existing PR from GitHub")
+			var err error
+			pr, err = client.PullRequest(ctx, branchMeta.PullRequest.ID)
+			if err != nil {
+				return nil, errors.WrapIf(err, "querying existing pull request")
+			}
 		}
 		if pr.State != githubv4.PullRequestStateOpen {
 			return nil, errPullRequestClosed{pr}
@@ -150,12 +197,34 @@ func CreatePullRequest(
 	if !ok {
 		return nil, ErrRepoNotInitialized
 	}
+	// Read the template upfront so that an unknown template fails before
+	// anything is pushed.
+	prTemplate, err := ReadPullRequestTemplate(repo, opts.Template)
+	if err != nil {
+		return nil, err
+	}
 	branchMeta, _ := tx.Branch(opts.BranchName)
+	if branchMeta.WIP {
+		_, _ = fmt.Fprint(os.Stderr,
+			colors.Failure("Branch "), colors.UserInput(opts.BranchName),
+			colors.Failure(" is marked as work in progress.\n"),
+			colors.Faint("  - use "), colors.CliCmd("av stack unwip"),
+			colors.Faint(" to push it and create a pull request for it\n"),
+		)
+		return nil, ErrExitSilently{ExitCode: 1}
+	}
+	if branchMeta.ReadOnly {
+		_, _ = fmt.Fprint(os.Stderr,
+			colors.Failure("Branch "), colors.UserInput(opts.BranchName),
+			colors.Failure(" is read-only since it belongs to someone else.\n"),
+		)
+		return nil, ErrExitSilently{ExitCode: 1}
+	}
 
 	var existingPR *gh.PullRequest
 	if !opts.Force {
 		var err error
-		existingPR, err = getExistingOpenPR(ctx, client, repoMeta, branchMeta, opts.BranchName)
+		existingPR, err = getExistingOpenPR(ctx, client, repoMeta, branchMeta, opts.BranchName, opts.PullRequest)
 		if closed, ok := errutils.As[errPullRequestClosed](err); ok {
 			_, _ = fmt.Fprint(os.Stderr,
 				colors.Failure("Existing pull request for branch "),
@@ -188,6 +257,19 @@ func CreatePullRequest(
 		"\n",
 	)
 	if !opts.NoPush || opts.ForcePush {
+		// Stamping the trailers rewrites the head commit, which isn't allowed
+		// for frozen stacks.
+		if _, freeze := meta.StackFreeze(tx, opts.BranchName); config.Av.PullRequest.StackTrailers && freeze == nil {
+			stamped, err := StampStackTrailers(repo, tx, opts.BranchName)
+			if err != nil {
+				return nil, err
+			}
+			if stamped {
+				_, _ = fmt.Fprint(os.Stderr,
+					"  - updated the stack trailers of the head commit\n",
+				)
+			}
+		}
 		pushFlags := []string{"push"}
 
 		if opts.ForcePush {
@@ -196,7 +278,11 @@ func CreatePullRequest(
 			// Use --force-with-lease to allow pushing branches that have been
 			// rebased but don't overwrite changes if we don't expect them to
 			// be there.
-			pushFlags = append(pushFlags, "--force-with-lease")
+			leaseArg, err := forceWithLeaseArg(repo, opts.BranchName)
+			if err != nil {
+				return nil, err
+			}
+			pushFlags = append(pushFlags, leaseArg)
 		}
 
 		// NOTE: This assumes that the user use the default push strategy (simple). It would
@@ -208,13 +294,15 @@ func CreatePullRequest(
 			"  - pushing to ", color.CyanString("origin/%s", opts.BranchName),
 			"\n",
 		)
-		if _, err := repo.Git(pushFlags...); err != nil {
+		res, err := repo.Run(&git.RunOpts{Args: pushFlags})
+		if err != nil {
 			return nil, errors.WrapIf(err, "failed to push")
 		}
-		if err := repo.BranchSetConfig(opts.BranchName, "av-pushed-remote", "origin"); err != nil {
-			return nil, err
+		if res.ExitCode != 0 {
+			printPushFailure(string(res.Stderr))
+			return nil, errors.Errorf("failed to push branch %q", opts.BranchName)
 		}
-		if err := repo.BranchSetConfig(opts.BranchName, "av-pushed-ref", fmt.Sprintf("refs/heads/%s", opts.BranchName)); err != nil {
+		if err := RecordPush(repo, opts.BranchName); err != nil {
 			return nil, err
 		}
 	} else {
@@ -315,11 +403,23 @@ func CreatePullRequest(
 			opts.Title = commits[0].Subject
 		}
 		// Reasonable defaults for body:
-		// 1. Try and find a pull request template
+		// 1. Use the template that was asked for (with --template)
+		if opts.Body == "" && opts.Template != "" {
+			opts.Body = prTemplate
+		}
+		// 2. Use the description of the branch (see `av stack describe-branch`)
 		if opts.Body == "" {
-			opts.Body = readDefaultPullRequestTemplate(repo)
+			description, err := repo.BranchDescription(opts.BranchName)
+			if err != nil {
+				logrus.WithError(err).Debug("failed to read the description of the branch")
+			}
+			opts.Body = description
 		}
-		// 2. Use the commit message from the first PR
+		// 3. Try and find a pull request template
+		if opts.Body == "" {
+			opts.Body = prTemplate
+		}
+		// 4. Use the commit message from the first PR
 		if opts.Body == "" {
 			opts.Body = commits[0].Body
 		}
@@ -374,11 +474,13 @@ func CreatePullRequest(
 		draft = true
 	}
 
+	body := AddPRIssueReference(opts.Body, branchMeta.Issue)
+	body = SetPRStackDescription(body, prStackDescription(tx, opts.BranchName))
 	pull, didCreatePR, err := ensurePR(ctx, client, repoMeta, ensurePROpts{
 		baseRefName: parentState.Name,
 		headRefName: opts.BranchName,
 		title:       opts.Title,
-		body:        opts.Body,
+		body:        body,
 		meta:        prMeta,
 		draft:       draft,
 		existingPR:  existingPR,
@@ -473,21 +575,79 @@ var prBodyTemplate = template.Must(
 `),
 )
 
-func readDefaultPullRequestTemplate(repo *git.Repo) string {
-	for _, dir := range []string{"", ".github", "data"} {
-		for _, f := range []string{
-			"PULL_REQUEST_TEMPLATE.md",
-			"pull_request_template.md",
-		} {
-			tpl := filepath.Join(repo.Dir(), dir, f)
-			data, err := os.ReadFile(tpl)
+// pullRequestTemplateDirs are the directories (relative to the root of the
+// repository) that GitHub looks for pull request templates in.
+var pullRequestTemplateDirs = []string{"", ".github", "docs", "data"}
+
+// ReadPullRequestTemplate returns the pull request template with the given
+// name, i.e., the file name (with or without the .md extension) of a template in
+// a PULL_REQUEST_TEMPLATE directory (see PullRequestTemplateNames). If the name
+// is empty, the default template (PULL_REQUEST_TEMPLATE.md) is returned, or an
+// empty string if the repository doesn't have one.
+func ReadPullRequestTemplate(repo *git.Repo, name string) (string, error) {
+	if name == "" {
+		for _, dir := range pullRequestTemplateDirs {
+			for _, f := range []string{
+				"PULL_REQUEST_TEMPLATE.md",
+				"pull_request_template.md",
+			} {
+				data, err := os.ReadFile(filepath.Join(repo.Dir(), dir, f))
+				if err != nil {
+					continue
+				}
+				return string(data), nil
+			}
+		}
+		return "", nil
+	}
+
+	names := PullRequestTemplateNames(repo)
+	for _, tpl := range names {
+		if !strings.EqualFold(strings.TrimSuffix(filepath.Base(tpl), ".md"), strings.TrimSuffix(name, ".md")) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(repo.Dir(), tpl))
+		if err != nil {
+			return "", errors.WrapIff(err, "failed to read pull request template %q", tpl)
+		}
+		return string(data), nil
+	}
+	if len(names) == 0 {
+		return "", errors.Errorf("pull request template %q not found (the repository has no PULL_REQUEST_TEMPLATE directory)", name)
+	}
+	var available []string
+	for _, tpl := range names {
+		available = append(available, strings.TrimSuffix(filepath.Base(tpl), ".md"))
+	}
+	return "", errors.Errorf(
+		"pull request template %q not found (available templates: %s)",
+		name, strings.Join(available, ", "),
+	)
+}
+
+// PullRequestTemplateNames returns the paths (relative to the root of the
+// repository) of the pull request templates in the PULL_REQUEST_TEMPLATE
+// directories, which can be selected with ReadPullRequestTemplate.
+func PullRequestTemplateNames(repo *git.Repo) []string {
+	var names []string
+	for _, dir := range pullRequestTemplateDirs {
+		for _, d := range []string{"PULL_REQUEST_TEMPLATE", "pull_request_template"} {
+			entries, err := os.ReadDir(filepath.Join(repo.Dir(), dir, d))
 			if err != nil {
 				continue
 			}
-			return string(data)
+			for _, entry := range entries {
+				if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".md") {
+					continue
+				}
+				names = append(names, filepath.Join(dir, d, entry.Name()))
+			}
+			// Both spellings refer to the same directory on case-insensitive
+			// file systems.
+			break
 		}
 	}
-	return ""
+	return names
 }
 
 type ensurePROpts struct {
@@ -514,7 +674,16 @@ func ensurePR(
 	var initialStack *stackutils.StackTreeNode = nil
 
 	if opts.existingPR != nil {
+		if err := checkPRMetadataVersion(opts.existingPR.Body); err != nil {
+			return nil, false, errors.WrapIff(err, "refusing to update pull request #%d", opts.existingPR.Number)
+		}
 		newBody := AddPRMetadataAndStack(opts.body, opts.meta, opts.headRefName, initialStack, "")
+		if opts.existingPR.Title == opts.title &&
+			opts.existingPR.BaseRefName == opts.baseRefName &&
+			PRBodyEqual(opts.existingPR.Body, newBody) {
+			logrus.WithField("pr", opts.existingPR.Number).Debug("pull request is up-to-date, not updating")
+			return opts.existingPR, false, nil
+		}
 		updatedPR, err := client.UpdatePullRequest(ctx, githubv4.UpdatePullRequestInput{
 			PullRequestID: opts.existingPR.ID,
 			Title:         gh.Ptr(githubv4.String(opts.title)),
@@ -674,12 +843,51 @@ func UpdatePullRequestState(
 }
 
 type PRMetadata struct {
+	// The version of the metadata schema (see PRMetadataVersion).
+	Version    int    `json:"version"`
 	Parent     string `json:"parent"`
 	ParentHead string `json:"parentHead"`
 	ParentPull int64  `json:"parentPull,omitempty"`
 	Trunk      string `json:"trunk"`
 }
 
+// PRMetadataVersion is the current version of the PR metadata schema. It must be
+// incremented (and a migration must be added to prMetadataMigrations) whenever
+// the schema changes in a way that older versions of av can't handle.
+const PRMetadataVersion = 1
+
+// prMetadataMigrations[i] migrates the metadata from version i to version i+1.
+var prMetadataMigrations = []func(*PRMetadata){
+	// 0 -> 1: The metadata wasn't versioned before, but the schema itself didn't
+	// change.
+	func(*PRMetadata) {},
+}
+
+// PRMetadataVersionError is returned when the PR metadata was written by a newer
+// version of av that uses a schema that this version doesn't understand.
+type PRMetadataVersionError struct {
+	Version int
+}
+
+func (e PRMetadataVersionError) Error() string {
+	return fmt.Sprintf(
+		"the pull request metadata was written by a newer version of av (schema version %d, "+
+			"but this version of av only supports up to version %d); upgrade av to continue",
+		e.Version, PRMetadataVersion,
+	)
+}
+
+// migrate upgrades the metadata to the current schema version.
+func (m *PRMetadata) migrate() error {
+	if m.Version > PRMetadataVersion {
+		return PRMetadataVersionError{Version: m.Version}
+	}
+	for ; m.Version < PRMetadataVersion; m.Version++ {
+		prMetadataMigrations[m.Version](m)
+	}
+	return nil
+}
+
 const PRMetadataCommentStart = "<!-- av pr metadata"
 
 const PRMetadataCommentHelpText = "This information is embedded by the av CLI when creating PRs to track the status of stacks when using Aviator. Please do not delete or edit this section of the PR.\n"
@@ -688,6 +896,65 @@ const PRMetadataCommentEnd = "-->"
 const PRStackCommentStart = "<!-- av pr stack begin -->"
 const PRStackCommentEnd = "<!-- av pr stack end -->"
 
+const PRStackDescriptionCommentStart = "<!-- av stack description begin -->"
+const PRStackDescriptionCommentEnd = "<!-- av stack description end -->"
+
+// SetPRStackDescription returns the given pull request body with the
+// description of the stack (see `av stack describe`) at the top, replacing the
+// description that was added before. If the description is empty, the
+// previous description is removed.
+func SetPRStackDescription(body string, description string) string {
+	if strings.Contains(body, PRStackDescriptionCommentStart) {
+		pre, _, post := extractContent(body, PRStackDescriptionCommentStart, PRStackDescriptionCommentEnd)
+		body = strings.TrimSpace(pre + "\n\n" + post)
+	}
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return body
+	}
+	return PRStackDescriptionCommentStart + "\n" +
+		"### About this stack\n\n" +
+		description + "\n" +
+		PRStackDescriptionCommentEnd + "\n\n" +
+		body
+}
+
+// prStackDescription returns the stack description to add to the pull request
+// of the given branch. Only the pull request at the bottom of the stack gets
+// the description.
+func prStackDescription(tx meta.ReadTx, branchName string) string {
+	branch, ok := tx.Branch(branchName)
+	if !ok || !branch.IsStackRoot() {
+		return ""
+	}
+	return branch.StackDescription
+}
+
+// closingKeywordPattern matches the keywords that GitHub recognizes for
+// closing an issue with a pull request (e.g., "Fixes #123").
+var closingKeywordPattern = regexp.MustCompile(`(?i)\b(close[sd]?|fix(e[sd])?|resolve[sd]?):?\s+#(\d+)\b`)
+
+// AddPRIssueReference returns the given pull request body with a reference to
+// the given issue ("Fixes #123") at the end, so that GitHub links the pull
+// request to the issue and closes the issue when the pull request is merged.
+// The body is returned as is if the issue is zero or the body already closes
+// the issue.
+func AddPRIssueReference(body string, issue int64) string {
+	if issue == 0 {
+		return body
+	}
+	for _, m := range closingKeywordPattern.FindAllStringSubmatch(body, -1) {
+		if m[3] == strconv.FormatInt(issue, 10) {
+			return body
+		}
+	}
+	body = strings.TrimRight(body, "\n")
+	if body != "" {
+		body += "\n\n"
+	}
+	return fmt.Sprintf("%sFixes #%d\n", body, issue)
+}
+
 func extractContent(input string, start string, end string) (pre string, content string, post string) {
 	startIndex := strings.Index(input, start)
 	if startIndex == -1 {
@@ -715,9 +982,47 @@ func ParsePRBody(input string) (body string, prMeta PRMetadata, hasStack bool, r
 	preStack, stack, postStack := extractContent(input, PRStackCommentStart, PRStackCommentEnd)
 	hasStack = stack != ""
 	body = preStack + postStack
+	// The body is still returned if the metadata can't be migrated so that the
+	// caller can tell it apart from the metadata.
+	retErr = prMeta.migrate()
 	return
 }
 
+// PRBodyEqual returns true if the two pull request bodies only differ in ways
+// that don't matter when rendered (line endings, trailing whitespace, and
+// repeated blank lines). This is used to avoid updating pull requests (which
+// notifies subscribers and consumes API rate limits) when nothing actually
+// changed.
+func PRBodyEqual(a, b string) bool {
+	return normalizePRBody(a) == normalizePRBody(b)
+}
+
+func normalizePRBody(body string) string {
+	// GitHub converts line endings to CRLF when the body is edited in the
+	// browser.
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if line == "" && len(lines) > 0 && lines[len(lines)-1] == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// checkPRMetadataVersion returns an error if the metadata embedded in the given
+// pull request body was written by a newer version of av (in which case we must
+// not overwrite it). Missing or malformed metadata is not an error.
+func checkPRMetadataVersion(body string) error {
+	_, _, _, err := ParsePRBody(body)
+	var versionErr PRMetadataVersionError
+	if errors.As(err, &versionErr) {
+		return versionErr
+	}
+	return nil
+}
+
 func ReadPRMetadata(body string) (PRMetadata, error) {
 	_, prMeta, _, err := ParsePRBody(body)
 	return prMeta, err
@@ -731,7 +1036,8 @@ func AddPRMetadataAndStack(
 	setting config.WriteStackSetting,
 ) string {
 	body, _, _, err := ParsePRBody(input)
-	if err != nil {
+	var versionErr PRMetadataVersionError
+	if err != nil && !errors.As(err, &versionErr) {
 		// No existing metadata comment, so add one.
 		logrus.WithError(err).Debug("could not parse PR metadata (assuming it doesn't exist)")
 		body = input + "\n\n"
@@ -871,6 +1177,7 @@ func AddPRMetadataAndStack(
 	sb.WriteString("\n")
 	sb.WriteString(PRMetadataCommentHelpText)
 	sb.WriteString("```\n")
+	prMeta.Version = PRMetadataVersion
 	// Note: Encoder.Encode implicitly adds a newline at the end of the JSON
 	// which is important here so that the ``` below appears on its own line.
 	if err := json.NewEncoder(&sb).Encode(prMeta); err != nil {
@@ -887,6 +1194,8 @@ func AddPRMetadataAndStack(
 // UpdatePullRequestWithStack updates the GitHub pull request associated with the given branch to include
 // the stack of branches that the branch is a part of.
 // This should be called after all applicable PRs have been created to ensure we can properly link them.
+// If setting is empty, the stack is only refreshed if the pull request already
+// contains it (see prStackSetting).
 func UpdatePullRequestWithStack(
 	ctx context.Context,
 	client *gh.Client,
@@ -894,6 +1203,21 @@ func UpdatePullRequestWithStack(
 	tx meta.WriteTx,
 	branchName string,
 	setting config.WriteStackSetting,
+) error {
+	return updatePullRequestWithStack(ctx, client, repo, tx, branchName, nil, setting)
+}
+
+// updatePullRequestWithStack is like UpdatePullRequestWithStack, but it takes
+// the pull request of the branch if it was already queried (see
+// FetchPullRequests).
+func updatePullRequestWithStack(
+	ctx context.Context,
+	client *gh.Client,
+	repo *git.Repo,
+	tx meta.WriteTx,
+	branchName string,
+	pr *gh.PullRequest,
+	setting config.WriteStackSetting,
 ) error {
 	branchMeta, _ := tx.Branch(branchName)
 	logrus.WithField("branch", branchName).WithField("pr", branchMeta.PullRequest.ID).Debug("Updating pull requests with stack")
@@ -909,14 +1233,33 @@ func UpdatePullRequestWithStack(
 		return err
 	}
 
-	existingPR, err := getExistingOpenPR(ctx, client, repoMeta, branchMeta, branchName)
-	if err != nil {
+	existingPR, err := getExistingOpenPR(ctx, client, repoMeta, branchMeta, branchName, pr)
+	if closed, ok := errutils.As[errPullRequestClosed](err); ok {
+		logrus.WithField("pr", closed.Number).Debug("pull request is closed, not updating stack")
+		return nil
+	} else if err != nil {
 		return errors.WithStack(err)
+	} else if existingPR == nil {
+		return nil
+	}
+	if setting == "" {
+		if setting = prStackSetting(existingPR.Body); setting == "" {
+			return nil
+		}
 	}
 
 	body, prMeta, _, err := ParsePRBody(existingPR.Body)
+	var versionErr PRMetadataVersionError
+	if errors.As(err, &versionErr) {
+		return errors.WrapIff(err, "refusing to update pull request #%d", existingPR.Number)
+	}
 
 	newBody := AddPRMetadataAndStack(body, prMeta, branchName, stackToWrite, setting)
+	newBody = SetPRStackDescription(newBody, prStackDescription(tx, branchName))
+	if PRBodyEqual(existingPR.Body, newBody) {
+		logrus.WithField("pr", existingPR.Number).Debug("pull request stack is up-to-date, not updating")
+		return nil
+	}
 	_, err = client.UpdatePullRequest(ctx, githubv4.UpdatePullRequestInput{
 		PullRequestID: existingPR.ID,
 		Body:          gh.Ptr(githubv4.String(newBody)),
@@ -936,8 +1279,22 @@ func UpdatePullRequestsWithStack(
 	branchNames []string,
 	setting config.WriteStackSetting,
 ) error {
+	var updated []string
 	for _, branchName := range branchNames {
-		if err := UpdatePullRequestWithStack(ctx, client, repo, tx, branchName, setting); err != nil {
+		// The pull requests of read-only branches belong to someone else.
+		if branch, _ := tx.Branch(branchName); branch.WIP || branch.ReadOnly {
+			continue
+		}
+		updated = append(updated, branchName)
+	}
+	prs, err := FetchPullRequests(ctx, client, tx, updated)
+	if err != nil {
+		return err
+	}
+	for _, branchName := range updated {
+		if err := updatePullRequestWithStack(
+			ctx, client, repo, tx, branchName, prs[branchName], setting,
+		); err != nil {
 			return err
 		}
 	}
@@ -960,3 +1317,63 @@ func UpdatePullRequestsWithStackForStack(
 
 	return UpdatePullRequestsWithStack(ctx, client, repo, tx, stackBranches, setting)
 }
+
+// RefreshPullRequestStacks updates the stack written to the pull requests of
+// all of the branches in the stacks of the given branches. Adding, reordering,
+// or merging branches changes the stack of every pull request in the stack, so
+// this should be called whenever the stacks may have changed. Pull requests that
+// don't contain the stack yet are only updated if pullRequest.writeStack is set.
+//
+// The branches that no longer exist (e.g., merged branches that were deleted)
+// are looked up in original to refresh the stacks that they were part of.
+func RefreshPullRequestStacks(
+	ctx context.Context,
+	client *gh.Client,
+	repo *git.Repo,
+	tx meta.WriteTx,
+	branchNames []string,
+	original map[string]OriginalBranchState,
+) error {
+	seen := make(map[string]bool)
+	var stackBranches []string
+	for _, branchName := range branchNames {
+		if _, ok := tx.Branch(branchName); !ok {
+			parent := original[branchName].Parent
+			if parent.Name == "" || parent.Trunk {
+				continue
+			}
+			branchName = parent.Name
+		}
+		if seen[branchName] {
+			continue
+		}
+		branches, err := meta.StackBranches(tx, branchName)
+		if err != nil {
+			return err
+		}
+		for _, name := range branches {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if branch, _ := tx.Branch(name); branch.PullRequest != nil {
+				stackBranches = append(stackBranches, name)
+			}
+		}
+	}
+
+	return UpdatePullRequestsWithStack(ctx, client, repo, tx, stackBranches, config.Av.PullRequest.WriteStack)
+}
+
+// prStackSetting returns where the stack is written in the given pull request
+// body, or an empty string if the body doesn't contain the stack.
+func prStackSetting(body string) config.WriteStackSetting {
+	if !strings.Contains(body, PRStackCommentStart) {
+		return ""
+	}
+	// The stack at the top is enclosed in a table (see AddPRMetadataAndStack).
+	if strings.Contains(body, PRStackCommentStart+"<table>") {
+		return config.WriteStackTop
+	}
+	return config.WriteStackBottom
+}
@@ -0,0 +1,76 @@
+package actions
+
+import (
+	"strings"
+)
+
+// prBodySections are the parts of a pull request body: the content written by
+// people and the sections around it that av manages.
+type prBodySections struct {
+	// The description of the stack (see SetPRStackDescription).
+	stackDescription string
+	// The stack, which is either above or below the content (see
+	// AddPRMetadataAndStack).
+	stackTop    string
+	content     string
+	stackBottom string
+	metadata    string
+}
+
+// splitPRBody splits the given pull request body into its sections. The
+// managed sections are kept verbatim (including their markers).
+func splitPRBody(body string) prBodySections {
+	var s prBodySections
+	s.stackDescription, body = cutPRBodySection(body, PRStackDescriptionCommentStart, PRStackDescriptionCommentEnd)
+	s.metadata, body = cutPRBodySection(body, PRMetadataCommentStart, PRMetadataCommentEnd)
+	if i := strings.Index(body, PRStackCommentStart); i != -1 {
+		var stack string
+		stack, body = cutPRBodySection(body, PRStackCommentStart, PRStackCommentEnd)
+		if strings.TrimSpace(body[:i]) == "" {
+			s.stackTop = stack
+		} else {
+			s.stackBottom = stack
+		}
+	}
+	s.content = strings.TrimSpace(body)
+	return s
+}
+
+// cutPRBodySection removes the section between the start and end markers
+// (inclusive) from the body. It returns the section and the rest of the body,
+// or an empty section if the body doesn't contain it.
+func cutPRBodySection(body string, start string, end string) (string, string) {
+	startIndex := strings.Index(body, start)
+	if startIndex == -1 {
+		return "", body
+	}
+	endIndex := strings.Index(body[startIndex+len(start):], end)
+	if endIndex == -1 {
+		return "", body
+	}
+	endIndex += startIndex + len(start) + len(end)
+	return body[startIndex:endIndex], body[:startIndex] + "\n\n" + body[endIndex:]
+}
+
+// PRBodyContent returns the part of the given pull request body that was
+// written by people, i.e., without the sections that av manages (the description
+// of the stack, the stack, and the av metadata).
+func PRBodyContent(body string) string {
+	return splitPRBody(body).content
+}
+
+// ReplacePRBodyContent returns the given pull request body with the part that
+// was written by people (see PRBodyContent) replaced by content. The sections
+// that av manages are kept as they are.
+func ReplacePRBodyContent(body string, content string) string {
+	s := splitPRBody(body)
+	var parts []string
+	for _, part := range []string{
+		s.stackDescription, s.stackTop, strings.TrimSpace(content), s.stackBottom, s.metadata,
+	} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "\n\n") + "\n"
+}
@@ -0,0 +1,53 @@
+package actions_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/utils/stackutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplacePRBodyContent(t *testing.T) {
+	prMeta := actions.PRMetadata{Parent: "one", ParentHead: "abc", ParentPull: 1, Trunk: "main"}
+	stack := &stackutils.StackTreeNode{
+		Branch: &stackutils.StackTreeBranchInfo{BranchName: "main"},
+		Children: []*stackutils.StackTreeNode{{
+			Branch: &stackutils.StackTreeBranchInfo{BranchName: "one", PullRequestNumber: "1"},
+			Children: []*stackutils.StackTreeNode{{
+				Branch: &stackutils.StackTreeBranchInfo{BranchName: "two", PullRequestNumber: "2"},
+			}},
+		}},
+	}
+
+	for _, setting := range []config.WriteStackSetting{config.WriteStackBottom, config.WriteStackTop, ""} {
+		t.Run(string(setting), func(t *testing.T) {
+			body := actions.AddPRMetadataAndStack("Old description.\n\n- a list", prMeta, "two", stack, setting)
+			body = actions.SetPRStackDescription(body, "About the stack.")
+			require.Equal(t, "Old description.\n\n- a list", actions.PRBodyContent(body))
+
+			newBody := actions.ReplacePRBodyContent(body, "New description.\n")
+			assert.Equal(t, "New description.", actions.PRBodyContent(newBody))
+			assert.NotContains(t, newBody, "Old description.")
+			// The managed sections are kept where av puts them.
+			assert.True(t, strings.HasPrefix(newBody, actions.PRStackDescriptionCommentStart))
+			readMeta, err := actions.ReadPRMetadata(newBody)
+			require.NoError(t, err)
+			assert.Equal(t, "one", readMeta.Parent)
+			// Rendering the stack again results in the same body.
+			assert.True(t, actions.PRBodyEqual(
+				newBody,
+				actions.SetPRStackDescription(
+					actions.AddPRMetadataAndStack("New description.", prMeta, "two", stack, setting),
+					"About the stack.",
+				),
+			))
+		})
+	}
+
+	// Bodies without any managed sections are replaced entirely.
+	assert.Equal(t, "New.\n", actions.ReplacePRBodyContent("Old.", "New."))
+}
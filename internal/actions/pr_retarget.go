@@ -0,0 +1,135 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/shurcooL/githubv4"
+)
+
+// RetargetResult is a pull request whose base branch was outdated (see
+// RetargetPullRequests).
+type RetargetResult struct {
+	Branch      string
+	PullRequest *gh.PullRequest
+	// The branch that the pull request was based on.
+	OldBase string
+	// The branch that the pull request should be based on.
+	NewBase string
+	// True if the pull request is closed, so its base branch couldn't be
+	// changed. GitHub closes the pull requests that are based on a branch that
+	// is deleted (e.g., when the pull request of the parent branch is merged).
+	Closed bool
+}
+
+// RetargetPullRequests changes the base branch of the pull requests of the
+// given branches to the branch that they should be merged into: the parent
+// branch or, if the pull request of the parent was merged, the closest ancestor
+// that wasn't merged yet (or the trunk). When the pull request of a branch is
+// merged, GitHub doesn't know that the pull requests of its children should now
+// be merged into the trunk, so they're left based on the merged branch.
+//
+// The state of the pull requests is queried from GitHub, so the merged parents
+// are detected even if the stack wasn't synced yet. Read-only branches are
+// skipped since their pull requests belong to someone else.
+func RetargetPullRequests(
+	ctx context.Context,
+	client *gh.Client,
+	tx meta.ReadTx,
+	branchNames []string,
+) ([]RetargetResult, error) {
+	// The parents of the branches have to be queried as well to know whether
+	// they were merged.
+	seen := make(map[string]bool)
+	var queried []string
+	for _, name := range branchNames {
+		for _, name := range []string{name, parentName(tx, name)} {
+			if name != "" && !seen[name] {
+				seen[name] = true
+				queried = append(queried, name)
+			}
+		}
+	}
+	prs, err := FetchPullRequests(ctx, client, tx, queried)
+	if err != nil {
+		return nil, err
+	}
+	merged := func(name string) bool {
+		branch, _ := tx.Branch(name)
+		if branch.MergeCommit != "" {
+			return true
+		}
+		if pr, ok := prs[name]; ok {
+			return pr.State == githubv4.PullRequestStateMerged
+		}
+		return branch.PullRequest != nil && branch.PullRequest.State == githubv4.PullRequestStateMerged
+	}
+
+	var results []RetargetResult
+	for _, name := range branchNames {
+		branch, ok := tx.Branch(name)
+		pr := prs[name]
+		if !ok || branch.ReadOnly || pr == nil || pr.State == githubv4.PullRequestStateMerged {
+			continue
+		}
+		base := branch.Parent
+		for !base.Trunk && merged(base.Name) {
+			parent, ok := tx.Branch(base.Name)
+			if !ok {
+				break
+			}
+			base = parent.Parent
+		}
+		if pr.BaseBranchName() == base.Name {
+			continue
+		}
+
+		res := RetargetResult{Branch: name, PullRequest: pr, OldBase: pr.BaseBranchName(), NewBase: base.Name}
+		if pr.State == githubv4.PullRequestStateClosed {
+			res.Closed = true
+			results = append(results, res)
+			continue
+		}
+		if _, err := client.UpdatePullRequest(ctx, githubv4.UpdatePullRequestInput{
+			PullRequestID: githubv4.ID(pr.ID),
+			BaseRefName:   gh.Ptr(githubv4.String(base.Name)),
+		}); err != nil {
+			return results, errors.WrapIff(err, "failed to change the base branch of pull request #%d", pr.Number)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// PrintRetargetResults shows the pull requests that were retargeted by
+// RetargetPullRequests (and the ones that couldn't be).
+func PrintRetargetResults(results []RetargetResult) {
+	for _, res := range results {
+		if res.Closed {
+			_, _ = fmt.Fprint(os.Stderr,
+				"  - ", colors.Warning("WARNING:"), " pull request ", colors.UserInput("#", res.PullRequest.Number),
+				" of ", colors.UserInput(res.Branch), " is closed, so it can't be based on ",
+				colors.UserInput(res.NewBase), "\n",
+				"      - re-open it (or create a new one with ", colors.CliCmd("av pr create --force"), ")\n",
+			)
+			continue
+		}
+		_, _ = fmt.Fprint(os.Stderr,
+			"  - changed the base branch of pull request ", colors.UserInput("#", res.PullRequest.Number),
+			" of ", colors.UserInput(res.Branch), " from ", colors.UserInput(res.OldBase),
+			" to ", colors.UserInput(res.NewBase), "\n",
+		)
+	}
+}
+
+// parentName returns the name of the parent of the given branch (or an empty
+// string if the branch isn't tracked).
+func parentName(tx meta.ReadTx, name string) string {
+	branch, _ := tx.Branch(name)
+	return branch.Parent.Name
+}
@@ -0,0 +1,94 @@
+package actions_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/gh/ghtest"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetargetPullRequests(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+	tx := db.WriteTx()
+	defer tx.Abort()
+	// The pull request of "one" was merged, but the stack wasn't synced yet.
+	tx.SetBranch(meta.Branch{
+		Name:        "one",
+		Parent:      meta.BranchState{Name: "main", Trunk: true},
+		PullRequest: &meta.PullRequest{ID: "PR_1", Number: 1},
+	})
+	tx.SetBranch(meta.Branch{
+		Name:        "two",
+		Parent:      meta.BranchState{Name: "one"},
+		PullRequest: &meta.PullRequest{ID: "PR_2", Number: 2},
+	})
+	tx.SetBranch(meta.Branch{
+		Name:        "three",
+		Parent:      meta.BranchState{Name: "two"},
+		PullRequest: &meta.PullRequest{ID: "PR_3", Number: 3},
+	})
+	tx.SetBranch(meta.Branch{
+		Name:        "four",
+		Parent:      meta.BranchState{Name: "three"},
+		PullRequest: &meta.PullRequest{ID: "PR_4", Number: 4},
+	})
+
+	pulls := map[string]map[string]any{
+		"PR_1": {"id": "PR_1", "number": 1, "state": "MERGED", "baseRefName": "main"},
+		"PR_2": {"id": "PR_2", "number": 2, "state": "OPEN", "baseRefName": "one"},
+		"PR_3": {"id": "PR_3", "number": 3, "state": "OPEN", "baseRefName": "two"},
+		"PR_4": {"id": "PR_4", "number": 4, "state": "CLOSED", "baseRefName": "deleted"},
+	}
+	updated := make(map[string]string)
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string
+			Variables map[string]json.RawMessage
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if strings.HasPrefix(req.Query, "mutation") {
+			var input struct {
+				PullRequestID string `json:"pullRequestId"`
+				BaseRefName   string `json:"baseRefName"`
+			}
+			require.NoError(t, json.Unmarshal(req.Variables["input"], &input))
+			updated[input.PullRequestID] = input.BaseRefName
+			_, _ = fmt.Fprintf(w, `{"data": {"updatePullRequest": {"pullRequest": {"id": %q}}}}`, input.PullRequestID)
+			return
+		}
+		var ids []string
+		require.NoError(t, json.Unmarshal(req.Variables["ids"], &ids))
+		var nodes []map[string]any
+		for _, id := range ids {
+			nodes = append(nodes, pulls[id])
+		}
+		res, err := json.Marshal(nodes)
+		require.NoError(t, err)
+		_, _ = fmt.Fprintf(w, `{"data": {"nodes": %s}}`, res)
+	}))
+
+	results, err := actions.RetargetPullRequests(
+		context.Background(), client, tx, []string{"one", "two", "three", "four"},
+	)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"PR_2": "main"}, updated)
+	require.Len(t, results, 2)
+	require.Equal(t, "two", results[0].Branch)
+	require.Equal(t, "one", results[0].OldBase)
+	require.Equal(t, "main", results[0].NewBase)
+	require.False(t, results[0].Closed)
+	require.Equal(t, "four", results[1].Branch)
+	require.Equal(t, "three", results[1].NewBase)
+	require.True(t, results[1].Closed)
+}
@@ -0,0 +1,86 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/shurcooL/githubv4"
+)
+
+// PullRequestSetup is what's added to a pull request after it's created (see
+// SetUpPullRequest).
+type PullRequestSetup struct {
+	// The users or teams (e.g., "@org/team") to request reviews from.
+	Reviewers []string
+	// The names of the labels to add (they're created if they don't exist).
+	Labels []string
+	// The logins of the users to assign ("@me" is the authenticated user).
+	Assignees []string
+}
+
+// IsEmpty returns true if there's nothing to add to the pull request.
+func (s PullRequestSetup) IsEmpty() bool {
+	return len(s.Reviewers) == 0 && len(s.Labels) == 0 && len(s.Assignees) == 0
+}
+
+// SetUpPullRequest requests reviews from the reviewers of the setup and adds
+// its labels and assignees to the given pull request.
+func SetUpPullRequest(
+	ctx context.Context,
+	client *gh.Client,
+	repository meta.Repository,
+	pr *gh.PullRequest,
+	setup PullRequestSetup,
+) error {
+	if len(setup.Reviewers) > 0 {
+		if err := AddPullRequestReviewers(ctx, client, githubv4.ID(pr.ID), setup.Reviewers); err != nil {
+			return err
+		}
+	}
+	if len(setup.Labels) > 0 {
+		_, _ = fmt.Fprint(os.Stderr,
+			"  - adding label(s) ", colors.UserInput(strings.Join(setup.Labels, ", ")), " to pull request\n",
+		)
+		if err := client.AddIssueLabels(ctx, gh.AddIssueLabelInput{
+			Owner:      repository.Owner,
+			Repo:       repository.Name,
+			Number:     pr.Number,
+			LabelNames: setup.Labels,
+		}); err != nil {
+			return err
+		}
+	}
+	if len(setup.Assignees) > 0 {
+		assignees := slices.Clone(setup.Assignees)
+		for i, assignee := range assignees {
+			if assignee != "@me" {
+				assignees[i] = strings.TrimPrefix(assignee, "@")
+				continue
+			}
+			viewer, err := client.Viewer(ctx)
+			if err != nil {
+				return errors.WrapIf(err, "failed to determine the authenticated user")
+			}
+			assignees[i] = viewer.Login
+		}
+		_, _ = fmt.Fprint(os.Stderr,
+			"  - assigning ", colors.UserInput(strings.Join(assignees, ", ")), " to pull request\n",
+		)
+		if err := client.AddIssueAssignees(ctx, gh.AddIssueAssigneesInput{
+			Owner:  repository.Owner,
+			Repo:   repository.Name,
+			Number: pr.Number,
+			Logins: assignees,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package actions_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/gh/ghtest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetUpPullRequest(t *testing.T) {
+	rest := make(map[string]string)
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		if r.URL.Path != "/api/graphql" {
+			rest[r.URL.Path] = string(body)
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+		switch {
+		case strings.Contains(string(body), "viewer"):
+			_, _ = w.Write([]byte(`{"data": {"viewer": {"name": "Me", "login": "me"}}}`))
+		case strings.Contains(string(body), "requestReviews"):
+			require.Contains(t, string(body), `"userIds":["U_alice"]`)
+			_, _ = w.Write([]byte(`{"data": {"requestReviews": {"pullRequest": {"id": "PR_1"}}}}`))
+		default:
+			_, _ = w.Write([]byte(`{"data": {"user": {"id": "U_alice", "login": "alice"}}}`))
+		}
+	}))
+
+	require.NoError(t, actions.SetUpPullRequest(
+		context.Background(), client,
+		meta.Repository{Owner: "owner", Name: "repo"},
+		&gh.PullRequest{ID: "PR_1", Number: 7},
+		actions.PullRequestSetup{
+			Reviewers: []string{"alice"},
+			Labels:    []string{"bug"},
+			Assignees: []string{"@me", "@bob"},
+		},
+	))
+	require.Equal(t, map[string]string{
+		"/api/v3/repos/owner/repo/issues/7/labels":    `{"labels":["bug"]}`,
+		"/api/v3/repos/owner/repo/issues/7/assignees": `{"assignees":["me","bob"]}`,
+	}, rest)
+	require.True(t, actions.PullRequestSetup{}.IsEmpty())
+}
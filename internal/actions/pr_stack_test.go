@@ -0,0 +1,108 @@
+package actions_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/gh/ghtest"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/aviator-co/av/internal/utils/stackutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshPullRequestStacks(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+
+	_, err = repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	one := gittest.CommitFile(t, repo, "one", []byte("one\n"))
+	_, err = repo.Git("checkout", "-b", "two")
+	require.NoError(t, err)
+	two := gittest.CommitFile(t, repo, "two", []byte("two\n"))
+	_, err = repo.Git("checkout", "-b", "three")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "three", []byte("three\n"))
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetRepository(meta.Repository{ID: "R_1", Owner: "owner", Name: "repo"})
+	tx.SetBranch(meta.Branch{
+		Name:        "one",
+		Parent:      meta.BranchState{Name: "main", Trunk: true},
+		PullRequest: &meta.PullRequest{ID: "PR_1", Number: 1},
+	})
+	tx.SetBranch(meta.Branch{
+		Name:        "two",
+		Parent:      meta.BranchState{Name: "one", Head: one},
+		PullRequest: &meta.PullRequest{ID: "PR_2", Number: 2},
+	})
+
+	// The stack was written to the pull requests when the stack only had two
+	// branches. The pull request of the third branch doesn't have it.
+	stack, err := stackutils.BuildStackTreeForPullRequest(repo, tx, "one")
+	require.NoError(t, err)
+	bodies := map[string]string{
+		"PR_1": actions.AddPRMetadataAndStack("one", actions.PRMetadata{}, "one", stack, config.WriteStackBottom),
+		"PR_2": actions.AddPRMetadataAndStack("two", actions.PRMetadata{}, "two", stack, config.WriteStackTop),
+		"PR_3": actions.AddPRMetadataAndStack("three", actions.PRMetadata{}, "three", nil, ""),
+	}
+	require.NotContains(t, bodies["PR_1"], "#3")
+	tx.SetBranch(meta.Branch{
+		Name:        "three",
+		Parent:      meta.BranchState{Name: "two", Head: two},
+		PullRequest: &meta.PullRequest{ID: "PR_3", Number: 3},
+	})
+
+	updated := make(map[string]string)
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string
+			Variables map[string]json.RawMessage
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if strings.HasPrefix(req.Query, "mutation") {
+			var input struct {
+				PullRequestID string `json:"pullRequestId"`
+				Body          string `json:"body"`
+			}
+			require.NoError(t, json.Unmarshal(req.Variables["input"], &input))
+			updated[input.PullRequestID] = input.Body
+			_, _ = fmt.Fprintf(w, `{"data": {"updatePullRequest": {"pullRequest": {"id": %q}}}}`, input.PullRequestID)
+			return
+		}
+		// The pull requests of the whole stack are queried at once.
+		var ids []string
+		require.NoError(t, json.Unmarshal(req.Variables["ids"], &ids))
+		require.Equal(t, []string{"PR_1", "PR_2", "PR_3"}, ids)
+		var nodes []map[string]any
+		for _, id := range ids {
+			nodes = append(nodes, map[string]any{"id": id, "state": "OPEN", "body": bodies[id]})
+		}
+		prs, err := json.Marshal(nodes)
+		require.NoError(t, err)
+		_, _ = fmt.Fprintf(w, `{"data": {"nodes": %s}}`, prs)
+	}))
+	writeStack := config.Av.PullRequest.WriteStack
+	config.Av.PullRequest.WriteStack = ""
+	defer func() { config.Av.PullRequest.WriteStack = writeStack }()
+
+	require.NoError(t, actions.RefreshPullRequestStacks(context.Background(), client, repo, tx, []string{"three"}, nil))
+
+	// The existing stacks are refreshed in place, and the stack isn't added to
+	// the pull request that didn't have it.
+	require.Len(t, updated, 2)
+	require.Contains(t, updated["PR_1"], "**#3**")
+	require.True(t, strings.HasPrefix(updated["PR_1"], "one"))
+	require.Contains(t, updated["PR_2"], "**#3**")
+	require.True(t, strings.HasPrefix(updated["PR_2"], actions.PRStackCommentStart))
+}
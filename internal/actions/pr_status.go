@@ -0,0 +1,92 @@
+package actions
+
+import (
+	"context"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/shurcooL/githubv4"
+)
+
+// PullRequestStatus is the state of the pull request of a branch along with
+// the state of its reviews and checks (see StackPullRequestStatus).
+type PullRequestStatus struct {
+	Branch string
+	// The pull request of the branch (nil if it has none).
+	PullRequest *gh.PullRequest
+	// The reviews and checks of the pull request (nil unless it's open).
+	Reviews *gh.PullRequestReviews
+	Checks  *gh.PullRequestChecks
+}
+
+// Blockers returns the reasons why the pull request can't be merged yet (e.g.,
+// "changes requested" or "failing checks"). Returns nil if nothing blocks the
+// pull request or if it isn't open.
+func (s PullRequestStatus) Blockers() []string {
+	if s.PullRequest == nil {
+		return []string{"no pull request"}
+	}
+	if s.PullRequest.State != githubv4.PullRequestStateOpen {
+		return nil
+	}
+	var blockers []string
+	if s.PullRequest.IsDraft {
+		blockers = append(blockers, "draft")
+	}
+	if s.Reviews != nil {
+		if len(s.Reviews.ChangesRequestedBy) > 0 {
+			blockers = append(blockers, "changes requested")
+		} else if !s.Reviews.Satisfied() {
+			blockers = append(blockers, "review required")
+		}
+	}
+	if s.Checks != nil {
+		switch s.Checks.State() {
+		case gh.CheckStateFailure:
+			blockers = append(blockers, "failing checks")
+		case gh.CheckStatePending:
+			blockers = append(blockers, "pending checks")
+		}
+	}
+	return blockers
+}
+
+// StackPullRequestStatus queries GitHub for the state of the pull requests of
+// the given branches (in the same order) along with their reviews and checks.
+// All of the pull requests are queried at once (see gh.PullRequestsWithStatus).
+func StackPullRequestStatus(
+	ctx context.Context,
+	client *gh.Client,
+	tx meta.ReadTx,
+	branches []string,
+) ([]PullRequestStatus, error) {
+	res := make([]PullRequestStatus, len(branches))
+	var ids []string
+	var indexes []int
+	for i, name := range branches {
+		res[i].Branch = name
+		branch, _ := tx.Branch(name)
+		if branch.PullRequest == nil || branch.PullRequest.ID == "" {
+			continue
+		}
+		ids = append(ids, branch.PullRequest.ID)
+		indexes = append(indexes, i)
+	}
+	if len(ids) == 0 {
+		return res, nil
+	}
+	prs, err := client.PullRequestsWithStatus(ctx, ids)
+	if err != nil {
+		return nil, errors.WrapIf(err, "failed to get the pull requests of the stack")
+	}
+	for j, pr := range prs {
+		status := &res[indexes[j]]
+		status.PullRequest = pr.PullRequest
+		if pr.PullRequest.State == githubv4.PullRequestStateOpen {
+			status.Reviews = pr.Reviews
+			status.Checks = pr.Checks
+		}
+	}
+	return res, nil
+}
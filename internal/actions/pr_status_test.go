@@ -0,0 +1,73 @@
+package actions_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/gh/ghtest"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackPullRequestStatus(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{
+		Name:        "merged",
+		Parent:      meta.BranchState{Name: "main", Trunk: true},
+		PullRequest: &meta.PullRequest{ID: "PR_1", Number: 1},
+	})
+	tx.SetBranch(meta.Branch{
+		Name:        "blocked",
+		Parent:      meta.BranchState{Name: "merged"},
+		PullRequest: &meta.PullRequest{ID: "PR_2", Number: 2},
+	})
+	tx.SetBranch(meta.Branch{Name: "new", Parent: meta.BranchState{Name: "blocked"}})
+
+	var requests int
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Contains(t, string(body), `"ids":["PR_1","PR_2"]`)
+		_, _ = w.Write([]byte(`{"data": {"nodes": [
+			{"id": "PR_1", "number": 1, "state": "MERGED",
+				"reviewRequests": {"nodes": []}, "latestOpinionatedReviews": {"nodes": []},
+				"commits": {"nodes": [{"commit": {"oid": "def", "statusCheckRollup": null}}]}},
+			{"id": "PR_2", "number": 2, "state": "OPEN", "isDraft": true, "reviewDecision": "CHANGES_REQUESTED",
+				"reviewRequests": {"nodes": []},
+				"latestOpinionatedReviews": {"nodes": [{"author": {"login": "bob"}, "state": "CHANGES_REQUESTED"}]},
+				"commits": {"nodes": [{"commit": {"oid": "abc", "statusCheckRollup": {"contexts": {"nodes": [
+					{"__typename": "StatusContext", "context": "lint", "state": "FAILURE"}
+				]}}}}]}}
+		]}}`))
+	}))
+
+	statuses, err := actions.StackPullRequestStatus(
+		context.Background(), client, tx, []string{"merged", "blocked", "new"},
+	)
+	require.NoError(t, err)
+	require.Equal(t, 1, requests)
+	require.Len(t, statuses, 3)
+
+	require.Equal(t, int64(1), statuses[0].PullRequest.Number)
+	require.Nil(t, statuses[0].Reviews)
+	require.Nil(t, statuses[0].Checks)
+	require.Nil(t, statuses[0].Blockers())
+
+	require.Equal(t, []string{"bob"}, statuses[1].Reviews.ChangesRequestedBy)
+	require.Equal(t, gh.CheckStateFailure, statuses[1].Checks.State())
+	require.Equal(t, []string{"draft", "changes requested", "failing checks"}, statuses[1].Blockers())
+
+	require.Nil(t, statuses[2].PullRequest)
+	require.Equal(t, []string{"no pull request"}, statuses[2].Blockers())
+}
@@ -2,9 +2,13 @@ package actions_test
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git/gittest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -62,3 +66,106 @@ func TestPRMetadataPreservesBody(t *testing.T) {
 	assert.Contains(t, body2, "It's very neat, actually.")
 	assert.Contains(t, body2, "\n"+actions.PRMetadataCommentStart)
 }
+
+func TestPRBodyEqual(t *testing.T) {
+	sampleMeta := actions.PRMetadata{
+		Parent:     "foo",
+		ParentHead: "bar",
+		ParentPull: 123,
+		Trunk:      "baz",
+	}
+	body := actions.AddPRMetadataAndStack("Hello!\n\nThis PR does things.", sampleMeta, "branch", nil, "")
+
+	// Rendering the body again without any changes is a no-op.
+	assert.True(t, actions.PRBodyEqual(body, actions.AddPRMetadataAndStack(body, sampleMeta, "branch", nil, "")))
+	// GitHub may convert line endings and users may leave trailing whitespace.
+	assert.True(t, actions.PRBodyEqual(body, strings.ReplaceAll(body, "\n", "  \r\n")))
+
+	sampleMeta.ParentHead = "baz"
+	assert.False(t, actions.PRBodyEqual(body, actions.AddPRMetadataAndStack(body, sampleMeta, "branch", nil, "")))
+	assert.False(t, actions.PRBodyEqual(body, strings.Replace(body, "things", "stuff", 1)))
+}
+
+func TestPRMetadataVersion(t *testing.T) {
+	legacyBody := "Hello!\n\n" + actions.PRMetadataCommentStart + "\n```\n" +
+		`{"parent":"foo","parentHead":"bar","trunk":"main"}` + "\n```\n" + actions.PRMetadataCommentEnd + "\n"
+	prMeta, err := actions.ReadPRMetadata(legacyBody)
+	require.NoError(t, err)
+	assert.Equal(t, actions.PRMetadataVersion, prMeta.Version)
+	assert.Equal(t, "foo", prMeta.Parent)
+
+	newerBody := strings.Replace(legacyBody, `{"parent"`, `{"version":999,"parent"`, 1)
+	_, err = actions.ReadPRMetadata(newerBody)
+	var versionErr actions.PRMetadataVersionError
+	require.ErrorAs(t, err, &versionErr)
+	assert.Equal(t, 999, versionErr.Version)
+
+	// The metadata is still recognized (and not duplicated) when re-rendering.
+	body := actions.AddPRMetadataAndStack(newerBody, prMeta, "branch", nil, "")
+	assert.Equal(t, 1, strings.Count(body, actions.PRMetadataCommentStart))
+	assert.Contains(t, body, `"version":1`)
+}
+
+func TestSetPRStackDescription(t *testing.T) {
+	body := actions.SetPRStackDescription("Original body.", "This stack adds a feature.")
+	assert.True(t, strings.HasPrefix(body, actions.PRStackDescriptionCommentStart))
+	assert.Contains(t, body, "This stack adds a feature.")
+	assert.True(t, strings.HasSuffix(body, "\n\nOriginal body."))
+
+	// Updating the description replaces the previous one.
+	body = actions.SetPRStackDescription(body, "A new description.")
+	assert.NotContains(t, body, "This stack adds a feature.")
+	assert.Contains(t, body, "A new description.")
+	assert.Equal(t, 1, strings.Count(body, actions.PRStackDescriptionCommentStart))
+
+	// An empty description removes it.
+	assert.Equal(t, "Original body.", actions.SetPRStackDescription(body, ""))
+}
+
+func TestAddPRIssueReference(t *testing.T) {
+	assert.Equal(t, "Some body.\n\nFixes #123\n", actions.AddPRIssueReference("Some body.\n", 123))
+	assert.Equal(t, "Fixes #123\n", actions.AddPRIssueReference("", 123))
+	assert.Equal(t, "Some body.", actions.AddPRIssueReference("Some body.", 0))
+
+	// The reference isn't duplicated if the body already closes the issue.
+	for _, body := range []string{"Fixes #123", "This closes #123.", "resolved: #123"} {
+		assert.Equal(t, body, actions.AddPRIssueReference(body, 123))
+	}
+	// Other issues and mere mentions don't count.
+	assert.Equal(t, "Fixes #1234\n\nFixes #123\n", actions.AddPRIssueReference("Fixes #1234", 123))
+	assert.Equal(t, "See #123\n\nFixes #123\n", actions.AddPRIssueReference("See #123", 123))
+}
+
+func TestReadPullRequestTemplate(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+
+	// No templates at all.
+	tpl, err := actions.ReadPullRequestTemplate(repo, "")
+	require.NoError(t, err)
+	assert.Equal(t, "", tpl)
+	_, err = actions.ReadPullRequestTemplate(repo, "bugfix")
+	require.Error(t, err)
+
+	dir := filepath.Join(repo.Dir(), ".github", "PULL_REQUEST_TEMPLATE")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Dir(), ".github", "pull_request_template.md"), []byte("Default"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bugfix.md"), []byte("Bug fix"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "feature.md"), []byte("Feature"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("Not a template"), 0644))
+
+	assert.Equal(t, []string{
+		filepath.Join(".github", "PULL_REQUEST_TEMPLATE", "bugfix.md"),
+		filepath.Join(".github", "PULL_REQUEST_TEMPLATE", "feature.md"),
+	}, actions.PullRequestTemplateNames(repo))
+
+	tpl, err = actions.ReadPullRequestTemplate(repo, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Default", tpl)
+	for _, name := range []string{"bugfix", "bugfix.md", "BugFix"} {
+		tpl, err = actions.ReadPullRequestTemplate(repo, name)
+		require.NoError(t, err)
+		assert.Equal(t, "Bug fix", tpl)
+	}
+	_, err = actions.ReadPullRequestTemplate(repo, "notes")
+	require.ErrorContains(t, err, "available templates: bugfix, feature")
+}
@@ -0,0 +1,110 @@
+package actions
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/sirupsen/logrus"
+)
+
+// PromptInfo is a compact summary of the current branch for shell prompts (see
+// `av prompt`).
+type PromptInfo struct {
+	// The name of the branch.
+	Branch string
+	// The name of the stack (i.e., the name of its root branch).
+	Stack string
+	// The position of the branch in the stack (starting at 1).
+	Position int
+	// The number of branches in the stack.
+	Total int
+	// True if the branch or one of its ancestors isn't based on the latest
+	// version of its parent (i.e., av stack sync would restack it).
+	NeedsSync bool
+	// The number of the pull request of the branch (or zero if there is none).
+	PullRequest int64
+}
+
+// promptCacheEntry records whether a branch needed a sync when the branches in
+// its stack were at the given commits.
+type promptCacheEntry struct {
+	Key       string `json:"key"`
+	NeedsSync bool   `json:"needsSync"`
+}
+
+// GetPromptInfo returns the PromptInfo of the given branch. Returns false if
+// the branch isn't part of a stack.
+//
+// Since this runs every time the shell prompt is shown, checking whether the
+// branch needs to be synced is cached (in the av directory of the repository)
+// until one of the branches involved changes.
+func GetPromptInfo(repo *git.Repo, tx meta.ReadTx, branchName string) (PromptInfo, bool) {
+	branch, ok := tx.Branch(branchName)
+	if !ok {
+		return PromptInfo{}, false
+	}
+	root, ok := meta.Root(tx, branchName)
+	if !ok {
+		return PromptInfo{}, false
+	}
+	stack, err := meta.StackBranches(tx, branchName)
+	if err != nil {
+		return PromptInfo{}, false
+	}
+	info := PromptInfo{
+		Branch:      branchName,
+		Stack:       root,
+		Position:    slices.Index(stack, branchName) + 1,
+		Total:       len(stack),
+		PullRequest: branch.PullRequest.GetNumber(),
+	}
+
+	// The branch needs to be synced if any branch between the root and the
+	// branch doesn't contain its parent.
+	previous, err := meta.PreviousBranches(tx, branchName)
+	if err != nil {
+		return info, true
+	}
+	chain := append(previous, branchName)
+	refs := make([]string, len(chain))
+	for i, name := range chain {
+		refs[i] = "refs/heads/" + name
+	}
+	tips, err := repo.Git(append([]string{"rev-parse"}, refs...)...)
+	if err != nil {
+		// Some of the branches are gone (e.g., a merged parent was deleted).
+		info.NeedsSync = true
+		return info, true
+	}
+	key := strings.Join(chain, " ") + "\n" + tips
+
+	cacheFile := filepath.Join(repo.AvDir(), "prompt-cache.json")
+	cache := make(map[string]promptCacheEntry)
+	if data, err := os.ReadFile(cacheFile); err == nil {
+		_ = json.Unmarshal(data, &cache)
+	}
+	if entry, ok := cache[branchName]; ok && entry.Key == key {
+		info.NeedsSync = entry.NeedsSync
+		return info, true
+	}
+
+	for i := 1; i < len(chain); i++ {
+		contained, err := repo.IsAncestor(refs[i-1], refs[i])
+		if err != nil || !contained {
+			info.NeedsSync = true
+			break
+		}
+	}
+	cache[branchName] = promptCacheEntry{Key: key, NeedsSync: info.NeedsSync}
+	if data, err := json.Marshal(cache); err == nil {
+		if err := os.WriteFile(cacheFile, data, 0644); err != nil {
+			logrus.WithError(err).Debug("failed to write the prompt cache")
+		}
+	}
+	return info, true
+}
@@ -0,0 +1,71 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPromptInfo(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+
+	_, err = repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	one := gittest.CommitFile(t, repo, "one", []byte("one\n"))
+	_, err = repo.Git("checkout", "-b", "two")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "two", []byte("two\n"))
+	_, err = repo.Git("checkout", "-b", "three")
+	require.NoError(t, err)
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{Name: "one", Parent: meta.BranchState{Name: "main", Trunk: true}})
+	tx.SetBranch(meta.Branch{
+		Name:        "two",
+		Parent:      meta.BranchState{Name: "one", Head: one},
+		PullRequest: &meta.PullRequest{Number: 123},
+	})
+	tx.SetBranch(meta.Branch{Name: "three", Parent: meta.BranchState{Name: "two"}})
+
+	_, ok := actions.GetPromptInfo(repo, tx, "main")
+	require.False(t, ok)
+
+	info, ok := actions.GetPromptInfo(repo, tx, "two")
+	require.True(t, ok)
+	require.Equal(t, actions.PromptInfo{
+		Branch:      "two",
+		Stack:       "one",
+		Position:    2,
+		Total:       3,
+		PullRequest: 123,
+	}, info)
+
+	// The parent moves on, so the branch (and the branches on top of it)
+	// need to be synced. The cached result must not be used.
+	gittest.CheckoutBranch(t, repo, "one")
+	gittest.CommitFile(t, repo, "one", []byte("one\nmore\n"))
+	for _, name := range []string{"two", "three"} {
+		info, ok = actions.GetPromptInfo(repo, tx, name)
+		require.True(t, ok)
+		require.True(t, info.NeedsSync, "%s should need a sync", name)
+	}
+	info, _ = actions.GetPromptInfo(repo, tx, "one")
+	require.False(t, info.NeedsSync)
+
+	_, err = repo.Git("rebase", "one", "two")
+	require.NoError(t, err)
+	twoHead, err := repo.RevParse(&git.RevParse{Rev: "two"})
+	require.NoError(t, err)
+	_, err = repo.Git("branch", "-f", "three", twoHead)
+	require.NoError(t, err)
+	info, _ = actions.GetPromptInfo(repo, tx, "three")
+	require.False(t, info.NeedsSync)
+}
@@ -0,0 +1,38 @@
+package actions
+
+import (
+	"slices"
+
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+)
+
+// MissingBranches returns the names (sorted) of the branches that are tracked
+// by av but no longer exist in Git (e.g., because they were deleted with
+// `git branch -D` rather than with av).
+func MissingBranches(repo *git.Repo, tx meta.ReadTx) ([]string, error) {
+	var res []string
+	for name := range tx.AllBranches() {
+		exists, err := repo.DoesBranchExist(name)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			res = append(res, name)
+		}
+	}
+	slices.Sort(res)
+	return res, nil
+}
+
+// PruneBranchMetadata removes the metadata of the given branches (see
+// RemoveBranchFromStack). This is used to clean up after branches that were
+// deleted outside of av.
+func PruneBranchMetadata(repo *git.Repo, tx meta.WriteTx, branches []string) error {
+	for _, name := range branches {
+		if err := RemoveBranchFromStack(repo, tx, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -35,35 +35,16 @@ type PushOpts struct {
 
 // Push pushes the given branch to the Git origin.
 func Push(repo *git.Repo, branchName string, opts PushOpts) error {
-	if opts.SkipIfRemoteBranchNotExist || opts.SkipIfRemoteBranchIsUpToDate {
-		// NOTE: This remote branch pattern is configurable with the fetch spec. This code
-		// assumes that the user won't change the fetch spec from the default. Technically,
-		// this must be generated from the fetch spec.
-		remoteBranch := "refs/remotes/origin/" + branchName
-		remoteBranchCommit, err := repo.RevParse(&git.RevParse{Rev: remoteBranch})
-		if err != nil {
-			return errors.WrapIff(err, "corresponding remote branch %q doesn't exist", remoteBranch)
-		}
+	if skip, err := skipPush(repo, branchName, opts); err != nil || skip {
+		return err
+	}
 
-		head, err := repo.RevParse(&git.RevParse{Rev: branchName})
+	var leaseArg string
+	if opts.Force == ForceWithLease {
+		var err error
+		leaseArg, err = forceWithLeaseArg(repo, branchName)
 		if err != nil {
-			return errors.WrapIff(
-				err,
-				"failed to determine HEAD for branch %q",
-				branchName,
-			)
-		}
-		logrus.WithFields(logrus.Fields{
-			"remote_branch": remoteBranch,
-			"remote_head":   remoteBranchCommit,
-			"local_head":    head,
-		}).Debug("checking if remote branch is up-to-date")
-		if opts.SkipIfRemoteBranchIsUpToDate && remoteBranchCommit == head {
-			_, _ = fmt.Fprint(os.Stderr,
-				"  - not pushing branch ", colors.UserInput(branchName),
-				" (upstream is already up-to-date)\n",
-			)
-			return nil
+			return err
 		}
 	}
 
@@ -75,7 +56,7 @@ func Push(repo *git.Repo, branchName string, opts PushOpts) error {
 	case NoForce:
 		// pass
 	case ForceWithLease:
-		pushArgs = append(pushArgs, "--force-with-lease")
+		pushArgs = append(pushArgs, leaseArg)
 	case ForcePush:
 		pushArgs = append(pushArgs, "--force")
 	}
@@ -100,19 +81,234 @@ func Push(repo *git.Repo, branchName string, opts PushOpts) error {
 			"stderr": string(res.Stderr),
 		}).Debug("git push failed")
 		if strings.Contains(string(res.Stderr), "stale info") {
-			_, _ = colors.TroubleshootingC.Fprint(os.Stderr,
-				"      - the remote branch seems to have diverged (were new commits pushed to\n",
-				"        it without using av?); to fix this, confirm that the remote branch is\n",
-				"        as expected and then force-push this branch\n",
-			)
+			return explainStaleInfo(repo, branchName)
+		} else if isOfflinePushOutput(string(res.Stderr)) {
+			return errors.WrapIff(ErrOffline, "failed to push branch %q", branchName)
+		}
+		printPushFailure(string(res.Stderr))
+		return errors.Errorf("failed to push branch %q", branchName)
+	}
+	if err := RecordPush(repo, branchName); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprint(os.Stderr,
+		colors.Success("okay"), "\n",
+	)
+	return nil
+}
+
+// PushBranches pushes the given branches to the Git origin with a single git
+// push (which is much faster than pushing them one by one) and reports the
+// result for each branch. Returns the error for each branch that couldn't be
+// pushed (branches that are skipped according to opts count as pushed). The
+// returned error is only set if the push failed as a whole (e.g., if the
+// remote can't be reached, in which case it wraps ErrOffline).
+func PushBranches(repo *git.Repo, branchNames []string, opts PushOpts) (map[string]error, error) {
+	failed := make(map[string]error)
+	pushArgs := []string{"push", "--porcelain"}
+	var toPush []string
+	for _, branchName := range branchNames {
+		if skip, err := skipPush(repo, branchName, opts); err != nil {
+			failed[branchName] = err
+			continue
+		} else if skip {
+			continue
+		}
+		if opts.Force == ForceWithLease {
+			leaseArg, err := forceWithLeaseArg(repo, branchName)
+			if err != nil {
+				failed[branchName] = err
+				continue
+			}
+			if leaseArg == "--force-with-lease" {
+				// A bare --force-with-lease would apply to all of the pushed
+				// branches.
+				leaseArg += "=refs/heads/" + branchName
+			}
+			pushArgs = append(pushArgs, leaseArg)
+		}
+		toPush = append(toPush, branchName)
+	}
+	if len(toPush) == 0 {
+		return failed, nil
+	}
+	if len(toPush) == 1 {
+		// Keep the output of a single push the same as Push.
+		if err := Push(repo, toPush[0], PushOpts{Force: opts.Force}); err != nil {
+			if errors.Is(err, ErrOffline) {
+				return failed, err
+			}
+			failed[toPush[0]] = err
+		}
+		return failed, nil
+	}
+
+	_, _ = fmt.Fprint(os.Stderr,
+		"  - pushing ", colors.UserInput(strings.Join(toPush, ", ")), "...\n",
+	)
+	pushArgs = append(pushArgs, "origin")
+	for _, branchName := range toPush {
+		if opts.Force == ForcePush {
+			pushArgs = append(pushArgs, "+refs/heads/"+branchName)
 		} else {
-			_, _ = colors.TroubleshootingC.Fprint(os.Stderr,
-				"      - git output:\n",
-				text.Indent(string(res.Stderr), "        "),
-				"\n",
+			pushArgs = append(pushArgs, "refs/heads/"+branchName)
+		}
+	}
+	res, err := repo.Run(&git.RunOpts{Args: pushArgs})
+	if err != nil {
+		return failed, errors.WrapIf(err, "failed to push branches")
+	}
+	logrus.WithFields(logrus.Fields{
+		"stdout": string(res.Stdout),
+		"stderr": string(res.Stderr),
+	}).Debug("git push finished")
+
+	// With --porcelain, git prints a line for each pushed ref, e.g.
+	// "+\trefs/heads/one:refs/heads/one\tabc...def (forced update)".
+	results := make(map[string]string)
+	rejected := make(map[string]bool)
+	for _, line := range strings.Split(string(res.Stdout), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 || len(fields[0]) != 1 {
+			continue
+		}
+		src, _, _ := strings.Cut(fields[1], ":")
+		branchName := strings.TrimPrefix(src, "refs/heads/")
+		results[branchName] = strings.TrimSpace(fields[2])
+		rejected[branchName] = fields[0] == "!"
+	}
+	if len(results) == 0 && res.ExitCode != 0 {
+		if isOfflinePushOutput(string(res.Stderr)) {
+			return failed, errors.WrapIf(ErrOffline, "failed to push branches")
+		}
+		_, _ = fmt.Fprint(os.Stderr, "      - ", colors.Failure("failed to push"), "\n")
+		printPushFailure(string(res.Stderr))
+		return failed, errors.New("failed to push branches")
+	}
+
+	// The output of git push covers all of the branches, so it's only
+	// explained once.
+	var explain bool
+	for _, branchName := range toPush {
+		result, ok := results[branchName]
+		switch {
+		case !ok:
+			_, _ = fmt.Fprint(os.Stderr,
+				"      - ", colors.UserInput(branchName), ": ", colors.Failure("not pushed"), "\n",
+			)
+			failed[branchName] = errors.Errorf("failed to push branch %q", branchName)
+		case rejected[branchName]:
+			_, _ = fmt.Fprint(os.Stderr,
+				"      - ", colors.UserInput(branchName), ": ", colors.Failure("failed to push ", result), "\n",
+			)
+			if strings.Contains(result, "stale info") {
+				failed[branchName] = explainStaleInfo(repo, branchName)
+			} else {
+				explain = true
+				failed[branchName] = errors.Errorf("failed to push branch %q", branchName)
+			}
+		default:
+			if err := RecordPush(repo, branchName); err != nil {
+				return failed, err
+			}
+			_, _ = fmt.Fprint(os.Stderr,
+				"      - ", colors.UserInput(branchName), ": ", colors.Success("okay"), "\n",
 			)
 		}
-		return errors.WrapIff(err, "failed to push branch %q", branchName)
+	}
+	if explain {
+		printPushFailure(string(res.Stderr))
+	}
+	return failed, nil
+}
+
+// explainStaleInfo explains why the push of the given branch was rejected
+// because of a failed --force-with-lease check and returns the corresponding
+// error.
+func explainStaleInfo(repo *git.Repo, branchName string) error {
+	if exists, err := remoteBranchExists(repo, branchName); err == nil && !exists {
+		PrintRemoteBranchDeleted(branchName, nil)
+		_, _ = colors.TroubleshootingC.Fprint(os.Stderr,
+			"      - run ", colors.CliCmd("git fetch --prune"),
+			colors.Troubleshooting(" and then push again to re-create it\n"),
+		)
+		return errors.WrapIff(ErrRemoteBranchDeleted, "failed to push branch %q", branchName)
+	}
+	_, _ = colors.TroubleshootingC.Fprint(os.Stderr,
+		"      - the remote branch seems to have diverged (were new commits pushed to\n",
+		"        it without using av?); to fix this, confirm that the remote branch is\n",
+		"        as expected and then force-push this branch\n",
+	)
+	return errors.Errorf("failed to push branch %q", branchName)
+}
+
+// skipPush returns true if the given branch shouldn't be pushed according to
+// the Skip* options (and explains why).
+func skipPush(repo *git.Repo, branchName string, opts PushOpts) (bool, error) {
+	if opts.SkipIfRemoteBranchNotExist {
+		// NOTE: This remote branch pattern is configurable with the fetch spec. This code
+		// assumes that the user won't change the fetch spec from the default. Technically,
+		// this must be generated from the fetch spec.
+		remoteBranch := "refs/remotes/origin/" + branchName
+		remoteBranchExists, err := repo.DoesRefExist(remoteBranch)
+		if err != nil {
+			return false, err
+		}
+		if !remoteBranchExists {
+			_, _ = fmt.Fprint(os.Stderr,
+				"  - not pushing branch ", colors.UserInput(branchName),
+				" (the remote branch doesn't exist; use ",
+				colors.CliCmd("av pr create"), " to push it again)\n",
+			)
+			return true, nil
+		}
+	}
+	if opts.SkipIfRemoteBranchIsUpToDate {
+		remoteBranch := "refs/remotes/origin/" + branchName
+		remoteBranchCommit, err := repo.RevParse(&git.RevParse{Rev: remoteBranch})
+		if err != nil {
+			// The remote branch doesn't exist, so it can't be up-to-date.
+			remoteBranchCommit = ""
+		}
+
+		head, err := repo.RevParse(&git.RevParse{Rev: branchName})
+		if err != nil {
+			return false, errors.WrapIff(
+				err,
+				"failed to determine HEAD for branch %q",
+				branchName,
+			)
+		}
+		logrus.WithFields(logrus.Fields{
+			"remote_branch": remoteBranch,
+			"remote_head":   remoteBranchCommit,
+			"local_head":    head,
+		}).Debug("checking if remote branch is up-to-date")
+		if remoteBranchCommit == head {
+			_, _ = fmt.Fprint(os.Stderr,
+				"  - not pushing branch ", colors.UserInput(branchName),
+				" (upstream is already up-to-date)\n",
+			)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ErrRemoteBranchDiverged is returned when a force-push would overwrite
+// commits on the remote branch that were pushed by somebody else since the last
+// time av pushed the branch.
+var ErrRemoteBranchDiverged = errors.Sentinel(
+	"the remote branch contains commits that are not in the local branch",
+)
+
+// RecordPush records that the branch was pushed to origin (along with the
+// commit that was pushed so that we can later detect if somebody else pushed to
+// the branch).
+func RecordPush(repo *git.Repo, branchName string) error {
+	head, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + branchName})
+	if err != nil {
+		return errors.WrapIff(err, "failed to determine HEAD for branch %q", branchName)
 	}
 	if err := repo.BranchSetConfig(branchName, "av-pushed-remote", "origin"); err != nil {
 		return err
@@ -120,8 +316,82 @@ func Push(repo *git.Repo, branchName string, opts PushOpts) error {
 	if err := repo.BranchSetConfig(branchName, "av-pushed-ref", fmt.Sprintf("refs/heads/%s", branchName)); err != nil {
 		return err
 	}
+	return repo.BranchSetConfig(branchName, "av-pushed-sha", head)
+}
+
+// forceWithLeaseArg returns the --force-with-lease argument that should be
+// used to force-push the given branch.
+//
+// A bare --force-with-lease compares the remote branch against the
+// remote-tracking branch, which is updated by every fetch, so it wouldn't
+// protect commits that were fetched but never incorporated into the local
+// branch. Instead, we expect the remote branch to still be at the commit that
+// we pushed last. If the remote branch has moved since then, the push is only
+// allowed if the new remote commits have been incorporated into the local
+// branch (e.g., with `git pull --rebase`).
+func forceWithLeaseArg(repo *git.Repo, branchName string) (string, error) {
+	lastPushed, err := repo.BranchGetConfig(branchName, "av-pushed-sha")
+	if err != nil {
+		return "", err
+	}
+	if lastPushed == "" {
+		// We don't know what we pushed last (e.g., the branch was pushed by an
+		// older version of av), so fall back to the default behavior.
+		return "--force-with-lease", nil
+	}
+	expected := lastPushed
+
+	remoteBranch := "refs/remotes/origin/" + branchName
+	if exists, err := repo.DoesRefExist(remoteBranch); err != nil {
+		return "", err
+	} else if !exists {
+		// The remote-tracking branch is gone (e.g., pruned by `git fetch
+		// --prune`), which probably means that the remote branch was deleted
+		// since we last pushed. In that case, there's nothing that could be
+		// overwritten and we just need to make sure that nobody re-created it
+		// in the meantime.
+		onRemote, err := remoteBranchExists(repo, branchName)
+		if err != nil {
+			return "", err
+		}
+		if !onRemote {
+			return fmt.Sprintf("--force-with-lease=refs/heads/%s:", branchName), nil
+		}
+	} else {
+		remoteHead, err := repo.RevParse(&git.RevParse{Rev: remoteBranch})
+		if err != nil {
+			return "", err
+		}
+		if remoteHead != lastPushed {
+			incorporated, err := repo.IsAncestor(remoteHead, "refs/heads/"+branchName)
+			if err != nil {
+				return "", err
+			}
+			if !incorporated {
+				printRemoteBranchDiverged(repo, branchName, remoteBranch)
+				return "", errors.WrapIff(ErrRemoteBranchDiverged, "refusing to force-push branch %q", branchName)
+			}
+			expected = remoteHead
+		}
+	}
+	return fmt.Sprintf("--force-with-lease=refs/heads/%s:%s", branchName, expected), nil
+}
+
+func printRemoteBranchDiverged(repo *git.Repo, branchName string, remoteBranch string) {
+	commits, err := repo.Git("log", "--oneline", "refs/heads/"+branchName+".."+remoteBranch)
+	if err != nil {
+		logrus.WithError(err).Debug("failed to list remote commits")
+	}
 	_, _ = fmt.Fprint(os.Stderr,
-		colors.Success("okay"), "\n",
+		colors.Failure("  - refusing to push ", branchName, ": "),
+		colors.Failure("the remote branch has commits that aren't in the local branch\n"),
+	)
+	if commits != "" {
+		_, _ = colors.TroubleshootingC.Fprint(os.Stderr, text.Indent(commits, "        "), "\n")
+	}
+	_, _ = colors.TroubleshootingC.Fprint(os.Stderr,
+		"      - somebody else probably pushed to this branch since av last pushed it;\n",
+		"        incorporate their changes first (e.g., with `git pull --rebase`) or\n",
+		"        overwrite them with `git push --force-with-lease origin ", branchName, "`\n",
 	)
-	return nil
 }
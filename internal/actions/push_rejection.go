@@ -0,0 +1,101 @@
+package actions
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/kr/text"
+)
+
+// PushRejection describes a known reason for why a push failed (e.g., because
+// the push was rejected by the branch protection rules of the remote).
+type PushRejection struct {
+	// A short description of why the push failed.
+	Reason string
+	// Suggestions for how to fix the problem.
+	Advice []string
+	// Substrings of the git push output that identify the rejection.
+	patterns []string
+}
+
+// pushRejections are the known push rejections. More specific rejections must
+// come before more general ones.
+var pushRejections = []PushRejection{
+	{
+		Reason: "the branch protection rules of the remote don't allow force-pushing to this branch",
+		Advice: []string{
+			"av rewrites the history of stacked branches when syncing them, which requires force-pushing",
+			"ask a repository admin to allow force-pushes to this branch (or to exclude stacked branches from the rule)",
+		},
+		patterns: []string{
+			"Cannot force-push to this branch",
+			"You are not allowed to force push code to a protected branch",
+		},
+	},
+	{
+		Reason: "the branch protection rules of the remote require signed commits",
+		Advice: []string{
+			"configure Git to sign commits with `git config commit.gpgSign true` (commits that av creates while rebasing are signed too)",
+			"then re-sign the commits of the branch, e.g., with `git rebase --exec 'git commit --amend --no-edit --no-verify -S' <parent-branch>`",
+		},
+		patterns: []string{"Commits must have verified signatures", "commits must have valid signatures"},
+	},
+	{
+		Reason: "the remote doesn't support signed pushes (push.gpgSign is set to true)",
+		Advice: []string{
+			"GitHub doesn't accept signed pushes; use `git config push.gpgSign if-asked` to only sign pushes when the remote supports it",
+		},
+		patterns: []string{"does not support --signed push"},
+	},
+	{
+		Reason: "failed to sign the push (push.gpgSign is set)",
+		Advice: []string{
+			"make sure that GPG (or the program configured with gpg.program) can sign with your key",
+		},
+		patterns: []string{"failed to sign the push certificate"},
+	},
+	{
+		Reason: "the push was rejected by the branch protection rules of the remote",
+		Advice: []string{
+			"check the branch protection rules (and rulesets) that apply to this branch in the repository settings",
+		},
+		patterns: []string{
+			"GH006: Protected branch update failed",
+			"GH013: Repository rule violations",
+			"protected branch hook declined",
+		},
+	},
+}
+
+// ClassifyPushRejection returns the known rejection that explains the given
+// git push output (or nil if the failure isn't recognized).
+func ClassifyPushRejection(output string) *PushRejection {
+	for i := range pushRejections {
+		for _, pattern := range pushRejections[i].patterns {
+			if strings.Contains(output, pattern) {
+				return &pushRejections[i]
+			}
+		}
+	}
+	return nil
+}
+
+// printPushFailure explains why pushing the branch failed based on the output
+// of git push. Unrecognized failures are explained by the raw output.
+func printPushFailure(output string) {
+	rejection := ClassifyPushRejection(output)
+	if rejection == nil {
+		_, _ = colors.TroubleshootingC.Fprint(os.Stderr,
+			"      - git output:\n",
+			text.Indent(output, "        "),
+			"\n",
+		)
+		return
+	}
+	_, _ = fmt.Fprint(os.Stderr, "      - ", colors.Failure(rejection.Reason), "\n")
+	for _, advice := range rejection.Advice {
+		_, _ = colors.TroubleshootingC.Fprint(os.Stderr, "      - ", advice, "\n")
+	}
+}
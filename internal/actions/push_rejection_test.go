@@ -0,0 +1,63 @@
+package actions_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushSigned(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	_, err := repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "one", []byte("one\n"))
+
+	// The remote (like GitHub) doesn't accept signed pushes.
+	_, err = repo.Git("config", "push.gpgSign", "true")
+	require.NoError(t, err)
+	res, err := repo.Run(&git.RunOpts{Args: []string{"push", "origin", "one"}})
+	require.NoError(t, err)
+	require.NotEqual(t, 0, res.ExitCode)
+	rejection := actions.ClassifyPushRejection(string(res.Stderr))
+	require.NotNil(t, rejection)
+	require.Contains(t, rejection.Reason, "doesn't support signed pushes")
+	require.Error(t, actions.Push(repo, "one", actions.PushOpts{Force: actions.ForceWithLease}))
+
+	_, err = repo.Git("config", "push.gpgSign", "if-asked")
+	require.NoError(t, err)
+	require.NoError(t, actions.Push(repo, "one", actions.PushOpts{Force: actions.ForceWithLease}))
+}
+
+func TestPushBranchProtection(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	_, err := repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "one", []byte("one\n"))
+	require.NoError(t, actions.Push(repo, "one", actions.PushOpts{Force: actions.ForceWithLease}))
+
+	// Mimic GitHub's branch protection on the remote.
+	remote, err := repo.Git("remote", "get-url", "origin")
+	require.NoError(t, err)
+	hook := "#!/bin/sh\n" +
+		"echo 'error: GH006: Protected branch update failed for refs/heads/one.' >&2\n" +
+		"echo 'error: Cannot force-push to this branch' >&2\n" +
+		"exit 1\n"
+	require.NoError(t, os.MkdirAll(filepath.Join(remote, "hooks"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(remote, "hooks", "pre-receive"), []byte(hook), 0o755))
+
+	gittest.CommitFile(t, repo, "one", []byte("two\n"), gittest.WithAmend())
+	res, err := repo.Run(&git.RunOpts{Args: []string{"push", "--force", "origin", "one"}})
+	require.NoError(t, err)
+	require.NotEqual(t, 0, res.ExitCode)
+	rejection := actions.ClassifyPushRejection(string(res.Stderr))
+	require.NotNil(t, rejection)
+	require.Contains(t, rejection.Reason, "don't allow force-pushing")
+	require.Error(t, actions.Push(repo, "one", actions.PushOpts{Force: actions.ForceWithLease}))
+
+	require.Nil(t, actions.ClassifyPushRejection("fatal: unable to access remote"))
+}
@@ -0,0 +1,220 @@
+package actions_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/gh/ghtest"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushForceWithLease(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	pushOpts := actions.PushOpts{Force: actions.ForceWithLease}
+
+	_, err := repo.Git("checkout", "-b", "feature")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "file", []byte("one\n"))
+	require.NoError(t, actions.Push(repo, "feature", pushOpts))
+
+	// Somebody else pushes a commit to the branch, which we fetch but don't
+	// incorporate into the local branch.
+	_, err = repo.Git("checkout", "-b", "other", "feature")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "other", []byte("other\n"))
+	_, err = repo.Git("push", "origin", "other:feature")
+	require.NoError(t, err)
+	_, err = repo.Git("checkout", "feature")
+	require.NoError(t, err)
+	_, err = repo.Git("fetch", "origin")
+	require.NoError(t, err)
+
+	// Rewriting the local branch and pushing it would drop their commit.
+	gittest.CommitFile(t, repo, "file", []byte("two\n"), gittest.WithAmend())
+	err = actions.Push(repo, "feature", pushOpts)
+	require.True(t, errors.Is(err, actions.ErrRemoteBranchDiverged), "unexpected error: %v", err)
+
+	// Once their commit is incorporated, pushing is fine again.
+	_, err = repo.Git("reset", "--hard", "origin/feature")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "file", []byte("two\n"))
+	require.NoError(t, actions.Push(repo, "feature", pushOpts))
+}
+
+func TestPushBranches(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	pushOpts := actions.PushOpts{Force: actions.ForceWithLease}
+	for _, name := range []string{"one", "two", "three"} {
+		_, err := repo.Git("checkout", "-b", name)
+		require.NoError(t, err)
+		gittest.CommitFile(t, repo, name, []byte(name+"\n"))
+	}
+	failed, err := actions.PushBranches(repo, []string{"one", "two", "three"}, pushOpts)
+	require.NoError(t, err)
+	require.Empty(t, failed)
+	for _, name := range []string{"one", "two", "three"} {
+		local, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + name})
+		require.NoError(t, err)
+		remote, err := repo.RevParse(&git.RevParse{Rev: "refs/remotes/origin/" + name})
+		require.NoError(t, err)
+		require.Equal(t, local, remote, "branch %q wasn't pushed", name)
+	}
+
+	// Somebody else pushes a commit to the second branch.
+	_, err = repo.Git("checkout", "-b", "other", "two")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "other", []byte("other\n"))
+	_, err = repo.Git("push", "origin", "other:two")
+	require.NoError(t, err)
+	_, err = repo.Git("fetch", "origin")
+	require.NoError(t, err)
+
+	// Rewriting the branches and pushing them only fails for that branch.
+	for _, name := range []string{"one", "two", "three"} {
+		_, err := repo.Git("checkout", name)
+		require.NoError(t, err)
+		gittest.CommitFile(t, repo, name, []byte(name+" again\n"), gittest.WithAmend())
+	}
+	failed, err = actions.PushBranches(repo, []string{"one", "two", "three"}, pushOpts)
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	require.True(t, errors.Is(failed["two"], actions.ErrRemoteBranchDiverged), "unexpected error: %v", failed["two"])
+	for _, name := range []string{"one", "three"} {
+		local, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + name})
+		require.NoError(t, err)
+		remote, err := repo.RevParse(&git.RevParse{Rev: "refs/remotes/origin/" + name})
+		require.NoError(t, err)
+		require.Equal(t, local, remote, "branch %q wasn't pushed", name)
+	}
+}
+
+func TestPushSyncedBranchesRestoresDraft(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	_, err := repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "one", []byte("one\n"))
+	_, err = repo.Git("push", "origin", "one")
+	require.NoError(t, err)
+	// Somebody else pushes to the branch, so pushing the rewritten branch is
+	// rejected.
+	_, err = repo.Git("checkout", "-b", "other", "one")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "other", []byte("other\n"))
+	_, err = repo.Git("push", "origin", "other:one")
+	require.NoError(t, err)
+	_, err = repo.Git("fetch", "origin")
+	require.NoError(t, err)
+	_, err = repo.Git("checkout", "one")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "one", []byte("one again\n"), gittest.WithAmend())
+
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{
+		Name:        "one",
+		Parent:      meta.BranchState{Name: "main", Trunk: true},
+		PullRequest: &meta.PullRequest{ID: "PR_1", Number: 1},
+	})
+
+	var mutations []string
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		for _, mutation := range []string{"convertPullRequestToDraft", "markPullRequestReadyForReview"} {
+			if strings.Contains(string(body), mutation+"(") {
+				mutations = append(mutations, mutation)
+				_, _ = w.Write([]byte(`{"data": {"` + mutation + `": {"pullRequest": {"id": "PR_1"}}}}`))
+				return
+			}
+		}
+		_, _ = w.Write([]byte(`{"data": {"node": {"id": "PR_1", "number": 1, "state": "OPEN"}}}`))
+	}))
+	rebaseWithDraft := config.Av.PullRequest.RebaseWithDraft
+	config.Av.PullRequest.RebaseWithDraft = gh.Ptr(true)
+	defer func() { config.Av.PullRequest.RebaseWithDraft = rebaseWithDraft }()
+
+	err = actions.PushSyncedBranches(context.Background(), repo, client, tx, actions.StackSyncState{
+		Pushes: []string{"one"},
+	})
+	require.Error(t, err)
+	// The pull request isn't left as a draft even though the push failed.
+	require.Equal(t, []string{"convertPullRequestToDraft", "markPullRequestReadyForReview"}, mutations)
+}
+
+func TestPushSyncedBranchesPostsRangeDiffOnce(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	_, err := repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "one", []byte("one\n"))
+	_, err = repo.Git("push", "origin", "one")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "one", []byte("one again\n"), gittest.WithAmend())
+
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{
+		Name:        "one",
+		Parent:      meta.BranchState{Name: "main", Trunk: true},
+		PullRequest: &meta.PullRequest{ID: "PR_1", Number: 1},
+	})
+
+	var comments []string
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string
+			Variables struct {
+				Input struct {
+					Body string
+				}
+			}
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		switch {
+		case strings.Contains(req.Query, "addComment("):
+			comments = append(comments, req.Variables.Input.Body)
+			_, _ = w.Write([]byte(`{"data": {"addComment": {"clientMutationId": ""}}}`))
+		case strings.Contains(req.Query, "updatePullRequest("):
+			_, _ = w.Write([]byte(`{"data": {"updatePullRequest": {"pullRequest": {"id": "PR_1"}}}}`))
+		case strings.Contains(req.Query, "requestReviews("):
+			_, _ = w.Write([]byte(`{"data": {"requestReviews": {"pullRequest": {"id": "PR_1"}}}}`))
+		case strings.Contains(req.Query, "user("):
+			_, _ = w.Write([]byte(`{"data": {"user": {"id": "U_1", "login": "alice"}}}`))
+		case strings.Contains(req.Query, "latestOpinionatedReviews"):
+			_, _ = w.Write([]byte(`{"data": {"node": {
+				"id": "PR_1",
+				"latestOpinionatedReviews": {"nodes": [{"author": {"login": "alice"}, "state": "APPROVED"}]}
+			}}}`))
+		case strings.Contains(req.Query, "comments("):
+			_, _ = w.Write([]byte(`{"data": {"node": {"id": "PR_1", "comments": {"nodes": []}}}}`))
+		default:
+			_, _ = w.Write([]byte(`{"data": {"node": {"id": "PR_1", "number": 1, "state": "OPEN"}}}`))
+		}
+	}))
+
+	err = actions.PushSyncedBranches(context.Background(), repo, client, tx, actions.StackSyncState{
+		Pushes: []string{"one"},
+		Config: actions.StackSyncConfig{ReviewersKeep: true, SummaryComment: true},
+	})
+	require.NoError(t, err)
+	require.Len(t, comments, 2)
+	// The range-diff is only shown in the summary comment.
+	require.Contains(t, comments[0], "re-requesting your review")
+	require.NotContains(t, comments[0], "range-diff")
+	require.Contains(t, comments[1], "range-diff")
+}
@@ -0,0 +1,69 @@
+package actions
+
+import (
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+)
+
+// The maximum length of the range-diff in a comment (GitHub rejects comments
+// that are longer than 65536 characters).
+const maxRangeDiffCommentLength = 60000
+
+// RangeDiff returns the output of git range-diff that compares the commits of a
+// branch before (oldBase..oldHead) and after (newBase..newHead) it was rebased.
+// Commits whose changes didn't change are listed as equal, so it shows what
+// actually changed beyond the rebase itself.
+func RangeDiff(repo *git.Repo, oldBase, oldHead, newBase, newHead string) (string, error) {
+	out, err := repo.Run(&git.RunOpts{
+		Args:      []string{"range-diff", "--no-color", oldBase + ".." + oldHead, newBase + ".." + newHead},
+		ExitError: true,
+	})
+	if err != nil {
+		return "", errors.WrapIf(err, "failed to compute the range-diff")
+	}
+	return strings.TrimRight(string(out.Stdout), "\n"), nil
+}
+
+// TruncateRangeDiff shortens the range-diff to at most limit bytes (plus a
+// marker that shows that it was cut) by dropping the lines that don't fit, so
+// that it's never cut in the middle of a line (or of a UTF-8 character).
+func TruncateRangeDiff(rangeDiff string, limit int) string {
+	if len(rangeDiff) <= limit {
+		return rangeDiff
+	}
+	return rangeDiff[:strings.LastIndexByte(rangeDiff[:limit+1], '\n')+1] + "[...]"
+}
+
+// branchRangeDiff compares the commits of the branch as they were on the remote
+// before the push (e.g., as they were approved) with the commits that were
+// pushed.
+func branchRangeDiff(
+	repo *git.Repo,
+	tx meta.ReadTx,
+	push *syncPush,
+	original OriginalBranchState,
+	newHead string,
+) (string, error) {
+	newBase, err := BranchBase(repo, tx, push.branchName)
+	if err != nil {
+		return "", err
+	}
+	// The approved commits are based on the parent as it was before the sync
+	// (if they're based on it at all).
+	oldBase := original.Parent.Head
+	if oldBase != "" {
+		if ok, err := repo.IsAncestor(oldBase, push.oldHead); err != nil || !ok {
+			oldBase = ""
+		}
+	}
+	if oldBase == "" {
+		oldBase, err = repo.MergeBase(&git.MergeBase{Revs: []string{push.oldHead, newBase}})
+		if err != nil {
+			return "", err
+		}
+	}
+	return RangeDiff(repo, oldBase, push.oldHead, newBase, newHead)
+}
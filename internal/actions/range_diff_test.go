@@ -0,0 +1,56 @@
+package actions_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangeDiff(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	oldBase := gittest.CommitFile(t, repo, "base", []byte("base\n"))
+	_, err := repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "one", []byte("one\n"))
+	two := strings.Repeat("two\n", 20)
+	oldHead := gittest.CommitFile(t, repo, "two", []byte(two))
+
+	// The branch is rebased onto a new commit of the trunk, and its last
+	// commit is amended.
+	_, err = repo.Git("checkout", "main")
+	require.NoError(t, err)
+	newBase := gittest.CommitFile(t, repo, "base", []byte("base 2\n"))
+	_, err = repo.Git("checkout", "one")
+	require.NoError(t, err)
+	_, err = repo.Git("rebase", "main")
+	require.NoError(t, err)
+	newHead := gittest.CommitFile(t, repo, "two", []byte(two+"amended\n"), gittest.WithAmend())
+
+	diff, err := actions.RangeDiff(repo, oldBase, oldHead, newBase, newHead)
+	require.NoError(t, err)
+	var lines []string
+	for _, line := range strings.Split(diff, "\n") {
+		if len(line) > 0 && line[0] != ' ' {
+			lines = append(lines, line)
+		}
+	}
+	require.Len(t, lines, 2, "unexpected range-diff:\n%s", diff)
+	// The first commit is unchanged, the second one isn't.
+	require.Contains(t, lines[0], " = ")
+	require.Contains(t, lines[1], " ! ")
+	require.Contains(t, diff, "+amended")
+}
+
+func TestTruncateRangeDiff(t *testing.T) {
+	diff := "1:  f4f8330 ! 1:  b8a58cf Fix the widget\n    -ä\n    +ö"
+	require.Equal(t, diff, actions.TruncateRangeDiff(diff, len(diff)))
+	// The diff is cut at the end of the last line that fits, even if the limit
+	// is in the middle of a multi-byte character.
+	limit := strings.Index(diff, "ä") + 1
+	require.Equal(t, "1:  f4f8330 ! 1:  b8a58cf Fix the widget\n[...]", actions.TruncateRangeDiff(diff, limit))
+	require.Equal(t, "1:  f4f8330 ! 1:  b8a58cf Fix the widget\n    -ä\n[...]", actions.TruncateRangeDiff(diff, len(diff)-1))
+	require.Equal(t, "[...]", actions.TruncateRangeDiff(diff, 3))
+}
@@ -0,0 +1,150 @@
+package actions
+
+import (
+	"fmt"
+	"os"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/shurcooL/githubv4"
+)
+
+// ErrRemoteBranchDeleted is returned when a branch that was previously pushed
+// to origin no longer exists there (e.g., because it was deleted after the pull
+// request was merged or closed, or by a repository admin).
+var ErrRemoteBranchDeleted = errors.Sentinel("the remote branch was deleted")
+
+// RemotelyDeletedBranches returns the branches (out of the given branches) that
+// were previously pushed to origin but no longer exist there.
+//
+// Branches that are known to be merged are not included since their remote
+// branches are expected to be deleted.
+func RemotelyDeletedBranches(repo *git.Repo, tx meta.ReadTx, branches []string) ([]string, error) {
+	var candidates []string
+	for _, name := range branches {
+		branch, _ := tx.Branch(name)
+		if branch.MergeCommit != "" ||
+			(branch.PullRequest != nil && branch.PullRequest.State == githubv4.PullRequestStateMerged) {
+			continue
+		}
+		// Work-in-progress branches aren't pushed, so it doesn't matter that
+		// the remote branch is gone.
+		if branch.WIP {
+			continue
+		}
+		pushed, err := wasPushed(repo, branch)
+		if err != nil {
+			return nil, err
+		}
+		if pushed {
+			candidates = append(candidates, name)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	remoteBranches, err := repo.LsRemote("origin")
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, name := range candidates {
+		if _, exists := remoteBranches["refs/heads/"+name]; !exists {
+			res = append(res, name)
+		}
+	}
+	return res, nil
+}
+
+// wasPushed returns true if av has pushed the branch to origin before.
+func wasPushed(repo *git.Repo, branch meta.Branch) (bool, error) {
+	if branch.PullRequest != nil && branch.PullRequest.ID != "" {
+		return true, nil
+	}
+	pushedRemote, err := repo.BranchGetConfig(branch.Name, "av-pushed-remote")
+	if err != nil {
+		return false, err
+	}
+	return pushedRemote != "", nil
+}
+
+// remoteBranchExists queries origin (rather than looking at the possibly stale
+// remote-tracking branch) to determine whether the branch exists there.
+func remoteBranchExists(repo *git.Repo, branchName string) (bool, error) {
+	out, err := repo.Run(&git.RunOpts{
+		Args:      []string{"ls-remote", "origin", "refs/heads/" + branchName},
+		ExitError: true,
+	})
+	if err != nil {
+		return false, errors.WrapIff(err, "failed to query origin for branch %q", branchName)
+	}
+	return len(out.Lines()) > 0, nil
+}
+
+// PrintRemoteBranchDeleted explains to the user that the remote branch of a
+// stack branch no longer exists.
+// The pull request may be nil if it's not known.
+func PrintRemoteBranchDeleted(branchName string, pull *meta.PullRequest) {
+	_, _ = fmt.Fprint(os.Stderr,
+		"  - ", colors.Warning("WARNING:"),
+		" the remote branch ", colors.UserInput("origin/", branchName),
+		" no longer exists\n",
+	)
+	if pull != nil {
+		_, _ = colors.TroubleshootingC.Fprint(os.Stderr,
+			"      - it was probably deleted when pull request #", pull.Number,
+			" was merged or closed (or by somebody else)\n",
+		)
+	} else {
+		_, _ = colors.TroubleshootingC.Fprint(os.Stderr,
+			"      - it was probably deleted by somebody else\n",
+		)
+	}
+}
+
+// RemoveBranchFromStack removes the branch metadata of the given branch (the
+// Git branch itself is left untouched). Its children are re-parented onto the
+// branch's parent so that the next sync rebases them without the commits of
+// the removed branch.
+//
+// The Git branch doesn't need to exist (e.g., if it was deleted outside of av).
+func RemoveBranchFromStack(repo *git.Repo, tx meta.WriteTx, name string) error {
+	branch, ok := tx.Branch(name)
+	if !ok {
+		return errors.Errorf("branch metadata not found for %q", name)
+	}
+	exists, err := repo.DoesBranchExist(name)
+	if err != nil {
+		return err
+	}
+	var head string
+	if exists {
+		head, err = repo.RevParse(&git.RevParse{Rev: "refs/heads/" + name})
+		if err != nil {
+			return errors.WrapIff(err, "failed to determine HEAD for branch %q", name)
+		}
+	}
+	for _, child := range meta.Children(tx, name) {
+		// The children are based on the removed branch, so that's where the
+		// commits that belong to the children start. If the branch is gone,
+		// the commit that was recorded for the child during the last sync is
+		// the best we can do.
+		childHead := child.Parent.Head
+		if head != "" {
+			childHead = head
+		}
+		child.Parent = meta.BranchState{
+			Name:  branch.Parent.Name,
+			Trunk: branch.Parent.Trunk,
+		}
+		if !child.Parent.Trunk {
+			child.Parent.Head = childHead
+		}
+		tx.SetBranch(child)
+	}
+	tx.DeleteBranch(name)
+	return nil
+}
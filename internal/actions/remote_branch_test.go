@@ -0,0 +1,86 @@
+package actions_test
+
+import (
+	"testing"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemotelyDeletedBranches(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+	pushOpts := actions.PushOpts{Force: actions.ForceWithLease}
+
+	_, err = repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "one", []byte("one\n"))
+	require.NoError(t, actions.Push(repo, "one", pushOpts))
+	_, err = repo.Git("checkout", "-b", "two")
+	require.NoError(t, err)
+	two := gittest.CommitFile(t, repo, "two", []byte("two\n"))
+	require.NoError(t, actions.Push(repo, "two", pushOpts))
+	_, err = repo.Git("checkout", "-b", "three")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "three", []byte("three\n"))
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{Name: "one", Parent: meta.BranchState{Name: "main", Trunk: true}})
+	tx.SetBranch(meta.Branch{Name: "two", Parent: meta.BranchState{Name: "one"}})
+	tx.SetBranch(meta.Branch{Name: "three", Parent: meta.BranchState{Name: "two", Head: two}})
+	branches := []string{"one", "two", "three"}
+
+	deleted, err := actions.RemotelyDeletedBranches(repo, tx, branches)
+	require.NoError(t, err)
+	require.Empty(t, deleted)
+
+	// Somebody deletes the remote branch. The branch that was never pushed
+	// isn't considered deleted.
+	remote, err := repo.Git("remote", "get-url", "origin")
+	require.NoError(t, err)
+	_, err = repo.Git("--git-dir="+remote, "branch", "-D", "two")
+	require.NoError(t, err)
+	deleted, err = actions.RemotelyDeletedBranches(repo, tx, branches)
+	require.NoError(t, err)
+	require.Equal(t, []string{"two"}, deleted)
+
+	// Work-in-progress branches aren't pushed, so they're never considered
+	// deleted.
+	twoMeta, _ := tx.Branch("two")
+	twoMeta.WIP = true
+	tx.SetBranch(twoMeta)
+	deleted, err = actions.RemotelyDeletedBranches(repo, tx, branches)
+	require.NoError(t, err)
+	require.Empty(t, deleted)
+	twoMeta.WIP = false
+	tx.SetBranch(twoMeta)
+
+	// Pushing fails since the remote branch isn't where we left it, but it can
+	// be re-created once the stale remote-tracking branch is pruned.
+	gittest.CheckoutBranch(t, repo, "two")
+	gittest.CommitFile(t, repo, "two", []byte("two\nmore\n"))
+	err = actions.Push(repo, "two", pushOpts)
+	require.True(t, errors.Is(err, actions.ErrRemoteBranchDeleted), "unexpected error: %v", err)
+	_, err = repo.Git("fetch", "--prune", "origin")
+	require.NoError(t, err)
+	require.NoError(t, actions.Push(repo, "two", pushOpts))
+	deleted, err = actions.RemotelyDeletedBranches(repo, tx, branches)
+	require.NoError(t, err)
+	require.Empty(t, deleted)
+
+	// Removing a branch from the stack moves its children onto its parent.
+	require.NoError(t, actions.RemoveBranchFromStack(repo, tx, "two"))
+	_, ok := tx.Branch("two")
+	require.False(t, ok)
+	three, _ := tx.Branch("three")
+	twoHead, err := repo.RevParse(&git.RevParse{Rev: "two"})
+	require.NoError(t, err)
+	require.Equal(t, meta.BranchState{Name: "one", Head: twoHead}, three.Parent)
+}
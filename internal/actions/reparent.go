@@ -3,6 +3,7 @@ package actions
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	"emperror.dev/errors"
@@ -19,6 +20,9 @@ type ReparentOpts struct {
 	NewParent string
 	// If true, consider the NewParent a trunk branch.
 	NewParentTrunk bool
+	// The options of the merge strategy that the branch is rebased with (see
+	// ParseStrategyOptions). If nil, the sync.strategyOptions config is used.
+	StrategyOptions []string
 }
 
 type ReparentResult struct {
@@ -33,6 +37,14 @@ func Reparent(
 	tx meta.WriteTx,
 	opts ReparentOpts,
 ) (*ReparentResult, error) {
+	if opts.NewParent == opts.Branch ||
+		slices.Contains(meta.SubsequentBranches(tx, opts.Branch), opts.NewParent) {
+		return nil, errors.WithStack(meta.CycleError{Branches: append(
+			[]string{opts.Branch},
+			pathToDescendant(tx, opts.Branch, opts.NewParent)...,
+		)})
+	}
+
 	branchMeta, exist := tx.Branch(opts.Branch)
 	if !exist {
 		_, _ = fmt.Fprint(
@@ -97,6 +109,9 @@ func Reparent(
 	if branchMeta.Parent.Trunk {
 		upstream = "remotes/origin/" + branchMeta.Parent.Name
 	}
+	// Don't replay the commits of a parent that was squash-merged into the new
+	// parent.
+	upstream = skipSquashMergedCommits(repo, opts.Branch, upstream, parentSha)
 
 	// We might need to rebase the branch on top of the new parent. This
 	// requires a special rebase command because the "normal" rebase command
@@ -117,10 +132,15 @@ func Reparent(
 		"onto_head":   parentSha,
 		"upstream":    upstream,
 	}).Debug("rebasing branch")
+	strategyOptions, err := defaultStrategyOptions(opts.StrategyOptions)
+	if err != nil {
+		return nil, err
+	}
 	output, err := repo.Rebase(git.RebaseOpts{
-		Onto:     parentSha,
-		Upstream: upstream,
-		Branch:   opts.Branch,
+		Onto:            parentSha,
+		Upstream:        upstream,
+		Branch:          opts.Branch,
+		StrategyOptions: strategyOptions,
 	})
 	if err != nil {
 		return nil, errors.WrapIff(err, "failed to run git rebase")
@@ -147,13 +167,26 @@ func ReparentSkipContinue(
 	}
 
 	if output.ExitCode != 0 && strings.Contains(string(output.Stderr), "no rebase in progress") {
-		// If there's no rebase, assume the user did `git rebase --continue/--skip` manually.
-		// TODO: we could try to detect if the user `git rebase --abort`-ed here
-		_, _ = fmt.Fprint(os.Stderr,
-			"    - ", colors.Failure("WARNING: "),
-			"no rebase in progress -- assuming rebase was completed (not aborted)",
-			"\n",
-		)
+		// If there's no rebase, the user either completed it with `git rebase
+		// --continue/--skip` or aborted it with `git rebase --abort`.
+		var newParentHead string
+		if !opts.NewParentTrunk {
+			newParentHead, err = repo.RevParse(&git.RevParse{Rev: opts.NewParent})
+			if err != nil {
+				return nil, errors.WrapIff(err, "failed to read head commit of %q", opts.NewParent)
+			}
+		}
+		completed, err := rebaseWasCompleted(repo, opts.Branch, newParentHead)
+		if err != nil {
+			return nil, err
+		}
+		if !completed {
+			_, _ = fmt.Fprint(os.Stderr,
+				"    - the rebase was aborted outside of av, rebasing ", colors.UserInput(opts.Branch), " again\n",
+			)
+			return Reparent(repo, tx, opts)
+		}
+		_, _ = fmt.Fprint(os.Stderr, "    - the rebase was completed with git rebase --continue\n")
 		if err := reparentWriteMetadata(repo, tx, opts); err != nil {
 			return nil, err
 		}
@@ -209,3 +242,19 @@ func handleReparentRebaseOutput(
 	}
 	return &ReparentResult{Success: true}, nil
 }
+
+// pathToDescendant returns the branches between the given branch (exclusive)
+// and its descendant (inclusive) in reverse order (i.e., starting at the
+// descendant).
+func pathToDescendant(tx meta.ReadTx, branch string, descendant string) []string {
+	var path []string
+	for current := descendant; current != branch && current != ""; {
+		path = append(path, current)
+		br, ok := tx.Branch(current)
+		if !ok || br.Parent.Trunk {
+			break
+		}
+		current = br.Parent.Name
+	}
+	return path
+}
@@ -0,0 +1,46 @@
+package actions
+
+import (
+	"sort"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+)
+
+// BranchesNeedingRestack returns the tracked branches that no longer contain
+// the head of their parent branch (e.g., because the parent was amended or
+// rebased outside of av), so they need to be synced onto their parent again.
+// Branches based on a trunk and branches whose parent is merged or doesn't
+// exist are ignored. The result is sorted.
+func BranchesNeedingRestack(repo *git.Repo, tx meta.ReadTx) ([]string, error) {
+	var res []string
+	for name, branch := range tx.AllBranches() {
+		if branch.Parent.Trunk || branch.MergeCommit != "" {
+			continue
+		}
+		parent, ok := tx.Branch(branch.Parent.Name)
+		if !ok || parent.MergeCommit != "" {
+			continue
+		}
+		for _, b := range []string{name, branch.Parent.Name} {
+			if exists, err := repo.DoesBranchExist(b); err != nil {
+				return nil, err
+			} else if !exists {
+				ok = false
+			}
+		}
+		if !ok {
+			continue
+		}
+		contained, err := repo.IsAncestor("refs/heads/"+branch.Parent.Name, "refs/heads/"+name)
+		if err != nil {
+			return nil, errors.WrapIff(err, "failed to check whether %q contains %q", name, branch.Parent.Name)
+		}
+		if !contained {
+			res = append(res, name)
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
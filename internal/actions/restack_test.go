@@ -0,0 +1,52 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBranchesNeedingRestack(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+
+	_, err = repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	one := gittest.CommitFile(t, repo, "one", []byte("one\n"))
+	_, err = repo.Git("checkout", "-b", "two")
+	require.NoError(t, err)
+	two := gittest.CommitFile(t, repo, "two", []byte("two\n"))
+	_, err = repo.Git("checkout", "-b", "three")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "three", []byte("three\n"))
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{Name: "one", Parent: meta.BranchState{Name: "main", Trunk: true}})
+	tx.SetBranch(meta.Branch{Name: "two", Parent: meta.BranchState{Name: "one", Head: one}})
+	tx.SetBranch(meta.Branch{Name: "three", Parent: meta.BranchState{Name: "two", Head: two}})
+
+	branches, err := actions.BranchesNeedingRestack(repo, tx)
+	require.NoError(t, err)
+	require.Empty(t, branches)
+
+	// Amend the bottom of the stack with plain Git. Only its child needs to
+	// be restacked for now (the grandchild still contains its parent).
+	gittest.CheckoutBranch(t, repo, "one")
+	gittest.CommitFile(t, repo, "one", []byte("one\namended\n"), gittest.WithAmend())
+	branches, err = actions.BranchesNeedingRestack(repo, tx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"two"}, branches)
+
+	// Advancing the trunk doesn't require a restack.
+	gittest.CheckoutBranch(t, repo, "main")
+	gittest.CommitFile(t, repo, "main", []byte("main\n"))
+	branches, err = actions.BranchesNeedingRestack(repo, tx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"two"}, branches)
+}
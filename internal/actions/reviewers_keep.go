@@ -0,0 +1,111 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+)
+
+// recordRemoteHead records what the remote branch looked like before the branch
+// is pushed, so that the pushed commits can be compared with it afterwards (see
+// branchRangeDiff). Nothing is recorded if the branch was never pushed.
+func recordRemoteHead(repo *git.Repo, push *syncPush) {
+	oldHead, err := repo.RevParse(&git.RevParse{Rev: "refs/remotes/origin/" + push.branchName})
+	if err != nil {
+		return
+	}
+	push.oldHead = oldHead
+}
+
+// recordApprovals records who approved the pull request of the branch before
+// the branch is pushed, so that keepReviewers can re-request their reviews
+// afterwards. It has to be called after recordRemoteHead.
+func recordApprovals(ctx context.Context, client *gh.Client, push *syncPush) {
+	if push.oldHead == "" {
+		// The branch was never pushed, so there's nothing to compare with.
+		return
+	}
+	reviews, err := client.PullRequestReviews(ctx, push.pr.ID)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to fetch the reviews of the pull request")
+		return
+	}
+	push.approvedBy = reviews.ApprovedBy
+}
+
+// keepReviewers re-requests reviews from the reviewers who approved the pull
+// request before its branch was pushed (since GitHub might dismiss approvals
+// when a branch is force-pushed) and posts a comment that summarizes what
+// changed with a range-diff. If summarized is true, the range-diff is left out
+// of the comment since the summary comment (see postSyncSummary) shows it
+// already. Failures are only reported as warnings since the branch was pushed
+// already.
+func keepReviewers(
+	ctx context.Context,
+	repo *git.Repo,
+	client *gh.Client,
+	tx meta.ReadTx,
+	push *syncPush,
+	original OriginalBranchState,
+	summarized bool,
+) {
+	newHead, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + push.branchName})
+	if err != nil || newHead == push.oldHead {
+		// The branch wasn't pushed (e.g., it was up-to-date already).
+		return
+	}
+
+	if err := AddPullRequestReviewers(ctx, client, githubv4.ID(push.pr.ID), push.approvedBy); err != nil {
+		_, _ = fmt.Fprint(os.Stderr,
+			"  - ", colors.Warning("WARNING:"), " failed to re-request reviews: ", err.Error(), "\n",
+		)
+		return
+	}
+
+	var rangeDiff string
+	if !summarized {
+		rangeDiff, err = branchRangeDiff(repo, tx, push, original, newHead)
+		if err != nil {
+			logrus.WithError(err).Warn("failed to compare the branch with the approved version")
+		}
+	}
+	if err := client.AddComment(ctx, push.pr.ID, reviewersKeepComment(push.approvedBy, rangeDiff, summarized)); err != nil {
+		_, _ = fmt.Fprint(os.Stderr,
+			"  - ", colors.Warning("WARNING:"), " failed to comment on the pull request: ", err.Error(), "\n",
+		)
+		return
+	}
+	_, _ = fmt.Fprint(os.Stderr,
+		"  - re-requested reviews from ", colors.UserInput("@", strings.Join(push.approvedBy, ", @")),
+		" on pull request ", colors.UserInput("#", push.pr.Number), "\n",
+	)
+}
+
+func reviewersKeepComment(reviewers []string, rangeDiff string, summarized bool) string {
+	var sb strings.Builder
+	sb.WriteString("This branch was updated by `av stack sync`, which might have dismissed ")
+	sb.WriteString("the previous approvals. @")
+	sb.WriteString(strings.Join(reviewers, ", @"))
+	sb.WriteString(": re-requesting your review.\n")
+	if summarized {
+		sb.WriteString("\nSee the summary comment of `av stack sync` for what changed.\n")
+		return sb.String()
+	}
+	if rangeDiff == "" {
+		return sb.String()
+	}
+	rangeDiff = TruncateRangeDiff(rangeDiff, maxRangeDiffCommentLength)
+	sb.WriteString("\n<details>\n<summary>Changes since the approved version (<code>git range-diff</code>)</summary>\n\n")
+	sb.WriteString("```\n")
+	sb.WriteString(rangeDiff)
+	sb.WriteString("\n```\n\n</details>\n")
+	return sb.String()
+}
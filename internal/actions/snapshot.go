@@ -0,0 +1,135 @@
+package actions
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/sirupsen/logrus"
+)
+
+// SnapshotRefPrefix is the prefix of the refs that record the branch tips from
+// before a history-rewriting operation. Each snapshot is stored as
+// refs/av/backup/<timestamp>/<branch>.
+const SnapshotRefPrefix = "refs/av/backup/"
+
+// MaxSnapshots is the number of snapshots that are kept. Older snapshots are
+// pruned whenever a new snapshot is taken.
+const MaxSnapshots = 50
+
+// SnapshotBranches records the current tips of the given branches under a new
+// snapshot (see SnapshotRefPrefix) so that they can be recovered manually if an
+// operation goes wrong. Branches that don't exist are ignored. The name of the
+// snapshot is returned (or an empty string if there was nothing to record).
+func SnapshotBranches(repo *git.Repo, branches []string) (string, error) {
+	tips := make(map[string]string)
+	for _, branch := range branches {
+		if exists, err := repo.DoesBranchExist(branch); err != nil {
+			return "", err
+		} else if !exists {
+			continue
+		}
+		tip, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + branch})
+		if err != nil {
+			return "", errors.WrapIff(err, "failed to determine HEAD for branch %q", branch)
+		}
+		tips[branch] = tip
+	}
+	if len(tips) == 0 {
+		return "", nil
+	}
+
+	existing, err := Snapshots(repo)
+	if err != nil {
+		return "", err
+	}
+	name := time.Now().UTC().Format("20060102T150405Z")
+	// Multiple snapshots can be taken within the same second.
+	for i, base := 2, name; slices.Contains(existing, name); i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	var updates strings.Builder
+	for branch, tip := range tips {
+		_, _ = fmt.Fprintf(&updates, "create %s%s/%s %s\n", SnapshotRefPrefix, name, branch, tip)
+	}
+	if _, err := repo.Run(&git.RunOpts{
+		Args:      []string{"update-ref", "--stdin"},
+		Stdin:     strings.NewReader(updates.String()),
+		ExitError: true,
+	}); err != nil {
+		return "", errors.WrapIf(err, "failed to record a snapshot of the branches")
+	}
+	logrus.WithFields(logrus.Fields{
+		"snapshot": name,
+		"branches": branches,
+	}).Debug("recorded snapshot of branches")
+
+	if err := PruneSnapshots(repo, MaxSnapshots); err != nil {
+		// The snapshot was recorded, so this isn't worth failing over.
+		logrus.WithError(err).Warn("failed to prune old snapshots")
+	}
+	return name, nil
+}
+
+// Snapshots returns the names of all recorded snapshots (from oldest to newest).
+func Snapshots(repo *git.Repo) ([]string, error) {
+	out, err := repo.Run(&git.RunOpts{
+		Args:      []string{"for-each-ref", "--format=%(refname)", SnapshotRefPrefix},
+		ExitError: true,
+	})
+	if err != nil {
+		return nil, errors.WrapIf(err, "failed to list snapshots")
+	}
+	var names []string
+	for _, ref := range out.Lines() {
+		name, _, _ := strings.Cut(strings.TrimPrefix(ref, SnapshotRefPrefix), "/")
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	// The names are timestamps, so sorting them lexically sorts them
+	// chronologically.
+	sort.Strings(names)
+	return names, nil
+}
+
+// PruneSnapshots deletes all but the newest keep snapshots.
+func PruneSnapshots(repo *git.Repo, keep int) error {
+	names, err := Snapshots(repo)
+	if err != nil {
+		return err
+	}
+	if len(names) <= keep {
+		return nil
+	}
+	prune := names[:len(names)-keep]
+
+	var args []string
+	for _, name := range prune {
+		args = append(args, SnapshotRefPrefix+name+"/")
+	}
+	out, err := repo.Run(&git.RunOpts{
+		Args:      append([]string{"for-each-ref", "--format=%(refname)"}, args...),
+		ExitError: true,
+	})
+	if err != nil {
+		return errors.WrapIf(err, "failed to list snapshots")
+	}
+	var updates strings.Builder
+	for _, ref := range out.Lines() {
+		_, _ = fmt.Fprintf(&updates, "delete %s\n", ref)
+	}
+	if _, err := repo.Run(&git.RunOpts{
+		Args:      []string{"update-ref", "--stdin"},
+		Stdin:     strings.NewReader(updates.String()),
+		ExitError: true,
+	}); err != nil {
+		return errors.WrapIf(err, "failed to delete old snapshots")
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotBranches(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+
+	_, err := repo.Git("checkout", "-b", "feature/one")
+	require.NoError(t, err)
+	one := gittest.CommitFile(t, repo, "one", []byte("one\n"))
+
+	name, err := actions.SnapshotBranches(repo, []string{"main", "feature/one", "missing"})
+	require.NoError(t, err)
+	require.NotEmpty(t, name)
+	snapshotted, err := repo.RevParse(&git.RevParse{Rev: actions.SnapshotRefPrefix + name + "/feature/one"})
+	require.NoError(t, err)
+	require.Equal(t, one, snapshotted)
+	exists, err := repo.DoesRefExist(actions.SnapshotRefPrefix + name + "/missing")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	// Snapshots taken in quick succession don't overwrite each other.
+	gittest.CommitFile(t, repo, "one", []byte("two\n"), gittest.WithAmend())
+	second, err := actions.SnapshotBranches(repo, []string{"feature/one"})
+	require.NoError(t, err)
+	require.NotEqual(t, name, second)
+	snapshotted, err = repo.RevParse(&git.RevParse{Rev: actions.SnapshotRefPrefix + name + "/feature/one"})
+	require.NoError(t, err)
+	require.Equal(t, one, snapshotted)
+
+	// Old snapshots are pruned.
+	third, err := actions.SnapshotBranches(repo, []string{"feature/one"})
+	require.NoError(t, err)
+	require.NoError(t, actions.PruneSnapshots(repo, 2))
+	snapshots, err := actions.Snapshots(repo)
+	require.NoError(t, err)
+	require.Equal(t, []string{second, third}, snapshots)
+}
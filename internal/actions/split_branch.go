@@ -0,0 +1,125 @@
+package actions
+
+import (
+	"slices"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+)
+
+// SplitPart is one of the branches that a branch is split into by SplitBranch.
+type SplitPart struct {
+	// The name of the branch.
+	Branch string
+	// The commits of the branch (oldest first).
+	Commits []string
+}
+
+// BranchCommits returns the commits of the given branch that aren't part of
+// its parent (oldest first).
+func BranchCommits(repo *git.Repo, tx meta.ReadTx, branchName string) ([]string, error) {
+	base, err := BranchBase(repo, tx, branchName)
+	if err != nil {
+		return nil, err
+	}
+	return repo.RevList(git.RevListOpts{
+		Specifiers: []string{"refs/heads/" + branchName, "^" + base},
+		Reverse:    true,
+	})
+}
+
+// BranchBase returns the commit that the given branch is based on: the
+// recorded HEAD of its parent or, if the parent is a trunk, the commit where the
+// branch forked off the remote trunk.
+func BranchBase(repo *git.Repo, tx meta.ReadTx, branchName string) (string, error) {
+	branch, ok := tx.Branch(branchName)
+	if !ok {
+		return "", errors.Errorf("branch %q is not tracked by av", branchName)
+	}
+	if branch.Parent.Head != "" {
+		return branch.Parent.Head, nil
+	}
+	base, err := repo.MergeBase(&git.MergeBase{
+		Revs: []string{"refs/heads/" + branchName, "refs/remotes/origin/" + branch.Parent.Name},
+	})
+	if err != nil {
+		return "", errors.WrapIff(err, "failed to determine where %q branched off %q", branchName, branch.Parent.Name)
+	}
+	return base, nil
+}
+
+// SplitBranch splits a branch into several stacked branches. The first part
+// must be the branch itself (which keeps its pull request) and every other part
+// becomes a new branch that is stacked on top of the previous part. Together,
+// the parts must contain the commits of the branch in their original order, so
+// no commits are rewritten. The children of the branch are moved onto the last
+// part.
+//
+// The last part is checked out afterwards.
+func SplitBranch(repo *git.Repo, tx meta.WriteTx, branchName string, parts []SplitPart) error {
+	if len(parts) < 2 {
+		return errors.New("the branch must be split into at least two branches")
+	}
+	if parts[0].Branch != branchName {
+		return errors.Errorf("the first part of the split must be %q", branchName)
+	}
+	commits, err := BranchCommits(repo, tx, branchName)
+	if err != nil {
+		return err
+	}
+	var all []string
+	for i, part := range parts {
+		if len(part.Commits) == 0 {
+			return errors.Errorf("branch %q has no commits", part.Branch)
+		}
+		if i > 0 {
+			if _, ok := tx.Branch(part.Branch); ok {
+				return errors.Errorf("branch %q is already tracked by av", part.Branch)
+			}
+			if exists, err := repo.DoesBranchExist(part.Branch); err != nil {
+				return err
+			} else if exists {
+				return errors.Errorf("branch %q already exists", part.Branch)
+			}
+		}
+		all = append(all, part.Commits...)
+	}
+	if !slices.Equal(all, commits) {
+		return errors.Errorf("the split must contain the commits of %q in their original order", branchName)
+	}
+
+	// The children have to be determined before the new branches (the first of
+	// which is a child of the branch too) are added.
+	children := meta.Children(tx, branchName)
+	last := parts[len(parts)-1]
+	for i, part := range parts[1:] {
+		prev := parts[i]
+		if _, err := repo.Git("branch", part.Branch, part.Commits[len(part.Commits)-1]); err != nil {
+			return errors.WrapIff(err, "failed to create branch %q", part.Branch)
+		}
+		tx.SetBranch(meta.Branch{
+			Name: part.Branch,
+			Parent: meta.BranchState{
+				Name: prev.Branch,
+				Head: prev.Commits[len(prev.Commits)-1],
+			},
+		})
+	}
+	for _, child := range children {
+		child.Parent.Name = last.Branch
+		tx.SetBranch(child)
+	}
+
+	// The last part points to the commit that the branch used to point to, so
+	// checking it out doesn't touch the working tree. After that, the branch
+	// isn't checked out anymore and can be moved.
+	if _, err := repo.CheckoutBranch(&git.CheckoutBranch{Name: last.Branch}); err != nil {
+		return err
+	}
+	first := parts[0]
+	if _, err := repo.Git("branch", "--force", first.Branch, first.Commits[len(first.Commits)-1]); err != nil {
+		return errors.WrapIff(err, "failed to move branch %q", first.Branch)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitBranch(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+
+	_, err = repo.Git("checkout", "-b", "feature")
+	require.NoError(t, err)
+	c1 := gittest.CommitFile(t, repo, "one", []byte("one\n"))
+	c2 := gittest.CommitFile(t, repo, "two", []byte("two\n"))
+	c3 := gittest.CommitFile(t, repo, "three", []byte("three\n"))
+	_, err = repo.Git("checkout", "-b", "child")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "four", []byte("four\n"))
+	gittest.CheckoutBranch(t, repo, "feature")
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{
+		Name:        "feature",
+		Parent:      meta.BranchState{Name: "main", Trunk: true},
+		PullRequest: &meta.PullRequest{Number: 123},
+	})
+	tx.SetBranch(meta.Branch{Name: "child", Parent: meta.BranchState{Name: "feature", Head: c3}})
+
+	commits, err := actions.BranchCommits(repo, tx, "feature")
+	require.NoError(t, err)
+	require.Equal(t, []string{c1, c2, c3}, commits)
+
+	// The commits have to stay in order.
+	err = actions.SplitBranch(repo, tx, "feature", []actions.SplitPart{
+		{Branch: "feature", Commits: []string{c2}},
+		{Branch: "feature-2", Commits: []string{c1, c3}},
+	})
+	require.Error(t, err)
+	// The new branches must not exist yet.
+	err = actions.SplitBranch(repo, tx, "feature", []actions.SplitPart{
+		{Branch: "feature", Commits: []string{c1}},
+		{Branch: "child", Commits: []string{c2, c3}},
+	})
+	require.Error(t, err)
+
+	err = actions.SplitBranch(repo, tx, "feature", []actions.SplitPart{
+		{Branch: "feature", Commits: []string{c1}},
+		{Branch: "feature-2", Commits: []string{c2}},
+		{Branch: "feature-3", Commits: []string{c3}},
+	})
+	require.NoError(t, err)
+
+	current, err := repo.CurrentBranchName()
+	require.NoError(t, err)
+	require.Equal(t, "feature-3", current)
+	for name, want := range map[string]string{"feature": c1, "feature-2": c2, "feature-3": c3} {
+		head, err := repo.RevParse(&git.RevParse{Rev: name})
+		require.NoError(t, err)
+		require.Equal(t, want, head, "branch %q", name)
+	}
+
+	feature, _ := tx.Branch("feature")
+	require.Equal(t, "main", feature.Parent.Name)
+	require.EqualValues(t, 123, feature.PullRequest.GetNumber())
+	feature2, _ := tx.Branch("feature-2")
+	require.Equal(t, meta.BranchState{Name: "feature", Head: c1}, feature2.Parent)
+	require.Nil(t, feature2.PullRequest)
+	feature3, _ := tx.Branch("feature-3")
+	require.Equal(t, meta.BranchState{Name: "feature-2", Head: c2}, feature3.Parent)
+	child, _ := tx.Branch("child")
+	require.Equal(t, meta.BranchState{Name: "feature-3", Head: c3}, child.Parent)
+}
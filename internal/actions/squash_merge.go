@@ -0,0 +1,138 @@
+package actions
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+)
+
+// The maximum number of trunk commits that are inspected when looking for the
+// commit that a branch was squash-merged as.
+const squashMergeSearchDepth = 200
+
+// FindSquashMergeCommit looks for a commit of the trunk (up to trunkCommit)
+// that introduces the same change as all of the commits of the branch combined,
+// which is the commit that GitHub creates when a pull request is squash-merged
+// (or rebase-merged, if it has a single commit). The changes are compared by
+// their patch-ids, so the commit is found even if the trunk had moved on before
+// the branch was merged. It returns an empty string if there's no such commit.
+//
+// This detects merged branches even if av doesn't know about the merge (e.g.,
+// the pull request was merged without av, or GitHub didn't record the merge
+// commit).
+func FindSquashMergeCommit(repo *git.Repo, tx meta.ReadTx, branchName string, trunkCommit string) (string, error) {
+	base, err := BranchBase(repo, tx, branchName)
+	if err != nil {
+		return "", err
+	}
+	diff, err := repo.Run(&git.RunOpts{
+		Args:      []string{"diff", "--no-color", base, "refs/heads/" + branchName, "--"},
+		ExitError: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(bytes.TrimSpace(diff.Stdout)) == 0 {
+		// A branch without changes can't be told apart from a merged one.
+		return "", nil
+	}
+	want, err := patchIDs(repo, diff.Stdout)
+	if err != nil || len(want) == 0 {
+		return "", err
+	}
+
+	log, err := repo.Run(&git.RunOpts{
+		Args: []string{
+			"log", "--no-color", "--no-merges", "-p", "--format=commit %H",
+			"--max-count=" + strconv.Itoa(squashMergeSearchDepth), trunkCommit, "^" + base, "--",
+		},
+		ExitError: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	ids, err := patchIDs(repo, log.Stdout)
+	if err != nil {
+		return "", err
+	}
+	for _, id := range ids {
+		if id.ID == want[0].ID {
+			return id.Commit, nil
+		}
+	}
+	return "", nil
+}
+
+// The maximum number of commits of a branch that SkipSquashMergedCommits
+// considers to be part of a squash-merged branch.
+const squashedCommitsSearchDepth = 50
+
+// SkipSquashMergedCommits returns the upstream to use when rebasing the given
+// branch onto the given commit (as in `git rebase --onto <onto> <upstream>`).
+// If the first commits that would be replayed were squash-merged into onto as a
+// single commit (e.g., because they belong to a parent branch whose pull
+// request was squash-merged without av knowing about it), the last of them is
+// returned instead of upstream, so that they aren't replayed (which would
+// conflict with the squash commit). The squash commit is returned as well (or
+// an empty string if no commits are skipped).
+//
+// Git only skips commits that were cherry-picked one by one, so this compares
+// the combined patch-ids of the first commits with the commits of onto.
+func SkipSquashMergedCommits(repo *git.Repo, branch, upstream, onto string) (string, string, error) {
+	commits, err := repo.RevList(git.RevListOpts{
+		Specifiers: []string{branch, "^" + upstream},
+		Reverse:    true,
+	})
+	if err != nil || len(commits) == 0 {
+		return upstream, "", err
+	}
+	if len(commits) > squashedCommitsSearchDepth {
+		commits = commits[:squashedCommitsSearchDepth]
+	}
+	mergeBase, err := repo.MergeBase(&git.MergeBase{Revs: []string{branch, onto}})
+	if err != nil {
+		return upstream, "", err
+	}
+	log, err := repo.Run(&git.RunOpts{
+		Args: []string{
+			"log", "--no-color", "--no-merges", "-p", "--format=commit %H",
+			"--max-count=" + strconv.Itoa(squashMergeSearchDepth), onto, "^" + mergeBase, "--",
+		},
+		ExitError: true,
+	})
+	if err != nil {
+		return upstream, "", err
+	}
+	ids, err := patchIDs(repo, log.Stdout)
+	if err != nil || len(ids) == 0 {
+		return upstream, "", err
+	}
+	squashCommits := make(map[string]string, len(ids))
+	for _, id := range ids {
+		squashCommits[id.ID] = id.Commit
+	}
+
+	// Prefer skipping as many commits as possible.
+	for i := len(commits) - 1; i >= 0; i-- {
+		diff, err := repo.Run(&git.RunOpts{
+			Args:      []string{"diff", "--no-color", commits[0] + "^", commits[i], "--"},
+			ExitError: true,
+		})
+		if err != nil {
+			return upstream, "", err
+		}
+		want, err := patchIDs(repo, diff.Stdout)
+		if err != nil {
+			return upstream, "", err
+		}
+		if len(want) == 0 {
+			continue
+		}
+		if squash, ok := squashCommits[want[0].ID]; ok {
+			return commits[i], squash, nil
+		}
+	}
+	return upstream, "", nil
+}
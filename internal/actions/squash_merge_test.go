@@ -0,0 +1,79 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindSquashMergeCommit(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+
+	_, err = repo.Git("checkout", "-b", "feature")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "one", []byte("one\n"))
+	gittest.CommitFile(t, repo, "two", []byte("two\n"))
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{Name: "feature", Parent: meta.BranchState{Name: "main", Trunk: true}})
+
+	// The branch hasn't been merged yet.
+	gittest.CheckoutBranch(t, repo, "main")
+	gittest.CommitFile(t, repo, "other", []byte("other\n"))
+	commit, err := actions.FindSquashMergeCommit(repo, tx, "feature", "main")
+	require.NoError(t, err)
+	require.Empty(t, commit)
+
+	// Squash-merge the branch (with a commit landing on top of it).
+	_, err = repo.Git("merge", "--squash", "feature")
+	require.NoError(t, err)
+	_, err = repo.Git("commit", "-m", "Squashed feature")
+	require.NoError(t, err)
+	squashCommit, err := repo.RevParse(&git.RevParse{Rev: "HEAD"})
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "later", []byte("later\n"))
+
+	commit, err = actions.FindSquashMergeCommit(repo, tx, "feature", "main")
+	require.NoError(t, err)
+	require.Equal(t, squashCommit, commit)
+}
+
+func TestSkipSquashMergedCommits(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+
+	base, err := repo.RevParse(&git.RevParse{Rev: "main"})
+	require.NoError(t, err)
+	_, err = repo.Git("checkout", "-b", "feature")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "one", []byte("1a\n"))
+	c2 := gittest.CommitFile(t, repo, "one", []byte("1a\n1b\n"))
+	gittest.CommitFile(t, repo, "two", []byte("two\n"))
+
+	// Nothing was merged yet.
+	upstream, squash, err := actions.SkipSquashMergedCommits(repo, "feature", base, "main")
+	require.NoError(t, err)
+	require.Equal(t, base, upstream)
+	require.Empty(t, squash)
+
+	// Squash the first two commits into main.
+	gittest.CheckoutBranch(t, repo, "main")
+	_, err = repo.Git("merge", "--squash", c2)
+	require.NoError(t, err)
+	_, err = repo.Git("commit", "-m", "Squashed")
+	require.NoError(t, err)
+	squashCommit, err := repo.RevParse(&git.RevParse{Rev: "HEAD"})
+	require.NoError(t, err)
+
+	upstream, squash, err = actions.SkipSquashMergedCommits(repo, "feature", base, "main")
+	require.NoError(t, err)
+	require.Equal(t, c2, upstream)
+	require.Equal(t, squashCommit, squash)
+}
@@ -0,0 +1,160 @@
+package actions
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+)
+
+// SquashPreviewOpts configures SquashPreview.
+type SquashPreviewOpts struct {
+	// The title of the pull request. It's only used if the branch has more
+	// than one commit.
+	Title string
+	// The number of the pull request (or zero if the branch doesn't have one).
+	Number int64
+}
+
+// SquashPreview creates (but doesn't check out or reference) the commit that
+// GitHub would create when squash-merging the pull request of the given branch
+// after the branches below it in the stack were squash-merged as well. The
+// branches are applied to the latest fetched commit of the trunk in memory (see
+// CheckSync), so the commit's parent is the (simulated) squash commit of the
+// parent branch and its diff contains exactly the changes of the branch.
+//
+// The commit message follows GitHub's default for squash merges (see
+// SquashCommitMessage).
+func SquashPreview(repo *git.Repo, tx meta.ReadTx, branchName string, opts SquashPreviewOpts) (string, error) {
+	if _, ok := tx.Branch(branchName); !ok {
+		return "", errors.Errorf("branch %q is not tracked by av", branchName)
+	}
+	previous, err := meta.PreviousBranches(tx, branchName)
+	if err != nil {
+		return "", err
+	}
+	trunk, ok := meta.Trunk(tx, branchName)
+	if !ok {
+		return "", errors.Errorf("failed to find the trunk branch for %q", branchName)
+	}
+	parent, err := latestParentCommit(repo, trunk, true)
+	if err != nil {
+		return "", err
+	}
+
+	commits, err := BranchCommits(repo, tx, branchName)
+	if err != nil {
+		return "", err
+	}
+	if len(commits) == 0 {
+		return "", errors.Errorf("branch %q doesn't have any commits", branchName)
+	}
+	msg, err := SquashCommitMessage(repo, commits, opts)
+	if err != nil {
+		return "", err
+	}
+
+	results, err := CheckSync(repo, tx, append(previous, branchName), SyncCheckOpts{ToTrunk: true})
+	if err != nil {
+		return "", err
+	}
+	for _, res := range results {
+		switch {
+		case res.BlockedBy != "":
+			return "", errors.Errorf("branch %q can't be applied because %q conflicts with its parent", res.Branch, res.BlockedBy)
+		case len(res.Conflicts) > 0:
+			return "", errors.Errorf(
+				"branch %q conflicts with %q (sync the stack first): %s",
+				res.Branch, res.Parent, strings.Join(res.Conflicts, ", "),
+			)
+		}
+		var squash string
+		if res.Branch == branchName {
+			squash = msg
+		} else {
+			squash = fmt.Sprintf("Squashed %s", res.Branch)
+		}
+		out, err := repo.Run(&git.RunOpts{
+			Args:      []string{"commit-tree", res.NewHead + "^{tree}", "-p", parent, "-F", "-"},
+			Stdin:     strings.NewReader(squash),
+			ExitError: true,
+		})
+		if err != nil {
+			return "", errors.WrapIff(err, "failed to create the squash commit of %q", res.Branch)
+		}
+		parent = strings.TrimSpace(string(out.Stdout))
+	}
+	return parent, nil
+}
+
+// SquashCommitMessage returns the commit message that GitHub creates by default
+// when squash-merging a pull request with the given commits (oldest first):
+// for a single commit, its message (with the pull request number appended to
+// the title); otherwise, the title of the pull request and a list of the
+// commit messages. The authors of the commits (other than the current Git
+// user) are credited with Co-authored-by trailers.
+func SquashCommitMessage(repo *git.Repo, commits []string, opts SquashPreviewOpts) (string, error) {
+	self, err := repo.Git("var", "GIT_AUTHOR_IDENT")
+	if err != nil {
+		return "", err
+	}
+	// GIT_AUTHOR_IDENT is "Name <email> timestamp timezone".
+	if i := strings.LastIndex(self, ">"); i >= 0 {
+		self = self[:i+1]
+	}
+
+	var messages, coAuthors []string
+	for _, commit := range commits {
+		out, err := repo.Run(&git.RunOpts{
+			Args:      []string{"log", "-1", "--format=%an <%ae>%n%B", commit},
+			ExitError: true,
+		})
+		if err != nil {
+			return "", errors.WrapIff(err, "failed to read commit %q", commit)
+		}
+		author, message, _ := strings.Cut(string(out.Stdout), "\n")
+		messages = append(messages, strings.TrimSpace(message))
+		if author != self && !slices.Contains(coAuthors, author) {
+			coAuthors = append(coAuthors, author)
+		}
+	}
+
+	var title, body string
+	if len(messages) == 1 {
+		title, body, _ = strings.Cut(messages[0], "\n")
+		body = strings.TrimSpace(body)
+	} else {
+		if opts.Title == "" {
+			return "", errors.New("the pull request title is required for a branch with more than one commit")
+		}
+		title = opts.Title
+		for i, message := range messages {
+			messages[i] = "* " + message
+		}
+		body = strings.Join(messages, "\n\n")
+	}
+	if opts.Number != 0 {
+		title = fmt.Sprintf("%s (#%d)", title, opts.Number)
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(title)
+	sb.WriteString("\n")
+	if body != "" {
+		sb.WriteString("\n")
+		sb.WriteString(body)
+		sb.WriteString("\n")
+	}
+	if len(coAuthors) > 0 {
+		sb.WriteString("\n---------\n\n")
+		for _, author := range coAuthors {
+			sb.WriteString("Co-authored-by: ")
+			sb.WriteString(author)
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String(), nil
+}
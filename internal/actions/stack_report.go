@@ -0,0 +1,260 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/shurcooL/githubv4"
+)
+
+// The formats that a stack report can be rendered in.
+const (
+	StackReportFormatText     = "text"
+	StackReportFormatMarkdown = "markdown"
+	StackReportFormatSlack    = "slack"
+)
+
+// StackReportOpts are the options for StackReport.
+type StackReportOpts struct {
+	// The time that the report is made at.
+	Now time.Time
+	// How long a branch can go without a new commit before it's stale.
+	StaleAfter time.Duration
+	// How long a stack can be behind its trunk before it needs to be synced.
+	UnsyncedAfter time.Duration
+}
+
+// StackReportStack is the state of a stack in a stack report.
+type StackReportStack struct {
+	// The root branch of the stack and the trunk it's based on.
+	Root  string
+	Trunk string
+	// When the trunk got the first commit that the stack doesn't contain (zero
+	// if the stack is up-to-date with the trunk).
+	UnsyncedSince time.Time
+	// True if the stack has been behind the trunk for too long.
+	Unsynced bool
+	// The branches of the stack that haven't been merged (starting with the
+	// root).
+	Branches []*StackReportBranch
+}
+
+// StackReportBranch is the state of a branch in a stack report.
+type StackReportBranch struct {
+	Name        string
+	PullRequest *meta.PullRequest
+	// When the head commit of the branch was committed.
+	LastActivity time.Time
+	// True if the branch hasn't had any activity for too long.
+	Stale bool
+	// What the owner of the branch needs to do for it (see
+	// PullRequestActions).
+	Actions []string
+}
+
+// NeedsAttention returns true if the stack or any of its branches needs
+// attention.
+func (s *StackReportStack) NeedsAttention() bool {
+	if s.Unsynced {
+		return true
+	}
+	for _, b := range s.Branches {
+		if b.NeedsAttention() {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsAttention returns true if the branch needs attention.
+func (b *StackReportBranch) NeedsAttention() bool {
+	return b.Stale || len(b.Actions) > 0
+}
+
+// StackReport returns the state of all stacks (that are based on a trunk) for
+// a report of the stacks that are rotting: branches without any activity and
+// stacks that haven't been synced with their trunk for a while. The actions
+// for the pull requests are left empty (see PullRequestActions).
+func StackReport(repo *git.Repo, tx meta.ReadTx, opts StackReportOpts) ([]*StackReportStack, error) {
+	var roots []meta.Branch
+	for _, branch := range tx.AllBranches() {
+		if branch.Parent.Trunk && !isMergedBranch(branch) {
+			roots = append(roots, branch)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].Name < roots[j].Name })
+
+	var stacks []*StackReportStack
+	for _, root := range roots {
+		stack := &StackReportStack{Root: root.Name, Trunk: root.Parent.Name}
+		for _, name := range append([]string{root.Name}, meta.SubsequentBranches(tx, root.Name)...) {
+			branch, _ := tx.Branch(name)
+			if isMergedBranch(branch) {
+				continue
+			}
+			out, err := repo.Run(&git.RunOpts{
+				Args: []string{"log", "-1", "--format=%ct", "refs/heads/" + name, "--"},
+			})
+			if err != nil {
+				return nil, err
+			}
+			if out.ExitCode != 0 {
+				// The branch was deleted outside of av.
+				continue
+			}
+			lastActivity, err := parseUnixTime(out.Stdout)
+			if err != nil {
+				return nil, errors.WrapIff(err, "failed to determine the last commit of %q", name)
+			}
+			stack.Branches = append(stack.Branches, &StackReportBranch{
+				Name:         name,
+				PullRequest:  branch.PullRequest,
+				LastActivity: lastActivity,
+				Stale:        opts.Now.Sub(lastActivity) >= opts.StaleAfter,
+			})
+		}
+		if len(stack.Branches) == 0 {
+			continue
+		}
+
+		// The oldest commit of the trunk that the stack doesn't contain yet.
+		out, err := repo.Run(&git.RunOpts{
+			Args: []string{
+				"log", "--format=%ct", "--reverse",
+				"refs/heads/" + root.Name + "..refs/remotes/origin/" + root.Parent.Name, "--",
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if out.ExitCode == 0 && len(out.Lines()) > 0 {
+			stack.UnsyncedSince, err = parseUnixTime([]byte(out.Lines()[0]))
+			if err != nil {
+				return nil, errors.WrapIff(err, "failed to determine the commits of %q", root.Parent.Name)
+			}
+			stack.Unsynced = opts.Now.Sub(stack.UnsyncedSince) >= opts.UnsyncedAfter
+		}
+		stacks = append(stacks, stack)
+	}
+	return stacks, nil
+}
+
+func isMergedBranch(branch meta.Branch) bool {
+	return branch.MergeCommit != "" ||
+		(branch.PullRequest != nil && branch.PullRequest.State == githubv4.PullRequestStateMerged)
+}
+
+func parseUnixTime(b []byte) (time.Time, error) {
+	sec, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// PullRequestActions returns what the author of the pull request needs to do
+// for it to make progress (e.g., address requested changes or land it).
+func PullRequestActions(pr *gh.PullRequest, checks *gh.PullRequestChecks) []string {
+	if pr.State != githubv4.PullRequestStateOpen {
+		return nil
+	}
+	var actions []string
+	if pr.ReviewDecision == githubv4.PullRequestReviewDecisionChangesRequested {
+		actions = append(actions, "address requested changes")
+	}
+	if checks.State() == gh.CheckStateFailure {
+		actions = append(actions, "fix failing checks")
+	}
+	if pr.Mergeable == githubv4.MergeableStateConflicting {
+		actions = append(actions, "resolve conflicts with "+pr.BaseBranchName())
+	}
+	if len(actions) == 0 && !pr.IsDraft && pr.ReviewDecision == githubv4.PullRequestReviewDecisionApproved &&
+		checks.State() == gh.CheckStateSuccess {
+		actions = append(actions, "approved, ready to land")
+	}
+	return actions
+}
+
+// RenderStackReport writes the stacks that need attention in the given format
+// (see StackReportFormat*).
+func RenderStackReport(w io.Writer, stacks []*StackReportStack, format string, now time.Time) error {
+	var attention []*StackReportStack
+	for _, stack := range stacks {
+		if stack.NeedsAttention() {
+			attention = append(attention, stack)
+		}
+	}
+
+	var header, stackItem, branchItem string
+	bold := func(s string) string { return s }
+	code := func(s string) string { return s }
+	link := func(text, url string) string { return text }
+	switch format {
+	case StackReportFormatText:
+		header, stackItem, branchItem = "Stacks that need attention", "", "  - "
+		bold = func(s string) string { return colors.UserInput(s) }
+	case StackReportFormatMarkdown:
+		header, stackItem, branchItem = "### Stacks that need attention", "- ", "  - "
+		bold = func(s string) string { return "**" + s + "**" }
+		code = func(s string) string { return "`" + s + "`" }
+		link = func(text, url string) string { return "[" + text + "](" + url + ")" }
+	case StackReportFormatSlack:
+		header, stackItem, branchItem = "*Stacks that need attention*", "• ", "    ◦ "
+		bold = func(s string) string { return "*" + s + "*" }
+		code = func(s string) string { return "`" + s + "`" }
+		link = func(text, url string) string { return "<" + url + "|" + text + ">" }
+	default:
+		return errors.Errorf("invalid report format %q (expected text, markdown, or slack)", format)
+	}
+
+	if len(attention) == 0 {
+		_, _ = fmt.Fprint(w, "No stacks need attention.\n")
+		return nil
+	}
+	_, _ = fmt.Fprint(w, header, "\n")
+	for _, stack := range attention {
+		_, _ = fmt.Fprint(w, stackItem, bold(stack.Root), " (onto ", code(stack.Trunk), ")")
+		if stack.Unsynced {
+			_, _ = fmt.Fprint(w, ": not synced with ", code(stack.Trunk), " for ",
+				formatDays(now.Sub(stack.UnsyncedSince)))
+		}
+		_, _ = fmt.Fprint(w, "\n")
+		for _, branch := range stack.Branches {
+			if !branch.NeedsAttention() {
+				continue
+			}
+			_, _ = fmt.Fprint(w, branchItem, code(branch.Name))
+			if pr := branch.PullRequest; pr != nil && pr.Number != 0 {
+				number := "#" + strconv.FormatInt(pr.Number, 10)
+				if pr.Permalink != "" {
+					number = link(number, pr.Permalink)
+				}
+				_, _ = fmt.Fprint(w, " ", number)
+			}
+			var notes []string
+			if branch.Stale {
+				notes = append(notes, "no activity for "+formatDays(now.Sub(branch.LastActivity)))
+			}
+			notes = append(notes, branch.Actions...)
+			_, _ = fmt.Fprint(w, ": ", strings.Join(notes, ", "), "\n")
+		}
+	}
+	return nil
+}
+
+func formatDays(d time.Duration) string {
+	days := int(d.Hours() / 24)
+	if days == 1 {
+		return "1 day"
+	}
+	return strconv.Itoa(days) + " days"
+}
@@ -0,0 +1,142 @@
+package actions_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/shurcooL/githubv4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackReport(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+
+	_, err = repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	one := gittest.CommitFile(t, repo, "one", []byte("one\n"))
+	_, err = repo.Git("checkout", "-b", "two")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "two", []byte("two\n"))
+	_, err = repo.Git("checkout", "-b", "merged", "main")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "merged", []byte("merged\n"))
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{Name: "one", Parent: meta.BranchState{Name: "main", Trunk: true}})
+	tx.SetBranch(meta.Branch{Name: "two", Parent: meta.BranchState{Name: "one", Head: one}})
+	tx.SetBranch(meta.Branch{
+		Name:        "merged",
+		Parent:      meta.BranchState{Name: "main", Trunk: true},
+		MergeCommit: "0123456789abcdef",
+	})
+
+	opts := actions.StackReportOpts{
+		Now:           time.Now(),
+		StaleAfter:    7 * 24 * time.Hour,
+		UnsyncedAfter: 3 * 24 * time.Hour,
+	}
+	stacks, err := actions.StackReport(repo, tx, opts)
+	require.NoError(t, err)
+	require.Len(t, stacks, 1)
+	require.Equal(t, "one", stacks[0].Root)
+	require.Equal(t, "main", stacks[0].Trunk)
+	require.True(t, stacks[0].UnsyncedSince.IsZero())
+	require.Len(t, stacks[0].Branches, 2)
+	require.False(t, stacks[0].NeedsAttention())
+
+	// The trunk moves on and a while later, nothing has happened.
+	gittest.CheckoutBranch(t, repo, "main")
+	gittest.CommitFile(t, repo, "main", []byte("main\n"))
+	_, err = repo.Git("push", "origin", "main")
+	require.NoError(t, err)
+	opts.Now = opts.Now.Add(10 * 24 * time.Hour)
+	stacks, err = actions.StackReport(repo, tx, opts)
+	require.NoError(t, err)
+	require.Len(t, stacks, 1)
+	require.False(t, stacks[0].UnsyncedSince.IsZero())
+	require.True(t, stacks[0].Unsynced)
+	for _, branch := range stacks[0].Branches {
+		require.True(t, branch.Stale, "branch %q should be stale", branch.Name)
+	}
+}
+
+func TestPullRequestActions(t *testing.T) {
+	checks := func(state gh.CheckState) *gh.PullRequestChecks {
+		return &gh.PullRequestChecks{Checks: []gh.Check{{Name: "build", State: state}}}
+	}
+	pr := &gh.PullRequest{
+		State:          githubv4.PullRequestStateOpen,
+		BaseRefName:    "main",
+		ReviewDecision: githubv4.PullRequestReviewDecisionChangesRequested,
+		Mergeable:      githubv4.MergeableStateConflicting,
+	}
+	require.Equal(t,
+		[]string{"address requested changes", "fix failing checks", "resolve conflicts with main"},
+		actions.PullRequestActions(pr, checks(gh.CheckStateFailure)),
+	)
+
+	pr = &gh.PullRequest{
+		State:          githubv4.PullRequestStateOpen,
+		ReviewDecision: githubv4.PullRequestReviewDecisionApproved,
+		Mergeable:      githubv4.MergeableStateMergeable,
+	}
+	require.Equal(t, []string{"approved, ready to land"}, actions.PullRequestActions(pr, checks(gh.CheckStateSuccess)))
+	require.Empty(t, actions.PullRequestActions(pr, checks(gh.CheckStatePending)))
+	pr.ReviewDecision = githubv4.PullRequestReviewDecisionReviewRequired
+	require.Empty(t, actions.PullRequestActions(pr, checks(gh.CheckStateSuccess)))
+}
+
+func TestRenderStackReport(t *testing.T) {
+	now := time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)
+	stacks := []*actions.StackReportStack{
+		{
+			Root:          "one",
+			Trunk:         "main",
+			UnsyncedSince: now.Add(-5 * 24 * time.Hour),
+			Unsynced:      true,
+			Branches: []*actions.StackReportBranch{
+				{
+					Name:         "one",
+					PullRequest:  &meta.PullRequest{Number: 12, Permalink: "https://github.com/o/r/pull/12"},
+					LastActivity: now.Add(-9 * 24 * time.Hour),
+					Stale:        true,
+					Actions:      []string{"address requested changes"},
+				},
+				{Name: "two", LastActivity: now},
+			},
+		},
+		{
+			Root:     "fine",
+			Trunk:    "main",
+			Branches: []*actions.StackReportBranch{{Name: "fine", LastActivity: now}},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, actions.RenderStackReport(&buf, stacks, actions.StackReportFormatMarkdown, now))
+	require.Equal(t, "### Stacks that need attention\n"+
+		"- **one** (onto `main`): not synced with `main` for 5 days\n"+
+		"  - `one` [#12](https://github.com/o/r/pull/12): no activity for 9 days, address requested changes\n",
+		buf.String())
+
+	buf.Reset()
+	require.NoError(t, actions.RenderStackReport(&buf, stacks, actions.StackReportFormatSlack, now))
+	require.Equal(t, "*Stacks that need attention*\n"+
+		"• *one* (onto `main`): not synced with `main` for 5 days\n"+
+		"    ◦ `one` <https://github.com/o/r/pull/12|#12>: no activity for 9 days, address requested changes\n",
+		buf.String())
+
+	buf.Reset()
+	require.NoError(t, actions.RenderStackReport(&buf, stacks[1:], actions.StackReportFormatText, now))
+	require.Equal(t, "No stacks need attention.\n", buf.String())
+	require.Error(t, actions.RenderStackReport(&buf, stacks, "html", now))
+}
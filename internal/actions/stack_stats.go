@@ -0,0 +1,266 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+)
+
+// StackStats are the metrics of a stack (see `av stack stats`).
+type StackStats struct {
+	// The root branch of the stack and the trunk it's based on.
+	Root  string `json:"root"`
+	Trunk string `json:"trunk"`
+	// The branches of the stack (starting with the root), including the
+	// merged branches that are still tracked. Merged branches that were pruned
+	// (see `av stack sync --prune`) aren't tracked anymore, so they're not
+	// included (and neither is their time-to-merge).
+	Branches []*StackStatsBranch `json:"branches"`
+}
+
+// StackStatsBranch are the metrics of a branch of a stack.
+type StackStatsBranch struct {
+	Name        string            `json:"name"`
+	PullRequest *meta.PullRequest `json:"pullRequest,omitempty"`
+	Merged      bool              `json:"merged"`
+	// The number of commits of the branch (on top of its parent) and the size
+	// of its diff. These are zero for merged branches whose commits are gone.
+	Commits      int `json:"commits"`
+	LinesAdded   int `json:"linesAdded"`
+	LinesDeleted int `json:"linesDeleted"`
+	// When the first commit of the branch was authored (zero if unknown).
+	FirstCommit time.Time `json:"firstCommit,omitempty"`
+	// How long the pull request of a merged branch was open before it was
+	// merged (zero if unknown).
+	TimeToMerge time.Duration `json:"timeToMerge,omitempty"`
+}
+
+// DiffSize returns the number of changed lines of the branch.
+func (b *StackStatsBranch) DiffSize() int {
+	return b.LinesAdded + b.LinesDeleted
+}
+
+// Merged returns the number of merged branches of the stack.
+func (s *StackStats) Merged() int {
+	n := 0
+	for _, b := range s.Branches {
+		if b.Merged {
+			n++
+		}
+	}
+	return n
+}
+
+// Commits returns the total number of commits of the branches of the stack.
+func (s *StackStats) Commits() int {
+	n := 0
+	for _, b := range s.Branches {
+		n += b.Commits
+	}
+	return n
+}
+
+// Created returns when the first commit of the stack was authored (zero if
+// unknown).
+func (s *StackStats) Created() time.Time {
+	var created time.Time
+	for _, b := range s.Branches {
+		if !b.FirstCommit.IsZero() && (created.IsZero() || b.FirstCommit.Before(created)) {
+			created = b.FirstCommit
+		}
+	}
+	return created
+}
+
+// StackStatsFor returns the metrics of all stacks (that are based on a trunk)
+// from the local repository. The time-to-merge of merged branches is left
+// empty since it needs to be looked up on GitHub.
+func StackStatsFor(repo *git.Repo, tx meta.ReadTx) ([]*StackStats, error) {
+	var roots []meta.Branch
+	for _, branch := range tx.AllBranches() {
+		if branch.Parent.Trunk {
+			roots = append(roots, branch)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].Name < roots[j].Name })
+
+	var stacks []*StackStats
+	for _, root := range roots {
+		stack := &StackStats{Root: root.Name, Trunk: root.Parent.Name}
+		for _, name := range append([]string{root.Name}, meta.SubsequentBranches(tx, root.Name)...) {
+			branch, _ := tx.Branch(name)
+			stats := &StackStatsBranch{
+				Name:        name,
+				PullRequest: branch.PullRequest,
+				Merged:      isMergedBranch(branch),
+			}
+			stack.Branches = append(stack.Branches, stats)
+			if exists, err := repo.DoesBranchExist(name); err != nil {
+				return nil, err
+			} else if !exists {
+				continue
+			}
+			commits, err := BranchCommits(repo, tx, name)
+			if err != nil {
+				// E.g., the trunk of a merged branch was never fetched.
+				continue
+			}
+			stats.Commits = len(commits)
+			if len(commits) == 0 {
+				continue
+			}
+			out, err := repo.Run(&git.RunOpts{
+				Args:      []string{"log", "-1", "--format=%at", commits[0], "--"},
+				ExitError: true,
+			})
+			if err != nil {
+				return nil, err
+			}
+			stats.FirstCommit, err = parseUnixTime(out.Stdout)
+			if err != nil {
+				return nil, errors.WrapIff(err, "failed to determine the first commit of %q", name)
+			}
+			base, err := commitParentOrEmptyTree(repo, commits[0])
+			if err != nil {
+				return nil, err
+			}
+			out, err = repo.Run(&git.RunOpts{
+				Args:      []string{"diff", "--numstat", base, "refs/heads/" + name, "--"},
+				ExitError: true,
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, line := range out.Lines() {
+				// Binary files are listed with "-" instead of line counts.
+				fields := strings.Fields(line)
+				if len(fields) < 2 {
+					continue
+				}
+				added, _ := strconv.Atoi(fields[0])
+				deleted, _ := strconv.Atoi(fields[1])
+				stats.LinesAdded += added
+				stats.LinesDeleted += deleted
+			}
+		}
+		stacks = append(stacks, stack)
+	}
+	return stacks, nil
+}
+
+// commitParentOrEmptyTree returns the (first) parent of the given commit, or
+// the empty tree if it's a root commit (so that diffing against it shows
+// everything that the commit added).
+func commitParentOrEmptyTree(repo *git.Repo, commit string) (string, error) {
+	out, err := repo.Run(&git.RunOpts{
+		Args: []string{"rev-parse", "--verify", "--quiet", commit + "~"},
+	})
+	if err != nil {
+		return "", err
+	}
+	if out.ExitCode == 0 {
+		return strings.TrimSpace(string(out.Stdout)), nil
+	}
+	out, err = repo.Run(&git.RunOpts{
+		Args:      []string{"hash-object", "-t", "tree", "--stdin"},
+		Stdin:     strings.NewReader(""),
+		ExitError: true,
+	})
+	if err != nil {
+		return "", errors.WrapIff(err, "failed to determine the empty tree")
+	}
+	return strings.TrimSpace(string(out.Stdout)), nil
+}
+
+// RenderStackStats writes the metrics of the given stacks, followed by a
+// summary of all of them.
+func RenderStackStats(w io.Writer, stacks []*StackStats, now time.Time) {
+	if len(stacks) == 0 {
+		_, _ = fmt.Fprint(w, "No stacks.\n")
+		return
+	}
+	var allBranches []*StackStatsBranch
+	for _, stack := range stacks {
+		allBranches = append(allBranches, stack.Branches...)
+		_, _ = fmt.Fprintf(w, "%s (onto %s)\n", stack.Root, stack.Trunk)
+		_, _ = fmt.Fprintf(w, "  branches:      %d (%d merged)\n", len(stack.Branches), stack.Merged())
+		_, _ = fmt.Fprintf(w, "  commits:       %d\n", stack.Commits())
+		_, _ = fmt.Fprintf(w, "  diff size:     %s\n", formatDiffSizes(stack.Branches))
+		if created := stack.Created(); !created.IsZero() {
+			_, _ = fmt.Fprintf(w, "  age:           %s\n", formatDuration(now.Sub(created)))
+		}
+		if ttm := formatTimeToMerge(stack.Branches); ttm != "" {
+			_, _ = fmt.Fprintf(w, "  time to merge: %s\n", ttm)
+		}
+	}
+
+	_, _ = fmt.Fprintf(w, "\nAll stacks (%d)\n", len(stacks))
+	_, _ = fmt.Fprintf(w, "  branches per stack: %.1f\n", float64(len(allBranches))/float64(len(stacks)))
+	_, _ = fmt.Fprintf(w, "  diff size:          %s\n", formatDiffSizes(allBranches))
+	if ttm := formatTimeToMerge(allBranches); ttm != "" {
+		_, _ = fmt.Fprintf(w, "  time to merge:      %s\n", ttm)
+	}
+}
+
+// formatDiffSizes formats the total diff size of the given branches along with
+// the average and the largest diff size of a branch. Branches without commits
+// (e.g., merged branches that were deleted) are ignored.
+func formatDiffSizes(branches []*StackStatsBranch) string {
+	var added, deleted, largest, n int
+	for _, b := range branches {
+		if b.Commits == 0 {
+			continue
+		}
+		added += b.LinesAdded
+		deleted += b.LinesDeleted
+		largest = max(largest, b.DiffSize())
+		n++
+	}
+	if n == 0 {
+		return "-"
+	}
+	return fmt.Sprintf(
+		"+%d -%d (%d lines per branch on average, %d at most)",
+		added, deleted, (added+deleted)/n, largest,
+	)
+}
+
+// formatTimeToMerge formats the median time-to-merge of the given branches (or
+// returns an empty string if none of them has one).
+func formatTimeToMerge(branches []*StackStatsBranch) string {
+	var durations []time.Duration
+	for _, b := range branches {
+		if b.TimeToMerge > 0 {
+			durations = append(durations, b.TimeToMerge)
+		}
+	}
+	if len(durations) == 0 {
+		return ""
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	median := durations[len(durations)/2]
+	if len(durations)%2 == 0 {
+		median = (durations[len(durations)/2-1] + median) / 2
+	}
+	return fmt.Sprintf("%s (median of %d merged)", formatDuration(median), len(durations))
+}
+
+// formatDuration formats the duration in days (or hours, if it's shorter than
+// a day).
+func formatDuration(d time.Duration) string {
+	if d < 24*time.Hour {
+		hours := int(d.Hours())
+		if hours == 1 {
+			return "1 hour"
+		}
+		return strconv.Itoa(hours) + " hours"
+	}
+	return formatDays(d)
+}
@@ -0,0 +1,94 @@
+package actions_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackStats(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+
+	_, err = repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	one := gittest.CommitFile(t, repo, "one", []byte("1\n2\n3\n"))
+	_, err = repo.Git("checkout", "-b", "two")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "one", []byte("1\n3\n"))
+	gittest.CommitFile(t, repo, "two", []byte("two\n"))
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{Name: "one", Parent: meta.BranchState{Name: "main", Trunk: true}})
+	tx.SetBranch(meta.Branch{Name: "two", Parent: meta.BranchState{Name: "one", Head: one}})
+	// Merged branches whose commits are gone still count.
+	tx.SetBranch(meta.Branch{
+		Name:        "merged",
+		Parent:      meta.BranchState{Name: "main", Trunk: true},
+		MergeCommit: "0123456789abcdef",
+	})
+
+	stacks, err := actions.StackStatsFor(repo, tx)
+	require.NoError(t, err)
+	require.Len(t, stacks, 2)
+
+	merged := stacks[0]
+	require.Equal(t, "merged", merged.Root)
+	require.Equal(t, 1, merged.Merged())
+	require.Equal(t, 0, merged.Commits())
+
+	stack := stacks[1]
+	require.Equal(t, "one", stack.Root)
+	require.Equal(t, "main", stack.Trunk)
+	require.Equal(t, 0, stack.Merged())
+	require.Equal(t, 3, stack.Commits())
+	require.Len(t, stack.Branches, 2)
+	require.Equal(t, 3, stack.Branches[0].LinesAdded)
+	require.Equal(t, 1, stack.Branches[1].LinesAdded)
+	require.Equal(t, 1, stack.Branches[1].LinesDeleted)
+	require.False(t, stack.Created().IsZero())
+
+	merged.Branches[0].TimeToMerge = 3 * 24 * time.Hour
+	var out bytes.Buffer
+	actions.RenderStackStats(&out, stacks, stack.Created().Add(2*time.Hour))
+	require.Contains(t, out.String(), "one (onto main)\n")
+	require.Contains(t, out.String(), "  commits:       3\n")
+	require.Contains(t, out.String(), "  diff size:     +4 -1 (2 lines per branch on average, 3 at most)\n")
+	require.Contains(t, out.String(), "  age:           2 hours\n")
+	require.Contains(t, out.String(), "  time to merge:      3 days (median of 1 merged)\n")
+}
+
+func TestStackStatsRootCommit(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+	mainHead, err := repo.RevParse(&git.RevParse{Rev: "HEAD"})
+	require.NoError(t, err)
+
+	// A branch that doesn't share any history with its parent, so its first
+	// commit is a root commit.
+	_, err = repo.Git("checkout", "--orphan", "orphan")
+	require.NoError(t, err)
+	_, err = repo.Git("rm", "-rf", "--cached", ".")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "orphan", []byte("1\n2\n"))
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{Name: "orphan", Parent: meta.BranchState{Name: "main", Trunk: true, Head: mainHead}})
+
+	stacks, err := actions.StackStatsFor(repo, tx)
+	require.NoError(t, err)
+	require.Len(t, stacks, 1)
+	require.Equal(t, 1, stacks[0].Commits())
+	require.Equal(t, 2, stacks[0].Branches[0].LinesAdded)
+}
@@ -0,0 +1,288 @@
+package actions
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/sirupsen/logrus"
+)
+
+// The trailers that StampStackTrailers adds to the head commit of a branch.
+// CI pipelines can read them (e.g., with
+// `git log -1 --format='%(trailers:key=Av-Parent-Commit,valueonly)'`) to only
+// test the changes that a branch makes on top of its parent.
+//
+// Git matches trailers by prefix when replacing them, so none of the keys may
+// be a prefix of another.
+const (
+	// The name of the parent branch.
+	StackTrailerParent = "Av-Parent-Branch"
+	// The commit of the parent branch that the branch is based on.
+	StackTrailerParentHead = "Av-Parent-Commit"
+	// The position of the branch in the stack (1 for the root of the stack).
+	StackTrailerDepth = "Av-Stack-Depth"
+)
+
+// StampStackTrailers makes sure that the head commit of the given branch has
+// trailers (see StackTrailerParent etc.) that identify the position of the
+// branch in the stack and the commit of the parent that it's based on. If the
+// trailers are missing or outdated, the head commit is re-created with the
+// updated trailers (keeping its author and content). Returns true if the
+// branch was updated.
+func StampStackTrailers(repo *git.Repo, tx meta.ReadTx, branchName string) (bool, error) {
+	branch, ok := tx.Branch(branchName)
+	if !ok || branch.Parent.Name == "" {
+		return false, nil
+	}
+	head, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + branchName})
+	if err != nil {
+		return false, errors.WrapIff(err, "failed to determine HEAD for branch %q", branchName)
+	}
+	parentHead := branch.Parent.Head
+	if branch.Parent.Trunk {
+		parentHead, err = repo.MergeBase(&git.MergeBase{
+			Revs: []string{head, "refs/remotes/origin/" + branch.Parent.Name},
+		})
+		if err != nil {
+			return false, errors.WrapIff(err, "failed to determine the merge base of %q and its trunk", branchName)
+		}
+	} else if parentHead == "" {
+		parentHead, err = repo.RevParse(&git.RevParse{Rev: "refs/heads/" + branch.Parent.Name})
+		if err != nil {
+			return false, errors.WrapIff(err, "failed to determine HEAD for branch %q", branch.Parent.Name)
+		}
+	}
+	if parentHead == head {
+		// The branch doesn't have any commits of its own (yet).
+		return false, nil
+	}
+	previous, err := meta.PreviousBranches(tx, branchName)
+	if err != nil {
+		return false, err
+	}
+
+	out, err := repo.Run(&git.RunOpts{
+		Args:      []string{"log", "-1", "--format=%B", head},
+		ExitError: true,
+	})
+	if err != nil {
+		return false, errors.WrapIff(err, "failed to read the head commit of %q", branchName)
+	}
+	msg := strings.TrimSpace(string(out.Stdout)) + "\n"
+	out, err = repo.Run(&git.RunOpts{
+		Args: []string{
+			"interpret-trailers", "--if-exists", "replace",
+			"--trailer", StackTrailerParent + ": " + branch.Parent.Name,
+			"--trailer", StackTrailerParentHead + ": " + parentHead,
+			"--trailer", StackTrailerDepth + ": " + strconv.Itoa(len(previous)+1),
+		},
+		Stdin:     strings.NewReader(msg),
+		ExitError: true,
+	})
+	if err != nil {
+		return false, errors.WrapIf(err, "failed to add the stack trailers")
+	}
+	newMsg := strings.TrimSpace(string(out.Stdout)) + "\n"
+	if newMsg == msg {
+		return false, nil
+	}
+
+	// Re-create the commit with the new message. Git only allows amending the
+	// commit that's checked out, so this uses commit-tree instead.
+	newHead, err := recreateCommit(repo, head, newMsg, nil)
+	if err != nil {
+		return false, errors.WrapIff(err, "failed to update the head commit of %q", branchName)
+	}
+	if err := repo.UpdateRef(&git.UpdateRef{
+		Ref: "refs/heads/" + branchName,
+		New: newHead,
+		Old: head,
+	}); err != nil {
+		return false, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"branch":   branchName,
+		"old_head": head,
+		"new_head": newHead,
+	}).Debug("stamped stack trailers onto head commit")
+	return true, nil
+}
+
+// The trailers that WithCommitTrailers adds to the commits that av creates (if
+// stack.commitTrailers is set). Unlike the trailers above, they're added to
+// every commit of a branch, so they survive squash-merges.
+const (
+	// The name of the branch that the commit was made on.
+	CommitTrailerBranch = "Stack-Branch"
+	// The name of the parent of that branch.
+	CommitTrailerParent = "Stack-Parent"
+)
+
+// WithCommitTrailers returns the arguments of a `git commit` invocation
+// (starting with "commit") that also adds the Stack-Branch and Stack-Parent
+// trailers (see CommitTrailerBranch) for the given branch to the commit.
+// Existing trailers (e.g., of an amended commit) are replaced. The arguments are
+// returned unchanged if stack.commitTrailers isn't set or if the branch isn't
+// tracked by av.
+func WithCommitTrailers(tx meta.ReadTx, branchName string, commitArgs []string) []string {
+	if !config.Av.Stack.CommitTrailers {
+		return commitArgs
+	}
+	branch, ok := tx.Branch(branchName)
+	if !ok || branch.Parent.Name == "" {
+		return commitArgs
+	}
+	args := []string{"-c", "trailer.ifexists=replace"}
+	args = append(args, commitArgs...)
+	return append(args,
+		"--trailer", CommitTrailerBranch+": "+branchName,
+		"--trailer", CommitTrailerParent+": "+branch.Parent.Name,
+	)
+}
+
+// RestampCommitTrailers updates the Stack-Branch and Stack-Parent trailers (see
+// WithCommitTrailers) of the commits of the given branch whose trailers are
+// outdated, e.g., because the branch was moved onto another parent or renamed.
+// Commits without these trailers are left alone. The commits are re-created
+// (keeping their authors and contents) from the first outdated one. Does
+// nothing if stack.commitTrailers isn't set. Returns true if the branch was
+// updated.
+func RestampCommitTrailers(repo *git.Repo, tx meta.ReadTx, branchName string) (bool, error) {
+	if !config.Av.Stack.CommitTrailers {
+		return false, nil
+	}
+	branch, ok := tx.Branch(branchName)
+	if !ok || branch.Parent.Name == "" {
+		return false, nil
+	}
+	base, err := BranchBase(repo, tx, branchName)
+	if err != nil {
+		return false, err
+	}
+	head, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + branchName})
+	if err != nil {
+		return false, errors.WrapIff(err, "failed to determine HEAD for branch %q", branchName)
+	}
+	out, err := repo.Run(&git.RunOpts{
+		Args: []string{
+			"log", "--reverse", "--topo-order",
+			"--format=%H%x00" +
+				"%(trailers:key=" + CommitTrailerBranch + ",valueonly,separator=%x2C)%x00" +
+				"%(trailers:key=" + CommitTrailerParent + ",valueonly,separator=%x2C)%x1e",
+			base + ".." + head,
+		},
+		ExitError: true,
+	})
+	if err != nil {
+		return false, errors.WrapIff(err, "failed to read the commits of %q", branchName)
+	}
+
+	// The re-created commits by the commits that they replace.
+	recreated := make(map[string]string)
+	for _, record := range strings.Split(string(out.Stdout), "\x1e") {
+		fields := strings.Split(strings.TrimSpace(record), "\x00")
+		if len(fields) != 3 {
+			continue
+		}
+		commit, trailerBranch, trailerParent := fields[0], fields[1], fields[2]
+		outdated := (trailerBranch != "" || trailerParent != "") &&
+			(trailerBranch != branchName || trailerParent != branch.Parent.Name)
+		parents, err := repo.Git("log", "-1", "--format=%P", commit)
+		if err != nil {
+			return false, err
+		}
+		parentChanged := slices.ContainsFunc(strings.Fields(parents), func(p string) bool {
+			return recreated[p] != ""
+		})
+		if !outdated && !parentChanged {
+			continue
+		}
+
+		msg, err := repo.Git("log", "-1", "--format=%B", commit)
+		if err != nil {
+			return false, err
+		}
+		msg = strings.TrimSpace(msg) + "\n"
+		if outdated {
+			res, err := repo.Run(&git.RunOpts{
+				Args: []string{
+					"interpret-trailers", "--if-exists", "replace",
+					"--trailer", CommitTrailerBranch + ": " + branchName,
+					"--trailer", CommitTrailerParent + ": " + branch.Parent.Name,
+				},
+				Stdin:     strings.NewReader(msg),
+				ExitError: true,
+			})
+			if err != nil {
+				return false, errors.WrapIf(err, "failed to update the commit trailers")
+			}
+			msg = strings.TrimSpace(string(res.Stdout)) + "\n"
+		}
+		newCommit, err := recreateCommit(repo, commit, msg, recreated)
+		if err != nil {
+			return false, errors.WrapIff(err, "failed to update commit %s of %q", git.ShortSha(commit), branchName)
+		}
+		recreated[commit] = newCommit
+	}
+
+	newHead := recreated[head]
+	if newHead == "" {
+		return false, nil
+	}
+	if err := repo.UpdateRef(&git.UpdateRef{
+		Ref: "refs/heads/" + branchName,
+		New: newHead,
+		Old: head,
+	}); err != nil {
+		return false, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"branch":   branchName,
+		"old_head": head,
+		"new_head": newHead,
+	}).Debug("updated the commit trailers of the branch")
+	return true, nil
+}
+
+// recreateCommit creates a copy of the given commit (with the same tree and
+// author) with the given message. The parents of the commit are replaced with
+// their entries in the given map, if any.
+func recreateCommit(repo *git.Repo, commit string, msg string, parents map[string]string) (string, error) {
+	out, err := repo.Run(&git.RunOpts{
+		Args:      []string{"log", "-1", "--format=%T%n%P%n%an%n%ae%n%ad", "--date=raw", commit},
+		ExitError: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(strings.TrimSuffix(string(out.Stdout), "\n"), "\n")
+	if len(lines) != 5 {
+		return "", errors.Errorf("failed to parse commit %s", commit)
+	}
+	args := []string{"commit-tree", lines[0], "-F", "-"}
+	for _, p := range strings.Fields(lines[1]) {
+		if newParent := parents[p]; newParent != "" {
+			p = newParent
+		}
+		args = append(args, "-p", p)
+	}
+	out, err = repo.Run(&git.RunOpts{
+		Args: args,
+		Env: []string{
+			"GIT_AUTHOR_NAME=" + lines[2],
+			"GIT_AUTHOR_EMAIL=" + lines[3],
+			"GIT_AUTHOR_DATE=" + lines[4],
+		},
+		Stdin:     strings.NewReader(msg),
+		ExitError: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out.Stdout)), nil
+}
@@ -0,0 +1,144 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStampStackTrailers(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+	base, err := repo.RevParse(&git.RevParse{Rev: "main"})
+	require.NoError(t, err)
+
+	_, err = repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	one := gittest.CommitFile(t, repo, "one", []byte("one\n"))
+	_, err = repo.Git("checkout", "-b", "two")
+	require.NoError(t, err)
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{Name: "one", Parent: meta.BranchState{Name: "main", Trunk: true}})
+	tx.SetBranch(meta.Branch{Name: "two", Parent: meta.BranchState{Name: "one", Head: one}})
+
+	// A branch without commits of its own isn't stamped.
+	stamped, err := actions.StampStackTrailers(repo, tx, "two")
+	require.NoError(t, err)
+	require.False(t, stamped)
+
+	gittest.CommitFile(t, repo, "two", []byte("two\n"))
+	trailer := func(branch, key string) string {
+		out, err := repo.Git("log", "-1", "--format=%(trailers:key="+key+",valueonly)", "refs/heads/"+branch)
+		require.NoError(t, err)
+		return out
+	}
+	stamped, err = actions.StampStackTrailers(repo, tx, "two")
+	require.NoError(t, err)
+	require.True(t, stamped)
+	require.Equal(t, "one", trailer("two", actions.StackTrailerParent))
+	require.Equal(t, one, trailer("two", actions.StackTrailerParentHead))
+	require.Equal(t, "2", trailer("two", actions.StackTrailerDepth))
+	// The content of the branch and the checked out branch are unchanged.
+	_, err = repo.Git("diff", "--quiet", "HEAD")
+	require.NoError(t, err)
+	current, err := repo.CurrentBranchName()
+	require.NoError(t, err)
+	require.Equal(t, "two", current)
+
+	// Stamping is idempotent.
+	stamped, err = actions.StampStackTrailers(repo, tx, "two")
+	require.NoError(t, err)
+	require.False(t, stamped)
+
+	// The root of the stack is based on the trunk.
+	stamped, err = actions.StampStackTrailers(repo, tx, "one")
+	require.NoError(t, err)
+	require.True(t, stamped)
+	require.Equal(t, base, trailer("one", actions.StackTrailerParentHead))
+	require.Equal(t, "1", trailer("one", actions.StackTrailerDepth))
+}
+
+func TestWithCommitTrailers(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+	_, err = repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{Name: "one", Parent: meta.BranchState{Name: "main", Trunk: true}})
+
+	args := []string{"commit", "--allow-empty", "--message", "Empty"}
+	require.Equal(t, args, actions.WithCommitTrailers(tx, "one", args), "disabled by default")
+
+	commitTrailers := config.Av.Stack.CommitTrailers
+	config.Av.Stack.CommitTrailers = true
+	defer func() { config.Av.Stack.CommitTrailers = commitTrailers }()
+	require.Equal(t, args, actions.WithCommitTrailers(tx, "main", args), "untracked branch")
+
+	trailers := func() string {
+		out, err := repo.Git("log", "-1", "--format=%(trailers:only)", "HEAD")
+		require.NoError(t, err)
+		return out
+	}
+	_, err = repo.Git(actions.WithCommitTrailers(tx, "one", args)...)
+	require.NoError(t, err)
+	require.Equal(t, "Stack-Branch: one\nStack-Parent: main", trailers())
+
+	// Amending the commit replaces the trailers instead of adding new ones.
+	tx.SetBranch(meta.Branch{Name: "one", Parent: meta.BranchState{Name: "zero"}})
+	_, err = repo.Git(actions.WithCommitTrailers(tx, "one", []string{"commit", "--amend", "--allow-empty", "--no-edit"})...)
+	require.NoError(t, err)
+	require.Equal(t, "Stack-Branch: one\nStack-Parent: zero", trailers())
+}
+
+func TestRestampCommitTrailers(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+	_, err = repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+
+	commitTrailers := config.Av.Stack.CommitTrailers
+	config.Av.Stack.CommitTrailers = true
+	defer func() { config.Av.Stack.CommitTrailers = commitTrailers }()
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{Name: "one", Parent: meta.BranchState{Name: "main", Trunk: true}})
+	for _, msg := range []string{"First", "Second"} {
+		_, err = repo.Git(actions.WithCommitTrailers(tx, "one", []string{"commit", "--allow-empty", "-m", msg})...)
+		require.NoError(t, err)
+	}
+	// A commit that wasn't created by av doesn't get the trailers.
+	gittest.CommitFile(t, repo, "one", []byte("one\n"), gittest.WithMessage("Third"))
+
+	restamped, err := actions.RestampCommitTrailers(repo, tx, "one")
+	require.NoError(t, err)
+	require.False(t, restamped, "the trailers are up-to-date")
+
+	// The branch is moved onto another parent (without any commits of its own).
+	base, err := repo.RevParse(&git.RevParse{Rev: "main"})
+	require.NoError(t, err)
+	_, err = repo.Git("branch", "zero", base)
+	require.NoError(t, err)
+	tx.SetBranch(meta.Branch{Name: "one", Parent: meta.BranchState{Name: "zero", Head: base}})
+	restamped, err = actions.RestampCommitTrailers(repo, tx, "one")
+	require.NoError(t, err)
+	require.True(t, restamped)
+	out, err := repo.Git("log", "--format=%s:%(trailers:key=Stack-Parent,valueonly,separator=)", "main..one")
+	require.NoError(t, err)
+	require.Equal(t, "Third:\nSecond:zero\nFirst:zero", out)
+	_, err = repo.Git("diff", "--quiet", "HEAD")
+	require.NoError(t, err)
+}
@@ -0,0 +1,137 @@
+package actions
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+)
+
+// ParseSuggestion returns the suggested replacement in the body of a review
+// comment (i.e., the contents of its ```suggestion block). An empty suggestion
+// means that the lines should be removed. Returns false if the comment doesn't
+// contain a suggestion.
+func ParseSuggestion(body string) (string, bool) {
+	lines := strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+	start := slices.IndexFunc(lines, func(line string) bool {
+		return strings.TrimSpace(line) == "```suggestion"
+	})
+	if start == -1 {
+		return "", false
+	}
+	var res strings.Builder
+	for _, line := range lines[start+1:] {
+		if strings.TrimSpace(line) == "```" {
+			return res.String(), true
+		}
+		res.WriteString(line)
+		res.WriteString("\n")
+	}
+	// The block isn't closed.
+	return "", false
+}
+
+// ApplySuggestion replaces the lines of the content that a suggestion was made
+// on with the suggestion. The original lines are the lines of the file that
+// the suggestion was made on, starting at the given (1-based) line. If the
+// content doesn't contain the original lines at the same position anymore
+// (e.g., because lines above them were changed since), they're searched for in
+// the rest of the content, and the suggestion is only applied if they occur
+// exactly once. Returns the updated content and the (1-based) line at which the
+// original lines were found.
+func ApplySuggestion(content string, original string, start int, suggestion string) (string, int, error) {
+	lines := strings.SplitAfter(content, "\n")
+	originalLines := strings.SplitAfter(strings.TrimSuffix(original, "\n"), "\n")
+	matchesAt := func(i int) bool {
+		if i < 0 || i+len(originalLines) > len(lines) {
+			return false
+		}
+		for j, line := range originalLines {
+			if strings.TrimSuffix(lines[i+j], "\n") != strings.TrimSuffix(line, "\n") {
+				return false
+			}
+		}
+		return true
+	}
+
+	at := start - 1
+	if !matchesAt(at) {
+		at = -1
+		for i := range lines {
+			if !matchesAt(i) {
+				continue
+			}
+			if at != -1 {
+				return "", 0, errors.New("the suggested lines were changed and occur more than once in the file")
+			}
+			at = i
+		}
+		if at == -1 {
+			return "", 0, errors.New("the suggested lines were changed since the suggestion was made")
+		}
+	}
+
+	end := at + len(originalLines)
+	if !strings.HasSuffix(lines[end-1], "\n") {
+		// The last line of the file doesn't end with a newline, so neither
+		// should the suggestion.
+		suggestion = strings.TrimSuffix(suggestion, "\n")
+	}
+	var res strings.Builder
+	for _, line := range lines[:at] {
+		res.WriteString(line)
+	}
+	res.WriteString(suggestion)
+	for _, line := range lines[end:] {
+		res.WriteString(line)
+	}
+	return res.String(), at + 1, nil
+}
+
+// SuggestionFixupTarget returns the commit of the given branch that most
+// recently changed the given (1-based, inclusive) lines of the file at the head
+// of the branch. This is the commit that a fixup for a suggestion on these
+// lines should be squashed into.
+func SuggestionFixupTarget(
+	repo *git.Repo,
+	tx meta.ReadTx,
+	branchName string,
+	path string,
+	start, end int,
+) (string, error) {
+	commits, err := BranchCommits(repo, tx, branchName)
+	if err != nil {
+		return "", err
+	}
+	out, err := repo.Run(&git.RunOpts{
+		Args: []string{
+			"blame", "--porcelain", "-L", strconv.Itoa(start) + "," + strconv.Itoa(end),
+			"refs/heads/" + branchName, "--", path,
+		},
+		ExitError: true,
+	})
+	if err != nil {
+		return "", errors.WrapIff(err, "failed to determine the commits that changed %s", path)
+	}
+	target := -1
+	for _, line := range out.Lines() {
+		// The header of each blamed line starts with the full commit hash.
+		fields := strings.Fields(line)
+		if len(fields) < 3 || len(fields[0]) != 40 {
+			continue
+		}
+		if i := slices.Index(commits, fields[0]); i > target {
+			target = i
+		}
+	}
+	if target == -1 {
+		return "", errors.Errorf(
+			"the suggested lines weren't changed by the commits of %q (so there's nothing to fix up)",
+			branchName,
+		)
+	}
+	return commits[target], nil
+}
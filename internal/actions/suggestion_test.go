@@ -0,0 +1,134 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSuggestion(t *testing.T) {
+	suggestion, ok := actions.ParseSuggestion("Maybe:\r\n```suggestion\r\nfoo := 1\r\nbar := 2\r\n```\r\nWDYT?")
+	require.True(t, ok)
+	require.Equal(t, "foo := 1\nbar := 2\n", suggestion)
+
+	// An empty suggestion removes the lines.
+	suggestion, ok = actions.ParseSuggestion("```suggestion\n```")
+	require.True(t, ok)
+	require.Equal(t, "", suggestion)
+
+	_, ok = actions.ParseSuggestion("```go\nfoo := 1\n```")
+	require.False(t, ok)
+	_, ok = actions.ParseSuggestion("```suggestion\nfoo := 1\n")
+	require.False(t, ok)
+}
+
+func TestApplySuggestion(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		content    string
+		original   string
+		start      int
+		suggestion string
+		want       string
+		wantLine   int
+		wantErr    bool
+	}{
+		{
+			name:       "unchanged",
+			content:    "a\nb\nc\nd\n",
+			original:   "b\nc\n",
+			start:      2,
+			suggestion: "x\n",
+			want:       "a\nx\nd\n",
+			wantLine:   2,
+		},
+		{
+			name:       "moved",
+			content:    "new\na\nb\nc\nd\n",
+			original:   "b\nc\n",
+			start:      2,
+			suggestion: "x\ny\nz\n",
+			want:       "new\na\nx\ny\nz\nd\n",
+			wantLine:   3,
+		},
+		{
+			name:       "removed lines",
+			content:    "a\nb\nc\n",
+			original:   "b\n",
+			start:      2,
+			suggestion: "",
+			want:       "a\nc\n",
+			wantLine:   2,
+		},
+		{
+			name:       "no newline at end of file",
+			content:    "a\nb",
+			original:   "b",
+			start:      2,
+			suggestion: "x\n",
+			want:       "a\nx",
+			wantLine:   2,
+		},
+		{
+			name:       "changed",
+			content:    "a\nB\nc\n",
+			original:   "b\n",
+			start:      2,
+			suggestion: "x\n",
+			wantErr:    true,
+		},
+		{
+			name:       "ambiguous",
+			content:    "new\na\nb\na\nb\n",
+			original:   "b\n",
+			start:      1,
+			suggestion: "x\n",
+			wantErr:    true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, line, err := actions.ApplySuggestion(tt.content, tt.original, tt.start, tt.suggestion)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantLine, line)
+		})
+	}
+}
+
+func TestSuggestionFixupTarget(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "file", []byte("a\nb\nc\n"))
+	base, err := repo.RevParse(&git.RevParse{Rev: "HEAD"})
+	require.NoError(t, err)
+
+	_, err = repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	first := gittest.CommitFile(t, repo, "file", []byte("a\nB\nc\n"))
+	second := gittest.CommitFile(t, repo, "file", []byte("a\nB\nC\n"))
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{Name: "one", Parent: meta.BranchState{Name: "main", Trunk: true, Head: base}})
+
+	target, err := actions.SuggestionFixupTarget(repo, tx, "one", "file", 2, 2)
+	require.NoError(t, err)
+	require.Equal(t, first, target)
+	target, err = actions.SuggestionFixupTarget(repo, tx, "one", "file", 2, 3)
+	require.NoError(t, err)
+	require.Equal(t, second, target)
+
+	// The first line wasn't changed on the branch.
+	_, err = actions.SuggestionFixupTarget(repo, tx, "one", "file", 1, 1)
+	require.Error(t, err)
+}
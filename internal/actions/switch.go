@@ -0,0 +1,90 @@
+package actions
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/sirupsen/logrus"
+)
+
+const branchHistoryFile = "branch-history.json"
+
+// branchHistory records the most recent branch switch performed by av.
+type branchHistory struct {
+	// The branch that was checked out before the switch.
+	Previous string `json:"previous"`
+	// The branch that was checked out by the switch.
+	Current string `json:"current"`
+}
+
+// SwitchBranch checks out the given branch and records the branch that was
+// checked out before so that the user can return to it with PreviousBranch.
+// This should be used (instead of git.Repo.CheckoutBranch) for checkouts that
+// are initiated by the user rather than for temporary checkouts that av
+// performs internally (e.g., while syncing a stack).
+func SwitchBranch(repo *git.Repo, opts *git.CheckoutBranch) error {
+	previous, err := repo.CheckoutBranch(opts)
+	if err != nil {
+		return err
+	}
+	if previous == "" || previous == opts.Name {
+		return nil
+	}
+	data, err := json.Marshal(branchHistory{Previous: previous, Current: opts.Name})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(repo.AvDir(), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path.Join(repo.AvDir(), branchHistoryFile), data, 0644); err != nil {
+		// The checkout itself succeeded, so this isn't worth failing over.
+		logrus.WithError(err).Warn("failed to record branch history")
+	}
+	return nil
+}
+
+// PreviousBranch returns the name of the branch that was checked out before
+// the current branch.
+//
+// If the current branch was checked out by av (see SwitchBranch), the branch
+// recorded at that point is returned. This ignores any intermediate checkouts
+// that av performs internally (which would otherwise pollute Git's own
+// history). Otherwise, this falls back to Git's notion of the previous branch
+// (i.e., `@{-1}`).
+func PreviousBranch(repo *git.Repo) (string, error) {
+	current, err := repo.CurrentBranchName()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path.Join(repo.AvDir(), branchHistoryFile))
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if err == nil {
+		var history branchHistory
+		if err := json.Unmarshal(data, &history); err != nil {
+			logrus.WithError(err).Debug("failed to parse branch history, ignoring")
+		} else if history.Current == current && history.Previous != "" {
+			return history.Previous, nil
+		}
+	}
+
+	ref, err := repo.RevParse(&git.RevParse{Rev: "@{-1}", SymbolicFullName: true})
+	if err != nil || ref == "" {
+		return "", errors.New("no previously checked out branch")
+	}
+	if ref == "refs/heads/"+current {
+		return "", errors.New("no previously checked out branch")
+	}
+	name, ok := strings.CutPrefix(ref, "refs/heads/")
+	if !ok {
+		return "", errors.Errorf("previously checked out ref %q is not a branch", ref)
+	}
+	return name, nil
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"emperror.dev/errors"
 	"github.com/aviator-co/av/internal/config"
@@ -15,6 +17,7 @@ import (
 	"github.com/aviator-co/av/internal/utils/ghutils"
 	"github.com/aviator-co/av/internal/utils/stackutils"
 	"github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
 )
 
 type SyncBranchOpts struct {
@@ -27,6 +30,16 @@ type SyncBranchOpts struct {
 	// If true, skip the current commit.
 	// This must only be set after a rebase conflict in a sync.
 	Skip bool
+	// How the branch is updated with the changes of its parent (empty means
+	// SyncStrategyRebase).
+	Strategy SyncStrategy
+	// The options of the merge strategy that the branch is rebased (or merged)
+	// with (see ParseStrategyOptions).
+	StrategyOptions []string
+	// If set (along with Push), the branch isn't pushed right away. Instead,
+	// it's added to Pushes so that all of the synced branches can be pushed at
+	// once (see PushSyncedBranches).
+	Pushes *[]string
 
 	Continuation *SyncBranchContinuation
 }
@@ -54,6 +67,25 @@ func SyncBranch(
 	var cont *SyncBranchContinuation
 	var pull *gh.PullRequest
 
+	if opts.Continuation != nil && !opts.Skip {
+		// If the rebase that stopped the sync was aborted outside of av (e.g.,
+		// with git rebase --abort), there's nothing to continue: start over.
+		if op, err := repo.OperationInProgress(); err != nil {
+			return nil, err
+		} else if op == git.OperationNone {
+			completed, err := rebaseWasCompleted(repo, branch.Name, opts.Continuation.NewParentCommit)
+			if err != nil {
+				return nil, err
+			}
+			if !completed {
+				_, _ = fmt.Fprint(os.Stderr,
+					"  - the rebase was aborted outside of av, rebasing ", colors.UserInput(branch.Name), " again\n",
+				)
+				opts.Continuation = nil
+			}
+		}
+	}
+
 	if opts.Continuation != nil {
 		var err error
 		cont, err = syncBranchContinue(ctx, repo, tx, opts, branch)
@@ -61,11 +93,13 @@ func SyncBranch(
 			return nil, err
 		}
 	} else {
+		var trunkHead string
 		if opts.Fetch {
 			fetchHead, err := fetchRemoteTrunkHead(repo, tx, branch)
 			if err != nil {
 				return nil, err
 			}
+			trunkHead = fetchHead
 			update, err := UpdatePullRequestState(ctx, client, tx, branch.Name)
 			if err != nil {
 				_, _ = fmt.Fprint(os.Stderr, colors.Failure("      - error: ", err.Error()), "\n")
@@ -93,6 +127,20 @@ func SyncBranch(
 			}
 		}
 
+		if branch.MergeCommit == "" && !branch.ReadOnly {
+			// Comparing the patches against the trunk is expensive, so it's only
+			// done for the branches that look like they were merged.
+			merged, err := mightBeSquashMerged(repo, branch)
+			if err != nil {
+				return nil, err
+			}
+			if merged {
+				if err := syncBranchDetectSquashMerge(repo, tx, &branch, trunkHead); err != nil {
+					return nil, err
+				}
+			}
+		}
+
 		if branch.MergeCommit != "" {
 			_, _ = fmt.Fprint(os.Stderr,
 				"  - skipping sync for merged branch "+
@@ -102,6 +150,29 @@ func SyncBranch(
 			return nil, nil
 		}
 
+		if branch.ReadOnly {
+			// The branch belongs to someone else, so it's updated from the
+			// remote (which its children are then rebased onto) instead of
+			// being rebased itself. It's never pushed.
+			if !opts.Fetch {
+				_, _ = fmt.Fprint(os.Stderr, "  - skipping sync for read-only branch (not fetching)\n")
+				return nil, nil
+			}
+			return nil, syncBranchPullReadOnly(repo, branch.Name)
+		}
+
+		if queued, err := isInGitHubMergeQueue(ctx, client, pull); err != nil {
+			// The merge queue might not be available (e.g., on older GHES
+			// versions), so this isn't fatal.
+			logrus.WithError(err).Warn("failed to determine whether the pull request is in the merge queue")
+		} else if queued {
+			_, _ = fmt.Fprint(os.Stderr,
+				"  - skipping sync for branch in the merge queue"+
+					" (pushing it would remove it from the queue)\n",
+			)
+			return nil, nil
+		}
+
 		var err error
 		cont, err = syncBranchRebase(ctx, repo, tx, opts)
 		if err != nil {
@@ -112,9 +183,43 @@ func SyncBranch(
 	if cont != nil {
 		return cont, nil
 	}
+	RecordLastSync(repo, tx, opts.Branch, time.Now())
+
+	// Rebasing keeps the commit trailers as they are, so they're updated (e.g.,
+	// after the branch was moved onto another parent) before the children are
+	// synced onto the branch. Merged branches only gain commits, so they're
+	// never rewritten.
+	if opts.Strategy != SyncStrategyMerge {
+		if _, err := RestampCommitTrailers(repo, tx, opts.Branch); err != nil {
+			return nil, err
+		}
+	}
 
-	if opts.Push {
-		if err := syncBranchPushAndUpdatePullRequest(ctx, repo, client, tx, opts.Branch, pull); err != nil {
+	if branch, _ := tx.Branch(opts.Branch); opts.Push && branch.WIP {
+		_, _ = fmt.Fprint(os.Stderr,
+			"  - skipping push of work-in-progress branch ", colors.UserInput(opts.Branch), "\n",
+		)
+	} else if opts.Push && opts.Pushes != nil {
+		// The trailers have to be stamped before the children are synced onto
+		// the branch.
+		if config.Av.PullRequest.StackTrailers && branch.PullRequest != nil && branch.PullRequest.ID != "" {
+			if _, err := StampStackTrailers(repo, tx, opts.Branch); err != nil {
+				return nil, err
+			}
+		}
+		if !slices.Contains(*opts.Pushes, opts.Branch) {
+			*opts.Pushes = append(*opts.Pushes, opts.Branch)
+		}
+	} else if opts.Push {
+		err := syncBranchPushAndUpdatePullRequest(ctx, repo, client, tx, opts.Branch, pull, opts.Strategy)
+		if IsOffline(err) {
+			// The sync itself is done, so don't fail it just because GitHub
+			// can't be updated right now.
+			return nil, queueOfflinePush(repo, opts.Branch, opts.Strategy, err)
+		} else if err != nil {
+			return nil, err
+		}
+		if err := finishPendingUpdate(ctx, repo, client, tx, opts.Branch); err != nil {
 			return nil, err
 		}
 	}
@@ -122,6 +227,129 @@ func SyncBranch(
 	return nil, nil
 }
 
+// syncBranchDetectSquashMerge records the merge commit of a branch that was
+// squash-merged into its trunk without av knowing about it (see
+// FindSquashMergeCommit), so that the branch is treated as merged and its
+// children are moved onto the trunk. If trunkHead is empty, the last fetched
+// commit of the trunk is used.
+func syncBranchDetectSquashMerge(repo *git.Repo, tx meta.WriteTx, branch *meta.Branch, trunkHead string) error {
+	if trunkHead == "" {
+		trunk, ok := meta.Trunk(tx, branch.Name)
+		if !ok {
+			return errors.Errorf("failed to find the trunk branch for %q", branch.Name)
+		}
+		var err error
+		trunkHead, err = latestParentCommit(repo, trunk, true)
+		if err != nil {
+			return err
+		}
+	}
+	commit, err := FindSquashMergeCommit(repo, tx, branch.Name, trunkHead)
+	if err != nil {
+		// This is only a fallback for when av doesn't know about the merge, so
+		// it's not fatal.
+		logrus.WithError(err).WithField("branch", branch.Name).
+			Warn("failed to check whether the branch was squash-merged")
+		return nil
+	}
+	if commit == "" {
+		return nil
+	}
+	_, _ = fmt.Fprint(os.Stderr,
+		"  - found the changes of this branch in commit ", colors.UserInput(git.ShortSha(commit)),
+		" of the trunk (it was squash-merged)\n",
+	)
+	branch.MergeCommit = commit
+	tx.SetBranch(*branch)
+	return nil
+}
+
+// mightBeSquashMerged returns true if the branch might have been merged
+// without av knowing about it: its pull request was closed (but no merge commit
+// was found for it) or its remote branch was deleted (as GitHub does once a
+// pull request is merged if the repository is configured to).
+func mightBeSquashMerged(repo *git.Repo, branch meta.Branch) (bool, error) {
+	if branch.PullRequest != nil && branch.PullRequest.State == githubv4.PullRequestStateClosed {
+		return true, nil
+	}
+	// Only a branch that was pushed can have its remote branch deleted.
+	pushed, err := repo.BranchGetConfig(branch.Name, "av-pushed-sha")
+	if err != nil {
+		return false, err
+	}
+	if pushed == "" {
+		if pushed, err = repo.BranchGetConfig(branch.Name, "merge"); err != nil {
+			return false, err
+		}
+	}
+	if pushed == "" {
+		return false, nil
+	}
+	exists, err := repo.DoesRefExist("refs/remotes/origin/" + branch.Name)
+	return !exists, err
+}
+
+// syncBranchPullReadOnly updates a read-only branch to the latest version of
+// its remote branch (even if it was force-pushed).
+func syncBranchPullReadOnly(repo *git.Repo, branchName string) error {
+	if _, err := repo.Run(&git.RunOpts{
+		Args:      []string{"fetch", "origin", branchName},
+		ExitError: true,
+	}); err != nil {
+		return errors.WrapIff(err, "failed to fetch %q from origin", branchName)
+	}
+	remoteHead, err := repo.RevParse(&git.RevParse{Rev: "FETCH_HEAD"})
+	if err != nil {
+		return err
+	}
+	head, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + branchName})
+	if err != nil {
+		return err
+	}
+	if head == remoteHead {
+		_, _ = fmt.Fprint(os.Stderr,
+			"  - read-only branch is up-to-date with ", colors.UserInput("origin/", branchName), "\n",
+		)
+		return nil
+	}
+
+	if current, _ := repo.CurrentBranchName(); current == branchName {
+		// Keep the working tree in sync (this fails if it has changes that
+		// would be overwritten).
+		if _, err := repo.Run(&git.RunOpts{
+			Args:      []string{"reset", "--keep", remoteHead},
+			ExitError: true,
+		}); err != nil {
+			return errors.WrapIff(err, "failed to update %q", branchName)
+		}
+	} else if err := repo.UpdateRef(&git.UpdateRef{
+		Ref:          "refs/heads/" + branchName,
+		New:          remoteHead,
+		Old:          head,
+		CreateReflog: true,
+	}); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprint(os.Stderr,
+		"  - updated read-only branch from ", colors.UserInput("origin/", branchName),
+		" (", colors.UserInput(git.ShortSha(head)), " -> ", colors.UserInput(git.ShortSha(remoteHead)), ")\n",
+	)
+	return nil
+}
+
+// isInGitHubMergeQueue returns true if the given pull request is in GitHub's
+// merge queue (which is only checked if it's enabled in the config).
+func isInGitHubMergeQueue(ctx context.Context, client *gh.Client, pull *gh.PullRequest) (bool, error) {
+	if !config.Av.GitHub.MergeQueue || pull == nil || pull.State != githubv4.PullRequestStateOpen {
+		return false, nil
+	}
+	entry, err := client.PullRequestMergeQueueEntry(ctx, pull.ID)
+	if err != nil {
+		return false, err
+	}
+	return entry != nil, nil
+}
+
 // syncBranchRebase does the actual rebase part of SyncBranch
 func syncBranchRebase(
 	ctx context.Context,
@@ -130,6 +358,9 @@ func syncBranchRebase(
 	opts SyncBranchOpts,
 ) (*SyncBranchContinuation, error) {
 	branch, _ := tx.Branch(opts.Branch)
+	if err := syncBranchReconcileParentHead(repo, tx, &branch); err != nil {
+		return nil, err
+	}
 	parentState := branch.Parent
 	parentBranch, _ := tx.Branch(parentState.Name)
 	origParentState := branch.Parent
@@ -142,39 +373,11 @@ func syncBranchRebase(
 	if parentState.Trunk {
 		var newUpstreamCommitHash string
 		if opts.ToTrunk {
-			// First, try to fetch latest commit from the trunk...
-			_, _ = fmt.Fprint(
-				os.Stderr,
-				"  - fetching latest commit from ",
-				colors.UserInput("origin/", parentState.Name),
-				"\n",
-			)
-			if _, err := repo.Run(&git.RunOpts{
-				Args: []string{"fetch", "origin", parentState.Name},
-			}); err != nil {
-				_, _ = fmt.Fprint(
-					os.Stderr,
-					"  - ",
-					colors.Failure(
-						"error: failed to fetch HEAD of ",
-					),
-					colors.UserInput(parentState.Name),
-					colors.Failure(" from origin: ", err.Error()),
-					"\n",
-				)
-				return nil, errors.WrapIff(
-					err,
-					"failed to fetch trunk branch %q from origin",
-					parentState.Name,
-				)
-			}
-
-			// NOTE: Strictly speaking, if a user doesn't use the default refspec (e.g. fetch is
-			// not +refs/heads/*:refs/remotes/origin/*, the remote tracking branch is not
-			// origin/$TRUNK. As we just fetched from a remote, it'd be safe to use FETCH_HEAD.
-			trunkHead, err := repo.RevParse(&git.RevParse{Rev: "origin/" + parentState.Name})
+			// The trunk was fetched before the sync started (see FetchTrunks),
+			// unless fetching was disabled.
+			trunkHead, err := latestParentCommit(repo, parentState.Name, true)
 			if err != nil {
-				return nil, errors.WrapIff(err, "failed to get HEAD of %q", parentState.Name)
+				return nil, err
 			}
 			newUpstreamCommitHash = trunkHead
 		} else if origParentBranch.MergeCommit != "" {
@@ -214,20 +417,20 @@ func syncBranchRebase(
 		} else {
 			origUpstream = origParentState.Head
 		}
+		if opts.ToTrunk && opts.Strategy != SyncStrategyMerge {
+			// The branch might still contain the commits of a parent that was
+			// squash-merged without av knowing about it.
+			origUpstream = skipSquashMergedCommits(repo, branch.Name, origUpstream, newUpstreamCommitHash)
+		}
 
 		continuation := SyncBranchContinuation{
 			NewParentName: parentState.Name,
 		}
-		rebase, err := repo.RebaseParse(git.RebaseOpts{
-			Branch:   branch.Name,
-			Upstream: origUpstream,
-			Onto:     newUpstreamCommitHash,
-		})
+		conflict, err := syncBranchApply(repo, opts, branch.Name, origUpstream, newUpstreamCommitHash, parentState.Name)
 		if err != nil {
 			return nil, err
 		}
-		msgRebaseResult(rebase)
-		if rebase.Status == git.RebaseConflict {
+		if conflict {
 			return &continuation, nil
 		}
 		syncBranchUpdateParent(tx, branch, &continuation)
@@ -246,10 +449,12 @@ func syncBranchRebase(
 	// Scenario 1: the parent branch has been merged.
 	if origParentBranch.MergeCommit != "" {
 		short := git.ShortSha(origParentBranch.MergeCommit)
+		pull := ""
+		if origParentBranch.PullRequest != nil {
+			pull = fmt.Sprint(" (pull ", colors.UserInput("#", origParentBranch.PullRequest.GetNumber()), ")")
+		}
 		_, _ = fmt.Fprint(os.Stderr,
-			"  - parent ", colors.UserInput(origParentState.Name),
-			" (pull ", colors.UserInput("#", origParentBranch.PullRequest.GetNumber()), ")",
-			" was merged\n",
+			"  - parent ", colors.UserInput(origParentState.Name), pull, " was merged\n",
 		)
 		_, _ = fmt.Fprint(os.Stderr,
 			"  - rebasing ", colors.UserInput(branch.Name),
@@ -307,16 +512,11 @@ func syncBranchRebase(
 			)
 			continuation.NewParentCommit = newUpstreamCommitHash
 		}
-		rebase, err := repo.RebaseParse(git.RebaseOpts{
-			Branch:   branch.Name,
-			Upstream: origUpstream,
-			Onto:     newUpstreamCommitHash,
-		})
+		conflict, err := syncBranchApply(repo, opts, branch.Name, origUpstream, newUpstreamCommitHash, parentState.Name)
 		if err != nil {
 			return nil, err
 		}
-		msgRebaseResult(rebase)
-		if rebase.Status == git.RebaseConflict {
+		if conflict {
 			return &continuation, nil
 		}
 		syncBranchUpdateParent(tx, branch, &continuation)
@@ -407,22 +607,70 @@ func syncBranchRebase(
 		NewParentName:   parentState.Name,
 		NewParentCommit: parentHead,
 	}
-	rebase, err := repo.RebaseParse(git.RebaseOpts{
-		Branch:   branch.Name,
-		Upstream: origUpstream,
-		Onto:     parentHead,
-	})
+	conflict, err := syncBranchApply(repo, opts, branch.Name, origUpstream, parentHead, parentState.Name)
 	if err != nil {
 		return nil, err
 	}
-	msgRebaseResult(rebase)
-	if rebase.Status == git.RebaseConflict {
+	if conflict {
 		return &continuation, nil
 	}
 	syncBranchUpdateParent(tx, branch, &continuation)
 	return nil, nil
 }
 
+// syncBranchApply updates the branch with the changes of its parent according
+// to the sync strategy: by default, the commits of the branch after upstream
+// are rebased onto the given commit of the parent; with SyncStrategyMerge, the
+// commit is merged into the branch instead. Returns true if it stopped at a
+// conflict.
+func syncBranchApply(
+	repo *git.Repo,
+	opts SyncBranchOpts,
+	branchName, upstream, onto, parentName string,
+) (bool, error) {
+	if opts.Strategy == SyncStrategyMerge {
+		merge, err := repo.Merge(git.MergeOpts{
+			Branch:          branchName,
+			Commit:          onto,
+			Message:         fmt.Sprintf("Merge %s into %s", parentName, branchName),
+			StrategyOptions: opts.StrategyOptions,
+		})
+		if err != nil {
+			return false, err
+		}
+		if merge, err = mergeRegeneratingConflicts(repo, merge); err != nil {
+			return false, err
+		}
+		msgMergeResult(merge)
+		return merge.Status == git.MergeConflict, nil
+	}
+	rebase, err := repo.RebaseParse(git.RebaseOpts{
+		Branch:          branchName,
+		Upstream:        upstream,
+		Onto:            onto,
+		StrategyOptions: opts.StrategyOptions,
+	})
+	if err != nil {
+		return false, err
+	}
+	if rebase, err = rebaseRegeneratingConflicts(repo, rebase); err != nil {
+		return false, err
+	}
+	msgRebaseResult(rebase)
+	return rebase.Status == git.RebaseConflict, nil
+}
+
+// abortSyncBranch aborts the rebase (or, with SyncStrategyMerge, the merge) of
+// a branch that stopped at a conflict.
+func abortSyncBranch(repo *git.Repo, strategy SyncStrategy) error {
+	if strategy == SyncStrategyMerge {
+		_, err := repo.Merge(git.MergeOpts{Abort: true})
+		return err
+	}
+	_, err := repo.Rebase(git.RebaseOpts{Abort: true})
+	return err
+}
+
 func fetchRemoteTrunkHead(repo *git.Repo, tx meta.WriteTx, branch meta.Branch) (string, error) {
 	parent, ok := meta.Trunk(tx, branch.Name)
 	if !ok {
@@ -439,6 +687,61 @@ func fetchRemoteTrunkHead(repo *git.Repo, tx meta.WriteTx, branch meta.Branch) (
 	return commitHash, nil
 }
 
+// skipSquashMergedCommits returns the upstream to rebase the branch with (see
+// SkipSquashMergedCommits), falling back to the given upstream on errors.
+func skipSquashMergedCommits(repo *git.Repo, branchName, upstream, onto string) string {
+	newUpstream, squash, err := SkipSquashMergedCommits(repo, "refs/heads/"+branchName, upstream, onto)
+	if err != nil {
+		logrus.WithError(err).WithField("branch", branchName).
+			Debug("failed to check for squash-merged commits")
+		return upstream
+	}
+	if squash != "" {
+		_, _ = fmt.Fprint(os.Stderr,
+			"  - skipping the commits up to ", colors.UserInput(git.ShortSha(newUpstream)),
+			" (they were squash-merged in commit ", colors.UserInput(git.ShortSha(squash)), ")\n",
+		)
+	}
+	return newUpstream
+}
+
+// FetchTrunks fetches the latest commits of the trunks that the given branches
+// are based on from origin (see FetchTrunk). Each trunk is only fetched once.
+func FetchTrunks(repo *git.Repo, tx meta.ReadTx, branches []string) error {
+	fetched := make(map[string]bool)
+	for _, name := range branches {
+		trunk, ok := meta.Trunk(tx, name)
+		if !ok || fetched[trunk] {
+			continue
+		}
+		fetched[trunk] = true
+		if err := FetchTrunk(repo, trunk); err != nil {
+			return err
+		}
+	}
+	if len(fetched) > 0 {
+		_, _ = fmt.Fprint(os.Stderr, "\n")
+	}
+	return nil
+}
+
+// FetchTrunk fetches the latest commit of the given trunk from origin, so that
+// syncing with --trunk rebases onto the actual latest commit of the trunk
+// rather than the last fetched one.
+func FetchTrunk(repo *git.Repo, trunk string) error {
+	_, _ = fmt.Fprint(os.Stderr,
+		"Fetching the latest commit of ", colors.UserInput("origin/", trunk), "...\n",
+	)
+	if _, err := repo.Git("fetch", "origin", trunk); err != nil {
+		_, _ = fmt.Fprint(os.Stderr,
+			"  - ", colors.Failure("error: failed to fetch "), colors.UserInput(trunk),
+			colors.Failure(" from origin: ", err.Error()), "\n",
+		)
+		return errors.WrapIff(err, "failed to fetch trunk branch %q from origin", trunk)
+	}
+	return nil
+}
+
 // findMergeCommitWithGitLog looks for the merge commit for a specified PR.
 //
 // Usually, GitHub should set which commit closes a pull request. This is known to be not that
@@ -465,6 +768,28 @@ func syncBranchContinue(
 	opts SyncBranchOpts,
 	branch meta.Branch,
 ) (*SyncBranchContinuation, error) {
+	if opts.Strategy == SyncStrategyMerge {
+		if opts.Skip {
+			return nil, errors.New("commits can't be skipped when merging: resolve the conflicts or abort the sync")
+		}
+		merge, err := repo.Merge(git.MergeOpts{Continue: true})
+		if err != nil {
+			return nil, err
+		}
+		//nolint:exhaustive
+		switch merge.Status {
+		case git.MergeNotInProgress:
+			_, _ = fmt.Fprint(os.Stderr, "  - the merge was completed with git commit\n")
+		case git.MergeConflict:
+			msgMergeResult(merge)
+			return opts.Continuation, nil
+		default:
+			msgMergeResult(merge)
+		}
+		syncBranchUpdateParent(tx, branch, opts.Continuation)
+		return nil, nil
+	}
+
 	var rebaseOpts git.RebaseOpts
 	if opts.Skip {
 		rebaseOpts.Skip = true
@@ -475,19 +800,17 @@ func syncBranchContinue(
 	if err != nil {
 		return nil, err
 	}
+	// The following commits may stop at conflicts in generated files as well.
+	if rebase, err = rebaseRegeneratingConflicts(repo, rebase); err != nil {
+		return nil, err
+	}
 
 	//nolint:exhaustive
 	switch rebase.Status {
 	case git.RebaseNotInProgress:
-		// TODO:
-		//    I think we could try to detect whether or not the rebase was
-		//    actually completed or just aborted, but it's whatever for right
-		//    now.
+		// SyncBranch already restarted the rebase if it was aborted.
 		_, _ = fmt.Fprint(os.Stderr,
-			"  - ", colors.Warning("WARNING: expected a rebase to be in progress"),
-			" (assuming the rebase was completed with git rebase --continue)\n",
-			"      - use ", colors.CliCmd("av stack sync --continue"),
-			" instead of git rebase --continue to avoid this warning\n",
+			"  - the rebase was completed with git rebase --continue\n",
 		)
 	case git.RebaseConflict:
 		msgRebaseResult(rebase)
@@ -501,6 +824,47 @@ func syncBranchContinue(
 	return nil, nil
 }
 
+// rebaseWasCompleted returns whether an interrupted rebase of the branch onto
+// the given commit was completed outside of av (e.g., with git rebase
+// --continue) as opposed to aborted. If the commit isn't known (i.e., when
+// rebasing onto a trunk), the rebase is assumed to have been completed.
+func rebaseWasCompleted(repo *git.Repo, branchName string, newParentCommit string) (bool, error) {
+	if newParentCommit == "" {
+		return true, nil
+	}
+	return repo.IsAncestor(newParentCommit, "refs/heads/"+branchName)
+}
+
+// syncBranchReconcileParentHead makes sure that the recorded parent HEAD of the
+// branch (which is used as the upstream of the rebase) is still part of the
+// branch's history. See ReconcileParentHead.
+func syncBranchReconcileParentHead(repo *git.Repo, tx meta.WriteTx, branch *meta.Branch) error {
+	res, err := ReconcileParentHead(repo, *branch)
+	if err != nil {
+		return errors.WrapIff(err, "failed to check the parent commit of %q", branch.Name)
+	}
+	if res.Updated {
+		_, _ = fmt.Fprint(os.Stderr,
+			"  - parent ", colors.UserInput(branch.Parent.Name),
+			" was rewritten outside of av, updating the recorded parent commit from ",
+			colors.UserInput(git.ShortSha(branch.Parent.Head)),
+			" to ", colors.UserInput(git.ShortSha(res.Head)), "\n",
+		)
+		branch.Parent.Head = res.Head
+		tx.SetBranch(*branch)
+	} else if res.Stale {
+		_, _ = fmt.Fprint(os.Stderr,
+			"  - ", colors.Warning("warning: "),
+			"the recorded parent commit ", colors.UserInput(git.ShortSha(branch.Parent.Head)),
+			" of ", colors.UserInput(branch.Name),
+			" is no longer part of its history (was ", colors.UserInput(branch.Parent.Name),
+			" rewritten outside of av?)\n",
+			"    the rebase might replay commits that belong to the parent branch\n",
+		)
+	}
+	return nil
+}
+
 func syncBranchUpdateParent(
 	tx meta.WriteTx,
 	branch meta.Branch,
@@ -515,6 +879,11 @@ func syncBranchUpdateParent(
 	} else {
 		branch.Parent.Head = continuation.NewParentCommit
 	}
+	if !oldParentState.Trunk && branch.Parent.Trunk && branch.StackDescription == "" {
+		// The root of the stack was merged, so the description of the stack
+		// moves on to the new root.
+		branch.StackDescription = meta.StackDescription(tx, oldParentState.Name)
+	}
 	tx.SetBranch(branch)
 
 	if !oldParentState.Trunk && branch.Parent.Trunk {
@@ -533,17 +902,75 @@ func syncBranchPushAndUpdatePullRequest(
 	branchName string,
 	// pr can be nil, in which case the PR info is fetched from GitHub
 	pr *gh.PullRequest,
+	strategy SyncStrategy,
 ) error {
+	push, err := prepareSyncPush(ctx, repo, client, tx, branchName, pr)
+	if err != nil || push == nil {
+		return err
+	}
+	defer restoreSyncPushDraft(ctx, repo, client, push)
+	if config.Av.PullRequest.StackTrailers {
+		if _, err := StampStackTrailers(repo, tx, branchName); err != nil {
+			return err
+		}
+	}
+	if err := Push(repo, branchName, syncPushOpts(strategy)); err != nil {
+		return err
+	}
+	return finishSyncPush(ctx, repo, client, tx, push)
+}
+
+// syncPush is a branch that is about to be pushed by a sync (see
+// prepareSyncPush).
+type syncPush struct {
+	branchName string
+	pr         *gh.PullRequest
+	// If true, the pull request was converted to a draft for the push and has
+	// to be marked as ready for review again afterwards.
+	rebaseWithDraft bool
+	// The reviewers who approved the pull request before the push and the
+	// commit of the remote branch at that point (see recordApprovals).
+	approvedBy []string
+	oldHead    string
+}
+
+func syncPushOpts(strategy SyncStrategy) PushOpts {
+	// Merged branches only gain commits, so they don't have to be
+	// force-pushed (a force-push would indicate that the remote branch
+	// diverged).
+	force := ForceWithLease
+	if strategy == SyncStrategyMerge {
+		force = NoForce
+	}
+	return PushOpts{
+		Force:                        force,
+		SkipIfRemoteBranchNotExist:   true,
+		SkipIfRemoteBranchIsUpToDate: true,
+	}
+}
+
+// prepareSyncPush makes sure that the pull request of the branch can be
+// updated (and converts it to a draft if needed) before the branch is pushed.
+// Returns nil if the branch shouldn't be pushed.
+func prepareSyncPush(
+	ctx context.Context,
+	repo *git.Repo,
+	client *gh.Client,
+	tx meta.ReadTx,
+	branchName string,
+	// pr can be nil, in which case the PR info is fetched from GitHub
+	pr *gh.PullRequest,
+) (*syncPush, error) {
 	branch, _ := tx.Branch(branchName)
 	if branch.PullRequest == nil || branch.PullRequest.ID == "" {
-		return nil
+		return nil, nil
 	}
 
 	if pr == nil {
 		var err error
 		pr, err = client.PullRequest(ctx, branch.PullRequest.ID)
 		if err != nil {
-			return errors.WrapIff(err, "failed to fetch pull request info for %q", branch.Name)
+			return nil, errors.WrapIff(err, "failed to fetch pull request info for %q", branch.Name)
 		}
 	}
 
@@ -558,54 +985,91 @@ func syncBranchPushAndUpdatePullRequest(
 			"      - re-open the pull request (or create a new one with ",
 			colors.CliCmd("av pr create"), ") to push changes\n",
 		)
-		return nil
+		return nil, nil
+	}
+
+	if err := checkPRMetadataVersion(pr.Body); err != nil {
+		return nil, errors.WrapIff(err, "refusing to update pull request #%d", pr.Number)
 	}
 
 	rebaseWithDraft := shouldRebaseWithDraft(repo, pr)
 	if rebaseWithDraft {
 		_, err := client.ConvertPullRequestToDraft(ctx, pr.ID)
 		if err != nil {
-			return errors.WrapIff(err, "failed to convert pull request to draft")
+			return nil, errors.WrapIff(err, "failed to convert pull request to draft")
 		}
 	}
+	return &syncPush{branchName: branchName, pr: pr, rebaseWithDraft: rebaseWithDraft}, nil
+}
 
-	if err := Push(repo, branchName, PushOpts{
-		Force:                        ForceWithLease,
-		SkipIfRemoteBranchNotExist:   true,
-		SkipIfRemoteBranchIsUpToDate: true,
-	}); err != nil {
-		return err
-	}
-
+// finishSyncPush updates the pull request of a branch that was pushed by a sync
+// (its base branch and the stack information in its body).
+func finishSyncPush(ctx context.Context, repo *git.Repo, client *gh.Client, tx meta.ReadTx, push *syncPush) error {
+	branch, _ := tx.Branch(push.branchName)
+	pr := push.pr
 	prMeta, err := getPRMetadata(tx, branch, nil)
 	if err != nil {
 		return err
 	}
 
+	// Keep the stack that was written to the pull request before even if
+	// pullRequest.writeStack isn't set (anymore).
+	writeStack := config.Av.PullRequest.WriteStack
+	if writeStack == "" {
+		writeStack = prStackSetting(pr.Body)
+	}
 	var stackToWrite *stackutils.StackTreeNode
-	if config.Av.PullRequest.WriteStack != "" {
-		if stackToWrite, err = stackutils.BuildStackTreeForPullRequest(repo, tx, branchName); err != nil {
+	if writeStack != "" {
+		if stackToWrite, err = stackutils.BuildStackTreeForPullRequest(repo, tx, push.branchName); err != nil {
 			return err
 		}
 	}
-	prBody := AddPRMetadataAndStack(pr.Body, prMeta, branchName, stackToWrite, config.Av.PullRequest.WriteStack)
-	if _, err := client.UpdatePullRequest(ctx, githubv4.UpdatePullRequestInput{
-		PullRequestID: branch.PullRequest.ID,
-		BaseRefName:   gh.Ptr(githubv4.String(branch.Parent.Name)),
-		Body:          gh.Ptr(githubv4.String(prBody)),
-	}); err != nil {
-		return err
+	prBody := AddPRMetadataAndStack(pr.Body, prMeta, push.branchName, stackToWrite, writeStack)
+	prBody = SetPRStackDescription(prBody, prStackDescription(tx, push.branchName))
+	if pr.BaseRefName != branch.Parent.Name || !PRBodyEqual(pr.Body, prBody) {
+		if _, err := client.UpdatePullRequest(ctx, githubv4.UpdatePullRequestInput{
+			PullRequestID: branch.PullRequest.ID,
+			BaseRefName:   gh.Ptr(githubv4.String(branch.Parent.Name)),
+			Body:          gh.Ptr(githubv4.String(prBody)),
+		}); err != nil {
+			return err
+		}
+	} else {
+		logrus.WithField("pr", pr.Number).Debug("pull request is up-to-date, not updating")
 	}
 
-	if rebaseWithDraft {
+	if push.rebaseWithDraft {
 		if _, err := client.MarkPullRequestReadyForReview(ctx, pr.ID); err != nil {
 			return err
 		}
+		push.rebaseWithDraft = false
 	}
 
 	return nil
 }
 
+// restoreSyncPushDraft marks the pull request of the push as ready for review
+// again if it was converted to a draft for the push and finishSyncPush didn't
+// get to undo that. If GitHub can't be reached, that's queued instead (see
+// PendingUpdate.Ready).
+func restoreSyncPushDraft(ctx context.Context, repo *git.Repo, client *gh.Client, push *syncPush) {
+	if !push.rebaseWithDraft {
+		return
+	}
+	_, err := client.MarkPullRequestReadyForReview(ctx, push.pr.ID)
+	if IsOffline(err) {
+		err = queueReadyForReview(repo, push.branchName)
+	}
+	if err != nil {
+		_, _ = fmt.Fprint(os.Stderr,
+			"  - ", colors.Warning("WARNING:"), " failed to mark pull request ", colors.UserInput("#", push.pr.Number),
+			" as ready for review again: ", err.Error(), "\n",
+		)
+		return
+	}
+	push.rebaseWithDraft = false
+}
+
 func shouldRebaseWithDraft(repo *git.Repo, pr *gh.PullRequest) bool {
 	if pr.IsDraft {
 		// If the PR is already a draft, then we don't need to do anything.
@@ -0,0 +1,186 @@
+package actions
+
+import (
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+)
+
+// SyncCheckOpts configures CheckSync.
+type SyncCheckOpts struct {
+	// If set, the stack roots are checked against the latest commit of their
+	// trunk on origin (as with `av stack sync --trunk`).
+	ToTrunk bool
+	// If set, the first branch is checked against this new parent (as with
+	// `av stack sync --parent`).
+	Parent string
+}
+
+// SyncCheckResult is the predicted outcome of syncing a branch.
+type SyncCheckResult struct {
+	Branch string
+	// The parent that the branch would be synced onto.
+	Parent string
+	// True if the branch is already based on the latest commit of its parent
+	// (so it wouldn't be rebased). For read-only branches, true if the branch
+	// is the same as its remote branch.
+	UpToDate bool
+	// True if the branch is read-only (so it would be updated from its remote
+	// branch instead of being rebased).
+	ReadOnly bool
+	// The commit that the branch would be rebased onto (unless it's
+	// up-to-date).
+	NewParentCommit string
+	// The commit that the branch would point to after the sync (unless it
+	// would conflict). For rebased branches, this is a simulated commit.
+	NewHead string
+	// The files that would conflict, if any.
+	Conflicts []string
+	// If set, the branch can't be checked because it's based on this branch,
+	// which would conflict.
+	BlockedBy string
+}
+
+// CheckSync predicts which of the given branches (in sync order) would run into
+// conflicts when synced, without changing any branch: the commits of each branch
+// are merged onto its prospective new parent in memory with git merge-tree, and
+// the resulting (conflict-free) commit is used as the new parent of its
+// children.
+//
+// Since all of the commits of a branch are merged at once instead of being
+// replayed one by one, the prediction can differ from the actual rebase in rare
+// cases (e.g., when a later commit of the branch reverts a conflicting change).
+// Read-only branches are checked against the version that was last fetched, and
+// merged pull requests aren't taken into account.
+func CheckSync(repo *git.Repo, tx meta.ReadTx, branches []string, opts SyncCheckOpts) ([]SyncCheckResult, error) {
+	// The (simulated) commits that the branches would point to after the sync.
+	heads := make(map[string]string)
+	// The conflicting branches that the branches depend on.
+	blocked := make(map[string]string)
+	var results []SyncCheckResult
+	for i, name := range branches {
+		branch, ok := tx.Branch(name)
+		if !ok {
+			continue
+		}
+		head, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + name})
+		if err != nil {
+			// The branch was deleted outside of av.
+			continue
+		}
+		res := SyncCheckResult{Branch: name, Parent: branch.Parent.Name}
+		if branch.ReadOnly {
+			res.ReadOnly = true
+			res.NewHead = head
+			if remote, err := repo.RevParse(&git.RevParse{Rev: "refs/remotes/origin/" + name}); err == nil {
+				res.NewHead = remote
+			}
+			res.UpToDate = res.NewHead == head
+			heads[name] = res.NewHead
+			results = append(results, res)
+			continue
+		}
+
+		parentTrunk := branch.Parent.Trunk
+		if i == 0 && opts.Parent != "" {
+			res.Parent = opts.Parent
+			_, tracked := tx.Branch(opts.Parent)
+			parentTrunk = !tracked
+		}
+		if conflicting, ok := blocked[res.Parent]; ok {
+			res.BlockedBy = conflicting
+			blocked[name] = conflicting
+			results = append(results, res)
+			continue
+		}
+
+		base, err := BranchBase(repo, tx, name)
+		if err != nil {
+			return nil, err
+		}
+		var newParent string
+		if h, ok := heads[res.Parent]; ok {
+			newParent = h
+		} else if parentTrunk && !opts.ToTrunk && res.Parent == branch.Parent.Name {
+			// Without --trunk, the stack roots stay where they are.
+			newParent = base
+		} else {
+			newParent, err = latestParentCommit(repo, res.Parent, parentTrunk)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if newParent == base {
+			res.UpToDate = true
+			res.NewHead = head
+			heads[name] = head
+			results = append(results, res)
+			continue
+		}
+		res.NewParentCommit = newParent
+
+		newHead, conflicts, err := simulateRebase(repo, base, head, newParent)
+		if err != nil {
+			return nil, errors.WrapIff(err, "failed to check branch %q", name)
+		}
+		if len(conflicts) > 0 {
+			res.Conflicts = conflicts
+			blocked[name] = name
+		} else {
+			res.NewHead = newHead
+			heads[name] = newHead
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// latestParentCommit returns the commit that a branch would be synced onto: the
+// latest commit of a trunk on origin (or the local trunk if it was never
+// pushed), or the HEAD of any other branch.
+func latestParentCommit(repo *git.Repo, parent string, trunk bool) (string, error) {
+	if trunk {
+		if commit, err := repo.RevParse(&git.RevParse{Rev: "refs/remotes/origin/" + parent}); err == nil {
+			return commit, nil
+		}
+	}
+	commit, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + parent})
+	if err != nil {
+		return "", errors.WrapIff(err, "failed to determine HEAD for branch %q", parent)
+	}
+	return commit, nil
+}
+
+// simulateRebase merges the changes between base and head onto newParent in
+// memory. It returns the commit that the rebased branch would point to, or the
+// conflicting files if the changes don't apply cleanly. No refs are changed.
+func simulateRebase(repo *git.Repo, base, head, newParent string) (string, []string, error) {
+	// git merge-tree computes the merge base itself (--merge-base requires Git
+	// 2.40), so merge two commits whose only common ancestor is base.
+	ours, err := repo.Git("commit-tree", newParent+"^{tree}", "-p", base, "-m", "av sync check")
+	if err != nil {
+		return "", nil, err
+	}
+	theirs, err := repo.Git("commit-tree", head+"^{tree}", "-p", base, "-m", "av sync check")
+	if err != nil {
+		return "", nil, err
+	}
+	out, err := repo.Run(&git.RunOpts{
+		Args: []string{"merge-tree", "--write-tree", "--name-only", "--no-messages", ours, theirs},
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	lines := out.Lines()
+	switch {
+	case out.ExitCode == 1 && len(lines) > 0:
+		return "", lines[1:], nil
+	case out.ExitCode != 0 || len(lines) == 0:
+		return "", nil, errors.Errorf("git merge-tree failed: %s", out.Stderr)
+	}
+	commit, err := repo.Git("commit-tree", lines[0], "-p", newParent, "-m", "av sync check")
+	if err != nil {
+		return "", nil, err
+	}
+	return commit, nil, nil
+}
@@ -0,0 +1,100 @@
+package actions
+
+import (
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+)
+
+// DirtyBranchesOpts are the options of DirtyBranches, which mirror the options
+// of the sync.
+type DirtyBranchesOpts struct {
+	// If true, the stack roots are synced onto the latest commits of their
+	// trunks (see SyncBranchOpts.ToTrunk).
+	ToTrunk bool
+	// If true, the branches aren't pushed, so it doesn't matter whether their
+	// remote branches are up-to-date.
+	NoPush bool
+}
+
+// DirtyBranches returns the given branches (in the same order) that a sync
+// would actually change, so that the others can be skipped without checking
+// them out or pushing them. A branch is dirty if:
+//   - it doesn't contain the latest commit of its parent (or, for a stack root,
+//     of its trunk with ToTrunk), or the recorded parent commit is outdated,
+//   - its parent was merged or is dirty itself,
+//   - it's read-only and differs from its remote branch, or
+//   - it would be pushed, i.e., it has a pull request and differs from its
+//     remote branch (unless NoPush).
+//
+// This only looks at the local repository (as of the last fetch), so pull
+// requests that were merged since the last sync aren't taken into account.
+func DirtyBranches(repo *git.Repo, tx meta.ReadTx, branches []string, opts DirtyBranchesOpts) ([]string, error) {
+	dirty := make(map[string]bool)
+	var res []string
+	for _, name := range branches {
+		ok, err := isBranchDirty(repo, tx, name, dirty, opts)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			dirty[name] = true
+			res = append(res, name)
+		}
+	}
+	return res, nil
+}
+
+func isBranchDirty(
+	repo *git.Repo,
+	tx meta.ReadTx,
+	name string,
+	dirty map[string]bool,
+	opts DirtyBranchesOpts,
+) (bool, error) {
+	branch, ok := tx.Branch(name)
+	if !ok {
+		return true, nil
+	}
+	head, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + name})
+	if err != nil {
+		// Let the sync deal with the missing branch.
+		return true, nil
+	}
+	remote, err := repo.RevParse(&git.RevParse{Rev: "refs/remotes/origin/" + name})
+	if err != nil {
+		remote = ""
+	}
+	if branch.ReadOnly {
+		return remote != "" && remote != head, nil
+	}
+
+	parent := branch.Parent
+	if parent.Trunk {
+		if opts.ToTrunk {
+			trunkHead, err := latestParentCommit(repo, parent.Name, true)
+			if err != nil {
+				return false, err
+			}
+			if contained, err := repo.IsAncestor(trunkHead, head); err != nil || !contained {
+				return true, nil
+			}
+		}
+	} else {
+		parentBranch, ok := tx.Branch(parent.Name)
+		if !ok || parentBranch.MergeCommit != "" || dirty[parent.Name] {
+			return true, nil
+		}
+		parentHead, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + parent.Name})
+		if err != nil || parentHead != parent.Head {
+			return true, nil
+		}
+		if contained, err := repo.IsAncestor(parentHead, head); err != nil || !contained {
+			return true, nil
+		}
+	}
+
+	if opts.NoPush || branch.WIP || branch.PullRequest == nil || branch.PullRequest.ID == "" {
+		return false, nil
+	}
+	return remote != head, nil
+}
@@ -0,0 +1,146 @@
+package actions
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/sirupsen/logrus"
+)
+
+// generatedFilesCommand is a command that regenerates the given conflicting
+// files (see config.Sync.GeneratedFiles).
+type generatedFilesCommand struct {
+	Command string
+	Files   []string
+}
+
+// generatedFilesCommands returns the commands that regenerate the given
+// conflicting files in the order of the configuration. Returns false if any of
+// the files isn't generated (in which case the conflict has to be resolved by
+// hand) or if there are no files.
+func generatedFilesCommands(generated []config.GeneratedFiles, files []string) ([]generatedFilesCommand, bool) {
+	if len(files) == 0 {
+		return nil, false
+	}
+	commands := make([]generatedFilesCommand, len(generated))
+	for _, file := range files {
+		i := matchGeneratedFiles(generated, file)
+		if i < 0 {
+			return nil, false
+		}
+		commands[i].Command = generated[i].Command
+		commands[i].Files = append(commands[i].Files, file)
+	}
+	var res []generatedFilesCommand
+	for _, command := range commands {
+		if len(command.Files) > 0 {
+			res = append(res, command)
+		}
+	}
+	return res, true
+}
+
+// matchGeneratedFiles returns the index of the first entry of generated whose
+// paths match the given file, or -1 if there's none.
+func matchGeneratedFiles(generated []config.GeneratedFiles, file string) int {
+	for i, entry := range generated {
+		if entry.Command == "" {
+			continue
+		}
+		for _, pattern := range entry.Paths {
+			name := file
+			if !strings.Contains(pattern, "/") {
+				name = path.Base(file)
+			}
+			ok, err := path.Match(pattern, name)
+			if err != nil {
+				logrus.WithError(err).WithField("pattern", pattern).Debug("invalid generated files pattern")
+				continue
+			}
+			if ok {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// regenerateConflictingFiles resolves the conflicts of the current rebase (or
+// merge) if they're only in generated files: it runs the commands that
+// regenerate the files and stages the results. Returns false if the conflicts
+// weren't resolved (e.g., because other files conflict as well or because a
+// command failed) and have to be resolved by hand.
+func regenerateConflictingFiles(repo *git.Repo) (bool, error) {
+	if len(config.Av.Sync.GeneratedFiles) == 0 {
+		return false, nil
+	}
+	files, err := repo.UnmergedFiles()
+	if err != nil {
+		return false, err
+	}
+	commands, ok := generatedFilesCommands(config.Av.Sync.GeneratedFiles, files)
+	if !ok {
+		return false, nil
+	}
+	for _, command := range commands {
+		_, _ = fmt.Fprint(os.Stderr,
+			"  - regenerating conflicting file(s) ", colors.UserInput(strings.Join(command.Files, ", ")),
+			" with ", colors.CliCmd(command.Command), "\n",
+		)
+		// The files are passed as the positional parameters of the script
+		// ("$@"), the first argument is $0.
+		cmd := exec.Command("sh", append([]string{"-c", command.Command, "sh"}, command.Files...)...)
+		cmd.Dir = repo.Dir()
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			_, _ = fmt.Fprint(os.Stderr,
+				"  - ", colors.Warning("WARNING:"), " failed to regenerate the files: ", err.Error(), "\n",
+			)
+			return false, nil
+		}
+		if _, err := repo.Run(&git.RunOpts{
+			Args:      append([]string{"add", "--all", "--"}, command.Files...),
+			ExitError: true,
+		}); err != nil {
+			return false, errors.WrapIf(err, "failed to stage the regenerated files")
+		}
+	}
+	return true, nil
+}
+
+// rebaseRegeneratingConflicts continues the given rebase as long as it stops
+// at conflicts that are only in generated files (see
+// regenerateConflictingFiles). Returns the result of the last rebase.
+func rebaseRegeneratingConflicts(repo *git.Repo, rebase *git.RebaseResult) (*git.RebaseResult, error) {
+	for rebase.Status == git.RebaseConflict {
+		if ok, err := regenerateConflictingFiles(repo); err != nil || !ok {
+			return rebase, err
+		}
+		var err error
+		if rebase, err = repo.RebaseParse(git.RebaseOpts{Continue: true}); err != nil {
+			return nil, err
+		}
+	}
+	return rebase, nil
+}
+
+// mergeRegeneratingConflicts commits the given merge if it stopped at
+// conflicts that are only in generated files (see regenerateConflictingFiles).
+// Returns the result of the merge.
+func mergeRegeneratingConflicts(repo *git.Repo, merge *git.MergeResult) (*git.MergeResult, error) {
+	if merge.Status != git.MergeConflict {
+		return merge, nil
+	}
+	if ok, err := regenerateConflictingFiles(repo); err != nil || !ok {
+		return merge, err
+	}
+	return repo.Merge(git.MergeOpts{Continue: true})
+}
@@ -6,12 +6,16 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"slices"
+	"strings"
 
 	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/config"
 	"github.com/aviator-co/av/internal/gh"
 	"github.com/aviator-co/av/internal/git"
 	"github.com/aviator-co/av/internal/meta"
 	"github.com/aviator-co/av/internal/utils/colors"
+	"golang.org/x/exp/maps"
 )
 
 // StackSyncConfig contains the configuration for a sync operation.
@@ -34,6 +38,102 @@ type StackSyncConfig struct {
 	Parent string `json:"parent"`
 	// If set, delete the merged branches.
 	Prune bool `json:"prune"`
+	// How the branches are updated with the changes of their parents (empty
+	// means SyncStrategyRebase).
+	Strategy SyncStrategy `json:"strategy,omitempty"`
+	// If set, reviews are re-requested from the reviewers who approved a pull
+	// request before its branch was pushed (see keepReviewers).
+	ReviewersKeep bool `json:"reviewersKeep,omitempty"`
+	// If set, a comment that summarizes what changed since the previous push
+	// is posted on (or updated in) the pull requests that are pushed (see
+	// postSyncSummary).
+	SummaryComment bool `json:"summaryComment,omitempty"`
+	// The options of the merge strategy that the branches are rebased (or
+	// merged) with (see ParseStrategyOptions). If nil, the sync.strategyOptions
+	// config is used.
+	StrategyOptions []string `json:"strategyOptions,omitempty"`
+}
+
+// SyncStrategy is how a sync updates a branch with the changes of its parent.
+type SyncStrategy string
+
+const (
+	// SyncStrategyRebase rebases the branch onto its parent (and force-pushes
+	// it).
+	SyncStrategyRebase SyncStrategy = "rebase"
+	// SyncStrategyMerge merges the parent into the branch instead, so that the
+	// history of the branch is append-only and it never has to be
+	// force-pushed.
+	SyncStrategyMerge SyncStrategy = "merge"
+)
+
+// ParseSyncStrategy parses the name of a sync strategy ("rebase" or "merge").
+func ParseSyncStrategy(name string) (SyncStrategy, error) {
+	switch strategy := SyncStrategy(strings.ToLower(name)); strategy {
+	case "", SyncStrategyRebase:
+		return SyncStrategyRebase, nil
+	case SyncStrategyMerge:
+		return strategy, nil
+	}
+	return "", errors.Errorf("unknown sync strategy %q (expected rebase or merge)", name)
+}
+
+// strategyOptionNames are the options of Git's ort merge strategy (see
+// git-merge(1)), some of which take a value (e.g., "find-renames=50%").
+var strategyOptionNames = []string{
+	"ours",
+	"theirs",
+	"ignore-space-change",
+	"ignore-all-space",
+	"ignore-space-at-eol",
+	"ignore-cr-at-eol",
+	"renormalize",
+	"no-renormalize",
+	"find-renames",
+	"rename-threshold",
+	"subtree",
+	"diff-algorithm",
+}
+
+// diffAlgorithms are the diff algorithms that can be given as a strategy
+// option by name.
+var diffAlgorithms = []string{"histogram", "minimal", "myers", "patience"}
+
+// ParseStrategyOptions validates the options of the merge strategy that
+// branches are rebased (or merged) with, as given to `git rebase -X`. For
+// example, "theirs" makes the changes of the branch win over its parent's in
+// conflicting hunks when rebasing. A diff algorithm can be given by its name
+// (e.g., "histogram" for "diff-algorithm=histogram").
+func ParseStrategyOptions(options []string) ([]string, error) {
+	res := make([]string, 0, len(options))
+	for _, option := range options {
+		option = strings.TrimSpace(option)
+		if slices.Contains(diffAlgorithms, option) {
+			option = "diff-algorithm=" + option
+		}
+		name, _, _ := strings.Cut(option, "=")
+		if !slices.Contains(strategyOptionNames, name) {
+			return nil, errors.Errorf(
+				"unknown strategy option %q (expected one of %s, or a diff algorithm: %s)",
+				option, strings.Join(strategyOptionNames, ", "), strings.Join(diffAlgorithms, ", "),
+			)
+		}
+		res = append(res, option)
+	}
+	return res, nil
+}
+
+// defaultStrategyOptions returns the given strategy options, or the ones from
+// the sync.strategyOptions config if they're nil.
+func defaultStrategyOptions(options []string) ([]string, error) {
+	if options != nil {
+		return options, nil
+	}
+	options, err := ParseStrategyOptions(config.Av.Sync.StrategyOptions)
+	if err != nil {
+		return nil, errors.WrapIf(err, "invalid sync.strategyOptions config")
+	}
+	return options, nil
 }
 
 // StackSyncState is the state of an in-progress sync operation.
@@ -46,15 +146,31 @@ type StackSyncState struct {
 	CurrentBranch string `json:"currentBranch"`
 	// All of the branches that are being synced (including branches that have
 	// already been synced).
-	// TODO: We should probably store the original HEAD commit for each branch
-	//       and revert each branch individually if we --abort.
 	Branches []string `json:"branches"`
+	// The state of the branches before the sync started, so that they can be
+	// restored if the sync is aborted (see RecordOriginalBranches).
+	OriginalBranches map[string]OriginalBranchState `json:"originalBranches,omitempty"`
+	// The branches that were synced so far and have to be pushed once all of
+	// the branches are synced (see PushSyncedBranches).
+	Pushes []string `json:"pushes,omitempty"`
 	// The continuation state for the current branch.
 	Continuation *SyncBranchContinuation `json:"continuation,omitempty"`
+	// The stash commit of the changes in the working tree that were stashed
+	// before the sync started (see CreateAutostash). They're reapplied once the
+	// sync is done.
+	Autostash string `json:"autostash,omitempty"`
 	// The config of the sync.
 	Config StackSyncConfig `json:"config"`
 }
 
+// OriginalBranchState is the state of a branch before a sync started.
+type OriginalBranchState struct {
+	// The HEAD commit of the branch.
+	Head string `json:"head"`
+	// The parent of the branch.
+	Parent meta.BranchState `json:"parent"`
+}
+
 type (
 	SyncStackOpt  func(*syncStackOpts)
 	syncStackOpts struct {
@@ -76,6 +192,11 @@ func WithLocalOnly() SyncStackOpt {
 }
 
 // SyncStack performs stack sync on all branches in branchesToSync. Branches may span multiple "stacks".
+//
+// If a branch can't be rebased because of a conflict, the sync of the branch
+// (and its descendants) is postponed until the unrelated branches (e.g., its
+// siblings) are synced. The sync then stops at the conflict so that it can be
+// resolved and resumed with `av stack sync --continue`.
 func SyncStack(ctx context.Context,
 	repo *git.Repo,
 	client *gh.Client,
@@ -90,40 +211,46 @@ func SyncStack(ctx context.Context,
 	}
 
 	state.Branches = branchesToSync
-	skip := opts.skipNextCommit
-	for i, currentBranch := range branchesToSync {
-		if i > 0 {
-			// Add spacing in the output between each branch sync
-			_, _ = fmt.Fprint(os.Stderr, "\n\n")
+	if err := RecordOriginalBranches(repo, tx, &state, branchesToSync); err != nil {
+		return err
+	}
+	conflicts, postponed, err := syncStackBranches(ctx, repo, client, tx, branchesToSync, &state, opts, true)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		_, _ = fmt.Fprint(os.Stderr,
+			"\n\n", colors.Failure("Could not sync the following branches because of conflicts:"), "\n",
+		)
+		for _, conflict := range conflicts {
+			descendants := meta.SubsequentBranches(tx, conflict)
+			_, _ = fmt.Fprint(os.Stderr, "  - ", colors.UserInput(conflict))
+			if len(descendants) > 0 {
+				_, _ = fmt.Fprint(os.Stderr,
+					" (and its descendants ", colors.UserInput(strings.Join(descendants, ", ")), ")",
+				)
+			}
+			_, _ = fmt.Fprint(os.Stderr, "\n")
 		}
-		state.CurrentBranch = currentBranch
-		cont, err := SyncBranch(ctx, repo, client, tx, SyncBranchOpts{
-			Branch:       currentBranch,
-			Fetch:        !state.Config.NoFetch && !opts.localOnly,
-			Push:         !state.Config.NoPush && !opts.localOnly,
-			Continuation: state.Continuation,
-			ToTrunk:      state.Config.Trunk,
-			Skip:         skip,
-		})
-		if err != nil {
+		_, _ = fmt.Fprint(os.Stderr, "Restoring the conflict in ", colors.UserInput(conflicts[0]), "...\n\n")
+
+		// Sync the postponed branches again without postponing conflicts, so
+		// that the sync stops at the first one.
+		state.Branches = postponed
+		opts.skipNextCommit = false
+		if _, _, err := syncStackBranches(ctx, repo, client, tx, postponed, &state, opts, false); err != nil {
 			return err
 		}
-		if cont != nil {
-			state.Continuation = cont
-			if err := WriteStackSyncState(repo, &state); err != nil {
-				return errors.Wrap(err, "failed to write stack sync state")
-			}
-			if err := tx.Commit(); err != nil {
-				return err
-			}
-			return ErrExitSilently{ExitCode: 1}
-		}
-		state.Continuation = nil
-		// If skip was specified, it was because the sync was interrupted by a
-		// conflict. The user wanted to skip a commit and continue the sync. If
-		// we get here, the rebase succeeded, and it doesn't make sense to start
-		// subsequent rebases with `git rebase --skip`.
-		skip = false
+	}
+
+	// Push the branches only once they're all synced so that they can be
+	// pushed at once. Even if some of them can't be pushed, the sync itself is
+	// done, so it's still completed before the error is returned.
+	var pushErr error
+	if len(state.Pushes) > 0 {
+		_, _ = fmt.Fprint(os.Stderr, "\n\n")
+		pushErr = PushSyncedBranches(ctx, repo, client, tx, state)
+		state.Pushes = nil
 	}
 
 	if state.Config.Prune {
@@ -216,12 +343,50 @@ func SyncStack(ctx context.Context,
 		}
 	}
 
+	// Adding, reordering, or merging branches changes the stack of every pull
+	// request in the stack (not only of the branches that were pushed), so
+	// refresh all of them once the merged branches are deleted.
+	if client != nil && !state.Config.NoPush && !opts.localOnly {
+		// The pull requests of the children of merged branches are still based
+		// on the merged branches (unless they were pushed by the sync).
+		retargeted, err := RetargetPullRequests(ctx, client, tx, branchesToSync)
+		if len(retargeted) > 0 {
+			_, _ = fmt.Fprint(os.Stderr, "\nUpdating the base branches of the pull requests...\n")
+			PrintRetargetResults(retargeted)
+		}
+		if IsOffline(err) {
+			err = QueueRetarget(repo, branchesToSync...)
+			if err == nil {
+				_, _ = fmt.Fprint(os.Stderr,
+					"\n", colors.Warning("GitHub can't be reached:"),
+					" queued the update of the base branches of the pull requests (run ",
+					colors.CliCmd("av push --pending"), " once you're online)\n",
+				)
+			}
+		}
+		if err != nil {
+			_, _ = fmt.Fprint(os.Stderr,
+				"\n", colors.Warning("WARNING:"), " failed to update the base branches of the pull requests: ", err.Error(), "\n",
+			)
+		}
+		if err := RefreshPullRequestStacks(ctx, client, repo, tx, branchesToSync, state.OriginalBranches); err != nil {
+			_, _ = fmt.Fprint(os.Stderr,
+				"\n", colors.Warning("WARNING:"), " failed to update the stack in the pull requests: ", err.Error(), "\n",
+			)
+		}
+	}
+
 	// Return to the original branch
 	if state.OriginalBranch != "" {
 		if _, err := repo.CheckoutBranch(&git.CheckoutBranch{Name: state.OriginalBranch}); err != nil {
 			return err
 		}
 	}
+	if state.Autostash != "" {
+		if err := ApplyAutostash(repo, state.Autostash); err != nil {
+			return err
+		}
+	}
 	if err := WriteStackSyncState(repo, nil); err != nil {
 		return errors.Wrap(err, "failed to write stack sync state")
 	}
@@ -229,9 +394,173 @@ func SyncStack(ctx context.Context,
 		return err
 	}
 
+	return pushErr
+}
+
+// PushSyncedBranches pushes the branches that were synced (see
+// StackSyncState.Pushes) with a single git push and updates their pull
+// requests. Branches that can't be pushed because GitHub can't be reached are
+// queued (see PushPendingUpdates). Returns an error if any of the branches
+// couldn't be pushed.
+func PushSyncedBranches(
+	ctx context.Context,
+	repo *git.Repo,
+	client *gh.Client,
+	tx meta.WriteTx,
+	state StackSyncState,
+) error {
+	_, _ = fmt.Fprint(os.Stderr, "Pushing the synced branches...\n")
+	strategy := state.Config.Strategy
+	var pushes []*syncPush
+	// The pull requests that were converted to drafts for the push are only
+	// marked as ready for review again once they're updated (see
+	// finishSyncPush), so they mustn't be left as drafts if that doesn't
+	// happen (e.g., because a branch couldn't be pushed).
+	defer func() {
+		for _, push := range pushes {
+			restoreSyncPushDraft(ctx, repo, client, push)
+		}
+	}()
+	var names []string
+	for _, name := range state.Pushes {
+		if _, ok := tx.Branch(name); !ok {
+			continue
+		}
+		push, err := prepareSyncPush(ctx, repo, client, tx, name, nil)
+		if IsOffline(err) {
+			if err := queueOfflinePush(repo, name, strategy, err); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+		if push == nil {
+			continue
+		}
+		if state.Config.ReviewersKeep || state.Config.SummaryComment {
+			recordRemoteHead(repo, push)
+		}
+		if state.Config.ReviewersKeep {
+			recordApprovals(ctx, client, push)
+		}
+		pushes = append(pushes, push)
+		names = append(names, name)
+	}
+
+	failed, err := PushBranches(repo, names, syncPushOpts(strategy))
+	if IsOffline(err) {
+		for _, name := range names {
+			if err := queueOfflinePush(repo, name, strategy, err); err != nil {
+				return err
+			}
+		}
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, push := range pushes {
+		if failed[push.branchName] != nil {
+			continue
+		}
+		err := finishSyncPush(ctx, repo, client, tx, push)
+		if IsOffline(err) {
+			if err := queueOfflinePush(repo, push.branchName, strategy, err); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := finishPendingUpdate(ctx, repo, client, tx, push.branchName); err != nil {
+			return err
+		}
+		// Both comments would show the same range-diff, so the summary comment
+		// shows it for both of them.
+		summarize := state.Config.SummaryComment && push.oldHead != ""
+		if len(push.approvedBy) > 0 {
+			keepReviewers(ctx, repo, client, tx, push, state.OriginalBranches[push.branchName], summarize)
+		}
+		if summarize {
+			postSyncSummary(ctx, repo, client, tx, push, state.OriginalBranches[push.branchName])
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("failed to push %d branch(es)", len(failed))
+	}
+	return nil
+}
+
+// RecordOriginalBranches records the HEAD and the parent of the given branches
+// in the sync state (unless they were recorded already, i.e., when continuing a
+// sync) so that RestoreOriginalBranches can undo the sync.
+func RecordOriginalBranches(repo *git.Repo, tx meta.ReadTx, state *StackSyncState, branches []string) error {
+	for _, name := range branches {
+		if _, ok := state.OriginalBranches[name]; ok {
+			continue
+		}
+		branch, ok := tx.Branch(name)
+		if !ok {
+			continue
+		}
+		head, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + name})
+		if err != nil {
+			return errors.WrapIff(err, "failed to determine HEAD for branch %q", name)
+		}
+		if state.OriginalBranches == nil {
+			state.OriginalBranches = make(map[string]OriginalBranchState)
+		}
+		state.OriginalBranches[name] = OriginalBranchState{Head: head, Parent: branch.Parent}
+	}
 	return nil
 }
 
+// RestoreOriginalBranches resets the branches that were recorded in the sync
+// state (see RecordOriginalBranches) to their original HEAD and parent. Any
+// rebase in progress must have been aborted already. Returns the names of the
+// branches that were changed.
+func RestoreOriginalBranches(repo *git.Repo, tx meta.WriteTx, state StackSyncState) ([]string, error) {
+	var restored []string
+	names := maps.Keys(state.OriginalBranches)
+	slices.Sort(names)
+	for _, name := range names {
+		original := state.OriginalBranches[name]
+		branch, ok := tx.Branch(name)
+		if !ok {
+			continue
+		}
+		head, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + name})
+		if err != nil {
+			// The branch was deleted in the meantime.
+			continue
+		}
+		if head == original.Head && branch.Parent == original.Parent {
+			continue
+		}
+		if head != original.Head {
+			// Make sure that the branch isn't checked out so that the working
+			// tree doesn't end up with the changes of the sync.
+			if current, err := repo.CurrentBranchName(); err == nil && current == name {
+				if _, err := repo.Git("switch", "--detach"); err != nil {
+					return nil, errors.WrapIf(err, "failed to detach HEAD")
+				}
+			}
+			if err := repo.UpdateRef(&git.UpdateRef{
+				Ref: "refs/heads/" + name,
+				New: original.Head,
+				Old: head,
+			}); err != nil {
+				return nil, errors.WrapIff(err, "failed to restore branch %q", name)
+			}
+		}
+		branch.Parent = original.Parent
+		tx.SetBranch(branch)
+		restored = append(restored, name)
+	}
+	return restored, nil
+}
+
 const stackSyncStateFile = "stack-sync.state.json"
 
 func ReadStackSyncState(repo *git.Repo) (StackSyncState, error) {
@@ -273,3 +602,111 @@ func WriteStackSyncState(repo *git.Repo, state *StackSyncState) error {
 	}
 	return os.WriteFile(path.Join(avDir, stackSyncStateFile), data, 0644)
 }
+
+// syncStackBranches syncs the given branches (in order). If a branch runs into a
+// conflict, the sync stops (returning ErrExitSilently) unless postponeConflicts
+// is set and there are other branches left to sync that don't depend on the
+// conflicting branch. In that case, the rebase (or merge) is aborted and the
+// branch (and its descendants) are skipped. Returns the conflicting branches and
+// all of the branches that were skipped (in order).
+func syncStackBranches(
+	ctx context.Context,
+	repo *git.Repo,
+	client *gh.Client,
+	tx meta.WriteTx,
+	branches []string,
+	state *StackSyncState,
+	opts *syncStackOpts,
+	postponeConflicts bool,
+) (conflicts []string, postponed []string, err error) {
+	strategyOptions, err := defaultStrategyOptions(state.Config.StrategyOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	skipped := make(map[string]bool)
+	skip := opts.skipNextCommit
+	for i, currentBranch := range branches {
+		if skipped[currentBranch] {
+			postponed = append(postponed, currentBranch)
+			continue
+		}
+		if i > 0 {
+			// Add spacing in the output between each branch sync
+			_, _ = fmt.Fprint(os.Stderr, "\n\n")
+		}
+		state.CurrentBranch = currentBranch
+		cont, err := SyncBranch(ctx, repo, client, tx, SyncBranchOpts{
+			Branch:          currentBranch,
+			Fetch:           !state.Config.NoFetch && !opts.localOnly,
+			Push:            !state.Config.NoPush && !opts.localOnly,
+			Continuation:    state.Continuation,
+			ToTrunk:         state.Config.Trunk,
+			Skip:            skip,
+			Strategy:        state.Config.Strategy,
+			StrategyOptions: strategyOptions,
+			Pushes:          &state.Pushes,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		// A conflict that comes up while continuing a sync isn't postponed
+		// since that would throw away the resolution of the previous conflict.
+		if cont != nil && postponeConflicts && state.Continuation == nil {
+			descendants := meta.SubsequentBranches(tx, currentBranch)
+			independent := slices.ContainsFunc(branches[i+1:], func(name string) bool {
+				return !skipped[name] && !slices.Contains(descendants, name)
+			})
+			if independent {
+				if err := abortSyncBranch(repo, state.Config.Strategy); err != nil {
+					return nil, nil, errors.WrapIff(err, "failed to abort the sync of %q", currentBranch)
+				}
+				_, _ = fmt.Fprint(os.Stderr,
+					"  - postponing the sync of ", colors.UserInput(currentBranch),
+					" until the rest of the stack is synced\n",
+				)
+				conflicts = append(conflicts, currentBranch)
+				postponed = append(postponed, currentBranch)
+				for _, name := range descendants {
+					skipped[name] = true
+				}
+				continue
+			}
+		}
+		if cont != nil {
+			if len(postponed) > 0 {
+				// `av stack sync --continue` resumes with the branches after the
+				// current one, so the postponed branches (and the skipped
+				// descendants that come later) are moved after the remaining
+				// branches so that they're synced once the conflict is resolved.
+				var remaining []string
+				for _, name := range branches[i:] {
+					if skipped[name] {
+						postponed = append(postponed, name)
+					} else {
+						remaining = append(remaining, name)
+					}
+				}
+				state.Branches = append(remaining, postponed...)
+				_, _ = fmt.Fprint(os.Stderr,
+					"  - the postponed branches (", colors.UserInput(strings.Join(postponed, ", ")),
+					") will be synced after this conflict is resolved\n",
+				)
+			}
+			state.Continuation = cont
+			if err := WriteStackSyncState(repo, state); err != nil {
+				return nil, nil, errors.Wrap(err, "failed to write stack sync state")
+			}
+			if err := tx.Commit(); err != nil {
+				return nil, nil, err
+			}
+			return nil, nil, ErrExitSilently{ExitCode: 1}
+		}
+		state.Continuation = nil
+		// If skip was specified, it was because the sync was interrupted by a
+		// conflict. The user wanted to skip a commit and continue the sync. If
+		// we get here, the rebase succeeded, and it doesn't make sense to start
+		// subsequent rebases with `git rebase --skip`.
+		skip = false
+	}
+	return conflicts, postponed, nil
+}
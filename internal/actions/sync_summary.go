@@ -0,0 +1,150 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/utils/colors"
+	"github.com/sirupsen/logrus"
+)
+
+// The hidden marker that identifies the summary comment of a pull request, so
+// that it's updated instead of posting a new comment after every sync.
+const syncSummaryMarker = "<!-- av sync summary -->"
+
+// rangeDiffLineRegex matches the lines of git range-diff that pair up the old
+// and the new commits, e.g. "1:  f4f8330 ! 1:  b8a58cf Fix the widget" (the
+// indented lines that follow show the changes).
+var rangeDiffLineRegex = regexp.MustCompile(`^\s*(?:\d+|-):\s+(?:[0-9a-f]+|-+) ([=!<>])\s+(?:\d+|-):\s+(?:[0-9a-f]+|-+) (.*)$`)
+
+// RangeDiffSummary is the commit subjects of a range-diff (see RangeDiff),
+// grouped by how the commits changed.
+type RangeDiffSummary struct {
+	// The commits that are the same, except for their base.
+	Unchanged []string
+	// The commits whose changes (or message) changed.
+	Changed []string
+	// The commits that only exist in the new range.
+	Added []string
+	// The commits that only exist in the old range.
+	Removed []string
+}
+
+// Clean returns true if no commit changed (e.g., when the branch was only
+// rebased onto a new commit of its parent without conflicts).
+func (s RangeDiffSummary) Clean() bool {
+	return len(s.Changed) == 0 && len(s.Added) == 0 && len(s.Removed) == 0
+}
+
+// SummarizeRangeDiff groups the commits of the given git range-diff output by
+// how they changed.
+func SummarizeRangeDiff(rangeDiff string) RangeDiffSummary {
+	var summary RangeDiffSummary
+	for _, line := range strings.Split(rangeDiff, "\n") {
+		m := rangeDiffLineRegex.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		switch m[1] {
+		case "=":
+			summary.Unchanged = append(summary.Unchanged, m[2])
+		case "!":
+			summary.Changed = append(summary.Changed, m[2])
+		case ">":
+			summary.Added = append(summary.Added, m[2])
+		case "<":
+			summary.Removed = append(summary.Removed, m[2])
+		}
+	}
+	return summary
+}
+
+// postSyncSummary posts a comment on the pull request of the branch that was
+// just pushed with a summary of what changed since the previous push, so that
+// reviewers can tell whether they need to review it again. The previous summary
+// comment (if any) is updated instead of posting a new one. Failures are only
+// reported as warnings since the branch was pushed already.
+func postSyncSummary(
+	ctx context.Context,
+	repo *git.Repo,
+	client *gh.Client,
+	tx meta.ReadTx,
+	push *syncPush,
+	original OriginalBranchState,
+) {
+	newHead, err := repo.RevParse(&git.RevParse{Rev: "refs/heads/" + push.branchName})
+	if err != nil || newHead == push.oldHead {
+		// The branch wasn't pushed (e.g., it was up-to-date already).
+		return
+	}
+	rangeDiff, err := branchRangeDiff(repo, tx, push, original, newHead)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to compare the branch with the previous push")
+		return
+	}
+	branch, _ := tx.Branch(push.branchName)
+	body := syncSummaryComment(branch.Parent.Name, push.oldHead, newHead, rangeDiff)
+
+	comments, err := client.PullRequestComments(ctx, push.pr.ID)
+	if err == nil {
+		var commentID string
+		for _, comment := range comments {
+			if comment.ViewerDidAuthor && strings.HasPrefix(comment.Body, syncSummaryMarker) {
+				commentID = comment.ID
+			}
+		}
+		if commentID != "" {
+			err = client.UpdateComment(ctx, commentID, body)
+		} else {
+			err = client.AddComment(ctx, push.pr.ID, body)
+		}
+	}
+	if err != nil {
+		_, _ = fmt.Fprint(os.Stderr,
+			"  - ", colors.Warning("WARNING:"), " failed to post the summary comment: ", err.Error(), "\n",
+		)
+		return
+	}
+	_, _ = fmt.Fprint(os.Stderr,
+		"  - posted the summary of the changes on pull request ", colors.UserInput("#", push.pr.Number), "\n",
+	)
+}
+
+func syncSummaryComment(parent, oldHead, newHead, rangeDiff string) string {
+	summary := SummarizeRangeDiff(rangeDiff)
+	var sb strings.Builder
+	sb.WriteString(syncSummaryMarker)
+	sb.WriteString("\n")
+	_, _ = fmt.Fprintf(&sb, "This branch was updated by `av stack sync` (%s → %s, based on `%s`).\n\n",
+		git.ShortSha(oldHead), git.ShortSha(newHead), parent)
+	if summary.Clean() {
+		sb.WriteString("**No new changes:** the commits are the same as before apart from their base, ")
+		sb.WriteString("so there's nothing new to review.\n")
+		return sb.String()
+	}
+	sb.WriteString("**The commits changed** since the previous push:\n\n")
+	for _, group := range []struct {
+		label    string
+		subjects []string
+	}{
+		{"changed", summary.Changed},
+		{"added", summary.Added},
+		{"removed", summary.Removed},
+	} {
+		for _, subject := range group.subjects {
+			_, _ = fmt.Fprintf(&sb, "- %s: %s\n", group.label, subject)
+		}
+	}
+	rangeDiff = TruncateRangeDiff(rangeDiff, maxRangeDiffCommentLength)
+	sb.WriteString("\n<details>\n<summary>Changes since the previous push (<code>git range-diff</code>)</summary>\n\n")
+	sb.WriteString("```\n")
+	sb.WriteString(rangeDiff)
+	sb.WriteString("\n```\n\n</details>\n")
+	return sb.String()
+}
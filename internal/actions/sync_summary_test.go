@@ -0,0 +1,32 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeRangeDiff(t *testing.T) {
+	summary := actions.SummarizeRangeDiff(` 1:  f4f8330 =  1:  b8a58cf Add the widget
+ 2:  cda56d4 !  2:  16a49a8 Fix the widget
+    @@ widget.go
+     func Widget() {
+    -	return 1
+    +	return 2
+     }
+ 3:  0c1d2e3 <  -:  ------- Remove the gadget
+ -:  ------- >  3:  9a8b7c6 Rename the gadget
+10:  1234567 = 11:  89abcde Update the docs`)
+	require.Equal(t, actions.RangeDiffSummary{
+		Unchanged: []string{"Add the widget", "Update the docs"},
+		Changed:   []string{"Fix the widget"},
+		Added:     []string{"Rename the gadget"},
+		Removed:   []string{"Remove the gadget"},
+	}, summary)
+	require.False(t, summary.Clean())
+
+	summary = actions.SummarizeRangeDiff("1:  f4f8330 = 1:  b8a58cf Add the widget")
+	require.True(t, summary.Clean())
+	require.True(t, actions.SummarizeRangeDiff("").Clean())
+}
@@ -0,0 +1,43 @@
+package actions
+
+import (
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+)
+
+// ChangesOnTrunk returns the name of the trunk branch (see meta.IsTrunk) if
+// it's currently checked out and there are staged changes (or, if all is true,
+// unstaged changes to tracked files as with `git commit --all`), i.e., it looks
+// like the user is about to commit directly to the trunk. Otherwise, an empty
+// string is returned.
+func ChangesOnTrunk(repo *git.Repo, tx meta.ReadTx, all bool) (string, error) {
+	if dh, err := repo.DetachedHead(); err != nil || dh {
+		return "", err
+	}
+	currentBranch, err := repo.CurrentBranchName()
+	if err != nil {
+		return "", err
+	}
+	defaultBranch, err := repo.DefaultBranch()
+	if err != nil {
+		return "", err
+	}
+	if !meta.IsTrunk(tx, currentBranch, defaultBranch) {
+		return "", nil
+	}
+	hasChanges, err := repo.HasChangesToBeCommitted()
+	if err != nil {
+		return "", err
+	}
+	if !hasChanges && all {
+		status, err := repo.Status()
+		if err != nil {
+			return "", err
+		}
+		hasChanges = len(status.Unstaged) > 0
+	}
+	if !hasChanges {
+		return "", nil
+	}
+	return currentBranch, nil
+}
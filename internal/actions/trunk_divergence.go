@@ -0,0 +1,73 @@
+package actions
+
+import (
+	"strconv"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/git"
+	"github.com/aviator-co/av/internal/meta"
+)
+
+// TrunkDivergence describes how far a stack has fallen behind its trunk on
+// origin.
+type TrunkDivergence struct {
+	// The root branch of the stack.
+	Root string
+	// The trunk of the stack.
+	Trunk string
+	// The number of commits of the remote trunk that the stack root isn't
+	// based on.
+	Behind int
+	// The branches of the stack that would conflict if the stack was synced
+	// onto the latest commit of the trunk (see CheckSync).
+	Conflicts []SyncCheckResult
+}
+
+// CheckTrunkDivergence determines how far the stack of the given branch is
+// behind its trunk on origin and predicts whether syncing the stack onto the
+// latest trunk (as with `av stack sync --trunk`) would conflict. Returns nil if
+// the branch isn't in a stack or the trunk was never pushed.
+//
+// The remote trunk isn't fetched, so this is only as up-to-date as the last
+// fetch.
+func CheckTrunkDivergence(repo *git.Repo, tx meta.ReadTx, branchName string) (*TrunkDivergence, error) {
+	root, ok := meta.Root(tx, branchName)
+	if !ok {
+		return nil, nil
+	}
+	trunk, _ := meta.Trunk(tx, root)
+	if _, err := repo.RevParse(&git.RevParse{Rev: "refs/remotes/origin/" + trunk}); err != nil {
+		return nil, nil
+	}
+	base, err := BranchBase(repo, tx, root)
+	if err != nil {
+		return nil, err
+	}
+	out, err := repo.Git("rev-list", "--count", base+"..refs/remotes/origin/"+trunk)
+	if err != nil {
+		return nil, errors.WrapIff(err, "failed to count the new commits of %q", trunk)
+	}
+	behind, err := strconv.Atoi(out)
+	if err != nil {
+		return nil, errors.WrapIff(err, "failed to count the new commits of %q", trunk)
+	}
+	res := &TrunkDivergence{Root: root, Trunk: trunk, Behind: behind}
+	if behind == 0 {
+		return res, nil
+	}
+
+	branches, err := meta.StackBranches(tx, root)
+	if err != nil {
+		return nil, err
+	}
+	results, err := CheckSync(repo, tx, branches, SyncCheckOpts{ToTrunk: true})
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if len(result.Conflicts) > 0 {
+			res.Conflicts = append(res.Conflicts, result)
+		}
+	}
+	return res, nil
+}
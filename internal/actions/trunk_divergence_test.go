@@ -0,0 +1,60 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/git/gittest"
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTrunkDivergence(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	db, err := jsonfiledb.OpenRepo(repo)
+	require.NoError(t, err)
+
+	_, err = repo.Git("checkout", "-b", "one")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "one", []byte("one\n"))
+	oneHead := gittest.CommitFile(t, repo, "shared", []byte("one\n"))
+	_, err = repo.Git("checkout", "-b", "two")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "two", []byte("two\n"))
+
+	tx := db.WriteTx()
+	defer tx.Abort()
+	tx.SetBranch(meta.Branch{Name: "one", Parent: meta.BranchState{Name: "main", Trunk: true}})
+	tx.SetBranch(meta.Branch{Name: "two", Parent: meta.BranchState{Name: "one", Head: oneHead}})
+
+	res, err := actions.CheckTrunkDivergence(repo, tx, "two")
+	require.NoError(t, err)
+	require.Equal(t, &actions.TrunkDivergence{Root: "one", Trunk: "main"}, res)
+
+	// The trunk moves on without touching the files of the stack.
+	gittest.CheckoutBranch(t, repo, "main")
+	gittest.CommitFile(t, repo, "unrelated", []byte("unrelated\n"))
+	_, err = repo.Git("push", "origin", "main")
+	require.NoError(t, err)
+	res, err = actions.CheckTrunkDivergence(repo, tx, "two")
+	require.NoError(t, err)
+	require.Equal(t, 1, res.Behind)
+	require.Empty(t, res.Conflicts)
+
+	// The trunk changes a file that the stack changed as well.
+	gittest.CommitFile(t, repo, "shared", []byte("main\n"))
+	_, err = repo.Git("push", "origin", "main")
+	require.NoError(t, err)
+	res, err = actions.CheckTrunkDivergence(repo, tx, "two")
+	require.NoError(t, err)
+	require.Equal(t, 2, res.Behind)
+	require.Len(t, res.Conflicts, 1)
+	require.Equal(t, "one", res.Conflicts[0].Branch)
+	require.Equal(t, []string{"shared"}, res.Conflicts[0].Conflicts)
+
+	// Branches that aren't in a stack have no trunk to diverge from.
+	res, err = actions.CheckTrunkDivergence(repo, tx, "main")
+	require.NoError(t, err)
+	require.Nil(t, res)
+}
@@ -0,0 +1,88 @@
+package actions
+
+import (
+	"text/template"
+
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/utils/templateutils"
+)
+
+// SyncWorkflowPath is where the GitHub Actions workflow generated by
+// SyncWorkflow is written to (relative to the root of the repository).
+const SyncWorkflowPath = ".github/workflows/av-sync.yml"
+
+// SyncWorkflowOpts are the options for SyncWorkflow.
+type SyncWorkflowOpts struct {
+	// The trunk branches whose updates trigger the workflow.
+	Trunks []string
+	// How the stack is written to the pull request descriptions (see
+	// config.PullRequest.WriteStack). If empty, the stack isn't written.
+	WriteStack config.WriteStackSetting
+	// Which results of the sync to comment on the pull requests (see
+	// `av ci sync --comment`).
+	Comment string
+}
+
+// SyncWorkflow returns a GitHub Actions workflow that runs `av ci sync`
+// whenever one of the trunks is updated, which keeps the open stacks rebased
+// on their trunk (and the stacks in the pull request descriptions current).
+func SyncWorkflow(opts SyncWorkflowOpts) string {
+	return templateutils.MustString(syncWorkflowTemplate, opts)
+}
+
+var syncWorkflowTemplate = template.Must(
+	template.New("syncWorkflow").Parse(`# Keeps the stacks of pull requests created by av rebased on their trunk.
+# Generated by ` + "`av init --actions`" + `.
+#
+# Pushes made with the default GITHUB_TOKEN don't trigger other workflows, so the
+# checks of the pull requests won't re-run after a sync. To make them re-run,
+# store a personal access token (with the repo scope) in the AV_GITHUB_TOKEN
+# secret.
+name: av sync
+
+on:
+  push:
+    branches:
+{{- range .Trunks}}
+      - {{.}}
+{{- end}}
+  workflow_dispatch:
+
+permissions:
+  contents: write
+  pull-requests: write
+
+concurrency:
+  group: av-sync
+  cancel-in-progress: false
+
+jobs:
+  sync:
+    runs-on: ubuntu-latest
+    steps:
+      - name: Check out the repository
+        uses: actions/checkout@v4
+        with:
+          fetch-depth: 0
+          token: ${{"{{"}} secrets.AV_GITHUB_TOKEN || github.token {{"}}"}}
+      - name: Install av
+        run: |
+          gh release download --repo aviator-co/av --pattern '*_linux_x86_64.tar.gz' --output "$RUNNER_TEMP/av.tar.gz"
+          tar -xzf "$RUNNER_TEMP/av.tar.gz" -C "$RUNNER_TEMP" av
+          sudo install "$RUNNER_TEMP/av" /usr/local/bin/av
+        env:
+          GH_TOKEN: ${{"{{"}} github.token {{"}}"}}
+      - name: Configure Git and av
+        run: |
+          git config user.name "github-actions[bot]"
+          git config user.email "41898282+github-actions[bot]@users.noreply.github.com"
+{{- if .WriteStack}}
+          mkdir -p "$(git rev-parse --git-common-dir)/av"
+          printf 'pullRequest:\n  writeStack: {{.WriteStack}}\n' > "$(git rev-parse --git-common-dir)/av/config.yml"
+{{- end}}
+      - name: Sync stacks
+        run: av ci sync --comment={{.Comment}}
+        env:
+          AV_GITHUB_TOKEN: ${{"{{"}} secrets.AV_GITHUB_TOKEN || github.token {{"}}"}}
+`),
+)
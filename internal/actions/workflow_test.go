@@ -0,0 +1,27 @@
+package actions_test
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/actions"
+	"github.com/aviator-co/av/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncWorkflow(t *testing.T) {
+	workflow := actions.SyncWorkflow(actions.SyncWorkflowOpts{
+		Trunks:     []string{"main", "release/1.0"},
+		WriteStack: config.WriteStackBottom,
+		Comment:    "failures",
+	})
+	require.Contains(t, workflow, "    branches:\n      - main\n      - release/1.0\n")
+	require.Contains(t, workflow, "writeStack: bottom")
+	require.Contains(t, workflow, "run: av ci sync --comment=failures\n")
+	require.Contains(t, workflow, "AV_GITHUB_TOKEN: ${{ secrets.AV_GITHUB_TOKEN || github.token }}\n")
+
+	workflow = actions.SyncWorkflow(actions.SyncWorkflowOpts{
+		Trunks:  []string{"main"},
+		Comment: "none",
+	})
+	require.NotContains(t, workflow, "writeStack")
+}
@@ -0,0 +1,102 @@
+package avgql
+
+import (
+	"context"
+
+	"emperror.dev/errors"
+	"github.com/shurcooL/graphql"
+)
+
+// QueueStatus is the state of a pull request in MergeQueue.
+type QueueStatus struct {
+	// The status of the pull request (e.g., "QUEUED", "PENDING", "BLOCKED", or
+	// "MERGED").
+	Status string
+	// Why the pull request is in its current status (e.g., why it's blocked).
+	StatusReason string
+	// The position of the pull request in the queue (starting at 1), or 0 if
+	// it isn't queued or its position isn't known.
+	Position int
+}
+
+// IsQueued returns true if the pull request is in the queue (including if it's
+// waiting for CI or blocked while queued).
+func (s QueueStatus) IsQueued() bool {
+	switch s.Status {
+	case "QUEUED", "PENDING", "BLOCKED", "TAGGED":
+		return true
+	}
+	return false
+}
+
+// PullRequestQueueStatus returns the MergeQueue status of the given pull
+// request.
+func PullRequestQueueStatus(
+	ctx context.Context,
+	client *graphql.Client,
+	owner, repo string,
+	number int64,
+) (*QueueStatus, error) {
+	var query struct {
+		ViewerSubquery
+		GithubRepository struct {
+			PullRequest struct {
+				Number       graphql.Int
+				Status       graphql.String
+				StatusReason graphql.String
+				// Null if the pull request isn't queued.
+				QueuePosition *graphql.Int
+			} `graphql:"pullRequest(number: $prNumber)"`
+		} `graphql:"githubRepository(owner: $repoOwner, name:$repoName)"`
+	}
+	if err := client.Query(ctx, &query, map[string]any{
+		"repoOwner": graphql.String(owner),
+		"repoName":  graphql.String(repo),
+		"prNumber":  graphql.Int(number),
+	}); err != nil {
+		return nil, errors.WrapIff(err, "failed to query MergeQueue status of pull request #%d", number)
+	}
+	if err := query.CheckViewer(); err != nil {
+		return nil, err
+	}
+	pr := query.GithubRepository.PullRequest
+	if pr.Number == 0 {
+		return nil, errors.Errorf("pull request #%d not found", number)
+	}
+	status := &QueueStatus{
+		Status:       string(pr.Status),
+		StatusReason: string(pr.StatusReason),
+	}
+	if pr.QueuePosition != nil {
+		status.Position = int(*pr.QueuePosition)
+	}
+	return status, nil
+}
+
+// QueuePullRequest adds the given pull request to MergeQueue.
+func QueuePullRequest(
+	ctx context.Context,
+	client *graphql.Client,
+	owner, repo string,
+	number int64,
+) error {
+	var mutation struct {
+		QueuePullRequest struct {
+			QueuePullRequestPayload struct {
+				PullRequest struct {
+					// We don't currently use anything here, but we need to select
+					// at least one field to make the GraphQL query valid.
+					Status graphql.String
+				}
+			} `graphql:"... on QueuePullRequestPayload"`
+		} `graphql:"queuePullRequest(input: {repoOwner: $repoOwner, repoName:$repoName, number:$prNumber})"`
+	}
+	if err := client.Mutate(ctx, &mutation, map[string]any{
+		"repoOwner": graphql.String(owner),
+		"repoName":  graphql.String(repo),
+		"prNumber":  graphql.Int(number),
+	}); err != nil {
+		return errors.WrapIff(err, "failed to queue pull request #%d", number)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package avgql_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aviator-co/av/internal/avgql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullRequestQueueStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Contains(t, string(body), `"prNumber":42`)
+		_, _ = w.Write([]byte(`{"data": {
+			"viewer": {"email": "user@example.com", "fullName": "User"},
+			"githubRepository": {"pullRequest": {
+				"number": 42, "status": "BLOCKED", "statusReason": "merge conflict", "queuePosition": 3
+			}}
+		}}`))
+	}))
+	defer srv.Close()
+	t.Setenv("AV_GRAPHQL_URL", srv.URL)
+
+	client, err := avgql.NewClient()
+	require.NoError(t, err)
+	status, err := avgql.PullRequestQueueStatus(context.Background(), client, "owner", "repo", 42)
+	require.NoError(t, err)
+	require.Equal(t, &avgql.QueueStatus{Status: "BLOCKED", StatusReason: "merge conflict", Position: 3}, status)
+	require.True(t, status.IsQueued())
+	require.False(t, avgql.QueueStatus{Status: "OPEN"}.IsQueued())
+}
+
+func TestQueuePullRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Contains(t, string(body), "queuePullRequest")
+		require.Contains(t, string(body), `"prNumber":42`)
+		_, _ = w.Write([]byte(`{"data": {
+			"queuePullRequest": {"pullRequest": {"status": "QUEUED"}}
+		}}`))
+	}))
+	defer srv.Close()
+	t.Setenv("AV_GRAPHQL_URL", srv.URL)
+
+	client, err := avgql.NewClient()
+	require.NoError(t, err)
+	require.NoError(t, avgql.QueuePullRequest(context.Background(), client, "owner", "repo", 42))
+}
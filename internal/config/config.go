@@ -1,8 +1,10 @@
 package config
 
 import (
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"emperror.dev/errors"
 	"github.com/sirupsen/logrus"
@@ -15,8 +17,56 @@ type GitHub struct {
 	// The base URL of the GitHub instance to use.
 	// This should only be set for GitHub Enterprise Server (GHES) instances.
 	// For example, "https://github.mycompany.com/" (without a "/api/v3" or
-	// "/api/graphql" suffix).
+	// "/api/graphql" suffix, which is removed if given).
+	// Can also be set with the AV_GITHUB_BASE_URL environment variable (or
+	// GITHUB_API_URL, which GitHub Actions sets).
 	BaseURL string
+	// The base URL of the uploads API of a GHES instance. Defaults to
+	// "<BaseURL>/api/uploads". Can also be set with the AV_GITHUB_UPLOADS_URL
+	// environment variable.
+	UploadsURL string
+	// If true, the repository uses GitHub's (native) merge queue: av pr queue
+	// adds pull requests to it (instead of Aviator's MergeQueue) and av stack
+	// sync doesn't rewrite branches whose pull requests are queued (since
+	// pushing them would remove them from the queue).
+	MergeQueue bool
+	// The URL of the HTTP(S) proxy to access the GitHub API through (e.g.,
+	// "http://proxy.mycompany.com:3128"). By default, the proxy is read from
+	// the HTTPS_PROXY, HTTP_PROXY, and NO_PROXY environment variables.
+	ProxyURL string
+	// The path to a PEM file with additional CA certificates to trust when
+	// accessing the GitHub API (e.g., the certificate of a proxy that
+	// intercepts TLS connections or of the internal CA of a GHES instance).
+	// The system's CA certificates are still trusted.
+	CACertPath string
+}
+
+// Host returns the host name of the GitHub instance (e.g., "github.com" or
+// "github.mycompany.com").
+func (g GitHub) Host() string {
+	if g.BaseURL == "" {
+		return "github.com"
+	}
+	u, err := url.Parse(g.BaseURL)
+	if err != nil || u.Host == "" {
+		return g.BaseURL
+	}
+	return u.Host
+}
+
+// NormalizeGitHubBaseURL returns the given GitHub base URL without a trailing
+// slash and without the path of the API (e.g., "/api/v3"), so that the API
+// URLs can be derived from it. The URL of the API of GitHub cloud
+// (https://api.github.com) is normalized to an empty string.
+func NormalizeGitHubBaseURL(baseURL string) string {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	for _, suffix := range []string{"/api/v3", "/api/graphql"} {
+		baseURL = strings.TrimSuffix(baseURL, suffix)
+	}
+	if baseURL == "https://api.github.com" || baseURL == "https://github.com" {
+		return ""
+	}
+	return baseURL
 }
 
 type WriteStackSetting string
@@ -48,6 +98,92 @@ type PullRequest struct {
 	// If true, the CLI will automatically add/update a comment to all PRs linking other PRs in the stack.
 	// False by default, since MergeQueue also adds a similar comment.
 	WriteStack WriteStackSetting
+
+	// If true, av adds trailers to the head commit of each branch before
+	// pushing it that identify the parent branch, the parent commit, and the
+	// position of the branch in the stack. CI pipelines can use them to only
+	// test the changes of the branch itself.
+	StackTrailers bool
+
+	// The reviewers (users or teams), labels, and assignees that are added to
+	// new pull requests unless the corresponding flags are given (e.g.,
+	// --reviewer).
+	Reviewers []string
+	Labels    []string
+	Assignees []string
+}
+
+type Stack struct {
+	// If true, av warns when committing while the trunk branch is checked out
+	// (e.g., with av commit create) and offers to create a new stacked branch
+	// instead. This is meant to prevent accidentally committing changes
+	// directly to trunk. av status and av doctor also only warn about staged
+	// changes on the trunk if this is set.
+	GuardTrunkCommits bool
+	// If true, av adds Stack-Branch and Stack-Parent trailers to the commits
+	// that it creates or amends (e.g., with av commit create), so that the
+	// branch a change was made on can still be found in the history of the
+	// trunk after its pull request was squash-merged.
+	CommitTrailers bool
+	// The stack templates that `av stack new --template` creates stacks from,
+	// by name. Each template is the list of the branches of the stack (from
+	// the bottom up).
+	Templates map[string][]StackTemplateBranch
+}
+
+// StackTemplateBranch is a branch of a stack template.
+type StackTemplateBranch struct {
+	// The name of the branch, which is appended to the name of the stack
+	// (e.g., "schema" for the "login-schema" branch of the "login" stack).
+	Name string
+	// What the branch is for. It's used as the message of the initial
+	// (empty) commit of the branch and thus as the title of its pull request.
+	Description string
+}
+
+type Sync struct {
+	// If true, untracked files don't prevent syncing a stack. They're left
+	// as-is (Git still refuses to rebase if a rebase would overwrite one).
+	IgnoreUntracked bool
+	// If true, submodules whose checked out commit differs from the one
+	// recorded in the branch (or that contain changes) don't prevent syncing a
+	// stack.
+	IgnoreSubmodules bool
+	// If true, `av stack sync --trunk` fetches the trunk branches from the
+	// remote before syncing (unless --no-fetch is given). If false, the stacks
+	// are synced onto the last fetched commits of their trunks.
+	FetchTrunk bool
+	// If true, `av stack sync` stashes the changes in the working tree before
+	// syncing and reapplies them afterwards (as if --autostash was given).
+	Autostash bool
+	// How `av stack sync` updates the branches with the changes of their
+	// parents: "rebase" (the default) or "merge" (see --strategy).
+	Strategy string
+	// If true, `av stack sync` posts (or updates) a comment on each pull
+	// request that it pushes with a summary of what changed since the previous
+	// push (as if --summary-comment was given).
+	SummaryComment bool
+	// The options of the merge strategy that av rebases (and merges) branches
+	// with, as given to `git rebase -X` (e.g., "theirs" or "histogram"). See
+	// --strategy-option.
+	StrategyOptions []string
+	// The commands that regenerate generated files (e.g., lock files or
+	// generated code). If a sync stops at conflicts that are only in generated
+	// files, av runs the commands, stages the regenerated files, and continues
+	// the sync.
+	GeneratedFiles []GeneratedFiles
+}
+
+// GeneratedFiles are files that av regenerates (instead of stopping the sync)
+// when they conflict.
+type GeneratedFiles struct {
+	// The glob patterns of the paths of the files (e.g., "*.pb.go" or
+	// "web/yarn.lock"). Patterns without a slash match the file name in any
+	// directory.
+	Paths []string
+	// The shell command that regenerates the files. It's run in the root of
+	// the repository with the conflicting paths as arguments.
+	Command string
 }
 
 type Aviator struct {
@@ -64,6 +200,8 @@ var Av = struct {
 	PullRequest PullRequest
 	GitHub      GitHub
 	Aviator     Aviator
+	Stack       Stack
+	Sync        Sync
 }{
 	Aviator: Aviator{
 		APIHost: "https://api.aviator.co",
@@ -72,6 +210,11 @@ var Av = struct {
 		OpenBrowser: true,
 	},
 	GitHub: GitHub{},
+	Sync: Sync{
+		IgnoreUntracked: true,
+		FetchTrunk:      true,
+		Strategy:        "rebase",
+	},
 }
 
 // Load initializes the configuration values.
@@ -85,6 +228,8 @@ func Load(repoConfigDir string) error {
 	if err := loadFromEnv(); err != nil {
 		return err
 	}
+	Av.GitHub.BaseURL = NormalizeGitHubBaseURL(Av.GitHub.BaseURL)
+	Av.GitHub.UploadsURL = strings.TrimRight(Av.GitHub.UploadsURL, "/")
 	return nil
 }
 
@@ -142,6 +287,15 @@ func loadFromEnv() error {
 		Av.GitHub.Token = githubToken
 	}
 
+	if baseURL := os.Getenv("AV_GITHUB_BASE_URL"); baseURL != "" {
+		Av.GitHub.BaseURL = baseURL
+	} else if baseURL := os.Getenv("GITHUB_API_URL"); baseURL != "" && Av.GitHub.BaseURL == "" {
+		Av.GitHub.BaseURL = baseURL
+	}
+	if uploadsURL := os.Getenv("AV_GITHUB_UPLOADS_URL"); uploadsURL != "" {
+		Av.GitHub.UploadsURL = uploadsURL
+	}
+
 	if apiToken := os.Getenv("AV_API_TOKEN"); apiToken != "" {
 		Av.Aviator.APIToken = apiToken
 	}
@@ -0,0 +1,199 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/shurcooL/githubv4"
+)
+
+// CheckState is the simplified state of a check (or of a set of checks).
+type CheckState string
+
+const (
+	CheckStatePending CheckState = "PENDING"
+	CheckStateSuccess CheckState = "SUCCESS"
+	CheckStateFailure CheckState = "FAILURE"
+)
+
+// Check is a single CI check of a commit. GitHub has two kinds of checks:
+// check runs (e.g., GitHub Actions jobs) and commit statuses (set by external
+// CI systems through the status API). Both are represented as a Check.
+type Check struct {
+	Name  string
+	State CheckState
+	// The URL where the details of the check can be found (if any).
+	URL string
+	// The ID of the check run (zero for commit statuses).
+	CheckRunID int64
+	// The ID of the GitHub Actions workflow run that the check run is a job of
+	// (zero if the check isn't a GitHub Actions job).
+	WorkflowRunID int64
+	// When the check started and completed. Either can be zero if it's not
+	// known (e.g., for commit statuses or checks that haven't started yet).
+	StartedAt   time.Time
+	CompletedAt time.Time
+}
+
+// Duration returns how long the check took (or has been running so far). Zero
+// is returned if it's not known.
+func (c Check) Duration(now time.Time) time.Duration {
+	if c.StartedAt.IsZero() {
+		return 0
+	}
+	if c.CompletedAt.IsZero() {
+		return now.Sub(c.StartedAt)
+	}
+	return c.CompletedAt.Sub(c.StartedAt)
+}
+
+// PullRequestChecks is the state of the checks of the head commit of a pull
+// request.
+type PullRequestChecks struct {
+	// The commit that the checks belong to.
+	HeadOID string
+	Checks  []Check
+}
+
+// State returns the overall state of the checks: failed if any check failed,
+// pending if any check hasn't completed yet, and successful otherwise
+// (including if there are no checks at all).
+func (p *PullRequestChecks) State() CheckState {
+	state := CheckStateSuccess
+	for _, check := range p.Checks {
+		switch check.State {
+		case CheckStateFailure:
+			return CheckStateFailure
+		case CheckStatePending:
+			state = CheckStatePending
+		}
+	}
+	return state
+}
+
+type checkContext struct {
+	Typename string `graphql:"__typename"`
+	CheckRun struct {
+		DatabaseID  int64 `graphql:"databaseId"`
+		Name        string
+		Status      githubv4.CheckStatusState
+		Conclusion  githubv4.CheckConclusionState
+		DetailsURL  string `graphql:"detailsUrl"`
+		StartedAt   *githubv4.DateTime
+		CompletedAt *githubv4.DateTime
+		CheckSuite  struct {
+			WorkflowRun struct {
+				DatabaseID int64 `graphql:"databaseId"`
+			}
+		}
+	} `graphql:"... on CheckRun"`
+	StatusContext struct {
+		Context   string
+		State     githubv4.StatusState
+		TargetURL string `graphql:"targetUrl"`
+	} `graphql:"... on StatusContext"`
+}
+
+func (c checkContext) check() Check {
+	if c.Typename == "StatusContext" {
+		check := Check{Name: c.StatusContext.Context, URL: c.StatusContext.TargetURL}
+		switch c.StatusContext.State {
+		case githubv4.StatusStateSuccess:
+			check.State = CheckStateSuccess
+		case githubv4.StatusStateFailure, githubv4.StatusStateError:
+			check.State = CheckStateFailure
+		default:
+			check.State = CheckStatePending
+		}
+		return check
+	}
+	check := Check{
+		Name:          c.CheckRun.Name,
+		URL:           c.CheckRun.DetailsURL,
+		CheckRunID:    c.CheckRun.DatabaseID,
+		WorkflowRunID: c.CheckRun.CheckSuite.WorkflowRun.DatabaseID,
+	}
+	if c.CheckRun.StartedAt != nil {
+		check.StartedAt = c.CheckRun.StartedAt.Time
+	}
+	if c.CheckRun.CompletedAt != nil {
+		check.CompletedAt = c.CheckRun.CompletedAt.Time
+	}
+	switch {
+	case c.CheckRun.Status != githubv4.CheckStatusStateCompleted:
+		check.State = CheckStatePending
+	case c.CheckRun.Conclusion == githubv4.CheckConclusionStateSuccess,
+		c.CheckRun.Conclusion == githubv4.CheckConclusionStateNeutral,
+		c.CheckRun.Conclusion == githubv4.CheckConclusionStateSkipped:
+		check.State = CheckStateSuccess
+	default:
+		check.State = CheckStateFailure
+	}
+	return check
+}
+
+// checksFields are the fields of a pull request that PullRequestChecks is
+// built from (see checks).
+type checksFields struct {
+	Commits struct {
+		Nodes []struct {
+			Commit struct {
+				Oid               string
+				StatusCheckRollup struct {
+					Contexts struct {
+						Nodes []checkContext
+					} `graphql:"contexts(first: 100)"`
+				}
+			}
+		}
+	} `graphql:"commits(last: 1)"`
+}
+
+// checks returns the checks of the head commit of the pull request (nil if
+// the pull request wasn't found or has no commits).
+func (f checksFields) checks() *PullRequestChecks {
+	commits := f.Commits.Nodes
+	if len(commits) == 0 {
+		return nil
+	}
+	res := &PullRequestChecks{HeadOID: commits[0].Commit.Oid}
+	for _, node := range commits[0].Commit.StatusCheckRollup.Contexts.Nodes {
+		res.Checks = append(res.Checks, node.check())
+	}
+	return res
+}
+
+// PullRequestChecks returns the checks of the head commit of the pull request
+// with the given node ID.
+func (c *Client) PullRequestChecks(ctx context.Context, id string) (*PullRequestChecks, error) {
+	var query struct {
+		Node struct {
+			PullRequest struct {
+				checksFields
+			} `graphql:"... on PullRequest"`
+		} `graphql:"node(id: $id)"`
+	}
+	if err := c.query(ctx, &query, map[string]any{
+		"id": githubv4.ID(id),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to query pull request checks")
+	}
+	res := query.Node.PullRequest.checks()
+	if res == nil {
+		return nil, errors.Errorf("pull request %q not found (or has no commits)", id)
+	}
+	return res, nil
+}
+
+// RerunFailedWorkflowJobs re-runs the failed jobs (and the jobs that depend on
+// them) of the given GitHub Actions workflow run. GitHub only allows this once
+// all jobs of the run have completed.
+func (c *Client) RerunFailedWorkflowJobs(ctx context.Context, owner, repo string, runID int64) error {
+	endpoint := fmt.Sprintf("/repos/%s/%s/actions/runs/%d/rerun-failed-jobs", owner, repo, runID)
+	if err := c.restPost(ctx, endpoint, struct{}{}, nil); err != nil {
+		return errors.WrapIff(err, "failed to re-run the failed jobs of workflow run %d", runID)
+	}
+	return nil
+}
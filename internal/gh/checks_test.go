@@ -0,0 +1,73 @@
+package gh_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/gh/ghtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullRequestChecks(t *testing.T) {
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/graphql", r.URL.Path)
+		_, _ = w.Write([]byte(`{"data": {"node": {"commits": {"nodes": [{"commit": {
+			"oid": "abc123",
+			"statusCheckRollup": {"contexts": {"nodes": [
+				{"__typename": "CheckRun", "databaseId": 1, "name": "build", "status": "COMPLETED", "conclusion": "SUCCESS", "detailsUrl": "https://ci/1", "startedAt": "2024-01-01T00:00:00Z", "completedAt": "2024-01-01T00:01:30Z", "checkSuite": {"workflowRun": {"databaseId": 7}}},
+				{"__typename": "CheckRun", "databaseId": 2, "name": "lint", "status": "COMPLETED", "conclusion": "SKIPPED", "detailsUrl": ""},
+				{"__typename": "CheckRun", "databaseId": 3, "name": "test", "status": "IN_PROGRESS", "conclusion": null, "detailsUrl": "https://ci/3", "startedAt": "2024-01-01T00:00:00Z", "completedAt": null, "checkSuite": {"workflowRun": null}},
+				{"__typename": "StatusContext", "context": "deploy", "state": "ERROR", "targetUrl": "https://ci/deploy"}
+			]}}
+		}}]}}}}`))
+	}))
+
+	started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	checks, err := client.PullRequestChecks(context.Background(), "PR_1")
+	require.NoError(t, err)
+	require.Equal(t, &gh.PullRequestChecks{
+		HeadOID: "abc123",
+		Checks: []gh.Check{
+			{
+				Name:          "build",
+				State:         gh.CheckStateSuccess,
+				URL:           "https://ci/1",
+				CheckRunID:    1,
+				WorkflowRunID: 7,
+				StartedAt:     started,
+				CompletedAt:   started.Add(90 * time.Second),
+			},
+			{Name: "lint", State: gh.CheckStateSuccess, CheckRunID: 2},
+			{Name: "test", State: gh.CheckStatePending, URL: "https://ci/3", CheckRunID: 3, StartedAt: started},
+			{Name: "deploy", State: gh.CheckStateFailure, URL: "https://ci/deploy"},
+		},
+	}, checks)
+	require.Equal(t, gh.CheckStateFailure, checks.State())
+	now := started.Add(time.Hour)
+	require.Equal(t, 90*time.Second, checks.Checks[0].Duration(now))
+	require.Equal(t, time.Hour, checks.Checks[2].Duration(now))
+	require.Zero(t, checks.Checks[3].Duration(now))
+
+	checks.Checks = checks.Checks[:3]
+	require.Equal(t, gh.CheckStatePending, checks.State())
+	checks.Checks = checks.Checks[:2]
+	require.Equal(t, gh.CheckStateSuccess, checks.State())
+}
+
+func TestRerunFailedWorkflowJobs(t *testing.T) {
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		if r.URL.Path != "/api/v3/repos/owner/repo/actions/runs/7/rerun-failed-jobs" {
+			// GitHub refuses to re-run workflow runs that are still in progress.
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	require.NoError(t, client.RerunFailedWorkflowJobs(context.Background(), "owner", "repo", 7))
+	require.Error(t, client.RerunFailedWorkflowJobs(context.Background(), "owner", "repo", 8))
+}
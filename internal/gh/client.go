@@ -20,27 +20,76 @@ import (
 type Client struct {
 	httpClient *http.Client
 	gh         *githubv4.Client
+	endpoints  Endpoints
 }
 
-const githubCloudApiBaseUrl = "https://api.github.com"
+const (
+	githubCloudApiBaseUrl     = "https://api.github.com"
+	githubCloudUploadsBaseUrl = "https://uploads.github.com"
+)
+
+// Endpoints are the URLs of the APIs of the GitHub instance that av uses.
+type Endpoints struct {
+	// The URL of the GraphQL API.
+	GraphQL string
+	// The base URL of the REST API (the endpoints are appended to it).
+	REST string
+	// The base URL of the uploads API (e.g., for release assets).
+	Uploads string
+}
+
+// GetEndpoints returns the API URLs of GitHub cloud or, if config.GitHub.BaseURL
+// is set, of the GitHub Enterprise Server instance.
+func GetEndpoints() Endpoints {
+	return endpointsFor(config.Av.GitHub.BaseURL)
+}
+
+// endpointsFor returns the API URLs of the GitHub instance at the given base URL
+// (or of GitHub cloud if it's empty).
+func endpointsFor(baseURL string) Endpoints {
+	if baseURL == "" {
+		return Endpoints{
+			GraphQL: githubCloudApiBaseUrl + "/graphql",
+			REST:    githubCloudApiBaseUrl,
+			Uploads: githubCloudUploadsBaseUrl,
+		}
+	}
+	// GitHub cloud and GHES have different API URLs.
+	// For cloud, it's `https://api.github.com/repos/...`.
+	// For GHES, it's `https://github.mycompany.com/api/v3/repos/...`.
+	endpoints := Endpoints{
+		GraphQL: baseURL + "/api/graphql",
+		REST:    baseURL + "/api/v3",
+		Uploads: baseURL + "/api/uploads",
+	}
+	if config.Av.GitHub.UploadsURL != "" {
+		endpoints.Uploads = config.Av.GitHub.UploadsURL
+	}
+	return endpoints
+}
 
-// NewClient creates a new GitHub client.
-// It takes configuration from the global config.Av.GitHub variable.
-func NewClient(token string) (*Client, error) {
+// NewClient creates a new GitHub client for the GitHub instance at the given
+// base URL (see config.GitHub.BaseURL; empty for GitHub cloud). The rest of its
+// configuration (e.g., the proxy) is taken from the global config.Av.GitHub
+// variable.
+func NewClient(token string, baseURL string) (*Client, error) {
 	if token == "" {
 		return nil, errors.Errorf("no GitHub token provided (do you need to configure one?)")
 	}
 	src := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
 	)
-	httpClient := oauth2.NewClient(context.Background(), src)
-	var gh *githubv4.Client
-	if config.Av.GitHub.BaseURL == "" {
-		gh = githubv4.NewClient(httpClient)
-	} else {
-		gh = githubv4.NewEnterpriseClient(config.Av.GitHub.BaseURL+"/api/graphql", httpClient)
+	transport, err := newTransport()
+	if err != nil {
+		return nil, err
 	}
-	return &Client{httpClient, gh}, nil
+	// The OAuth2 client wraps the HTTP client given in the context.
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: transport})
+	httpClient := oauth2.NewClient(ctx, src)
+	endpoints := endpointsFor(baseURL)
+	logrus.WithField("endpoints", logutils.Format("%#+v", endpoints)).Debug("creating GitHub client")
+	gh := githubv4.NewEnterpriseClient(endpoints.GraphQL, httpClient)
+	return &Client{httpClient, gh, endpoints}, nil
 }
 
 func (c *Client) query(ctx context.Context, query any, variables map[string]any) (reterr error) {
@@ -60,7 +109,7 @@ func (c *Client) query(ctx context.Context, query any, variables map[string]any)
 			log.Debug("GitHub API query succeeded")
 		}
 	}()
-	return c.gh.Query(ctx, query, variables)
+	return wrapTLSError(c.gh.Query(ctx, query, variables))
 }
 
 func (c *Client) mutate(
@@ -85,7 +134,7 @@ func (c *Client) mutate(
 			log.Debug("GitHub API mutation succeeded")
 		}
 	}()
-	return c.gh.Mutate(ctx, mutation, input, variables)
+	return wrapTLSError(c.gh.Mutate(ctx, mutation, input, variables))
 }
 
 // restPost executes a POST request to the endpoint (e.g., /repos/:owner/:repo/pulls).
@@ -96,36 +145,62 @@ func (c *Client) restPost(
 	body interface{},
 	result interface{},
 ) error {
+	return c.rest(ctx, http.MethodPost, endpoint, body, result)
+}
+
+// restGet executes a GET request to the endpoint (e.g.,
+// /repos/:owner/:repo/pulls/comments/:id) and unmarshals the response into the
+// given result type.
+func (c *Client) restGet(ctx context.Context, endpoint string, result interface{}) error {
+	return c.rest(ctx, http.MethodGet, endpoint, nil, result)
+}
+
+// rest executes a REST request to the endpoint. The body is omitted if it's nil.
+func (c *Client) rest(
+	ctx context.Context,
+	method string,
+	endpoint string,
+	body interface{},
+	result interface{},
+) error {
+	_, err := c.restWithHeader(ctx, method, endpoint, body, result)
+	return err
+}
+
+// restWithHeader is like rest but it also returns the headers of the response.
+func (c *Client) restWithHeader(
+	ctx context.Context,
+	method string,
+	endpoint string,
+	body interface{},
+	result interface{},
+) (http.Header, error) {
 	if endpoint[0] != '/' {
 		logrus.WithField("endpoint", endpoint).Panicf("malformed REST endpoint")
 	}
 
 	startTime := time.Now()
 
-	// GitHub cloud and GHES have different API URLs.
-	// For cloud, it's `https://api.github.com/repos/...`.
-	// For GHES, it's `https://github.mycompany.com/api/v3/repos/...`.
-	var url string
-	if config.Av.GitHub.BaseURL == "" {
-		url = githubCloudApiBaseUrl + endpoint
-	} else {
-		url = config.Av.GitHub.BaseURL + "/api/v3" + endpoint
-	}
+	url := c.endpoints.REST + endpoint
 
 	log := logrus.WithFields(logrus.Fields{
 		"url":  url,
 		"body": logutils.Format("%#+v", body),
 	})
-	bodyJson, err := json.Marshal(body)
-	if err != nil {
-		return errors.Wrap(err, "failed to marshal request body to JSON")
+	var reqBody io.Reader
+	if body != nil {
+		bodyJson, err := json.Marshal(body)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal request body to JSON")
+		}
+		reqBody = bytes.NewBuffer(bodyJson)
 	}
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(bodyJson))
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
-		return errors.Wrap(err, "failed to create request")
+		return nil, errors.Wrap(err, "failed to create request")
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
@@ -134,22 +209,23 @@ func (c *Client) restPost(
 	res, err := c.httpClient.Do(req)
 	log.Debugf("header: %#+v", req.Header)
 	if err != nil {
-		return errors.Wrap(err, "failed to make API request")
+		return nil, errors.Wrap(wrapTLSError(err), "failed to make API request")
 	}
 	defer res.Body.Close()
 
 	resBody, err := io.ReadAll(res.Body)
 	if err != nil {
-		return errors.Wrap(err, "failed to read response body")
+		return nil, errors.Wrap(err, "failed to read response body")
 	}
 	log.WithField("elapsed", time.Since(startTime)).Debug("GitHub API request completed")
 
-	if res.StatusCode != http.StatusOK {
+	// Some endpoints respond with 201 Created or 204 No Content instead.
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
 		log.WithFields(logrus.Fields{
 			"status": res.StatusCode,
 			"body":   string(resBody),
 		}).Debug("GitHub API request failed")
-		return errors.Errorf("GitHub API request for %s failed: %s", endpoint, res.Status)
+		return res.Header, &HTTPError{Endpoint: endpoint, StatusCode: res.StatusCode, Status: res.Status}
 	}
 
 	// Don't try to unmarshal into nil, it will return an error.
@@ -157,11 +233,11 @@ func (c *Client) restPost(
 	// only capture an untyped nil (i.e., where the result parameter is given as
 	// a nil literal), but that should be fine.
 	if result == nil {
-		return nil
+		return res.Header, nil
 	}
 
 	if err := json.Unmarshal(resBody, result); err != nil {
-		return errors.Wrap(err, "failed to unmarshal response body")
+		return res.Header, errors.Wrap(err, "failed to unmarshal response body")
 	}
-	return nil
+	return res.Header, nil
 }
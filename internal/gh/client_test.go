@@ -0,0 +1,35 @@
+package gh_test
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetEndpoints(t *testing.T) {
+	github := config.Av.GitHub
+	defer func() { config.Av.GitHub = github }()
+
+	config.Av.GitHub = config.GitHub{}
+	require.Equal(t, gh.Endpoints{
+		GraphQL: "https://api.github.com/graphql",
+		REST:    "https://api.github.com",
+		Uploads: "https://uploads.github.com",
+	}, gh.GetEndpoints())
+	require.Equal(t, "github.com", config.Av.GitHub.Host())
+
+	config.Av.GitHub.BaseURL = config.NormalizeGitHubBaseURL("https://github.example.com/api/v3/")
+	require.Equal(t, gh.Endpoints{
+		GraphQL: "https://github.example.com/api/graphql",
+		REST:    "https://github.example.com/api/v3",
+		Uploads: "https://github.example.com/api/uploads",
+	}, gh.GetEndpoints())
+	require.Equal(t, "github.example.com", config.Av.GitHub.Host())
+
+	config.Av.GitHub.UploadsURL = "https://uploads.github.example.com"
+	require.Equal(t, "https://uploads.github.example.com", gh.GetEndpoints().Uploads)
+
+	require.Equal(t, "", config.NormalizeGitHubBaseURL("https://api.github.com/"))
+}
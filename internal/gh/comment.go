@@ -0,0 +1,57 @@
+package gh
+
+import (
+	"context"
+
+	"emperror.dev/errors"
+	"github.com/shurcooL/githubv4"
+)
+
+// IssueComment is a (top-level) comment on a pull request or an issue.
+type IssueComment struct {
+	ID   string
+	Body string
+	// True if the comment was written by the authenticated user.
+	ViewerDidAuthor bool
+}
+
+// PullRequestComments returns the last 100 comments of the given pull request
+// (in chronological order). Review comments aren't included.
+func (c *Client) PullRequestComments(ctx context.Context, id string) ([]IssueComment, error) {
+	var query struct {
+		Node struct {
+			PullRequest struct {
+				ID       string
+				Comments struct {
+					Nodes []IssueComment
+				} `graphql:"comments(last: 100)"`
+			} `graphql:"... on PullRequest"`
+		} `graphql:"node(id: $id)"`
+	}
+	if err := c.query(ctx, &query, map[string]any{
+		"id": githubv4.ID(id),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to query pull request comments")
+	}
+	if query.Node.PullRequest.ID == "" {
+		return nil, errors.Errorf("pull request %q not found", id)
+	}
+	return query.Node.PullRequest.Comments.Nodes, nil
+}
+
+// UpdateComment replaces the body of the given comment (see AddComment).
+func (c *Client) UpdateComment(ctx context.Context, id string, body string) error {
+	var mutation struct {
+		UpdateIssueComment struct {
+			ClientMutationID string
+		} `graphql:"updateIssueComment(input: $input)"`
+	}
+	input := githubv4.UpdateIssueCommentInput{
+		ID:   githubv4.ID(id),
+		Body: githubv4.String(body),
+	}
+	if err := c.mutate(ctx, &mutation, input, nil); err != nil {
+		return errors.Wrap(err, "failed to update comment: github error")
+	}
+	return nil
+}
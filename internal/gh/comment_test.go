@@ -0,0 +1,41 @@
+package gh_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/gh/ghtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullRequestComments(t *testing.T) {
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		if !strings.Contains(string(body), "updateIssueComment") {
+			_, _ = w.Write([]byte(`{"data": {"node": {
+				"id": "PR_1",
+				"comments": {"nodes": [
+					{"id": "IC_1", "body": "LGTM", "viewerDidAuthor": false},
+					{"id": "IC_2", "body": "Updated", "viewerDidAuthor": true}
+				]}
+			}}}`))
+			return
+		}
+		require.Contains(t, string(body), `"id":"IC_2"`)
+		require.Contains(t, string(body), `"body":"Updated again"`)
+		_, _ = w.Write([]byte(`{"data": {"updateIssueComment": {"clientMutationId": ""}}}`))
+	}))
+
+	comments, err := client.PullRequestComments(context.Background(), "PR_1")
+	require.NoError(t, err)
+	require.Equal(t, []gh.IssueComment{
+		{ID: "IC_1", Body: "LGTM"},
+		{ID: "IC_2", Body: "Updated", ViewerDidAuthor: true},
+	}, comments)
+	require.NoError(t, client.UpdateComment(context.Background(), "IC_2", "Updated again"))
+}
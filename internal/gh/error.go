@@ -1,6 +1,28 @@
 package gh
 
-import "strings"
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+// HTTPError is returned when a REST request to the GitHub API fails with a
+// non-2xx status code.
+type HTTPError struct {
+	// The endpoint of the request (e.g., /repos/:owner/:repo).
+	Endpoint string
+	// The HTTP status code of the response (e.g., 404).
+	StatusCode int
+	// The HTTP status of the response (e.g., "404 Not Found").
+	Status string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("GitHub API request for %s failed: %s", e.Endpoint, e.Status)
+}
 
 // IsHTTPUnauthorized returns true if the given error is an HTTP 401 Unauthorized error.
 func IsHTTPUnauthorized(err error) bool {
@@ -9,3 +31,19 @@ func IsHTTPUnauthorized(err error) bool {
 	// the string.
 	return strings.Contains(err.Error(), "status code: 401")
 }
+
+// IsNetworkError returns true if the given error means that the GitHub API
+// couldn't be reached at all (e.g., the host name couldn't be resolved or the
+// connection timed out), as opposed to GitHub responding with an error.
+func IsNetworkError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	if errors.As(err, &dnsErr) || errors.As(err, &opErr) {
+		return true
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && urlErr.Timeout()
+}
@@ -0,0 +1,23 @@
+package ghtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/stretchr/testify/require"
+)
+
+// NewServer starts a test server that serves the GitHub API with the given
+// handler and returns a client that talks to it. The server is closed when the
+// test finishes.
+func NewServer(t *testing.T, handler http.Handler) *gh.Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	client, err := gh.NewClient("token", srv.URL)
+	require.NoError(t, err)
+	return client
+}
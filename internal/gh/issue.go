@@ -0,0 +1,36 @@
+package gh
+
+import (
+	"context"
+
+	"emperror.dev/errors"
+	"github.com/shurcooL/githubv4"
+)
+
+type Issue struct {
+	ID     githubv4.ID         `graphql:"id"`
+	Number int64               `graphql:"number"`
+	Title  string              `graphql:"title"`
+	State  githubv4.IssueState `graphql:"state"`
+}
+
+// Issue returns information about the issue with the given number in the given
+// repository.
+func (c *Client) Issue(ctx context.Context, owner, repo string, number int64) (*Issue, error) {
+	var query struct {
+		Repository struct {
+			Issue Issue `graphql:"issue(number: $number)"`
+		} `graphql:"repository(owner: $owner, name: $name)"`
+	}
+	if err := c.query(ctx, &query, map[string]any{
+		"owner":  githubv4.String(owner),
+		"name":   githubv4.String(repo),
+		"number": githubv4.Int(number),
+	}); err != nil {
+		return nil, errors.WrapIff(err, "failed to fetch issue #%d", number)
+	}
+	if query.Repository.Issue.ID == nil || query.Repository.Issue.ID == "" {
+		return nil, errors.Errorf("GitHub issue #%d not found in %s/%s", number, owner, repo)
+	}
+	return &query.Repository.Issue, nil
+}
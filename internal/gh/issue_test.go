@@ -0,0 +1,57 @@
+package gh_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/gh/ghtest"
+	"github.com/shurcooL/githubv4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssue(t *testing.T) {
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Contains(t, string(body), `"number":123`)
+		require.Contains(t, string(body), `"owner":"owner"`)
+		_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {
+			"id": "I_1", "number": 123, "title": "Fix the widget", "state": "OPEN"
+		}}}}`))
+	}))
+
+	issue, err := client.Issue(context.Background(), "owner", "repo", 123)
+	require.NoError(t, err)
+	require.Equal(t, &gh.Issue{
+		ID:     "I_1",
+		Number: 123,
+		Title:  "Fix the widget",
+		State:  githubv4.IssueStateOpen,
+	}, issue)
+}
+
+func TestAddIssueLabelsAndAssignees(t *testing.T) {
+	requests := make(map[string]string)
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests[r.URL.Path] = string(body)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`[]`))
+	}))
+
+	require.NoError(t, client.AddIssueLabels(context.Background(), gh.AddIssueLabelInput{
+		Owner: "owner", Repo: "repo", Number: 7, LabelNames: []string{"bug", "backend"},
+	}))
+	require.NoError(t, client.AddIssueAssignees(context.Background(), gh.AddIssueAssigneesInput{
+		Owner: "owner", Repo: "repo", Number: 7, Logins: []string{"alice"},
+	}))
+	require.Equal(t, map[string]string{
+		"/api/v3/repos/owner/repo/issues/7/labels":    `{"labels":["bug","backend"]}`,
+		"/api/v3/repos/owner/repo/issues/7/assignees": `{"assignees":["alice"]}`,
+	}, requests)
+}
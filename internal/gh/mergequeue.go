@@ -0,0 +1,92 @@
+package gh
+
+import (
+	"context"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/shurcooL/githubv4"
+)
+
+// The states of a pull request in GitHub's merge queue.
+const (
+	MergeQueueStateAwaitingChecks = "AWAITING_CHECKS"
+	MergeQueueStateLocked         = "LOCKED"
+	MergeQueueStateMergeable      = "MERGEABLE"
+	MergeQueueStateQueued         = "QUEUED"
+	MergeQueueStateUnmergeable    = "UNMERGEABLE"
+)
+
+// MergeQueueEntry is the entry of a pull request in GitHub's (native) merge
+// queue.
+type MergeQueueEntry struct {
+	// The state of the entry (see MergeQueueState*).
+	State string
+	// The position of the pull request in the queue (starting at 1).
+	Position   int
+	EnqueuedAt time.Time
+}
+
+type mergeQueueEntry struct {
+	State      string
+	Position   int
+	EnqueuedAt githubv4.DateTime
+}
+
+func (e *mergeQueueEntry) entry() *MergeQueueEntry {
+	if e == nil {
+		return nil
+	}
+	return &MergeQueueEntry{State: e.State, Position: e.Position, EnqueuedAt: e.EnqueuedAt.Time}
+}
+
+// PullRequestMergeQueueEntry returns the merge queue entry of the pull request
+// with the given node ID or nil if the pull request isn't in the merge queue.
+func (c *Client) PullRequestMergeQueueEntry(ctx context.Context, id string) (*MergeQueueEntry, error) {
+	var query struct {
+		Node struct {
+			PullRequest struct {
+				ID              string
+				MergeQueueEntry *mergeQueueEntry
+			} `graphql:"... on PullRequest"`
+		} `graphql:"node(id: $id)"`
+	}
+	if err := c.query(ctx, &query, map[string]any{
+		"id": githubv4.ID(id),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to query merge queue entry of pull request")
+	}
+	if query.Node.PullRequest.ID == "" {
+		return nil, errors.Errorf("pull request %q not found", id)
+	}
+	return query.Node.PullRequest.MergeQueueEntry.entry(), nil
+}
+
+// EnqueuePullRequestInput is the input of the enqueuePullRequest mutation
+// (which the version of githubv4 that we use doesn't know about yet).
+type EnqueuePullRequestInput struct {
+	PullRequestID githubv4.ID `json:"pullRequestId"`
+	// If true, the pull request is added to the front of the queue.
+	Jump *githubv4.Boolean `json:"jump,omitempty"`
+}
+
+// EnqueuePullRequest adds the pull request with the given node ID to GitHub's
+// merge queue. If jump is true, it's added to the front of the queue.
+func (c *Client) EnqueuePullRequest(ctx context.Context, id string, jump bool) (*MergeQueueEntry, error) {
+	var mutation struct {
+		EnqueuePullRequest struct {
+			MergeQueueEntry *mergeQueueEntry
+		} `graphql:"enqueuePullRequest(input: $input)"`
+	}
+	input := EnqueuePullRequestInput{PullRequestID: githubv4.ID(id)}
+	if jump {
+		input.Jump = githubv4.NewBoolean(true)
+	}
+	if err := c.mutate(ctx, &mutation, input, nil); err != nil {
+		return nil, errors.Wrap(err, "failed to add pull request to the merge queue")
+	}
+	if mutation.EnqueuePullRequest.MergeQueueEntry == nil {
+		return nil, errors.New("failed to add pull request to the merge queue: no merge queue entry was returned")
+	}
+	return mutation.EnqueuePullRequest.MergeQueueEntry.entry(), nil
+}
@@ -0,0 +1,54 @@
+package gh_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/gh/ghtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeQueue(t *testing.T) {
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		switch {
+		case strings.Contains(string(body), "enqueuePullRequest"):
+			require.Contains(t, string(body), `"input":{"pullRequestId":"PR_1","jump":true}`)
+			_, _ = w.Write([]byte(`{"data": {"enqueuePullRequest": {"mergeQueueEntry": {
+				"state": "QUEUED", "position": 1, "enqueuedAt": "2024-01-01T00:00:00Z"
+			}}}}`))
+		case strings.Contains(string(body), `"id":"PR_1"`):
+			_, _ = w.Write([]byte(`{"data": {"node": {"id": "PR_1", "mergeQueueEntry": {
+				"state": "AWAITING_CHECKS", "position": 2, "enqueuedAt": "2024-01-01T00:00:00Z"
+			}}}}`))
+		default:
+			_, _ = w.Write([]byte(`{"data": {"node": {"id": "PR_2", "mergeQueueEntry": null}}}`))
+		}
+	}))
+
+	enqueuedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry, err := client.PullRequestMergeQueueEntry(context.Background(), "PR_1")
+	require.NoError(t, err)
+	require.Equal(t, &gh.MergeQueueEntry{
+		State:      gh.MergeQueueStateAwaitingChecks,
+		Position:   2,
+		EnqueuedAt: enqueuedAt,
+	}, entry)
+	entry, err = client.PullRequestMergeQueueEntry(context.Background(), "PR_2")
+	require.NoError(t, err)
+	require.Nil(t, entry)
+
+	entry, err = client.EnqueuePullRequest(context.Background(), "PR_1", true)
+	require.NoError(t, err)
+	require.Equal(t, &gh.MergeQueueEntry{
+		State:      gh.MergeQueueStateQueued,
+		Position:   1,
+		EnqueuedAt: enqueuedAt,
+	}, entry)
+}
@@ -0,0 +1,72 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// RepositoryAccess describes what the token of the client is allowed to do in
+// a repository.
+type RepositoryAccess struct {
+	// The OAuth scopes of the token (e.g., "repo"). This is only known for
+	// classic personal access tokens (and OAuth tokens); it's nil for other
+	// tokens (e.g., fine-grained personal access tokens or GitHub App tokens),
+	// whose permissions are configured per repository instead.
+	Scopes []string
+	// True if the token can push to the repository.
+	Push bool
+	// True if the repository is private.
+	Private bool
+}
+
+// HasScope returns true if the token has the given scope. Unknown scopes (see
+// RepositoryAccess.Scopes) are assumed to be granted.
+func (a *RepositoryAccess) HasScope(scope string) bool {
+	if a.Scopes == nil {
+		return true
+	}
+	for _, s := range a.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// RepositoryAccess returns what the token of the client is allowed to do in the
+// given repository. If the token can't access the repository at all, GitHub
+// responds with 404 Not Found (as if the repository didn't exist), which is
+// returned as an *HTTPError.
+func (c *Client) RepositoryAccess(ctx context.Context, owner, repo string) (*RepositoryAccess, error) {
+	var result struct {
+		Private     bool `json:"private"`
+		Permissions struct {
+			Push bool `json:"push"`
+		} `json:"permissions"`
+	}
+	header, err := c.restWithHeader(
+		ctx, http.MethodGet, fmt.Sprintf("/repos/%s/%s", owner, repo), nil, &result,
+	)
+	if err != nil {
+		return nil, err
+	}
+	access := &RepositoryAccess{
+		Push:    result.Permissions.Push,
+		Private: result.Private,
+	}
+	// GitHub only sets the header for tokens that have OAuth scopes (an empty
+	// header means that the token doesn't have any scopes).
+	if values, ok := header["X-Oauth-Scopes"]; ok {
+		access.Scopes = []string{}
+		for _, value := range values {
+			for _, scope := range strings.Split(value, ",") {
+				if scope = strings.TrimSpace(scope); scope != "" {
+					access.Scopes = append(access.Scopes, scope)
+				}
+			}
+		}
+	}
+	return access, nil
+}
@@ -0,0 +1,52 @@
+package gh_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/gh/ghtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositoryAccess(t *testing.T) {
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/owner/classic":
+			w.Header().Set("X-OAuth-Scopes", "repo, workflow")
+			_, _ = w.Write([]byte(`{"private": true, "permissions": {"push": true}}`))
+		case "/api/v3/repos/owner/no-scopes":
+			w.Header().Set("X-OAuth-Scopes", "")
+			_, _ = w.Write([]byte(`{"private": false, "permissions": {"push": false}}`))
+		case "/api/v3/repos/owner/fine-grained":
+			_, _ = w.Write([]byte(`{"private": true, "permissions": {"push": true}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	ctx := context.Background()
+
+	access, err := client.RepositoryAccess(ctx, "owner", "classic")
+	require.NoError(t, err)
+	require.Equal(t, &gh.RepositoryAccess{Scopes: []string{"repo", "workflow"}, Push: true, Private: true}, access)
+	require.True(t, access.HasScope("repo"))
+	require.False(t, access.HasScope("admin:org"))
+
+	access, err = client.RepositoryAccess(ctx, "owner", "no-scopes")
+	require.NoError(t, err)
+	require.Equal(t, &gh.RepositoryAccess{Scopes: []string{}}, access)
+	require.False(t, access.HasScope("repo"))
+
+	// The scopes of other tokens are unknown.
+	access, err = client.RepositoryAccess(ctx, "owner", "fine-grained")
+	require.NoError(t, err)
+	require.Nil(t, access.Scopes)
+	require.True(t, access.HasScope("repo"))
+
+	_, err = client.RepositoryAccess(ctx, "owner", "missing")
+	var httpErr *gh.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+}
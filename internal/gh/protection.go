@@ -0,0 +1,124 @@
+package gh
+
+import (
+	"context"
+
+	"emperror.dev/errors"
+	"github.com/shurcooL/githubv4"
+)
+
+// BranchProtection is the subset of the branch protection rules of a branch
+// that determine whether a pull request can be merged into it.
+type BranchProtection struct {
+	// The number of approving reviews that are required (zero if none).
+	RequiredApprovingReviewCount int
+	// True if a review from a code owner is required.
+	RequiresCodeOwnerReviews bool
+	// The names of the checks that are required to pass.
+	RequiredStatusChecks []string
+	// True if the history of the branch must be linear (i.e., pull requests
+	// can't be merged with a merge commit).
+	RequiresLinearHistory bool
+	// True if all commits must be signed.
+	RequiresSignatures bool
+	// True if all review conversations must be resolved.
+	RequiresConversationResolution bool
+}
+
+// PullRequestProtection is the state of a pull request that's relevant to the
+// branch protection rules of its base branch.
+type PullRequestProtection struct {
+	// The rules of the base branch (nil if the base branch isn't protected).
+	Protection *BranchProtection
+	// How GitHub sees the mergeability of the pull request (e.g., "BEHIND" if
+	// the branch has to be brought up-to-date with the base branch first).
+	MergeStateStatus string
+	// The number of approving reviews (from reviewers with write access).
+	Approvals int
+	// The commits of the pull request that aren't (validly) signed.
+	UnsignedCommits []string
+	// The number of unresolved review conversations.
+	UnresolvedThreads int
+}
+
+// PullRequestProtection returns the branch protection rules of the base branch
+// of the pull request with the given node ID and the state of the pull request
+// that they apply to. Only the rules that are visible to users without admin
+// access to the repository are considered.
+func (c *Client) PullRequestProtection(ctx context.Context, id string) (*PullRequestProtection, error) {
+	var query struct {
+		Node struct {
+			PullRequest struct {
+				ID               string
+				MergeStateStatus string
+				BaseRef          struct {
+					RefUpdateRule *struct {
+						RequiredApprovingReviewCount   int
+						RequiresCodeOwnerReviews       bool
+						RequiredStatusCheckContexts    []string
+						RequiresLinearHistory          bool
+						RequiresSignatures             bool
+						RequiresConversationResolution bool
+					}
+				}
+				LatestOpinionatedReviews struct {
+					Nodes []struct {
+						State githubv4.PullRequestReviewState
+					}
+				} `graphql:"latestOpinionatedReviews(first: 100, writersOnly: true)"`
+				Commits struct {
+					Nodes []struct {
+						Commit struct {
+							Oid       string
+							Signature *struct {
+								IsValid bool
+							}
+						}
+					}
+				} `graphql:"commits(first: 100)"`
+				ReviewThreads struct {
+					Nodes []struct {
+						IsResolved bool
+					}
+				} `graphql:"reviewThreads(first: 100)"`
+			} `graphql:"... on PullRequest"`
+		} `graphql:"node(id: $id)"`
+	}
+	if err := c.query(ctx, &query, map[string]any{
+		"id": githubv4.ID(id),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to query branch protection of pull request")
+	}
+	pr := query.Node.PullRequest
+	if pr.ID == "" {
+		return nil, errors.Errorf("pull request %q not found", id)
+	}
+
+	res := &PullRequestProtection{MergeStateStatus: pr.MergeStateStatus}
+	if rule := pr.BaseRef.RefUpdateRule; rule != nil {
+		res.Protection = &BranchProtection{
+			RequiredApprovingReviewCount:   rule.RequiredApprovingReviewCount,
+			RequiresCodeOwnerReviews:       rule.RequiresCodeOwnerReviews,
+			RequiredStatusChecks:           rule.RequiredStatusCheckContexts,
+			RequiresLinearHistory:          rule.RequiresLinearHistory,
+			RequiresSignatures:             rule.RequiresSignatures,
+			RequiresConversationResolution: rule.RequiresConversationResolution,
+		}
+	}
+	for _, node := range pr.LatestOpinionatedReviews.Nodes {
+		if node.State == githubv4.PullRequestReviewStateApproved {
+			res.Approvals++
+		}
+	}
+	for _, node := range pr.Commits.Nodes {
+		if node.Commit.Signature == nil || !node.Commit.Signature.IsValid {
+			res.UnsignedCommits = append(res.UnsignedCommits, node.Commit.Oid)
+		}
+	}
+	for _, node := range pr.ReviewThreads.Nodes {
+		if !node.IsResolved {
+			res.UnresolvedThreads++
+		}
+	}
+	return res, nil
+}
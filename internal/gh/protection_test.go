@@ -0,0 +1,52 @@
+package gh_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/gh/ghtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullRequestProtection(t *testing.T) {
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"node": {
+			"id": "PR_1",
+			"mergeStateStatus": "BEHIND",
+			"baseRef": {"refUpdateRule": {
+				"requiredApprovingReviewCount": 2,
+				"requiresCodeOwnerReviews": true,
+				"requiredStatusCheckContexts": ["build", "test"],
+				"requiresLinearHistory": true,
+				"requiresSignatures": true,
+				"requiresConversationResolution": true
+			}},
+			"latestOpinionatedReviews": {"nodes": [{"state": "APPROVED"}, {"state": "CHANGES_REQUESTED"}]},
+			"commits": {"nodes": [
+				{"commit": {"oid": "aaa", "signature": {"isValid": true}}},
+				{"commit": {"oid": "bbb", "signature": {"isValid": false}}},
+				{"commit": {"oid": "ccc", "signature": null}}
+			]},
+			"reviewThreads": {"nodes": [{"isResolved": true}, {"isResolved": false}]}
+		}}}`))
+	}))
+
+	protection, err := client.PullRequestProtection(context.Background(), "PR_1")
+	require.NoError(t, err)
+	require.Equal(t, &gh.PullRequestProtection{
+		Protection: &gh.BranchProtection{
+			RequiredApprovingReviewCount:   2,
+			RequiresCodeOwnerReviews:       true,
+			RequiredStatusChecks:           []string{"build", "test"},
+			RequiresLinearHistory:          true,
+			RequiresSignatures:             true,
+			RequiresConversationResolution: true,
+		},
+		MergeStateStatus:  "BEHIND",
+		Approvals:         1,
+		UnsignedCommits:   []string{"bbb", "ccc"},
+		UnresolvedThreads: 1,
+	}, protection)
+}
@@ -20,11 +20,14 @@ type PullRequest struct {
 	BaseRefName         string
 	IsDraft             bool
 	Mergeable           githubv4.MergeableState
+	ReviewDecision      githubv4.PullRequestReviewDecision
 	Merged              bool
 	Permalink           string
 	State               githubv4.PullRequestState
 	Title               string
 	Body                string
+	CreatedAt           githubv4.DateTime
+	MergedAt            *githubv4.DateTime
 	PRIVATE_MergeCommit struct {
 		Oid string
 	} `graphql:"mergeCommit"`
@@ -87,6 +90,53 @@ func (c *Client) PullRequest(ctx context.Context, id string) (*PullRequest, erro
 	return &query.Node.PullRequest, nil
 }
 
+// maxNodesPerQuery is the maximum number of node IDs that can be queried at
+// once with nodes(ids: ...).
+const maxNodesPerQuery = 100
+
+// chunkIDs splits the given node IDs into chunks of at most size IDs.
+func chunkIDs(ids []string, size int) [][]githubv4.ID {
+	var chunks [][]githubv4.ID
+	for len(ids) > 0 {
+		n := min(len(ids), size)
+		chunk := make([]githubv4.ID, n)
+		for i, id := range ids[:n] {
+			chunk[i] = githubv4.ID(id)
+		}
+		chunks = append(chunks, chunk)
+		ids = ids[n:]
+	}
+	return chunks
+}
+
+// PullRequests returns the pull requests with the given node IDs (in the same
+// order). Unlike calling PullRequest for each of them, the pull requests are
+// queried in a single request (per 100 pull requests), which makes a big
+// difference for large stacks.
+func (c *Client) PullRequests(ctx context.Context, ids []string) ([]*PullRequest, error) {
+	res := make([]*PullRequest, 0, len(ids))
+	for _, chunk := range chunkIDs(ids, maxNodesPerQuery) {
+		var query struct {
+			Nodes []struct {
+				PullRequest PullRequest `graphql:"... on PullRequest"`
+			} `graphql:"nodes(ids: $ids)"`
+		}
+		if err := c.query(ctx, &query, map[string]any{
+			"ids": chunk,
+		}); err != nil {
+			return nil, errors.Wrap(err, "failed to query pull requests")
+		}
+		for i, node := range query.Nodes {
+			if node.PullRequest.ID == "" {
+				return nil, errors.Errorf("pull request %q not found", chunk[i])
+			}
+			pr := node.PullRequest
+			res = append(res, &pr)
+		}
+	}
+	return res, nil
+}
+
 type GetPullRequestsInput struct {
 	// REQUIRED
 	Owner string
@@ -187,6 +237,74 @@ func (c *Client) RequestReviews(
 	return &mutation.RequestReviews.PullRequest, nil
 }
 
+// AddComment adds a comment to the given pull request (or issue).
+func (c *Client) AddComment(ctx context.Context, subjectID string, body string) error {
+	var mutation struct {
+		AddComment struct {
+			ClientMutationID string
+		} `graphql:"addComment(input: $input)"`
+	}
+	input := githubv4.AddCommentInput{
+		SubjectID: subjectID,
+		Body:      githubv4.String(body),
+	}
+	if err := c.mutate(ctx, &mutation, input, nil); err != nil {
+		return errors.Wrap(err, "failed to add comment: github error")
+	}
+	return nil
+}
+
+// MergePullRequest merges the given pull request.
+func (c *Client) MergePullRequest(
+	ctx context.Context,
+	input githubv4.MergePullRequestInput,
+) (*PullRequest, error) {
+	var mutation struct {
+		MergePullRequest struct {
+			PullRequest PullRequest
+		} `graphql:"mergePullRequest(input: $input)"`
+	}
+	if err := c.mutate(ctx, &mutation, input, nil); err != nil {
+		return nil, errors.Wrap(err, "failed to merge pull request: github error")
+	}
+	return &mutation.MergePullRequest.PullRequest, nil
+}
+
+// EnablePullRequestAutoMerge enables auto-merge for the pull request with the
+// given node ID so that GitHub merges it (with the given method) as soon as its
+// requirements are met.
+func (c *Client) EnablePullRequestAutoMerge(
+	ctx context.Context,
+	id string,
+	method githubv4.PullRequestMergeMethod,
+) (*PullRequest, error) {
+	var mutation struct {
+		EnablePullRequestAutoMerge struct {
+			PullRequest PullRequest
+		} `graphql:"enablePullRequestAutoMerge(input: $input)"`
+	}
+	if err := c.mutate(ctx, &mutation, githubv4.EnablePullRequestAutoMergeInput{
+		PullRequestID: id,
+		MergeMethod:   &method,
+	}, nil); err != nil {
+		return nil, errors.Wrap(err, "failed to enable auto-merge: github error")
+	}
+	return &mutation.EnablePullRequestAutoMerge.PullRequest, nil
+}
+
+// ClosePullRequest closes the given pull request without merging it.
+func (c *Client) ClosePullRequest(ctx context.Context, id string) (*PullRequest, error) {
+	var mutation struct {
+		ClosePullRequest struct {
+			PullRequest PullRequest
+		} `graphql:"closePullRequest(input: $input)"`
+	}
+	if err := c.mutate(ctx, &mutation, githubv4.ClosePullRequestInput{PullRequestID: id}, nil); err != nil {
+		return nil, errors.Wrap(err, "failed to close pull request: github error")
+	}
+	return &mutation.ClosePullRequest.PullRequest, nil
+}
+
 func (c *Client) ConvertPullRequestToDraft(ctx context.Context, id string) (*PullRequest, error) {
 	var mutation struct {
 		ConvertPullRequestToDraft struct {
@@ -238,13 +356,40 @@ func (c *Client) AddIssueLabels(ctx context.Context, input AddIssueLabelInput) e
 	}{
 		Labels: input.LabelNames,
 	}
-	endpoint := fmt.Sprintf("/repos/%s/%s/issues/%d", input.Owner, input.Repo, input.Number)
+	endpoint := fmt.Sprintf("/repos/%s/%s/issues/%d/labels", input.Owner, input.Repo, input.Number)
 	if err := c.restPost(ctx, endpoint, req, nil); err != nil {
 		return errors.Wrap(err, "failed to add labels")
 	}
 	return nil
 }
 
+type AddIssueAssigneesInput struct {
+	// The owner of the GitHub repository.
+	Owner string
+	// The name of the GitHub repository.
+	Repo string
+	// The number of the issue or pull request to assign.
+	Number int64
+	// The logins of the users to assign to the issue.
+	Logins []string
+}
+
+// AddIssueAssignees assigns users to an issue (or pull request). Like
+// AddIssueLabels, this uses the REST API so that the users can be given by
+// login instead of node id.
+func (c *Client) AddIssueAssignees(ctx context.Context, input AddIssueAssigneesInput) error {
+	req := struct {
+		Assignees []string `json:"assignees"`
+	}{
+		Assignees: input.Logins,
+	}
+	endpoint := fmt.Sprintf("/repos/%s/%s/issues/%d/assignees", input.Owner, input.Repo, input.Number)
+	if err := c.restPost(ctx, endpoint, req, nil); err != nil {
+		return errors.Wrap(err, "failed to add assignees")
+	}
+	return nil
+}
+
 type RepoPullRequestOpts struct {
 	Owner  string
 	Repo   string
@@ -0,0 +1,70 @@
+package gh_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aviator-co/av/internal/gh/ghtest"
+	"github.com/shurcooL/githubv4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullRequests(t *testing.T) {
+	var requests int
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var req struct {
+			Query     string
+			Variables struct {
+				IDs []string
+			}
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Contains(t, req.Query, "nodes(ids: $ids)")
+		var nodes []string
+		for _, id := range req.Variables.IDs {
+			nodes = append(nodes, fmt.Sprintf(`{"id": %q, "number": %s, "state": "OPEN"}`, id, strings.TrimPrefix(id, "PR_")))
+		}
+		_, _ = fmt.Fprintf(w, `{"data": {"nodes": [%s]}}`, strings.Join(nodes, ","))
+	}))
+
+	var ids []string
+	for i := 1; i <= 101; i++ {
+		ids = append(ids, fmt.Sprintf("PR_%d", i))
+	}
+	prs, err := client.PullRequests(context.Background(), ids)
+	require.NoError(t, err)
+	// At most 100 pull requests are queried at once.
+	require.Equal(t, 2, requests)
+	require.Len(t, prs, 101)
+	for i, pr := range prs {
+		require.Equal(t, ids[i], pr.ID)
+		require.Equal(t, int64(i+1), pr.Number)
+	}
+}
+
+func TestEnablePullRequestAutoMerge(t *testing.T) {
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string
+			Variables struct {
+				Input map[string]any
+			}
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Contains(t, req.Query, "enablePullRequestAutoMerge(input: $input)")
+		require.Equal(t, map[string]any{"pullRequestId": "PR_1", "mergeMethod": "SQUASH"}, req.Variables.Input)
+		_, _ = w.Write([]byte(`{"data": {"enablePullRequestAutoMerge": {"pullRequest": {
+			"id": "PR_1", "number": 1, "state": "OPEN"
+		}}}}`))
+	}))
+
+	pr, err := client.EnablePullRequestAutoMerge(context.Background(), "PR_1", githubv4.PullRequestMergeMethodSquash)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), pr.Number)
+	require.Equal(t, githubv4.PullRequestStateOpen, pr.State)
+}
@@ -2,6 +2,8 @@ package gh
 
 import (
 	"context"
+	"fmt"
+	"net/url"
 	"strings"
 
 	"emperror.dev/errors"
@@ -38,3 +40,16 @@ func (c *Client) GetRepositoryBySlug(ctx context.Context, slug string) (*Reposit
 
 	return &query.Repository, nil
 }
+
+// RenameBranch renames a branch of the repository. GitHub updates the open pull
+// requests whose head or base is the branch accordingly.
+func (c *Client) RenameBranch(ctx context.Context, owner, repo, branch, newName string) error {
+	endpoint := fmt.Sprintf("/repos/%s/%s/branches/%s/rename", owner, repo, url.PathEscape(branch))
+	body := struct {
+		NewName string `json:"new_name"`
+	}{newName}
+	if err := c.restPost(ctx, endpoint, body, nil); err != nil {
+		return errors.WrapIff(err, "failed to rename branch %q to %q", branch, newName)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package gh_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aviator-co/av/internal/gh/ghtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameBranch(t *testing.T) {
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/api/v3/repos/owner/repo/branches/feature%2Fold/rename", r.URL.EscapedPath())
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, map[string]string{"new_name": "feature/new"}, body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"name": "feature/new"}`))
+	}))
+
+	require.NoError(t, client.RenameBranch(context.Background(), "owner", "repo", "feature/old", "feature/new"))
+}
@@ -0,0 +1,243 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/shurcooL/githubv4"
+)
+
+// ReviewRequest is a pending request for a review of a pull request.
+type ReviewRequest struct {
+	// The login of the requested user or the "org/team" slug of the requested
+	// team.
+	Reviewer string
+	// True if the review was requested because the reviewer owns some of the
+	// changed files (see CODEOWNERS).
+	AsCodeOwner bool
+}
+
+// PullRequestReviews is the review state of a pull request.
+type PullRequestReviews struct {
+	// Whether the pull request has the reviews that the branch protection rules
+	// of its base branch require (including the reviews of code owners). Empty
+	// if no reviews are required.
+	Decision githubv4.PullRequestReviewDecision
+	// The reviews that were requested but not submitted yet.
+	Pending []ReviewRequest
+	// The logins of the reviewers whose latest review requested changes.
+	ChangesRequestedBy []string
+	// The logins of the reviewers whose latest review approved the pull
+	// request.
+	ApprovedBy []string
+}
+
+// Satisfied returns true if the pull request has all the reviews it requires.
+func (r *PullRequestReviews) Satisfied() bool {
+	return r.Decision == "" || r.Decision == githubv4.PullRequestReviewDecisionApproved
+}
+
+// reviewsFields are the fields of a pull request that PullRequestReviews is
+// built from (see reviews).
+type reviewsFields struct {
+	ReviewRequests struct {
+		Nodes []struct {
+			AsCodeOwner       bool
+			RequestedReviewer struct {
+				User struct {
+					Login string
+				} `graphql:"... on User"`
+				Team struct {
+					CombinedSlug string
+				} `graphql:"... on Team"`
+			}
+		}
+	} `graphql:"reviewRequests(first: 100)"`
+	LatestOpinionatedReviews struct {
+		Nodes []struct {
+			Author struct {
+				Login string
+			}
+			State githubv4.PullRequestReviewState
+		}
+	} `graphql:"latestOpinionatedReviews(first: 100, writersOnly: true)"`
+}
+
+// reviews returns the review state of the pull request with the given review
+// decision.
+func (f reviewsFields) reviews(decision githubv4.PullRequestReviewDecision) *PullRequestReviews {
+	res := &PullRequestReviews{Decision: decision}
+	for _, node := range f.ReviewRequests.Nodes {
+		reviewer := node.RequestedReviewer.User.Login
+		if reviewer == "" {
+			reviewer = node.RequestedReviewer.Team.CombinedSlug
+		}
+		if reviewer == "" {
+			// E.g., a mannequin or a bot.
+			continue
+		}
+		res.Pending = append(res.Pending, ReviewRequest{Reviewer: reviewer, AsCodeOwner: node.AsCodeOwner})
+	}
+	for _, node := range f.LatestOpinionatedReviews.Nodes {
+		switch node.State {
+		case githubv4.PullRequestReviewStateChangesRequested:
+			res.ChangesRequestedBy = append(res.ChangesRequestedBy, node.Author.Login)
+		case githubv4.PullRequestReviewStateApproved:
+			res.ApprovedBy = append(res.ApprovedBy, node.Author.Login)
+		}
+	}
+	return res
+}
+
+// PullRequestReviews returns the review state of the pull request with the
+// given node ID.
+func (c *Client) PullRequestReviews(ctx context.Context, id string) (*PullRequestReviews, error) {
+	var query struct {
+		Node struct {
+			PullRequest struct {
+				ID             string
+				ReviewDecision githubv4.PullRequestReviewDecision
+				reviewsFields
+			} `graphql:"... on PullRequest"`
+		} `graphql:"node(id: $id)"`
+	}
+	if err := c.query(ctx, &query, map[string]any{
+		"id": githubv4.ID(id),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to query pull request reviews")
+	}
+	pr := query.Node.PullRequest
+	if pr.ID == "" {
+		return nil, errors.Errorf("pull request %q not found", id)
+	}
+	return pr.reviews(pr.ReviewDecision), nil
+}
+
+// ReviewThread is a thread of review comments on a pull request.
+type ReviewThread struct {
+	// The file and line that the thread is about. The line is zero if the
+	// thread is about the whole file.
+	Path string
+	Line int
+	// True if the lines that the thread is about were changed since.
+	IsOutdated bool
+	// The comments of the thread (oldest first).
+	Comments []ReviewComment
+}
+
+// ReviewComment is a comment in a ReviewThread.
+type ReviewComment struct {
+	Author string
+	Body   string
+	URL    string
+}
+
+// UnresolvedReviewThreads returns the review threads of the pull request with
+// the given node ID that haven't been resolved yet. Only the first 100 threads
+// (and the first 50 comments of each thread) are considered.
+func (c *Client) UnresolvedReviewThreads(ctx context.Context, id string) ([]ReviewThread, error) {
+	var query struct {
+		Node struct {
+			PullRequest struct {
+				ID            string
+				ReviewThreads struct {
+					Nodes []struct {
+						IsResolved   bool
+						IsOutdated   bool
+						Path         string
+						Line         int
+						OriginalLine int
+						Comments     struct {
+							Nodes []struct {
+								Author struct {
+									Login string
+								}
+								Body string
+								URL  string
+							}
+						} `graphql:"comments(first: 50)"`
+					}
+				} `graphql:"reviewThreads(first: 100)"`
+			} `graphql:"... on PullRequest"`
+		} `graphql:"node(id: $id)"`
+	}
+	if err := c.query(ctx, &query, map[string]any{
+		"id": githubv4.ID(id),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to query pull request review threads")
+	}
+	pr := query.Node.PullRequest
+	if pr.ID == "" {
+		return nil, errors.Errorf("pull request %q not found", id)
+	}
+	var res []ReviewThread
+	for _, node := range pr.ReviewThreads.Nodes {
+		if node.IsResolved {
+			continue
+		}
+		thread := ReviewThread{Path: node.Path, Line: node.Line, IsOutdated: node.IsOutdated}
+		if thread.Line == 0 {
+			// Outdated threads don't have a line in the current diff anymore.
+			thread.Line = node.OriginalLine
+		}
+		for _, comment := range node.Comments.Nodes {
+			thread.Comments = append(thread.Comments, ReviewComment{
+				Author: comment.Author.Login,
+				Body:   comment.Body,
+				URL:    comment.URL,
+			})
+		}
+		res = append(res, thread)
+	}
+	return res, nil
+}
+
+// PullRequestReviewComment is a review comment on some lines of a pull request
+// as returned by the REST API.
+type PullRequestReviewComment struct {
+	ID   int64  `json:"id"`
+	Body string `json:"body"`
+	Path string `json:"path"`
+	// The lines of the file (at CommitID) that the comment is about. StartLine
+	// is zero for single-line comments, and Line is zero if the comment is
+	// outdated (in which case only the original lines are known).
+	StartLine int    `json:"start_line"`
+	Line      int    `json:"line"`
+	CommitID  string `json:"commit_id"`
+	// The lines of the file (at OriginalCommitID) that the comment was made on.
+	OriginalStartLine int    `json:"original_start_line"`
+	OriginalLine      int    `json:"original_line"`
+	OriginalCommitID  string `json:"original_commit_id"`
+	// The API URL of the pull request (which ends with its number).
+	PullRequestURL string `json:"pull_request_url"`
+	HTMLURL        string `json:"html_url"`
+	User           struct {
+		Login string `json:"login"`
+	} `json:"user"`
+}
+
+// PullRequestNumber returns the number of the pull request of the comment.
+func (c *PullRequestReviewComment) PullRequestNumber() int64 {
+	i := strings.LastIndex(c.PullRequestURL, "/")
+	number, _ := strconv.ParseInt(c.PullRequestURL[i+1:], 10, 64)
+	return number
+}
+
+// PullRequestReviewComment returns the review comment with the given (REST)
+// ID, e.g., the number at the end of the URL of a comment
+// (https://github.com/owner/repo/pull/123#discussion_r<ID>).
+func (c *Client) PullRequestReviewComment(
+	ctx context.Context,
+	owner, repo string,
+	id int64,
+) (*PullRequestReviewComment, error) {
+	var comment PullRequestReviewComment
+	endpoint := fmt.Sprintf("/repos/%s/%s/pulls/comments/%d", owner, repo, id)
+	if err := c.restGet(ctx, endpoint, &comment); err != nil {
+		return nil, errors.WrapIff(err, "failed to get review comment %d", id)
+	}
+	return &comment, nil
+}
@@ -0,0 +1,112 @@
+package gh_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/gh/ghtest"
+	"github.com/shurcooL/githubv4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullRequestReviews(t *testing.T) {
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"node": {
+			"id": "PR_1",
+			"reviewDecision": "CHANGES_REQUESTED",
+			"reviewRequests": {"nodes": [
+				{"asCodeOwner": false, "requestedReviewer": {"login": "alice"}},
+				{"asCodeOwner": true, "requestedReviewer": {"combinedSlug": "org/backend"}},
+				{"asCodeOwner": false, "requestedReviewer": {}}
+			]},
+			"latestOpinionatedReviews": {"nodes": [
+				{"author": {"login": "bob"}, "state": "APPROVED"},
+				{"author": {"login": "carol"}, "state": "CHANGES_REQUESTED"}
+			]}
+		}}}`))
+	}))
+
+	reviews, err := client.PullRequestReviews(context.Background(), "PR_1")
+	require.NoError(t, err)
+	require.Equal(t, &gh.PullRequestReviews{
+		Decision: githubv4.PullRequestReviewDecisionChangesRequested,
+		Pending: []gh.ReviewRequest{
+			{Reviewer: "alice"},
+			{Reviewer: "org/backend", AsCodeOwner: true},
+		},
+		ChangesRequestedBy: []string{"carol"},
+		ApprovedBy:         []string{"bob"},
+	}, reviews)
+	require.False(t, reviews.Satisfied())
+	require.True(t, (&gh.PullRequestReviews{}).Satisfied())
+	require.True(t, (&gh.PullRequestReviews{Decision: githubv4.PullRequestReviewDecisionApproved}).Satisfied())
+}
+
+func TestUnresolvedReviewThreads(t *testing.T) {
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"node": {
+			"id": "PR_1",
+			"reviewThreads": {"nodes": [
+				{"isResolved": true, "isOutdated": false, "path": "a.go", "line": 1, "originalLine": 1,
+				 "comments": {"nodes": [{"author": {"login": "alice"}, "body": "Done", "url": "https://x/1"}]}},
+				{"isResolved": false, "isOutdated": false, "path": "b.go", "line": 12, "originalLine": 10,
+				 "comments": {"nodes": [
+					{"author": {"login": "bob"}, "body": "Why?", "url": "https://x/2"},
+					{"author": {"login": "carol"}, "body": "Because.", "url": "https://x/3"}
+				 ]}},
+				{"isResolved": false, "isOutdated": true, "path": "c.go", "line": null, "originalLine": 7,
+				 "comments": {"nodes": [{"author": {"login": "bob"}, "body": "Typo", "url": "https://x/4"}]}}
+			]}
+		}}}`))
+	}))
+
+	threads, err := client.UnresolvedReviewThreads(context.Background(), "PR_1")
+	require.NoError(t, err)
+	require.Equal(t, []gh.ReviewThread{
+		{
+			Path: "b.go",
+			Line: 12,
+			Comments: []gh.ReviewComment{
+				{Author: "bob", Body: "Why?", URL: "https://x/2"},
+				{Author: "carol", Body: "Because.", URL: "https://x/3"},
+			},
+		},
+		{
+			Path:       "c.go",
+			Line:       7,
+			IsOutdated: true,
+			Comments:   []gh.ReviewComment{{Author: "bob", Body: "Typo", URL: "https://x/4"}},
+		},
+	}, threads)
+}
+
+func TestPullRequestReviewComment(t *testing.T) {
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		require.Equal(t, "/api/v3/repos/owner/repo/pulls/comments/42", r.URL.Path)
+		_, _ = w.Write([]byte(`{
+			"id": 42,
+			"body": "Nit:\n` + "```suggestion\\nfoo\\n```" + `",
+			"path": "a.go",
+			"start_line": null,
+			"line": 3,
+			"commit_id": "abc",
+			"original_start_line": null,
+			"original_line": 3,
+			"original_commit_id": "abc",
+			"pull_request_url": "https://api.github.com/repos/owner/repo/pulls/12",
+			"html_url": "https://github.com/owner/repo/pull/12#discussion_r42",
+			"user": {"login": "alice"}
+		}`))
+	}))
+
+	comment, err := client.PullRequestReviewComment(context.Background(), "owner", "repo", 42)
+	require.NoError(t, err)
+	require.Equal(t, "Nit:\n```suggestion\nfoo\n```", comment.Body)
+	require.Equal(t, 0, comment.StartLine)
+	require.Equal(t, 3, comment.Line)
+	require.Equal(t, "alice", comment.User.Login)
+	require.EqualValues(t, 12, comment.PullRequestNumber())
+}
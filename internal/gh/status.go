@@ -0,0 +1,62 @@
+package gh
+
+import (
+	"context"
+
+	"emperror.dev/errors"
+)
+
+// statusQueryBatchSize is the number of pull requests whose status is queried
+// at once by PullRequestsWithStatus. It's lower than maxNodesPerQuery since
+// each pull request has up to a hundred review requests, reviews, and checks,
+// which GitHub counts towards the rate limit (and the node limit) of the query.
+const statusQueryBatchSize = 25
+
+// PullRequestWithStatus is a pull request along with the state of its reviews
+// and of the checks of its head commit.
+type PullRequestWithStatus struct {
+	PullRequest *PullRequest
+	Reviews     *PullRequestReviews
+	// The checks of the head commit (empty if the pull request has no commits).
+	Checks *PullRequestChecks
+}
+
+// PullRequestsWithStatus returns the pull requests with the given node IDs (in
+// the same order) along with their reviews and checks. This is equivalent to
+// calling PullRequest, PullRequestReviews, and PullRequestChecks for each of
+// the pull requests, but it only takes a single request per 25 pull requests.
+func (c *Client) PullRequestsWithStatus(ctx context.Context, ids []string) ([]PullRequestWithStatus, error) {
+	res := make([]PullRequestWithStatus, 0, len(ids))
+	for _, chunk := range chunkIDs(ids, statusQueryBatchSize) {
+		var query struct {
+			Nodes []struct {
+				PullRequest struct {
+					PullRequest
+					reviewsFields
+					checksFields
+				} `graphql:"... on PullRequest"`
+			} `graphql:"nodes(ids: $ids)"`
+		}
+		if err := c.query(ctx, &query, map[string]any{
+			"ids": chunk,
+		}); err != nil {
+			return nil, errors.Wrap(err, "failed to query pull requests")
+		}
+		for i, node := range query.Nodes {
+			pr := node.PullRequest
+			if pr.ID == "" {
+				return nil, errors.Errorf("pull request %q not found", chunk[i])
+			}
+			checks := pr.checks()
+			if checks == nil {
+				checks = &PullRequestChecks{}
+			}
+			res = append(res, PullRequestWithStatus{
+				PullRequest: &pr.PullRequest,
+				Reviews:     pr.reviews(pr.ReviewDecision),
+				Checks:      checks,
+			})
+		}
+	}
+	return res, nil
+}
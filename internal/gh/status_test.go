@@ -0,0 +1,47 @@
+package gh_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/aviator-co/av/internal/gh/ghtest"
+	"github.com/shurcooL/githubv4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullRequestsWithStatus(t *testing.T) {
+	client := ghtest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"nodes": [
+			{"id": "PR_1", "number": 1, "state": "OPEN", "reviewDecision": "APPROVED",
+				"reviewRequests": {"nodes": [{"asCodeOwner": true, "requestedReviewer": {"combinedSlug": "org/team"}}]},
+				"latestOpinionatedReviews": {"nodes": [{"author": {"login": "alice"}, "state": "APPROVED"}]},
+				"commits": {"nodes": [{"commit": {"oid": "abc", "statusCheckRollup": {"contexts": {"nodes": [
+					{"__typename": "StatusContext", "context": "lint", "state": "PENDING"}
+				]}}}}]}},
+			{"id": "PR_2", "number": 2, "state": "MERGED",
+				"reviewRequests": {"nodes": []},
+				"latestOpinionatedReviews": {"nodes": []},
+				"commits": {"nodes": [{"commit": {"oid": "def", "statusCheckRollup": null}}]}}
+		]}}`))
+	}))
+
+	prs, err := client.PullRequestsWithStatus(context.Background(), []string{"PR_1", "PR_2"})
+	require.NoError(t, err)
+	require.Len(t, prs, 2)
+
+	require.Equal(t, int64(1), prs[0].PullRequest.Number)
+	require.Equal(t, &gh.PullRequestReviews{
+		Decision:   githubv4.PullRequestReviewDecisionApproved,
+		Pending:    []gh.ReviewRequest{{Reviewer: "org/team", AsCodeOwner: true}},
+		ApprovedBy: []string{"alice"},
+	}, prs[0].Reviews)
+	require.Equal(t, &gh.PullRequestChecks{
+		HeadOID: "abc",
+		Checks:  []gh.Check{{Name: "lint", State: gh.CheckStatePending}},
+	}, prs[0].Checks)
+
+	require.Equal(t, githubv4.PullRequestStateMerged, prs[1].PullRequest.State)
+	require.Equal(t, &gh.PullRequestChecks{HeadOID: "def"}, prs[1].Checks)
+}
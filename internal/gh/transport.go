@@ -0,0 +1,79 @@
+package gh
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"os"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/config"
+)
+
+// newTransport returns the HTTP transport to use for the GitHub API. By
+// default, the proxy is taken from the HTTPS_PROXY, HTTP_PROXY, and NO_PROXY
+// environment variables and the system's CA certificates are trusted (like any
+// other Go program), but both can be configured explicitly (see
+// config.GitHub.ProxyURL and config.GitHub.CACertPath).
+func newTransport() (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if config.Av.GitHub.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.Av.GitHub.ProxyURL)
+		if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, errors.Errorf(
+				"invalid github.proxyUrl %q (expected a URL such as http://proxy.example.com:3128)",
+				config.Av.GitHub.ProxyURL,
+			)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if config.Av.GitHub.CACertPath != "" {
+		pem, err := os.ReadFile(config.Av.GitHub.CACertPath)
+		if err != nil {
+			return nil, errors.WrapIff(err, "failed to read github.caCertPath")
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf(
+				"github.caCertPath %q doesn't contain any PEM-encoded certificates",
+				config.Av.GitHub.CACertPath,
+			)
+		}
+		transport.TLSClientConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    pool,
+		}
+	}
+	return transport, nil
+}
+
+// wrapTLSError explains TLS certificate errors, which usually mean that a proxy
+// intercepts the TLS connections (or that a GHES instance uses a certificate of
+// an internal CA), and how to fix them. Other errors are returned as-is.
+func wrapTLSError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return errors.WrapIf(err, "the TLS certificate of the GitHub API is signed by an unknown authority "+
+			"(if a proxy intercepts the connection or GitHub Enterprise Server uses an internal CA, "+
+			"set github.caCertPath to the CA certificate)")
+	case errors.As(err, &invalid):
+		return errors.WrapIf(err, "the TLS certificate of the GitHub API is invalid "+
+			"(check the system clock and the certificate of the proxy, if any)")
+	case errors.As(err, &hostname):
+		return errors.WrapIf(err, "the TLS certificate of the GitHub API doesn't match its host name "+
+			"(check github.baseUrl and github.proxyUrl)")
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package gh_test
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aviator-co/av/internal/config"
+	"github.com/aviator-co/av/internal/gh"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCACertPath(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"viewer": {"name": "Jane", "login": "jane"}}}`))
+	}))
+	defer srv.Close()
+	github := config.Av.GitHub
+	defer func() { config.Av.GitHub = github }()
+
+	// The certificate of the test server isn't trusted by default.
+	client, err := gh.NewClient("token", srv.URL)
+	require.NoError(t, err)
+	_, err = client.Viewer(context.Background())
+	require.ErrorContains(t, err, "github.caCertPath")
+
+	certPath := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: srv.Certificate().Raw,
+	}), 0644))
+	config.Av.GitHub.CACertPath = certPath
+	client, err = gh.NewClient("token", srv.URL)
+	require.NoError(t, err)
+	viewer, err := client.Viewer(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "jane", viewer.Login)
+
+	// Files without certificates are rejected up front.
+	require.NoError(t, os.WriteFile(certPath, []byte("not a certificate"), 0644))
+	_, err = gh.NewClient("token", srv.URL)
+	require.ErrorContains(t, err, "doesn't contain any PEM-encoded certificates")
+}
+
+func TestClientProxyURL(t *testing.T) {
+	var proxiedHost string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Requests through a proxy use the absolute URL of the target.
+		proxiedHost = r.URL.Host
+		_, _ = w.Write([]byte(`{"data": {"viewer": {"name": "Jane", "login": "jane"}}}`))
+	}))
+	defer proxy.Close()
+	github := config.Av.GitHub
+	defer func() { config.Av.GitHub = github }()
+	config.Av.GitHub.ProxyURL = proxy.URL
+
+	client, err := gh.NewClient("token", "http://github.example.com")
+	require.NoError(t, err)
+	viewer, err := client.Viewer(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "jane", viewer.Login)
+	require.Equal(t, "github.example.com", proxiedHost)
+
+	config.Av.GitHub.ProxyURL = "proxy.example.com"
+	_, err = gh.NewClient("token", "http://github.example.com")
+	require.ErrorContains(t, err, "invalid github.proxyUrl")
+}
+
+func TestIsNetworkError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	client, err := gh.NewClient("token", srv.URL)
+	require.NoError(t, err)
+	_, err = client.Viewer(context.Background())
+	require.Error(t, err)
+	require.False(t, gh.IsNetworkError(err), "unexpected network error: %v", err)
+
+	// Nothing listens on the address of the closed server anymore.
+	srv.Close()
+	client, err = gh.NewClient("token", srv.URL)
+	require.NoError(t, err)
+	_, err = client.Viewer(context.Background())
+	require.True(t, gh.IsNetworkError(err), "unexpected error: %v", err)
+}
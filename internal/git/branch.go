@@ -1,6 +1,12 @@
 package git
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"emperror.dev/errors"
+)
 
 // BranchDelete deletes the given branches (equivalent to `git branch -D`).
 func (r *Repo) BranchDelete(names ...string) error {
@@ -20,3 +26,92 @@ func (r *Repo) BranchSetConfig(name, key, value string) error {
 	})
 	return err
 }
+
+// BranchGetConfig gets a config on the given branch (equivalent to `git config
+// branch.<branch>.<key>`). An empty string is returned if the config is not set.
+func (r *Repo) BranchGetConfig(name, key string) (string, error) {
+	out, err := r.Run(&RunOpts{
+		Args: []string{"config", "--get", fmt.Sprintf("branch.%s.%s", name, key)},
+	})
+	if err != nil {
+		return "", err
+	}
+	// git config exits with 1 if the key is not set.
+	if out.ExitCode == 1 {
+		return "", nil
+	}
+	if out.ExitCode != 0 {
+		return "", errors.Errorf("failed to read branch config %q: %s", key, string(out.Stderr))
+	}
+	return strings.TrimSpace(string(out.Stdout)), nil
+}
+
+// BranchDescription returns the description of the given branch (the
+// branch.<name>.description config that `git branch --edit-description` sets).
+// An empty string is returned if the branch has no description.
+func (r *Repo) BranchDescription(name string) (string, error) {
+	return r.BranchGetConfig(name, "description")
+}
+
+// SetBranchDescription sets the description of the given branch (see
+// BranchDescription). The description is removed if it's empty.
+func (r *Repo) SetBranchDescription(name, description string) error {
+	if description != "" {
+		return r.BranchSetConfig(name, "description", description)
+	}
+	out, err := r.Run(&RunOpts{
+		Args: []string{"config", "--unset", fmt.Sprintf("branch.%s.description", name)},
+	})
+	if err != nil {
+		return err
+	}
+	// git config exits with 5 if the key is not set.
+	if out.ExitCode != 0 && out.ExitCode != 5 {
+		return errors.Errorf("failed to remove the description of branch %q: %s", name, string(out.Stderr))
+	}
+	return nil
+}
+
+// BranchCaseCollision returns the name of an existing branch (other than the
+// ones given in ignore) that collides with the given branch name on a
+// case-insensitive filesystem (e.g., on macOS or Windows), or an empty string
+// if there is no such branch.
+//
+// Branches are stored as files under .git/refs/heads, so `Feature-x` and
+// `feature-x` refer to the same file on such filesystems (as do the
+// directories of `Feature/x` and `feature/y`). Git doesn't detect this, which
+// leaves the refs in a confusing state.
+func (r *Repo) BranchCaseCollision(name string, ignore ...string) (string, error) {
+	out, err := r.Run(&RunOpts{
+		Args:      []string{"for-each-ref", "--format=%(refname)", "refs/heads/"},
+		ExitError: true,
+	})
+	if err != nil {
+		return "", errors.WrapIf(err, "failed to list branches")
+	}
+	for _, ref := range out.Lines() {
+		existing := strings.TrimPrefix(ref, "refs/heads/")
+		if slices.Contains(ignore, existing) {
+			continue
+		}
+		if branchNamesCollide(existing, name) {
+			return existing, nil
+		}
+	}
+	return "", nil
+}
+
+// branchNamesCollide returns true if the two branch names map to the same file
+// or directory on a case-insensitive filesystem while being different names.
+func branchNamesCollide(a, b string) bool {
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		// The first path component that differs determines whether the paths
+		// collide.
+		return strings.EqualFold(as[i], bs[i])
+	}
+	return false
+}
@@ -322,6 +322,28 @@ func (r *Repo) MergeBase(mb *MergeBase) (string, error) {
 	return r.Git(args...)
 }
 
+// IsAncestor returns true if the commit ancestor is an ancestor of (or the same
+// as) the commit descendant.
+func (r *Repo) IsAncestor(ancestor, descendant string) (bool, error) {
+	out, err := r.Run(&RunOpts{
+		Args: []string{"merge-base", "--is-ancestor", ancestor, descendant},
+	})
+	if err != nil {
+		return false, err
+	}
+	switch out.ExitCode {
+	case 0:
+		return true, nil
+	case 1:
+		return false, nil
+	default:
+		return false, errors.Errorf(
+			"failed to determine if %s is an ancestor of %s: %s",
+			ancestor, descendant, string(out.Stderr),
+		)
+	}
+}
+
 type UpdateRef struct {
 	// The name of the ref (e.g., refs/heads/my-branch).
 	Ref string
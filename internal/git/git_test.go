@@ -1,8 +1,11 @@
 package git_test
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/aviator-co/av/internal/git"
 	"github.com/aviator-co/av/internal/git/gittest"
 	"github.com/stretchr/testify/require"
 )
@@ -21,3 +24,71 @@ func TestOrigin(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "aviator-co/av", origin.RepoSlug)
 }
+
+func TestOperationInProgress(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	op, err := repo.OperationInProgress()
+	require.NoError(t, err)
+	require.Equal(t, git.OperationNone, op)
+
+	gittest.CommitFile(t, repo, "file", []byte("main\n"))
+	_, err = repo.Git("checkout", "-b", "feature", "HEAD~1")
+	require.NoError(t, err)
+	gittest.CommitFile(t, repo, "file", []byte("feature\n"))
+
+	out, err := repo.Run(&git.RunOpts{Args: []string{"merge", "main"}})
+	require.NoError(t, err)
+	require.NotEqual(t, 0, out.ExitCode, "merge should conflict")
+	op, err = repo.OperationInProgress()
+	require.NoError(t, err)
+	require.Equal(t, git.OperationMerge, op)
+}
+
+func TestStatus(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	status, err := repo.Status()
+	require.NoError(t, err)
+	require.Equal(t, &git.WorkingTreeStatus{}, status)
+
+	gittest.CommitFile(t, repo, "staged", []byte("one\n"))
+	gittest.CommitFile(t, repo, "unstaged", []byte("one\n"))
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Dir(), "staged"), []byte("two\n"), 0644))
+	_, err = repo.Git("add", "staged")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Dir(), "unstaged"), []byte("two\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo.Dir(), "untracked file"), []byte("new\n"), 0644))
+
+	status, err = repo.Status()
+	require.NoError(t, err)
+	require.Equal(t, &git.WorkingTreeStatus{
+		Staged:    []string{"staged"},
+		Unstaged:  []string{"unstaged"},
+		Untracked: []string{"untracked file"},
+	}, status)
+}
+
+func TestBranchCaseCollision(t *testing.T) {
+	repo := gittest.NewTempRepo(t)
+	_, err := repo.Git("branch", "feature-x")
+	require.NoError(t, err)
+	_, err = repo.Git("branch", "team/feature-y")
+	require.NoError(t, err)
+
+	for name, want := range map[string]string{
+		"feature-x":      "",
+		"feature-z":      "",
+		"Feature-X":      "feature-x",
+		"team/feature-z": "",
+		"Team/feature-z": "team/feature-y",
+		"team/Feature-Y": "team/feature-y",
+	} {
+		got, err := repo.BranchCaseCollision(name)
+		require.NoError(t, err)
+		require.Equal(t, want, got, "unexpected collision for %q", name)
+	}
+
+	// Renaming a branch to a different case of its own name isn't a collision.
+	got, err := repo.BranchCaseCollision("Feature-X", "feature-x")
+	require.NoError(t, err)
+	require.Empty(t, got)
+}
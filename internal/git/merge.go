@@ -0,0 +1,122 @@
+package git
+
+import (
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+type MergeOpts struct {
+	// The branch to merge into. It's checked out first.
+	Branch string
+	// The commit to merge into the branch.
+	Commit string
+	// The message of the merge commit.
+	Message string
+	// The options of the merge strategy (`git merge --strategy-option`), e.g.
+	// "ours" or "diff-algorithm=histogram".
+	StrategyOptions []string
+	// Optional (mutually exclusive with all other options)
+	// If set, conclude a merge that stopped at a conflict (once the conflicts
+	// are resolved and staged) by committing it.
+	Continue bool
+	// Optional (mutually exclusive with all other options)
+	Abort bool
+}
+
+type MergeStatus int
+
+const (
+	MergeAlreadyUpToDate MergeStatus = iota
+	MergeUpdated
+	MergeConflict
+	MergeAborted
+	MergeNotInProgress
+)
+
+type MergeResult struct {
+	Status MergeStatus
+	// The files that conflict (if any).
+	Conflicts []string
+}
+
+// Merge merges a commit into a branch (with `git merge`) or continues or
+// aborts a merge that stopped at a conflict. Unlike a rebase, a merge never
+// rewrites the existing commits of the branch.
+func (r *Repo) Merge(opts MergeOpts) (*MergeResult, error) {
+	switch {
+	case opts.Abort:
+		if _, err := r.Run(&RunOpts{Args: []string{"merge", "--abort"}, ExitError: true}); err != nil {
+			return nil, errors.WrapIf(err, "failed to abort the merge")
+		}
+		return &MergeResult{Status: MergeAborted}, nil
+	case opts.Continue:
+		if op, err := r.OperationInProgress(); err != nil {
+			return nil, err
+		} else if op != OperationMerge {
+			return &MergeResult{Status: MergeNotInProgress}, nil
+		}
+		conflicts, err := r.UnmergedFiles()
+		if err != nil {
+			return nil, err
+		}
+		if len(conflicts) > 0 {
+			return &MergeResult{Status: MergeConflict, Conflicts: conflicts}, nil
+		}
+		if _, err := r.Run(&RunOpts{
+			Args:      []string{"commit", "--no-edit"},
+			Env:       []string{"GIT_EDITOR=true"},
+			ExitError: true,
+		}); err != nil {
+			return nil, errors.WrapIf(err, "failed to commit the merge")
+		}
+		return &MergeResult{Status: MergeUpdated}, nil
+	}
+
+	if _, err := r.CheckoutBranch(&CheckoutBranch{Name: opts.Branch}); err != nil {
+		return nil, err
+	}
+	if upToDate, err := r.IsAncestor(opts.Commit, "HEAD"); err != nil {
+		return nil, err
+	} else if upToDate {
+		return &MergeResult{Status: MergeAlreadyUpToDate}, nil
+	}
+	args := []string{"merge", "--no-edit", "-m", opts.Message}
+	for _, option := range opts.StrategyOptions {
+		args = append(args, "--strategy-option="+option)
+	}
+	out, err := r.Run(&RunOpts{
+		Args: append(args, opts.Commit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.ExitCode == 0 {
+		return &MergeResult{Status: MergeUpdated}, nil
+	}
+	if op, err := r.OperationInProgress(); err != nil {
+		return nil, err
+	} else if op != OperationMerge {
+		// The merge didn't start (e.g., because it would overwrite untracked
+		// files).
+		return nil, errors.Errorf("failed to merge %q into %q: %s",
+			opts.Commit, opts.Branch, strings.TrimSpace(string(out.Stderr)))
+	}
+	conflicts, err := r.UnmergedFiles()
+	if err != nil {
+		return nil, err
+	}
+	return &MergeResult{Status: MergeConflict, Conflicts: conflicts}, nil
+}
+
+// UnmergedFiles returns the files that have unresolved conflicts.
+func (r *Repo) UnmergedFiles() ([]string, error) {
+	out, err := r.Git("diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
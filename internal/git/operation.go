@@ -0,0 +1,46 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Operation is a multi-step Git operation that can be interrupted (e.g., by a
+// conflict) and has to be continued or aborted by the user.
+type Operation string
+
+const (
+	OperationNone       Operation = ""
+	OperationRebase     Operation = "rebase"
+	OperationMerge      Operation = "merge"
+	OperationCherryPick Operation = "cherry-pick"
+	OperationRevert     Operation = "revert"
+)
+
+// operationMarkers maps the files/directories that Git creates in the .git
+// directory while an operation is in progress to the operation.
+var operationMarkers = []struct {
+	name string
+	op   Operation
+}{
+	{"rebase-merge", OperationRebase},
+	{"rebase-apply", OperationRebase},
+	{"MERGE_HEAD", OperationMerge},
+	{"CHERRY_PICK_HEAD", OperationCherryPick},
+	{"REVERT_HEAD", OperationRevert},
+}
+
+// OperationInProgress returns the Git operation that is currently in progress
+// (or OperationNone if there is none).
+func (r *Repo) OperationInProgress() (Operation, error) {
+	for _, marker := range operationMarkers {
+		_, err := os.Stat(filepath.Join(r.GitDir(), marker.name))
+		if err == nil {
+			return marker.op, nil
+		}
+		if !os.IsNotExist(err) {
+			return OperationNone, err
+		}
+	}
+	return OperationNone, nil
+}
@@ -25,6 +25,10 @@ type RebaseOpts struct {
 	// If set, this is the branch that will be rebased; otherwise, the current
 	// branch is rebased.
 	Branch string
+	// Optional
+	// The options of the merge strategy (`git rebase --strategy-option`), e.g.
+	// "theirs" or "diff-algorithm=histogram".
+	StrategyOptions []string
 }
 
 func (r *Repo) Rebase(opts RebaseOpts) (*Output, error) {
@@ -52,6 +56,9 @@ func (r *Repo) Rebase(opts RebaseOpts) (*Output, error) {
 	if opts.Onto != "" {
 		args = append(args, "--onto", opts.Onto)
 	}
+	for _, option := range opts.StrategyOptions {
+		args = append(args, "--strategy-option="+option)
+	}
 	args = append(args, opts.Upstream)
 	if opts.Branch != "" {
 		args = append(args, opts.Branch)
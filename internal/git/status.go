@@ -0,0 +1,108 @@
+package git
+
+import (
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+// WorkingTreeStatus is the state of the working tree and the index relative to
+// HEAD.
+type WorkingTreeStatus struct {
+	// The paths of tracked files that have staged changes.
+	Staged []string
+	// The paths of tracked files that have unstaged changes.
+	Unstaged []string
+	// The paths of files that have unresolved conflicts.
+	Unmerged []string
+	// The paths of untracked (and not ignored) files.
+	Untracked []string
+	// The paths of submodules whose checked out commit or contents differ from
+	// what's recorded in HEAD.
+	Submodules []string
+}
+
+// Status returns the status of the working tree.
+func (r *Repo) Status() (*WorkingTreeStatus, error) {
+	out, err := r.Run(&RunOpts{
+		Args:      []string{"status", "--porcelain=v2", "-z", "--untracked-files=all"},
+		ExitError: true,
+	})
+	if err != nil {
+		return nil, errors.WrapIf(err, "failed to determine the status of the working tree")
+	}
+	return parseStatus(string(out.Stdout))
+}
+
+// StagedConflictMarkers returns the locations (as "<path>:<line>") of conflict
+// markers that are introduced by the staged changes (e.g., because a file was
+// staged with `git add` before the conflicts in it were resolved).
+func (r *Repo) StagedConflictMarkers() ([]string, error) {
+	// `git diff --check` exits with a non-zero code if it finds any problems.
+	out, err := r.Run(&RunOpts{
+		Args: []string{"diff", "--cached", "--check", "--no-color"},
+	})
+	if err != nil {
+		return nil, errors.WrapIf(err, "failed to check for conflict markers")
+	}
+	var res []string
+	for _, line := range out.Lines() {
+		// Besides conflict markers, this also reports whitespace errors, which
+		// we don't care about here.
+		if loc, ok := strings.CutSuffix(line, ": leftover conflict marker"); ok {
+			res = append(res, loc)
+		}
+	}
+	return res, nil
+}
+
+// parseStatus parses the output of `git status --porcelain=v2 -z`.
+func parseStatus(out string) (*WorkingTreeStatus, error) {
+	var status WorkingTreeStatus
+	entries := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if entry == "" {
+			continue
+		}
+		switch entry[0] {
+		case '1', '2', 'u':
+			// <type> <XY> <sub> ... <path>
+			nfields := 9
+			if entry[0] == '2' {
+				nfields = 10
+			} else if entry[0] == 'u' {
+				nfields = 11
+			}
+			fields := strings.SplitN(entry, " ", nfields)
+			if len(fields) != nfields {
+				return nil, errors.Errorf("failed to parse git status entry %q", entry)
+			}
+			xy, sub, path := fields[1], fields[2], fields[nfields-1]
+			if entry[0] == '2' {
+				// Renames and copies are followed by the original path.
+				i++
+			}
+			switch {
+			case entry[0] == 'u':
+				status.Unmerged = append(status.Unmerged, path)
+			case sub[0] == 'S':
+				status.Submodules = append(status.Submodules, path)
+			default:
+				if xy[0] != '.' {
+					status.Staged = append(status.Staged, path)
+				}
+				if xy[1] != '.' {
+					status.Unstaged = append(status.Unstaged, path)
+				}
+			}
+		case '?':
+			status.Untracked = append(status.Untracked, strings.TrimPrefix(entry, "? "))
+		case '!':
+			// Ignored files are only listed with --ignored.
+		default:
+			return nil, errors.Errorf("failed to parse git status entry %q", entry)
+		}
+	}
+	return &status, nil
+}
@@ -2,6 +2,7 @@ package meta
 
 import (
 	"encoding/json"
+	"time"
 
 	"emperror.dev/errors"
 	"github.com/shurcooL/githubv4"
@@ -23,6 +24,54 @@ type Branch struct {
 
 	// The merge commit onto the trunk branch, if any
 	MergeCommit string `json:"mergeCommit,omitempty"`
+
+	// Information about the last successful sync of the branch, if any.
+	LastSync *SyncInfo `json:"lastSync,omitempty"`
+
+	// A free-form description of the stack that this branch is the root of
+	// (see `av stack describe`). Only set on stack roots.
+	StackDescription string `json:"stackDescription,omitempty"`
+
+	// The number of the GitHub issue that the branch was created for (see
+	// `av stack branch --issue`), if any. The pull request of the branch
+	// closes the issue.
+	Issue int64 `json:"issue,omitempty"`
+
+	// If true, the branch is a work in progress (see `av stack wip`). It's
+	// still synced locally but it's never pushed and no pull request is created
+	// or updated for it.
+	WIP bool `json:"wip,omitempty"`
+
+	// If true, the branch belongs to someone else (e.g., a colleague's branch
+	// that is still in review) and a stack is based on it (see
+	// `av stack adopt --read-only`). It's updated from the remote instead of
+	// being rebased, and it's never pushed.
+	ReadOnly bool `json:"readOnly,omitempty"`
+
+	// Set if the stack that this branch is the root of is frozen (see
+	// `av stack freeze`). Only set on stack roots.
+	Freeze *FreezeInfo `json:"freeze,omitempty"`
+}
+
+// FreezeInfo describes who froze a stack and why.
+type FreezeInfo struct {
+	// The Git identity of the user who froze the stack (e.g.,
+	// "Jane Doe <jane@example.com>").
+	By string `json:"by"`
+	// When the stack was frozen.
+	Time time.Time `json:"time"`
+	// Why the stack was frozen, if given.
+	Reason string `json:"reason,omitempty"`
+}
+
+// SyncInfo describes a successful sync of a branch.
+type SyncInfo struct {
+	// When the branch was synced.
+	Time time.Time `json:"time"`
+	// The commit of the trunk that the branch was based on after the sync
+	// (i.e., the merge base of the branch and the remote trunk). Empty if it
+	// couldn't be determined.
+	TrunkCommit string `json:"trunkCommit,omitempty"`
 }
 
 func (b *Branch) IsStackRoot() bool {
@@ -92,6 +141,9 @@ func (p *PullRequest) GetNumber() int64 {
 // PreviousBranches finds all the ancestor branches of the given branch name in
 // "dependency order" (i.e., A comes before B if A is an ancestor of B).
 func PreviousBranches(tx ReadTx, name string) ([]string, error) {
+	if err := checkCycle(tx, name); err != nil {
+		return nil, err
+	}
 	current, ok := tx.Branch(name)
 	if !ok {
 		return nil, errors.Errorf("branch metadata not found for %q", name)
@@ -115,15 +167,27 @@ func PreviousBranches(tx ReadTx, name string) ([]string, error) {
 
 // SubsequentBranches finds all the child branches of the given branch name in
 // "dependency order" (i.e., A comes before B if A is an ancestor of B).
-// If the tree is not a straight line (which isn't explicitly supported!), the
-// branches will be returned in depth-first traversal order.
+// If a branch has more than one child, the branches are returned in depth-first
+// traversal order (with siblings sorted by name), so every branch still comes
+// after its parent.
 func SubsequentBranches(tx ReadTx, name string) []string {
 	logrus.Debugf("finding subsequent branches for %q", name)
+	return subsequentBranches(tx, name, map[string]bool{name: true})
+}
+
+func subsequentBranches(tx ReadTx, name string, visited map[string]bool) []string {
 	var res []string
 	children := Children(tx, name)
 	for _, child := range children {
+		if visited[child.Name] {
+			// This can only happen if the metadata contains a cycle (which is
+			// reported when the metadata is written). Don't loop forever.
+			logrus.WithField("branch", child.Name).Warn("branch metadata contains a parent cycle")
+			continue
+		}
+		visited[child.Name] = true
 		res = append(res, child.Name)
-		res = append(res, SubsequentBranches(tx, child.Name)...)
+		res = append(res, subsequentBranches(tx, child.Name, visited)...)
 	}
 	return res
 }
@@ -159,6 +223,9 @@ func StackBranchesMap(tx ReadTx, name string) (map[string]Branch, error) {
 
 // Root determines the stack root of a branch.
 func Root(tx ReadTx, name string) (string, bool) {
+	if err := checkCycle(tx, name); err != nil {
+		return "", false
+	}
 	for name != "" {
 		branch, _ := tx.Branch(name)
 		if branch.Parent.Trunk {
@@ -169,8 +236,47 @@ func Root(tx ReadTx, name string) (string, bool) {
 	return "", false
 }
 
+// StackDescription returns the description of the stack that the given branch
+// belongs to (which is stored on the root of the stack).
+func StackDescription(tx ReadTx, name string) string {
+	root, ok := Root(tx, name)
+	if !ok {
+		return ""
+	}
+	branch, _ := tx.Branch(root)
+	return branch.StackDescription
+}
+
+// StackFreeze returns the root of the stack of the given branch along with the
+// FreezeInfo of the stack (which is nil if the stack isn't frozen).
+func StackFreeze(tx ReadTx, name string) (string, *FreezeInfo) {
+	root, ok := Root(tx, name)
+	if !ok {
+		return "", nil
+	}
+	branch, _ := tx.Branch(root)
+	return root, branch.Freeze
+}
+
+// IsTrunk returns true if the given branch is the repository's default branch
+// or the trunk of an existing stack.
+func IsTrunk(tx ReadTx, name string, defaultBranch string) bool {
+	if name == defaultBranch {
+		return true
+	}
+	for _, branch := range tx.AllBranches() {
+		if branch.Parent.Trunk && branch.Parent.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Trunk determines the trunk of a branch.
 func Trunk(tx ReadTx, name string) (string, bool) {
+	if err := checkCycle(tx, name); err != nil {
+		return "", false
+	}
 	for name != "" {
 		branch, _ := tx.Branch(name)
 		if branch.Parent.Trunk {
@@ -0,0 +1,83 @@
+package meta
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// CycleError is returned when the parent relationships of branches form a
+// cycle (e.g., A is the parent of B and B is the parent of A). Such metadata is
+// invalid since the branches can never reach a trunk branch.
+type CycleError struct {
+	// The branches that form the cycle, in parent order (i.e., the parent of
+	// Branches[i] is Branches[i+1] and the parent of the last branch is
+	// Branches[0]).
+	Branches []string
+}
+
+func (e CycleError) Error() string {
+	path := append(slices.Clone(e.Branches), e.Branches[0])
+	return fmt.Sprintf(
+		"branch metadata contains a parent cycle: %s",
+		strings.Join(path, " -> "),
+	)
+}
+
+// FindCycles returns all parent cycles among the given branches. Each cycle is
+// reported once, starting from its alphabetically-first branch.
+func FindCycles(branches map[string]Branch) []CycleError {
+	names := make([]string, 0, len(branches))
+	for name := range branches {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	// Branches that are known to either be part of a cycle that was already
+	// reported or lead to a trunk (or a cycle) eventually.
+	done := make(map[string]bool)
+	var cycles []CycleError
+	for _, name := range names {
+		var path []string
+		onPath := make(map[string]int)
+		for current := name; current != "" && !done[current]; {
+			if idx, ok := onPath[current]; ok {
+				cycle := slices.Clone(path[idx:])
+				first := slices.Index(cycle, slices.Min(cycle))
+				cycle = append(cycle[first:], cycle[:first]...)
+				cycles = append(cycles, CycleError{Branches: cycle})
+				break
+			}
+			onPath[current] = len(path)
+			path = append(path, current)
+			branch, ok := branches[current]
+			if !ok || branch.Parent.Trunk {
+				break
+			}
+			current = branch.Parent.Name
+		}
+		for _, visited := range path {
+			done[visited] = true
+		}
+	}
+	return cycles
+}
+
+// checkCycle returns a CycleError if following the parents of the given branch
+// leads back to a branch that was already visited.
+func checkCycle(tx ReadTx, name string) error {
+	var path []string
+	for current := name; current != ""; {
+		if idx := slices.Index(path, current); idx != -1 {
+			return CycleError{Branches: path[idx:]}
+		}
+		path = append(path, current)
+		branch, ok := tx.Branch(current)
+		if !ok || branch.Parent.Trunk {
+			return nil
+		}
+		current = branch.Parent.Name
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package meta_test
+
+import (
+	"testing"
+
+	"github.com/aviator-co/av/internal/meta"
+	"github.com/aviator-co/av/internal/meta/jsonfiledb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindCycles(t *testing.T) {
+	branches := map[string]meta.Branch{
+		"one":   {Name: "one", Parent: meta.BranchState{Name: "main", Trunk: true}},
+		"two":   {Name: "two", Parent: meta.BranchState{Name: "one"}},
+		"three": {Name: "three", Parent: meta.BranchState{Name: "two"}},
+	}
+	require.Empty(t, meta.FindCycles(branches))
+
+	// two -> three -> two
+	branches["two"] = meta.Branch{Name: "two", Parent: meta.BranchState{Name: "three"}}
+	// four -> five -> six -> four (plus seven, which leads into the cycle)
+	branches["four"] = meta.Branch{Name: "four", Parent: meta.BranchState{Name: "five"}}
+	branches["five"] = meta.Branch{Name: "five", Parent: meta.BranchState{Name: "six"}}
+	branches["six"] = meta.Branch{Name: "six", Parent: meta.BranchState{Name: "four"}}
+	branches["seven"] = meta.Branch{Name: "seven", Parent: meta.BranchState{Name: "five"}}
+	require.Equal(t, []meta.CycleError{
+		{Branches: []string{"five", "six", "four"}},
+		{Branches: []string{"three", "two"}},
+	}, meta.FindCycles(branches))
+	require.Equal(
+		t,
+		"branch metadata contains a parent cycle: three -> two -> three",
+		meta.CycleError{Branches: []string{"three", "two"}}.Error(),
+	)
+}
+
+func TestCycleTraversal(t *testing.T) {
+	db, err := jsonfiledb.OpenPath(t.TempDir() + "/db.json")
+	require.NoError(t, err)
+
+	tx := db.WriteTx()
+	tx.SetBranch(meta.Branch{Name: "one", Parent: meta.BranchState{Name: "two"}})
+	tx.SetBranch(meta.Branch{Name: "two", Parent: meta.BranchState{Name: "one"}})
+
+	// None of these should loop forever.
+	_, ok := meta.Root(tx, "one")
+	require.False(t, ok)
+	_, ok = meta.Trunk(tx, "one")
+	require.False(t, ok)
+	_, err = meta.PreviousBranches(tx, "one")
+	require.ErrorAs(t, err, &meta.CycleError{})
+	require.Equal(t, []string{"two"}, meta.SubsequentBranches(tx, "one"))
+
+	// The cycle is reported when the metadata is written.
+	require.ErrorAs(t, tx.Commit(), &meta.CycleError{})
+	_, ok = db.ReadTx().Branch("one")
+	require.False(t, ok, "metadata with a cycle should not be committed")
+}
@@ -1,8 +1,10 @@
 package jsonfiledb_test
 
 import (
+	"os"
 	"testing"
 
+	"emperror.dev/errors"
 	"github.com/aviator-co/av/internal/meta"
 	"github.com/aviator-co/av/internal/meta/jsonfiledb"
 	"github.com/stretchr/testify/assert"
@@ -47,3 +49,27 @@ func TestJSONFileDB(t *testing.T) {
 	require.True(t, ok, "branch should be found after re-open")
 	require.Equal(t, "foo", foo.Name, "branch name should match")
 }
+
+func TestJSONFileDBVersion(t *testing.T) {
+	tempfile := t.TempDir() + "/db.json"
+
+	// State files written before the schema was versioned are upgraded.
+	require.NoError(t, os.WriteFile(tempfile, []byte(`{"branches": {"foo": {"parent": "main"}}}`), 0644))
+	db, err := jsonfiledb.OpenPath(tempfile)
+	require.NoError(t, err)
+	foo, ok := db.ReadTx().Branch("foo")
+	require.True(t, ok, "branch should be found")
+	require.Equal(t, "main", foo.Parent.Name)
+	tx := db.WriteTx()
+	require.NoError(t, tx.Commit())
+	data, err := os.ReadFile(tempfile)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"version": 3`)
+
+	// State files written by newer versions of av are rejected.
+	require.NoError(t, os.WriteFile(tempfile, []byte(`{"version": 999, "branches": {}}`), 0644))
+	_, err = jsonfiledb.OpenPath(tempfile)
+	var versionErr jsonfiledb.UnsupportedVersionError
+	require.True(t, errors.As(err, &versionErr), "unexpected error: %v", err)
+	require.Equal(t, 999, versionErr.Version)
+}
@@ -2,6 +2,7 @@ package jsonfiledb
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 
 	"emperror.dev/errors"
@@ -21,19 +22,71 @@ func readState(filepath string) (*state, error) {
 	if err := json.Unmarshal(data, &state); err != nil {
 		return nil, errors.WrapIff(err, "failed to read av state file %q", filepath)
 	}
+	if err := state.migrate(); err != nil {
+		return nil, errors.WrapIff(err, "failed to read av state file %q", filepath)
+	}
 	return &state, nil
 }
 
+// stateVersion is the current version of the state file schema. It must be
+// incremented (and a migration must be added to stateMigrations) whenever the
+// schema changes in a way that older versions of av can't handle.
+const stateVersion = 3
+
+// stateMigrations[i] migrates the state from version i to version i+1.
+var stateMigrations = []func(*state) error{
+	// 0 -> 1: The state file wasn't versioned before, but the schema itself
+	// didn't change. (Branches whose parent is stored as a plain string are
+	// upgraded by meta.Branch.UnmarshalJSON.)
+	func(*state) error { return nil },
+	// 1 -> 2: Stacks can be frozen (meta.Branch.Freeze). Older versions of av
+	// would drop the freeze and rewrite the history of the frozen stack.
+	func(*state) error { return nil },
+	// 2 -> 3: Branches can be read-only (meta.Branch.ReadOnly). Older versions
+	// of av would drop the flag and then rebase and force-push someone else's
+	// branch.
+	func(*state) error { return nil },
+}
+
+// UnsupportedVersionError is returned when the state file was written by a
+// newer version of av that uses a schema that this version doesn't understand.
+type UnsupportedVersionError struct {
+	Version int
+}
+
+func (e UnsupportedVersionError) Error() string {
+	return fmt.Sprintf(
+		"the av state file was written by a newer version of av (schema version %d, "+
+			"but this version of av only supports up to version %d); upgrade av to continue",
+		e.Version, stateVersion,
+	)
+}
+
 type state struct {
+	Version         int                    `json:"version"`
 	BranchState     map[string]meta.Branch `json:"branches"`
 	RepositoryState meta.Repository        `json:"repository"`
 }
 
 func (d *state) copy() state {
 	return state{
-		maputils.Copy(d.BranchState),
-		d.RepositoryState,
+		Version:         d.Version,
+		BranchState:     maputils.Copy(d.BranchState),
+		RepositoryState: d.RepositoryState,
+	}
+}
+
+// migrate upgrades the state to the current schema version.
+func (d *state) migrate() error {
+	if d.Version > stateVersion {
+		return UnsupportedVersionError{Version: d.Version}
+	}
+	for ; d.Version < stateVersion; d.Version++ {
+		if err := stateMigrations[d.Version](d); err != nil {
+			return errors.WrapIff(err, "failed to migrate from version %d", d.Version)
+		}
 	}
+	return nil
 }
 
 func (d *state) write(filepath string) error {
@@ -41,6 +94,7 @@ func (d *state) write(filepath string) error {
 	if err != nil {
 		return errors.WrapIff(err, "failed to write av state file")
 	}
+	d.Version = stateVersion
 	enc := json.NewEncoder(f)
 	enc.SetIndent("", "  ")
 	if err := enc.Encode(d); err != nil {
@@ -37,6 +37,11 @@ func (tx *writeTx) Commit() error {
 	}
 	// Always unlock the database even if there is an error.
 	defer tx.db.stateMu.Unlock()
+	// Refuse to write metadata that we won't be able to work with later.
+	if cycles := meta.FindCycles(tx.state.BranchState); len(cycles) > 0 {
+		tx.db = nil
+		return cycles[0]
+	}
 	err := tx.state.write(tx.db.filepath)
 	if err != nil {
 		return err
@@ -0,0 +1,129 @@
+package reorder
+
+import (
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/aviator-co/av/internal/editor"
+	"github.com/aviator-co/av/internal/git"
+)
+
+// ReorderBranches rewrites the plan so that the branches are stacked in the
+// given order (i.e., the first branch is based on the trunk of the stack and
+// every other branch is based on the branch before it). Each branch keeps its
+// own commits. Branches that are missing from the order are dropped from the
+// plan.
+//
+// Only plans for linear stacks (where no branch has more than one child) can be
+// reordered this way since the order alone doesn't say where the other
+// children would go.
+func ReorderBranches(plan []Cmd, order []string) ([]Cmd, error) {
+	var trunk string
+	var prev string
+	blocks := make(map[string][]Cmd)
+	var current string
+	for _, cmd := range plan {
+		sb, ok := cmd.(StackBranchCmd)
+		if !ok {
+			if current == "" {
+				return nil, errors.New("the reorder plan must start with a stack-branch command")
+			}
+			blocks[current] = append(blocks[current], cmd)
+			continue
+		}
+		if sb.Trunk != "" {
+			if trunk != "" {
+				return nil, errors.New("the branches of a stack with several roots can't be reordered")
+			}
+			trunk = sb.Trunk
+		} else if sb.Parent != prev {
+			return nil, errors.Errorf(
+				"branch %q has a sibling: the branches of a stack that branches out can't be reordered",
+				sb.Name,
+			)
+		}
+		current = sb.Name
+		prev = sb.Name
+		blocks[current] = []Cmd{sb}
+	}
+
+	var res []Cmd
+	seen := make(map[string]bool)
+	prev = ""
+	for _, name := range order {
+		block, ok := blocks[name]
+		if !ok {
+			return nil, errors.Errorf("branch %q is not part of the stack", name)
+		}
+		if seen[name] {
+			return nil, errors.Errorf("branch %q is listed more than once", name)
+		}
+		seen[name] = true
+
+		sb := block[0].(StackBranchCmd)
+		if prev == "" {
+			sb.Trunk = trunk
+			sb.Parent = ""
+		} else {
+			sb.Trunk = ""
+			sb.Parent = prev
+		}
+		res = append(res, sb)
+		res = append(res, block[1:]...)
+		prev = name
+	}
+	return res, nil
+}
+
+// EditBranchOrder opens the user's editor with the branches of the plan (one
+// per line) and returns the plan reordered according to the edited list (see
+// ReorderBranches).
+func EditBranchOrder(repo *git.Repo, plan []Cmd) ([]Cmd, error) {
+	var names []string
+	for _, cmd := range plan {
+		if sb, ok := cmd.(StackBranchCmd); ok {
+			names = append(names, sb.Name)
+		}
+	}
+	// Make sure that the stack can be reordered before asking the user.
+	if _, err := ReorderBranches(plan, names); err != nil {
+		return nil, err
+	}
+
+	text := strings.Builder{}
+	for _, name := range names {
+		text.WriteString(name)
+		text.WriteString("\n")
+	}
+	text.WriteString(branchOrderInstructionsText)
+
+	res, err := editor.Launch(repo, editor.Config{
+		Text:              text.String(),
+		CommentPrefix:     "#",
+		EndOfLineComments: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var order []string
+	for _, line := range strings.Split(res, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		order = append(order, line)
+	}
+	return ReorderBranches(plan, order)
+}
+
+const branchOrderInstructionsText = `
+# Instructions:
+#
+# Reorder the branches by reordering the lines above. The first branch is based
+# on the trunk and every other branch is stacked on top of the branch before it.
+# Each branch keeps its own commits.
+#
+# Removing a line removes the branch from the stack.
+# If all the lines are removed, the reorder is aborted.
+`
@@ -0,0 +1,52 @@
+package reorder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReorderBranches(t *testing.T) {
+	plan := []Cmd{
+		StackBranchCmd{Name: "one", Trunk: "main@c0"},
+		PickCmd{Commit: "c1a"},
+		PickCmd{Commit: "c1b"},
+		StackBranchCmd{Name: "two", Parent: "one"},
+		PickCmd{Commit: "c2"},
+		StackBranchCmd{Name: "three", Parent: "two", Comment: "this branch has no commits"},
+	}
+
+	reordered, err := ReorderBranches(plan, []string{"two", "one", "three"})
+	require.NoError(t, err)
+	require.Equal(t, []Cmd{
+		StackBranchCmd{Name: "two", Trunk: "main@c0"},
+		PickCmd{Commit: "c2"},
+		StackBranchCmd{Name: "one", Parent: "two"},
+		PickCmd{Commit: "c1a"},
+		PickCmd{Commit: "c1b"},
+		StackBranchCmd{Name: "three", Parent: "one", Comment: "this branch has no commits"},
+	}, reordered)
+
+	// Missing branches are dropped.
+	reordered, err = ReorderBranches(plan, []string{"three", "one"})
+	require.NoError(t, err)
+	require.Equal(t, []Cmd{
+		StackBranchCmd{Name: "three", Trunk: "main@c0", Comment: "this branch has no commits"},
+		StackBranchCmd{Name: "one", Parent: "three"},
+		PickCmd{Commit: "c1a"},
+		PickCmd{Commit: "c1b"},
+	}, reordered)
+
+	_, err = ReorderBranches(plan, []string{"one", "four"})
+	require.Error(t, err)
+	_, err = ReorderBranches(plan, []string{"one", "two", "one"})
+	require.Error(t, err)
+
+	// Stacks that branch out can't be reordered.
+	_, err = ReorderBranches([]Cmd{
+		StackBranchCmd{Name: "one", Trunk: "main@c0"},
+		StackBranchCmd{Name: "two", Parent: "one"},
+		StackBranchCmd{Name: "other", Parent: "one"},
+	}, []string{"one", "two", "other"})
+	require.Error(t, err)
+}
@@ -18,6 +18,35 @@ type StackTreeBranchInfo struct {
 	PullRequestLink   string
 	NeedSync          bool
 	Deleted           bool
+	// The MergeQueue status of the pull request (e.g., "QUEUED" or "BLOCKED")
+	// and the reason for it, if the pull request is queued. Empty if the
+	// pull request isn't queued or the status is not known.
+	QueueStatus       string
+	QueueStatusReason string
+	// The position of the pull request in the queue (starting at 1), or 0 if
+	// it's not known.
+	QueuePosition int
+	// The review decision of the pull request (e.g., "APPROVED" or
+	// "REVIEW_REQUIRED"), the reviewers whose reviews are still pending (e.g.,
+	// "@alice" or "@org/team (code owner)"), and the reviewers who requested
+	// changes. Empty if the review state is not known or no reviews are
+	// required.
+	ReviewDecision           string
+	ReviewPending            []string
+	ReviewChangesRequestedBy []string
+	// True if the branch is marked as work in progress.
+	WIP bool
+	// True if the branch belongs to someone else (see meta.Branch.ReadOnly).
+	ReadOnly bool
+	// True if the stack is frozen (only set for stack roots).
+	Frozen bool
+	// The description of the stack (only set for stack roots).
+	StackDescription string
+	// The description of the branch (see git.Repo.BranchDescription).
+	Description string
+	// When the branch was last synced and how far its trunk has moved since
+	// (e.g., "3 days ago, 41 commits behind main"). Empty if not known.
+	LastSync string
 }
 
 type StackTreeNode struct {
@@ -103,9 +132,16 @@ func getBranchInfo(repo *git.Repo, branch meta.Branch) *StackTreeBranchInfo {
 	if branch.PullRequest != nil && branch.PullRequest.Permalink != "" {
 		branchInfo.PullRequestLink = branch.PullRequest.Permalink
 	}
+	branchInfo.WIP = branch.WIP
+	branchInfo.ReadOnly = branch.ReadOnly
+	if branch.IsStackRoot() {
+		branchInfo.StackDescription = branch.StackDescription
+		branchInfo.Frozen = branch.Freeze != nil
+	}
 	if _, err := repo.RevParse(&git.RevParse{Rev: branch.Name}); err != nil {
 		branchInfo.Deleted = true
 	}
+	branchInfo.Description, _ = repo.BranchDescription(branch.Name)
 
 	parentHead, err := repo.RevParse(&git.RevParse{Rev: branch.Parent.Name})
 	if err != nil {
@@ -142,8 +178,14 @@ func getBranchInfo(repo *git.Repo, branch meta.Branch) *StackTreeBranchInfo {
 	return &branchInfo
 }
 
-func BuildStackTree(repo *git.Repo, tx meta.ReadTx, currentBranch string) []*StackTreeNode {
-	return buildStackTree(repo, currentBranch, tx.AllBranches(), true)
+func BuildStackTree(repo *git.Repo, tx meta.ReadTx, currentBranch string) ([]*StackTreeNode, error) {
+	branches := tx.AllBranches()
+	// Branches that are part of a cycle are unreachable from any root, so
+	// they'd silently be omitted from the tree.
+	if cycles := meta.FindCycles(branches); len(cycles) > 0 {
+		return nil, cycles[0]
+	}
+	return buildStackTree(repo, currentBranch, branches, true), nil
 }
 
 func BuildStackTreeForPullRequest(repo *git.Repo, tx meta.ReadTx, currentBranch string) (*StackTreeNode, error) {
@@ -230,6 +272,24 @@ func PrintNode(columns int, currentBranchName string, isTrunk bool, node *StackT
 	if branch.NeedSync {
 		stats = append(stats, boldString(color.RedString("need sync")))
 	}
+	if branch.WIP {
+		stats = append(stats, boldString(color.YellowString("wip")))
+	}
+	if branch.ReadOnly {
+		stats = append(stats, boldString(color.BlueString("read-only")))
+	}
+	if branch.Frozen {
+		stats = append(stats, boldString(color.BlueString("frozen")))
+	}
+	if status := queueStatusString(branch); status != "" {
+		stats = append(stats, status)
+	}
+	if status := reviewStatusString(branch); status != "" {
+		stats = append(stats, status)
+	}
+	if branch.LastSync != "" {
+		stats = append(stats, color.HiBlackString("synced "+branch.LastSync))
+	}
 	if len(stats) > 0 {
 		fmt.Print(" (")
 		fmt.Print(strings.Join(stats, ", "))
@@ -238,6 +298,22 @@ func PrintNode(columns int, currentBranchName string, isTrunk bool, node *StackT
 	fmt.Println()
 
 	if !isTrunk {
+		if branch.StackDescription != "" {
+			fmt.Print(" ")
+			for i := 0; i < columns+1; i++ {
+				fmt.Print(" │")
+			}
+			fmt.Print(" " + color.CyanString(descriptionSummary(branch.StackDescription)))
+			fmt.Println()
+		}
+		if branch.Description != "" {
+			fmt.Print(" ")
+			for i := 0; i < columns+1; i++ {
+				fmt.Print(" │")
+			}
+			fmt.Print(" " + descriptionSummary(branch.Description))
+			fmt.Println()
+		}
 		fmt.Print(" ")
 		for i := 0; i < columns+1; i++ {
 			fmt.Print(" │")
@@ -250,3 +326,54 @@ func PrintNode(columns int, currentBranchName string, isTrunk bool, node *StackT
 		fmt.Println()
 	}
 }
+
+// descriptionSummary returns the first line of the description of a stack or
+// a branch (which is all that fits into the tree).
+func descriptionSummary(description string) string {
+	summary, rest, _ := strings.Cut(strings.TrimSpace(description), "\n")
+	summary = strings.TrimSpace(summary)
+	if strings.TrimSpace(rest) != "" {
+		summary += " ..."
+	}
+	return summary
+}
+
+func queueStatusString(branch *StackTreeBranchInfo) string {
+	if branch.QueueStatus == "" {
+		return ""
+	}
+	status := "queued"
+	if branch.QueuePosition > 0 {
+		status += fmt.Sprintf(" (position %d)", branch.QueuePosition)
+	}
+	if branch.QueueStatus != "QUEUED" {
+		status += ", " + strings.ToLower(branch.QueueStatus)
+	}
+	if branch.QueueStatusReason != "" {
+		status += ": " + branch.QueueStatusReason
+	}
+	if branch.QueueStatus == "BLOCKED" {
+		return boldString(color.RedString(status))
+	}
+	return boldString(color.YellowString(status))
+}
+
+func reviewStatusString(branch *StackTreeBranchInfo) string {
+	switch branch.ReviewDecision {
+	case "APPROVED":
+		return boldString(color.GreenString("approved"))
+	case "CHANGES_REQUESTED":
+		status := "changes requested"
+		if len(branch.ReviewChangesRequestedBy) > 0 {
+			status += " by " + strings.Join(branch.ReviewChangesRequestedBy, ", ")
+		}
+		return boldString(color.RedString(status))
+	case "REVIEW_REQUIRED":
+		status := "needs review"
+		if len(branch.ReviewPending) > 0 {
+			status += " from " + strings.Join(branch.ReviewPending, ", ")
+		}
+		return boldString(color.YellowString(status))
+	}
+	return ""
+}
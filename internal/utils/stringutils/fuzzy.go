@@ -0,0 +1,25 @@
+package stringutils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// FuzzyMatch returns true if all the characters of the query appear in s in
+// the same order (but not necessarily next to each other). The comparison is
+// case-insensitive and whitespace in the query is ignored, so "fb" and "f b"
+// both match "feature-bar".
+func FuzzyMatch(query string, s string) bool {
+	s = strings.ToLower(s)
+	for _, r := range strings.ToLower(query) {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(string(r)):]
+	}
+	return true
+}
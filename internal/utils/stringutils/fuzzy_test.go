@@ -0,0 +1,29 @@
+package stringutils
+
+import "testing"
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		s     string
+		want  bool
+	}{
+		{"", "feature-bar", true},
+		{"feature-bar", "feature-bar", true},
+		{"fb", "feature-bar", true},
+		{"f b", "feature-bar", true},
+		{"FB", "feature-bar", true},
+		{"bar", "feature-bar", true},
+		{"bf", "feature-bar", false},
+		{"baz", "feature-bar", false},
+		{"feature-bar-2", "feature-bar", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := FuzzyMatch(tt.query, tt.s); got != tt.want {
+				t.Errorf("FuzzyMatch(%q, %q) = %v, want %v", tt.query, tt.s, got, tt.want)
+			}
+		})
+	}
+}